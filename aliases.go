@@ -0,0 +1,265 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// aliasArgPattern matches the argument references in an alias: $1, $2, ... and $@
+var aliasArgPattern = regexp.MustCompile(`\$(@|[1-9][0-9]*)`)
+
+// aliasNamePattern is the form of a valid alias name
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// interactiveCommands are the built-in interactive commands, which aliases
+// cannot replace
+var interactiveCommands = []string{"exit", "quit", "q", "help", "h", "?", "list", "ls", "l", "groups", "call", "c", "alias", "unalias"}
+
+// aliasStore holds the interactive aliases of the profile or saved server in
+// use. save persists them; it is nil when no profile or saved server is used,
+// so aliases only last for the session.
+type aliasStore struct {
+	aliases map[string]string
+	source  string
+	save    func(aliases map[string]string) error
+}
+
+// newAliasStore returns the aliases of a profile, saved to it with save
+func newAliasStore(aliases map[string]string, source string, save func(map[string]string) error) *aliasStore {
+	store := &aliasStore{aliases: map[string]string{}, source: source, save: save}
+	for name, command := range aliases {
+		store.aliases[name] = command
+	}
+	return store
+}
+
+// profileAliasSaver returns a function that saves aliases to a profile in
+// the config file. The file is edited in place, keeping its comments.
+func profileAliasSaver(path, profile string) func(map[string]string) error {
+	return func(aliases map[string]string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if len(doc.Content) == 0 {
+			return fmt.Errorf("config file %s is empty", path)
+		}
+		node := yamlMappingValue(doc.Content[0], "profiles")
+		node = yamlMappingValue(node, profile)
+		if node == nil || node.Kind != yaml.MappingNode {
+			return fmt.Errorf("profile '%s' not found in %s", profile, path)
+		}
+
+		var value yaml.Node
+		if err := value.Encode(aliases); err != nil {
+			return fmt.Errorf("failed to encode aliases: %w", err)
+		}
+		setYAMLMappingValue(node, "aliases", &value, len(aliases) == 0)
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return fmt.Errorf("failed to encode config file: %w", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+		return nil
+	}
+}
+
+// savedServerAliasSaver returns a function that saves aliases to a saved server
+func savedServerAliasSaver(name string) func(map[string]string) error {
+	return func(aliases map[string]string) error {
+		servers, err := loadSavedServers()
+		if err != nil {
+			return err
+		}
+		server, err := servers.lookup(name)
+		if err != nil {
+			return err
+		}
+		server.Aliases = aliases
+		servers[name] = *server
+		return servers.save()
+	}
+}
+
+// yamlMappingValue returns the value of a key in a YAML mapping, or nil
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setYAMLMappingValue sets or, with remove, deletes a key in a YAML mapping
+func setYAMLMappingValue(node *yaml.Node, key string, value *yaml.Node, remove bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		if remove {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		} else {
+			node.Content[i+1] = value
+		}
+		return
+	}
+	if !remove {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	}
+}
+
+// command handles the interactive 'alias' command: with no arguments it
+// lists the aliases, and 'alias name = command' defines one
+func (s *aliasStore) command(line string) error {
+	definition := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "alias"))
+	if definition == "" {
+		s.list()
+		return nil
+	}
+	name, command, ok := strings.Cut(definition, "=")
+	name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+	if !ok || command == "" {
+		if alias, exists := s.aliases[name]; exists && !ok {
+			fmt.Printf("  %s = %s\n", name, alias)
+			return nil
+		}
+		return fmt.Errorf("usage: alias <name> = <command>, e.g. alias s = call search query=$1")
+	}
+	if err := validateAliasName(name); err != nil {
+		return err
+	}
+	if _, err := splitCommandLine(command); err != nil {
+		return fmt.Errorf("invalid command: %w", err)
+	}
+
+	s.aliases[name] = command
+	return s.persist(fmt.Sprintf("Alias '%s' defined", name))
+}
+
+// unalias handles the interactive 'unalias name' command
+func (s *aliasStore) unalias(name string) error {
+	if _, ok := s.aliases[name]; !ok {
+		return fmt.Errorf("no alias named '%s'", name)
+	}
+	delete(s.aliases, name)
+	return s.persist(fmt.Sprintf("Alias '%s' removed", name))
+}
+
+// persist saves the aliases to the profile, if there is one
+func (s *aliasStore) persist(done string) error {
+	if s.save == nil {
+		fmt.Printf("%s for this session (use -profile or -server to save aliases)\n", done)
+		return nil
+	}
+	if err := s.save(s.aliases); err != nil {
+		return fmt.Errorf("failed to save aliases: %w", err)
+	}
+	fmt.Printf("%s and saved to %s\n", done, s.source)
+	return nil
+}
+
+// list prints the defined aliases
+func (s *aliasStore) list() {
+	if len(s.aliases) == 0 {
+		fmt.Println("No aliases defined (e.g. alias s = call search query=$1)")
+		return
+	}
+	names := make([]string, 0, len(s.aliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("\nAliases:")
+	for _, name := range names {
+		fmt.Printf("  %s = %s\n", name, s.aliases[name])
+	}
+}
+
+// expand returns the command line for an alias invocation. $1, $2, ... are
+// replaced with the arguments and $@ with all of them; without references,
+// the arguments are appended. ok is false if name is not an alias.
+func (s *aliasStore) expand(name string, args []string) (line string, ok bool, err error) {
+	command, ok := s.aliases[name]
+	if !ok {
+		return "", false, nil
+	}
+	refs := aliasArgPattern.FindAllStringSubmatch(command, -1)
+	if len(refs) == 0 {
+		for _, arg := range args {
+			command += " " + quoteCommandArg(arg)
+		}
+		return command, true, nil
+	}
+
+	needed := 0
+	for _, ref := range refs {
+		if n, err := strconv.Atoi(ref[1]); err == nil {
+			needed = max(needed, n)
+		}
+	}
+	if len(args) < needed {
+		return "", true, fmt.Errorf("alias '%s' needs %d argument(s): %s", name, needed, command)
+	}
+	line = aliasArgPattern.ReplaceAllStringFunc(command, func(ref string) string {
+		if ref == "$@" {
+			quoted := make([]string, len(args))
+			for i, arg := range args {
+				quoted[i] = quoteCommandArg(arg)
+			}
+			return strings.Join(quoted, " ")
+		}
+		n, _ := strconv.Atoi(ref[1:])
+		return quoteCommandArg(args[n-1])
+	})
+	return line, true, nil
+}
+
+// validateAliasName checks that an alias name is usable and does not replace
+// a built-in command or a tool number
+func validateAliasName(name string) error {
+	if !aliasNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid alias name '%s' (use letters, digits, '_', '-' and '.')", name)
+	}
+	for _, builtin := range interactiveCommands {
+		if name == builtin {
+			return fmt.Errorf("'%s' is a built-in command", name)
+		}
+	}
+	return nil
+}
+
+// quoteCommandArg quotes an argument so that splitCommandLine reads it back
+// as a single word
+func quoteCommandArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t'\"\\") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolTitle returns a tool's display title: its title field, or the title
+// in its annotations, which servers used before tools had one
+func toolTitle(tool mcp.Tool) string {
+	if title := report.toolTitle(tool.Name); title != "" {
+		return title
+	}
+	return tool.Annotations.Title
+}
+
+// toolLabel is a tool's name followed by its title, if it has a different one
+func toolLabel(tool mcp.Tool) string {
+	if title := toolTitle(tool); title != "" && title != tool.Name {
+		return fmt.Sprintf("%s (%s)", tool.Name, title)
+	}
+	return tool.Name
+}
+
+// formatToolHints describes every behavior hint of a tool, marking the ones
+// the server left at their default. The destructive and idempotent hints
+// only apply to tools that modify their environment, so their defaults are
+// left out for read-only tools. It is empty if no hint is set.
+func formatToolHints(annotations mcp.ToolAnnotation) string {
+	readOnly := annotations.ReadOnlyHint != nil && *annotations.ReadOnlyHint
+	hints := []struct {
+		name    string
+		value   *bool
+		def     bool
+		applies bool
+	}{
+		{"readOnlyHint", annotations.ReadOnlyHint, false, true},
+		{"destructiveHint", annotations.DestructiveHint, true, !readOnly},
+		{"idempotentHint", annotations.IdempotentHint, false, !readOnly},
+		{"openWorldHint", annotations.OpenWorldHint, true, true},
+	}
+	var parts []string
+	set := false
+	for _, h := range hints {
+		switch {
+		case h.value != nil:
+			parts = append(parts, fmt.Sprintf("%s=%t", h.name, *h.value))
+			set = true
+		case h.applies:
+			parts = append(parts, fmt.Sprintf("%s=%t (default)", h.name, h.def))
+		}
+	}
+	if !set {
+		return ""
+	}
+	return strings.Join(parts, ", ")
+}
+
+// isDestructive reports whether the server flagged a tool as destructive.
+// The hint only applies to tools that are not read-only, and tools without
+// it are not treated as destructive, as most servers do not annotate.
+func isDestructive(tool *mcp.Tool) bool {
+	a := tool.Annotations
+	if a.ReadOnlyHint != nil && *a.ReadOnlyHint {
+		return false
+	}
+	return a.DestructiveHint != nil && *a.DestructiveHint
+}
+
+// confirmDestructiveCall asks before an interactive call of a tool flagged as
+// destructive. It reports whether to go ahead.
+func confirmDestructiveCall(tool *mcp.Tool, scanner *bufio.Scanner) bool {
+	if !isDestructive(tool) {
+		return true
+	}
+	fmt.Printf("\nWarning: '%s' is flagged as destructive by the server and may modify or delete data\n", tool.Name)
+	fmt.Print("Call it anyway? [y/N]: ")
+	if !scanner.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	if answer == "y" || answer == "yes" {
+		return true
+	}
+	fmt.Println("Call cancelled")
+	return false
+}
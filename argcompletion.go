@@ -0,0 +1,292 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The types of reference whose arguments completion/complete completes
+const (
+	refPrompt   = "ref/prompt"
+	refResource = "ref/resource"
+)
+
+// completionRequestBase is the first ID of the raw completion/complete
+// requests, clear of the IDs the client assigns and of promptRequestID
+const completionRequestBase = 3_000_000
+
+// maxCompletionValues is the most values a completion result may hold
+const maxCompletionValues = 100
+
+// shownCompletionValues is how many suggestions are offered when asking
+// for an argument
+const shownCompletionValues = 10
+
+// completionRequests numbers the raw completion/complete requests
+var completionRequests atomic.Int64
+
+// completionRef is the prompt or resource template whose argument is
+// completed, as sent in the request's ref
+type completionRef struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// completionResult is the completion of a completion/complete response
+type completionResult struct {
+	Values  []string
+	Total   *int
+	HasMore bool
+}
+
+// argumentCompleter asks the server for the values of an argument that
+// start with partial. resolved holds the arguments already given, which the
+// server may use as context. It is nil when the server offers no completions.
+type argumentCompleter func(arg, partial string, resolved map[string]string) (*completionResult, error)
+
+// String names the reference in messages
+func (r completionRef) String() string {
+	if r.Type == refPrompt {
+		return "prompt " + r.Name
+	}
+	return "resource template " + r.URI
+}
+
+// subject is the reference's name or URI, the subject of its findings
+func (r completionRef) subject() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.URI
+}
+
+// parseCompleteSpec parses -complete: prompt:<name>:<arg>:<partial> or
+// resource:<uri-template>:<arg>:<partial>. The argument and partial value
+// are taken from the end, as URI templates contain colons themselves.
+func parseCompleteSpec(spec string) (completionRef, string, string, error) {
+	kind, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return completionRef{}, "", "", fmt.Errorf("expected prompt:<name>:<arg>:<partial> or resource:<uri-template>:<arg>:<partial>")
+	}
+	i := strings.LastIndex(rest, ":")
+	if i < 0 {
+		return completionRef{}, "", "", fmt.Errorf("'%s' has no argument name and partial value", spec)
+	}
+	rest, partial := rest[:i], rest[i+1:]
+	i = strings.LastIndex(rest, ":")
+	if i < 0 {
+		return completionRef{}, "", "", fmt.Errorf("'%s' has no argument name", spec)
+	}
+	target, arg := rest[:i], rest[i+1:]
+	if target == "" || arg == "" {
+		return completionRef{}, "", "", fmt.Errorf("'%s' needs both a %s and an argument name", spec, kind)
+	}
+	switch kind {
+	case "prompt":
+		return completionRef{Type: refPrompt, Name: target}, arg, partial, nil
+	case "resource":
+		return completionRef{Type: refResource, URI: target}, arg, partial, nil
+	default:
+		return completionRef{}, "", "", fmt.Errorf("unknown reference type '%s' (use prompt or resource)", kind)
+	}
+}
+
+// requestCompletion sends completion/complete for an argument of ref. The
+// request is sent raw so that malformed results are reported as problems
+// rather than failing to parse.
+func requestCompletion(ctx context.Context, mcpClient *client.Client, ref completionRef, arg, partial string, resolved map[string]string) (*completionResult, []string, error) {
+	params := map[string]any{
+		"ref":      ref,
+		"argument": map[string]string{"name": arg, "value": partial},
+	}
+	if len(resolved) > 0 && negotiatedSupports(featureCompletionContext, "previously entered arguments are not sent as context") {
+		params["context"] = map[string]any{"arguments": resolved}
+	}
+	body, _ := json.Marshal(params)
+	request := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(completionRequestBase + completionRequests.Add(1)),
+		Method:  string(mcp.MethodCompletionComplete),
+		Params:  json.RawMessage(body),
+	}
+	start := time.Now()
+	response, err := mcpClient.GetTransport().SendRequest(ctx, request)
+	if err == nil && response.Error != nil {
+		err = fmt.Errorf("server returned error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	report.addTiming(string(mcp.MethodCompletionComplete), time.Since(start), err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to complete '%s' of %s: %w", arg, ref, err)
+	}
+
+	var raw struct {
+		Completion *struct {
+			Values  []any `json:"values"`
+			Total   *int  `json:"total"`
+			HasMore bool  `json:"hasMore"`
+		} `json:"completion"`
+	}
+	if err := json.Unmarshal(response.Result, &raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid response to %s: %w", mcp.MethodCompletionComplete, err)
+	}
+	if raw.Completion == nil {
+		return &completionResult{}, []string{"result has no completion"}, nil
+	}
+	result := &completionResult{Total: raw.Completion.Total, HasMore: raw.Completion.HasMore}
+	var problems []string
+	if raw.Completion.Values == nil {
+		problems = append(problems, "completion has no values list")
+	}
+	for i, v := range raw.Completion.Values {
+		s, ok := v.(string)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("value %d is not a string: %s", i+1, itemJSON(v)))
+			continue
+		}
+		result.Values = append(result.Values, s)
+	}
+	return result, append(problems, validateCompletion(result)...), nil
+}
+
+// validateCompletion checks a completion result against the limits of the
+// specification
+func validateCompletion(result *completionResult) []string {
+	var problems []string
+	if len(result.Values) > maxCompletionValues {
+		problems = append(problems, fmt.Sprintf("completion has %d values, the maximum is %d", len(result.Values), maxCompletionValues))
+	}
+	if result.Total != nil {
+		switch {
+		case *result.Total < len(result.Values):
+			problems = append(problems, fmt.Sprintf("total is %d but %d values were returned", *result.Total, len(result.Values)))
+		case *result.Total > len(result.Values) && !result.HasMore:
+			problems = append(problems, fmt.Sprintf("total is %d but only %d values were returned and hasMore is false", *result.Total, len(result.Values)))
+		}
+	}
+	return problems
+}
+
+// completeArgument requests the completions of an argument for -complete,
+// prints them and validates the server's response
+func completeArgument(ctx context.Context, mcpClient *client.Client, spec string) error {
+	fmt.Println("\n=== Complete Argument ===")
+	ref, arg, partial, err := parseCompleteSpec(spec)
+	if err != nil {
+		return fmt.Errorf("invalid -complete: %w", err)
+	}
+	if mcpClient.GetServerCapabilities().Completions == nil {
+		fmt.Printf("Warning: %s\n", report.addWarning(warningCapability, "the server does not advertise the completions capability; requesting anyway"))
+	}
+	negotiatedSupports(featureCompletions, "the server need not answer; requesting anyway")
+	fmt.Printf("Completing '%s' of %s from %q\n", arg, ref, partial)
+
+	start := time.Now()
+	result, problems, err := requestCompletion(ctx, mcpClient, ref, arg, partial, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Got %d value(s) in %s\n\n", len(result.Values), humanDuration(time.Since(start)))
+	for _, v := range result.Values {
+		fmt.Printf("  %s\n", v)
+	}
+	if result.Total != nil {
+		fmt.Printf("\nTotal: %d", *result.Total)
+		if result.HasMore {
+			fmt.Print(" (more available)")
+		}
+		fmt.Println()
+	} else if result.HasMore {
+		fmt.Println("\nMore values are available")
+	}
+
+	if len(problems) > 0 {
+		fmt.Println("\nProblems with the response:")
+		unsuppressed := 0
+		for _, p := range problems {
+			f := report.addFinding(checkIDCompletionResponse, ref.subject(), "%s %s: %s", mcp.MethodCompletionComplete, ref.subject(), p)
+			fmt.Printf("  ! %s\n", f)
+			if f.fails() {
+				unsuppressed++
+			}
+		}
+		if unsuppressed > 0 {
+			return fmt.Errorf("invalid response to %s (%d problem(s))", mcp.MethodCompletionComplete, unsuppressed)
+		}
+		fmt.Println("\nAll problems are suppressed")
+		return nil
+	}
+	fmt.Println("\nResponse is valid")
+	return nil
+}
+
+// newArgumentCompleter returns a completer for the arguments of ref, or nil
+// if the server does not advertise completions
+func newArgumentCompleter(ctx context.Context, mcpClient *client.Client, ref completionRef) argumentCompleter {
+	if mcpClient.GetServerCapabilities().Completions == nil {
+		return nil
+	}
+	return func(arg, partial string, resolved map[string]string) (*completionResult, error) {
+		result, _, err := requestCompletion(ctx, mcpClient, ref, arg, partial, resolved)
+		return result, err
+	}
+}
+
+// askArgument reads a value for an argument on the terminal. With a
+// completer, the server's suggestions are shown first, and a value ending in
+// '?' asks for the suggestions that start with it instead of being used.
+func askArgument(reader *bufio.Reader, name string, complete argumentCompleter, resolved map[string]string) (string, error) {
+	partial := ""
+	for {
+		if complete != nil {
+			printSuggestions(complete, name, partial, resolved)
+		}
+		fmt.Printf("%s: ", name)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("no value for '%s'", name)
+		}
+		value := strings.TrimRight(line, "\r\n")
+		if complete == nil || !strings.HasSuffix(value, "?") {
+			return value, nil
+		}
+		partial = strings.TrimSuffix(value, "?")
+	}
+}
+
+// printSuggestions prints the server's completions of an argument, if any
+func printSuggestions(complete argumentCompleter, name, partial string, resolved map[string]string) {
+	result, err := complete(name, partial, resolved)
+	if err != nil {
+		fmt.Printf("  (no suggestions: %v)\n", err)
+		return
+	}
+	if len(result.Values) == 0 {
+		if partial != "" {
+			fmt.Printf("  (no suggestions for '%s')\n", partial)
+		}
+		return
+	}
+	values := result.Values
+	more := result.HasMore
+	if len(values) > shownCompletionValues {
+		values, more = values[:shownCompletionValues], true
+	}
+	fmt.Printf("  Suggestions: %s", strings.Join(values, ", "))
+	if more {
+		fmt.Print(", ...")
+	}
+	fmt.Println(" (end with '?' to narrow)")
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// protectedResourceMetadata is the RFC 9728 OAuth protected resource metadata
+type protectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported,omitempty"`
+	ResourceDocumentation  string   `json:"resource_documentation,omitempty"`
+}
+
+// authServerMetadata is the RFC 8414 OAuth authorization server metadata
+type authServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	GrantTypesSupported           []string `json:"grant_types_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// authChallenge is a parsed WWW-Authenticate challenge
+type authChallenge struct {
+	scheme string
+	params map[string]string
+}
+
+// isUnauthorized reports whether err is the server rejecting the client with 401
+func isUnauthorized(err error) bool {
+	return errors.Is(err, transport.ErrUnauthorized) || client.IsOAuthAuthorizationRequiredError(err)
+}
+
+// diagnoseUnauthorized explains a 401 response. The transport does not expose
+// the response, so the request is repeated to read the WWW-Authenticate
+// challenge; the protected resource metadata (RFC 9728) and authorization
+// server metadata (RFC 8414) are then fetched and summarized.
+func diagnoseUnauthorized(serverURL, transportName string, headers map[string]string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	httpClient := newProbeHTTPClient(timeout, 0)
+
+	fmt.Println("\n=== Authorization Required ===")
+	challenges, err := fetchChallenges(ctx, httpClient, serverURL, transportName, headers)
+	if err != nil {
+		fmt.Printf("Could not read the authorization challenge: %v\n", err)
+		return
+	}
+
+	var bearer *authChallenge
+	for i, c := range challenges {
+		fmt.Printf("WWW-Authenticate: %s\n", c.scheme)
+		for _, key := range []string{"realm", "error", "error_description", "scope", "resource_metadata"} {
+			if v := c.params[key]; v != "" {
+				fmt.Printf("  %-18s %s\n", key+":", v)
+			}
+		}
+		if strings.EqualFold(c.scheme, "Bearer") && bearer == nil {
+			bearer = &challenges[i]
+		}
+	}
+	if len(challenges) == 0 {
+		fmt.Println("The server did not send a WWW-Authenticate header")
+	}
+
+	// Prefer the metadata URL from the challenge, then the well-known locations
+	var candidates []string
+	if bearer != nil && bearer.params["resource_metadata"] != "" {
+		candidates = append(candidates, bearer.params["resource_metadata"])
+	}
+	candidates = append(candidates, wellKnownURLs(serverURL, "oauth-protected-resource")...)
+
+	var resource protectedResourceMetadata
+	metadataURL, err := fetchFirstJSON(ctx, httpClient, candidates, &resource)
+	authServers := resource.AuthorizationServers
+	if err != nil {
+		fmt.Printf("\nProtected resource metadata: not found (%v)\n", err)
+		// Servers from before RFC 9728 support act as their own authorization server
+		if parsed, perr := url.Parse(serverURL); perr == nil {
+			authServers = []string{parsed.Scheme + "://" + parsed.Host}
+		}
+	} else {
+		fmt.Printf("\nProtected resource metadata: %s\n", metadataURL)
+		printField("Resource", resource.Resource)
+		printField("Name", resource.ResourceName)
+		printField("Authorization servers", strings.Join(resource.AuthorizationServers, ", "))
+		printField("Scopes supported", strings.Join(resource.ScopesSupported, " "))
+		printField("Bearer methods", strings.Join(resource.BearerMethodsSupported, ", "))
+		printField("Documentation", resource.ResourceDocumentation)
+	}
+
+	for _, issuer := range authServers {
+		var as authServerMetadata
+		candidates := append(wellKnownURLs(issuer, "oauth-authorization-server"), wellKnownURLs(issuer, "openid-configuration")...)
+		asURL, err := fetchFirstJSON(ctx, httpClient, candidates, &as)
+		if err != nil {
+			fmt.Printf("\nAuthorization server %s: metadata not found (%v)\n", issuer, err)
+			continue
+		}
+		fmt.Printf("\nAuthorization server: %s\n", as.Issuer)
+		printField("Metadata", asURL)
+		printField("Authorization endpoint", as.AuthorizationEndpoint)
+		printField("Token endpoint", as.TokenEndpoint)
+		if as.RegistrationEndpoint != "" {
+			printField("Registration endpoint", as.RegistrationEndpoint+" (dynamic client registration)")
+		}
+		printField("Scopes supported", strings.Join(as.ScopesSupported, " "))
+		printField("Grant types", strings.Join(as.GrantTypesSupported, ", "))
+		printField("PKCE methods", strings.Join(as.CodeChallengeMethodsSupported, ", "))
+	}
+
+	if bearer != nil && bearer.params["scope"] != "" {
+		fmt.Printf("\nRequired scopes: %s\n", bearer.params["scope"])
+	}
+	fmt.Println("\nAuthenticate with -oauth (browser), -oauth-client-credentials (CI) or -H 'Authorization: Bearer <token>'")
+	fmt.Println()
+}
+
+// printField prints an indented label and value, skipping empty values
+func printField(label, value string) {
+	if value != "" {
+		fmt.Printf("  %-23s %s\n", label+":", value)
+	}
+}
+
+// fetchChallenges repeats the request that was rejected and returns the
+// challenges from its WWW-Authenticate headers
+func fetchChallenges(ctx context.Context, httpClient *http.Client, serverURL, transportName string, headers map[string]string) ([]authChallenge, error) {
+	var req *http.Request
+	var err error
+	if transportName == "sse" {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+		if err == nil {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+	} else {
+		body, _ := json.Marshal(map[string]any{
+			"jsonrpc": mcp.JSONRPC_VERSION,
+			"id":      1,
+			"method":  string(mcp.MethodInitialize),
+			"params":  newInitializeRequest().Params,
+		})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, serverURL, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json, text/event-stream")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil, fmt.Errorf("the server now responds %s", resp.Status)
+	}
+
+	var challenges []authChallenge
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		challenges = append(challenges, parseAuthChallenges(header)...)
+	}
+	return challenges, nil
+}
+
+// parseAuthChallenges parses a WWW-Authenticate header value (RFC 9110
+// section 11.6.1), which may hold several comma separated challenges
+func parseAuthChallenges(header string) []authChallenge {
+	var challenges []authChallenge
+	var current *authChallenge
+	s := strings.TrimSpace(header)
+
+	for s != "" {
+		s = strings.TrimLeft(s, ", ")
+		if s == "" {
+			break
+		}
+		// A token followed by '=' is a parameter of the current challenge,
+		// otherwise it starts a new challenge
+		end := strings.IndexAny(s, " =,")
+		if end < 0 {
+			end = len(s)
+		}
+		token := s[:end]
+		rest := strings.TrimLeft(s[end:], " ")
+
+		if strings.HasPrefix(rest, "=") && current != nil && !strings.HasPrefix(rest, "==") {
+			var value string
+			value, s = parseAuthParamValue(strings.TrimLeft(rest[1:], " "))
+			current.params[strings.ToLower(token)] = value
+			continue
+		}
+		if strings.HasPrefix(rest, "=") && current != nil {
+			// token68 credentials such as "Basic abc=="
+			s = strings.TrimLeft(rest, "=")
+			continue
+		}
+		challenges = append(challenges, authChallenge{scheme: token, params: map[string]string{}})
+		current = &challenges[len(challenges)-1]
+		s = rest
+	}
+	return challenges
+}
+
+// parseAuthParamValue reads a token or quoted-string parameter value and
+// returns it with the remaining input
+func parseAuthParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " ,")
+		if end < 0 {
+			return s, ""
+		}
+		return s[:end], s[end:]
+	}
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+			}
+		case '"':
+			return value.String(), s[i+1:]
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+	return value.String(), ""
+}
+
+// wellKnownURLs returns the well-known metadata URLs for a resource or issuer.
+// The well-known segment is inserted before any path (RFC 8414 section 3.1),
+// with the root location as a fallback.
+func wellKnownURLs(base, name string) []string {
+	parsed, err := url.Parse(base)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+	path := strings.TrimRight(parsed.Path, "/")
+	urls := []string{}
+	if path != "" {
+		urls = append(urls, origin+"/.well-known/"+name+path)
+	}
+	return append(urls, origin+"/.well-known/"+name)
+}
+
+// fetchFirstJSON decodes the first URL that returns a JSON document and
+// returns that URL
+func fetchFirstJSON(ctx context.Context, httpClient *http.Client, urls []string, target any) (string, error) {
+	var lastErr error = errors.New("no metadata URL")
+	for _, u := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("%s: %s", u, resp.Status)
+			continue
+		}
+		if err := json.Unmarshal(body, target); err != nil {
+			lastErr = fmt.Errorf("%s: invalid JSON: %w", u, err)
+			continue
+		}
+		return u, nil
+	}
+	return "", lastErr
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAuthChallenges(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []authChallenge
+	}{
+		{
+			name:   "bearer with resource metadata",
+			header: `Bearer resource_metadata="https://api.example.com/.well-known/oauth-protected-resource"`,
+			want: []authChallenge{{scheme: "Bearer", params: map[string]string{
+				"resource_metadata": "https://api.example.com/.well-known/oauth-protected-resource",
+			}}},
+		},
+		{
+			name:   "several challenges",
+			header: `Basic realm="api", Bearer realm="api", error="invalid_token", scope="read write"`,
+			want: []authChallenge{
+				{scheme: "Basic", params: map[string]string{"realm": "api"}},
+				{scheme: "Bearer", params: map[string]string{"realm": "api", "error": "invalid_token", "scope": "read write"}},
+			},
+		},
+		{
+			name:   "token values and case-insensitive names",
+			header: `Bearer Realm=api,Error=insufficient_scope`,
+			want:   []authChallenge{{scheme: "Bearer", params: map[string]string{"realm": "api", "error": "insufficient_scope"}}},
+		},
+		{
+			name:   "escaped quotes",
+			header: `Bearer error_description="the \"token\" expired"`,
+			want:   []authChallenge{{scheme: "Bearer", params: map[string]string{"error_description": `the "token" expired`}}},
+		},
+		{
+			name:   "token68",
+			header: `Negotiate abc==, Bearer`,
+			want: []authChallenge{
+				{scheme: "Negotiate", params: map[string]string{}},
+				{scheme: "Bearer", params: map[string]string{}},
+			},
+		},
+		{
+			name:   "empty",
+			header: "  ",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAuthChallenges(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAuthChallenges(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Kinds of items compared by compare-auth
+const (
+	surfaceTool     = "tool"
+	surfaceResource = "resource"
+	surfaceTemplate = "resource template"
+	surfacePrompt   = "prompt"
+)
+
+// surfaceKinds is the order in which the kinds are listed
+var surfaceKinds = []string{surfaceTool, surfaceResource, surfaceTemplate, surfacePrompt}
+
+// surfacePromptArg is the value given to each required argument when a
+// prompt is fetched to see whether it is served without credentials
+const surfacePromptArg = "mcpprobe"
+
+// compareAuthCommandArgs maps "probe compare-auth ..." onto the -compare-auth
+// flag so that the command accepts every connection option
+func compareAuthCommandArgs(args []string) []string {
+	return append([]string{args[0], "-compare-auth"}, args[2:]...)
+}
+
+// anonymousHeaders returns the headers without the ones that carry
+// credentials, and the names of the headers it dropped
+func anonymousHeaders(headers map[string]string) (map[string]string, []string) {
+	kept := make(map[string]string, len(headers))
+	var dropped []string
+	for _, name := range sortedKeys(headers) {
+		if isSecretHeader(name) {
+			dropped = append(dropped, name)
+			continue
+		}
+		kept[name] = headers[name]
+	}
+	return kept, dropped
+}
+
+// authSurfaceItem is a tool, resource, resource template or prompt and what
+// each session could do with it
+type authSurfaceItem struct {
+	Kind          string `json:"kind"`
+	Name          string `json:"name"`
+	Authenticated string `json:"authenticated"`
+	Anonymous     string `json:"anonymous"`
+	Exposed       bool   `json:"exposed"`
+
+	// exposure lists what the anonymous session could do, e.g. "listed"
+	exposure []string
+}
+
+// authSurfaceReport is the result of compare-auth
+type authSurfaceReport struct {
+	DroppedHeaders      []string          `json:"droppedHeaders,omitempty"`
+	DroppedOAuth        bool              `json:"droppedOAuth,omitempty"`
+	AnonymousInitialize string            `json:"anonymousInitialize"`
+	Items               []authSurfaceItem `json:"items"`
+	Exposed             int               `json:"exposed"`
+}
+
+// authSurfaceSession is what one session could list
+type authSurfaceSession struct {
+	names    map[string][]string
+	listErrs map[string]error
+	prompts  map[string]mcp.Prompt
+}
+
+// listAuthSurface lists the tools, resources, resource templates and prompts
+// of the capabilities the session's server advertised
+func listAuthSurface(ctx context.Context, mcpClient *client.Client, caps mcp.ServerCapabilities) *authSurfaceSession {
+	s := &authSurfaceSession{names: make(map[string][]string), listErrs: make(map[string]error), prompts: make(map[string]mcp.Prompt)}
+	if caps.Tools != nil {
+		if result, _, err := listAllTools(ctx, mcpClient); err != nil {
+			s.listErrs[surfaceTool] = err
+		} else {
+			for _, tool := range result.Tools {
+				s.names[surfaceTool] = append(s.names[surfaceTool], tool.Name)
+			}
+		}
+	}
+	if caps.Resources != nil {
+		if result, err := listAllResources(ctx, mcpClient); err != nil {
+			s.listErrs[surfaceResource] = err
+		} else {
+			for _, res := range result.Resources {
+				s.names[surfaceResource] = append(s.names[surfaceResource], res.URI)
+			}
+		}
+		if result, err := listAllResourceTemplates(ctx, mcpClient); err != nil {
+			s.listErrs[surfaceTemplate] = err
+		} else {
+			for _, tmpl := range result.ResourceTemplates {
+				if tmpl.URITemplate != nil {
+					s.names[surfaceTemplate] = append(s.names[surfaceTemplate], tmpl.URITemplate.Raw())
+				}
+			}
+		}
+	}
+	if caps.Prompts != nil {
+		if result, err := listAllPrompts(ctx, mcpClient); err != nil {
+			s.listErrs[surfacePrompt] = err
+		} else {
+			for _, prompt := range result.Prompts {
+				s.names[surfacePrompt] = append(s.names[surfacePrompt], prompt.Name)
+				s.prompts[prompt.Name] = prompt
+			}
+		}
+	}
+	return s
+}
+
+// listState describes whether a session listed an item
+func (s *authSurfaceSession) listState(kind, name string) string {
+	switch {
+	case s.listErrs[kind] != nil:
+		return "list rejected"
+	case slices.Contains(s.names[kind], name):
+		return "listed"
+	default:
+		return "not listed"
+	}
+}
+
+// runAuthComparison probes the server with the configured credentials and
+// again without them, and reports every tool, resource, resource template
+// and prompt the anonymous session could list, read, get or call. Resources
+// are read and prompts fetched anonymously even when they are only listed
+// with credentials, since hiding an item from a list does not protect it.
+// Only the -call tool, if any, is called. It returns an error if an item is
+// usable without credentials.
+func runAuthComparison(dial, dialAnonymous func(ctx context.Context) (*client.Client, error), surface *authSurfaceReport, tool string, args map[string]any, timeout, callTimeout time.Duration) error {
+	fmt.Println("=== Authentication Surface ===")
+	var without []string
+	for _, name := range surface.DroppedHeaders {
+		without = append(without, "the "+name+" header")
+	}
+	if surface.DroppedOAuth {
+		without = append(without, "the OAuth token")
+	}
+	fmt.Printf("Anonymous session: without %s\n", strings.Join(without, " and "))
+
+	// Each session's client lives as long as its context, so the contexts
+	// cover the whole comparison
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	authClient, err := dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect with credentials: %w", err)
+	}
+	defer func() { _ = authClient.Close() }()
+	initResult, err := authClient.Initialize(ctx, newInitializeRequest())
+	if err != nil {
+		return fmt.Errorf("failed to initialize with credentials: %w", err)
+	}
+	report.setInitResult(initResult)
+	authenticated := listAuthSurface(ctx, authClient, initResult.Capabilities)
+
+	var toolDef *mcp.Tool
+	if tool != "" {
+		if _, toolDef, err = resolveToolName(ctx, authClient, tool, false); err != nil {
+			return err
+		}
+		if toolDef != nil && !confirmDestructiveCall(toolDef, stdinScanner()) {
+			return nil
+		}
+	}
+
+	anonCtx, anonCancel := context.WithTimeout(context.Background(), timeout)
+	defer anonCancel()
+	var anonymous *authSurfaceSession
+	var anonClient *client.Client
+	anonClient, err = dialAnonymous(anonCtx)
+	if err == nil {
+		defer func() { _ = anonClient.Close() }()
+		var anonInit *mcp.InitializeResult
+		if anonInit, err = anonClient.Initialize(anonCtx, newInitializeRequest()); err == nil {
+			anonymous = listAuthSurface(anonCtx, anonClient, anonInit.Capabilities)
+		}
+	}
+	surface.AnonymousInitialize = "accepted"
+	if err != nil {
+		surface.AnonymousInitialize = "rejected: " + strings.TrimSpace(err.Error())
+	}
+	fmt.Printf("Anonymous initialize: %s\n\n", surface.AnonymousInitialize)
+
+	// Items in the order the authenticated session listed them, followed by
+	// any only the anonymous session listed
+	for _, kind := range surfaceKinds {
+		names := slices.Clone(authenticated.names[kind])
+		if anonymous != nil {
+			for _, name := range anonymous.names[kind] {
+				if !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+		for _, name := range names {
+			item := authSurfaceItem{Kind: kind, Name: name, Authenticated: authenticated.listState(kind, name), Anonymous: "rejected"}
+			if anonymous != nil {
+				item.Anonymous = anonymous.listState(kind, name)
+				if item.Anonymous == "listed" {
+					item.exposure = append(item.exposure, "listed")
+				}
+				switch kind {
+				case surfaceResource:
+					readCtx, readCancel := context.WithTimeout(anonCtx, callTimeout)
+					request := mcp.ReadResourceRequest{}
+					request.Params.URI = name
+					_, readErr := anonClient.ReadResource(readCtx, request)
+					readCancel()
+					item.Anonymous += accessState(readErr, "readable", "read rejected")
+					if readErr == nil {
+						item.exposure = append(item.exposure, "readable")
+					}
+				case surfacePrompt:
+					prompt, ok := authenticated.prompts[name]
+					if !ok {
+						prompt = anonymous.prompts[name]
+					}
+					getCtx, getCancel := context.WithTimeout(anonCtx, callTimeout)
+					request := mcp.GetPromptRequest{}
+					request.Params.Name = name
+					request.Params.Arguments = make(map[string]string)
+					for _, arg := range prompt.Arguments {
+						if arg.Required {
+							request.Params.Arguments[arg.Name] = surfacePromptArg
+						}
+					}
+					_, getErr := anonClient.GetPrompt(getCtx, request)
+					getCancel()
+					item.Anonymous += accessState(getErr, "gettable", "get rejected")
+					if getErr == nil {
+						item.exposure = append(item.exposure, "gettable")
+					}
+				}
+			}
+			surface.Items = append(surface.Items, item)
+		}
+	}
+
+	// Call the -call tool with and without credentials
+	if tool != "" {
+		name := tool
+		if toolDef != nil {
+			name = toolDef.Name
+		}
+		i := slices.IndexFunc(surface.Items, func(item authSurfaceItem) bool {
+			return item.Kind == surfaceTool && item.Name == name
+		})
+		if i < 0 {
+			surface.Items = append(surface.Items, authSurfaceItem{Kind: surfaceTool, Name: name, Authenticated: "not listed", Anonymous: "rejected"})
+			if anonymous != nil {
+				surface.Items[len(surface.Items)-1].Anonymous = anonymous.listState(surfaceTool, name)
+			}
+			i = len(surface.Items) - 1
+		}
+		item := &surface.Items[i]
+		item.Authenticated += surfaceCall(authClient, name, args, callTimeout)
+		if anonymous != nil {
+			state := surfaceCall(anonClient, name, args, callTimeout)
+			item.Anonymous += state
+			if state == ", called" {
+				item.exposure = append(item.exposure, "callable")
+			}
+		}
+	}
+
+	nameWidth, stateWidth := len("Item"), len("Authenticated")
+	for _, item := range surface.Items {
+		nameWidth = max(nameWidth, len(item.Kind)+1+len(item.Name))
+		stateWidth = max(stateWidth, len(item.Authenticated))
+	}
+	if len(surface.Items) == 0 {
+		fmt.Println("The server lists no tools, resources, resource templates or prompts with credentials")
+	} else {
+		fmt.Printf("%-*s  %-*s  %s\n", nameWidth, "Item", stateWidth, "Authenticated", "Anonymous")
+	}
+
+	// Reading, getting or calling an item without credentials is an error;
+	// an item that is only listed is a warning, since its name and schema
+	// are visible but it is not usable
+	usable, listed, failed, suppressed := 0, 0, 0, 0
+	for i := range surface.Items {
+		item := &surface.Items[i]
+		item.Exposed = len(item.exposure) > 0
+		status := checkPass
+		var line string
+		if item.Exposed {
+			subject := item.Kind + " " + item.Name
+			check := checkIDAnonymousAccess
+			if len(item.exposure) == 1 && item.exposure[0] == "listed" {
+				check = checkIDAnonymousListing
+			}
+			f := report.addFinding(check, subject, "%s is %s without credentials", subject, strings.Join(item.exposure, " and "))
+			switch {
+			case f.Suppressed:
+				suppressed++
+			case check == checkIDAnonymousListing:
+				listed++
+			default:
+				usable++
+			}
+			if f.fails() {
+				failed++
+				status = checkFail
+			}
+			line = f.String()
+		}
+		emitEvent(eventCheck, map[string]any{
+			"id":            item.Kind + " " + item.Name,
+			"status":        status,
+			"authenticated": item.Authenticated,
+			"anonymous":     item.Anonymous,
+		})
+		fmt.Printf("%-*s  %-*s  %s\n", nameWidth, item.Kind+" "+item.Name, stateWidth, item.Authenticated, item.Anonymous)
+		if line != "" {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	surface.Exposed = usable + listed
+	report.setAuthSurface(surface)
+
+	fmt.Printf("\n%d item%s: %d usable and %d only listed without credentials", len(surface.Items), pluralS(len(surface.Items)), usable, listed)
+	if suppressed > 0 {
+		fmt.Printf(", %d suppressed", suppressed)
+	}
+	fmt.Println()
+	if failed > 0 {
+		fmt.Println("Result: EXPOSED")
+		return fmt.Errorf("server exposes %d item%s without credentials", surface.Exposed, pluralS(surface.Exposed))
+	}
+	if surface.Exposed > 0 {
+		fmt.Println("Result: PROTECTED, but items are listed without credentials")
+		return nil
+	}
+	fmt.Println("Result: PROTECTED")
+	return nil
+}
+
+// accessState describes the outcome of an anonymous read or get
+func accessState(err error, allowed, rejected string) string {
+	if err != nil {
+		return ", " + rejected
+	}
+	return ", " + allowed
+}
+
+// surfaceCall calls a tool and describes the outcome. A tool error counts as
+// a rejection, since servers that check credentials per tool report them so.
+func surfaceCall(mcpClient *client.Client, tool string, args map[string]any, callTimeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	request.Params.Arguments = args
+	result, err := mcpClient.CallTool(ctx, request)
+	switch {
+	case err != nil:
+		return ", call rejected"
+	case result.IsError:
+		return ", tool error"
+	default:
+		return ", called"
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Semantic version bumps
+const (
+	bumpMajor = "major"
+	bumpMinor = "minor"
+	bumpPatch = "patch"
+	bumpNone  = "none"
+)
+
+// versionBump is the semantic version bump suggested by a baseline comparison
+type versionBump struct {
+	Suggested       string `json:"suggested"`
+	Reason          string `json:"reason"`
+	BaselineVersion string `json:"baselineVersion,omitempty"`
+	CurrentVersion  string `json:"currentVersion,omitempty"`
+	Expected        string `json:"expected,omitempty"`
+	Consistent      *bool  `json:"consistent,omitempty"`
+}
+
+// Inconsistent reports whether the current version is lower than expected
+func (b *versionBump) Inconsistent() bool {
+	return b.Consistent != nil && !*b.Consistent
+}
+
+// runBaselineComparison runs the capability checks against a previous
+// release of the server (the baseline) and the current one, classifies the
+// differences and suggests the semantic version bump for the current release.
+// It returns an error if the current release breaks the baseline's clients
+// or a check failed.
+func runBaselineComparison(baseline, current transportTarget, opts suiteOptions, timeout time.Duration) error {
+	fmt.Println("=== Baseline Comparison ===")
+	fmt.Printf("baseline  %s\n", baseline.url)
+	fmt.Printf("current   %s\n\n", current.url)
+
+	outcomesA := runCapabilitySuite(baseline.dial, timeout, opts)
+	outcomesB := runCapabilitySuite(current.dial, timeout, opts)
+
+	width := len("Check")
+	for _, o := range outcomesA {
+		width = max(width, len(o.ID))
+	}
+	fmt.Printf("%-*s  %-22s  %s\n", width, "Check", "baseline", "current")
+	for i := range outcomesA {
+		fmt.Printf("%-*s  %-22s  %s\n", width, outcomesA[i].ID, outcomeCell(outcomesA[i]), outcomeCell(outcomesB[i]))
+	}
+
+	// Server versions are compared by the suggestion, not reported as changes
+	diffs := compareOutcomes("baseline", "current", withoutServerVersion(outcomesA), withoutServerVersion(outcomesB))
+	bump := suggestVersionBump(diffs)
+	bump.BaselineVersion, bump.CurrentVersion = serverVersion(outcomesA), serverVersion(outcomesB)
+	checkVersionBump(bump)
+	report.setBaselineDiffs(diffs, bump)
+	emitEvent(eventBaselineDiff, map[string]any{
+		"urls":        []string{baseline.url, current.url},
+		"differences": diffs,
+		"summary":     impactSummary(diffs),
+		"versionBump": bump,
+	})
+
+	failed := recordSuiteFailures(outcomesA, outcomesB) > 0
+	breaking := recordDifferences(checkIDBreakingChange, diffs, func(d behaviorDifference) bool { return d.Impact == impactBreaking }) > 0
+
+	if len(diffs) == 0 {
+		fmt.Println("\nNo differences: the current release behaves the same as the baseline")
+	} else {
+		fmt.Printf("\nDifferences (%d: %s):\n", len(diffs), impactSummary(diffs))
+		printDifferences(diffs)
+	}
+	printVersionBump(bump)
+
+	switch {
+	case bump.Suggested == bumpMajor && breaking:
+		return fmt.Errorf("the current release has breaking changes (%s)", impactSummary(diffs))
+	case failed:
+		return fmt.Errorf("checks failed")
+	}
+	return nil
+}
+
+// suggestVersionBump suggests a semantic version bump for a release with the
+// given differences from the previous one: major for breaking changes, minor
+// for new functionality and patch for other compatible changes
+func suggestVersionBump(diffs []behaviorDifference) *versionBump {
+	breaking, features := 0, 0
+	for _, d := range diffs {
+		switch {
+		case d.Impact == impactBreaking:
+			breaking++
+		case d.feature:
+			features++
+		}
+	}
+	switch {
+	case breaking > 0:
+		return &versionBump{Suggested: bumpMajor, Reason: fmt.Sprintf("%d breaking change(s)", breaking)}
+	case features > 0:
+		return &versionBump{Suggested: bumpMinor, Reason: fmt.Sprintf("%d backward-compatible addition(s)", features)}
+	case len(diffs) > 0:
+		return &versionBump{Suggested: bumpPatch, Reason: fmt.Sprintf("%d compatible change(s) without new functionality", len(diffs))}
+	default:
+		return &versionBump{Suggested: bumpNone, Reason: "no change to the server's surface (patch for fixes only)"}
+	}
+}
+
+// checkVersionBump sets the version the current release should have at
+// least, and whether its actual version is consistent with the suggestion.
+// Versions that are not semantic versions are not checked.
+func checkVersionBump(bump *versionBump) {
+	base, ok := parseSemver(bump.BaselineVersion)
+	if !ok {
+		return
+	}
+	expected := base
+	switch bump.Suggested {
+	case bumpMajor:
+		// Before 1.0.0 breaking changes conventionally bump the minor version
+		if base[0] == 0 {
+			expected = [3]int{0, base[1] + 1, 0}
+		} else {
+			expected = [3]int{base[0] + 1, 0, 0}
+		}
+	case bumpMinor:
+		expected = [3]int{base[0], base[1] + 1, 0}
+	case bumpPatch:
+		expected = [3]int{base[0], base[1], base[2] + 1}
+	}
+	bump.Expected = fmt.Sprintf("%d.%d.%d", expected[0], expected[1], expected[2])
+
+	if current, ok := parseSemver(bump.CurrentVersion); ok {
+		consistent := compareSemver(current, expected) >= 0
+		bump.Consistent = &consistent
+	}
+}
+
+// printVersionBump prints the suggested version bump
+func printVersionBump(bump *versionBump) {
+	fmt.Printf("\nSuggested version bump: %s (%s)\n", bump.Suggested, bump.Reason)
+	if bump.BaselineVersion == "" {
+		return
+	}
+	fmt.Printf("Server version: %s → %s", bump.BaselineVersion, valueOr(bump.CurrentVersion, "(none)"))
+	switch {
+	case bump.Expected == "":
+		fmt.Println(" (not semantic versions; not checked)")
+	case bump.Consistent == nil:
+		fmt.Printf(" (expected at least %s)\n", bump.Expected)
+	case *bump.Consistent:
+		fmt.Printf(" (consistent: at least %s expected)\n", bump.Expected)
+	default:
+		fmt.Printf(" (INCONSISTENT: expected at least %s)\n", bump.Expected)
+	}
+}
+
+// parseSemver parses "1.2.3" or "v1.2.3", ignoring pre-release and build
+// metadata; missing minor and patch numbers count as 0
+func parseSemver(version string) ([3]int, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	if version == "" || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// compareSemver returns -1, 0 or 1 as a is lower than, equal to or higher than b
+func compareSemver(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// serverVersion returns the version in the server info of an initialize outcome
+func serverVersion(outcomes []checkOutcome) string {
+	for _, o := range outcomes {
+		if o.ID == checkInitialize && o.Err == nil {
+			var info struct {
+				Version string `json:"version"`
+			}
+			_ = json.Unmarshal([]byte(o.Items["serverInfo"]), &info)
+			return info.Version
+		}
+	}
+	return ""
+}
+
+// withoutServerVersion returns a copy of the outcomes with the version removed
+// from the server info of the initialize result
+func withoutServerVersion(outcomes []checkOutcome) []checkOutcome {
+	result := make([]checkOutcome, len(outcomes))
+	copy(result, outcomes)
+	for i, o := range result {
+		if o.ID != checkInitialize || o.Items == nil {
+			continue
+		}
+		var info map[string]any
+		if json.Unmarshal([]byte(o.Items["serverInfo"]), &info) != nil {
+			continue
+		}
+		delete(info, "version")
+		items := make(map[string]string, len(o.Items))
+		for key, value := range o.Items {
+			items[key] = value
+		}
+		items["serverInfo"] = itemJSON(info)
+		result[i].Items = items
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version string
+		want    [3]int
+		ok      bool
+	}{
+		{"1.2.3", [3]int{1, 2, 3}, true},
+		{"v2.0.1", [3]int{2, 0, 1}, true},
+		{" 1.4 ", [3]int{1, 4, 0}, true},
+		{"3", [3]int{3, 0, 0}, true},
+		{"1.2.3-beta.1+build.5", [3]int{1, 2, 3}, true},
+		{"", [3]int{}, false},
+		{"1.2.3.4", [3]int{}, false},
+		{"1.x", [3]int{}, false},
+		{"1.-2.0", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSemver(tt.version)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseSemver(%q) = %v, %v, want %v, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSuggestVersionBump(t *testing.T) {
+	breaking := behaviorDifference{Impact: impactBreaking}
+	feature := behaviorDifference{Impact: impactCompatible, feature: true}
+	compatible := behaviorDifference{Impact: impactCompatible}
+	tests := []struct {
+		name  string
+		diffs []behaviorDifference
+		want  string
+	}{
+		{"breaking wins", []behaviorDifference{compatible, feature, breaking}, bumpMajor},
+		{"additions", []behaviorDifference{compatible, feature}, bumpMinor},
+		{"compatible changes", []behaviorDifference{compatible}, bumpPatch},
+		{"no changes", nil, bumpNone},
+	}
+	for _, tt := range tests {
+		if got := suggestVersionBump(tt.diffs); got.Suggested != tt.want {
+			t.Errorf("%s: suggested %s (%s), want %s", tt.name, got.Suggested, got.Reason, tt.want)
+		}
+	}
+}
+
+func TestCheckVersionBump(t *testing.T) {
+	tests := []struct {
+		suggested, baseline, current string
+		expected                     string
+		consistent                   *bool
+	}{
+		{bumpMajor, "1.4.2", "2.0.0", "2.0.0", ptr(true)},
+		{bumpMajor, "0.4.2", "0.5.0", "0.5.0", ptr(true)},
+		{bumpMinor, "1.4.2", "1.4.3", "1.5.0", ptr(false)},
+		{bumpPatch, "v1.4.2", "v1.4.3", "1.4.3", ptr(true)},
+		{bumpNone, "1.4.2", "1.4.2", "1.4.2", ptr(true)},
+		{bumpMinor, "1.4.2", "nightly", "1.5.0", nil},
+		{bumpMinor, "nightly", "1.5.0", "", nil},
+	}
+	for _, tt := range tests {
+		bump := &versionBump{Suggested: tt.suggested, BaselineVersion: tt.baseline, CurrentVersion: tt.current}
+		checkVersionBump(bump)
+		if bump.Expected != tt.expected {
+			t.Errorf("%s bump from %s: expected %q, want %q", tt.suggested, tt.baseline, bump.Expected, tt.expected)
+		}
+		if (bump.Consistent == nil) != (tt.consistent == nil) || (bump.Consistent != nil && *bump.Consistent != *tt.consistent) {
+			t.Errorf("%s bump from %s to %s: consistent = %v, want %v", tt.suggested, tt.baseline, tt.current, bump.Consistent, tt.consistent)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
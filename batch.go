@@ -0,0 +1,403 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// batchQuietWait is how long a stdio server is given to answer a batch of
+// notifications, which should get no answer at all
+const batchQuietWait = time.Second
+
+// batchEntry is a message of a batch
+type batchEntry struct {
+	method string
+	// id is the request's ID as JSON; empty for a notification
+	id string
+}
+
+// batchReply is the server's answer to a batch
+type batchReply struct {
+	// status is the HTTP status; zero over stdio
+	status int
+	// responses are the JSON-RPC responses, in the order they arrived
+	responses []json.RawMessage
+	// arrays is the number of arrays the responses came in; a server may
+	// also answer each request of a batch on its own
+	arrays int
+	// invalid is an answer that is not JSON-RPC
+	invalid []byte
+	// timedOut is set when responses were still missing at the deadline
+	timedOut bool
+}
+
+// add records a message the server sent: a response, an array of
+// responses, or something else. Requests and notifications from the server
+// are skipped.
+func (r *batchReply) add(message []byte) {
+	message = bytes.TrimSpace(message)
+	if len(message) == 0 {
+		return
+	}
+	var items []json.RawMessage
+	if message[0] == '[' && json.Unmarshal(message, &items) == nil {
+		r.arrays++
+		for _, item := range items {
+			if isJSONRPCResponse(item) {
+				r.responses = append(r.responses, item)
+			}
+		}
+		return
+	}
+	if !json.Valid(message) {
+		r.invalid = message
+		return
+	}
+	if isJSONRPCResponse(message) {
+		r.responses = append(r.responses, json.RawMessage(message))
+	}
+}
+
+// parseRawBatch checks -raw-batch: a JSON array of requests and
+// notifications. Each needs a method; jsonrpc is filled in, and requests
+// without an ID are given one, following the ID of -raw-method. Methods
+// under notifications/ without an ID are sent as notifications.
+func parseRawBatch(spec string) ([]byte, []batchEntry, error) {
+	var items []map[string]any
+	if err := json.Unmarshal([]byte(spec), &items); err != nil {
+		return nil, nil, fmt.Errorf("-raw-batch must be a JSON array of request objects: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("-raw-batch is empty; JSON-RPC does not allow an empty batch")
+	}
+	entries := make([]batchEntry, len(items))
+	for i, item := range items {
+		method, ok := item["method"].(string)
+		if !ok || method == "" {
+			return nil, nil, fmt.Errorf("-raw-batch item %d has no method", i+1)
+		}
+		if _, ok := item["jsonrpc"]; !ok {
+			item["jsonrpc"] = mcp.JSONRPC_VERSION
+		}
+		if _, ok := item["id"]; !ok && !strings.HasPrefix(method, "notifications/") {
+			item["id"] = rawRequestID + i + 1
+		}
+		entries[i].method = method
+		if id, ok := item["id"]; ok {
+			data, _ := json.Marshal(id)
+			entries[i].id = string(data)
+		}
+	}
+	message, err := json.Marshal(items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode the batch: %w", err)
+	}
+	return message, entries, nil
+}
+
+// batchResponse is a response of a batch
+type batchResponse struct {
+	ID     json.RawMessage          `json:"id"`
+	Result json.RawMessage          `json:"result"`
+	Error  *mcp.JSONRPCErrorDetails `json:"error"`
+}
+
+// matchBatchReply pairs the responses with the requests of the batch. It
+// returns the response to each entry (nil for notifications and missing
+// responses) and the problems: missing, duplicate and unexpected responses,
+// and answers that are not JSON-RPC.
+func matchBatchReply(entries []batchEntry, reply *batchReply) ([]*batchResponse, []string) {
+	var problems []string
+	if reply.status >= 400 {
+		problems = append(problems, fmt.Sprintf("HTTP %d", reply.status))
+	}
+	if reply.invalid != nil {
+		problems = append(problems, fmt.Sprintf("the answer is not JSON-RPC: %s", truncateText(string(reply.invalid), 80)))
+	}
+
+	byID := map[string]*batchResponse{}
+	for _, raw := range reply.responses {
+		var response batchResponse
+		if err := json.Unmarshal(raw, &response); err != nil {
+			problems = append(problems, fmt.Sprintf("a response is not a JSON-RPC response: %s", truncateText(string(raw), 80)))
+			continue
+		}
+		id := string(bytes.TrimSpace(response.ID))
+		if byID[id] != nil {
+			problems = append(problems, fmt.Sprintf("more than one response with id %s", id))
+			continue
+		}
+		byID[id] = &response
+	}
+
+	matched := make([]*batchResponse, len(entries))
+	expected := map[string]bool{}
+	for i, e := range entries {
+		if e.id == "" {
+			continue
+		}
+		expected[e.id] = true
+		if matched[i] = byID[e.id]; matched[i] == nil {
+			problems = append(problems, fmt.Sprintf("no response to %s (id %s)", e.method, e.id))
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(byID)) {
+		response := byID[id]
+		if expected[id] {
+			continue
+		}
+		if response.Error != nil && (id == "null" || id == "") {
+			problems = append(problems, fmt.Sprintf("the batch was rejected: error %d: %s", response.Error.Code, response.Error.Message))
+		} else {
+			problems = append(problems, fmt.Sprintf("a response with id %s, which no request of the batch has", id))
+		}
+	}
+	return matched, problems
+}
+
+// describeBatchShape says how the server answered a batch
+func describeBatchShape(reply *batchReply) string {
+	switch {
+	case len(reply.responses) == 0:
+		return "no responses"
+	case reply.arrays == 1:
+		return fmt.Sprintf("%d response%s in one batch", len(reply.responses), pluralS(len(reply.responses)))
+	case reply.arrays == 0:
+		return fmt.Sprintf("%d response%s, each on its own", len(reply.responses), pluralS(len(reply.responses)))
+	}
+	return fmt.Sprintf("%d response%s in %d batches", len(reply.responses), pluralS(len(reply.responses)), reply.arrays)
+}
+
+// runRawBatch sends a batch of requests and notifications over a session of
+// its own, once the server has negotiated a protocol version with batching,
+// and prints the response to each. The responses go to the result output as
+// one array, so that they are the only output with -q. An error is returned
+// if a request got no response or an error.
+func runRawBatch(open func() (negativeWire, error), message []byte, entries []batchEntry, timeout time.Duration) error {
+	fmt.Println("=== Raw Batch ===")
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, message, "", "  ") == nil {
+		fmt.Println(pretty.String())
+	}
+
+	wire, err := open()
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer wire.close()
+	_, version, err := initializeNegativeWire(wire, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	if feature := findProtocolFeature(featureBatching); !feature.available(version) {
+		return fmt.Errorf("the server negotiated %s; %s, so the batch was not sent", version, feature.requirement())
+	}
+
+	want := 0
+	for _, e := range entries {
+		if e.id != "" {
+			want++
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	start := time.Now()
+	reply, err := wire.exchangeBatch(ctx, message, want)
+	duration := time.Since(start)
+	cancel()
+	report.addTiming("batch", duration, err)
+	if err != nil {
+		return fmt.Errorf("failed to send the batch: %w", err)
+	}
+
+	matched, problems := matchBatchReply(entries, reply)
+	fmt.Printf("\n=== Batch Response (%s, %s) ===\n", humanDuration(duration), describeBatchShape(reply))
+	failed := 0
+	for i, e := range entries {
+		var outcome string
+		switch response := matched[i]; {
+		case e.id == "":
+			outcome = "notification, no response expected"
+		case response == nil:
+			outcome = "no response"
+			if reply.timedOut {
+				outcome += fmt.Sprintf(" within %s", humanDuration(timeout))
+			}
+		case response.Error != nil:
+			failed++
+			outcome = fmt.Sprintf("error %d: %s", response.Error.Code, response.Error.Message)
+		default:
+			outcome = "result " + truncateText(string(response.Result), 80)
+		}
+		id := e.id
+		if id == "" {
+			id = "-"
+		}
+		fmt.Printf("  %2d  %-28s  id %-10s  %s\n", i+1, e.method, id, outcome)
+	}
+	if len(reply.responses) > 0 {
+		data, err := json.MarshalIndent(reply.responses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode the responses: %w", err)
+		}
+		fmt.Println()
+		fmt.Fprintln(resultOut, string(data))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the batch was not answered properly: %s", strings.Join(problems, "; "))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests of the batch failed", failed, want)
+	}
+	return nil
+}
+
+// checkBatching sends a batch of two pings and a notification over a
+// session of its own and checks that the server answers both pings and not
+// the notification. Servers of the protocol version with batching must
+// accept batches.
+func checkBatching(s *conformanceSession, opts conformanceOptions) (string, string) {
+	if feature := findProtocolFeature(featureBatching); !feature.available(s.version) {
+		return checkSkip, fmt.Sprintf("the server negotiated %s; %s", valueOr(s.version, "no version"), feature.requirement())
+	}
+	if opts.openWire == nil {
+		return checkSkip, "batches are only sent over stdio and streamable HTTP"
+	}
+	wire, err := opts.openWire()
+	if err != nil {
+		return checkFail, fmt.Sprintf("failed to connect: %v", err)
+	}
+	defer wire.close()
+	if _, _, err := initializeNegativeWire(wire, s.timeout); err != nil {
+		return checkFail, fmt.Sprintf("failed to initialize: %v", err)
+	}
+
+	first, second := s.nextID(), s.nextID()
+	message, entries, err := parseRawBatch(fmt.Sprintf(`[
+		{"id": %d, "method": "ping"},
+		{"method": "notifications/cancelled", "params": {"requestId": %d, "reason": "conformance check"}},
+		{"id": %d, "method": "ping"}
+	]`, first.Value(), conformanceRequestBase, second.Value()))
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	reply, err := wire.exchangeBatch(ctx, message, 2)
+	if err != nil {
+		return checkFail, fmt.Sprintf("failed to send the batch: %v", err)
+	}
+	matched, problems := matchBatchReply(entries, reply)
+	for _, response := range matched {
+		if response != nil && response.Error != nil {
+			problems = append(problems, fmt.Sprintf("ping failed: error %d: %s", response.Error.Code, response.Error.Message))
+		}
+	}
+	if len(problems) > 0 {
+		return checkFail, strings.Join(problems, "; ")
+	}
+	return checkPass, fmt.Sprintf("both pings answered, %s; the notification was not", describeBatchShape(reply))
+}
+
+func (w *httpNegativeWire) exchangeBatch(ctx context.Context, message []byte, want int) (*batchReply, error) {
+	resp, err := w.post(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	reply := &batchReply{status: resp.StatusCode}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, negativeReplyLimit))
+		if err != nil {
+			return nil, err
+		}
+		reply.add(body)
+		return reply, nil
+	}
+
+	// The responses may come as one event or one event each
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), negativeReplyLimit)
+	var lines []string
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		reply.add(eventData(lines))
+		lines = nil
+		if len(reply.responses) >= want {
+			return reply, nil
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	reply.add(eventData(lines))
+	reply.timedOut = ctx.Err() != nil
+	return reply, nil
+}
+
+func (w *stdioNegativeWire) exchangeBatch(ctx context.Context, message []byte, want int) (*batchReply, error) {
+	// Discard late answers to earlier messages
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-w.lines:
+			if !ok {
+				return nil, w.exitError()
+			}
+		default:
+			drained = true
+		}
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := w.stdin.Write(append(message, '\n'))
+		written <- err
+	}()
+	// A batch of notifications has no answer, so the server is given a
+	// moment to show that it sends none
+	var quiet <-chan time.Time
+	if want == 0 {
+		quiet = time.After(batchQuietWait)
+	}
+	reply := &batchReply{}
+	for {
+		select {
+		case err := <-written:
+			if err != nil {
+				return nil, fmt.Errorf("failed to write to the server: %w", err)
+			}
+			written = nil
+		case line, ok := <-w.lines:
+			if !ok {
+				return nil, w.exitError()
+			}
+			reply.add(line)
+			if want > 0 && len(reply.responses) >= want {
+				return reply, nil
+			}
+		case <-quiet:
+			return reply, nil
+		case <-ctx.Done():
+			reply.timedOut = true
+			return reply, nil
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// benchDefaultRequests is the number of calls of a benchmark given neither
+// -requests nor -duration
+const benchDefaultRequests = 100
+
+// benchInterval is how often a running benchmark prints its progress
+const benchInterval = 5 * time.Second
+
+// benchTopErrors is the number of distinct errors listed in the results
+const benchTopErrors = 5
+
+// Outcomes of a benchmark call. A tool error is an answer from the server,
+// so it counts towards the latency, and towards the error rate, but does not
+// fail the benchmark.
+const (
+	benchOK        = "ok"
+	benchToolError = "tool error"
+	benchFailed    = "failed"
+	benchTimeout   = "timeout"
+)
+
+// benchCommandArgs turns "bench [flags]" into the equivalent -bench flag,
+// so that the benchmark runs with the probe's usual connection options
+func benchCommandArgs(args []string) []string {
+	return append([]string{args[0], "-bench"}, args[2:]...)
+}
+
+// benchOptions configures a benchmark
+type benchOptions struct {
+	tool        string
+	args        map[string]any
+	concurrency int
+	sessions    int
+	requests    int
+	duration    time.Duration
+}
+
+// benchCall is the result of one call of a benchmark
+type benchCall struct {
+	outcome  string
+	err      string
+	duration time.Duration
+}
+
+// latencyStats summarizes a set of durations
+type latencyStats struct {
+	Min  time.Duration `json:"minNs"`
+	Mean time.Duration `json:"meanNs"`
+	P50  time.Duration `json:"p50Ns"`
+	P90  time.Duration `json:"p90Ns"`
+	P95  time.Duration `json:"p95Ns"`
+	P99  time.Duration `json:"p99Ns"`
+	Max  time.Duration `json:"maxNs"`
+}
+
+// newLatencyStats computes the statistics of the durations, sorting them
+func newLatencyStats(durations []time.Duration) *latencyStats {
+	if len(durations) == 0 {
+		return nil
+	}
+	slices.Sort(durations)
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return &latencyStats{
+		Min:  durations[0],
+		Mean: total / time.Duration(len(durations)),
+		P50:  latencyPercentile(durations, 50),
+		P90:  latencyPercentile(durations, 90),
+		P95:  latencyPercentile(durations, 95),
+		P99:  latencyPercentile(durations, 99),
+		Max:  durations[len(durations)-1],
+	}
+}
+
+// latencyPercentile returns the p-th percentile of sorted durations by the
+// nearest rank method
+func latencyPercentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// benchError is an error seen during a benchmark and how often
+type benchError struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
+
+// benchReport is the result of a benchmark
+type benchReport struct {
+	Tool        string        `json:"tool"`
+	Concurrency int           `json:"concurrency"`
+	Sessions    int           `json:"sessions"`
+	Calls       int           `json:"calls"`
+	Succeeded   int           `json:"succeeded"`
+	ToolErrors  int           `json:"toolErrors"`
+	Failed      int           `json:"failed"`
+	Timeouts    int           `json:"timeouts"`
+	ErrorRate   float64       `json:"errorRate"`
+	Duration    time.Duration `json:"durationNs"`
+	Throughput  float64       `json:"callsPerSecond"`
+	Latency     *latencyStats `json:"latency,omitempty"`
+	Errors      []benchError  `json:"errors,omitempty"`
+}
+
+// runBenchmark drives concurrent calls of a tool over one or more sessions
+// for a number of calls or a duration, and reports the throughput, error
+// rate and latency percentiles. Workers are spread over the sessions in
+// turn. An error is returned if calls failed.
+func runBenchmark(dial func(ctx context.Context) (*client.Client, error), opts benchOptions, timeout, callTimeout time.Duration) error {
+	fmt.Printf("=== Benchmark: %s ===\n", opts.tool)
+
+	sessions := make([]*client.Client, 0, opts.sessions)
+	defer func() {
+		for _, c := range sessions {
+			_ = c.Close()
+		}
+	}()
+	for i := 0; i < opts.sessions; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		mcpClient, err := dial(ctx)
+		if err == nil {
+			if _, err = mcpClient.Initialize(ctx, newInitializeRequest()); err != nil {
+				_ = mcpClient.Close()
+			}
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to open session %d of %d: %w", i+1, opts.sessions, err)
+		}
+		sessions = append(sessions, mcpClient)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	_, tool, err := resolveToolName(ctx, sessions[0], opts.tool, false)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if tool != nil && isDestructive(tool) {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("'%s' is flagged as destructive; benchmarking it needs confirmation on a terminal", tool.Name)
+		}
+		if !confirmDestructiveCall(tool, stdinScanner()) {
+			return nil
+		}
+	}
+
+	limit := fmt.Sprintf("%s calls", humanCount(opts.requests))
+	switch {
+	case opts.duration > 0 && opts.requests > 0:
+		limit = fmt.Sprintf("%s calls or %s, whichever comes first", humanCount(opts.requests), humanDuration(opts.duration))
+	case opts.duration > 0:
+		limit = opts.duration.String()
+	}
+	fmt.Printf("Workers: %d over %d session%s | Limit: %s\n", opts.concurrency, opts.sessions, pluralS(opts.sessions), limit)
+
+	var (
+		mu      sync.Mutex
+		calls   []benchCall
+		claimed atomic.Int64
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	deadline := time.Time{}
+	if opts.duration > 0 {
+		deadline = start.Add(opts.duration)
+	}
+	// next claims the next call, or returns false when the benchmark is over
+	next := func() bool {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return false
+		}
+		return opts.requests == 0 || claimed.Add(1) <= int64(opts.requests)
+	}
+
+	for w := 0; w < opts.concurrency; w++ {
+		mcpClient := sessions[w%len(sessions)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next() {
+				call := benchToolCall(mcpClient, opts.tool, opts.args, callTimeout)
+				mu.Lock()
+				calls = append(calls, call)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Print the progress until the workers are done
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(benchInterval)
+	lastCalls, lastTime := 0, start
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case now := <-ticker.C:
+			mu.Lock()
+			window := calls[lastCalls:]
+			failed := 0
+			var durations []time.Duration
+			for _, c := range window {
+				if c.outcome != benchOK && c.outcome != benchToolError {
+					failed++
+				} else {
+					durations = append(durations, c.duration)
+				}
+			}
+			total := len(calls)
+			mu.Unlock()
+			line := fmt.Sprintf("  %6s  %7s calls  %8.1f/s  %s failed", humanDuration(now.Sub(start).Round(time.Second)), humanCount(total), float64(len(window))/now.Sub(lastTime).Seconds(), humanCount(failed))
+			if stats := newLatencyStats(durations); stats != nil {
+				line += fmt.Sprintf("  p50 %s  p99 %s", humanDuration(stats.P50), humanDuration(stats.P99))
+			}
+			if lastCalls == 0 {
+				fmt.Println()
+			}
+			fmt.Println(line)
+			lastCalls, lastTime = total, now
+		}
+	}
+	ticker.Stop()
+	elapsed := time.Since(start)
+
+	result := summarizeBenchmark(opts, calls, elapsed)
+	report.setBench(result)
+	printBenchmark(result)
+	status := checkPass
+	if result.Failed+result.Timeouts > 0 {
+		status = checkFail
+	}
+	emitEvent(eventCheck, map[string]any{
+		"id":             "bench." + opts.tool,
+		"status":         status,
+		"calls":          result.Calls,
+		"errorRate":      result.ErrorRate,
+		"callsPerSecond": result.Throughput,
+		"durationMs":     durationMillis(elapsed),
+	})
+
+	if n := result.Failed + result.Timeouts; n > 0 {
+		return fmt.Errorf("%d/%d calls failed", n, result.Calls)
+	}
+	return nil
+}
+
+// benchToolCall makes one call of a benchmark
+func benchToolCall(mcpClient *client.Client, name string, args map[string]any, callTimeout time.Duration) benchCall {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	start := time.Now()
+	result, err := mcpClient.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+	duration := time.Since(start)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return benchCall{outcome: benchTimeout, err: fmt.Sprintf("no answer within %s", humanDuration(callTimeout)), duration: duration}
+	case err != nil:
+		return benchCall{outcome: benchFailed, err: err.Error(), duration: duration}
+	case result.IsError:
+		return benchCall{outcome: benchToolError, err: toolErrorText(result), duration: duration}
+	}
+	return benchCall{outcome: benchOK, duration: duration}
+}
+
+// summarizeBenchmark computes the results of a benchmark. The latency is
+// that of the calls the server answered, including tool errors.
+func summarizeBenchmark(opts benchOptions, calls []benchCall, elapsed time.Duration) *benchReport {
+	result := &benchReport{
+		Tool:        opts.tool,
+		Concurrency: opts.concurrency,
+		Sessions:    opts.sessions,
+		Calls:       len(calls),
+		Duration:    elapsed,
+	}
+	var durations []time.Duration
+	errorCounts := map[string]int{}
+	for _, c := range calls {
+		switch c.outcome {
+		case benchOK:
+			result.Succeeded++
+		case benchToolError:
+			result.ToolErrors++
+		case benchFailed:
+			result.Failed++
+		case benchTimeout:
+			result.Timeouts++
+		}
+		if c.err != "" {
+			errorCounts[c.err]++
+		}
+		if c.outcome == benchOK || c.outcome == benchToolError {
+			durations = append(durations, c.duration)
+		}
+	}
+	if result.Calls > 0 {
+		result.ErrorRate = float64(result.ToolErrors+result.Failed+result.Timeouts) / float64(result.Calls)
+	}
+	if elapsed > 0 {
+		result.Throughput = float64(result.Calls) / elapsed.Seconds()
+	}
+	result.Latency = newLatencyStats(durations)
+	for e, n := range errorCounts {
+		result.Errors = append(result.Errors, benchError{Error: e, Count: n})
+	}
+	slices.SortFunc(result.Errors, func(a, b benchError) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Error, b.Error))
+	})
+	return result
+}
+
+// printBenchmark prints the results of a benchmark
+func printBenchmark(result *benchReport) {
+	fmt.Println("\n=== Benchmark Results ===")
+	fmt.Printf("Calls:        %s (%s succeeded, %s tool errors, %s failed, %s timed out)\n", humanCount(result.Calls), humanCount(result.Succeeded), humanCount(result.ToolErrors), humanCount(result.Failed), humanCount(result.Timeouts))
+	fmt.Printf("Error rate:   %.2f%%\n", result.ErrorRate*100)
+	fmt.Printf("Duration:     %s\n", humanDuration(result.Duration))
+	fmt.Printf("Throughput:   %.2f calls/sec\n", result.Throughput)
+	if l := result.Latency; l != nil {
+		fmt.Println("Latency (answered calls):")
+		fmt.Printf("  Min:  %s\n", humanDuration(l.Min))
+		fmt.Printf("  Mean: %s\n", humanDuration(l.Mean))
+		fmt.Printf("  P50:  %s\n", humanDuration(l.P50))
+		fmt.Printf("  P90:  %s\n", humanDuration(l.P90))
+		fmt.Printf("  P95:  %s\n", humanDuration(l.P95))
+		fmt.Printf("  P99:  %s\n", humanDuration(l.P99))
+		fmt.Printf("  Max:  %s\n", humanDuration(l.Max))
+	}
+	if len(result.Errors) > 0 {
+		fmt.Println("Errors:")
+		for i, e := range result.Errors {
+			if i == benchTopErrors {
+				fmt.Printf("  ... and %d other error%s\n", len(result.Errors)-i, pluralS(len(result.Errors)-i))
+				break
+			}
+			fmt.Printf("  %6s × %s\n", humanCount(e.Count), truncateText(e.Error, 100))
+		}
+	}
+}
+
+// toolErrorText describes the error a tool reported in its result
+func toolErrorText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok && text.Text != "" {
+			return "tool error: " + text.Text
+		}
+	}
+	return "tool error"
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 20; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{
+		{0, 1 * time.Millisecond},
+		{5, 1 * time.Millisecond},
+		{50, 10 * time.Millisecond},
+		{90, 18 * time.Millisecond},
+		{95, 19 * time.Millisecond},
+		{99, 20 * time.Millisecond},
+		{100, 20 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := latencyPercentile(sorted, tt.p); got != tt.want {
+			t.Errorf("p%d = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+	if got := latencyPercentile([]time.Duration{7 * time.Millisecond}, 99); got != 7*time.Millisecond {
+		t.Errorf("p99 of one sample = %s, want 7ms", got)
+	}
+}
+
+func TestNewLatencyStats(t *testing.T) {
+	if newLatencyStats(nil) != nil {
+		t.Errorf("newLatencyStats(nil) is not nil")
+	}
+	stats := newLatencyStats([]time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond})
+	if stats.Min != 10*time.Millisecond || stats.Max != 30*time.Millisecond || stats.Mean != 20*time.Millisecond || stats.P50 != 20*time.Millisecond {
+		t.Errorf("newLatencyStats = %+v", stats)
+	}
+}
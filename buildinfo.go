@@ -0,0 +1,141 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// mcpGoModule is the module path of the MCP library, whose version is part
+// of the build information
+const mcpGoModule = "github.com/mark3labs/mcp-go"
+
+// buildCommit is the commit the binary was built from, for builds without
+// Go's VCS stamping. Release builds set it with
+// -ldflags "-X main.buildCommit=<sha>".
+var buildCommit string
+
+// buildInfo identifies the probe build, so that results can be traced to it
+type buildInfo struct {
+	Version          string   `json:"version"`
+	Commit           string   `json:"commit,omitempty"`
+	CommitTime       string   `json:"commitTime,omitempty"`
+	Modified         bool     `json:"modified,omitempty"`
+	MCPGoVersion     string   `json:"mcpGoVersion,omitempty"`
+	ProtocolVersion  string   `json:"protocolVersion"`
+	ProtocolVersions []string `json:"protocolVersions"`
+	GoVersion        string   `json:"goVersion"`
+	OS               string   `json:"os"`
+	Arch             string   `json:"arch"`
+}
+
+// currentBuildInfo collects the build information from the binary
+func currentBuildInfo() *buildInfo {
+	info := &buildInfo{
+		Version:          ProgVer,
+		Commit:           buildCommit,
+		ProtocolVersion:  newInitializeRequest().Params.ProtocolVersion,
+		ProtocolVersions: append([]string{}, mcp.ValidProtocolVersions...),
+		GoVersion:        runtime.Version(),
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == mcpGoModule {
+			info.MCPGoVersion = dep.Version
+			if dep.Replace != nil {
+				info.MCPGoVersion += " => " + dep.Replace.Path + " " + dep.Replace.Version
+			}
+		}
+	}
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			info.CommitTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// shortCommit abbreviates the commit for display
+func (b *buildInfo) shortCommit() string {
+	commit := b.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit != "" && b.Modified {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// Summary is a one-line description of the build
+func (b *buildInfo) Summary() string {
+	parts := []string{fmt.Sprintf("%s %s", ProgName, b.Version)}
+	if commit := b.shortCommit(); commit != "" {
+		parts = append(parts, "commit "+commit)
+	}
+	if b.MCPGoVersion != "" {
+		parts = append(parts, "mcp-go "+b.MCPGoVersion)
+	}
+	parts = append(parts, b.GoVersion, b.OS+"/"+b.Arch)
+	return strings.Join(parts, ", ")
+}
+
+// runVersionCommand implements the 'version' subcommand
+func runVersionCommand(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	verbose := fs.Bool("verbose", false, "Show the commit, library and Go versions, supported protocol versions and platform")
+	format := fs.String("output", outputText, "Output format: 'text' or 'json'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: version [-verbose] [-output text|json]")
+	}
+	if *format != outputText && *format != outputJSON {
+		return fmt.Errorf("unsupported output format '%s' (use 'text' or 'json')", *format)
+	}
+
+	info := currentBuildInfo()
+	if *format == outputJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode build information: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("%s %s\n", ProgName, info.Version)
+	if !*verbose {
+		return nil
+	}
+	fmt.Printf("  Commit:            %s\n", valueOr(info.shortCommit(), "unknown"))
+	if info.CommitTime != "" {
+		fmt.Printf("  Commit time:       %s\n", info.CommitTime)
+	}
+	fmt.Printf("  mcp-go:            %s\n", valueOr(info.MCPGoVersion, "unknown"))
+	fmt.Printf("  Protocol version:  %s (requested at initialization)\n", info.ProtocolVersion)
+	fmt.Printf("  Protocol versions: %s\n", strings.Join(info.ProtocolVersions, ", "))
+	fmt.Printf("  Go:                %s\n", info.GoVersion)
+	fmt.Printf("  Platform:          %s/%s\n", info.OS, info.Arch)
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// callRequestIDBase is the first ID of tool calls sent by the probe itself,
+// clear of the IDs the client assigns, so that a call can be cancelled by ID
+const callRequestIDBase = 3_000_000
+
+// cancelGrace is how long the server has to wind down an interrupted call
+const cancelGrace = 3 * time.Second
+
+// cancelledMethod is the notification that cancels a request
+const cancelledMethod = "notifications/cancelled"
+
+// interruptedExitCode is the exit status after an interrupted -call
+const interruptedExitCode = 130
+
+// errCallInterrupted is returned when a tool call is interrupted with Ctrl-C
+var errCallInterrupted = errors.New("tool call interrupted")
+
+var callRequestIDs atomic.Int64
+
+// callReply is the outcome of a raw tools/call request
+type callReply struct {
+	response *transport.JSONRPCResponse
+	err      error
+}
+
+// callToolInterruptible calls a tool like client.CallTool, but with a
+// request ID of its own. If Ctrl-C is pressed during the call, the server is
+// sent notifications/cancelled for that ID and how it winds the call down is
+// reported; errCallInterrupted is returned.
+func callToolInterruptible(ctx context.Context, mcpClient *client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := mcp.NewRequestId(callRequestIDBase + callRequestIDs.Add(1))
+	rawRequest := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      id,
+		Method:  string(mcp.MethodToolsCall),
+		Params:  request.Params,
+		Header:  request.Header,
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan callReply, 1)
+	go func() {
+		response, err := mcpClient.GetTransport().SendRequest(callCtx, rawRequest)
+		done <- callReply{response, err}
+	}()
+
+	select {
+	case reply := <-done:
+		return parseCallReply(reply)
+	case <-interrupt:
+	}
+
+	fmt.Printf("\nInterrupted: sending %s for request %v\n", cancelledMethod, id.Value())
+	if err := sendCancelled(mcpClient, id, "interrupted by the user"); err != nil {
+		fmt.Printf("  Failed to send the cancellation: %v\n", err)
+		report.addError("%s for tools/call %s: %v", cancelledMethod, request.Params.Name, err)
+	} else {
+		fmt.Println("  Cancellation accepted by the transport")
+	}
+
+	// The specification lets a server drop a cancelled request without
+	// replying, so silence within the grace period is graceful too
+	fmt.Printf("  Waiting up to %s for the server to wind down the call (Ctrl-C again to stop waiting)...\n", humanDuration(cancelGrace))
+	select {
+	case reply := <-done:
+		switch {
+		case reply.err != nil:
+			fmt.Printf("  The request ended with a transport error: %v\n", reply.err)
+		case reply.response.Error != nil:
+			fmt.Printf("  Server acknowledged with error %d: %s\n", reply.response.Error.Code, reply.response.Error.Message)
+		default:
+			f := report.addFinding(checkIDCancelIgnored, request.Params.Name, "tools/call %s: server ignored %s and completed the call", request.Params.Name, cancelledMethod)
+			fmt.Printf("  %s; its result is discarded\n", f)
+		}
+	case <-time.After(cancelGrace):
+		fmt.Printf("  No reply within %s: the server dropped the call without responding, as the specification allows\n", humanDuration(cancelGrace))
+	case <-interrupt:
+		fmt.Println("  Stopped waiting")
+	}
+	cancel()
+
+	// A server that handled the cancellation well is still usable
+	pingCtx, pingCancel := context.WithTimeout(context.Background(), cancelGrace)
+	defer pingCancel()
+	start := time.Now()
+	if err := mcpClient.Ping(pingCtx); err != nil {
+		fmt.Printf("  %s\n", report.addFinding(checkIDCancelUnresponsive, request.Params.Name, "server did not answer a ping after cancelling tools/call %s: %v", request.Params.Name, err))
+	} else {
+		fmt.Printf("  Server still responsive (ping %s)\n", humanDuration(time.Since(start)))
+	}
+	return nil, errCallInterrupted
+}
+
+// parseCallReply converts a raw tools/call reply as client.CallTool would
+func parseCallReply(reply callReply) (*mcp.CallToolResult, error) {
+	if reply.err != nil {
+		return nil, transport.NewError(reply.err)
+	}
+	if reply.response.Error != nil {
+		return nil, reply.response.Error.AsError()
+	}
+	return mcp.ParseCallToolResult(&reply.response.Result)
+}
+
+// sendCancelled sends notifications/cancelled for a request
+func sendCancelled(mcpClient *client.Client, id mcp.RequestId, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelGrace)
+	defer cancel()
+	notification := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: cancelledMethod,
+			Params: mcp.NotificationParams{
+				AdditionalFields: map[string]any{
+					"requestId": id,
+					"reason":    reason,
+				},
+			},
+		},
+	}
+	return mcpClient.GetTransport().SendNotification(ctx, notification)
+}
@@ -0,0 +1,294 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// captureMaxErrorBody is the most of an HTTP error body that is recorded
+const captureMaxErrorBody = 1 << 20
+
+// sessionCapture intercepts the probe's own traffic when -record or -trace
+// is set
+var sessionCapture *trafficCapture
+
+// trafficCapture passes the probe's traffic over one transport to the
+// session recording of -record and the wire trace of -trace; either may be
+// nil
+type trafficCapture struct {
+	recorder  *sessionRecorder
+	tracer    *wireTracer
+	path      string
+	transport string
+	messages  atomic.Int64
+}
+
+// startCapture intercepts the probe's traffic, recording it to path unless
+// it is empty and tracing it if tracer is set
+func startCapture(path, transportName string, tracer *wireTracer) error {
+	capture := &trafficCapture{tracer: tracer, path: path, transport: transportName}
+	if path != "" {
+		recorder, err := newSessionRecorder(path)
+		if err != nil {
+			return err
+		}
+		capture.recorder = recorder
+	}
+	sessionCapture = capture
+	return nil
+}
+
+// stopCapture closes the session recording and tells where it is
+func stopCapture() {
+	if sessionCapture == nil || sessionCapture.recorder == nil {
+		return
+	}
+	if err := sessionCapture.recorder.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to close the recording: %v\n", err)
+		return
+	}
+	n := int(sessionCapture.messages.Load())
+	fmt.Printf("Recorded %s message%s to %s\n", humanCount(n), pluralS(n), sessionCapture.path)
+}
+
+// add records and traces the JSON-RPC messages of a body with the transport
+// details of rec and counts them
+func (c *trafficCapture) add(rec sessionRecord, body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+	rec.Transport = c.transport
+	if c.tracer != nil {
+		c.tracer.traceMessages(rec, body)
+	}
+	if c.recorder == nil {
+		return
+	}
+	c.recorder.recordMessages(rec, body)
+	var batch []json.RawMessage
+	if body[0] == '[' && json.Unmarshal(body, &batch) == nil {
+		c.messages.Add(int64(len(batch)))
+	} else {
+		c.messages.Add(1)
+	}
+}
+
+// addError records and traces an HTTP error or a failed request
+func (c *trafficCapture) addError(rec sessionRecord, message string) {
+	rec.Transport = c.transport
+	rec.Error = message
+	if c.tracer != nil {
+		c.tracer.traceError(rec)
+	}
+	if c.recorder != nil {
+		c.recorder.record(rec)
+	}
+}
+
+// captureTransport records the JSON-RPC messages of the requests sent to
+// the server and of the responses, including the events of SSE streams as
+// they are read. Other requests, such as those of OAuth, are not recorded.
+type captureTransport struct {
+	base    http.RoundTripper
+	capture *trafficCapture
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.GetBody != nil {
+		if reader, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(reader)
+			_ = reader.Close()
+		}
+	}
+	stream := req.Method == http.MethodGet && strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+	if !stream && !bytes.Contains(body, []byte(`"jsonrpc"`)) {
+		return t.base.RoundTrip(req)
+	}
+
+	rec := sessionRecord{Direction: directionClient, HTTPMethod: req.Method, Path: req.URL.Path, Session: req.Header.Get(mcpSessionHeader)}
+	if rec.Session == "" {
+		// SSE servers name the session in the message endpoint's URL
+		rec.Session = req.URL.Query().Get("sessionId")
+	}
+	t.capture.add(rec, body)
+
+	resp, err := t.base.RoundTrip(req)
+	rec.Direction = directionServer
+	if err != nil {
+		t.capture.addError(rec, err.Error())
+		return nil, err
+	}
+	rec.Status = resp.StatusCode
+	if session := resp.Header.Get(mcpSessionHeader); session != "" {
+		rec.Session = session
+	}
+	switch {
+	case strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+		resp.Body = &captureStream{ReadCloser: resp.Body, capture: t.capture, rec: rec}
+	case resp.StatusCode >= 400:
+		// The client does not always read or close error bodies, so they
+		// are recorded as they arrive
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, captureMaxErrorBody))
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if len(bytes.TrimSpace(data)) > 0 {
+			t.capture.add(rec, data)
+		} else {
+			t.capture.addError(rec, resp.Status)
+		}
+	default:
+		resp.Body = &captureBody{ReadCloser: resp.Body, capture: t.capture, rec: rec}
+	}
+	return resp, nil
+}
+
+// captureBody records a JSON response once it has been read
+type captureBody struct {
+	io.ReadCloser
+	capture *trafficCapture
+	rec     sessionRecord
+	body    bytes.Buffer
+	once    sync.Once
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.body.Write(p[:n])
+	if err == io.EOF {
+		b.flush()
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	b.flush()
+	return b.ReadCloser.Close()
+}
+
+// flush records the response
+func (b *captureBody) flush() {
+	b.once.Do(func() {
+		b.capture.add(b.rec, b.body.Bytes())
+	})
+}
+
+// captureStream records the messages of an SSE stream event by event, as
+// the client reads them
+type captureStream struct {
+	io.ReadCloser
+	capture *trafficCapture
+	rec     sessionRecord
+	mu      sync.Mutex
+	pending []byte
+	lines   []string
+}
+
+func (s *captureStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, p[:n]...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(s.pending[:i]), "\r")
+		s.pending = s.pending[i+1:]
+		if line != "" {
+			s.lines = append(s.lines, line)
+			continue
+		}
+		s.event()
+	}
+	return n, err
+}
+
+// event records a complete event. The endpoint event of an SSE server names
+// the session of the messages that follow.
+func (s *captureStream) event() {
+	lines := s.lines
+	s.lines = nil
+	name := "message"
+	for _, line := range lines {
+		if v, ok := strings.CutPrefix(line, "event:"); ok {
+			name = strings.TrimSpace(v)
+		}
+	}
+	switch name {
+	case "endpoint":
+		if endpoint, err := url.Parse(string(eventData(lines))); err == nil && endpoint.Query().Get("sessionId") != "" {
+			s.rec.Session = endpoint.Query().Get("sessionId")
+		}
+	case "message":
+		s.capture.add(s.rec, eventData(lines))
+	}
+}
+
+// captureLines records each line that passes through a stdio stream as a
+// message in the given direction
+type captureLines struct {
+	capture   *trafficCapture
+	direction string
+	mu        sync.Mutex
+	pending   []byte
+}
+
+func (c *captureLines) add(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, p...)
+	for {
+		i := bytes.IndexByte(c.pending, '\n')
+		if i < 0 {
+			return
+		}
+		c.capture.add(sessionRecord{Direction: c.direction}, c.pending[:i])
+		c.pending = c.pending[i+1:]
+	}
+}
+
+// captureReader records the server's messages as the client reads them
+// from the server's stdout
+type captureReader struct {
+	io.Reader
+	lines *captureLines
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.lines.add(p[:n])
+	return n, err
+}
+
+// captureWriteCloser records the client's messages as they are written to
+// the server's stdin
+type captureWriteCloser struct {
+	io.WriteCloser
+	lines *captureLines
+}
+
+func (w *captureWriteCloser) Write(p []byte) (int, error) {
+	w.lines.add(p)
+	return w.WriteCloser.Write(p)
+}
+
+// stdioStreams wraps the streams of a stdio server so that its traffic is
+// recorded
+func (c *trafficCapture) stdioStreams(stdout io.Reader, stdin io.WriteCloser) (io.Reader, io.WriteCloser) {
+	return &captureReader{Reader: stdout, lines: &captureLines{capture: c, direction: directionServer}},
+		&captureWriteCloser{WriteCloser: stdin, lines: &captureLines{capture: c, direction: directionClient}}
+}
@@ -0,0 +1,407 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Faults injected by chaos mode: the session's connections are closed while
+// a call is in flight, or while the session is idle before a call
+const (
+	chaosNone    = "none"
+	chaosMidCall = "mid-call"
+	chaosBetween = "between-calls"
+)
+
+// Outcomes of the call made in each iteration. A call whose connection
+// drops should fail promptly, or complete if the drop came too late to
+// matter; it should not hang until the call timeout.
+const (
+	chaosCompleted = "completed"
+	chaosFailed    = "failed"
+	chaosHung      = "hung"
+)
+
+// How the session recovered after a fault
+const (
+	recoveryContinued     = "continued"
+	recoveryReestablished = "re-established"
+	recoveryLost          = "lost"
+)
+
+// chaosCleanCalls is the number of calls made without faults to time the
+// operation before the run
+const chaosCleanCalls = 3
+
+// chaosConns tracks the probe's HTTP connections in chaos mode, so that
+// they can be dropped. It is nil otherwise.
+var chaosConns *chaosDialer
+
+// chaosDialer hands out connections that it can close all at once, as if
+// the network had dropped them
+type chaosDialer struct {
+	mu    sync.Mutex
+	conns map[*chaosConn]struct{}
+}
+
+// chaosConn is a connection tracked by a chaosDialer
+type chaosConn struct {
+	net.Conn
+	dialer *chaosDialer
+}
+
+func (c *chaosConn) Close() error {
+	c.dialer.mu.Lock()
+	delete(c.dialer.conns, c)
+	c.dialer.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// enableChaos makes the HTTP transports created from now on track their
+// connections so that chaos mode can drop them
+func enableChaos() {
+	chaosConns = &chaosDialer{conns: map[*chaosConn]struct{}{}}
+}
+
+// wrap returns a dial function that tracks the connections dial opens
+func (d *chaosDialer) wrap(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tracked := &chaosConn{Conn: conn, dialer: d}
+		d.mu.Lock()
+		d.conns[tracked] = struct{}{}
+		d.mu.Unlock()
+		return tracked, nil
+	}
+}
+
+// drop closes every open connection and returns how many there were
+func (d *chaosDialer) drop() int {
+	d.mu.Lock()
+	conns := make([]*chaosConn, 0, len(d.conns))
+	for conn := range d.conns {
+		conns = append(conns, conn)
+	}
+	d.mu.Unlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	return len(conns)
+}
+
+// chaosCommandArgs turns "chaos [flags]" into the equivalent -chaos flag, so
+// that the run uses the probe's usual connection options
+func chaosCommandArgs(args []string) []string {
+	return append([]string{args[0], "-chaos"}, args[2:]...)
+}
+
+// chaosOptions configures a chaos run
+type chaosOptions struct {
+	tool       string
+	args       map[string]any
+	iterations int
+	rate       float64
+	seed       uint64
+	// httpSession is set for streamable HTTP, where the session is not
+	// tied to a connection and should survive a dropped one
+	httpSession bool
+}
+
+// chaosEvent is an iteration of a chaos run in which a fault was injected
+type chaosEvent struct {
+	Iteration    int           `json:"iteration"`
+	Fault        string        `json:"fault"`
+	Dropped      int           `json:"droppedConnections"`
+	Call         string        `json:"call"`
+	Detail       string        `json:"detail,omitempty"`
+	Duration     time.Duration `json:"durationNs"`
+	Recovery     string        `json:"recovery"`
+	RecoveryTime time.Duration `json:"recoveryNs,omitempty"`
+	Replay       string        `json:"replay,omitempty"`
+}
+
+// chaosReport is the result of a chaos run
+type chaosReport struct {
+	Operation  string         `json:"operation"`
+	Seed       uint64         `json:"seed"`
+	Iterations int            `json:"iterations"`
+	Rate       float64        `json:"rate"`
+	Calls      map[string]int `json:"calls"`
+	Recoveries map[string]int `json:"recoveries,omitempty"`
+	Events     []chaosEvent   `json:"faults,omitempty"`
+}
+
+// runChaos makes a call in each iteration and, at random, drops the
+// session's connections while the call is in flight or before it starts.
+// After each fault it checks whether the session continues, re-establishes
+// it if not, and repeats a call that failed. It returns an error if a call
+// hung, the session could not be re-established or a repeated call failed.
+func runChaos(dial func(ctx context.Context) (*client.Client, error), opts chaosOptions, timeout, callTimeout time.Duration) error {
+	operation := string(mcp.MethodToolsList)
+	if opts.tool != "" {
+		operation = string(mcp.MethodToolsCall) + " " + opts.tool
+	}
+	fmt.Printf("=== Chaos: %s ===\n", operation)
+
+	// An SSE session's stream lasts as long as the context it was started
+	// with, so sessions are started with one that lasts for the run
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	connect := func() (*client.Client, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		mcpClient, err := dial(runCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		if _, err := mcpClient.Initialize(ctx, newInitializeRequest()); err != nil {
+			_ = mcpClient.Close()
+			return nil, fmt.Errorf("failed to initialize: %w", err)
+		}
+		return mcpClient, nil
+	}
+	mcpClient, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = mcpClient.Close() }()
+
+	call := func(c *client.Client) (string, string, time.Duration) {
+		return chaosCall(c, opts.tool, opts.args, callTimeout)
+	}
+	if opts.tool != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, tool, err := resolveToolName(ctx, mcpClient, opts.tool, false)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if tool != nil && isDestructive(tool) {
+			if !stdinIsTerminal() {
+				return fmt.Errorf("'%s' is flagged as destructive; calling it repeatedly needs confirmation on a terminal", tool.Name)
+			}
+			if !confirmDestructiveCall(tool, stdinScanner()) {
+				return nil
+			}
+		}
+	}
+
+	// The fastest of a few clean calls sets the window in which mid-call
+	// drops are timed, so that they land before the call is answered
+	var window time.Duration
+	for i := 0; i < chaosCleanCalls; i++ {
+		outcome, detail, duration := call(mcpClient)
+		if outcome != chaosCompleted {
+			return fmt.Errorf("%s fails without faults: %s", operation, detail)
+		}
+		if i == 0 || duration < window {
+			window = duration
+		}
+	}
+	window = max(window, time.Microsecond)
+
+	// Without a seed a random one is used, printed so that the run can be repeated
+	for opts.seed == 0 {
+		opts.seed = rand.Uint64()
+	}
+	rng := rand.New(rand.NewPCG(opts.seed, opts.seed))
+	fmt.Printf("Seed: %d (repeat this run with -chaos-seed %d)\n", opts.seed, opts.seed)
+	fmt.Printf("Iterations: %d, fault rate %.0f%%, clean call %s\n\n", opts.iterations, opts.rate*100, humanDuration(window))
+
+	result := &chaosReport{Operation: operation, Seed: opts.seed, Iterations: opts.iterations, Rate: opts.rate, Calls: map[string]int{}, Recoveries: map[string]int{}}
+	reported := map[string]bool{}
+	var failed []string
+	finding := func(check, subject, format string, v ...any) {
+		if reported[check+subject] {
+			return
+		}
+		reported[check+subject] = true
+		f := report.addFinding(check, subject, format, v...)
+		fmt.Printf("        %s\n", f)
+		if f.fails() {
+			failed = append(failed, subject)
+		}
+	}
+
+	for i := 1; i <= opts.iterations; i++ {
+		fault := chaosNone
+		if rng.Float64() < opts.rate {
+			fault = chaosBetween
+			if rng.IntN(2) == 0 {
+				fault = chaosMidCall
+			}
+		}
+
+		var dropped atomic.Int64
+		var timer *time.Timer
+		switch fault {
+		case chaosBetween:
+			dropped.Store(int64(chaosConns.drop()))
+		case chaosMidCall:
+			delay := time.Duration(rng.Int64N(int64(window)))
+			timer = time.AfterFunc(delay, func() { dropped.Store(int64(chaosConns.drop())) })
+		}
+		outcome, detail, duration := call(mcpClient)
+		if timer != nil && timer.Stop() {
+			// The call finished before the drop: drop the connections now,
+			// before the next call
+			fault = chaosBetween
+			dropped.Store(int64(chaosConns.drop()))
+		}
+		result.Calls[outcome]++
+
+		line := fmt.Sprintf("  %04d  %-13s  %-9s  %6s", i, fault, outcome, humanDuration(duration))
+		if fault == chaosNone {
+			if outcome != chaosCompleted {
+				line += "  " + truncateText(detail, 60)
+			}
+			fmt.Println(line)
+			emitEvent(eventCheck, map[string]any{"id": fmt.Sprintf("chaos.%04d", i), "fault": fault, "status": outcome, "detail": detail, "durationMs": durationMillis(duration)})
+			continue
+		}
+
+		event := chaosEvent{Iteration: i, Fault: fault, Dropped: int(dropped.Load()), Call: outcome, Detail: detail, Duration: duration}
+		event.Recovery, event.RecoveryTime, detail = chaosRecover(&mcpClient, connect, timeout)
+		result.Recoveries[event.Recovery]++
+		if outcome != chaosCompleted && event.Recovery != recoveryLost {
+			replayOutcome, replayDetail, _ := call(mcpClient)
+			event.Replay = "succeeded"
+			if replayOutcome != chaosCompleted {
+				event.Replay = "failed: " + replayDetail
+			}
+		}
+		result.Events = append(result.Events, event)
+
+		line += fmt.Sprintf("  %d connection%s dropped, session %s", event.Dropped, pluralS(event.Dropped), event.Recovery)
+		if event.Recovery == recoveryReestablished {
+			line += " in " + humanDuration(event.RecoveryTime)
+		}
+		if event.Replay != "" {
+			line += ", repeated call " + truncateText(event.Replay, 40)
+		}
+		fmt.Println(line)
+		if outcome == chaosFailed {
+			fmt.Printf("        %s\n", event.Detail)
+		}
+		emitEvent(eventCheck, map[string]any{
+			"id":         fmt.Sprintf("chaos.%04d", i),
+			"fault":      fault,
+			"status":     outcome,
+			"detail":     event.Detail,
+			"durationMs": durationMillis(duration),
+			"recovery":   event.Recovery,
+			"replay":     event.Replay,
+		})
+
+		switch {
+		case outcome == chaosHung:
+			finding(checkIDChaosUnrecovered, fault, "%s: %s hung after its connection dropped (iteration %d of seed %d): %s", fault, operation, i, opts.seed, event.Detail)
+		case event.Recovery == recoveryLost:
+			finding(checkIDChaosUnrecovered, fault, "%s: the session could not be re-established (iteration %d of seed %d): %s", fault, i, opts.seed, detail)
+		case strings.HasPrefix(event.Replay, "failed"):
+			finding(checkIDChaosUnrecovered, fault, "%s: %s failed again after the session recovered (iteration %d of seed %d): %s", fault, operation, i, opts.seed, event.Replay)
+		}
+		if event.Recovery == recoveryReestablished && opts.httpSession {
+			finding(checkIDChaosSessionLost, fault, "%s: the streamable HTTP session did not survive a dropped connection (iteration %d of seed %d): %s", fault, i, opts.seed, detail)
+		}
+		if event.Recovery == recoveryLost {
+			result.Iterations = i
+			fmt.Printf("\nStopped after %d of %d iterations\n", i, opts.iterations)
+			break
+		}
+	}
+	report.setChaos(result)
+
+	fmt.Println("\n=== Chaos Results ===")
+	faults := len(result.Events)
+	midCall := 0
+	for _, e := range result.Events {
+		if e.Fault == chaosMidCall {
+			midCall++
+		}
+	}
+	fmt.Printf("%d call%s, %d fault%s injected (%d mid-call, %d between calls)\n", result.Iterations, pluralS(result.Iterations), faults, pluralS(faults), midCall, faults-midCall)
+	fmt.Printf("  Calls:      %d completed, %d failed, %d hung\n", result.Calls[chaosCompleted], result.Calls[chaosFailed], result.Calls[chaosHung])
+	if faults > 0 {
+		fmt.Printf("  Sessions:   %d continued, %d re-established, %d lost\n", result.Recoveries[recoveryContinued], result.Recoveries[recoveryReestablished], result.Recoveries[recoveryLost])
+		replayed, replayFailed := 0, 0
+		for _, e := range result.Events {
+			if e.Replay != "" {
+				replayed++
+				if e.Replay != "succeeded" {
+					replayFailed++
+				}
+			}
+		}
+		fmt.Printf("  Repeated:   %d failed call%s repeated, %d succeeded\n", replayed, pluralS(replayed), replayed-replayFailed)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("the session did not recover from %d kind%s of fault: %s", len(failed), pluralS(len(failed)), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// chaosCall makes the iteration's call: the tool call, or tools/list
+// without a tool. It returns the outcome, the error and how long it took.
+func chaosCall(mcpClient *client.Client, tool string, args map[string]any, callTimeout time.Duration) (string, string, time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	start := time.Now()
+	var err error
+	if tool != "" {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tool
+		request.Params.Arguments = args
+		_, err = mcpClient.CallTool(ctx, request)
+	} else {
+		_, err = mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	}
+	duration := time.Since(start)
+	switch {
+	case err == nil:
+		return chaosCompleted, "", duration
+	case errors.Is(err, context.DeadlineExceeded):
+		return chaosHung, fmt.Sprintf("no answer or error within %s", humanDuration(callTimeout)), duration
+	default:
+		return chaosFailed, strings.TrimSpace(err.Error()), duration
+	}
+}
+
+// chaosRecover checks whether the session still answers a ping after a
+// fault and, if not, replaces it with a new one. It returns how the session
+// recovered, how long a new session took, and why the session was replaced
+// or lost.
+func chaosRecover(mcpClient **client.Client, connect func() (*client.Client, error), timeout time.Duration) (string, time.Duration, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	pingErr := (*mcpClient).Ping(ctx)
+	cancel()
+	if pingErr == nil {
+		return recoveryContinued, 0, ""
+	}
+
+	start := time.Now()
+	_ = (*mcpClient).Close()
+	replacement, err := connect()
+	if err != nil {
+		return recoveryLost, 0, fmt.Sprintf("ping failed: %v; then %v", pingErr, err)
+	}
+	*mcpClient = replacement
+	return recoveryReestablished, time.Since(start), fmt.Sprintf("ping failed: %v", pingErr)
+}
@@ -0,0 +1,376 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Check IDs used by the capability suite
+const (
+	checkConnect       = "connect"
+	checkInitialize    = "initialize"
+	checkListTools     = "tools/list"
+	checkListResources = "resources/list"
+	checkListTemplates = "resources/templates/list"
+	checkListPrompts   = "prompts/list"
+	checkCallTool      = "tools/call"
+)
+
+// Check statuses
+const (
+	checkPass  = "pass"
+	checkFail  = "fail"
+	checkFlaky = "flaky"
+	checkSkip  = "skip"
+)
+
+// checkOutcome is the result of one check in a single run. Observed
+// summarizes what the server returned (for example the listed tool names)
+// so that answers which change between runs can be detected. Items holds the
+// JSON of each returned item keyed by name, for detailed comparisons.
+type checkOutcome struct {
+	ID       string
+	Skipped  bool
+	Err      error
+	Observed string
+	Items    map[string]string
+	Duration time.Duration
+}
+
+// checkSummary aggregates the outcomes of a check across all runs
+type checkSummary struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Runs    int    `json:"runs"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+	Varies  bool   `json:"varies,omitempty"`
+	// Suppressed is set when a suppression accepts the check's finding
+	Suppressed bool          `json:"suppressed,omitempty"`
+	Errors     []string      `json:"errors,omitempty"`
+	AvgTime    time.Duration `json:"avgDurationNs"`
+	totalTime  time.Duration
+	observed   map[string]bool
+}
+
+// suiteOptions adjusts how the capability checks are run. An empty protocol
+// version uses the probe's default; a tool name adds a call to that tool.
+type suiteOptions struct {
+	protocolVersion string
+	callTool        string
+	callArgs        map[string]any
+}
+
+// runCapabilitySuite runs the capability checks once against a fresh connection
+func runCapabilitySuite(dial func(ctx context.Context) (*client.Client, error), timeout time.Duration, opts suiteOptions) []checkOutcome {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var outcomes []checkOutcome
+	callID := ""
+	if opts.callTool != "" {
+		callID = checkCallTool + " " + opts.callTool
+	}
+	skipRest := func(ids ...string) []checkOutcome {
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			outcomes = append(outcomes, checkOutcome{ID: id, Skipped: true})
+		}
+		return outcomes
+	}
+
+	start := time.Now()
+	mcpClient, err := dial(ctx)
+	outcomes = append(outcomes, checkOutcome{ID: checkConnect, Err: err, Duration: time.Since(start)})
+	if err != nil {
+		return skipRest(checkInitialize, checkListTools, checkListResources, checkListTemplates, checkListPrompts, callID)
+	}
+	defer func() { _ = mcpClient.Close() }()
+
+	start = time.Now()
+	initRequest := newInitializeRequest()
+	if opts.protocolVersion != "" {
+		initRequest.Params.ProtocolVersion = opts.protocolVersion
+	}
+	initResult, err := mcpClient.Initialize(ctx, initRequest)
+	outcome := checkOutcome{ID: checkInitialize, Err: err, Duration: time.Since(start)}
+	if err == nil {
+		outcome.Observed = fmt.Sprintf("%s %s", initResult.ServerInfo.Name, initResult.ProtocolVersion)
+		outcome.Items = map[string]string{
+			"serverInfo":      itemJSON(initResult.ServerInfo),
+			"protocolVersion": itemJSON(initResult.ProtocolVersion),
+			"capabilities":    itemJSON(initResult.Capabilities),
+			"instructions":    itemJSON(initResult.Instructions),
+		}
+	}
+	outcomes = append(outcomes, outcome)
+	if err != nil {
+		return skipRest(checkListTools, checkListResources, checkListTemplates, checkListPrompts, callID)
+	}
+	caps := initResult.Capabilities
+
+	if caps.Tools != nil {
+		start = time.Now()
+		result, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+		outcome := checkOutcome{ID: checkListTools, Err: err, Duration: time.Since(start)}
+		if err == nil {
+			outcome.Observed = strings.Join(toolNames(result.Tools), ",")
+			outcome.Items = itemsByKey(result.Tools, func(t mcp.Tool) string { return t.Name })
+		}
+		outcomes = append(outcomes, outcome)
+	} else {
+		skipRest(checkListTools)
+	}
+
+	if caps.Resources != nil {
+		start = time.Now()
+		result, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{})
+		outcome := checkOutcome{ID: checkListResources, Err: err, Duration: time.Since(start)}
+		if err == nil {
+			outcome.Observed = strings.Join(resourceURIs(result.Resources), ",")
+			outcome.Items = itemsByKey(result.Resources, func(r mcp.Resource) string { return r.URI })
+		}
+		outcomes = append(outcomes, outcome)
+
+		start = time.Now()
+		templates, err := mcpClient.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+		outcome = checkOutcome{ID: checkListTemplates, Err: err, Duration: time.Since(start)}
+		if err == nil {
+			outcome.Observed = strings.Join(resourceTemplateStrings(templates.ResourceTemplates), ",")
+			outcome.Items = itemsByKey(templates.ResourceTemplates, func(t mcp.ResourceTemplate) string { return t.Name })
+		}
+		outcomes = append(outcomes, outcome)
+	} else {
+		skipRest(checkListResources, checkListTemplates)
+	}
+
+	if caps.Prompts != nil {
+		start = time.Now()
+		result, err := mcpClient.ListPrompts(ctx, mcp.ListPromptsRequest{})
+		outcome := checkOutcome{ID: checkListPrompts, Err: err, Duration: time.Since(start)}
+		if err == nil {
+			outcome.Observed = strings.Join(promptNames(result.Prompts), ",")
+			outcome.Items = itemsByKey(result.Prompts, func(p mcp.Prompt) string { return p.Name })
+		}
+		outcomes = append(outcomes, outcome)
+	} else {
+		skipRest(checkListPrompts)
+	}
+
+	if callID != "" {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = opts.callTool
+		request.Params.Arguments = opts.callArgs
+		start = time.Now()
+		result, err := mcpClient.CallTool(ctx, request)
+		outcome := checkOutcome{ID: callID, Err: err, Duration: time.Since(start)}
+		if err == nil {
+			outcome.Observed = itemJSON(result)
+			outcome.Items = map[string]string{"result": itemJSON(result)}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes
+}
+
+// itemJSON returns the JSON encoding of a value
+func itemJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// itemsByKey returns the JSON of each item keyed by its name
+func itemsByKey[T any](items []T, key func(T) string) map[string]string {
+	result := make(map[string]string, len(items))
+	for _, item := range items {
+		result[key(item)] = itemJSON(item)
+	}
+	return result
+}
+
+// aggregateChecks combines per-run outcomes into one summary per check,
+// in the order the checks were first seen. A check that both passed and
+// failed is flaky.
+func aggregateChecks(runs [][]checkOutcome) []checkSummary {
+	var order []string
+	summaries := make(map[string]*checkSummary)
+	for _, outcomes := range runs {
+		for _, o := range outcomes {
+			s, ok := summaries[o.ID]
+			if !ok {
+				s = &checkSummary{ID: o.ID, observed: make(map[string]bool)}
+				summaries[o.ID] = s
+				order = append(order, o.ID)
+			}
+			s.Runs++
+			switch {
+			case o.Skipped:
+				s.Skipped++
+			case o.Err != nil:
+				s.Failed++
+				s.Errors = appendUnique(s.Errors, o.Err.Error())
+				s.totalTime += o.Duration
+			default:
+				s.Passed++
+				s.observed[o.Observed] = true
+				s.totalTime += o.Duration
+			}
+		}
+	}
+
+	result := make([]checkSummary, 0, len(order))
+	for _, id := range order {
+		s := summaries[id]
+		if executed := s.Passed + s.Failed; executed > 0 {
+			s.AvgTime = s.totalTime / time.Duration(executed)
+		}
+		s.Varies = len(s.observed) > 1
+		switch {
+		case s.Failed > 0 && s.Passed > 0:
+			s.Status = checkFlaky
+		case s.Failed > 0:
+			s.Status = checkFail
+		case s.Passed > 0:
+			s.Status = checkPass
+		default:
+			s.Status = checkSkip
+		}
+		result = append(result, *s)
+	}
+	return result
+}
+
+// appendUnique appends value to list unless it is already present
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// runRepeatedSuite runs the capability suite the given number of times and
+// prints the aggregated results. It returns an error if any check failed,
+// was flaky, or returned different results across runs.
+func runRepeatedSuite(dial func(ctx context.Context) (*client.Client, error), runs int, timeout time.Duration) error {
+	fmt.Printf("Running capability checks %d times...\n", runs)
+
+	var results [][]checkOutcome
+	for i := 1; i <= runs; i++ {
+		outcomes := runCapabilitySuite(dial, timeout, suiteOptions{})
+		failed := 0
+		for _, o := range outcomes {
+			if o.Err != nil {
+				failed++
+			}
+			if !o.Skipped {
+				report.addTiming(o.ID, o.Duration, o.Err)
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("  Run %d: %d check(s) failed\n", i, failed)
+		} else {
+			fmt.Printf("  Run %d: ok\n", i)
+		}
+		results = append(results, outcomes)
+	}
+
+	summaries := aggregateChecks(results)
+	defer report.setChecks(summaries)
+
+	fmt.Printf("\n--- Check Results (%d runs) ---\n", runs)
+	width := 0
+	for _, s := range summaries {
+		width = max(width, len(s.ID))
+	}
+	var problems []string
+	for i, s := range summaries {
+		emitEvent(eventCheck, map[string]any{
+			"id":            s.ID,
+			"status":        s.Status,
+			"runs":          s.Runs,
+			"passed":        s.Passed,
+			"failed":        s.Failed,
+			"varies":        s.Varies,
+			"avgDurationMs": durationMillis(s.AvgTime),
+			"errors":        s.Errors,
+		})
+
+		line := fmt.Sprintf("  %-*s  %-5s  %d/%d passed", width, s.ID, strings.ToUpper(s.Status), s.Passed, s.Passed+s.Failed)
+		if s.Status != checkSkip {
+			line += fmt.Sprintf("  avg %s", humanDuration(s.AvgTime))
+		}
+		if s.Varies {
+			line += "  (results varied between runs)"
+		}
+		fmt.Println(line)
+		for _, e := range s.Errors {
+			fmt.Printf("      %s\n", e)
+		}
+
+		var f finding
+		problem := fmt.Sprintf("%s %s", s.ID, s.Status)
+		switch {
+		case s.Status == checkFail:
+			check, subject := suiteFindingCheck(s.ID)
+			f = report.addFinding(check, subject, "%s failed in %d of %d runs", s.ID, s.Failed, s.Passed+s.Failed)
+		case s.Status == checkFlaky:
+			f = report.addFinding(checkIDInconsistent, s.ID, "%s is flaky (%d of %d runs failed)", s.ID, s.Failed, s.Passed+s.Failed)
+		case s.Varies:
+			f = report.addFinding(checkIDInconsistent, s.ID, "%s returned different results between runs", s.ID)
+			problem = fmt.Sprintf("%s varies", s.ID)
+		default:
+			continue
+		}
+		fmt.Printf("      %s\n", f)
+		summaries[i].Suppressed = f.Suppressed
+		if f.fails() {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("checks did not pass consistently: %s", strings.Join(problems, ", "))
+	}
+	fmt.Println("\nAll checks passed consistently")
+	return nil
+}
+
+// suiteFindingCheck returns the check ID and subject of a failed check of
+// the capability suite
+func suiteFindingCheck(id string) (string, string) {
+	switch id {
+	case checkConnect:
+		return checkIDConnect, ""
+	case checkInitialize:
+		return checkIDInitialize, ""
+	case checkListTools:
+		return checkIDToolsList, ""
+	case checkListResources:
+		return checkIDResourcesList, ""
+	case checkListTemplates:
+		return checkIDTemplatesList, ""
+	case checkListPrompts:
+		return checkIDPromptsList, ""
+	}
+	return checkIDToolCall, strings.TrimPrefix(id, checkCallTool+" ")
+}
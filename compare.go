@@ -0,0 +1,512 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// transportTarget is one transport endpoint of the server being compared
+type transportTarget struct {
+	name string
+	url  string
+	dial func(ctx context.Context) (*client.Client, error)
+}
+
+// Impact of a difference on a client that works with the first side
+const (
+	impactBreaking   = "breaking"
+	impactCompatible = "compatible"
+)
+
+// behaviorDifference is a difference in behavior between two ways of probing
+// the same server, e.g. over two transports or two protocol versions
+type behaviorDifference struct {
+	Check  string `json:"check"`
+	Impact string `json:"impact"`
+	Detail string `json:"detail"`
+
+	// feature is set for compatible differences that add functionality
+	feature bool
+	// finding is set for differences reported as findings
+	finding *finding
+}
+
+// otherTransportURL derives the URL of the other transport by swapping the
+// conventional /mcp (streamable HTTP) and /sse endpoint paths
+func otherTransportURL(serverURL, transportName string) (string, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL '%s': %w", serverURL, err)
+	}
+	path := strings.TrimRight(parsed.Path, "/")
+	switch {
+	case transportName == "http" && strings.HasSuffix(path, "/mcp"):
+		parsed.Path = strings.TrimSuffix(path, "/mcp") + "/sse"
+	case transportName == "sse" && strings.HasSuffix(path, "/sse"):
+		parsed.Path = strings.TrimSuffix(path, "/sse") + "/mcp"
+	default:
+		return "", fmt.Errorf("cannot derive the other transport's URL from '%s'; use -compare-url", serverURL)
+	}
+	parsed.RawPath = ""
+	return parsed.String(), nil
+}
+
+// runTransportComparison runs the capability checks over both transports and
+// prints their results side by side followed by the differences. It returns
+// an error if the transports behave differently or a check failed.
+func runTransportComparison(a, b transportTarget, timeout time.Duration) error {
+	fmt.Println("=== Transport Comparison ===")
+	fmt.Printf("%-4s  %s\n", a.name, a.url)
+	fmt.Printf("%-4s  %s\n\n", b.name, b.url)
+
+	outcomesA := runCapabilitySuite(a.dial, timeout, suiteOptions{})
+	outcomesB := runCapabilitySuite(b.dial, timeout, suiteOptions{})
+
+	width := len("Check")
+	for _, o := range outcomesA {
+		width = max(width, len(o.ID))
+	}
+	fmt.Printf("%-*s  %-22s  %s\n", width, "Check", a.name, b.name)
+	var totalA, totalB time.Duration
+	for i := range outcomesA {
+		oa, ob := outcomesA[i], outcomesB[i]
+		totalA += oa.Duration
+		totalB += ob.Duration
+		fmt.Printf("%-*s  %-22s  %s\n", width, oa.ID, outcomeCell(oa), outcomeCell(ob))
+	}
+	fmt.Printf("%-*s  %-22s  %s\n", width, "total", humanDuration(totalA), humanDuration(totalB))
+	if totalA > 0 && totalB > 0 {
+		faster, ratio := a.name, float64(totalB)/float64(totalA)
+		if totalB < totalA {
+			faster, ratio = b.name, float64(totalA)/float64(totalB)
+		}
+		fmt.Printf("\nLatency: %s was %.1fx faster overall\n", faster, ratio)
+	}
+
+	diffs := compareOutcomes(a.name, b.name, outcomesA, outcomesB)
+	report.setTransportDiffs(diffs)
+	emitEvent(eventTransportDiff, map[string]any{
+		"transports":  []string{a.name, b.name},
+		"urls":        []string{a.url, b.url},
+		"differences": diffs,
+		"summary":     impactSummary(diffs),
+	})
+
+	failed := recordSuiteFailures(outcomesA, outcomesB) > 0
+	differ := recordDifferences(checkIDTransportDiff, diffs, nil) > 0
+
+	if len(diffs) == 0 {
+		fmt.Println("\nNo differences: both transports behave the same")
+	} else {
+		fmt.Printf("\nDifferences (%d: %s):\n", len(diffs), impactSummary(diffs))
+		printDifferences(diffs)
+	}
+
+	switch {
+	case differ:
+		return fmt.Errorf("transports differ (%s)", impactSummary(diffs))
+	case failed:
+		return fmt.Errorf("checks failed on both transports")
+	}
+	return nil
+}
+
+// outcomeCell formats a check outcome for the comparison table
+func outcomeCell(o checkOutcome) string {
+	switch {
+	case o.Skipped:
+		return checkSkip
+	case o.Err != nil:
+		return fmt.Sprintf("%s %s", checkFail, humanDuration(o.Duration))
+	case o.Items != nil && o.ID != checkInitialize && !strings.HasPrefix(o.ID, checkCallTool):
+		return fmt.Sprintf("%s %s (%d)", checkPass, humanDuration(o.Duration), len(o.Items))
+	default:
+		return fmt.Sprintf("%s %s", checkPass, humanDuration(o.Duration))
+	}
+}
+
+// compareOutcomes lists the differences between the outcomes of the same
+// checks. Each difference is classified by its impact on a client that works
+// with a: removed or newly required things are breaking, additions are
+// compatible.
+func compareOutcomes(nameA, nameB string, a, b []checkOutcome) []behaviorDifference {
+	diffs := []behaviorDifference{}
+	add := func(check, impact, format string, v ...any) {
+		diffs = append(diffs, behaviorDifference{Check: check, Impact: impact, Detail: fmt.Sprintf(format, v...)})
+	}
+	addFeature := func(check, format string, v ...any) {
+		diffs = append(diffs, behaviorDifference{Check: check, Impact: impactCompatible, Detail: fmt.Sprintf(format, v...), feature: true})
+	}
+
+	for i := range a {
+		oa, ob := a[i], b[i]
+		switch {
+		case oa.Err != nil && ob.Err != nil:
+			if oa.Err.Error() != ob.Err.Error() {
+				add(oa.ID, impactCompatible, "fails differently: %s: %v; %s: %v", nameA, oa.Err, nameB, ob.Err)
+			}
+			continue
+		case oa.Err != nil:
+			add(oa.ID, impactCompatible, "fails with %s only: %v", nameA, oa.Err)
+			continue
+		case ob.Err != nil:
+			add(oa.ID, impactBreaking, "fails with %s only: %v", nameB, ob.Err)
+			continue
+		case oa.Skipped != ob.Skipped:
+			if ob.Skipped {
+				add(oa.ID, impactBreaking, "skipped with %s only", nameB)
+			} else {
+				addFeature(oa.ID, "skipped with %s only", nameA)
+			}
+			continue
+		case oa.Skipped:
+			continue
+		}
+
+		var onlyA, onlyB, changed []string
+		for _, key := range sortedKeys(oa.Items) {
+			other, ok := ob.Items[key]
+			switch {
+			case !ok:
+				onlyA = append(onlyA, key)
+			case other != oa.Items[key]:
+				changed = append(changed, key)
+			}
+		}
+		for _, key := range sortedKeys(ob.Items) {
+			if _, ok := oa.Items[key]; !ok {
+				onlyB = append(onlyB, key)
+			}
+		}
+
+		if len(onlyA) > 0 {
+			add(oa.ID, impactBreaking, "only with %s: %s", nameA, strings.Join(onlyA, ", "))
+		}
+		if len(onlyB) > 0 {
+			addFeature(oa.ID, "only with %s: %s", nameB, strings.Join(onlyB, ", "))
+		}
+		for _, key := range changed {
+			x, y := oa.Items[key], ob.Items[key]
+			var changes []schemaChange
+			switch {
+			case oa.ID == checkInitialize && key == "capabilities":
+				changes = capabilityChanges(x, y)
+			case oa.ID == checkInitialize:
+				add(oa.ID, impactCompatible, "%s differs: %s: %s; %s: %s", key, nameA, x, nameB, y)
+				continue
+			case oa.ID == checkListTools:
+				changes = toolChanges(x, y)
+			case oa.ID == checkListPrompts:
+				changes = promptChanges(x, y)
+			}
+			for _, c := range changes {
+				diffs = append(diffs, behaviorDifference{Check: oa.ID, Impact: c.impact, Detail: fmt.Sprintf("'%s': %s", key, c.detail), feature: c.feature})
+			}
+
+			// Anything else that changed, such as descriptions, is compatible
+			// unless it changes what a client can rely on
+			fields := changedFields(x, y)
+			if len(changes) > 0 {
+				fields = slices.DeleteFunc(fields, func(f string) bool {
+					return f == "inputSchema" || f == "arguments" || f == "outputSchema" || oa.ID == checkInitialize
+				})
+			}
+			if len(fields) > 0 {
+				impact := impactCompatible
+				if slices.Contains(fields, "isError") || slices.Contains(fields, "mimeType") || slices.Contains(fields, "uriTemplate") {
+					impact = impactBreaking
+				}
+				add(oa.ID, impact, "'%s' differs in %s", key, strings.Join(fields, ", "))
+			}
+		}
+	}
+	return diffs
+}
+
+// schemaChange is one classified change to a tool, prompt or capability
+type schemaChange struct {
+	impact  string
+	detail  string
+	feature bool
+}
+
+// Constructors for the kinds of schema change
+func breakingChange(format string, v ...any) schemaChange {
+	return schemaChange{impact: impactBreaking, detail: fmt.Sprintf(format, v...)}
+}
+
+func featureChange(format string, v ...any) schemaChange {
+	return schemaChange{impact: impactCompatible, detail: fmt.Sprintf(format, v...), feature: true}
+}
+
+func compatibleChange(format string, v ...any) schemaChange {
+	return schemaChange{impact: impactCompatible, detail: fmt.Sprintf(format, v...)}
+}
+
+// toolChanges classifies the changes between two versions of a tool: removed
+// parameters, newly required parameters, narrowed types and removed output
+// fields are breaking; new optional parameters and widened types are not
+func toolChanges(x, y string) []schemaChange {
+	type schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	var tx, ty struct {
+		InputSchema  schema  `json:"inputSchema"`
+		OutputSchema *schema `json:"outputSchema"`
+	}
+	if json.Unmarshal([]byte(x), &tx) != nil || json.Unmarshal([]byte(y), &ty) != nil {
+		return nil
+	}
+
+	var changes []schemaChange
+	for _, name := range sortedAnyKeys(tx.InputSchema.Properties) {
+		prop, ok := ty.InputSchema.Properties[name]
+		if !ok {
+			changes = append(changes, breakingChange("parameter '%s' removed", name))
+			continue
+		}
+		before, after := schemaPropertyTypes(tx.InputSchema.Properties[name]), schemaPropertyTypes(prop)
+		switch {
+		case slices.Equal(before, after):
+		case len(after) == 0 || len(before) > 0 && !slices.ContainsFunc(before, func(t string) bool { return !slices.Contains(after, t) }):
+			changes = append(changes, featureChange("parameter '%s' type widened from %s to %s", name, typeList(before), typeList(after)))
+		default:
+			changes = append(changes, breakingChange("parameter '%s' type changed from %s to %s", name, typeList(before), typeList(after)))
+		}
+	}
+	for _, name := range sortedAnyKeys(ty.InputSchema.Properties) {
+		if _, ok := tx.InputSchema.Properties[name]; ok {
+			continue
+		}
+		if slices.Contains(ty.InputSchema.Required, name) {
+			changes = append(changes, breakingChange("new required parameter '%s'", name))
+		} else {
+			changes = append(changes, featureChange("new optional parameter '%s'", name))
+		}
+	}
+	for _, name := range ty.InputSchema.Required {
+		if _, existed := tx.InputSchema.Properties[name]; existed && !slices.Contains(tx.InputSchema.Required, name) {
+			changes = append(changes, breakingChange("parameter '%s' is now required", name))
+		}
+	}
+	for _, name := range tx.InputSchema.Required {
+		if _, exists := ty.InputSchema.Properties[name]; exists && !slices.Contains(ty.InputSchema.Required, name) {
+			changes = append(changes, featureChange("parameter '%s' is now optional", name))
+		}
+	}
+	if tx.OutputSchema != nil {
+		for _, name := range sortedAnyKeys(tx.OutputSchema.Properties) {
+			if ty.OutputSchema == nil {
+				changes = append(changes, breakingChange("output schema removed"))
+				break
+			}
+			if _, ok := ty.OutputSchema.Properties[name]; !ok {
+				changes = append(changes, breakingChange("output field '%s' removed", name))
+			}
+		}
+	}
+	return changes
+}
+
+// promptChanges classifies the changes to a prompt's arguments
+func promptChanges(x, y string) []schemaChange {
+	var px, py mcp.Prompt
+	if json.Unmarshal([]byte(x), &px) != nil || json.Unmarshal([]byte(y), &py) != nil {
+		return nil
+	}
+	args := func(p mcp.Prompt) map[string]bool {
+		m := make(map[string]bool, len(p.Arguments))
+		for _, a := range p.Arguments {
+			m[a.Name] = a.Required
+		}
+		return m
+	}
+	ax, ay := args(px), args(py)
+
+	var changes []schemaChange
+	for _, a := range px.Arguments {
+		required, ok := ay[a.Name]
+		switch {
+		case !ok:
+			changes = append(changes, breakingChange("argument '%s' removed", a.Name))
+		case required && !a.Required:
+			changes = append(changes, breakingChange("argument '%s' is now required", a.Name))
+		case !required && a.Required:
+			changes = append(changes, featureChange("argument '%s' is now optional", a.Name))
+		}
+	}
+	for _, a := range py.Arguments {
+		if _, ok := ax[a.Name]; ok {
+			continue
+		}
+		if a.Required {
+			changes = append(changes, breakingChange("new required argument '%s'", a.Name))
+		} else {
+			changes = append(changes, featureChange("new optional argument '%s'", a.Name))
+		}
+	}
+	return changes
+}
+
+// capabilityChanges classifies the changes to the advertised capabilities:
+// a capability or feature flag that is no longer advertised is breaking
+func capabilityChanges(x, y string) []schemaChange {
+	var cx, cy map[string]any
+	if json.Unmarshal([]byte(x), &cx) != nil || json.Unmarshal([]byte(y), &cy) != nil {
+		return []schemaChange{compatibleChange("changed from %s to %s", x, y)}
+	}
+	var changes []schemaChange
+	for _, name := range sortedAnyKeys(cx) {
+		after, ok := cy[name]
+		if !ok {
+			changes = append(changes, breakingChange("capability '%s' removed", name))
+			continue
+		}
+		fx, _ := cx[name].(map[string]any)
+		fy, _ := after.(map[string]any)
+		for _, flag := range sortedAnyKeys(fx) {
+			if fx[flag] == true && fy[flag] != true {
+				changes = append(changes, breakingChange("capability '%s' no longer supports %s", name, flag))
+			}
+		}
+		for _, flag := range sortedAnyKeys(fy) {
+			if fy[flag] == true && fx[flag] != true {
+				changes = append(changes, featureChange("capability '%s' now supports %s", name, flag))
+			}
+		}
+	}
+	for _, name := range sortedAnyKeys(cy) {
+		if _, ok := cx[name]; !ok {
+			changes = append(changes, featureChange("capability '%s' added", name))
+		}
+	}
+	return changes
+}
+
+// typeList formats the types of a schema property
+func typeList(types []string) string {
+	if len(types) == 0 {
+		return "any"
+	}
+	return strings.Join(types, "|")
+}
+
+// sortedAnyKeys returns the keys of a JSON object in sorted order
+func sortedAnyKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// impactSummary counts the differences by impact, e.g. "2 breaking, 1 compatible"
+func impactSummary(diffs []behaviorDifference) string {
+	breaking := 0
+	for _, d := range diffs {
+		if d.Impact == impactBreaking {
+			breaking++
+		}
+	}
+	return fmt.Sprintf("%d breaking, %d compatible", breaking, len(diffs)-breaking)
+}
+
+// printDifferences lists differences with their impact and finding
+func printDifferences(diffs []behaviorDifference) {
+	for _, d := range diffs {
+		label := ""
+		if d.finding != nil {
+			label = " [" + d.finding.Check
+			if d.finding.Suppressed {
+				label += ", suppressed: " + d.finding.Reason
+			} else if d.finding.WaiverExpired != "" {
+				label += ", waiver expired on " + d.finding.WaiverExpired
+			}
+			label += "]"
+		}
+		fmt.Printf("  %-10s  %s: %s%s\n", d.Impact, d.Check, d.Detail, label)
+	}
+}
+
+// recordDifferences records the differences that include accepts (all if
+// nil) as findings of a check. It returns how many are not suppressed.
+func recordDifferences(check string, diffs []behaviorDifference, include func(d behaviorDifference) bool) int {
+	unsuppressed := 0
+	for i := range diffs {
+		d := &diffs[i]
+		if include != nil && !include(*d) {
+			continue
+		}
+		f := report.addFinding(check, d.Check, "%s (%s): %s", d.Check, d.Impact, d.Detail)
+		d.finding = &f
+		if f.fails() {
+			unsuppressed++
+		}
+	}
+	return unsuppressed
+}
+
+// recordSuiteFailures records the failed capability checks of the given runs
+// as findings, once per check. It returns how many are not suppressed.
+func recordSuiteFailures(runs ...[]checkOutcome) int {
+	seen := map[string]bool{}
+	unsuppressed := 0
+	for _, outcomes := range runs {
+		for _, o := range outcomes {
+			if o.Err == nil || seen[o.ID] {
+				continue
+			}
+			seen[o.ID] = true
+			check, subject := suiteFindingCheck(o.ID)
+			if f := report.addFinding(check, subject, "%s failed: %v", o.ID, o.Err); f.fails() {
+				unsuppressed++
+			}
+		}
+	}
+	return unsuppressed
+}
+
+// changedFields returns the top-level JSON fields that differ between two objects
+func changedFields(x, y string) []string {
+	var mx, my map[string]any
+	if json.Unmarshal([]byte(x), &mx) != nil || json.Unmarshal([]byte(y), &my) != nil {
+		return []string{"content"}
+	}
+	var fields []string
+	seen := map[string]bool{}
+	for _, m := range []map[string]any{mx, my} {
+		for key := range m {
+			if !seen[key] && !reflect.DeepEqual(mx[key], my[key]) {
+				fields = append(fields, key)
+			}
+			seen[key] = true
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
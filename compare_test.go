@@ -0,0 +1,81 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestToolChanges(t *testing.T) {
+	before := `{"name":"search","inputSchema":{"type":"object",
+		"properties":{"query":{"type":"string"},"limit":{"type":"integer"},"lang":{"type":"string"},"page":{"type":"integer"}},
+		"required":["query","page"]},
+		"outputSchema":{"type":"object","properties":{"hits":{"type":"array"},"total":{"type":"integer"}}}}`
+	after := `{"name":"search","inputSchema":{"type":"object",
+		"properties":{"query":{"type":"string"},"limit":{"type":["integer","string"]},"lang":{"type":"integer"},"page":{"type":"integer"},"region":{"type":"string"},"sort":{"type":"string"}},
+		"required":["query","lang","sort"]},
+		"outputSchema":{"type":"object","properties":{"hits":{"type":"array"}}}}`
+
+	got := toolChanges(before, after)
+	want := []schemaChange{
+		breakingChange("parameter 'lang' type changed from string to integer"),
+		featureChange("parameter 'limit' type widened from integer to integer|string"),
+		featureChange("new optional parameter 'region'"),
+		breakingChange("new required parameter 'sort'"),
+		breakingChange("parameter 'lang' is now required"),
+		featureChange("parameter 'page' is now optional"),
+		breakingChange("output field 'total' removed"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toolChanges:\n got  %+v\n want %+v", got, want)
+	}
+
+	removed := toolChanges(`{"inputSchema":{"properties":{"id":{"type":"string"}}}}`, `{"inputSchema":{"properties":{}}}`)
+	if want := []schemaChange{breakingChange("parameter 'id' removed")}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("toolChanges for a removed parameter = %+v, want %+v", removed, want)
+	}
+	if changes := toolChanges(before, before); len(changes) != 0 {
+		t.Errorf("toolChanges of an unchanged tool = %+v, want none", changes)
+	}
+}
+
+func TestCompareOutcomes(t *testing.T) {
+	a := []checkOutcome{
+		{ID: checkListTools, Items: map[string]string{
+			"echo":   `{"name":"echo","inputSchema":{"properties":{"text":{"type":"string"}}}}`,
+			"legacy": `{"name":"legacy"}`,
+		}},
+		{ID: "resources/list", Skipped: true},
+		{ID: "prompts/list", Err: errors.New("method not found")},
+	}
+	b := []checkOutcome{
+		{ID: checkListTools, Items: map[string]string{
+			"echo": `{"name":"echo","inputSchema":{"properties":{"text":{"type":"string"},"upper":{"type":"boolean"}}}}`,
+			"new":  `{"name":"new"}`,
+		}},
+		{ID: "resources/list", Skipped: true},
+		{ID: "prompts/list", Items: map[string]string{}},
+	}
+
+	got := compareOutcomes("v1", "v2", a, b)
+	want := []behaviorDifference{
+		{Check: checkListTools, Impact: impactBreaking, Detail: "only with v1: legacy"},
+		{Check: checkListTools, Impact: impactCompatible, Detail: "only with v2: new", feature: true},
+		{Check: checkListTools, Impact: impactCompatible, Detail: "'echo': new optional parameter 'upper'", feature: true},
+		{Check: "prompts/list", Impact: impactCompatible, Detail: "fails with v1 only: method not found"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareOutcomes:\n got  %+v\n want %+v", got, want)
+	}
+
+	if diffs := compareOutcomes("v1", "v2", b, b); len(diffs) != 0 {
+		t.Errorf("compareOutcomes of identical outcomes = %+v, want none", diffs)
+	}
+	broken := compareOutcomes("v1", "v2", b[:1], []checkOutcome{{ID: checkListTools, Err: errors.New("timeout")}})
+	if len(broken) != 1 || broken[0].Impact != impactBreaking {
+		t.Errorf("a check failing with the second side only = %+v, want one breaking difference", broken)
+	}
+}
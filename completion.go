@@ -0,0 +1,326 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// completionCommandNames are the command names the completion script is
+// registered for when none are given
+var completionCommandNames = []string{"mcp-probe", "probe", "MCPProbe"}
+
+// bashCompletionScript asks the probe itself for candidates. The first line
+// of the answer is the word being completed as the probe split it; bash may
+// have split it further at characters such as ':' and '"', so that part is
+// removed from the candidates.
+const bashCompletionScript = `# MCPProbe shell completion
+_mcpprobe() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local IFS=$'\n'
+    local answer=($(command "${COMP_WORDS[0]}" __complete "$line" 2>/dev/null))
+    COMPREPLY=()
+    [ ${#answer[@]} -gt 1 ] || return 0
+    local prefix="${answer[0]%"$cur"}"
+    local word
+    for word in "${answer[@]:1}"; do
+        COMPREPLY+=("${word#"$prefix"}")
+    done
+    case "${answer[0]}" in
+        \'*) compopt -o nospace 2>/dev/null ;;
+    esac
+}
+`
+
+// zshCompletionPrelude lets zsh use the bash completion function
+const zshCompletionPrelude = `autoload -U +X bashcompinit && bashcompinit
+`
+
+// paramKeyPattern matches a key that is being typed in -params JSON
+var paramKeyPattern = regexp.MustCompile(`^\s*"?([^"]*)$`)
+
+// paramValuePattern matches a key followed by a value being typed
+var paramValuePattern = regexp.MustCompile(`^\s*"([^"]*)"\s*:\s*(.*)$`)
+
+// paramUsedKeyPattern finds the keys already present in -params JSON
+var paramUsedKeyPattern = regexp.MustCompile(`"([^"]*)"\s*:`)
+
+// runCompletionCommand implements the 'completion' subcommand, which prints
+// a shell completion script
+func runCompletionCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: completion bash|zsh [command name...]")
+	}
+	names := completionCommandNames
+	if len(args) > 1 {
+		names = args[1:]
+	}
+	script := bashCompletionScript + "complete -o default -F _mcpprobe " + strings.Join(names, " ") + "\n"
+	switch args[0] {
+	case "bash":
+		fmt.Print(script)
+	case "zsh":
+		fmt.Print(zshCompletionPrelude + script)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (use 'bash' or 'zsh')", args[0])
+	}
+	return nil
+}
+
+// runCompleteCommand implements the hidden '__complete' subcommand used by
+// the completion script. It prints the word being completed, then one
+// candidate per line.
+func runCompleteCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: __complete <command line>")
+	}
+	words, raw := completionWords(args[0])
+	candidates := completeWord(words, raw)
+	if len(candidates) == 0 {
+		return nil
+	}
+	fmt.Println(raw)
+	for _, c := range candidates {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+// completionWords splits a command line up to the cursor into the complete
+// words before it and the raw, still quoted, word being completed
+func completionWords(line string) ([]string, string) {
+	start := 0
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			start = i + 1
+		}
+	}
+	words, err := splitCommandLine(line[:start])
+	if err != nil {
+		return nil, line[start:]
+	}
+	return words, line[start:]
+}
+
+// completeWord returns the candidates for the word being completed, based on
+// the flag before it
+func completeWord(words []string, raw string) []string {
+	if len(words) == 0 {
+		return nil
+	}
+	previous := flagName(words[len(words)-1])
+	switch previous {
+	case "server":
+		servers, err := loadSavedServers()
+		if err != nil {
+			return nil
+		}
+		return withPrefix(servers.names(), raw)
+	case "profile":
+		cfg, err := loadConfig(completionFlagValue(words, "config"))
+		if err != nil {
+			return nil
+		}
+		return withPrefix(cfg.profileNames(), raw)
+	}
+
+	cached := completionToolCache(words)
+	if cached == nil {
+		return nil
+	}
+	switch previous {
+	case "call":
+		names := make([]string, len(cached.Tools))
+		for i, tool := range cached.Tools {
+			names[i] = tool.Name
+		}
+		sort.Strings(names)
+		return withPrefix(names, raw)
+	case "params":
+		tool := cached.find(completionFlagValue(words, "call"))
+		if tool == nil {
+			return nil
+		}
+		return completeParams(tool, raw)
+	}
+	return nil
+}
+
+// completionToolCache returns the cached tools of the saved server or
+// profile on the command line, or of the default profile
+func completionToolCache(words []string) *cachedTools {
+	name := ""
+	if server := completionFlagValue(words, "server"); server != "" {
+		name = "server-" + server
+	} else if profile := completionFlagValue(words, "profile"); profile != "" {
+		name = "profile-" + profile
+	} else if cfg, err := loadConfig(completionFlagValue(words, "config")); err == nil && cfg.DefaultProfile != "" {
+		name = "profile-" + cfg.DefaultProfile
+	}
+	if name == "" {
+		return nil
+	}
+	cached, err := loadToolSnapshot(name)
+	if err != nil {
+		return nil
+	}
+	return cached
+}
+
+// completeParams suggests the next key of the selected tool's input schema,
+// or a value for a key whose property is an enum or boolean, extending the
+// -params JSON typed so far. Only single-quoted top-level objects are
+// completed, as double quotes would need escaping.
+func completeParams(tool *listedTool, raw string) []string {
+	text := raw
+	switch {
+	case raw == "":
+	case strings.HasPrefix(raw, "'"):
+		text = raw[1:]
+	default:
+		return nil
+	}
+
+	head, fragment, ok := splitParamsJSON(text)
+	if !ok {
+		return nil
+	}
+	used := map[string]bool{}
+	for _, m := range paramUsedKeyPattern.FindAllStringSubmatch(head, -1) {
+		used[m[1]] = true
+	}
+
+	var candidates []string
+	if m := paramValuePattern.FindStringSubmatch(fragment); m != nil {
+		key, typed := m[1], strings.TrimSpace(m[2])
+		for _, value := range paramValueChoices(tool.InputSchema.Properties[key]) {
+			if strings.HasPrefix(value, typed) {
+				candidates = append(candidates, fmt.Sprintf("'%s\"%s\":%s", head, key, value))
+			}
+		}
+		return candidates
+	}
+	if m := paramKeyPattern.FindStringSubmatch(fragment); m != nil {
+		for _, key := range sortedAnyKeys(tool.InputSchema.Properties) {
+			if !used[key] && strings.HasPrefix(key, m[1]) {
+				candidates = append(candidates, fmt.Sprintf("'%s\"%s\":", head, key))
+			}
+		}
+	}
+	return candidates
+}
+
+// splitParamsJSON splits partial -params JSON after the last '{' or ',' of
+// the top-level object, giving the text before it and the member being typed.
+// ok is false inside nested values, which are not completed.
+func splitParamsJSON(text string) (head, fragment string, ok bool) {
+	if text == "" {
+		return "{", "", true
+	}
+	if text[0] != '{' {
+		return "", "", false
+	}
+	depth, split := 0, 0
+	inString, escaped := false, false
+	for i, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if r == '\\' {
+				escaped = true
+			} else if r == '"' {
+				inString = false
+			}
+		case r == '"':
+			inString = true
+		case r == '{' || r == '[':
+			depth++
+			if depth == 1 {
+				split = i + 1
+			}
+		case r == '}' || r == ']':
+			depth--
+		case r == ',' && depth == 1:
+			split = i + 1
+		}
+	}
+	if depth != 1 {
+		return "", "", false
+	}
+	return text[:split], text[split:], true
+}
+
+// paramValueChoices returns the JSON values suggested for a schema property:
+// its enum values, or true and false for a boolean
+func paramValueChoices(prop any) []string {
+	schema, _ := prop.(map[string]any)
+	if values, ok := schema["enum"].([]any); ok {
+		choices := make([]string, len(values))
+		for i, value := range values {
+			choices[i] = itemJSON(value)
+		}
+		return choices
+	}
+	for _, t := range schemaPropertyTypes(prop) {
+		if t == "boolean" {
+			return []string{"true", "false"}
+		}
+	}
+	return nil
+}
+
+// flagName returns the name of a flag argument such as -call or --call, or ""
+func flagName(word string) string {
+	if !strings.HasPrefix(word, "-") {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(word, "-"), "-")
+}
+
+// completionFlagValue returns the last value given to a flag on the command
+// line, as '-name value' or '-name=value'
+func completionFlagValue(words []string, name string) string {
+	value := ""
+	for i, word := range words {
+		flag, inline, hasInline := strings.Cut(flagName(word), "=")
+		if flag != name {
+			continue
+		}
+		if hasInline {
+			value = inline
+		} else if i+1 < len(words) {
+			value = words[i+1]
+		}
+	}
+	return value
+}
+
+// withPrefix returns the names starting with prefix
+func withPrefix(names []string, prefix string) []string {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
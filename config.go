@@ -0,0 +1,363 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigName is the config file looked for in the user's home directory
+const defaultConfigName = ".mcpprobe.yaml"
+
+// Environment variables used when the corresponding flags are not given
+const (
+	envURL     = "MCPPROBE_URL"
+	envHeaders = "MCPPROBE_HEADERS"
+)
+
+// envVarPattern matches ${VAR} references in header and parameter values
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// probeConfig is the contents of the MCPProbe config file
+type probeConfig struct {
+	DefaultProfile string                   `yaml:"default_profile"`
+	CheckUpdates   bool                     `yaml:"check_updates,omitempty"`
+	Profiles       map[string]profileConfig `yaml:"profiles"`
+}
+
+// profileConfig is a named set of connection settings. Every field is optional;
+// values given on the command line take precedence over the profile.
+type profileConfig struct {
+	URL           string               `yaml:"url,omitempty"`
+	Transport     string               `yaml:"transport,omitempty"`
+	Headers       map[string]string    `yaml:"headers,omitempty"`
+	Timeout       string               `yaml:"timeout,omitempty"`
+	CallTimeout   string               `yaml:"call_timeout,omitempty"`
+	AcceptTimeout string               `yaml:"accept_timeout,omitempty"`
+	Retries       int                  `yaml:"retries,omitempty"`
+	RetryBackoff  string               `yaml:"retry_backoff,omitempty"`
+	CACert        string               `yaml:"ca_cert,omitempty"`
+	Insecure      bool                 `yaml:"insecure,omitempty"`
+	Proxy         string               `yaml:"proxy,omitempty"`
+	Stdio         string               `yaml:"stdio,omitempty"`
+	Args          []string             `yaml:"args,omitempty"`
+	Env           map[string]string    `yaml:"env,omitempty"`
+	Auth          profileAuth          `yaml:"auth,omitempty"`
+	Aliases       map[string]string    `yaml:"aliases,omitempty"`
+	Suppressions  string               `yaml:"suppressions,omitempty"`
+	FailLevel     string               `yaml:"fail_level,omitempty"`
+	Expect        *profileExpectations `yaml:"expect,omitempty"`
+}
+
+// profileAuth holds authentication settings for a profile
+type profileAuth struct {
+	BearerToken     string       `yaml:"bearer_token,omitempty"`
+	BearerTokenFile string       `yaml:"bearer_token_file,omitempty"`
+	OAuth           profileOAuth `yaml:"oauth,omitempty"`
+}
+
+// profileOAuth holds OAuth client settings for a profile
+type profileOAuth struct {
+	ClientCredentials bool   `yaml:"client_credentials,omitempty"`
+	ClientID          string `yaml:"client_id,omitempty"`
+	ClientSecret      string `yaml:"client_secret,omitempty"`
+	TokenURL          string `yaml:"token_url,omitempty"`
+	Scopes            string `yaml:"scopes,omitempty"`
+}
+
+// defaultConfigPath returns the path of the config file in the home directory
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, defaultConfigName)
+}
+
+// loadConfig reads and parses a config file. If path is empty the default
+// config file is used, and a missing default file is not an error.
+func loadConfig(path string) (*probeConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+		if path == "" {
+			return &probeConfig{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return &probeConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg probeConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// profileNames returns the sorted names of all profiles in the config
+func (c *probeConfig) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectProfile returns the named profile, or the default profile if name is
+// empty. It returns nil if no profile was requested and there is no default.
+func (c *probeConfig) selectProfile(name string) (*profileConfig, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return nil, fmt.Errorf("profile '%s' not found (no profiles defined)", name)
+		}
+		return nil, fmt.Errorf("profile '%s' not found (available: %s)", name, strings.Join(c.profileNames(), ", "))
+	}
+	return &profile, nil
+}
+
+// applyProfile sets flags from the profile unless they were given explicitly
+// on the command line. Profile headers (including auth) are returned separately
+// so they can be merged with headers from the command line.
+func applyProfile(fs *flag.FlagSet, profile *profileConfig) (map[string]string, error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var envPairs []string
+	for key, value := range profile.Env {
+		envPairs = append(envPairs, key+"="+value)
+	}
+	sort.Strings(envPairs)
+
+	clientCredentials := ""
+	if profile.Auth.OAuth.ClientCredentials {
+		clientCredentials = "true"
+	}
+	insecure := ""
+	if profile.Insecure {
+		insecure = "true"
+	}
+	retries := ""
+	if profile.Retries > 0 {
+		retries = strconv.Itoa(profile.Retries)
+	}
+
+	values := []struct {
+		flag  string
+		value string
+	}{
+		{"url", profile.URL},
+		{"transport", profile.Transport},
+		{"timeout", profile.Timeout},
+		{"call-timeout", profile.CallTimeout},
+		{"accept-timeout", profile.AcceptTimeout},
+		{"retries", retries},
+		{"retry-backoff", profile.RetryBackoff},
+		{"ca-cert", profile.CACert},
+		{"insecure", insecure},
+		{"proxy", profile.Proxy},
+		{"bearer-token-file", profile.Auth.BearerTokenFile},
+		{"stdio", profile.Stdio},
+		{"args", strings.Join(profile.Args, ",")},
+		{"env", strings.Join(envPairs, ",")},
+		{"oauth-client-credentials", clientCredentials},
+		{"oauth-client-id", profile.Auth.OAuth.ClientID},
+		{"oauth-client-secret", profile.Auth.OAuth.ClientSecret},
+		{"oauth-token-url", profile.Auth.OAuth.TokenURL},
+		{"oauth-scopes", profile.Auth.OAuth.Scopes},
+		{"suppressions", profile.Suppressions},
+		{"fail-level", profile.FailLevel},
+	}
+	// A target given on the command line replaces the profile's target entirely
+	explicitTarget := explicit["url"] || explicit["stdio"]
+
+	for _, v := range values {
+		if v.value == "" || explicit[v.flag] {
+			continue
+		}
+		if explicitTarget && (v.flag == "url" || v.flag == "stdio") {
+			continue
+		}
+		if err := fs.Set(v.flag, v.value); err != nil {
+			return nil, fmt.Errorf("invalid profile value for %s: %w", v.flag, err)
+		}
+	}
+
+	headers := make(map[string]string)
+	for key, value := range profile.Headers {
+		headers[key] = value
+	}
+	if profile.Auth.BearerToken != "" {
+		headers["Authorization"] = "Bearer " + profile.Auth.BearerToken
+	}
+	return headers, nil
+}
+
+// mergeHeaders combines header maps; later maps take precedence
+func mergeHeaders(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for key, value := range m {
+			merged[http.CanonicalHeaderKey(key)] = value
+		}
+	}
+	return merged
+}
+
+// expandEnvVars replaces ${VAR} references with the value of the environment
+// variable. Referencing an unset variable is an error so that a missing secret
+// is not silently sent as an empty string. If escape is not nil it is applied
+// to each substituted value.
+func expandEnvVars(value string, escape func(string) string) (string, error) {
+	var missing []string
+	expanded := envVarPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envVarPattern.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+			return ref
+		}
+		if escape != nil {
+			return escape(v)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable not set: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// expandHeaderVars expands ${VAR} references in header values
+func expandHeaderVars(headers map[string]string) (map[string]string, error) {
+	expanded := make(map[string]string, len(headers))
+	for key, value := range headers {
+		v, err := expandEnvVars(value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", key, err)
+		}
+		expanded[key] = v
+	}
+	return expanded, nil
+}
+
+// expandParamVars expands ${VAR} references in a JSON parameter string.
+// Values are escaped so they are safe to use inside JSON strings.
+func expandParamVars(paramsJSON string) (string, error) {
+	return expandEnvVars(paramsJSON, func(v string) string {
+		quoted, _ := json.Marshal(v)
+		return string(quoted[1 : len(quoted)-1])
+	})
+}
+
+// readHeadersFile reads headers from a file with one "Key: Value" pair per
+// line. Blank lines and lines starting with # are ignored. ${VAR} references
+// are expanded later along with all other headers.
+func readHeadersFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open headers file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	headers := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := parseHeaderLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		headers[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read headers file: %w", err)
+	}
+	return headers, nil
+}
+
+// readBearerTokenFile reads a bearer token from a file, ignoring surrounding
+// whitespace such as a trailing newline
+func readBearerTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bearer token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file %s is empty", path)
+	}
+	if strings.ContainsAny(token, "\r\n") {
+		return "", fmt.Errorf("bearer token file %s must contain a single line", path)
+	}
+	return token, nil
+}
+
+// secretHeaderWords identify headers whose values are credentials
+var secretHeaderWords = []string{"auth", "token", "key", "secret", "password", "cookie", "session", "credential"}
+
+// redactHeaders returns a copy of the headers that is safe to print. Values of
+// credential headers are replaced, keeping only the scheme of an Authorization
+// header and any ${VAR} references, which name a secret without revealing it.
+func redactHeaders(headers map[string]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for key, value := range headers {
+		redacted[key] = value
+		if !isSecretHeader(key) {
+			continue
+		}
+		prefix, secret := "", value
+		if scheme, credentials, ok := strings.Cut(value, " "); ok && strings.HasSuffix(strings.ToLower(key), "authorization") {
+			prefix, secret = scheme+" ", credentials
+		}
+		if strings.TrimSpace(envVarPattern.ReplaceAllString(secret, "")) != "" {
+			redacted[key] = prefix + "<redacted>"
+		}
+	}
+	return redacted
+}
+
+// isSecretHeader reports whether a header name suggests a credential
+func isSecretHeader(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range secretHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	headers := map[string]string{
+		"Authorization":       "Bearer abc123",
+		"Proxy-Authorization": "Basic dXNlcjpwYXNz",
+		"X-Api-Key":           "k-123",
+		"X-Session-Token":     "${SESSION_TOKEN}",
+		"Cookie":              "sid=${SID}; theme=dark",
+		"X-Auth":              "",
+		"Accept":              "application/json",
+		"X-Request-Id":        "42",
+	}
+	want := map[string]string{
+		"Authorization":       "Bearer <redacted>",
+		"Proxy-Authorization": "Basic <redacted>",
+		"X-Api-Key":           "<redacted>",
+		"X-Session-Token":     "${SESSION_TOKEN}",
+		"Cookie":              "<redacted>",
+		"X-Auth":              "",
+		"Accept":              "application/json",
+		"X-Request-Id":        "42",
+	}
+	if got := redactHeaders(headers); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactHeaders:\n got  %v\n want %v", got, want)
+	}
+	if headers["Authorization"] != "Bearer abc123" {
+		t.Errorf("redactHeaders modified its input")
+	}
+}
@@ -0,0 +1,717 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// conformanceRequestBase is the first ID of the conformance requests, clear
+// of the IDs the client assigns and of the other raw requests
+const conformanceRequestBase = 7_000_000
+
+// conformanceCancelDelay is how long the in-flight cancellation check lets
+// the -call tool run before cancelling it
+const conformanceCancelDelay = 200 * time.Millisecond
+
+// Requirement levels of the conformance checks, as the specification words
+// them
+const (
+	levelMust   = "MUST"
+	levelShould = "SHOULD"
+)
+
+// Categories of the conformance checks, in the order they run
+const (
+	conformanceInit          = "initialization"
+	conformanceCapabilities  = "capability honesty"
+	conformancePagination    = "pagination"
+	conformanceErrors        = "error codes"
+	conformanceNotifications = "notifications"
+	conformanceCancellation  = "cancellation"
+	conformanceBatching      = "batching"
+)
+
+// conformanceResult is the outcome of one conformance check
+type conformanceResult struct {
+	ID       string        `json:"id"`
+	Category string        `json:"category"`
+	Level    string        `json:"level"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"durationNs"`
+	// Suppressed is set when a suppression accepts the check's finding
+	Suppressed bool `json:"suppressed,omitempty"`
+}
+
+// conformanceReport is the scored result of the conformance suite. The score
+// is the share of the executed checks that passed; skipped checks do not
+// count.
+type conformanceReport struct {
+	Score   int                 `json:"score"`
+	Passed  int                 `json:"passed"`
+	Failed  int                 `json:"failed"`
+	Skipped int                 `json:"skipped"`
+	Checks  []conformanceResult `json:"checks"`
+}
+
+// Executed returns the number of checks that ran
+func (r *conformanceReport) Executed() int {
+	return r.Passed + r.Failed
+}
+
+// conformanceOptions adjusts the conformance suite. A tool name enables the
+// in-flight cancellation check, which needs a call that takes a while, and a
+// raw wire the batching check, which sends bytes the client cannot.
+type conformanceOptions struct {
+	callTool string
+	callArgs map[string]any
+	openWire func() (negativeWire, error)
+}
+
+// conformanceCommandArgs turns "conformance [flags]" into the equivalent
+// -conformance flag, so that the suite runs with the probe's usual
+// connection options
+func conformanceCommandArgs(args []string) []string {
+	return append([]string{args[0], "-conformance"}, args[2:]...)
+}
+
+// conformanceSession is the raw session the conformance checks run on. Its
+// requests are sent raw so that the server's answers, errors included, are
+// seen as sent.
+type conformanceSession struct {
+	client  *client.Client
+	timeout time.Duration
+	ids     atomic.Int64
+	caps    map[string]json.RawMessage
+	version string
+}
+
+// request sends a request and returns the server's response. A transport
+// failure is an error; a JSON-RPC error is in the response.
+func (s *conformanceSession) request(method string, params any) (*transport.JSONRPCResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return s.send(ctx, s.nextID(), method, params)
+}
+
+// nextID returns the ID of the session's next request
+func (s *conformanceSession) nextID() mcp.RequestId {
+	return mcp.NewRequestId(conformanceRequestBase + s.ids.Add(1))
+}
+
+// send sends a request with the given ID
+func (s *conformanceSession) send(ctx context.Context, id mcp.RequestId, method string, params any) (*transport.JSONRPCResponse, error) {
+	request := transport.JSONRPCRequest{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Method: method}
+	if params != nil {
+		body, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the %s request: %w", method, err)
+		}
+		request.Params = json.RawMessage(body)
+	}
+	start := time.Now()
+	response, err := s.client.GetTransport().SendRequest(ctx, request)
+	report.addTiming(method, time.Since(start), err)
+	return response, err
+}
+
+// notify sends a notification
+func (s *conformanceSession) notify(method string, params map[string]any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	notification := mcp.JSONRPCNotification{
+		JSONRPC:      mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{Method: method},
+	}
+	notification.Params.AdditionalFields = params
+	return s.client.GetTransport().SendNotification(ctx, notification)
+}
+
+// ping returns an error unless the server answers a ping
+func (s *conformanceSession) ping() error {
+	response, err := s.request(string(mcp.MethodPing), nil)
+	if err != nil {
+		return err
+	}
+	return responseError(response)
+}
+
+// advertises reports whether the server advertised a capability
+func (s *conformanceSession) advertises(capability string) bool {
+	value, ok := s.caps[capability]
+	return ok && string(value) != "null"
+}
+
+// advertisesSubscribe reports whether the server advertised resource
+// subscriptions
+func (s *conformanceSession) advertisesSubscribe() bool {
+	var resources struct {
+		Subscribe bool `json:"subscribe"`
+	}
+	_ = json.Unmarshal(s.caps["resources"], &resources)
+	return resources.Subscribe
+}
+
+// capabilityProbe is the request that shows whether a capability works
+type capabilityProbe struct {
+	capability string
+	method     mcp.MCPMethod
+	params     any
+}
+
+// capabilityProbes are the requests of the capability honesty checks
+var capabilityProbes = []capabilityProbe{
+	{"tools", mcp.MethodToolsList, map[string]any{}},
+	{"resources", mcp.MethodResourcesList, map[string]any{}},
+	{"prompts", mcp.MethodPromptsList, map[string]any{}},
+	{"logging", mcp.MethodSetLogLevel, map[string]any{"level": "info"}},
+}
+
+// conformanceLists are the list methods the pagination checks follow
+var conformanceLists = []struct {
+	capability string
+	method     mcp.MCPMethod
+	itemsKey   string
+	idKey      string
+}{
+	{"tools", mcp.MethodToolsList, "tools", "name"},
+	{"resources", mcp.MethodResourcesList, "resources", "uri"},
+	{"prompts", mcp.MethodPromptsList, "prompts", "name"},
+}
+
+// conformanceCheck is a check of the suite. It returns the check's status
+// and what was seen.
+type conformanceCheck struct {
+	id       string
+	category string
+	level    string
+	run      func(s *conformanceSession) (string, string)
+}
+
+// conformanceChecks are the checks that run on the initialized session,
+// after the initialization checks
+func conformanceChecks(opts conformanceOptions) []conformanceCheck {
+	return []conformanceCheck{
+		{"capabilities.advertised-work", conformanceCapabilities, levelMust, checkAdvertisedWork},
+		{"capabilities.unadvertised-refused", conformanceCapabilities, levelShould, checkUnadvertisedRefused},
+		{"pagination.cursors", conformancePagination, levelShould, checkPaginationCursors},
+		{"pagination.invalid-cursor", conformancePagination, levelShould, checkInvalidCursor},
+		{"errors.method-not-found", conformanceErrors, levelMust, checkMethodNotFound},
+		{"errors.unknown-tool", conformanceErrors, levelShould, checkUnknownTool},
+		{"errors.unknown-resource", conformanceErrors, levelShould, checkUnknownResource},
+		{"errors.unknown-prompt", conformanceErrors, levelShould, checkUnknownPrompt},
+		{"notifications.unknown-ignored", conformanceNotifications, levelMust, checkUnknownNotification},
+		{"cancellation.unknown-request", conformanceCancellation, levelMust, checkCancelUnknown},
+		{"cancellation.in-flight", conformanceCancellation, levelShould, func(s *conformanceSession) (string, string) {
+			return checkCancelInFlight(s, opts)
+		}},
+		{"batching.requests", conformanceBatching, levelMust, func(s *conformanceSession) (string, string) {
+			return checkBatching(s, opts)
+		}},
+	}
+}
+
+// runConformance runs the conformance suite against a fresh session, prints
+// the result of each check and the score, and returns an error if a failed
+// check's finding counts as an error
+func runConformance(dial func(ctx context.Context) (*client.Client, error), opts conformanceOptions, timeout time.Duration) error {
+	fmt.Println("=== Conformance ===")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	mcpClient, err := dial(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() { _ = mcpClient.Close() }()
+	session := &conformanceSession{client: mcpClient, timeout: timeout}
+
+	var results []conformanceResult
+	var failed []string
+	category := ""
+	record := func(id, cat, level string, start time.Time, status, detail string) {
+		if cat != category {
+			category = cat
+			fmt.Printf("\n%s%s\n", strings.ToUpper(cat[:1]), cat[1:])
+		}
+		result := conformanceResult{ID: id, Category: cat, Level: level, Status: status, Detail: detail, Duration: time.Since(start)}
+		line := fmt.Sprintf("  %-4s  %-6s  %-34s  %s", strings.ToUpper(status), level, id, detail)
+		fmt.Println(strings.TrimRight(line, " "))
+		if status == checkFail {
+			checkID := checkIDConformanceMust
+			if level == levelShould {
+				checkID = checkIDConformanceShould
+			}
+			f := report.addFinding(checkID, id, "%s: %s", id, detail)
+			result.Suppressed = f.Suppressed
+			fmt.Printf("        %s\n", f)
+			if f.fails() {
+				failed = append(failed, id)
+			}
+		}
+		results = append(results, result)
+		emitEvent(eventCheck, map[string]any{
+			"id":         id,
+			"category":   cat,
+			"level":      level,
+			"status":     status,
+			"detail":     detail,
+			"durationMs": durationMillis(result.Duration),
+		})
+	}
+
+	// Initialization: the handshake of the session the other checks use
+	start := time.Now()
+	initResult, initErr := initializeConformanceSession(session)
+	for _, check := range initializationChecks(initResult, initErr) {
+		record(check.id, conformanceInit, levelMust, start, check.status, check.detail)
+		start = time.Now()
+	}
+	entry := initializeWithVersion(dial, futureProtocolVersion, timeout)
+	if entry.HardFailure() {
+		record("init.unknown-version", conformanceInit, levelMust, start, checkFail, entry.Detail)
+	} else {
+		record("init.unknown-version", conformanceInit, levelMust, start, checkPass, fmt.Sprintf("%s answered with %s", futureProtocolVersion, entry.Answered))
+	}
+
+	start = time.Now()
+	if initErr == nil {
+		if err := session.ping(); err != nil {
+			record("init.ping", conformanceInit, levelMust, start, checkFail, fmt.Sprintf("ping after initialization failed: %v", err))
+		} else {
+			record("init.ping", conformanceInit, levelMust, start, checkPass, "answered")
+		}
+	} else {
+		record("init.ping", conformanceInit, levelMust, start, checkSkip, "the session did not initialize")
+	}
+
+	for _, check := range conformanceChecks(opts) {
+		start := time.Now()
+		if initErr != nil {
+			record(check.id, check.category, check.level, start, checkSkip, "the session did not initialize")
+			continue
+		}
+		status, detail := check.run(session)
+		record(check.id, check.category, check.level, start, status, detail)
+	}
+
+	score := scoreConformance(results)
+	report.setConformance(score)
+	fmt.Printf("\nScore: %d%% (%d of %d executed checks passed", score.Score, score.Passed, score.Executed())
+	if score.Skipped > 0 {
+		fmt.Printf(", %d skipped", score.Skipped)
+	}
+	fmt.Println(")")
+	for _, cat := range []string{conformanceInit, conformanceCapabilities, conformancePagination, conformanceErrors, conformanceNotifications, conformanceCancellation, conformanceBatching} {
+		passed, executed := 0, 0
+		for _, r := range results {
+			if r.Category != cat || r.Status == checkSkip {
+				continue
+			}
+			executed++
+			if r.Status == checkPass {
+				passed++
+			}
+		}
+		if executed > 0 {
+			fmt.Printf("  %-20s %d/%d\n", cat, passed, executed)
+		} else {
+			fmt.Printf("  %-20s skipped\n", cat)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("conformance checks failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// scoreConformance totals the results of the suite
+func scoreConformance(results []conformanceResult) *conformanceReport {
+	score := &conformanceReport{Checks: results}
+	for _, r := range results {
+		switch r.Status {
+		case checkPass:
+			score.Passed++
+		case checkFail:
+			score.Failed++
+		default:
+			score.Skipped++
+		}
+	}
+	if score.Executed() > 0 {
+		score.Score = score.Passed * 100 / score.Executed()
+	}
+	return score
+}
+
+// initializeConformanceSession sends the initialize request raw, so that
+// the result is checked as sent, then completes the handshake. It returns
+// the raw result.
+func initializeConformanceSession(s *conformanceSession) (map[string]json.RawMessage, error) {
+	response, err := s.request(string(mcp.MethodInitialize), newInitializeRequest().Params)
+	if err != nil {
+		return nil, err
+	}
+	if err := responseError(response); err != nil {
+		return nil, err
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("the result is not an object: %w", err)
+	}
+	_ = json.Unmarshal(result["capabilities"], &s.caps)
+
+	var version string
+	_ = json.Unmarshal(result["protocolVersion"], &version)
+	s.version = version
+	if httpConn, ok := s.client.GetTransport().(transport.HTTPConnection); ok && version != "" {
+		httpConn.SetProtocolVersion(version)
+	}
+	if err := s.notify("notifications/initialized", nil); err != nil {
+		return result, fmt.Errorf("failed to send notifications/initialized: %w", err)
+	}
+	return result, nil
+}
+
+// initializationCheck is the outcome of a check of the initialize result
+type initializationCheck struct {
+	id, status, detail string
+}
+
+// initializationChecks checks the initialize result: the negotiated version,
+// the server's identity and the capabilities
+func initializationChecks(result map[string]json.RawMessage, err error) []initializationCheck {
+	if err != nil {
+		detail := fmt.Sprintf("initialization failed: %v", err)
+		return []initializationCheck{
+			{"init.protocol-version", checkFail, detail},
+			{"init.server-info", checkSkip, "the session did not initialize"},
+			{"init.capabilities", checkSkip, "the session did not initialize"},
+		}
+	}
+
+	var checks []initializationCheck
+	var version string
+	_ = json.Unmarshal(result["protocolVersion"], &version)
+	switch {
+	case version == "":
+		checks = append(checks, initializationCheck{"init.protocol-version", checkFail, "the result has no protocolVersion"})
+	case !slices.Contains(mcp.ValidProtocolVersions, version):
+		checks = append(checks, initializationCheck{"init.protocol-version", checkFail, fmt.Sprintf("answered %s, which is not a protocol version", version)})
+	case version > requestedProtocolVersion:
+		checks = append(checks, initializationCheck{"init.protocol-version", checkFail, fmt.Sprintf("answered %s, newer than the requested %s", version, requestedProtocolVersion)})
+	case version != requestedProtocolVersion:
+		checks = append(checks, initializationCheck{"init.protocol-version", checkPass, fmt.Sprintf("negotiated %s for the requested %s", version, requestedProtocolVersion)})
+	default:
+		checks = append(checks, initializationCheck{"init.protocol-version", checkPass, "answered " + version})
+	}
+
+	var info struct {
+		Name    *string `json:"name"`
+		Version *string `json:"version"`
+	}
+	var missing []string
+	if json.Unmarshal(result["serverInfo"], &info) != nil {
+		missing = append(missing, "serverInfo")
+	} else {
+		if info.Name == nil || *info.Name == "" {
+			missing = append(missing, "serverInfo.name")
+		}
+		if info.Version == nil || *info.Version == "" {
+			missing = append(missing, "serverInfo.version")
+		}
+	}
+	if len(missing) > 0 {
+		checks = append(checks, initializationCheck{"init.server-info", checkFail, "missing " + strings.Join(missing, " and ")})
+	} else {
+		checks = append(checks, initializationCheck{"init.server-info", checkPass, fmt.Sprintf("%s %s", *info.Name, *info.Version)})
+	}
+
+	var caps map[string]json.RawMessage
+	if raw, ok := result["capabilities"]; !ok || json.Unmarshal(raw, &caps) != nil || caps == nil {
+		checks = append(checks, initializationCheck{"init.capabilities", checkFail, "capabilities is missing or not an object"})
+	} else {
+		names := make([]string, 0, len(caps))
+		for name := range caps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		checks = append(checks, initializationCheck{"init.capabilities", checkPass, "advertises " + valueOr(strings.Join(names, ", "), "nothing")})
+	}
+	return checks
+}
+
+// responseError returns the JSON-RPC error of a response as an error, or nil
+func responseError(response *transport.JSONRPCResponse) error {
+	if response.Error != nil {
+		return &rpcError{Code: response.Error.Code, Message: response.Error.Message}
+	}
+	return nil
+}
+
+// checkAdvertisedWork checks that the endpoints of the advertised
+// capabilities answer
+func checkAdvertisedWork(s *conformanceSession) (string, string) {
+	var works, broken []string
+	probe := func(method mcp.MCPMethod, params any) {
+		response, err := s.request(string(method), params)
+		if err == nil {
+			err = responseError(response)
+		}
+		if err != nil {
+			broken = append(broken, fmt.Sprintf("%s: %v", method, err))
+		} else {
+			works = append(works, string(method))
+		}
+	}
+	for _, p := range capabilityProbes {
+		if s.advertises(p.capability) {
+			probe(p.method, p.params)
+		}
+	}
+	if s.advertises("resources") {
+		if uri := s.firstResourceURI(); uri != "" {
+			probe(mcp.MethodResourcesRead, map[string]any{"uri": uri})
+			if s.advertisesSubscribe() {
+				probe("resources/subscribe", map[string]any{"uri": uri})
+				probe("resources/unsubscribe", map[string]any{"uri": uri})
+			}
+		}
+	}
+	switch {
+	case len(broken) > 0:
+		return checkFail, strings.Join(broken, "; ")
+	case len(works) == 0:
+		return checkSkip, "no capabilities with endpoints to try are advertised"
+	}
+	return checkPass, strings.Join(works, ", ") + " answered"
+}
+
+// firstResourceURI returns the URI of the first listed resource, or ""
+func (s *conformanceSession) firstResourceURI() string {
+	response, err := s.request(string(mcp.MethodResourcesList), map[string]any{})
+	if err != nil || response.Error != nil {
+		return ""
+	}
+	var result struct {
+		Resources []struct {
+			URI string `json:"uri"`
+		} `json:"resources"`
+	}
+	if json.Unmarshal(response.Result, &result) != nil || len(result.Resources) == 0 {
+		return ""
+	}
+	return result.Resources[0].URI
+}
+
+// checkUnadvertisedRefused checks that the server refuses the methods of
+// capabilities it did not advertise, so that clients can trust the
+// capabilities it did
+func checkUnadvertisedRefused(s *conformanceSession) (string, string) {
+	var refused, answered []string
+	for _, p := range capabilityProbes {
+		if s.advertises(p.capability) {
+			continue
+		}
+		response, err := s.request(string(p.method), p.params)
+		switch {
+		case err != nil, response.Error != nil:
+			refused = append(refused, string(p.method))
+		default:
+			answered = append(answered, fmt.Sprintf("answers %s without advertising %s", p.method, p.capability))
+		}
+	}
+	switch {
+	case len(answered) > 0:
+		return checkFail, strings.Join(answered, "; ")
+	case len(refused) == 0:
+		return checkSkip, "every capability with an endpoint to try is advertised"
+	}
+	return checkPass, strings.Join(refused, ", ") + " refused"
+}
+
+// checkPaginationCursors follows the cursors of every advertised list
+func checkPaginationCursors(s *conformanceSession) (string, string) {
+	var listed, problems []string
+	for _, l := range conformanceLists {
+		if !s.advertises(l.capability) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		list, err := followPages(ctx, s.client, l.method, l.itemsKey, l.idKey)
+		cancel()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", l.method, err))
+			continue
+		}
+		for _, p := range list.problems {
+			problems = append(problems, fmt.Sprintf("%s: %s", l.method, p))
+		}
+		listed = append(listed, fmt.Sprintf("%s %d item(s) in %d page(s)", l.method, len(list.items), list.pages))
+	}
+	switch {
+	case len(problems) > 0:
+		return checkFail, strings.Join(problems, "; ")
+	case len(listed) == 0:
+		return checkSkip, "no lists are advertised"
+	}
+	return checkPass, strings.Join(listed, ", ")
+}
+
+// checkInvalidCursor checks that a list request with a cursor the server
+// never issued is answered with invalid params
+func checkInvalidCursor(s *conformanceSession) (string, string) {
+	for _, l := range conformanceLists {
+		if !s.advertises(l.capability) {
+			continue
+		}
+		response, err := s.request(string(l.method), map[string]any{"cursor": "mcpprobe-invalid-cursor"})
+		switch {
+		case err != nil:
+			return checkFail, fmt.Sprintf("%s: %v", l.method, err)
+		case response.Error == nil:
+			return checkFail, fmt.Sprintf("%s accepted a cursor it never issued; the specification asks for error %d", l.method, mcp.INVALID_PARAMS)
+		case response.Error.Code != mcp.INVALID_PARAMS:
+			return checkFail, fmt.Sprintf("%s answered error %d; the specification asks for %d", l.method, response.Error.Code, mcp.INVALID_PARAMS)
+		}
+		return checkPass, fmt.Sprintf("%s answered error %d", l.method, mcp.INVALID_PARAMS)
+	}
+	return checkSkip, "no lists are advertised"
+}
+
+// checkErrorCode checks that a request is answered with the expected
+// JSON-RPC error code
+func checkErrorCode(s *conformanceSession, method string, params any, want int) (string, string) {
+	response, err := s.request(method, params)
+	switch {
+	case err != nil:
+		return checkFail, err.Error()
+	case response.Error == nil:
+		var result struct {
+			IsError bool `json:"isError"`
+		}
+		if json.Unmarshal(response.Result, &result) == nil && result.IsError {
+			return checkFail, fmt.Sprintf("answered with a tool result with isError; the specification asks for error %d", want)
+		}
+		return checkFail, fmt.Sprintf("answered with a result; the specification asks for error %d", want)
+	case response.Error.Code != want:
+		return checkFail, fmt.Sprintf("answered error %d (%s); the specification asks for %d", response.Error.Code, response.Error.Message, want)
+	}
+	return checkPass, fmt.Sprintf("answered error %d", want)
+}
+
+// checkMethodNotFound checks the answer to a method no server implements
+func checkMethodNotFound(s *conformanceSession) (string, string) {
+	return checkErrorCode(s, "mcpprobe/no-such-method", map[string]any{}, mcp.METHOD_NOT_FOUND)
+}
+
+// checkUnknownTool checks the answer to a call of a tool that does not exist
+func checkUnknownTool(s *conformanceSession) (string, string) {
+	if !s.advertises("tools") {
+		return checkSkip, "tools are not advertised"
+	}
+	return checkErrorCode(s, string(mcp.MethodToolsCall), map[string]any{"name": "mcpprobe-no-such-tool", "arguments": map[string]any{}}, mcp.INVALID_PARAMS)
+}
+
+// checkUnknownResource checks the answer to a read of a resource that does
+// not exist
+func checkUnknownResource(s *conformanceSession) (string, string) {
+	if !s.advertises("resources") {
+		return checkSkip, "resources are not advertised"
+	}
+	return checkErrorCode(s, string(mcp.MethodResourcesRead), map[string]any{"uri": "mcpprobe://no-such-resource"}, mcp.RESOURCE_NOT_FOUND)
+}
+
+// checkUnknownPrompt checks the answer to a get of a prompt that does not
+// exist
+func checkUnknownPrompt(s *conformanceSession) (string, string) {
+	if !s.advertises("prompts") {
+		return checkSkip, "prompts are not advertised"
+	}
+	return checkErrorCode(s, string(mcp.MethodPromptsGet), map[string]any{"name": "mcpprobe-no-such-prompt"}, mcp.INVALID_PARAMS)
+}
+
+// checkUnknownNotification checks that the server ignores a notification
+// it does not know and keeps answering
+func checkUnknownNotification(s *conformanceSession) (string, string) {
+	if err := s.notify("notifications/mcpprobe/unknown", map[string]any{"probe": true}); err != nil {
+		return checkFail, fmt.Sprintf("the notification was refused: %v", err)
+	}
+	if err := s.ping(); err != nil {
+		return checkFail, fmt.Sprintf("ping after the notification failed: %v", err)
+	}
+	return checkPass, "ignored; ping still answered"
+}
+
+// checkCancelUnknown checks that the server ignores a cancellation of a
+// request it never received and keeps answering
+func checkCancelUnknown(s *conformanceSession) (string, string) {
+	if err := sendCancelled(s.client, s.nextID(), "conformance check"); err != nil {
+		return checkFail, fmt.Sprintf("the cancellation was refused: %v", err)
+	}
+	if err := s.ping(); err != nil {
+		return checkFail, fmt.Sprintf("ping after the cancellation failed: %v", err)
+	}
+	return checkPass, "ignored; ping still answered"
+}
+
+// checkCancelInFlight calls the -call tool, cancels the call while it runs
+// and checks that the server stops it and keeps answering
+func checkCancelInFlight(s *conformanceSession, opts conformanceOptions) (string, string) {
+	if opts.callTool == "" {
+		return checkSkip, "needs a tool that takes a while to answer (-call)"
+	}
+	id := s.nextID()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan callReply, 1)
+	go func() {
+		response, err := s.send(ctx, id, string(mcp.MethodToolsCall), map[string]any{"name": opts.callTool, "arguments": opts.callArgs})
+		done <- callReply{response, err}
+	}()
+
+	select {
+	case reply := <-done:
+		if reply.err == nil && reply.response.Error != nil {
+			reply.err = responseError(reply.response)
+		}
+		if reply.err != nil {
+			return checkSkip, fmt.Sprintf("the call of '%s' failed before it could be cancelled: %v", opts.callTool, reply.err)
+		}
+		return checkSkip, fmt.Sprintf("the call of '%s' finished within %s, before it could be cancelled", opts.callTool, conformanceCancelDelay)
+	case <-time.After(conformanceCancelDelay):
+	}
+
+	if err := sendCancelled(s.client, id, "conformance check"); err != nil {
+		return checkFail, fmt.Sprintf("the cancellation was refused: %v", err)
+	}
+	status, detail := checkPass, "the server stopped the call"
+	select {
+	case reply := <-done:
+		if reply.err == nil && reply.response.Error == nil {
+			status, detail = checkFail, fmt.Sprintf("the server completed the call of '%s' after %s", opts.callTool, cancelledMethod)
+		}
+	case <-time.After(cancelGrace):
+		detail = fmt.Sprintf("the server dropped the call without answering within %s", humanDuration(cancelGrace))
+	}
+	cancel()
+
+	if err := s.ping(); err != nil {
+		return checkFail, fmt.Sprintf("ping after the cancellation failed: %v", err)
+	}
+	return status, detail + "; ping still answered"
+}
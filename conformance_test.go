@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// verdictLine matches the line of a check in the probe's output: its status,
+// the requirement level if any, and its ID
+var verdictLine = regexp.MustCompile(`(?m)^  (PASS|FAIL|WARN|SKIP)  (?:(?:MUST|SHOULD) +)?(\S+)`)
+
+// verdicts returns the status of each check in the probe's output
+func verdicts(output string) map[string]string {
+	statuses := map[string]string{}
+	for _, m := range verdictLine.FindAllStringSubmatch(output, -1) {
+		statuses[m[2]] = m[1]
+	}
+	return statuses
+}
+
+// checkVerdicts compares the checks in the probe's output with the expected
+// statuses. Every check with a status in failing must be listed in want.
+func checkVerdicts(t *testing.T, output string, want map[string]string, failing ...string) {
+	t.Helper()
+	got := verdicts(output)
+	if len(got) == 0 {
+		t.Fatalf("no checks in the output:\n%s", output)
+	}
+	for id, status := range want {
+		if got[id] != status {
+			t.Errorf("%s = %q, want %s", id, got[id], status)
+		}
+	}
+	for id, status := range got {
+		if _, ok := want[id]; !ok && slices.Contains(failing, status) {
+			t.Errorf("%s = %s, want it to pass", id, status)
+		}
+	}
+	if t.Failed() {
+		t.Logf("output:\n%s", output)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	suppressions := filepath.Join(t.TempDir(), "suppressions.yaml")
+	if err := os.WriteFile(suppressions, []byte("suppressions:\n  - check: C025\n    subject: batching.requests\n    reason: the mock server does not batch\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	answerWithResult := interceptMethod("mcpprobe/no-such-method", func(w http.ResponseWriter, id json.RawMessage) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{}}`, id)
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		wrap     func(http.Handler) http.Handler
+		exitCode int
+		want     map[string]string
+		output   string
+	}{
+		{
+			name: "compliant",
+			want: map[string]string{
+				"init.unknown-version":   "PASS",
+				"errors.unknown-tool":    "PASS",
+				"cancellation.in-flight": "SKIP",
+				"batching.requests":      "SKIP",
+			},
+			output: "Score: 100% (15 of 15 executed checks passed, 2 skipped)",
+		},
+		{
+			// The mock server finishes the call; a SHOULD is only a warning
+			name:   "cancellation ignored",
+			args:   []string{"-call", "slow"},
+			want:   map[string]string{"cancellation.in-flight": "FAIL", "batching.requests": "SKIP"},
+			output: "[C026 warning] cancellation.in-flight",
+		},
+		{
+			name:     "cancellation ignored at -fail-level warning",
+			args:     []string{"-call", "slow", "-fail-level", "warning"},
+			exitCode: 1,
+			want:     map[string]string{"cancellation.in-flight": "FAIL", "batching.requests": "SKIP"},
+			output:   "conformance checks failed: cancellation.in-flight",
+		},
+		{
+			name:     "batch refused",
+			args:     []string{"-protocol-version", "2025-03-26"},
+			exitCode: 1,
+			want:     map[string]string{"cancellation.in-flight": "SKIP", "batching.requests": "FAIL"},
+			output:   "conformance checks failed: batching.requests",
+		},
+		{
+			name:   "batch refused and suppressed",
+			args:   []string{"-protocol-version", "2025-03-26", "-suppressions", suppressions},
+			want:   map[string]string{"cancellation.in-flight": "SKIP", "batching.requests": "FAIL"},
+			output: "(suppressed: the mock server does not batch)",
+		},
+		{
+			name:     "unknown method answered",
+			wrap:     answerWithResult,
+			exitCode: 1,
+			want: map[string]string{
+				"errors.method-not-found": "FAIL",
+				"cancellation.in-flight":  "SKIP",
+				"batching.requests":       "SKIP",
+			},
+			output: "conformance checks failed: errors.method-not-found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverURL := serveMock(t, defaultMockConfig, tt.wrap)
+			code, output := runProbe(t, append([]string{"-url", serverURL, "-conformance"}, tt.args...)...)
+			if code != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", code, tt.exitCode)
+			}
+			if !strings.Contains(output, tt.output) {
+				t.Errorf("the output does not contain %q", tt.output)
+			}
+			checkVerdicts(t, output, tt.want, "FAIL")
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// connTraceMinRequests is the number of requests after which a run where
+// every request opened a new connection is pointed out
+const connTraceMinRequests = 3
+
+// connTracing is set by -debug: each HTTP request is annotated with the
+// connection it used
+var connTracing bool
+
+// connTraceStats counts the connections used by the HTTP requests of the run
+var connTraceStats struct {
+	sync.Mutex
+	requests int
+	newConns int
+	reused   int
+	closed   int
+}
+
+// enableConnTracing annotates each HTTP request of the run with whether it
+// opened a new connection or reused one, and prints how the connections were
+// used when the run ends
+func enableConnTracing() {
+	connTracing = true
+	addExitHook(printConnTraceSummary)
+}
+
+// connTrace records the connection events of one HTTP request
+type connTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dns          time.Duration
+	addrs        []string
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	tls          time.Duration
+	tlsState     *tls.ConnectionState
+	gotConn      httptrace.GotConnInfo
+	firstByte    time.Duration
+}
+
+// clientTrace returns the hooks that fill in the trace
+func (t *connTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+			for _, addr := range info.Addrs {
+				t.addrs = append(t.addrs, addr.String())
+			}
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.tls = time.Since(t.tlsStart)
+				t.tlsState = &state
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = info
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Since(t.start)
+		},
+	}
+}
+
+// connTraceTransport prints the connection each HTTP request used: a new
+// one, with the time spent on DNS, connecting and the TLS handshake, or a
+// reused one, with how long it was idle
+type connTraceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *connTraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &connTrace{start: time.Now()}
+	label := req.Method + " " + req.URL.Path
+	if method := jsonRPCMethod(req); method != "" {
+		label += " (" + method + ")"
+	}
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace())))
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	if trace.gotConn.Conn == nil {
+		fmt.Printf("[DEBUG CONN] %s: no connection: %v\n", label, err)
+		return resp, err
+	}
+
+	var parts []string
+	remote := trace.gotConn.Conn.RemoteAddr().String()
+	if trace.gotConn.Reused {
+		line := "reused connection to " + remote
+		if trace.gotConn.WasIdle {
+			line += fmt.Sprintf(" (idle %s)", humanDuration(trace.gotConn.IdleTime))
+		}
+		parts = append(parts, line)
+	} else {
+		parts = append(parts, "new connection to "+remote)
+		if !trace.dnsStart.IsZero() {
+			parts = append(parts, fmt.Sprintf("dns %s (%s)", humanDuration(trace.dns), strings.Join(trace.addrs, ", ")))
+		}
+		if trace.connect > 0 {
+			parts = append(parts, "connect "+humanDuration(trace.connect))
+		}
+		if trace.tlsState != nil {
+			resumed := "full handshake"
+			if trace.tlsState.DidResume {
+				resumed = "resumed session"
+			}
+			parts = append(parts, fmt.Sprintf("tls %s (%s)", humanDuration(trace.tls), resumed))
+		}
+	}
+	if trace.firstByte > 0 {
+		parts = append(parts, "first byte "+humanDuration(trace.firstByte))
+	}
+	if err != nil {
+		parts = append(parts, fmt.Sprintf("failed: %v", err))
+	} else if resp.Close {
+		parts = append(parts, "server closes the connection")
+	}
+	fmt.Printf("[DEBUG CONN] %s: %s\n", label, strings.Join(parts, ", "))
+
+	connTraceStats.Lock()
+	connTraceStats.requests++
+	if trace.gotConn.Reused {
+		connTraceStats.reused++
+	} else {
+		connTraceStats.newConns++
+	}
+	if err == nil && resp.Close {
+		connTraceStats.closed++
+	}
+	connTraceStats.Unlock()
+	return resp, err
+}
+
+// jsonRPCMethod returns the method of the JSON-RPC message a request posts,
+// if its body can be read again
+func jsonRPCMethod(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = body.Close() }()
+	var message struct {
+		Method string `json:"method"`
+	}
+	data, err := io.ReadAll(body)
+	if err != nil || json.Unmarshal(data, &message) != nil {
+		return ""
+	}
+	return message.Method
+}
+
+// printConnTraceSummary prints how the run's HTTP requests used connections,
+// pointing out runs where no connection was reused
+func printConnTraceSummary() {
+	connTraceStats.Lock()
+	defer connTraceStats.Unlock()
+	if connTraceStats.requests == 0 {
+		return
+	}
+	fmt.Printf("[DEBUG CONN] %d HTTP request%s: %d new connection%s, %d reused\n",
+		connTraceStats.requests, pluralS(connTraceStats.requests), connTraceStats.newConns, pluralS(connTraceStats.newConns), connTraceStats.reused)
+	if connTraceStats.requests >= connTraceMinRequests && connTraceStats.reused == 0 {
+		reason := "check for a proxy or load balancer that closes idle connections"
+		if connTraceStats.closed > 0 {
+			reason = fmt.Sprintf("the server closed the connection after %d response%s (Connection: close)", connTraceStats.closed, pluralS(connTraceStats.closed))
+		}
+		fmt.Printf("[DEBUG CONN] Every request opened a new connection, adding connect and TLS time to each: %s\n", reason)
+	}
+}
@@ -0,0 +1,478 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// contract is the subset of a server's tools, resources and prompts that a
+// consumer (such as an agent) depends on. Verifying it against a live server
+// shows whether a server change would break that consumer.
+type contract struct {
+	Consumer  string             `yaml:"consumer,omitempty"`
+	Provider  string             `yaml:"provider,omitempty"`
+	Tools     []contractTool     `yaml:"tools,omitempty"`
+	Resources []contractResource `yaml:"resources,omitempty"`
+	Prompts   []contractPrompt   `yaml:"prompts,omitempty"`
+}
+
+// contractTool is a tool the consumer calls, with the parameters it sends
+// (name to JSON Schema type; an empty type accepts any) and an optional
+// sample call whose result must have the shape the consumer reads
+type contractTool struct {
+	Name   string            `yaml:"name"`
+	Params map[string]string `yaml:"params,omitempty"`
+	Call   *contractCall     `yaml:"call,omitempty"`
+}
+
+// contractCall is a sample tool call and the parts of its result the consumer
+// reads: the content types and the JSON types of structuredContent fields
+type contractCall struct {
+	Arguments  map[string]any    `yaml:"arguments,omitempty"`
+	IsError    bool              `yaml:"is_error,omitempty"`
+	Content    []string          `yaml:"content,omitempty"`
+	Structured map[string]string `yaml:"structured,omitempty"`
+}
+
+// contractResource is a resource or resource template the consumer uses
+type contractResource struct {
+	URI         string `yaml:"uri,omitempty"`
+	URITemplate string `yaml:"uri_template,omitempty"`
+	MIMEType    string `yaml:"mime_type,omitempty"`
+	Read        bool   `yaml:"read,omitempty"`
+}
+
+// contractPrompt is a prompt the consumer gets, with the arguments it sends
+type contractPrompt struct {
+	Name      string   `yaml:"name"`
+	Arguments []string `yaml:"arguments,omitempty"`
+}
+
+// schemaTypes are the JSON Schema types a contract parameter can declare
+var schemaTypes = []string{"string", "number", "integer", "boolean", "object", "array", "null"}
+
+// contractCommandArgs turns "verify-contract <file> [flags]" into the
+// equivalent -verify-contract flag, so that the contract is verified with
+// the probe's usual connection options
+func contractCommandArgs(args []string) ([]string, error) {
+	if len(args) < 3 || strings.HasPrefix(args[2], "-") {
+		return nil, fmt.Errorf("usage: probe verify-contract <contract.yaml> -url <server-url> [options]")
+	}
+	return append([]string{args[0], "-verify-contract", args[2]}, args[3:]...), nil
+}
+
+// loadContract reads and validates a contract file
+func loadContract(path string) (*contract, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract: %w", err)
+	}
+	var c contract
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(c.Tools)+len(c.Resources)+len(c.Prompts) == 0 {
+		return nil, fmt.Errorf("%s lists no tools, resources or prompts", path)
+	}
+	for i, tool := range c.Tools {
+		if tool.Name == "" {
+			return nil, fmt.Errorf("tool %d has no name", i+1)
+		}
+		for param, typ := range tool.Params {
+			if typ != "" && !slices.Contains(schemaTypes, typ) {
+				return nil, fmt.Errorf("tool '%s': unknown type '%s' for parameter '%s'", tool.Name, typ, param)
+			}
+		}
+		if tool.Call != nil {
+			for path, typ := range tool.Call.Structured {
+				if !slices.Contains(schemaTypes, typ) || typ == "integer" {
+					return nil, fmt.Errorf("tool '%s': unknown type '%s' for structured field '%s'", tool.Name, typ, path)
+				}
+			}
+		}
+	}
+	for i, res := range c.Resources {
+		if (res.URI == "") == (res.URITemplate == "") {
+			return nil, fmt.Errorf("resource %d needs either uri or uri_template", i+1)
+		}
+		if res.Read && res.URITemplate != "" {
+			return nil, fmt.Errorf("resource template '%s' cannot be read; use a uri", res.URITemplate)
+		}
+	}
+	for i, prompt := range c.Prompts {
+		if prompt.Name == "" {
+			return nil, fmt.Errorf("prompt %d has no name", i+1)
+		}
+	}
+	return &c, nil
+}
+
+// runContractVerification checks that a server satisfies a contract and
+// prints the result for each tool, resource and prompt. It returns an error
+// if the contract is violated.
+func runContractVerification(dial func(ctx context.Context) (*client.Client, error), c *contract, source string, timeout, callTimeout time.Duration) error {
+	title := source
+	if c.Consumer != "" {
+		title = fmt.Sprintf("%s (consumer: %s)", source, c.Consumer)
+	}
+	fmt.Printf("=== Contract %s ===\n", title)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	mcpClient, err := dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() { _ = mcpClient.Close() }()
+	initResult, err := mcpClient.Initialize(ctx, newInitializeRequest())
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	report.setInitResult(initResult)
+	if c.Provider != "" && c.Provider != initResult.ServerInfo.Name {
+		fmt.Printf("Note: contract is for provider '%s', server is '%s'\n", c.Provider, initResult.ServerInfo.Name)
+	}
+	fmt.Println()
+
+	var summaries []checkSummary
+	violated, suppressed := 0, 0
+	record := func(id string, start time.Time, problems []string) {
+		summary := checkSummary{ID: id, Runs: 1, AvgTime: time.Since(start)}
+		if len(problems) > 0 {
+			summary.Status, summary.Failed, summary.Errors = checkFail, 1, problems
+		} else {
+			summary.Status, summary.Passed = checkPass, 1
+		}
+		summaries = append(summaries, summary)
+		emitEvent(eventCheck, map[string]any{
+			"id":            id,
+			"status":        summary.Status,
+			"avgDurationMs": durationMillis(summary.AvgTime),
+			"errors":        summary.Errors,
+		})
+		fmt.Printf("  %-5s  %s\n", strings.ToUpper(summary.Status), id)
+		if len(problems) == 0 {
+			return
+		}
+		f := report.addFinding(checkIDContract, id, "%s does not meet the contract", id)
+		summaries[len(summaries)-1].Suppressed = f.Suppressed
+		if f.Suppressed {
+			suppressed++
+		} else {
+			violated++
+		}
+		fmt.Printf("         %s\n", f)
+		for _, p := range problems {
+			fmt.Printf("         %s\n", p)
+		}
+	}
+
+	if len(c.Tools) > 0 {
+		start := time.Now()
+		tools, err := contractTools(ctx, mcpClient, initResult.Capabilities)
+		for _, want := range c.Tools {
+			if err != nil {
+				record("tool "+want.Name, start, []string{err.Error()})
+				continue
+			}
+			record("tool "+want.Name, start, checkContractTool(mcpClient, tools, want, callTimeout))
+			start = time.Now()
+		}
+	}
+
+	if len(c.Resources) > 0 {
+		start := time.Now()
+		resources, templates, err := contractResources(ctx, mcpClient, initResult.Capabilities)
+		for _, want := range c.Resources {
+			id := "resource " + want.URI
+			if want.URITemplate != "" {
+				id = "resource template " + want.URITemplate
+			}
+			if err != nil {
+				record(id, start, []string{err.Error()})
+				continue
+			}
+			record(id, start, checkContractResource(ctx, mcpClient, resources, templates, want))
+			start = time.Now()
+		}
+	}
+
+	if len(c.Prompts) > 0 {
+		start := time.Now()
+		prompts, err := contractPrompts(ctx, mcpClient, initResult.Capabilities)
+		for _, want := range c.Prompts {
+			if err != nil {
+				record("prompt "+want.Name, start, []string{err.Error()})
+				continue
+			}
+			record("prompt "+want.Name, start, checkContractPrompt(prompts, want))
+			start = time.Now()
+		}
+	}
+	report.setChecks(summaries)
+
+	fmt.Printf("\n%d contract items: %d satisfied, %d violated", len(summaries), len(summaries)-violated-suppressed, violated)
+	if suppressed > 0 {
+		fmt.Printf(", %d suppressed", suppressed)
+	}
+	fmt.Println()
+	if violated > 0 {
+		return fmt.Errorf("contract violated: %d of %d items", violated, len(summaries))
+	}
+	return nil
+}
+
+// contractTools lists the server's tools by name
+func contractTools(ctx context.Context, mcpClient *client.Client, caps mcp.ServerCapabilities) (map[string]mcp.Tool, error) {
+	if caps.Tools == nil {
+		return nil, fmt.Errorf("server does not advertise tools")
+	}
+	result, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	report.setTools(result.Tools)
+	tools := make(map[string]mcp.Tool, len(result.Tools))
+	for _, tool := range result.Tools {
+		tools[tool.Name] = tool
+	}
+	return tools, nil
+}
+
+// contractResources lists the server's resources and resource templates
+func contractResources(ctx context.Context, mcpClient *client.Client, caps mcp.ServerCapabilities) (map[string]mcp.Resource, map[string]mcp.ResourceTemplate, error) {
+	if caps.Resources == nil {
+		return nil, nil, fmt.Errorf("server does not advertise resources")
+	}
+	result, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+	report.setResources(result.Resources)
+	resources := make(map[string]mcp.Resource, len(result.Resources))
+	for _, res := range result.Resources {
+		resources[res.URI] = res
+	}
+	// Servers without templates may not implement the method
+	templates := map[string]mcp.ResourceTemplate{}
+	if list, err := mcpClient.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{}); err == nil {
+		report.setResourceTemplates(list.ResourceTemplates)
+		for _, tmpl := range list.ResourceTemplates {
+			if tmpl.URITemplate != nil {
+				templates[tmpl.URITemplate.Raw()] = tmpl
+			}
+		}
+	}
+	return resources, templates, nil
+}
+
+// contractPrompts lists the server's prompts by name
+func contractPrompts(ctx context.Context, mcpClient *client.Client, caps mcp.ServerCapabilities) (map[string]mcp.Prompt, error) {
+	if caps.Prompts == nil {
+		return nil, fmt.Errorf("server does not advertise prompts")
+	}
+	result, err := mcpClient.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+	report.setPrompts(result.Prompts)
+	prompts := make(map[string]mcp.Prompt, len(result.Prompts))
+	for _, prompt := range result.Prompts {
+		prompts[prompt.Name] = prompt
+	}
+	return prompts, nil
+}
+
+// checkContractTool returns the ways a tool breaks the consumer's use of it
+func checkContractTool(mcpClient *client.Client, tools map[string]mcp.Tool, want contractTool, callTimeout time.Duration) []string {
+	tool, ok := tools[want.Name]
+	if !ok {
+		return []string{"tool not found"}
+	}
+	schema := tool.InputSchema
+	if len(tool.RawInputSchema) > 0 {
+		schema = mcp.ToolInputSchema{}
+		if err := json.Unmarshal(tool.RawInputSchema, &schema); err != nil {
+			return []string{fmt.Sprintf("input schema is not valid: %v", err)}
+		}
+	}
+
+	var problems []string
+	for _, param := range sortedKeys(want.Params) {
+		prop, ok := schema.Properties[param]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("parameter '%s' is not in the input schema", param))
+			continue
+		}
+		accepted := schemaPropertyTypes(prop)
+		if typ := want.Params[param]; typ != "" && len(accepted) > 0 && !slices.Contains(accepted, typ) && (typ != "integer" || !slices.Contains(accepted, "number")) {
+			problems = append(problems, fmt.Sprintf("parameter '%s' is %s, the consumer sends %s", param, strings.Join(accepted, " or "), typ))
+		}
+	}
+	for _, required := range schema.Required {
+		if _, ok := want.Params[required]; !ok {
+			problems = append(problems, fmt.Sprintf("parameter '%s' is required but the consumer does not send it", required))
+		}
+	}
+	if want.Call != nil && len(problems) == 0 {
+		problems = append(problems, checkContractCall(mcpClient, want.Name, want.Call, callTimeout)...)
+	}
+	return problems
+}
+
+// schemaPropertyTypes returns the types a JSON Schema property accepts, or
+// nil if it does not restrict the type
+func schemaPropertyTypes(prop any) []string {
+	schema, ok := prop.(map[string]any)
+	if !ok {
+		return nil
+	}
+	switch typ := schema["type"].(type) {
+	case string:
+		return []string{typ}
+	case []any:
+		var types []string
+		for _, t := range typ {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// checkContractCall makes the sample call and compares the parts of the
+// result the consumer reads
+func checkContractCall(mcpClient *client.Client, name string, call *contractCall, callTimeout time.Duration) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = call.Arguments
+	start := time.Now()
+	result, err := mcpClient.CallTool(ctx, request)
+	report.addTiming("tools/call "+name, time.Since(start), err)
+	if err != nil {
+		return []string{fmt.Sprintf("sample call failed: %v", err)}
+	}
+
+	var problems []string
+	if result.IsError != call.IsError {
+		if result.IsError {
+			problems = append(problems, fmt.Sprintf("sample call returned an error result: %s", firstLine(toolResultText(result))))
+		} else {
+			problems = append(problems, "sample call was expected to return an error result")
+		}
+	}
+	var types []string
+	for _, content := range result.Content {
+		var item struct {
+			Type string `json:"type"`
+		}
+		data, _ := json.Marshal(content)
+		_ = json.Unmarshal(data, &item)
+		types = append(types, item.Type)
+	}
+	for _, typ := range call.Content {
+		if !slices.Contains(types, typ) {
+			problems = append(problems, fmt.Sprintf("result has no %s content", typ))
+		}
+	}
+	if len(call.Structured) > 0 {
+		var structured any
+		if result.StructuredContent != nil {
+			data, _ := json.Marshal(result.StructuredContent)
+			_ = json.Unmarshal(data, &structured)
+		}
+		for _, path := range sortedKeys(call.Structured) {
+			value, ok := valueAtPath(structured, path)
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("structuredContent '%s' is missing", displayPath(path)))
+			case jsonTypeName(value) != call.Structured[path]:
+				problems = append(problems, fmt.Sprintf("structuredContent '%s' is %s, the consumer reads %s", displayPath(path), jsonTypeName(value), call.Structured[path]))
+			}
+		}
+	}
+	return problems
+}
+
+// toolResultText returns the text content of a tool result
+func toolResultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// checkContractResource returns the ways a resource breaks the consumer's use of it
+func checkContractResource(ctx context.Context, mcpClient *client.Client, resources map[string]mcp.Resource, templates map[string]mcp.ResourceTemplate, want contractResource) []string {
+	if want.URITemplate != "" {
+		tmpl, ok := templates[want.URITemplate]
+		if !ok {
+			return []string{"resource template not found"}
+		}
+		if want.MIMEType != "" && tmpl.MIMEType != want.MIMEType {
+			return []string{fmt.Sprintf("MIME type is '%s', the consumer expects '%s'", tmpl.MIMEType, want.MIMEType)}
+		}
+		return nil
+	}
+
+	var problems []string
+	res, listed := resources[want.URI]
+	if !listed && !want.Read {
+		return []string{"resource not listed"}
+	}
+	if listed && want.MIMEType != "" && res.MIMEType != want.MIMEType {
+		problems = append(problems, fmt.Sprintf("MIME type is '%s', the consumer expects '%s'", res.MIMEType, want.MIMEType))
+	}
+	if want.Read {
+		request := mcp.ReadResourceRequest{}
+		request.Params.URI = want.URI
+		result, err := mcpClient.ReadResource(ctx, request)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("read failed: %v", err))
+		case len(result.Contents) == 0:
+			problems = append(problems, "read returned no contents")
+		}
+	}
+	return problems
+}
+
+// checkContractPrompt returns the ways a prompt breaks the consumer's use of it
+func checkContractPrompt(prompts map[string]mcp.Prompt, want contractPrompt) []string {
+	prompt, ok := prompts[want.Name]
+	if !ok {
+		return []string{"prompt not found"}
+	}
+	var problems []string
+	for _, arg := range want.Arguments {
+		if !slices.ContainsFunc(prompt.Arguments, func(a mcp.PromptArgument) bool { return a.Name == arg }) {
+			problems = append(problems, fmt.Sprintf("argument '%s' is not accepted", arg))
+		}
+	}
+	for _, arg := range prompt.Arguments {
+		if arg.Required && !slices.Contains(want.Arguments, arg.Name) {
+			problems = append(problems, fmt.Sprintf("argument '%s' is required but the consumer does not send it", arg.Name))
+		}
+	}
+	return problems
+}
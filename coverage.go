@@ -0,0 +1,374 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// Kinds of schema branches tracked by the coverage report
+const (
+	branchOptional = "optional"
+	branchEnum     = "enum"
+)
+
+// coverageBranch is a branch of a tool's input schema or a prompt's
+// arguments: an optional parameter being set, or one value of an enum
+type coverageBranch struct {
+	Kind  string `json:"kind"`
+	Param string `json:"param"`
+	Value string `json:"value,omitempty"`
+	Uses  int    `json:"uses"`
+}
+
+// coverageItem is a listed tool, prompt, resource or resource template and
+// how often it was exercised
+type coverageItem struct {
+	Name     string            `json:"name"`
+	Uses     int               `json:"uses"`
+	Branches []*coverageBranch `json:"branches,omitempty"`
+}
+
+// coverageArea is the coverage of one kind of server capability
+type coverageArea struct {
+	Listed    int             `json:"listed"`
+	Exercised int             `json:"exercised"`
+	Items     []*coverageItem `json:"items"`
+}
+
+// coverageReport is the surface of a server exercised in a set of audit logs
+type coverageReport struct {
+	AuditLogs         []string     `json:"auditLogs"`
+	Tools             coverageArea `json:"tools"`
+	Prompts           coverageArea `json:"prompts"`
+	Resources         coverageArea `json:"resources"`
+	Templates         coverageArea `json:"resourceTemplates"`
+	Branches          int          `json:"branches"`
+	ExercisedBranches int          `json:"exercisedBranches"`
+}
+
+// listedTool is a tool from a recorded tools/list response
+type listedTool struct {
+	Name        string `json:"name"`
+	InputSchema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	} `json:"inputSchema"`
+}
+
+// listedPrompt is a prompt from a recorded prompts/list response
+type listedPrompt struct {
+	Name      string `json:"name"`
+	Arguments []struct {
+		Name     string `json:"name"`
+		Required bool   `json:"required"`
+	} `json:"arguments"`
+}
+
+// runCoverageCommand implements the 'coverage' subcommand
+func runCoverageCommand(args []string) error {
+	var paths []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		paths = append(paths, args[0])
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	auditLog := fs.String("audit-log", "", "Session recordings (JSON Lines) to analyze, comma-separated")
+	format := fs.String("output", outputText, "Output format: 'text' or 'json'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for _, path := range strings.Split(*auditLog, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	paths = append(paths, fs.Args()...)
+	if len(paths) == 0 {
+		return fmt.Errorf("usage: coverage -audit-log <session.jsonl>[,<session.jsonl>...] [-output text|json]")
+	}
+	if *format != outputText && *format != outputJSON {
+		return fmt.Errorf("unsupported output format '%s' (use 'text' or 'json')", *format)
+	}
+
+	// Request IDs are only unique within a recording, so each is paired separately
+	var exchanges []*recordedExchange
+	for _, path := range paths {
+		records, err := readSessionRecording(path)
+		if err != nil {
+			return err
+		}
+		paired, _ := pairRecordedExchanges(records)
+		exchanges = append(exchanges, paired...)
+	}
+	cov := buildCoverage(paths, exchanges)
+	if cov.Tools.Listed+cov.Prompts.Listed+cov.Resources.Listed+cov.Templates.Listed == 0 {
+		return fmt.Errorf("no tools, prompts or resources are listed in %s; record a run that lists the server's capabilities", strings.Join(paths, ", "))
+	}
+
+	if *format == outputJSON {
+		data, err := json.MarshalIndent(cov, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode coverage: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printCoverage(cov)
+	return nil
+}
+
+// buildCoverage compares what the server listed in the recorded exchanges
+// with the tools called, prompts got and resources read
+func buildCoverage(paths []string, exchanges []*recordedExchange) *coverageReport {
+	tools := map[string]*coverageItem{}
+	prompts := map[string]*coverageItem{}
+	resources := map[string]*coverageItem{}
+	templates := map[string]*coverageItem{}
+	compiled := map[string]*uritemplate.Template{}
+
+	// The server's surface is the union of all recorded list responses
+	for _, ex := range exchanges {
+		if !ex.Answered {
+			continue
+		}
+		switch ex.Method {
+		case "tools/list":
+			for _, tool := range listedTools(ex) {
+				if tools[tool.Name] == nil {
+					tools[tool.Name] = &coverageItem{Name: tool.Name, Branches: toolBranches(tool)}
+				}
+			}
+		case "prompts/list":
+			var result struct {
+				Prompts []listedPrompt `json:"prompts"`
+			}
+			_ = json.Unmarshal(ex.Result, &result)
+			for _, prompt := range result.Prompts {
+				if prompts[prompt.Name] != nil {
+					continue
+				}
+				item := &coverageItem{Name: prompt.Name}
+				for _, arg := range prompt.Arguments {
+					if !arg.Required {
+						item.Branches = append(item.Branches, &coverageBranch{Kind: branchOptional, Param: arg.Name})
+					}
+				}
+				prompts[prompt.Name] = item
+			}
+		case "resources/list":
+			var result struct {
+				Resources []struct {
+					URI string `json:"uri"`
+				} `json:"resources"`
+			}
+			_ = json.Unmarshal(ex.Result, &result)
+			for _, res := range result.Resources {
+				if resources[res.URI] == nil {
+					resources[res.URI] = &coverageItem{Name: res.URI}
+				}
+			}
+		case "resources/templates/list":
+			var result struct {
+				ResourceTemplates []struct {
+					URITemplate string `json:"uriTemplate"`
+				} `json:"resourceTemplates"`
+			}
+			_ = json.Unmarshal(ex.Result, &result)
+			for _, tmpl := range result.ResourceTemplates {
+				if templates[tmpl.URITemplate] != nil {
+					continue
+				}
+				templates[tmpl.URITemplate] = &coverageItem{Name: tmpl.URITemplate}
+				if t, err := uritemplate.New(tmpl.URITemplate); err == nil {
+					compiled[tmpl.URITemplate] = t
+				}
+			}
+		}
+	}
+
+	for _, ex := range exchanges {
+		var params struct {
+			Name      string                     `json:"name"`
+			URI       string                     `json:"uri"`
+			Arguments map[string]json.RawMessage `json:"arguments"`
+		}
+		_ = json.Unmarshal(ex.Params, &params)
+		switch ex.Method {
+		case "tools/call":
+			coverUse(tools[params.Name], params.Arguments)
+		case "prompts/get":
+			coverUse(prompts[params.Name], params.Arguments)
+		case "resources/read":
+			coverUse(resources[params.URI], nil)
+			for raw, t := range compiled {
+				if t.Match(params.URI) != nil {
+					coverUse(templates[raw], nil)
+				}
+			}
+		}
+	}
+
+	cov := &coverageReport{
+		AuditLogs: paths,
+		Tools:     coverageOf(tools),
+		Prompts:   coverageOf(prompts),
+		Resources: coverageOf(resources),
+		Templates: coverageOf(templates),
+	}
+	for _, area := range []coverageArea{cov.Tools, cov.Prompts} {
+		for _, item := range area.Items {
+			for _, b := range item.Branches {
+				cov.Branches++
+				if b.Uses > 0 {
+					cov.ExercisedBranches++
+				}
+			}
+		}
+	}
+	return cov
+}
+
+// listedTools returns the tools in a recorded tools/list response
+func listedTools(ex *recordedExchange) []listedTool {
+	var result struct {
+		Tools []listedTool `json:"tools"`
+	}
+	_ = json.Unmarshal(ex.Result, &result)
+	return result.Tools
+}
+
+// toolBranches returns the optional parameters and enum values of a tool's
+// top-level input schema properties
+func toolBranches(tool listedTool) []*coverageBranch {
+	required := map[string]bool{}
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+	var branches []*coverageBranch
+	for _, name := range sortedAnyKeys(tool.InputSchema.Properties) {
+		if !required[name] {
+			branches = append(branches, &coverageBranch{Kind: branchOptional, Param: name})
+		}
+		prop, _ := tool.InputSchema.Properties[name].(map[string]any)
+		values, _ := prop["enum"].([]any)
+		for _, value := range values {
+			branches = append(branches, &coverageBranch{Kind: branchEnum, Param: name, Value: itemJSON(value)})
+		}
+	}
+	return branches
+}
+
+// coverUse records one use of a listed item with the given arguments;
+// unlisted items are ignored
+func coverUse(item *coverageItem, args map[string]json.RawMessage) {
+	if item == nil {
+		return
+	}
+	item.Uses++
+	for _, b := range item.Branches {
+		value, ok := args[b.Param]
+		switch {
+		case !ok:
+		case b.Kind == branchOptional:
+			b.Uses++
+		case b.Kind == branchEnum && canonicalJSON(value) == b.Value:
+			b.Uses++
+		}
+	}
+}
+
+// canonicalJSON returns the JSON encoding of a value as itemJSON would
+func canonicalJSON(raw json.RawMessage) string {
+	var value any
+	if json.Unmarshal(raw, &value) != nil {
+		return string(raw)
+	}
+	return itemJSON(value)
+}
+
+// coverageOf returns the coverage of a set of listed items, sorted by name
+func coverageOf(items map[string]*coverageItem) coverageArea {
+	area := coverageArea{Listed: len(items), Items: []*coverageItem{}}
+	for _, item := range items {
+		if item.Uses > 0 {
+			area.Exercised++
+		}
+		area.Items = append(area.Items, item)
+	}
+	sort.Slice(area.Items, func(i, j int) bool { return area.Items[i].Name < area.Items[j].Name })
+	return area
+}
+
+// printCoverage prints the coverage summary and what was never exercised
+func printCoverage(cov *coverageReport) {
+	fmt.Printf("=== Coverage: %s ===\n", strings.Join(cov.AuditLogs, ", "))
+	rows := []struct {
+		name        string
+		done, total int
+	}{
+		{"Tools", cov.Tools.Exercised, cov.Tools.Listed},
+		{"Prompts", cov.Prompts.Exercised, cov.Prompts.Listed},
+		{"Resources", cov.Resources.Exercised, cov.Resources.Listed},
+		{"Resource templates", cov.Templates.Exercised, cov.Templates.Listed},
+		{"Schema branches", cov.ExercisedBranches, cov.Branches},
+	}
+	for _, row := range rows {
+		fmt.Printf("%-20s %s\n", row.name, coverageRatio(row.done, row.total))
+	}
+
+	printCoverageArea("Tools", "called", cov.Tools)
+	printCoverageArea("Prompts", "got", cov.Prompts)
+	printCoverageArea("Resources", "read", cov.Resources)
+	printCoverageArea("Resource templates", "read", cov.Templates)
+}
+
+// printCoverageArea lists the items of one area with their uses and the
+// branches that were never exercised
+func printCoverageArea(title, verb string, area coverageArea) {
+	if area.Listed == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	width := 0
+	for _, item := range area.Items {
+		width = max(width, len(item.Name))
+	}
+	for _, item := range area.Items {
+		if item.Uses == 0 {
+			fmt.Printf("  %-*s  never %s\n", width, item.Name, verb)
+			continue
+		}
+		fmt.Printf("  %-*s  %s %d time(s)\n", width, item.Name, verb, item.Uses)
+		var untouched []string
+		for _, b := range item.Branches {
+			if b.Uses > 0 {
+				continue
+			}
+			if b.Kind == branchOptional {
+				untouched = append(untouched, fmt.Sprintf("optional %s", b.Param))
+			} else {
+				untouched = append(untouched, fmt.Sprintf("%s=%s", b.Param, b.Value))
+			}
+		}
+		if len(untouched) > 0 {
+			fmt.Printf("  %-*s    never: %s\n", width, "", strings.Join(untouched, ", "))
+		}
+	}
+}
+
+// coverageRatio formats exercised/total with a percentage
+func coverageRatio(done, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%)", done, total, float64(done)*100/float64(total))
+}
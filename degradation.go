@@ -0,0 +1,172 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Classes of failure of an advertised capability's endpoint
+const (
+	failureNotImplemented = "not implemented"
+	failureAuth           = "authorization"
+	failureTimeout        = "timeout"
+	failureBug            = "server error"
+)
+
+// rpcError is a JSON-RPC error returned to a raw request
+type rpcError struct {
+	Code    int
+	Message string
+}
+
+// Error formats the error as the raw requests report it
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("server returned error %d: %s", e.Code, e.Message)
+}
+
+// httpStatusPattern finds the HTTP status in the transport's errors
+var httpStatusPattern = regexp.MustCompile(`status (\d{3})`)
+
+// authWords are words in error messages of servers that report missing
+// authorization as a JSON-RPC error
+var authWords = []string{"unauthorized", "unauthenticated", "forbidden", "permission denied", "access denied"}
+
+// classifyFailure tells why an endpoint failed: it is not implemented
+// (method not found, or an HTTP 404, 405 or 501), the client is not
+// authorized, it timed out, or anything else, which is taken to be a bug
+func classifyFailure(err error) string {
+	var rpcErr *rpcError
+	var oauthErr *transport.OAuthAuthorizationRequiredError
+	switch {
+	case errors.Is(err, mcp.ErrMethodNotFound),
+		errors.As(err, &rpcErr) && rpcErr.Code == mcp.METHOD_NOT_FOUND:
+		return failureNotImplemented
+	case errors.Is(err, transport.ErrUnauthorized), errors.As(err, &oauthErr):
+		return failureAuth
+	case errors.Is(err, context.DeadlineExceeded):
+		return failureTimeout
+	}
+	message := err.Error()
+	if m := httpStatusPattern.FindStringSubmatch(message); m != nil {
+		switch m[1] {
+		case "401", "403":
+			return failureAuth
+		case "404", "405", "501":
+			return failureNotImplemented
+		}
+	}
+	lower := strings.ToLower(message)
+	for _, word := range authWords {
+		if strings.Contains(lower, word) {
+			return failureAuth
+		}
+	}
+	return failureBug
+}
+
+// capabilityEndpoint is the outcome of a request to an endpoint of an
+// advertised capability
+type capabilityEndpoint struct {
+	Capability string `json:"capability"`
+	Method     string `json:"method"`
+	Works      bool   `json:"works"`
+	Failure    string `json:"failure,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// recordEndpoint records whether an endpoint of an advertised capability
+// works. A later outcome for the same endpoint replaces the earlier one.
+func recordEndpoint(capability string, method mcp.MCPMethod, err error) {
+	endpoint := capabilityEndpoint{Capability: capability, Method: string(method), Works: err == nil}
+	if err != nil {
+		endpoint.Failure = classifyFailure(err)
+		endpoint.Error = err.Error()
+		report.recordWarning(warningRecord{
+			Source:  warningCapability,
+			Subject: capability,
+			Message: fmt.Sprintf("%s is advertised, but %s fails (%s): %s", capability, method, endpoint.Failure, endpoint.Error),
+		})
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	for i := range report.Endpoints {
+		if report.Endpoints[i].Capability == capability && report.Endpoints[i].Method == endpoint.Method {
+			report.Endpoints[i] = endpoint
+			return
+		}
+	}
+	report.Endpoints = append(report.Endpoints, endpoint)
+}
+
+// partialCapability is an advertised capability with an endpoint that failed
+type partialCapability struct {
+	Capability string
+	Works      []string
+	Failed     []capabilityEndpoint
+}
+
+// partialCapabilities groups the endpoints of the capabilities that have at
+// least one failing endpoint, in the order they were probed
+func (r *probeReport) partialCapabilities() []partialCapability {
+	var partial []partialCapability
+	index := map[string]int{}
+	for _, e := range r.Endpoints {
+		i, ok := index[e.Capability]
+		if !ok {
+			i = len(partial)
+			index[e.Capability] = i
+			partial = append(partial, partialCapability{Capability: e.Capability})
+		}
+		if e.Works {
+			partial[i].Works = append(partial[i].Works, e.Method)
+		} else {
+			partial[i].Failed = append(partial[i].Failed, e)
+		}
+	}
+	var degraded []partialCapability
+	for _, p := range partial {
+		if len(p.Failed) > 0 {
+			degraded = append(degraded, p)
+		}
+	}
+	return degraded
+}
+
+// Reliable summarizes what a client can rely on
+func (p partialCapability) Reliable() string {
+	if len(p.Works) == 0 {
+		return "nothing: advertised but not usable"
+	}
+	return strings.Join(p.Works, ", ")
+}
+
+// printPartialCapabilities prints the advertised capabilities whose
+// endpoints failed, with why, and what of them a client can rely on
+func printPartialCapabilities() {
+	report.mu.Lock()
+	partial := report.partialCapabilities()
+	report.mu.Unlock()
+	if len(partial) == 0 {
+		return
+	}
+	fmt.Println("\n--- Partially Implemented Capabilities ---")
+	for _, p := range partial {
+		fmt.Printf("%s (advertised)\n", p.Capability)
+		for _, method := range p.Works {
+			fmt.Printf("  %-26s works\n", method)
+		}
+		for _, e := range p.Failed {
+			fmt.Printf("  %-26s %s: %s\n", e.Method, e.Failure, e.Error)
+		}
+		fmt.Printf("  A client can rely on: %s\n", p.Reliable())
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// elicitationAnswer is a scripted response in an -elicitation-answers file.
+// Message is matched case-insensitively as a substring of the request's
+// message; an empty Message matches every request.
+type elicitationAnswer struct {
+	Message string         `json:"message,omitempty"`
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// elicitationHandler answers elicitation/create requests from the server,
+// from an answers file or by asking on the terminal
+type elicitationHandler struct {
+	answers     []elicitationAnswer
+	answersPath string
+	interactive bool
+	// mu makes concurrent requests ask one at a time
+	mu sync.Mutex
+}
+
+// elicitationSchema is the part of a requested schema the probe uses. The
+// specification restricts it to an object of primitive properties.
+type elicitationSchema struct {
+	Properties map[string]any `json:"properties"`
+	Required   []string       `json:"required"`
+}
+
+// sharedStdinScanner reads stdin for the interactive mode and elicitation
+// prompts, so that neither loses input buffered by the other
+var (
+	sharedStdinScanner *bufio.Scanner
+	sharedStdinOnce    sync.Once
+)
+
+// stdinScanner returns the scanner shared by everything that reads stdin
+// line by line
+func stdinScanner() *bufio.Scanner {
+	sharedStdinOnce.Do(func() { sharedStdinScanner = bufio.NewScanner(os.Stdin) })
+	return sharedStdinScanner
+}
+
+// loadElicitationAnswers reads an -elicitation-answers file: a JSON list of
+// answers, each with an action and, for accept, the content to send
+func loadElicitationAnswers(path string) ([]elicitationAnswer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read answers: %w", err)
+	}
+	var answers []elicitationAnswer
+	if err := json.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("failed to parse %s (expected a JSON list of answers): %w", path, err)
+	}
+	for i, a := range answers {
+		switch mcp.ElicitationResponseAction(a.Action) {
+		case mcp.ElicitationResponseActionAccept:
+		case mcp.ElicitationResponseActionDecline, mcp.ElicitationResponseActionCancel:
+			if a.Content != nil {
+				return nil, fmt.Errorf("answer %d: content is only sent with action 'accept'", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("answer %d: action must be 'accept', 'decline' or 'cancel', not '%s'", i+1, a.Action)
+		}
+	}
+	return answers, nil
+}
+
+// newElicitationHandler creates the handler. Without answers or a terminal to
+// ask on it returns nil, so that the probe does not offer elicitation.
+func newElicitationHandler(answers []elicitationAnswer, answersPath string) *elicitationHandler {
+	interactive := stdinIsTerminal()
+	if answers == nil && !interactive {
+		return nil
+	}
+	return &elicitationHandler{answers: answers, answersPath: answersPath, interactive: interactive}
+}
+
+// enableElicitation makes the client answer elicitation requests with handler
+func enableElicitation(mcpClient *client.Client, handler *elicitationHandler) {
+	client.WithElicitationHandler(handler)(mcpClient)
+}
+
+// Elicit implements client.ElicitationHandler
+func (h *elicitationHandler) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	start := time.Now()
+	result, err := h.elicit(request.Params)
+	report.addTiming(string(mcp.MethodElicitationCreate), time.Since(start), err)
+	if err != nil {
+		fmt.Printf("[elicitation] failed: %v\n", err)
+		report.addError("%s: %v", mcp.MethodElicitationCreate, err)
+		return nil, err
+	}
+	fmt.Printf("[elicitation] responded with %s\n", result.Action)
+	return result, nil
+}
+
+// elicit answers one request from the answers file or the terminal
+func (h *elicitationHandler) elicit(params mcp.ElicitationParams) (*mcp.ElicitationResult, error) {
+	fmt.Printf("\n[elicitation] The server asks: %s\n", params.Message)
+	urlMode := params.Mode == mcp.ElicitationModeURL
+	if urlMode {
+		fmt.Printf("[elicitation] Open this URL to continue: %s\n", params.URL)
+	}
+	var schema elicitationSchema
+	if !urlMode {
+		data, err := json.Marshal(params.RequestedSchema)
+		if err != nil || json.Unmarshal(data, &schema) != nil {
+			return nil, fmt.Errorf("the requested schema is not a JSON Schema object")
+		}
+	}
+
+	if answer := h.findAnswer(params.Message); answer != nil {
+		action := mcp.ElicitationResponseAction(answer.Action)
+		fmt.Printf("[elicitation] Answering from %s\n", h.answersPath)
+		if action != mcp.ElicitationResponseActionAccept || urlMode {
+			return elicitationResult(action, nil), nil
+		}
+		if problems := elicitationProblems(schema, answer.Content); len(problems) > 0 {
+			return nil, fmt.Errorf("the answer in %s does not match the requested schema: %s", h.answersPath, strings.Join(problems, "; "))
+		}
+		return elicitationResult(action, answer.Content), nil
+	}
+
+	if !h.interactive {
+		fmt.Printf("[elicitation] No answer in %s matches and there is no terminal to ask on\n", h.answersPath)
+		return elicitationResult(mcp.ElicitationResponseActionCancel, nil), nil
+	}
+	return askElicitation(stdinScanner(), schema, urlMode), nil
+}
+
+// findAnswer returns the first answer whose message matches, or nil
+func (h *elicitationHandler) findAnswer(message string) *elicitationAnswer {
+	for i, a := range h.answers {
+		if strings.Contains(strings.ToLower(message), strings.ToLower(a.Message)) {
+			return &h.answers[i]
+		}
+	}
+	return nil
+}
+
+// elicitationResult builds the response to an elicitation request
+func elicitationResult(action mcp.ElicitationResponseAction, content map[string]any) *mcp.ElicitationResult {
+	result := &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: action}}
+	if content != nil {
+		result.Content = content
+	}
+	return result
+}
+
+// askElicitation asks the user to accept, decline or cancel and, for a form,
+// collects a value for each field. End of input cancels.
+func askElicitation(scanner *bufio.Scanner, schema elicitationSchema, urlMode bool) *mcp.ElicitationResult {
+	question := "Answer, decline or cancel? [a/d/c]: "
+	if urlMode {
+		question = "Continue at the URL, decline or cancel? [a/d/c]: "
+	}
+	for {
+		fmt.Print(question)
+		if !scanner.Scan() {
+			return elicitationResult(mcp.ElicitationResponseActionCancel, nil)
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "a", "accept", "y", "yes":
+			if urlMode {
+				return elicitationResult(mcp.ElicitationResponseActionAccept, nil)
+			}
+			content, ok := askElicitationFields(scanner, schema)
+			if !ok {
+				return elicitationResult(mcp.ElicitationResponseActionCancel, nil)
+			}
+			return elicitationResult(mcp.ElicitationResponseActionAccept, content)
+		case "d", "decline", "n", "no":
+			return elicitationResult(mcp.ElicitationResponseActionDecline, nil)
+		case "c", "cancel":
+			return elicitationResult(mcp.ElicitationResponseActionCancel, nil)
+		}
+	}
+}
+
+// askElicitationFields prompts for each field of the form, required fields
+// first. Empty input takes the field's default or skips an optional field.
+// It returns false if input ends.
+func askElicitationFields(scanner *bufio.Scanner, schema elicitationSchema) (map[string]any, bool) {
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	content := map[string]any{}
+	for _, name := range names {
+		prop, _ := schema.Properties[name].(map[string]any)
+		label := elicitationFieldLabel(name, prop, required[name])
+		for {
+			fmt.Printf("  %s: ", label)
+			if !scanner.Scan() {
+				return nil, false
+			}
+			input := strings.TrimSpace(scanner.Text())
+			if input == "" {
+				if def, ok := prop["default"]; ok {
+					content[name] = def
+					break
+				}
+				if !required[name] {
+					break
+				}
+				fmt.Println("    This field is required")
+				continue
+			}
+			value, err := coerceArgValue(input, prop)
+			if err == nil {
+				err = validateElicitationValue(prop, value)
+			}
+			if err != nil {
+				fmt.Printf("    %v\n", err)
+				continue
+			}
+			content[name] = value
+			break
+		}
+	}
+	return content, true
+}
+
+// elicitationFieldLabel describes a form field for its prompt
+func elicitationFieldLabel(name string, prop map[string]any, required bool) string {
+	label := name
+	if title, ok := prop["title"].(string); ok && title != "" && title != name {
+		label += " (" + title + ")"
+	}
+	var details []string
+	if description, ok := prop["description"].(string); ok && description != "" {
+		details = append(details, description)
+	}
+	if enum, ok := prop["enum"].([]any); ok {
+		var options []string
+		for _, option := range enum {
+			options = append(options, fmt.Sprint(option))
+		}
+		details = append(details, "one of "+strings.Join(options, ", "))
+	} else if types := schemaPropertyTypes(prop); len(types) > 0 {
+		details = append(details, strings.Join(types, " or "))
+	}
+	if format, ok := prop["format"].(string); ok {
+		details = append(details, "format "+format)
+	}
+	if def, ok := prop["default"]; ok {
+		details = append(details, fmt.Sprintf("default %v", def))
+	}
+	if len(details) > 0 {
+		label += " [" + strings.Join(details, "; ") + "]"
+	}
+	if required {
+		return label + " (required)"
+	}
+	return label + " (optional)"
+}
+
+// elicitationProblems checks content against a requested schema and returns
+// what does not match
+func elicitationProblems(schema elicitationSchema, content map[string]any) []string {
+	var problems []string
+	for _, name := range schema.Required {
+		if _, ok := content[name]; !ok {
+			problems = append(problems, fmt.Sprintf("required field '%s' is missing", name))
+		}
+	}
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, known := schema.Properties[name]
+		if !known {
+			problems = append(problems, fmt.Sprintf("'%s' is not a requested field", name))
+			continue
+		}
+		schemaProp, _ := prop.(map[string]any)
+		if err := validateElicitationValue(schemaProp, content[name]); err != nil {
+			problems = append(problems, fmt.Sprintf("field '%s': %v", name, err))
+		}
+	}
+	return problems
+}
+
+// validateElicitationValue checks a value against the type, enum, length,
+// range and format of a primitive schema property
+func validateElicitationValue(prop map[string]any, value any) error {
+	if types := schemaPropertyTypes(prop); len(types) > 0 && !valueHasType(value, types) {
+		return fmt.Errorf("%v is not a %s", value, typeList(types))
+	}
+	if enum, ok := prop["enum"].([]any); ok {
+		found := false
+		for _, option := range enum {
+			found = found || fmt.Sprint(option) == fmt.Sprint(value)
+		}
+		if !found {
+			return fmt.Errorf("'%v' is not one of the allowed values", value)
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if limit, ok := prop["minLength"].(float64); ok && length < limit {
+			return fmt.Errorf("must be at least %g characters", limit)
+		}
+		if limit, ok := prop["maxLength"].(float64); ok && length > limit {
+			return fmt.Errorf("must be at most %g characters", limit)
+		}
+		if format, ok := prop["format"].(string); ok {
+			if err := checkStringFormat(v, format); err != nil {
+				return err
+			}
+		}
+	case float64, int64:
+		n := toFloat(v)
+		if limit, ok := prop["minimum"].(float64); ok && n < limit {
+			return fmt.Errorf("must be at least %g", limit)
+		}
+		if limit, ok := prop["maximum"].(float64); ok && n > limit {
+			return fmt.Errorf("must be at most %g", limit)
+		}
+	}
+	return nil
+}
+
+// valueHasType reports whether a decoded value is one of the JSON types
+func valueHasType(value any, types []string) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case string:
+			if t == "string" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64, int64:
+			if t == "number" || (t == "integer" && toFloat(v) == math.Trunc(toFloat(v))) {
+				return true
+			}
+		case nil:
+			if t == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toFloat converts a decoded or coerced number to float64
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// checkStringFormat checks the string formats elicitation schemas may use
+func checkStringFormat(value, format string) error {
+	var err error
+	switch format {
+	case "email":
+		_, err = mail.ParseAddress(value)
+	case "uri":
+		var u *url.URL
+		if u, err = url.Parse(value); err == nil && u.Scheme == "" {
+			err = fmt.Errorf("no scheme")
+		}
+	case "date":
+		_, err = time.Parse(time.DateOnly, value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return fmt.Errorf("'%s' is not a valid %s", value, format)
+	}
+	return nil
+}
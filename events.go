@@ -0,0 +1,130 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Event types emitted with -output ndjson
+const (
+	eventWaitReady       = "wait_ready"
+	eventConnect         = "connect"
+	eventInit            = "init"
+	eventListTools       = "list_tools"
+	eventListResources   = "list_resources"
+	eventListTemplates   = "list_resource_templates"
+	eventListPrompts     = "list_prompts"
+	eventToolCallStart   = "tool_call_start"
+	eventToolCallResult  = "tool_call_result"
+	eventCheck           = "check"
+	eventTransportDiff   = "transport_diff"
+	eventVersionDiff     = "version_diff"
+	eventVersionMatrix   = "version_matrix"
+	eventBaselineDiff    = "baseline_diff"
+	eventResourceUpdated = "resource_updated"
+	eventLogMessage      = "log_message"
+	eventSamplingRequest = "sampling_request"
+	eventTLS             = "tls"
+	eventFinding         = "finding"
+	eventWarning         = "warning"
+	eventError           = "error"
+)
+
+var eventMu sync.Mutex
+
+// emitEvent writes a single event as one line of JSON to resultOut when
+// -output ndjson is selected. Events are written as they happen so that
+// streaming consumers can follow the probe in real time.
+func emitEvent(event string, fields map[string]any) {
+	if outputFormat != outputNDJSON {
+		return
+	}
+
+	line := map[string]any{
+		"time":  machineTime(time.Now()),
+		"event": event,
+	}
+	for k, v := range fields {
+		if v != nil {
+			line[k] = v
+		}
+	}
+
+	jsonBytes, err := json.Marshal(line)
+	if err != nil {
+		jsonBytes, _ = json.Marshal(map[string]any{
+			"time":  machineTime(time.Now()),
+			"event": eventError,
+			"error": fmt.Sprintf("failed to encode %s event: %v", event, err),
+		})
+	}
+
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	_, _ = fmt.Fprintln(resultOut, string(jsonBytes))
+}
+
+// emitListEvent emits an event for a completed list operation
+func emitListEvent(event string, names []string, duration time.Duration) {
+	emitEvent(event, map[string]any{
+		"count":      len(names),
+		"names":      names,
+		"durationMs": durationMillis(duration),
+	})
+}
+
+// toolNames returns the names of the given tools
+func toolNames(tools []mcp.Tool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+// resourceURIs returns the URIs of the given resources
+func resourceURIs(resources []mcp.Resource) []string {
+	uris := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		uris = append(uris, resource.URI)
+	}
+	return uris
+}
+
+// resourceTemplateStrings returns the URI templates of the given resource templates
+func resourceTemplateStrings(templates []mcp.ResourceTemplate) []string {
+	uris := make([]string, 0, len(templates))
+	for _, template := range templates {
+		uris = append(uris, resourceTemplateString(template))
+	}
+	return uris
+}
+
+// promptNames returns the names of the given prompts
+func promptNames(prompts []mcp.Prompt) []string {
+	names := make([]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		names = append(names, prompt.Name)
+	}
+	return names
+}
+
+// durationMillis converts a duration to fractional milliseconds for event output
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// errorField returns the error message for event output, or nil if err is nil
+func errorField(err error) any {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// serverCapabilityNames are the capabilities a profile can expect, alone or
+// with a sub-capability such as "resources.subscribe"
+var serverCapabilityNames = []string{"completions", "experimental", "logging", "prompts", "resources", "tools"}
+
+// profileExpectations is how a profile's server is expected to behave. They
+// are verified on every run with the profile, after initialization, so that
+// a changed deployment is noticed without separate assertion flags. Every
+// field is optional.
+type profileExpectations struct {
+	// ProtocolVersion is the version the server must negotiate
+	ProtocolVersion string `yaml:"protocol_version,omitempty"`
+	// Capabilities are the capabilities the server advertises, named as in
+	// the capability matrix. The server must advertise each of them and no
+	// other top-level capability; sub-capabilities are only checked when
+	// listed.
+	Capabilities []string `yaml:"capabilities,omitempty"`
+	// MinTools is the fewest tools the server lists
+	MinTools int `yaml:"min_tools,omitempty"`
+	// Tools are tools the server must list, each with the prefix of its
+	// schema hash (as shown by -list-only), or empty for any schema
+	Tools map[string]string `yaml:"tools,omitempty"`
+}
+
+// validate checks the expectations when the profile is loaded
+func (e *profileExpectations) validate() error {
+	if e.ProtocolVersion != "" && !slices.Contains(mcp.ValidProtocolVersions, e.ProtocolVersion) {
+		return fmt.Errorf("protocol_version: unknown protocol version '%s' (known: %s)", e.ProtocolVersion, strings.Join(mcp.ValidProtocolVersions, ", "))
+	}
+	for _, name := range e.Capabilities {
+		if top, _, _ := strings.Cut(name, "."); !slices.Contains(serverCapabilityNames, top) {
+			return fmt.Errorf("capabilities: unknown capability '%s' (known: %s)", name, strings.Join(serverCapabilityNames, ", "))
+		}
+	}
+	if e.MinTools < 0 {
+		return fmt.Errorf("min_tools cannot be negative")
+	}
+	for name, hash := range e.Tools {
+		if hash == "" {
+			continue
+		}
+		if err := validateSchemaHash(hash); err != nil {
+			return fmt.Errorf("tools: schema hash of '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// verifyExpectations checks the initialized session against the profile's
+// expectations and prints the result of each. Each unmet expectation is a
+// finding with the expectation as its subject. An error is returned if a
+// finding counts as an error.
+func verifyExpectations(ctx context.Context, mcpClient *client.Client, expect *profileExpectations) error {
+	fmt.Println("\n=== Profile Expectations ===")
+
+	report.mu.Lock()
+	negotiated := report.ProtocolVersion
+	advertised := advertisedCapabilities(report.Capabilities)
+	report.mu.Unlock()
+
+	var failed []string
+	record := func(subject, detail string, problems []string) {
+		if len(problems) == 0 {
+			fmt.Printf("  PASS  %-20s %s\n", subject, detail)
+			return
+		}
+		fmt.Printf("  FAIL  %-20s %s\n", subject, strings.Join(problems, "; "))
+		f := report.addFinding(checkIDExpectation, subject, "%s: %s", subject, strings.Join(problems, "; "))
+		fmt.Printf("        %s\n", f)
+		if f.fails() {
+			failed = append(failed, subject)
+		}
+	}
+
+	if expect.ProtocolVersion != "" {
+		var problems []string
+		if negotiated != expect.ProtocolVersion {
+			problems = append(problems, fmt.Sprintf("negotiated %s, expected %s", valueOr(negotiated, "no version"), expect.ProtocolVersion))
+		}
+		record("protocol_version", negotiated, problems)
+	}
+
+	if len(expect.Capabilities) > 0 {
+		var problems []string
+		for _, name := range expect.Capabilities {
+			if !slices.Contains(advertised, name) {
+				problems = append(problems, fmt.Sprintf("%s is not advertised", name))
+			}
+		}
+		expected := map[string]bool{}
+		for _, name := range expect.Capabilities {
+			top, _, _ := strings.Cut(name, ".")
+			expected[top] = true
+		}
+		for _, name := range advertised {
+			if !strings.Contains(name, ".") && !expected[name] {
+				problems = append(problems, fmt.Sprintf("%s is advertised but not expected", name))
+			}
+		}
+		record("capabilities", strings.Join(advertised, ", "), problems)
+	}
+
+	if expect.MinTools > 0 || len(expect.Tools) > 0 {
+		listed, _, err := listAllTools(ctx, mcpClient)
+		if err != nil {
+			problem := []string{fmt.Sprintf("failed to list tools: %v", err)}
+			if expect.MinTools > 0 {
+				record("min_tools", "", problem)
+			}
+			for _, name := range sortedKeys(expect.Tools) {
+				record("tools."+name, "", problem)
+			}
+		} else {
+			if expect.MinTools > 0 {
+				var problems []string
+				if len(listed.Tools) < expect.MinTools {
+					problems = append(problems, fmt.Sprintf("%d tool(s) listed, expected at least %d", len(listed.Tools), expect.MinTools))
+				}
+				record("min_tools", fmt.Sprintf("%d tool(s) listed", len(listed.Tools)), problems)
+			}
+			tools := make(map[string]mcp.Tool, len(listed.Tools))
+			for _, tool := range listed.Tools {
+				tools[tool.Name] = tool
+			}
+			for _, name := range sortedKeys(expect.Tools) {
+				record("tools."+name, expectedToolDetail(tools, name), expectedToolProblems(tools, name, expect.Tools[name]))
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("profile expectations not met: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// expectedToolDetail describes a listed tool for a passed expectation
+func expectedToolDetail(tools map[string]mcp.Tool, name string) string {
+	tool, ok := tools[name]
+	if !ok {
+		return ""
+	}
+	return "schema hash " + schemaHashLabel(tool)
+}
+
+// expectedToolProblems returns the ways a tool does not meet its
+// expectation: it is not listed, or its schema hash does not start with the
+// expected prefix
+func expectedToolProblems(tools map[string]mcp.Tool, name, hash string) []string {
+	tool, ok := tools[name]
+	if !ok {
+		return []string{"tool not listed"}
+	}
+	if hash == "" {
+		return nil
+	}
+	actual, err := toolSchemaHash(tool)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if !strings.HasPrefix(actual, strings.ToLower(hash)) {
+		return []string{fmt.Sprintf("schema hash is %s, expected %s: its schema changed", actual, hash)}
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Check categories
+const (
+	categoryConformance = "conformance"
+	categorySecurity    = "security"
+)
+
+// Finding severities, from most to least severe
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// severityRanks orders the severities for -fail-level
+var severityRanks = map[string]int{severityInfo: 0, severityWarning: 1, severityError: 2}
+
+// waiverReminder is how long before a waiver expires it is mentioned
+const waiverReminder = 14 * 24 * time.Hour
+
+// Check IDs. An ID stays with its check across releases and retired IDs are
+// not reused, so that suppression files keep meaning the same thing.
+const (
+	checkIDConnect            = "C001"
+	checkIDInitialize         = "C002"
+	checkIDToolsList          = "C003"
+	checkIDResourcesList      = "C004"
+	checkIDTemplatesList      = "C005"
+	checkIDPromptsList        = "C006"
+	checkIDToolCall           = "C007"
+	checkIDLogging            = "C008"
+	checkIDCancelIgnored      = "C009"
+	checkIDCancelUnresponsive = "C010"
+	checkIDPromptResponse     = "C011"
+	checkIDResourceResponse   = "C012"
+	checkIDPing               = "C013"
+	checkIDInconsistent       = "C014"
+	checkIDTransportDiff      = "C015"
+	checkIDVersionDiff        = "C016"
+	checkIDBreakingChange     = "C017"
+	checkIDTestVector         = "C018"
+	checkIDContract           = "C019"
+	checkIDCompletionResponse = "C020"
+	checkIDOutputSchema       = "C021"
+	checkIDPagination         = "C022"
+	checkIDVersionNegotiation = "C023"
+	checkIDStrict             = "C024"
+	checkIDConformanceMust    = "C025"
+	checkIDConformanceShould  = "C026"
+	checkIDExpectation        = "C027"
+	checkIDNegativeMishandled = "C028"
+	checkIDNegativeErrorCode  = "C029"
+	checkIDFuzzCrash          = "C030"
+	checkIDFuzzError          = "C031"
+	checkIDPolicy             = "C032"
+	checkIDChaosUnrecovered   = "C033"
+	checkIDChaosSessionLost   = "C034"
+	checkIDSSEReconnect       = "C035"
+	checkIDResumability       = "C036"
+	checkIDSessionAccepted    = "C037"
+	checkIDSessionStatus      = "C038"
+	checkIDSessionBleed       = "C039"
+	checkIDSessionConcurrency = "C040"
+	checkIDReplayRegression   = "C041"
+	checkIDReplayChanged      = "C042"
+
+	checkIDTLSVersion       = "S001"
+	checkIDInsecureCipher   = "S002"
+	checkIDNoFwdSecrecy     = "S003"
+	checkIDCBCCipher        = "S004"
+	checkIDCertExpired      = "S005"
+	checkIDCertExpiring     = "S006"
+	checkIDWeakKey          = "S007"
+	checkIDWeakSignature    = "S008"
+	checkIDCertUnverified   = "S009"
+	checkIDSamplingPolicy   = "S010"
+	checkIDAnonymousAccess  = "S011"
+	checkIDAnonymousListing = "S012"
+)
+
+// checkDefinition describes a check that can report findings
+type checkDefinition struct {
+	ID       string
+	Category string
+	Severity string
+	Title    string
+	Subject  string
+}
+
+// checkDefinitions lists every check with its ID. Subject describes what a
+// suppression's subject pattern is matched against.
+var checkDefinitions = []checkDefinition{
+	{checkIDConnect, categoryConformance, severityError, "connecting to the server fails", ""},
+	{checkIDInitialize, categoryConformance, severityError, "the initialization handshake fails", ""},
+	{checkIDToolsList, categoryConformance, severityError, "tools/list fails", ""},
+	{checkIDResourcesList, categoryConformance, severityError, "resources/list fails", ""},
+	{checkIDTemplatesList, categoryConformance, severityError, "resources/templates/list fails", ""},
+	{checkIDPromptsList, categoryConformance, severityError, "prompts/list fails", ""},
+	{checkIDToolCall, categoryConformance, severityError, "a tool call in the capability checks fails", "tool name"},
+	{checkIDLogging, categoryConformance, severityError, "logging/setLevel rejects a valid level", "level"},
+	{checkIDCancelIgnored, categoryConformance, severityError, "the server completes a call after notifications/cancelled", "tool name"},
+	{checkIDCancelUnresponsive, categoryConformance, severityError, "the server stops answering after a cancellation", "tool name"},
+	{checkIDPromptResponse, categoryConformance, severityError, "a prompts/get response does not follow the specification", "prompt name"},
+	{checkIDResourceResponse, categoryConformance, severityError, "a resources/read response does not follow the specification", "resource URI"},
+	{checkIDPing, categoryConformance, severityError, "the server does not answer ping", ""},
+	{checkIDInconsistent, categoryConformance, severityError, "a check is flaky or its results vary between runs", "check"},
+	{checkIDTransportDiff, categoryConformance, severityError, "the server behaves differently over SSE and streamable HTTP", "check"},
+	{checkIDVersionDiff, categoryConformance, severityError, "the server behaves differently under another protocol version", "check"},
+	{checkIDBreakingChange, categoryConformance, severityError, "an incompatible change since the baseline release", "check"},
+	{checkIDTestVector, categoryConformance, severityError, "a test vector fails", "vector ID"},
+	{checkIDContract, categoryConformance, severityError, "a consumer contract expectation is not met", "contract item"},
+	{checkIDCompletionResponse, categoryConformance, severityError, "a completion/complete response does not follow the specification", "prompt name or URI template"},
+	{checkIDOutputSchema, categoryConformance, severityError, "a tool result's structured content does not match the tool's output schema", "tool name"},
+	{checkIDPagination, categoryConformance, severityWarning, "list cursors do not page consistently", "list method"},
+	{checkIDVersionNegotiation, categoryConformance, severityWarning, "the server fails instead of negotiating a protocol version", "requested version"},
+	{checkIDStrict, categoryConformance, severityError, "a response does not follow the MCP schema of the negotiated protocol version (-strict)", "method"},
+	{checkIDConformanceMust, categoryConformance, severityError, "a conformance check of a MUST requirement fails", "conformance check"},
+	{checkIDConformanceShould, categoryConformance, severityWarning, "a conformance check of a SHOULD recommendation fails", "conformance check"},
+	{checkIDExpectation, categoryConformance, severityError, "the server does not meet an expectation of the profile", "expectation"},
+	{checkIDNegativeMishandled, categoryConformance, severityError, "the server accepts a malformed message, does not answer it, or stops answering", "negative test"},
+	{checkIDNegativeErrorCode, categoryConformance, severityWarning, "the server rejects a malformed message with an unexpected error", "negative test"},
+	{checkIDFuzzCrash, categoryConformance, severityError, "a fuzzed tool call times out or stops the server", "tool/mutation"},
+	{checkIDFuzzError, categoryConformance, severityWarning, "a fuzzed tool call fails with a server error instead of being rejected", "tool/mutation"},
+	{checkIDPolicy, categoryConformance, severityError, "the server exposes a capability, method, tool, resource or prompt outside the policy", "policy item"},
+	{checkIDChaosUnrecovered, categoryConformance, severityError, "a call hangs when its connection drops, or the session does not recover from the drop", "fault"},
+	{checkIDChaosSessionLost, categoryConformance, severityWarning, "a streamable HTTP session does not survive a dropped connection", "fault"},
+	{checkIDSSEReconnect, categoryConformance, severityError, "a closed SSE stream is not reopened, or calls fail on the reopened stream", "cycle"},
+	{checkIDResumability, categoryConformance, severityError, "a streamed response with event IDs cannot be resumed with Last-Event-ID, or loses or repeats events when resumed", "tool name"},
+	{checkIDSessionAccepted, categoryConformance, severityError, "the server accepts requests without a session ID, or with an unknown or terminated one", "check"},
+	{checkIDSessionStatus, categoryConformance, severityWarning, "the server rejects requests without a valid session, or a session termination, with an unexpected HTTP status", "check"},
+	{checkIDSessionBleed, categoryConformance, severityError, "sessions share a session ID, or a notification meant for one session is delivered to another", "check"},
+	{checkIDSessionConcurrency, categoryConformance, severityWarning, "operations fail while several sessions are open at once", "check"},
+	{checkIDReplayRegression, categoryConformance, severityError, "a replayed request that succeeded in the recording fails", "method and tool, prompt or URI"},
+	{checkIDReplayChanged, categoryConformance, severityWarning, "the response to a replayed request differs from the recording", "method and tool, prompt or URI"},
+	{checkIDTLSVersion, categorySecurity, severityWarning, "the TLS version is deprecated", "TLS version"},
+	{checkIDInsecureCipher, categorySecurity, severityWarning, "the cipher suite is insecure", "cipher suite"},
+	{checkIDNoFwdSecrecy, categorySecurity, severityWarning, "the cipher suite has no forward secrecy", "cipher suite"},
+	{checkIDCBCCipher, categorySecurity, severityInfo, "the cipher suite uses CBC mode", "cipher suite"},
+	{checkIDCertExpired, categorySecurity, severityWarning, "a certificate has expired", "certificate subject"},
+	{checkIDCertExpiring, categorySecurity, severityInfo, "a certificate expires within 30 days", "certificate subject"},
+	{checkIDWeakKey, categorySecurity, severityWarning, "a certificate has a weak key", "certificate subject"},
+	{checkIDWeakSignature, categorySecurity, severityWarning, "a certificate has a weak signature algorithm", "certificate subject"},
+	{checkIDCertUnverified, categorySecurity, severityWarning, "the certificate chain does not verify", "host"},
+	{checkIDSamplingPolicy, categorySecurity, severityWarning, "a sampling request violates the sampling policy", "policy rule"},
+	{checkIDAnonymousAccess, categorySecurity, severityError, "a resource is read, a prompt fetched or a tool called without credentials", "item"},
+	{checkIDAnonymousListing, categorySecurity, severityWarning, "a tool, resource, resource template or prompt is listed without credentials", "item"},
+}
+
+// findCheckDefinition returns the check with the given ID, or nil
+func findCheckDefinition(id string) *checkDefinition {
+	for i := range checkDefinitions {
+		if strings.EqualFold(checkDefinitions[i].ID, id) {
+			return &checkDefinitions[i]
+		}
+	}
+	return nil
+}
+
+// finding is a problem a check found. Suppressed findings and findings below
+// -fail-level are reported but do not count as errors. WaiverExpired is the
+// expiry date of a suppression that would have accepted the finding.
+type finding struct {
+	Check         string `json:"check"`
+	Severity      string `json:"severity"`
+	Subject       string `json:"subject,omitempty"`
+	Message       string `json:"message"`
+	Suppressed    bool   `json:"suppressed,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	WaiverExpired string `json:"waiverExpired,omitempty"`
+}
+
+// String formats a finding with its check ID and severity for output
+func (f finding) String() string {
+	switch {
+	case f.Suppressed:
+		return fmt.Sprintf("[%s %s] %s (suppressed: %s)", f.Check, f.Severity, f.Message, f.Reason)
+	case f.WaiverExpired != "":
+		return fmt.Sprintf("[%s %s] %s (waiver expired on %s: %s)", f.Check, f.Severity, f.Message, f.WaiverExpired, f.Reason)
+	}
+	return fmt.Sprintf("[%s %s] %s", f.Check, f.Severity, f.Message)
+}
+
+// fails reports whether a finding counts as an error of the run: it is not
+// suppressed and its severity is at or above -fail-level
+func (f finding) fails() bool {
+	return !f.Suppressed && severityRanks[f.Severity] >= severityRanks[failLevel]
+}
+
+// suppressionFile is the contents of a -suppressions file
+type suppressionFile struct {
+	Suppressions []suppression `yaml:"suppressions"`
+}
+
+// suppression accepts the findings of a check. Subject is an optional glob
+// matched against the finding's subject; Reason records why the deviation is
+// accepted and is required. A suppression with Expires (YYYY-MM-DD) is a
+// waiver: after that day it no longer suppresses anything.
+type suppression struct {
+	Check   string `yaml:"check"`
+	Subject string `yaml:"subject,omitempty"`
+	Reason  string `yaml:"reason"`
+	Expires string `yaml:"expires,omitempty"`
+	used    bool
+	// expiresAt is the end of the expiry day, zero without Expires
+	expiresAt time.Time
+	// lapsed counts the findings the waiver no longer accepts
+	lapsed int
+}
+
+// suppressions are the loaded -suppressions entries
+var (
+	suppressions     []*suppression
+	suppressionsPath string
+	suppressionsMu   sync.Mutex
+)
+
+// failLevel is the lowest severity that counts as an error. When it is set
+// with -fail-level, failLevelEnforced also makes such findings fail the run.
+var (
+	failLevel         = severityError
+	failLevelEnforced bool
+)
+
+// setFailLevel validates and enforces -fail-level
+func setFailLevel(level string) error {
+	level = strings.ToLower(level)
+	if _, ok := severityRanks[level]; !ok {
+		return fmt.Errorf("'%s' is not a severity (use error, warning or info)", level)
+	}
+	failLevel, failLevelEnforced = level, true
+	return nil
+}
+
+// enforceFailLevel exits with status 1 if -fail-level was given and a
+// finding at or above it was recorded. The check and comparison modes fail
+// on their findings themselves; this covers the other modes, whose findings
+// (such as TLS warnings) are otherwise only reported.
+func enforceFailLevel() {
+	if !failLevelEnforced {
+		return
+	}
+	report.mu.Lock()
+	failing := 0
+	for _, f := range report.Findings {
+		if f.fails() {
+			failing++
+		}
+	}
+	report.mu.Unlock()
+	if failing > 0 {
+		fmt.Printf("\n%d finding(s) at or above -fail-level %s\n", failing, failLevel)
+		report.addError("%d finding(s) at or above -fail-level %s", failing, failLevel)
+		exitProgram(1)
+	}
+}
+
+// expired reports whether a waiver has lapsed
+func (s *suppression) expired() bool {
+	return !s.expiresAt.IsZero() && time.Now().After(s.expiresAt)
+}
+
+// label names a suppression in notes
+func (s *suppression) label() string {
+	if s.Subject != "" {
+		return s.Check + " " + s.Subject
+	}
+	return s.Check
+}
+
+// loadSuppressions reads and validates a suppression file
+func loadSuppressions(filePath string) ([]*suppression, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppressions: %w", err)
+	}
+	var file suppressionFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	result := make([]*suppression, 0, len(file.Suppressions))
+	for i := range file.Suppressions {
+		s := file.Suppressions[i]
+		definition := findCheckDefinition(s.Check)
+		switch {
+		case s.Check == "":
+			return nil, fmt.Errorf("suppression %d has no check ID", i+1)
+		case definition == nil:
+			return nil, fmt.Errorf("suppression %d: unknown check ID '%s' (see 'checks' for the list)", i+1, s.Check)
+		case strings.TrimSpace(s.Reason) == "":
+			return nil, fmt.Errorf("suppression %d (%s): a reason is required", i+1, s.Check)
+		}
+		if _, err := path.Match(s.Subject, ""); err != nil {
+			return nil, fmt.Errorf("suppression %d (%s): invalid subject pattern '%s': %w", i+1, s.Check, s.Subject, err)
+		}
+		if s.Expires != "" {
+			day, err := time.ParseInLocation(time.DateOnly, s.Expires, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("suppression %d (%s): expires must be a date (YYYY-MM-DD), not '%s'", i+1, s.Check, s.Expires)
+			}
+			s.expiresAt = day.AddDate(0, 0, 1)
+		}
+		s.Check = definition.ID
+		result = append(result, &s)
+	}
+	return result, nil
+}
+
+// useSuppressions makes later findings honor the loaded suppressions and
+// reports the suppressions that matched nothing or lapsed when the run ends
+func useSuppressions(filePath string, loaded []*suppression) {
+	suppressionsMu.Lock()
+	suppressions, suppressionsPath = loaded, filePath
+	suppressionsMu.Unlock()
+	addExitHook(printSuppressionSummary)
+}
+
+// matchSuppression returns the suppression that matches a finding, or nil.
+// A suppression in force is preferred; otherwise the result may be an
+// expired waiver.
+func matchSuppression(check, subject string) *suppression {
+	suppressionsMu.Lock()
+	defer suppressionsMu.Unlock()
+	var lapsed *suppression
+	for _, s := range suppressions {
+		if s.Check != check {
+			continue
+		}
+		if s.Subject != "" {
+			if ok, _ := path.Match(s.Subject, subject); !ok {
+				continue
+			}
+		}
+		if s.expired() {
+			if lapsed == nil {
+				lapsed = s
+			}
+			continue
+		}
+		s.used = true
+		return s
+	}
+	if lapsed != nil {
+		lapsed.used = true
+	}
+	return lapsed
+}
+
+// newFinding creates a finding, marking it suppressed if a suppression
+// accepts it
+func newFinding(check, subject, format string, v ...any) finding {
+	f := finding{Check: check, Severity: severityError, Subject: subject, Message: fmt.Sprintf(format, v...)}
+	if definition := findCheckDefinition(check); definition != nil {
+		f.Severity = definition.Severity
+	}
+	if s := matchSuppression(check, subject); s != nil {
+		f.Reason = s.Reason
+		if s.expired() {
+			f.WaiverExpired = s.Expires
+			suppressionsMu.Lock()
+			s.lapsed++
+			suppressionsMu.Unlock()
+		} else {
+			f.Suppressed = true
+		}
+	}
+	return f
+}
+
+// addFinding records a problem found by a check. Unless it is suppressed or
+// below -fail-level, it is also recorded as an error of the run.
+func (r *probeReport) addFinding(check, subject, format string, v ...any) finding {
+	return r.recordFinding(newFinding(check, subject, format, v...))
+}
+
+// recordFinding records a finding created with newFinding
+func (r *probeReport) recordFinding(f finding) finding {
+	emitEvent(eventFinding, map[string]any{
+		"check":         f.Check,
+		"severity":      f.Severity,
+		"subject":       f.Subject,
+		"message":       f.Message,
+		"suppressed":    f.Suppressed,
+		"reason":        f.Reason,
+		"waiverExpired": f.WaiverExpired,
+	})
+	if f.fails() {
+		r.addError("%s", f)
+	}
+	if f.Severity == severityWarning && !f.Suppressed {
+		r.recordWarning(warningRecord{Source: warningFinding, Check: f.Check, Subject: f.Subject, Message: f.Message})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Findings = append(r.Findings, f)
+	return f
+}
+
+// printSuppressionSummary reports how many findings were suppressed, the
+// waivers that have lapsed or lapse soon, and any suppressions that matched
+// nothing, which may be stale
+func printSuppressionSummary() {
+	report.mu.Lock()
+	suppressed := 0
+	for _, f := range report.Findings {
+		if f.Suppressed {
+			suppressed++
+		}
+	}
+	report.mu.Unlock()
+
+	suppressionsMu.Lock()
+	defer suppressionsMu.Unlock()
+	if suppressed > 0 {
+		fmt.Printf("\n%d finding(s) suppressed by %s\n", suppressed, suppressionsPath)
+	}
+	for _, s := range suppressions {
+		switch {
+		case s.lapsed > 0:
+			fmt.Printf("Warning: %s\n", report.addWarning(warningProbe, "the waiver for %s expired on %s; %d finding(s) it accepted count again", s.label(), s.Expires, s.lapsed))
+		case s.expired():
+			fmt.Printf("Note: the waiver for %s expired on %s\n", s.label(), s.Expires)
+		case !s.expiresAt.IsZero() && time.Until(s.expiresAt) < waiverReminder:
+			fmt.Printf("Note: the waiver for %s expires on %s\n", s.label(), s.Expires)
+		}
+		if !s.used {
+			fmt.Printf("Note: suppression %s matched no finding in this run\n", s.label())
+		}
+	}
+}
+
+// runChecksCommand implements the 'checks' subcommand, which lists the
+// check IDs that findings and suppressions refer to
+func runChecksCommand(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: checks")
+	}
+	category := ""
+	for _, c := range checkDefinitions {
+		if c.Category != category {
+			category = c.Category
+			fmt.Printf("\n%s%s checks:\n", strings.ToUpper(category[:1]), category[1:])
+		}
+		subject := ""
+		if c.Subject != "" {
+			subject = fmt.Sprintf(" (subject: %s)", c.Subject)
+		}
+		fmt.Printf("  %s  %-7s  %s%s\n", c.ID, c.Severity, c.Title, subject)
+	}
+	return nil
+}
@@ -0,0 +1,474 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fuzzRequestBase is the first ID of the fuzzing calls, clear of the IDs the
+// client assigns and of the other raw requests
+const fuzzRequestBase = 9_000_000
+
+// Sizes of the huge strings sent to string properties
+const (
+	fuzzHugeMin = 64 << 10
+	fuzzHugeMax = 1 << 20
+)
+
+// Mutations applied to the tool's arguments
+const (
+	mutationBoundaryNumber  = "boundary-number"
+	mutationEmptyString     = "empty-string"
+	mutationHugeString      = "huge-string"
+	mutationWrongType       = "wrong-type"
+	mutationExtraProperty   = "extra-property"
+	mutationMissingRequired = "missing-required"
+	mutationOutsideEnum     = "outside-enum"
+)
+
+// fuzzMutations lists the mutations in the order they are reported
+var fuzzMutations = []string{
+	mutationBoundaryNumber, mutationEmptyString, mutationHugeString, mutationWrongType,
+	mutationExtraProperty, mutationMissingRequired, mutationOutsideEnum,
+}
+
+// Outcomes of a fuzzing call. A server should reject invalid arguments with
+// a tool error or -32602; internal errors, timeouts and crashes are problems.
+const (
+	fuzzAccepted = "accepted"
+	fuzzRejected = "rejected"
+	fuzzError    = "error"
+	fuzzTimeout  = "timeout"
+	fuzzCrash    = "crash"
+)
+
+// fuzzOutcomes lists the outcomes in the order they are reported
+var fuzzOutcomes = []string{fuzzRejected, fuzzAccepted, fuzzError, fuzzTimeout, fuzzCrash}
+
+// fuzzCommandArgs turns "fuzz [flags]" into the equivalent -fuzz flag, so
+// that fuzzing runs with the probe's usual connection options
+func fuzzCommandArgs(args []string) []string {
+	return append([]string{args[0], "-fuzz"}, args[2:]...)
+}
+
+// fuzzOptions configures a fuzzing run
+type fuzzOptions struct {
+	tool       string
+	baseline   map[string]any
+	iterations int
+	seed       uint64
+}
+
+// fuzzCase is a fuzzing call with a problem: an error, timeout or crash
+type fuzzCase struct {
+	Iteration int           `json:"iteration"`
+	Mutation  string        `json:"mutation"`
+	Input     string        `json:"input"`
+	Outcome   string        `json:"outcome"`
+	Detail    string        `json:"detail,omitempty"`
+	Duration  time.Duration `json:"durationNs"`
+}
+
+// fuzzReport is the result of a fuzzing run. Cases holds the calls with
+// problems; the others are only counted.
+type fuzzReport struct {
+	Tool       string         `json:"tool"`
+	Seed       uint64         `json:"seed"`
+	Iterations int            `json:"iterations"`
+	Outcomes   map[string]int `json:"outcomes"`
+	Cases      []fuzzCase     `json:"cases,omitempty"`
+}
+
+// fuzzProperty is a property of the tool's input schema
+type fuzzProperty struct {
+	name     string
+	kind     string
+	schema   map[string]any
+	required bool
+}
+
+// fuzzer generates mutated arguments for a tool from its input schema
+type fuzzer struct {
+	rng        *rand.Rand
+	baseline   map[string]any
+	properties []fuzzProperty
+	extra      int
+}
+
+// newFuzzer prepares the mutations of a tool's arguments. Required
+// properties missing from the baseline are given a valid value first, so
+// that each mutation is the only thing wrong with the arguments.
+func newFuzzer(tool *mcp.Tool, baseline map[string]any, seed uint64) *fuzzer {
+	properties, required := toolInputSchema(tool)
+	f := &fuzzer{rng: rand.New(rand.NewPCG(seed, seed)), baseline: maps.Clone(baseline)}
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		schema, _ := properties[name].(map[string]any)
+		p := fuzzProperty{name: name, kind: schemaType(schema), schema: schema, required: required[name]}
+		f.properties = append(f.properties, p)
+		if _, ok := f.baseline[name]; !ok && p.required {
+			f.baseline[name] = validSample(p)
+		}
+	}
+	return f
+}
+
+// schemaType returns the JSON type a property schema declares, the first
+// other than null if it lists several
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// validSample returns a value the property's schema accepts
+func validSample(p fuzzProperty) any {
+	if enum, ok := p.schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	switch p.kind {
+	case "integer", "number":
+		if minimum, ok := p.schema["minimum"].(float64); ok {
+			return minimum
+		}
+		return 1
+	case "boolean":
+		return true
+	case "array":
+		return []any{}
+	case "object":
+		return map[string]any{}
+	}
+	sample := "fuzz"
+	if minLength, ok := p.schema["minLength"].(float64); ok && int(minLength) > len(sample) {
+		sample = strings.Repeat("f", int(minLength))
+	}
+	return sample
+}
+
+// next returns the arguments of the next call: the baseline with one
+// mutation, its name and a description of what was changed
+func (f *fuzzer) next() (map[string]any, string, string) {
+	args := maps.Clone(f.baseline)
+	targets := map[string][]fuzzProperty{}
+	for _, p := range f.properties {
+		switch p.kind {
+		case "integer", "number":
+			targets[mutationBoundaryNumber] = append(targets[mutationBoundaryNumber], p)
+		case "string":
+			targets[mutationEmptyString] = append(targets[mutationEmptyString], p)
+			targets[mutationHugeString] = append(targets[mutationHugeString], p)
+		}
+		if _, ok := p.schema["enum"].([]any); ok {
+			targets[mutationOutsideEnum] = append(targets[mutationOutsideEnum], p)
+		}
+		if p.required {
+			targets[mutationMissingRequired] = append(targets[mutationMissingRequired], p)
+		}
+		targets[mutationWrongType] = append(targets[mutationWrongType], p)
+	}
+	mutations := []string{mutationExtraProperty}
+	for _, m := range fuzzMutations {
+		if len(targets[m]) > 0 {
+			mutations = append(mutations, m)
+		}
+	}
+
+	mutation := mutations[f.rng.IntN(len(mutations))]
+	if mutation == mutationExtraProperty {
+		f.extra++
+		name := fmt.Sprintf("mcpprobe_fuzz_%d", f.extra)
+		args[name] = f.anyValue()
+		return args, mutation, fmt.Sprintf("%s = %s", name, describeFuzzValue(args[name]))
+	}
+	p := targets[mutation][f.rng.IntN(len(targets[mutation]))]
+	switch mutation {
+	case mutationBoundaryNumber:
+		args[p.name] = f.boundaryNumber(p)
+	case mutationEmptyString:
+		args[p.name] = ""
+	case mutationHugeString:
+		args[p.name] = strings.Repeat(string(rune('a'+f.rng.IntN(26))), fuzzHugeMin+f.rng.IntN(fuzzHugeMax-fuzzHugeMin+1))
+	case mutationWrongType:
+		args[p.name] = f.wrongType(p.kind)
+	case mutationMissingRequired:
+		delete(args, p.name)
+		return args, mutation, fmt.Sprintf("%s omitted", p.name)
+	case mutationOutsideEnum:
+		args[p.name] = fmt.Sprintf("mcpprobe-not-in-enum-%d", f.rng.IntN(1000))
+	}
+	return args, mutation, fmt.Sprintf("%s = %s", p.name, describeFuzzValue(args[p.name]))
+}
+
+// boundaryNumber returns a number at or just beyond a boundary: the
+// schema's limits, zero, the limits of the JSON number types, or a fraction
+// for an integer
+func (f *fuzzer) boundaryNumber(p fuzzProperty) any {
+	candidates := []any{0, -1, int64(math.MaxInt64), int64(math.MinInt64), int64(1<<53 + 1), 1e308, -1e308, 5e-324}
+	if p.kind == "integer" {
+		candidates = append(candidates, 0.5)
+	}
+	if minimum, ok := p.schema["minimum"].(float64); ok {
+		candidates = append(candidates, minimum, minimum-1)
+	}
+	if maximum, ok := p.schema["maximum"].(float64); ok {
+		candidates = append(candidates, maximum, maximum+1)
+	}
+	return candidates[f.rng.IntN(len(candidates))]
+}
+
+// wrongType returns a value whose JSON type is not the declared one
+func (f *fuzzer) wrongType(kind string) any {
+	var candidates []any
+	for _, v := range []any{42, 3.5, "fuzz", true, nil, []any{"fuzz", 1}, map[string]any{"fuzz": 1}} {
+		if jsonTypeName(v) != kind && !(kind == "number" && jsonTypeName(v) == "integer") && !(kind == "integer" && v == 42) {
+			candidates = append(candidates, v)
+		}
+	}
+	return candidates[f.rng.IntN(len(candidates))]
+}
+
+// anyValue returns a value of a random JSON type
+func (f *fuzzer) anyValue() any {
+	values := []any{42, "fuzz", true, nil, []any{1}, map[string]any{"fuzz": true}}
+	return values[f.rng.IntN(len(values))]
+}
+
+// describeFuzzValue shows a mutated value, abbreviating huge strings
+func describeFuzzValue(v any) string {
+	if s, ok := v.(string); ok && len(s) > 40 {
+		return fmt.Sprintf("<%d × '%c'>", len(s), s[0])
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// runFuzz calls a tool with mutated arguments for the given number of
+// iterations and classifies how the server handled each. A server that
+// stops answering after a call is connected to again. An error is returned
+// if a finding counts as an error.
+func runFuzz(dial func(ctx context.Context) (*client.Client, error), opts fuzzOptions, timeout, callTimeout time.Duration) error {
+	fmt.Printf("=== Fuzzing: %s ===\n", opts.tool)
+
+	connect := func() (*client.Client, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		mcpClient, err := dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		if _, err := mcpClient.Initialize(ctx, newInitializeRequest()); err != nil {
+			_ = mcpClient.Close()
+			return nil, fmt.Errorf("failed to initialize: %w", err)
+		}
+		return mcpClient, nil
+	}
+	mcpClient, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = mcpClient.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	_, tool, err := resolveToolName(ctx, mcpClient, opts.tool, false)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if tool == nil {
+		return fmt.Errorf("cannot fuzz '%s' without its input schema: the tools could not be listed", opts.tool)
+	}
+	if isDestructive(tool) {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("'%s' is flagged as destructive; fuzzing it needs confirmation on a terminal", tool.Name)
+		}
+		if !confirmDestructiveCall(tool, stdinScanner()) {
+			return nil
+		}
+	}
+
+	// Without a seed a random one is used, printed so that the run can be repeated
+	for opts.seed == 0 {
+		opts.seed = rand.Uint64()
+	}
+	fz := newFuzzer(tool, opts.baseline, opts.seed)
+	fmt.Printf("Seed: %d (repeat this run with -fuzz-seed %d)\n", opts.seed, opts.seed)
+	fmt.Printf("Iterations: %d\n", opts.iterations)
+	if len(fz.baseline) > 0 {
+		fmt.Printf("Baseline arguments: %s\n", describeFuzzValue(fz.baseline))
+	}
+	fmt.Println()
+
+	result := &fuzzReport{Tool: tool.Name, Seed: opts.seed, Iterations: opts.iterations, Outcomes: map[string]int{}}
+	byMutation := map[string]map[string]int{}
+	reported := map[string]bool{}
+	var failed []string
+	for i := 1; i <= opts.iterations; i++ {
+		args, mutation, input := fz.next()
+		outcome, detail, duration := fuzzCall(mcpClient, tool.Name, args, fuzzRequestBase+int64(i), timeout, callTimeout)
+
+		if outcome == fuzzCrash {
+			_ = mcpClient.Close()
+			if mcpClient, err = connect(); err != nil {
+				mcpClient = nil
+				detail += fmt.Sprintf("; the server did not come back: %v", err)
+			}
+		}
+
+		result.Outcomes[outcome]++
+		if byMutation[mutation] == nil {
+			byMutation[mutation] = map[string]int{}
+		}
+		byMutation[mutation][outcome]++
+		line := fmt.Sprintf("  %04d  %-16s  %-40s  %-8s  %6s  %s", i, mutation, truncateText(input, 40), outcome, humanDuration(duration), truncateText(detail, 60))
+		fmt.Println(strings.TrimRight(line, " "))
+		emitEvent(eventCheck, map[string]any{
+			"id":         fmt.Sprintf("fuzz.%04d", i),
+			"mutation":   mutation,
+			"input":      input,
+			"status":     outcome,
+			"detail":     detail,
+			"durationMs": durationMillis(duration),
+		})
+
+		if outcome == fuzzError || outcome == fuzzTimeout || outcome == fuzzCrash {
+			result.Cases = append(result.Cases, fuzzCase{Iteration: i, Mutation: mutation, Input: input, Outcome: outcome, Detail: detail, Duration: duration})
+			// One finding for each kind of problem with each mutation
+			subject := tool.Name + "/" + mutation
+			key := subject + "/" + outcome
+			if !reported[key] {
+				reported[key] = true
+				checkID := checkIDFuzzCrash
+				if outcome == fuzzError {
+					checkID = checkIDFuzzError
+				}
+				f := report.addFinding(checkID, subject, "%s: %s with %s (iteration %d of seed %d): %s", subject, outcome, input, i, opts.seed, detail)
+				fmt.Printf("        %s\n", f)
+				if f.fails() {
+					failed = append(failed, fmt.Sprintf("%s %s", subject, outcome))
+				}
+			}
+		}
+		if mcpClient == nil {
+			result.Iterations = i
+			fmt.Printf("\nStopped after %d of %d iterations\n", i, opts.iterations)
+			break
+		}
+	}
+	report.setFuzz(result)
+
+	fmt.Println("\n=== Fuzzing Results ===")
+	var counts []string
+	for _, outcome := range fuzzOutcomes {
+		if n := result.Outcomes[outcome]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, outcome))
+		}
+	}
+	fmt.Printf("%d calls: %s\n\n", result.Iterations, strings.Join(counts, ", "))
+	fmt.Printf("  %-16s", "mutation")
+	for _, outcome := range fuzzOutcomes {
+		fmt.Printf("  %8s", outcome)
+	}
+	fmt.Println()
+	for _, mutation := range fuzzMutations {
+		if byMutation[mutation] == nil {
+			continue
+		}
+		fmt.Printf("  %-16s", mutation)
+		for _, outcome := range fuzzOutcomes {
+			fmt.Printf("  %8d", byMutation[mutation][outcome])
+		}
+		fmt.Println()
+	}
+	if result.Outcomes[fuzzAccepted] > 0 {
+		fmt.Println("\nEmpty strings and extra properties may be valid, but a tool that accepts wrong types or missing arguments does not validate its input.")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("fuzzing found problems: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// fuzzCall calls the tool with the mutated arguments and classifies the
+// answer, returning how long the call took. Without an answer, a ping tells
+// a busy server from one that crashed.
+func fuzzCall(mcpClient *client.Client, name string, args map[string]any, id int64, timeout, callTimeout time.Duration) (string, string, time.Duration) {
+	params, err := json.Marshal(map[string]any{"name": name, "arguments": args})
+	if err != nil {
+		return fuzzError, fmt.Sprintf("failed to encode the arguments: %v", err), 0
+	}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	response, err := mcpClient.GetTransport().SendRequest(ctx, transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(id),
+		Method:  string(mcp.MethodToolsCall),
+		Params:  json.RawMessage(params),
+	})
+	cancel()
+	duration := time.Since(start)
+
+	if err != nil {
+		outcome, detail := fuzzError, err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			outcome, detail = fuzzTimeout, fmt.Sprintf("no answer within %s", humanDuration(callTimeout))
+			sendCancelled(mcpClient, mcp.NewRequestId(id), "fuzzing call timed out")
+		}
+		pingCtx, pingCancel := context.WithTimeout(context.Background(), timeout)
+		defer pingCancel()
+		if pingErr := mcpClient.Ping(pingCtx); pingErr != nil {
+			return fuzzCrash, fmt.Sprintf("%s; then ping failed: %v", detail, pingErr), duration
+		}
+		return outcome, detail, duration
+	}
+	if response.Error != nil {
+		detail := fmt.Sprintf("error %d: %s", response.Error.Code, response.Error.Message)
+		if response.Error.Code == mcp.INVALID_PARAMS {
+			return fuzzRejected, detail, duration
+		}
+		return fuzzError, detail, duration
+	}
+	var result struct {
+		IsError bool `json:"isError"`
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return fuzzError, fmt.Sprintf("the result is not a tool result: %v", err), duration
+	}
+	if result.IsError {
+		detail := "tool error"
+		if len(result.Content) > 0 && result.Content[0].Text != "" {
+			detail += ": " + result.Content[0].Text
+		}
+		return fuzzRejected, detail, duration
+	}
+	return fuzzAccepted, "", duration
+}
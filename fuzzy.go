@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxToolSuggestions is how many close matches are suggested for an unknown tool
+const maxToolSuggestions = 5
+
+// toolMatch is a listed tool that resembles the requested name; lower
+// scores are closer
+type toolMatch struct {
+	name  string
+	score int
+}
+
+// resolveToolName checks that -call names a listed tool. An unknown name is
+// matched against the listed tools: the closest matches are suggested, or
+// with fuzzy an unambiguous best match is used instead. If the tools cannot
+// be listed, the name is used as given and the returned tool is nil.
+func resolveToolName(ctx context.Context, mcpClient *client.Client, name string, fuzzy bool) (string, *mcp.Tool, error) {
+	if mcpClient.GetServerCapabilities().Tools == nil {
+		return name, nil, nil
+	}
+	result, err := listToolsOnce(ctx, mcpClient)
+	if err != nil {
+		fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "could not list tools to check '%s': %v", name, err))
+		return name, nil, nil
+	}
+	for i, tool := range result.Tools {
+		if tool.Name == name {
+			return name, &result.Tools[i], nil
+		}
+	}
+
+	matches := matchToolName(name, result.Tools)
+	if len(matches) == 0 {
+		if len(result.Tools) == 0 {
+			return "", nil, fmt.Errorf("tool '%s' not found: the server lists no tools", name)
+		}
+		return "", nil, fmt.Errorf("tool '%s' not found and no listed tool resembles it (use -list to see the %d tools)", name, len(result.Tools))
+	}
+	suggestions := make([]string, 0, maxToolSuggestions)
+	for _, m := range matches[:min(len(matches), maxToolSuggestions)] {
+		suggestions = append(suggestions, m.name)
+	}
+
+	if !fuzzy {
+		return "", nil, fmt.Errorf("tool '%s' not found; did you mean %s? (use -fuzzy to call the closest match)", name, strings.Join(suggestions, ", "))
+	}
+	if len(matches) > 1 && matches[1].score == matches[0].score {
+		return "", nil, fmt.Errorf("tool '%s' not found and the closest matches are equally close: %s", name, strings.Join(suggestions, ", "))
+	}
+	fmt.Printf("Tool '%s' not found; using the closest match '%s'\n", name, matches[0].name)
+	for i, tool := range result.Tools {
+		if tool.Name == matches[0].name {
+			return tool.Name, &result.Tools[i], nil
+		}
+	}
+	return matches[0].name, nil, nil
+}
+
+// matchToolName returns the tools whose names resemble name, closest first.
+// Names are compared ignoring case and the separators '_', '-', '.' and '/'.
+func matchToolName(name string, tools []mcp.Tool) []toolMatch {
+	query := normalizeToolName(name)
+	var matches []toolMatch
+	for _, tool := range tools {
+		candidate := normalizeToolName(tool.Name)
+		score := -1
+		switch {
+		case candidate == query:
+			score = 0
+		case query != "" && (strings.Contains(candidate, query) || strings.Contains(query, candidate)):
+			score = 1
+		default:
+			if d := editDistance(query, candidate); d <= max(2, len(query)/3) {
+				score = 1 + d
+			}
+		}
+		if score >= 0 {
+			matches = append(matches, toolMatch{name: tool.Name, score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].name < matches[j].name
+	})
+	return matches
+}
+
+// normalizeToolName lowercases a tool name and removes its separators
+func normalizeToolName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.', '/', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
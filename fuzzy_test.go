@@ -0,0 +1,64 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"echo", "echo", 0},
+		{"ech", "echo", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchToolName(t *testing.T) {
+	tools := []mcp.Tool{
+		{Name: "get_weather"},
+		{Name: "get-weather-forecast"},
+		{Name: "echo"},
+		{Name: "search_docs"},
+		{Name: "delete_file"},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"GetWeather", []string{"get_weather", "get-weather-forecast"}},
+		{"ech", []string{"echo"}},
+		{"serch_docs", []string{"search_docs"}},
+		{"docs", []string{"search_docs"}},
+		{"upload", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range matchToolName(tt.query, tools) {
+			got = append(got, m.name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchToolName(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	matches := matchToolName("get.weather", tools)
+	if len(matches) == 0 || matches[0].score != 0 {
+		t.Errorf("a name differing only in separators should match exactly: %+v", matches)
+	}
+}
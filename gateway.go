@@ -0,0 +1,661 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// gatewayEndpointTimeout is how long the gateway waits for an SSE server to
+// announce its message endpoint
+const gatewayEndpointTimeout = 30 * time.Second
+
+// stdioGateway bridges an MCP client speaking stdio to a remote server. Each
+// line the client writes to stdin is sent to the server as is, and each
+// message from the server is written to stdout as one line, so the client
+// negotiates with the server directly.
+type stdioGateway struct {
+	endpoint  *url.URL
+	transport string
+	headers   map[string]string
+	oauth     *transport.OAuthConfig
+	client    *http.Client
+	logger    *log.Logger
+	logBodies bool
+	recorder  *sessionRecorder
+	tracer    *wireTracer
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	mu sync.Mutex
+	// session and version are sent with every streamable HTTP request once
+	// the server has answered initialize
+	session string
+	version string
+	// listening is set once the stream of server-initiated messages is open
+	listening bool
+	// messages is the endpoint announced by an SSE server
+	messages *url.URL
+	ready    chan struct{}
+	requests sync.WaitGroup
+}
+
+// runGatewayCommand implements the 'gateway' subcommand
+func runGatewayCommand(args []string) error {
+	fs := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	target := fs.String("url", "", "URL of the remote MCP server (required)")
+	transportName := fs.String("transport", "http", "Transport of the remote server: 'http' or 'sse'")
+	headers := fs.String("headers", "", "HTTP headers in format 'key1:value1,key2:value2' (${VAR} expansion)")
+	var headerList headerFlags
+	fs.Var(&headerList, "H", "HTTP header in format 'Key: Value' (repeatable; values may contain commas and colons)")
+	bearerFile := fs.String("bearer-token-file", "", "File containing the bearer token to send in the Authorization header")
+	caCert := fs.String("ca-cert", "", "PEM file with CA certificates to trust in addition to the system roots")
+	insecure := fs.Bool("insecure", false, "Skip TLS certificate verification (lab environments only)")
+	proxyFlag := fs.String("proxy", "", "Proxy for connections to the server: http://, https://, socks5:// or socks5h:// URL (default: HTTP_PROXY/HTTPS_PROXY)")
+	useOAuth := fs.Bool("oauth", false, "Authorize with the server's OAuth 2.1 authorization code flow (PKCE) before bridging")
+	oauthClient := fs.String("oauth-client-id", "", "OAuth client ID (default: register a client dynamically)")
+	oauthScopes := fs.String("oauth-scopes", "", "OAuth scopes to request (comma or space separated)")
+	oauthPort := fs.Int("oauth-port", 0, "Local port for the OAuth redirect listener (default: random)")
+	oauthCC := fs.Bool("oauth-client-credentials", false, "Obtain an OAuth token with the client credentials grant (no browser)")
+	oauthSecret := fs.String("oauth-client-secret", "", "OAuth client secret for -oauth-client-credentials (${VAR} expansion)")
+	oauthToken := fs.String("oauth-token-url", "", "OAuth token endpoint (default: discovered from the server)")
+	noTokenCache := fs.Bool("no-token-cache", false, "Do not read or write cached OAuth tokens")
+	logFile := fs.String("log", "", "Log the bridged traffic to this file instead of stderr")
+	logBodies := fs.Bool("log-bodies", true, "Log message bodies")
+	pretty := fs.Bool("pretty", false, "Log each JSON-RPC message as pretty-printed JSON with its direction and timing, instead of raw bodies")
+	record := fs.String("record", "", "Record the bridged traffic to this session recording file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *target == "" {
+		return fmt.Errorf("-url is required")
+	}
+	endpoint, err := url.Parse(*target)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return fmt.Errorf("invalid -url '%s'", *target)
+	}
+	if *transportName != "http" && *transportName != "sse" {
+		return fmt.Errorf("-transport must be 'http' or 'sse'")
+	}
+	if *useOAuth && *oauthCC {
+		return fmt.Errorf("-oauth and -oauth-client-credentials cannot be used together")
+	}
+	if *oauthCC && (*oauthClient == "" || *oauthSecret == "") {
+		return fmt.Errorf("-oauth-client-credentials requires -oauth-client-id and -oauth-client-secret")
+	}
+	if *bearerFile != "" && (*useOAuth || *oauthCC) {
+		return fmt.Errorf("-bearer-token-file cannot be combined with -oauth or -oauth-client-credentials")
+	}
+
+	// The same headers, TLS and proxy settings as the probe's own connections
+	headerMap := mergeHeaders(parseHeaders(*headers), headerList.toMap())
+	if *bearerFile != "" {
+		token, err := readBearerTokenFile(*bearerFile)
+		if err != nil {
+			return err
+		}
+		headerMap["Authorization"] = "Bearer " + token
+	}
+	if headerMap, err = expandHeaderVars(headerMap); err != nil {
+		return fmt.Errorf("invalid headers: %w", err)
+	}
+	if probeTLSConfig, err = loadTLSConfig(*caCert, *insecure); err != nil {
+		return fmt.Errorf("invalid TLS options: %w", err)
+	}
+	if *proxyFlag != "" {
+		if probeProxyURL, err = parseProxyURL(*proxyFlag); err != nil {
+			return err
+		}
+	}
+
+	// Messages printed while authorizing must not reach the client
+	redirectInfoToStderr()
+
+	// stdout belongs to the client, so everything else goes to the log
+	logOut := os.Stderr
+	if *logFile != "" {
+		if logOut, err = os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer func() { _ = logOut.Close() }()
+	}
+
+	if *insecure {
+		fmt.Fprintf(logOut, "Warning: %s\n", report.addWarning(warningProbe, "TLS certificate verification is disabled (-insecure)"))
+	}
+
+	var oauth *transport.OAuthConfig
+	switch {
+	case *useOAuth:
+		oauth, err = authorizeOAuth(*target, oauthOptions{
+			clientID: *oauthClient,
+			scopes:   parseScopes(*oauthScopes),
+			port:     *oauthPort,
+			cache:    !*noTokenCache,
+		})
+		if err != nil {
+			return fmt.Errorf("OAuth authorization failed: %w", err)
+		}
+	case *oauthCC:
+		secret, err := expandEnvVars(*oauthSecret, nil)
+		if err != nil {
+			return fmt.Errorf("invalid -oauth-client-secret: %w", err)
+		}
+		oauth, err = clientCredentialsOAuth(*target, oauthOptions{
+			clientID:     *oauthClient,
+			clientSecret: secret,
+			tokenURL:     *oauthToken,
+			scopes:       parseScopes(*oauthScopes),
+			cache:        !*noTokenCache,
+		})
+		if err != nil {
+			return fmt.Errorf("OAuth token request failed: %w", err)
+		}
+	}
+
+	g := &stdioGateway{
+		endpoint:  endpoint,
+		transport: *transportName,
+		headers:   headerMap,
+		oauth:     oauth,
+		client:    withOAuthRetry(newProbeHTTPClient(0, 0), oauth),
+		logger:    log.New(logOut, "", log.Ltime|log.Lmicroseconds),
+		logBodies: *logBodies,
+		out:       resultOut,
+		ready:     make(chan struct{}),
+	}
+	if *pretty {
+		g.tracer = newWireTracerTo(logOut)
+		g.logBodies = false
+	}
+	if *record != "" {
+		if g.recorder, err = newSessionRecorder(*record); err != nil {
+			return err
+		}
+		defer func() { _ = g.recorder.Close() }()
+	}
+
+	g.logger.Printf("Bridging stdio -> %s (%s)", endpoint, g.transport)
+	if *record != "" {
+		g.logger.Printf("Recording session to %s", *record)
+	}
+	if g.transport == "sse" {
+		if err := g.openSSE(); err != nil {
+			return err
+		}
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			g.forward(line)
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return fmt.Errorf("failed to read stdin: %w", err)
+			}
+			break
+		}
+	}
+
+	g.logger.Printf("Client closed stdin")
+	g.requests.Wait()
+	g.endSession()
+	return nil
+}
+
+// forward sends a message (or batch) from the client to the server. Requests
+// are sent concurrently, as their responses may take a while; notifications
+// and responses are sent in order.
+func (g *stdioGateway) forward(body []byte) {
+	messages := parseGatewayMessages(body)
+	g.logger.Printf("-> %s%s", describeGatewayMessages(messages), g.bodyForLog(body))
+	g.inspect(g.recordFor(directionClient, 0), body)
+
+	hasRequest := false
+	for _, m := range messages {
+		if m.Method != "" && len(m.ID) > 0 {
+			hasRequest = true
+		}
+	}
+	if !hasRequest {
+		g.post(body, messages)
+		for _, m := range messages {
+			if m.Method == "notifications/initialized" {
+				g.listen()
+			}
+		}
+		return
+	}
+	g.requests.Add(1)
+	go func() {
+		defer g.requests.Done()
+		g.post(body, messages)
+	}()
+}
+
+// post sends a message to the server and relays what it answers. Requests
+// that fail without a JSON-RPC answer are answered with an error, so that
+// the client does not wait for them forever.
+func (g *stdioGateway) post(body []byte, messages []jsonrpcMessage) {
+	target := g.endpoint
+	if g.transport == "sse" {
+		select {
+		case <-g.ready:
+		case <-time.After(gatewayEndpointTimeout):
+			g.fail(messages, "the server announced no message endpoint")
+			return
+		}
+		g.mu.Lock()
+		target = g.messages
+		g.mu.Unlock()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		g.fail(messages, err.Error())
+		return
+	}
+	g.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	start := time.Now()
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.logger.Printf("<- request failed: %v", err)
+		g.fail(messages, err.Error())
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if session := resp.Header.Get(mcpSessionHeader); session != "" {
+		g.mu.Lock()
+		g.session = session
+		g.mu.Unlock()
+	}
+	rec := g.recordFor(directionServer, resp.StatusCode)
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		g.logger.Printf("<- %d event stream (%s)", resp.StatusCode, humanDuration(time.Since(start)))
+		g.readEvents(resp.Body, func(name string, data []byte) {
+			if name == "message" {
+				g.deliver(rec, data)
+			}
+		})
+		return
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		g.logger.Printf("<- %d failed to read response: %v", resp.StatusCode, err)
+		g.fail(messages, err.Error())
+		return
+	}
+	respBody = bytes.TrimSpace(respBody)
+	// SSE servers answer on the event stream; an HTTP error carrying
+	// JSON-RPC errors is relayed as is
+	isJSONRPC := len(parseGatewayMessages(respBody)) > 0
+	failed := resp.StatusCode >= 400 && (g.transport == "sse" || !isJSONRPC)
+	note := ""
+	if failed {
+		note = g.bodyForLog(respBody)
+	}
+	g.logger.Printf("<- %d %s (%s)%s", resp.StatusCode, resp.Header.Get("Content-Type"), humanDuration(time.Since(start)), note)
+	switch {
+	case failed:
+		if g.recorder != nil {
+			rec.Error = resp.Status
+			g.recorder.record(rec)
+		}
+		g.fail(messages, "HTTP "+resp.Status)
+	case g.transport == "sse" || len(respBody) == 0:
+	case !isJSONRPC:
+		g.fail(messages, "the server answered with something other than JSON-RPC")
+	default:
+		g.deliver(rec, respBody)
+	}
+}
+
+// deliver writes the messages of a server body to the client, one per line
+func (g *stdioGateway) deliver(rec sessionRecord, body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+	g.logger.Printf("<- %s%s", describeGatewayMessages(parseGatewayMessages(body)), g.bodyForLog(body))
+	g.inspect(rec, body)
+	g.noteVersion(body)
+
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		g.logger.Printf("   not forwarded: the server sent invalid JSON")
+		return
+	}
+	g.writeLine(line.Bytes())
+}
+
+// fail answers each request among the messages with a JSON-RPC error
+func (g *stdioGateway) fail(messages []jsonrpcMessage, reason string) {
+	for _, m := range messages {
+		if m.Method == "" || len(m.ID) == 0 {
+			continue
+		}
+		g.logger.Printf("   answering request %s %s with an error: %s", m.ID, m.Method, reason)
+		answer, err := json.Marshal(map[string]any{
+			"jsonrpc": mcp.JSONRPC_VERSION,
+			"id":      m.ID,
+			"error": map[string]any{
+				"code":    mcp.INTERNAL_ERROR,
+				"message": fmt.Sprintf("%s gateway: %s", ProgName, reason),
+			},
+		})
+		if err == nil {
+			g.writeLine(answer)
+		}
+	}
+}
+
+// writeLine writes one message to the client
+func (g *stdioGateway) writeLine(line []byte) {
+	g.outMu.Lock()
+	defer g.outMu.Unlock()
+	_, _ = g.out.Write(append(line, '\n'))
+}
+
+// noteVersion remembers the protocol version the server answered initialize
+// with, which later streamable HTTP requests must carry
+func (g *stdioGateway) noteVersion(body []byte) {
+	var response struct {
+		Result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(body, &response) == nil && response.Result.ProtocolVersion != "" {
+		g.mu.Lock()
+		g.version = response.Result.ProtocolVersion
+		g.mu.Unlock()
+	}
+}
+
+// setHeaders adds the configured headers, the OAuth token and, for
+// streamable HTTP, the session and protocol version
+func (g *stdioGateway) setHeaders(req *http.Request) {
+	for key, value := range g.headers {
+		req.Header.Set(key, value)
+	}
+	if g.oauth != nil {
+		if token, err := g.oauth.TokenStore.GetToken(req.Context()); err == nil {
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		}
+	}
+	if g.transport != "http" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.session != "" {
+		req.Header.Set(mcpSessionHeader, g.session)
+	}
+	if g.version != "" {
+		req.Header.Set("MCP-Protocol-Version", g.version)
+	}
+}
+
+// listen opens the streamable HTTP stream of server-initiated messages, such
+// as notifications and sampling requests, once the session is initialized
+func (g *stdioGateway) listen() {
+	g.mu.Lock()
+	if g.transport != "http" || g.listening {
+		g.mu.Unlock()
+		return
+	}
+	g.listening = true
+	g.mu.Unlock()
+
+	go func() {
+		req, err := http.NewRequest(http.MethodGet, g.endpoint.String(), nil)
+		if err != nil {
+			return
+		}
+		g.setHeaders(req)
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := g.client.Do(req)
+		if err != nil {
+			g.logger.Printf("<- failed to open the stream of server messages: %v", err)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			g.logger.Printf("<- %d no stream of server messages", resp.StatusCode)
+			return
+		}
+		g.logger.Printf("<- %d stream of server messages opened", resp.StatusCode)
+		rec := g.recordFor(directionServer, resp.StatusCode)
+		rec.HTTPMethod = http.MethodGet
+		g.readEvents(resp.Body, func(name string, data []byte) {
+			if name == "message" {
+				g.deliver(rec, data)
+			}
+		})
+		g.logger.Printf("   stream of server messages closed")
+	}()
+}
+
+// openSSE connects to an SSE server and waits for its message endpoint.
+// Everything the server sends arrives on this stream; the gateway exits
+// when the server closes it, as the session is then over.
+func (g *stdioGateway) openSSE() error {
+	req, err := http.NewRequest(http.MethodGet, g.endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create SSE request: %w", err)
+	}
+	g.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SSE server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return fmt.Errorf("SSE server answered %s", resp.Status)
+	}
+	g.logger.Printf("<- %d event stream opened", resp.StatusCode)
+
+	go func() {
+		defer func() { _ = resp.Body.Close() }()
+		g.readEvents(resp.Body, func(name string, data []byte) {
+			switch name {
+			case "endpoint":
+				g.setMessageEndpoint(string(data))
+			case "message":
+				rec := g.recordFor(directionServer, resp.StatusCode)
+				rec.HTTPMethod = http.MethodGet
+				rec.Path = g.endpoint.Path
+				g.deliver(rec, data)
+			}
+		})
+		g.logger.Printf("   the server closed the event stream")
+		os.Exit(1)
+	}()
+
+	select {
+	case <-g.ready:
+		return nil
+	case <-time.After(gatewayEndpointTimeout):
+		return fmt.Errorf("the SSE server announced no message endpoint within %s", humanDuration(gatewayEndpointTimeout))
+	}
+}
+
+// setMessageEndpoint resolves the endpoint announced by an SSE server
+func (g *stdioGateway) setMessageEndpoint(data string) {
+	endpoint, err := g.endpoint.Parse(strings.TrimSpace(data))
+	if err != nil {
+		g.logger.Printf("   invalid endpoint event: %v", err)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.messages != nil {
+		return
+	}
+	g.messages = endpoint
+	g.session = endpoint.Query().Get("sessionId")
+	if g.session == "" {
+		g.session = endpoint.Query().Get("session_id")
+	}
+	g.logger.Printf("   message endpoint %s", endpoint)
+	close(g.ready)
+}
+
+// endSession terminates the streamable HTTP session, as a client should
+// when it is done
+func (g *stdioGateway) endSession() {
+	g.mu.Lock()
+	session := g.session
+	g.mu.Unlock()
+	if g.transport != "http" || session == "" {
+		return
+	}
+	req, err := http.NewRequest(http.MethodDelete, g.endpoint.String(), nil)
+	if err != nil {
+		return
+	}
+	g.setHeaders(req)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.logger.Printf("-> DELETE session failed: %v", err)
+		return
+	}
+	_ = resp.Body.Close()
+	g.logger.Printf("-> DELETE session %s: %d", session, resp.StatusCode)
+}
+
+// readEvents calls handle with the name and data of each event of an SSE
+// stream until it ends
+func (g *stdioGateway) readEvents(body io.Reader, handle func(name string, data []byte)) {
+	reader := bufio.NewReader(body)
+	var event []string
+	flush := func() {
+		if len(event) == 0 {
+			return
+		}
+		name := "message"
+		for _, line := range event {
+			if v, ok := strings.CutPrefix(line, "event:"); ok {
+				name = strings.TrimSpace(v)
+			}
+		}
+		handle(name, eventData(event))
+		event = nil
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" && err == nil {
+			flush()
+			continue
+		}
+		if line != "" {
+			event = append(event, line)
+		}
+		if err != nil {
+			flush()
+			return
+		}
+	}
+}
+
+// inspect records and pretty-prints the messages of a body, as configured
+func (g *stdioGateway) inspect(rec sessionRecord, body []byte) {
+	if g.recorder != nil {
+		g.recorder.recordMessages(rec, body)
+	}
+	if g.tracer != nil {
+		g.tracer.traceMessages(rec, body)
+	}
+}
+
+// recordFor returns a session record of a message to or from the server
+func (g *stdioGateway) recordFor(direction string, status int) sessionRecord {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	path := g.endpoint.Path
+	if g.messages != nil {
+		path = g.messages.Path
+	}
+	return sessionRecord{
+		Direction:  direction,
+		Transport:  g.transport,
+		HTTPMethod: http.MethodPost,
+		Path:       path,
+		Status:     status,
+		Session:    g.session,
+	}
+}
+
+// bodyForLog formats a body for logging, truncating long bodies
+func (g *stdioGateway) bodyForLog(body []byte) string {
+	if !g.logBodies {
+		return ""
+	}
+	return formatLogBody(body)
+}
+
+// parseGatewayMessages returns the messages of a body, which may be a single
+// message or a batch; it returns none if the body is not JSON-RPC
+func parseGatewayMessages(body []byte) []jsonrpcMessage {
+	var batch []jsonrpcMessage
+	if len(body) > 0 && body[0] == '[' {
+		if json.Unmarshal(body, &batch) != nil {
+			return nil
+		}
+		return batch
+	}
+	var m struct {
+		jsonrpcMessage
+		JSONRPC string `json:"jsonrpc"`
+	}
+	if json.Unmarshal(body, &m) != nil || m.JSONRPC == "" {
+		return nil
+	}
+	return []jsonrpcMessage{m.jsonrpcMessage}
+}
+
+// describeGatewayMessages summarizes messages for the log
+func describeGatewayMessages(messages []jsonrpcMessage) string {
+	switch {
+	case len(messages) == 0:
+		return "not JSON-RPC"
+	case len(messages) > 1:
+		return fmt.Sprintf("batch of %d messages", len(messages))
+	}
+	m := messages[0]
+	switch {
+	case m.Method != "" && len(m.ID) > 0:
+		return fmt.Sprintf("request %s %s", m.ID, m.Method)
+	case m.Method != "":
+		return "notification " + m.Method
+	default:
+		return "response " + string(m.ID)
+	}
+}
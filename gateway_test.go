@@ -0,0 +1,100 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGateway(t *testing.T) {
+	failCalls := interceptMethod("tools/call", func(w http.ResponseWriter, _ json.RawMessage) {
+		http.Error(w, "crashed", http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name     string
+		wrap     func(http.Handler) http.Handler
+		args     []string
+		exitCode int
+		output   string
+		logged   []string
+	}{
+		{
+			name:   "bridged",
+			args:   []string{"-call", "echo", "-params", `{"text":"through the gateway"}`},
+			output: "through the gateway",
+			logged: []string{"-> request 1 initialize", "-> notification notifications/initialized", "tools/call", `{"type":"text","text":"through the gateway"}`},
+		},
+		{
+			name:   "listed",
+			args:   []string{"-list-only"},
+			output: "echo",
+			logged: []string{"tools/list", `"name":"echo"`},
+		},
+		{
+			name:     "server error",
+			wrap:     failCalls,
+			args:     []string{"-call", "echo", "-params", `{"text":"hi"}`},
+			exitCode: 1,
+			output:   "MCPProbe gateway: HTTP 500 Internal Server Error",
+			logged:   []string{"<- 500", "tools/call with an error: HTTP 500"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverURL := serveMock(t, defaultMockConfig, tt.wrap)
+			logFile := filepath.Join(t.TempDir(), "gateway.log")
+			// The probe starts the test binary as its stdio server, which
+			// runs the gateway
+			args := append([]string{
+				"-stdio", os.Args[0],
+				"-args", strings.Join([]string{"gateway", "-url", serverURL, "-log", logFile}, ","),
+				"-env", envTestMain + "=1",
+			}, tt.args...)
+			code, output := runProbe(t, args...)
+			if code != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", code, tt.exitCode)
+			}
+			if !strings.Contains(output, tt.output) {
+				t.Errorf("the output does not contain %q", tt.output)
+			}
+			gatewayLog, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.logged {
+				if !strings.Contains(string(gatewayLog), want) {
+					t.Errorf("the gateway did not log %q", want)
+				}
+			}
+			if t.Failed() {
+				t.Logf("output:\n%s\ngateway log:\n%s", output, gatewayLog)
+			}
+		})
+	}
+}
+
+func TestGatewayOptions(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "-url is required"},
+		{[]string{"-url", "mock.example.com/mcp"}, "invalid -url"},
+		{[]string{"-url", "http://127.0.0.1:1/mcp", "-transport", "stdio"}, "-transport must be 'http' or 'sse'"},
+		{[]string{"-url", "http://127.0.0.1:1/mcp", "-oauth", "-oauth-client-credentials"}, "cannot be used together"},
+		{[]string{"-url", "http://127.0.0.1:1/mcp", "-oauth-client-credentials", "-oauth-client-id", "probe"}, "requires -oauth-client-id and -oauth-client-secret"},
+		{[]string{"-url", "http://127.0.0.1:1/mcp", "-oauth", "-bearer-token-file", "token.txt"}, "cannot be combined with -oauth"},
+	}
+	for _, tt := range tests {
+		if err := runGatewayCommand(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("gateway %v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
@@ -2,11 +2,14 @@ module MCPProbe
 
 go 1.24.3
 
-require github.com/mark3labs/mcp-go v0.46.0
+require (
+	github.com/google/jsonschema-go v0.4.2
+	github.com/mark3labs/mcp-go v0.46.0
+	github.com/yosida95/uritemplate/v3 v3.0.2
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/google/jsonschema-go v0.4.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/spf13/cast v1.10.0 // indirect
-	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 )
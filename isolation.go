@@ -0,0 +1,487 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// isolationRounds is the number of rounds of interleaved operations that
+// every session runs
+const isolationRounds = 3
+
+// isolationQuiet is how long the test waits after a session's call for the
+// notifications the call caused to arrive
+const isolationQuiet = 500 * time.Millisecond
+
+// isolationDefaultSessions is the number of sessions the isolation test
+// opens without -sessions
+const isolationDefaultSessions = 3
+
+// isolationProgressPrefix starts the progress tokens of the isolation test's
+// calls, which name the session that made the call
+const isolationProgressPrefix = "mcpprobe-isolation-"
+
+// isolationCommandArgs turns "isolation-test [flags]" into the equivalent
+// -isolation-test flag, so that the test uses the probe's usual connection
+// options
+func isolationCommandArgs(args []string) []string {
+	return append([]string{args[0], "-isolation-test"}, args[2:]...)
+}
+
+// isolationNotification is a notification a session received
+type isolationNotification struct {
+	Method string
+	// Token is the progress token of a progress notification
+	Token string
+	// URI is the resource of a resource update
+	URI string
+	At  time.Time
+}
+
+// isolationSession is one of the isolation test's sessions and the
+// notifications it received
+type isolationSession struct {
+	index    int
+	client   *client.Client
+	id       string
+	mu       sync.Mutex
+	received []isolationNotification
+}
+
+// observe records a notification the session received
+func (s *isolationSession) observe(n mcp.JSONRPCNotification) {
+	note := isolationNotification{Method: n.Method, At: time.Now()}
+	if token, ok := n.Params.AdditionalFields["progressToken"]; ok {
+		note.Token = fmt.Sprint(token)
+	}
+	note.URI, _ = n.Params.AdditionalFields["uri"].(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = append(s.received, note)
+}
+
+// since returns the notifications the session received since t
+func (s *isolationSession) since(t time.Time) []isolationNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notes []isolationNotification
+	for _, note := range s.received {
+		if !note.At.Before(t) {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// call calls the tool with a progress token that names the session
+func (s *isolationSession) call(tool string, args map[string]any, n int, callTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	request.Params.Arguments = args
+	request.Params.Meta = &mcp.Meta{ProgressToken: fmt.Sprintf("%s%d-%d", isolationProgressPrefix, s.index, n)}
+	result, err := s.client.CallTool(ctx, request)
+	if err != nil {
+		return err
+	}
+	if result.IsError {
+		return fmt.Errorf("the tool returned an error")
+	}
+	return nil
+}
+
+// isolationBleed is a notification delivered to a session it was not meant
+// for, or a session ID shared by sessions
+type isolationBleed struct {
+	Check   string `json:"check"`
+	Session int    `json:"session"`
+	Method  string `json:"method,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+// isolationReport is the result of the isolation test
+type isolationReport struct {
+	Sessions      int              `json:"sessions"`
+	SessionIDs    []string         `json:"sessionIds,omitempty"`
+	Notifications []int            `json:"notifications"`
+	Bleeds        []isolationBleed `json:"bleeds,omitempty"`
+}
+
+// runIsolationTest opens several sessions to the server at once, runs
+// interleaved operations on each and checks that nothing of one session
+// shows up in another: each session has its own session ID, the progress
+// notifications of a call reach the session that made it, a list_changed
+// notification caused by a session's call does not reach other sessions
+// only, and resource updates reach only the sessions subscribed to the
+// resource. It returns an error if a finding counts as an error.
+func runIsolationTest(dial func(ctx context.Context) (*client.Client, error), count int, tool string, args map[string]any, window, timeout, callTimeout time.Duration) error {
+	fmt.Printf("=== Session Isolation: %d sessions ===\n", count)
+
+	// The sessions are opened at once, as concurrent clients would
+	sessions := make([]*isolationSession, count)
+	defer func() {
+		for _, s := range sessions {
+			if s != nil {
+				_ = s.client.Close()
+			}
+		}
+	}()
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The streams that carry notifications live as long as the
+			// context the session is started with
+			mcpClient, err := dial(context.Background())
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			s := &isolationSession{index: i + 1, client: mcpClient}
+			mcpClient.OnNotification(s.observe)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if _, err := mcpClient.Initialize(ctx, newInitializeRequest()); err != nil {
+				_ = mcpClient.Close()
+				errs[i] = err
+				return
+			}
+			s.id = mcpClient.GetSessionId()
+			sessions[i] = s
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to open session %d of %d: %w", i+1, count, err)
+		}
+	}
+
+	result := &isolationReport{Sessions: count}
+	defer report.setIsolation(result)
+	for _, s := range sessions {
+		result.SessionIDs = append(result.SessionIDs, s.id)
+		fmt.Printf("Session %d: %s\n", s.index, valueOr(s.id, "no session ID"))
+	}
+	caps := sessions[0].client.GetServerCapabilities()
+
+	if tool != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		name, found, err := resolveToolName(ctx, sessions[0].client, tool, false)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if found != nil && isDestructive(found) {
+			if !stdinIsTerminal() {
+				return fmt.Errorf("'%s' is flagged as destructive; calling it from every session needs confirmation on a terminal", found.Name)
+			}
+			if !confirmDestructiveCall(found, stdinScanner()) {
+				return nil
+			}
+		}
+		tool = name
+	}
+	fmt.Println()
+
+	var summaries []checkSummary
+	var failed []string
+	record := func(id string, start time.Time, status, detail string, f *finding) {
+		summary := checkSummary{ID: id, Status: status, Runs: 1, AvgTime: time.Since(start)}
+		switch status {
+		case checkPass:
+			summary.Passed = 1
+		case checkSkip:
+			summary.Skipped = 1
+		default:
+			summary.Failed = 1
+			summary.Errors = []string{detail}
+		}
+		if f != nil && f.Suppressed {
+			summary.Suppressed = true
+		}
+		summaries = append(summaries, summary)
+		emitEvent(eventCheck, map[string]any{
+			"id":            id,
+			"status":        status,
+			"detail":        detail,
+			"avgDurationMs": durationMillis(summary.AvgTime),
+		})
+		fmt.Printf("  %-5s  %-28s %s\n", strings.ToUpper(status), id, detail)
+		if f != nil {
+			fmt.Printf("         %s\n", f)
+			if f.fails() {
+				failed = append(failed, id)
+			}
+		}
+	}
+	// bleed records notifications delivered to the wrong session and fails
+	// the check with one finding for all of them
+	bleed := func(id string, start time.Time, bleeds []isolationBleed) {
+		result.Bleeds = append(result.Bleeds, bleeds...)
+		more := ""
+		if len(bleeds) > 1 {
+			more = fmt.Sprintf(" (and %d more)", len(bleeds)-1)
+		}
+		f := report.addFinding(checkIDSessionBleed, id, "%s%s", bleeds[0].Detail, more)
+		record(id, start, checkFail, fmt.Sprintf("%d misdelivered", len(bleeds)), &f)
+	}
+
+	// Each session must have a session ID of its own
+	start := time.Now()
+	owners := map[string]int{}
+	var shared []isolationBleed
+	for _, s := range sessions {
+		if s.id == "" {
+			continue
+		}
+		if first, ok := owners[s.id]; ok {
+			shared = append(shared, isolationBleed{Check: "isolation.session-ids", Session: s.index, Detail: fmt.Sprintf("session %d was assigned the session ID of session %d", s.index, first)})
+			continue
+		}
+		owners[s.id] = s.index
+	}
+	switch {
+	case len(shared) > 0:
+		bleed("isolation.session-ids", start, shared)
+	case len(owners) == 0:
+		record("isolation.session-ids", start, checkSkip, "the server assigns no session IDs", nil)
+	default:
+		record("isolation.session-ids", start, checkPass, fmt.Sprintf("%d distinct session IDs", len(owners)), nil)
+	}
+
+	// Interleaved operations: every session runs each round at the same time
+	start = time.Now()
+	var mu sync.Mutex
+	var opErrors []string
+	operations := 0
+	for round := 1; round <= isolationRounds; round++ {
+		for _, s := range sessions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				fail := func(op string, err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					opErrors = append(opErrors, fmt.Sprintf("session %d round %d %s: %v", s.index, round, op, err))
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				n := 1
+				if err := s.client.Ping(ctx); err != nil {
+					fail("ping", err)
+				}
+				if caps.Tools != nil {
+					n++
+					if _, err := s.client.ListTools(ctx, mcp.ListToolsRequest{}); err != nil {
+						fail(string(mcp.MethodToolsList), err)
+					}
+				}
+				if tool != "" {
+					n++
+					if err := s.call(tool, args, round, callTimeout); err != nil {
+						fail("tools/call "+tool, err)
+					}
+				}
+				mu.Lock()
+				operations += n
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	}
+	if len(opErrors) > 0 {
+		f := report.addFinding(checkIDSessionConcurrency, "isolation.operations", "%d of %d operations failed with %d sessions open: %s", len(opErrors), operations, count, opErrors[0])
+		record("isolation.operations", start, checkFail, fmt.Sprintf("%d of %d operations failed", len(opErrors), operations), &f)
+	} else {
+		record("isolation.operations", start, checkPass, fmt.Sprintf("%d operations over %d rounds", operations, isolationRounds), nil)
+	}
+
+	// Each session calls the tool alone, so that the list_changed
+	// notifications that follow can be traced to its call
+	start = time.Now()
+	var misrouted []isolationBleed
+	broadcast, own := 0, 0
+	if tool != "" {
+		for _, s := range sessions {
+			turn := time.Now()
+			_ = s.call(tool, args, isolationRounds+1, callTimeout)
+			time.Sleep(isolationQuiet)
+			methods := map[string][]int{}
+			for _, other := range sessions {
+				for _, note := range other.since(turn) {
+					if strings.HasSuffix(note.Method, "/list_changed") && !slices.Contains(methods[note.Method], other.index) {
+						methods[note.Method] = append(methods[note.Method], other.index)
+					}
+				}
+			}
+			for method, receivers := range methods {
+				switch {
+				case len(receivers) == count:
+					broadcast++
+				case slices.Contains(receivers, s.index):
+					own++
+				default:
+					names := make([]string, len(receivers))
+					for i, receiver := range receivers {
+						names[i] = fmt.Sprint(receiver)
+					}
+					misrouted = append(misrouted, isolationBleed{Check: "isolation.list-changed", Session: receivers[0], Method: method,
+						Detail: fmt.Sprintf("%s after a call of session %d reached session %s but not session %d", method, s.index, strings.Join(names, ", "), s.index)})
+				}
+			}
+		}
+	}
+	switch {
+	case tool == "":
+		record("isolation.list-changed", start, checkSkip, "no -call tool to change the server's lists", nil)
+	case len(misrouted) > 0:
+		bleed("isolation.list-changed", start, misrouted)
+	case broadcast+own == 0:
+		record("isolation.list-changed", start, checkSkip, fmt.Sprintf("calling '%s' sent no list_changed notifications", tool), nil)
+	default:
+		record("isolation.list-changed", start, checkPass, fmt.Sprintf("%d reached the calling session only, %d every session", own, broadcast), nil)
+	}
+
+	// The progress notifications of every call so far must have reached
+	// the session that made it
+	start = time.Now()
+	var strays []isolationBleed
+	progress := 0
+	for _, s := range sessions {
+		for _, note := range s.since(time.Time{}) {
+			rest, ok := strings.CutPrefix(note.Token, isolationProgressPrefix)
+			if note.Method != "notifications/progress" || !ok {
+				continue
+			}
+			progress++
+			var owner, n int
+			if _, err := fmt.Sscanf(rest, "%d-%d", &owner, &n); err == nil && owner != s.index {
+				strays = append(strays, isolationBleed{Check: "isolation.progress", Session: s.index, Method: note.Method,
+					Detail: fmt.Sprintf("progress of a call of session %d (token %s) reached session %d", owner, note.Token, s.index)})
+			}
+		}
+	}
+	switch {
+	case tool == "":
+		record("isolation.progress", start, checkSkip, "no -call tool to report progress", nil)
+	case len(strays) > 0:
+		bleed("isolation.progress", start, strays)
+	case progress == 0:
+		record("isolation.progress", start, checkSkip, fmt.Sprintf("'%s' reports no progress", tool), nil)
+	default:
+		record("isolation.progress", start, checkPass, fmt.Sprintf("%d progress notifications, each on the calling session", progress), nil)
+	}
+
+	// Each session subscribes to a different resource, and must only be
+	// told about updates of its own
+	start = time.Now()
+	switch updates, strays, detail := isolateSubscriptions(sessions, window, timeout); {
+	case detail != "":
+		record("isolation.subscriptions", start, checkSkip, detail, nil)
+	case len(strays) > 0:
+		bleed("isolation.subscriptions", start, strays)
+	case updates == 0:
+		record("isolation.subscriptions", start, checkSkip, fmt.Sprintf("no resource updates in %s", humanDuration(window)), nil)
+	default:
+		record("isolation.subscriptions", start, checkPass, fmt.Sprintf("%d updates, each on a subscribed session", updates), nil)
+	}
+
+	for _, s := range sessions {
+		result.Notifications = append(result.Notifications, len(s.since(time.Time{})))
+	}
+	report.setChecks(summaries)
+
+	passed, skipped := countPassed(summaries), 0
+	for _, s := range summaries {
+		if s.Status == checkSkip {
+			skipped++
+		}
+	}
+	fmt.Printf("\n%d isolation checks: %d passed, %d failed, %d skipped\n", len(summaries), passed, len(summaries)-passed-skipped, skipped)
+	if len(failed) > 0 {
+		return fmt.Errorf("the server's sessions are not isolated: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// isolateSubscriptions subscribes each session to a different resource,
+// waits for updates and returns the number of updates and those delivered
+// to a session not subscribed to the resource. The detail tells why the
+// check could not be run.
+func isolateSubscriptions(sessions []*isolationSession, window, timeout time.Duration) (int, []isolationBleed, string) {
+	caps := sessions[0].client.GetServerCapabilities()
+	if caps.Resources == nil || !caps.Resources.Subscribe {
+		return 0, nil, "the server does not advertise resources.subscribe"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	listed, err := listAllResources(ctx, sessions[0].client)
+	cancel()
+	if err != nil {
+		return 0, nil, fmt.Sprintf("failed to list resources: %v", err)
+	}
+	if len(listed.Resources) == 0 {
+		return 0, nil, "the server lists no resources"
+	}
+
+	// Sessions beyond the number of resources subscribe to nothing, and
+	// must not be told about any update
+	subscribed := map[int]string{}
+	for i, s := range sessions {
+		if i == len(listed.Resources) {
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		request := mcp.SubscribeRequest{}
+		request.Params.URI = listed.Resources[i].URI
+		err := s.client.Subscribe(ctx, request)
+		cancel()
+		if err != nil {
+			return 0, nil, fmt.Sprintf("session %d failed to subscribe to %s: %v", s.index, request.Params.URI, err)
+		}
+		subscribed[s.index] = request.Params.URI
+	}
+	defer func() {
+		for _, s := range sessions {
+			if uri, ok := subscribed[s.index]; ok {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				request := mcp.UnsubscribeRequest{}
+				request.Params.URI = uri
+				_ = s.client.Unsubscribe(ctx, request)
+				cancel()
+			}
+		}
+	}()
+
+	start := time.Now()
+	fmt.Printf("  ...    waiting %s for updates of %d subscribed resource%s\n", humanDuration(window), len(subscribed), pluralS(len(subscribed)))
+	time.Sleep(window)
+	updates := 0
+	var strays []isolationBleed
+	for _, s := range sessions {
+		for _, note := range s.since(start) {
+			if note.Method != string(mcp.MethodNotificationResourceUpdated) {
+				continue
+			}
+			updates++
+			if note.URI != subscribed[s.index] {
+				strays = append(strays, isolationBleed{Check: "isolation.subscriptions", Session: s.index, Method: note.Method,
+					Detail: fmt.Sprintf("an update of %s reached session %d, which subscribed to %s", note.URI, s.index, valueOr(subscribed[s.index], "nothing"))})
+			}
+		}
+	}
+	return updates, strays, ""
+}
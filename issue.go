@@ -0,0 +1,429 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits for the wire excerpt included in a drafted issue
+const (
+	wireMaxExchanges = 100
+	wireMaxBody      = 2048
+	wireIssueExcerpt = 4
+)
+
+// wireExchange is one HTTP request and response seen by the probe
+type wireExchange struct {
+	time         time.Time
+	method       string
+	url          string
+	requestBody  string
+	status       string
+	contentType  string
+	responseBody bytes.Buffer
+	err          error
+}
+
+// wireLog keeps the most recent HTTP exchanges so that a drafted issue can
+// include the traffic that led to a failure
+type wireLog struct {
+	mu        sync.Mutex
+	exchanges []*wireExchange
+}
+
+// wireCapture is the log of recent exchanges when -draft-issue is set
+var wireCapture *wireLog
+
+// add appends an exchange, dropping the oldest one if the log is full
+func (l *wireLog) add(ex *wireExchange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.exchanges) == wireMaxExchanges {
+		l.exchanges = l.exchanges[1:]
+	}
+	l.exchanges = append(l.exchanges, ex)
+}
+
+// excerpt returns the last few exchanges that failed or, if none did, that
+// called one of the given methods, falling back to the last few exchanges
+func (l *wireLog) excerpt(methods []string) []*wireExchange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var failed, matched []*wireExchange
+	for _, ex := range l.exchanges {
+		if ex.err != nil || !strings.HasPrefix(ex.status, "2") || strings.Contains(ex.responseBody.String(), `"error"`) {
+			failed = append(failed, ex)
+		}
+		for _, method := range methods {
+			if strings.Contains(ex.requestBody, `"method":"`+method+`"`) {
+				matched = append(matched, ex)
+				break
+			}
+		}
+	}
+	for _, list := range [][]*wireExchange{failed, matched, l.exchanges} {
+		if len(list) > 0 {
+			return list[max(0, len(list)-wireIssueExcerpt):]
+		}
+	}
+	return nil
+}
+
+// wireCaptureTransport records requests and responses in a wireLog. Response
+// bodies are captured as they are read, so that long-lived SSE streams are
+// recorded up to the point the probe stopped. Only JSON-RPC exchanges and SSE
+// streams are kept: the same client performs OAuth token and refresh requests,
+// whose form bodies and error responses carry secrets that must never end up in
+// a public issue draft.
+type wireCaptureTransport struct {
+	base http.RoundTripper
+	log  *wireLog
+}
+
+func (t *wireCaptureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var data []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ = io.ReadAll(io.LimitReader(body, wireMaxBody+1))
+			_ = body.Close()
+		}
+	}
+	stream := req.Method == http.MethodGet && strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+	if !stream && !bytes.Contains(data, []byte(`"jsonrpc"`)) {
+		return t.base.RoundTrip(req)
+	}
+	ex := &wireExchange{time: time.Now().UTC(), method: req.Method, url: req.URL.String(), requestBody: truncateWire(string(data))}
+	t.log.add(ex)
+
+	resp, err := t.base.RoundTrip(req)
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+	if err != nil {
+		ex.err = err
+		return nil, err
+	}
+	ex.status = resp.Status
+	ex.contentType = resp.Header.Get("Content-Type")
+	resp.Body = &wireCaptureBody{ReadCloser: resp.Body, ex: ex, log: t.log}
+	return resp, nil
+}
+
+// wireCaptureBody copies what is read from a response body into its exchange
+type wireCaptureBody struct {
+	io.ReadCloser
+	ex  *wireExchange
+	log *wireLog
+}
+
+func (b *wireCaptureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.log.mu.Lock()
+		if room := wireMaxBody + 1 - b.ex.responseBody.Len(); room > 0 {
+			b.ex.responseBody.Write(p[:min(n, room)])
+		}
+		b.log.mu.Unlock()
+	}
+	return n, err
+}
+
+// truncateWire shortens captured data to the excerpt limit
+func truncateWire(s string) string {
+	if len(s) > wireMaxBody {
+		return s[:wireMaxBody] + "\n... (truncated)"
+	}
+	return s
+}
+
+// issueProblem is one failure found during the run, described as observed
+// versus expected behavior
+type issueProblem struct {
+	summary  string
+	method   string
+	observed string
+	expected string
+}
+
+// writeIssueDraft writes a markdown bug report for the problems found in the
+// run. Nothing is written if the run found no problems.
+func writeIssueDraft(path string, args []string) error {
+	problems := collectIssueProblems()
+	if len(problems) == 0 {
+		fmt.Println("No problems found; no issue drafted")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	renderIssueDraft(&buf, problems, args)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write issue draft: %w", err)
+	}
+	fmt.Printf("Issue draft written to %s (%d problem(s))\n", path, len(problems))
+	return nil
+}
+
+// collectIssueProblems lists the failures recorded in the run report
+func collectIssueProblems() []issueProblem {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	var problems []issueProblem
+	for _, c := range report.Checks {
+		if c.Suppressed {
+			continue
+		}
+		switch {
+		case c.Status == checkFail || c.Status == checkFlaky:
+			problems = append(problems, issueProblem{
+				summary:  fmt.Sprintf("%s is %s (%d of %d runs failed)", c.ID, c.Status, c.Failed, c.Passed+c.Failed),
+				method:   checkMethod(c.ID),
+				observed: strings.Join(c.Errors, "\n"),
+				expected: fmt.Sprintf("%s succeeds on every run", c.ID),
+			})
+		case c.Varies:
+			problems = append(problems, issueProblem{
+				summary:  fmt.Sprintf("%s returns different results between runs", c.ID),
+				method:   checkMethod(c.ID),
+				observed: "The results changed between runs without the server's state changing",
+				expected: fmt.Sprintf("%s returns the same results on every run", c.ID),
+			})
+		}
+	}
+	for _, d := range report.TransportDiffs {
+		if d.finding != nil && d.finding.Suppressed {
+			continue
+		}
+		problems = append(problems, issueProblem{
+			summary:  fmt.Sprintf("%s differs between transports (%s)", d.Check, d.Impact),
+			method:   checkMethod(d.Check),
+			observed: d.Detail,
+			expected: "The server behaves the same over SSE and streamable HTTP",
+		})
+	}
+	for _, d := range report.VersionDiffs {
+		if d.finding != nil && d.finding.Suppressed {
+			continue
+		}
+		problems = append(problems, issueProblem{
+			summary:  fmt.Sprintf("%s differs between protocol versions (%s)", d.Check, d.Impact),
+			method:   checkMethod(d.Check),
+			observed: d.Detail,
+			expected: "Behavior that the newer protocol version does not change stays the same",
+		})
+	}
+	// Compatible changes since the baseline are the server's to make
+	for _, d := range report.BaselineDiffs {
+		if d.Impact != impactBreaking || (d.finding != nil && d.finding.Suppressed) {
+			continue
+		}
+		problems = append(problems, issueProblem{
+			summary:  fmt.Sprintf("%s changed incompatibly since the baseline release", d.Check),
+			method:   checkMethod(d.Check),
+			observed: d.Detail,
+			expected: "Clients written for the baseline release keep working, or the major version is bumped",
+		})
+	}
+	// In the check and comparison modes the errors only summarize the
+	// problems above
+	if len(problems) > 0 {
+		return problems
+	}
+	for _, e := range report.Errors {
+		problems = append(problems, issueProblem{
+			summary:  firstLine(e),
+			observed: e,
+			expected: "The operation succeeds",
+		})
+	}
+	return problems
+}
+
+// renderIssueDraft writes the markdown issue
+func renderIssueDraft(w io.Writer, problems []issueProblem, args []string) {
+	report.mu.Lock()
+	server := report.Target
+	if report.ServerInfo != nil {
+		server = strings.TrimSpace(report.ServerInfo.Name + " " + report.ServerInfo.Version)
+	}
+	transportName := report.Transport
+	protocolVersion := report.ProtocolVersion
+	report.mu.Unlock()
+
+	title := problems[0].summary
+	if len(problems) > 1 {
+		title += fmt.Sprintf(" (and %d more)", len(problems)-1)
+	}
+	fmt.Fprintf(w, "# %s: %s\n\n", server, title)
+
+	fmt.Fprintf(w, "## Summary\n\n")
+	fmt.Fprintf(w, "Probing the server with %s found %d problem(s):\n\n", ProgName, len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(w, "- %s\n", p.summary)
+	}
+
+	fmt.Fprintf(w, "\n## Reproduction\n\n")
+	fmt.Fprintf(w, "```bash\n%s\n```\n", reproductionCommand(args))
+
+	fmt.Fprintf(w, "\n## Observed vs Expected\n")
+	for i, p := range problems {
+		fmt.Fprintf(w, "\n### %d. %s\n\n", i+1, p.summary)
+		fmt.Fprintf(w, "**Observed:**\n\n```\n%s\n```\n\n", p.observed)
+		fmt.Fprintf(w, "**Expected:** %s\n", p.expected)
+	}
+
+	var methods []string
+	for _, p := range problems {
+		if p.method != "" {
+			methods = append(methods, p.method)
+		}
+	}
+	var excerpt []*wireExchange
+	if wireCapture != nil {
+		excerpt = wireCapture.excerpt(methods)
+	}
+	fmt.Fprintf(w, "\n## Wire Excerpt\n\n")
+	switch {
+	case transportName == "stdio":
+		fmt.Fprintf(w, "Traffic is not captured for the stdio transport; rerun the reproduction command with `-debug` to see the raw messages.\n")
+	case len(excerpt) == 0:
+		fmt.Fprintf(w, "No HTTP traffic was captured.\n")
+	default:
+		for _, ex := range excerpt {
+			writeWireExchange(w, ex)
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Environment\n\n")
+	fmt.Fprintf(w, "- %s\n", currentBuildInfo().Summary())
+	fmt.Fprintf(w, "- Transport: %s\n", transportName)
+	if server != "" {
+		fmt.Fprintf(w, "- Server: %s\n", server)
+	}
+	if protocolVersion != "" {
+		fmt.Fprintf(w, "- Protocol version: %s\n", protocolVersion)
+	}
+	fmt.Fprintf(w, "- Date: %s\n", machineTime(time.Now()))
+}
+
+// writeWireExchange writes one captured exchange as markdown
+func writeWireExchange(w io.Writer, ex *wireExchange) {
+	wireCapture.mu.Lock()
+	defer wireCapture.mu.Unlock()
+
+	fmt.Fprintf(w, "`%s %s` at %s\n\n", ex.method, ex.url, machineTime(ex.time))
+	if ex.requestBody != "" {
+		fmt.Fprintf(w, "```json\n%s\n```\n\n", ex.requestBody)
+	}
+	switch {
+	case ex.err != nil:
+		fmt.Fprintf(w, "Request failed: `%v`\n\n", ex.err)
+	case ex.status == "":
+		fmt.Fprintf(w, "No response received\n\n")
+	default:
+		fmt.Fprintf(w, "Response: `%s`", ex.status)
+		if ex.contentType != "" {
+			fmt.Fprintf(w, " (`%s`)", ex.contentType)
+		}
+		fmt.Fprintf(w, "\n\n")
+		if body := strings.TrimSpace(truncateWire(ex.responseBody.String())); body != "" {
+			fmt.Fprintf(w, "```\n%s\n```\n\n", body)
+		}
+	}
+}
+
+// reproductionCommand rebuilds the command line without -draft-issue, with
+// header values and secrets redacted
+func reproductionCommand(args []string) string {
+	// Every flag that can carry a credential must be listed here
+	secretFlags := map[string]bool{
+		"H": true, "headers": true, "proxy": true, "bearer-token": true,
+		"oauth-client-secret": true, "sampling-api-key": true,
+	}
+	parts := []string{"mcp-probe"}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") {
+			parts = append(parts, shellQuote(arg))
+			continue
+		}
+		if name == "draft-issue" {
+			if !hasValue {
+				i++
+			}
+			continue
+		}
+		if !secretFlags[name] {
+			parts = append(parts, shellQuote(arg))
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				parts = append(parts, arg)
+				continue
+			}
+			i++
+			value = args[i]
+		}
+		parts = append(parts, "-"+name, shellQuote(redactFlagValue(name, value)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// redactFlagValue hides secret values while keeping header names visible
+func redactFlagValue(name, value string) string {
+	switch name {
+	case "H":
+		if key, _, ok := strings.Cut(value, ":"); ok {
+			return key + ": <redacted>"
+		}
+	case "headers":
+		var redacted []string
+		for _, pair := range strings.Split(value, ",") {
+			if key, _, ok := strings.Cut(pair, ":"); ok {
+				redacted = append(redacted, key+":<redacted>")
+			}
+		}
+		return strings.Join(redacted, ",")
+	case "proxy":
+		// Only the proxy's credentials are secret
+		if parsed, err := url.Parse(value); err == nil {
+			return parsed.Redacted()
+		}
+	}
+	return "<redacted>"
+}
+
+// shellQuote quotes an argument for a POSIX shell if needed
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// checkMethod returns the MCP method a check calls, e.g. "tools/call" for
+// "tools/call echo", or "" for checks that are not a single method
+func checkMethod(id string) string {
+	method, _, _ := strings.Cut(id, " ")
+	if method == checkConnect {
+		return ""
+	}
+	return method
+}
+
+// firstLine returns the first line of a message
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
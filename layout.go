@@ -0,0 +1,247 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Output layouts of a default run, selected with -layout
+const (
+	layoutSummary       = "summary"
+	layoutChronological = "chronological"
+)
+
+// validateLayout checks a -layout value
+func validateLayout(layout string) error {
+	switch layout {
+	case layoutSummary, layoutChronological:
+		return nil
+	default:
+		return fmt.Errorf("unsupported layout '%s' (use 'summary' or 'chronological')", layout)
+	}
+}
+
+// Sections of a default run's output
+const (
+	sectionConnection   = "Connection"
+	sectionCapabilities = "Capabilities"
+)
+
+// stdoutCapture holds back what is written to stdout until it is stopped
+type stdoutCapture struct {
+	orig *os.File
+	w    *os.File
+	buf  bytes.Buffer
+	done chan struct{}
+}
+
+// captureStdout redirects stdout to a pipe whose output is kept
+func captureStdout() (*stdoutCapture, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture pipe: %w", err)
+	}
+	c := &stdoutCapture{orig: os.Stdout, w: w, done: make(chan struct{})}
+	os.Stdout = w
+	go func() {
+		defer close(c.done)
+		_, _ = io.Copy(&c.buf, r)
+		_ = r.Close()
+	}()
+	return c, nil
+}
+
+// stop restores stdout and returns what was written to it
+func (c *stdoutCapture) stop() []byte {
+	os.Stdout = c.orig
+	_ = c.w.Close()
+	<-c.done
+	return c.buf.Bytes()
+}
+
+// layoutSection is the captured output of one section of the run
+type layoutSection struct {
+	name   string
+	output []byte
+}
+
+// summaryLayout prints the summary of a default run before its details. The
+// output of each section is held back as it is written; when the run ends the
+// summary is printed first, followed by the sections. The connection section
+// is only shown with -verbose. A nil layout leaves the output chronological,
+// so its methods may be called unconditionally.
+type summaryLayout struct {
+	verbose  bool
+	name     string
+	capture  *stdoutCapture
+	sections []layoutSection
+	released bool
+}
+
+// startSummaryLayout starts holding back the output of the connection
+// section. If stdout cannot be captured the output stays chronological.
+func startSummaryLayout(verbose bool) *summaryLayout {
+	capture, err := captureStdout()
+	if err != nil {
+		fmt.Printf("Warning: %s\n", report.addWarning(warningProbe, "%v; the output is chronological", err))
+		return nil
+	}
+	l := &summaryLayout{verbose: verbose, name: sectionConnection, capture: capture}
+	// Whatever ends the run early, the held back output is not lost
+	addExitHook(l.release)
+	return l
+}
+
+// section ends the current section and starts the next
+func (l *summaryLayout) section(name string) {
+	if l == nil || l.capture == nil {
+		return
+	}
+	l.sections = append(l.sections, layoutSection{l.name, l.capture.stop()})
+	capture, err := captureStdout()
+	if err != nil {
+		l.capture = nil
+		l.release()
+		return
+	}
+	l.name, l.capture = name, capture
+}
+
+// stop ends the current section
+func (l *summaryLayout) stop() {
+	if l.capture != nil {
+		l.sections = append(l.sections, layoutSection{l.name, l.capture.stop()})
+		l.capture = nil
+	}
+}
+
+// release prints the held back output as it was written, for runs that are
+// not summarized
+func (l *summaryLayout) release() {
+	if l == nil {
+		return
+	}
+	l.stop()
+	for _, s := range l.sections {
+		_, _ = os.Stdout.Write(s.output)
+	}
+	l.sections = nil
+	l.released = true
+}
+
+// finish prints the summary of the run followed by its sections
+func (l *summaryLayout) finish() {
+	if l == nil || l.released {
+		return
+	}
+	l.stop()
+	printRunSummary()
+	for _, s := range l.sections {
+		if s.name == sectionConnection && !l.verbose {
+			continue
+		}
+		fmt.Printf("\n=== %s ===\n", s.name)
+		_, _ = os.Stdout.Write(bytes.TrimLeft(s.output, "\n"))
+	}
+	if !l.verbose {
+		fmt.Println("\n(The connection and handshake details are shown with -verbose)")
+	}
+	l.sections = nil
+}
+
+// printRunSummary prints the conclusion of a default run: the server, what
+// it offers and the problems found
+func printRunSummary() {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	fmt.Println("\n=== Summary ===")
+	if report.ServerInfo != nil {
+		fmt.Printf("Server:       %s\n", strings.TrimSpace(report.ServerInfo.Name+" "+report.ServerInfo.Version))
+	}
+	fmt.Printf("Target:       %s (%s)\n", report.Target, report.Transport)
+	fmt.Printf("Protocol:     %s\n", valueOr(report.ProtocolVersion, "not negotiated"))
+
+	caps := report.Capabilities
+	failed := map[string]bool{}
+	for _, e := range report.Endpoints {
+		failed[e.Method] = !e.Works
+	}
+	fmt.Printf("Tools:        %s\n", summaryCount(caps.Tools != nil, failed[string(mcp.MethodToolsList)], len(report.Tools), "tool"))
+	resources := summaryCount(caps.Resources != nil, failed[string(mcp.MethodResourcesList)], len(report.Resources), "resource")
+	if caps.Resources != nil && !failed[string(mcp.MethodResourcesTemplatesList)] {
+		resources += fmt.Sprintf(", %d template%s", len(report.ResourceTemplates), pluralS(len(report.ResourceTemplates)))
+	}
+	fmt.Printf("Resources:    %s\n", resources)
+	fmt.Printf("Prompts:      %s\n", summaryCount(caps.Prompts != nil, failed[string(mcp.MethodPromptsList)], len(report.Prompts), "prompt"))
+	if caps.Logging != nil {
+		fmt.Println("Logging:      supported")
+	} else {
+		fmt.Println("Logging:      not advertised")
+	}
+
+	// Findings that fail the run are also recorded as errors
+	fromFindings := map[string]bool{}
+	severities := map[string]int{}
+	suppressed := 0
+	for _, f := range report.Findings {
+		if f.fails() {
+			fromFindings[f.String()] = true
+		}
+		if f.Suppressed {
+			suppressed++
+		} else {
+			severities[f.Severity]++
+		}
+	}
+	var errs []string
+	for _, e := range report.Errors {
+		if !fromFindings[e] {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) == 0 && len(report.Findings) == 0 {
+		fmt.Println("Problems:     none")
+		return
+	}
+
+	var counts []string
+	if n := len(errs); n > 0 {
+		counts = append(counts, fmt.Sprintf("%d error%s", n, pluralS(n)))
+	}
+	for _, severity := range []string{severityError, severityWarning, severityInfo} {
+		if n := severities[severity]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s finding%s", n, severity, pluralS(n)))
+		}
+	}
+	if suppressed > 0 {
+		counts = append(counts, fmt.Sprintf("%d suppressed", suppressed))
+	}
+	fmt.Printf("Problems:     %s\n", strings.Join(counts, ", "))
+	for _, e := range errs {
+		fmt.Printf("  %s\n", e)
+	}
+	for _, f := range report.Findings {
+		fmt.Printf("  %s\n", f)
+	}
+}
+
+// summaryCount describes how many items of an advertised capability were
+// listed
+func summaryCount(advertised, listFailed bool, n int, noun string) string {
+	switch {
+	case !advertised:
+		return "not advertised"
+	case listFailed:
+		return "advertised, but listing them failed"
+	}
+	return fmt.Sprintf("%d %s%s", n, noun, pluralS(n))
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// logMessageMethod is the notification servers send log entries with
+const logMessageMethod = "notifications/message"
+
+// logMessageWait is how long the logging test waits for log entries after
+// setting the last level
+const logMessageWait = 500 * time.Millisecond
+
+// logLevels are the MCP logging levels, from least to most severe
+var logLevels = []mcp.LoggingLevel{
+	mcp.LoggingLevelDebug,
+	mcp.LoggingLevelInfo,
+	mcp.LoggingLevelNotice,
+	mcp.LoggingLevelWarning,
+	mcp.LoggingLevelError,
+	mcp.LoggingLevelCritical,
+	mcp.LoggingLevelAlert,
+	mcp.LoggingLevelEmergency,
+}
+
+// logMessages counts the log entries received; logWatching is set once the
+// handler that prints them is registered
+var (
+	logMessages atomic.Int64
+	logWatching atomic.Bool
+)
+
+// parseLogLevel validates a -log-level value
+func parseLogLevel(level string) (mcp.LoggingLevel, error) {
+	names := make([]string, len(logLevels))
+	for i, l := range logLevels {
+		if strings.EqualFold(level, string(l)) {
+			return l, nil
+		}
+		names[i] = string(l)
+	}
+	return "", fmt.Errorf("unknown log level '%s' (use %s)", level, strings.Join(names, ", "))
+}
+
+// watchLogMessages prints the log entries the server sends. The handler is
+// only registered once, however many times this is called.
+func watchLogMessages(mcpClient *client.Client) {
+	if logWatching.Swap(true) {
+		return
+	}
+	mcpClient.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method != logMessageMethod {
+			return
+		}
+		level, _ := n.Params.AdditionalFields["level"].(string)
+		logger, _ := n.Params.AdditionalFields["logger"].(string)
+		data := n.Params.AdditionalFields["data"]
+		logMessages.Add(1)
+
+		text, ok := data.(string)
+		if !ok {
+			text = itemJSON(data)
+		}
+		if logger != "" {
+			text = logger + ": " + text
+		}
+		fmt.Printf("[%s] [%s] %s\n", consoleClock(time.Now()), valueOr(level, "no level"), text)
+		emitEvent(eventLogMessage, map[string]any{"level": level, "logger": logger, "data": data})
+	})
+}
+
+// setLogLevel asks the server to send log entries at level and above
+func setLogLevel(ctx context.Context, mcpClient *client.Client, level mcp.LoggingLevel) error {
+	request := mcp.SetLevelRequest{}
+	request.Params.Level = level
+	start := time.Now()
+	err := mcpClient.SetLevel(ctx, request)
+	report.addTiming(string(mcp.MethodSetLogLevel), time.Since(start), err)
+	return err
+}
+
+// applyLogLevel sets the -log-level level after initialization and prints
+// the log entries the server sends for the rest of the session
+func applyLogLevel(mcpClient *client.Client, level mcp.LoggingLevel, timeout time.Duration) {
+	if mcpClient.GetServerCapabilities().Logging == nil {
+		fmt.Printf("Warning: %s\n", report.addWarning(warningCapability, "the server does not advertise the logging capability; -log-level %s may be ignored", level))
+	}
+	watchLogMessages(mcpClient)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := setLogLevel(ctx, mcpClient, level); err != nil {
+		fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "failed to set log level %s: %v", level, err))
+		report.addError("logging/setLevel %s: %v", level, err)
+		return
+	}
+	fmt.Printf("Log level set to %s\n", level)
+}
+
+// testLogging sets each logging level in turn and prints the log entries the
+// server sends. Afterwards the level is restored to restore, if given.
+func testLogging(ctx context.Context, mcpClient *client.Client, restore mcp.LoggingLevel) error {
+	watchLogMessages(mcpClient)
+	before := logMessages.Load()
+
+	var failed []string
+	var firstErr error
+	for _, level := range logLevels {
+		start := time.Now()
+		err := setLogLevel(ctx, mcpClient, level)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			f := report.addFinding(checkIDLogging, string(level), "setLevel %s failed: %v", level, err)
+			fmt.Printf("  %s\n", f)
+			if f.fails() {
+				failed = append(failed, string(level))
+			}
+			continue
+		}
+		fmt.Printf("  setLevel %-9s ok (%s)\n", level, humanDuration(time.Since(start)))
+	}
+
+	recordEndpoint("Logging", mcp.MethodSetLogLevel, firstErr)
+
+	// Entries logged in response to the last level may still be in flight
+	time.Sleep(logMessageWait)
+	fmt.Printf("Received %d log message(s)\n", logMessages.Load()-before)
+
+	if restore != "" {
+		if err := setLogLevel(ctx, mcpClient, restore); err != nil {
+			report.addError("logging/setLevel %s: %v", restore, err)
+			return fmt.Errorf("failed to restore log level %s: %w", restore, err)
+		}
+		fmt.Printf("Log level restored to %s\n", restore)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("logging/setLevel failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
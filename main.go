@@ -7,13 +7,16 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,6 +40,14 @@ func (d *debugLogger) Errorf(format string, v ...any) {
 	fmt.Printf("[DEBUG ERROR] "+format+"\n", v...)
 }
 
+// quietLogger implements util.Logger for clients whose transport messages
+// are not shown
+type quietLogger struct{}
+
+func (quietLogger) Infof(string, ...any) {}
+
+func (quietLogger) Errorf(string, ...any) {}
+
 // loggingReader wraps an io.Reader and logs all data read
 type loggingReader struct {
 	reader io.Reader
@@ -111,27 +122,431 @@ const (
 )
 
 func main() {
+	// Subcommands are dispatched before flag parsing
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "server":
+			run = runServerCommand
+		case "mock-server", "mock":
+			run = runMockServerCommand
+		case "proxy":
+			run = runProxyCommand
+		case "gateway":
+			run = runGatewayCommand
+		case "serve":
+			run = runServeCommand
+		case "mcp-server":
+			run = runMCPServerCommand
+		case "serve-replay":
+			run = runServeReplayCommand
+		case "stats":
+			run = runStatsCommand
+		case "aggregate":
+			run = runAggregateCommand
+		case "coverage":
+			run = runCoverageCommand
+		case "completion":
+			run = runCompletionCommand
+		case "checks":
+			run = runChecksCommand
+		case "protocols":
+			run = runProtocolsCommand
+		case "self-update":
+			run = runSelfUpdateCommand
+		case "version":
+			run = runVersionCommand
+		case "__complete":
+			run = runCompleteCommand
+		case "tour":
+			// Connect with the probe's connection options, as -tour
+			os.Args = tourCommandArgs(os.Args)
+		case "conformance":
+			// Run with the probe's connection options, as -conformance
+			os.Args = conformanceCommandArgs(os.Args)
+		case "fuzz":
+			// Fuzz with the probe's connection options, as -fuzz
+			os.Args = fuzzCommandArgs(os.Args)
+		case "bench":
+			// Benchmark with the probe's connection options, as -bench
+			os.Args = benchCommandArgs(os.Args)
+		case "chaos":
+			// Drop connections with the probe's connection options, as -chaos
+			os.Args = chaosCommandArgs(os.Args)
+		case "compare-auth":
+			// Compare with the probe's connection options, as -compare-auth
+			os.Args = compareAuthCommandArgs(os.Args)
+		case "reconnect-test":
+			// Close the stream with the probe's connection options, as -reconnect-test
+			os.Args = reconnectTestCommandArgs(os.Args)
+		case "resumability":
+			// Resume a stream with the probe's connection options, as -resumability
+			os.Args = resumabilityCommandArgs(os.Args)
+		case "session-test":
+			// Terminate a session with the probe's connection options, as -session-test
+			os.Args = sessionTestCommandArgs(os.Args)
+		case "isolation-test":
+			// Open the sessions with the probe's connection options, as -isolation-test
+			os.Args = isolationCommandArgs(os.Args)
+		case "verify-contract":
+			// Verified with the probe's connection options, as -verify-contract
+			args, err := contractCommandArgs(os.Args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Args = args
+		case "verify-policy":
+			// Verified with the probe's connection options, as -verify-policy
+			args, err := policyCommandArgs(os.Args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Args = args
+		case "replay":
+			// Replayed with the probe's connection options, as -replay
+			args, err := replayCommandArgs(os.Args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Args = args
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Command line flags
 	var (
-		serverURL   = flag.String("url", "", "MCP server URL (required for SSE/HTTP)")
-		mode        = flag.String("transport", "http", "Transport mode: 'sse' or 'http'")
-		headers     = flag.String("headers", "", "HTTP headers in format 'key1:value1,key2:value2'")
-		timeout     = flag.Duration("timeout", 30*time.Second, "Connection timeout for initialization and listing")
-		callTimeout = flag.Duration("call-timeout", 300*time.Second, "Timeout for tool call execution")
-		verbose     = flag.Bool("verbose", true, "Enable verbose output")
-		debug       = flag.Bool("debug", false, "Enable debug output showing raw MCP messages")
-		callTool    = flag.String("call", "", "Name of the tool to call")
-		toolParams  = flag.String("params", "{}", "JSON string of parameters for the tool call")
-		listOnly    = flag.Bool("list-only", false, "Only list available tools, don't test capabilities")
-		list        = flag.Bool("list", false, "List tool names only (minimal output)")
-		interactive = flag.Bool("interactive", false, "Interactive mode for tool calling")
-		stdioCmd    = flag.String("stdio", "", "Path to MCP server executable (enables stdio transport)")
-		stdioArgs   = flag.String("args", "", "Arguments to pass to the stdio server (comma-separated)")
-		stdioEnv    = flag.String("env", "", "Environment variables for stdio server (KEY=VALUE,...)")
-		repeat      = flag.Int("repeat", 1, "Number of times to repeat the tool call (for load testing)")
-		concurrent  = flag.Int("concurrent", 1, "Number of concurrent workers for load testing (use with -repeat)")
+		serverURL    = flag.String("url", "", "MCP server URL (required for SSE/HTTP)")
+		mode         = flag.String("transport", "http", "Transport mode: 'sse' or 'http'")
+		headers      = flag.String("headers", "", "HTTP headers in format 'key1:value1,key2:value2'")
+		timeout      = flag.Duration("timeout", 30*time.Second, "Connection timeout for initialization and listing")
+		callTimeout  = flag.Duration("call-timeout", 300*time.Second, "Timeout for tool call execution")
+		acceptTime   = flag.Duration("accept-timeout", 0, "Time allowed to connect to the server, open event streams and answer initialization, pings and listings; 0 disables")
+		retries      = flag.Int("retries", 0, "Retry HTTP requests that fail transiently (connection refused, 429, 502, 503, 504) this many times")
+		retryBackoff = flag.Duration("retry-backoff", 500*time.Millisecond, "Wait before the first retry; doubles with each retry, with jitter")
+		settleDelay  = flag.Duration("settle-delay", 0, "Wait this long after initialization before listing capabilities")
+		maxPages     = flag.Int("max-pages", defaultMaxPages, "Most pages of each list to follow (0 for no limit)")
+		verbose      = flag.Bool("verbose", true, "Enable verbose output")
+		debug        = flag.Bool("debug", false, "Enable debug output showing raw MCP messages and the connection each HTTP request used")
+		timingsFlag  = flag.Bool("timings", false, "Print how long each request took, with p50/p95/p99 for repeated requests")
+		traceFlag    = flag.Bool("trace", false, "Print every JSON-RPC message sent and received as pretty-printed JSON, with its direction and timing")
+		callTool     = flag.String("call", "", "Name of the tool to call")
+		toolParams   = flag.String("params", "{}", "JSON string of parameters for the tool call")
+		fuzzy        = flag.Bool("fuzzy", false, "With -call, call the closest listed tool when the name does not match one exactly")
+		expectHash   = flag.String("expect-schema-hash", "", "With -call, refuse to call the tool unless its schema hash (shown by -list-only) starts with this hex value")
+		readTmpl     = flag.String("read-template", "", "Expand this resource template (name or URI template) and read the resulting resource")
+		tmplVars     = flag.String("template-vars", "", "Variables for -read-template: 'name=value,...' or a JSON object (missing ones are prompted for)")
+		getPromptArg = flag.String("get-prompt", "", "Get this prompt (prompts/get), render its messages and validate the response")
+		promptArgs   = flag.String("prompt-args", "", "JSON object of arguments for -get-prompt, e.g. '{\"language\":\"go\"}'")
+		completeArg  = flag.String("complete", "", "Request completions for a prompt or resource template argument: prompt:<name>:<arg>:<partial> or resource:<uri-template>:<arg>:<partial>")
+		rawMethod    = flag.String("raw-method", "", "Send a request with this JSON-RPC method over the session and print the raw response")
+		rawParams    = flag.String("raw-params", "", "Params for -raw-method: a JSON object or array")
+		rawBatchSpec = flag.String("raw-batch", "", "Send this JSON array of requests as one JSON-RPC batch (protocol 2025-03-26) and print the response to each")
+		subscribe    = flag.String("subscribe", "", "Subscribe to these resource URIs (comma-separated) and print update notifications until interrupted")
+		subscribeAll = flag.Bool("subscribe-all", false, "Subscribe to every resource the server lists and print update notifications until interrupted")
+		pingMode     = flag.Bool("ping", false, "Send MCP ping requests and report the round-trip latency")
+		pingCount    = flag.Int("ping-count", 1, "Number of pings to send with -ping; more than one also reports min/avg/max")
+		pingInterval = flag.Duration("ping-interval", time.Second, "Time between pings with -ping-count")
+		samplingURL  = flag.String("sampling-endpoint", "", "Answer the server's sampling requests with this OpenAI-compatible API base URL, e.g. https://api.openai.com/v1")
+		samplingMdl  = flag.String("sampling-model", "", "Models for -sampling-endpoint (comma-separated); the server's model hints select among them, the first is the default")
+		samplingKey  = flag.String("sampling-api-key", "", "API key for -sampling-endpoint (${VAR} expansion)")
+		samplingStb  = flag.Bool("sampling-stub", false, "Answer the server's sampling requests with a built-in deterministic stub instead of an LLM")
+		stubDelay    = flag.Duration("sampling-stub-delay", 0, "Time the -sampling-stub takes to answer each request")
+		stubReply    = flag.String("sampling-stub-reply", "", "Text the -sampling-stub answers with (default: an echo of the last message)")
+		sampleMaxTok = flag.Int("sampling-max-tokens", 0, "Flag sampling requests asking for more than this many tokens; 0 for no limit")
+		sampleBudget = flag.Int("sampling-token-budget", 0, "Flag sampling requests once the session's requests ask for more than this many tokens in total; 0 for no limit")
+		sampleDeny   = flag.String("sampling-deny-models", "", "Flag sampling requests whose model hints match these patterns (comma-separated, * wildcards)")
+		sampleEnforc = flag.Bool("sampling-enforce", false, "Refuse sampling requests that violate the sampling policy instead of only flagging them")
+		elicitFile   = flag.String("elicitation-answers", "", "JSON file of scripted answers to the server's elicitation requests, for runs without a terminal")
+		keepalive    = flag.Duration("keepalive", 0, "Ping the server this often during -interactive and -subscribe sessions so idle gateways keep the stream open; 0 disables")
+		logLevel     = flag.String("log-level", "", "Ask the server to send log messages at this level and above (debug, info, notice, warning, error, critical, alert, emergency) and print them")
+		groupFlag    = flag.Bool("group", false, "Group tools in listings by category (from tool metadata or the name prefix before '_', '.' or '/')")
+		expandGroups = flag.String("expand-groups", "", "With -group, show descriptions and schemas for these groups (comma-separated, or 'all'); others list names only")
+		saveContent  = flag.String("save-content", "", "Write each content item of tool results and resource reads to a file in this directory")
+		listOnly     = flag.Bool("list-only", false, "Only list available tools, don't test capabilities")
+		list         = flag.Bool("list", false, "List tool names only (minimal output)")
+		interactive  = flag.Bool("interactive", false, "Interactive mode for tool calling")
+		stdioCmd     = flag.String("stdio", "", "Path to MCP server executable (enables stdio transport)")
+		stdioArgs    = flag.String("args", "", "Arguments to pass to the stdio server (comma-separated)")
+		stdioEnv     = flag.String("env", "", "Environment variables for stdio server (KEY=VALUE,...)")
+		repeat       = flag.Int("repeat", 1, "Number of times to repeat the tool call (for load testing)")
+		concurrent   = flag.Int("concurrent", 1, "Number of concurrent workers for load testing (use with -repeat)")
+		teeFile      = flag.String("tee", "", "Also write all output to this file (ANSI codes stripped)")
+		output       = flag.String("output", outputText, "Output format: 'text', 'json' or 'ndjson' (event stream)")
+		quietFlag    = flag.Bool("q", false, "Quiet: discard informational output; with -output json only the run report is written to stdout")
+		noBanner     = flag.Bool("no-banner", false, "Do not print the startup banner")
+		layoutFlag   = flag.String("layout", layoutSummary, "Layout of the capability test output: 'summary' (the summary first, then the details) or 'chronological'")
+		resultOnly   = flag.Bool("result-only", false, "With -call, print only the tool result content (for shell pipelines)")
+		reportFmt    = flag.String("report", "", "Generate a report of the probe run in this format: 'html', 'json' or 'matrix'")
+		stdinParam   = flag.String("stdin-param", "", "Read stdin and pass it to the tool as this string parameter (use with -call)")
+		configPath   = flag.String("config", "", "Config file with named profiles (default: ~/"+defaultConfigName+")")
+		profileName  = flag.String("profile", "", "Name of the config file profile to use")
+		serverAlias  = flag.String("server", "", "Name of a saved server connection (see 'probe server help')")
+		waitReady    = flag.Bool("wait-ready", false, "Poll the server (connect + initialize) until it is ready before probing")
+		waitTimeout  = flag.Duration("wait-timeout", 2*time.Minute, "Maximum time to wait for the server with -wait-ready")
+		runs         = flag.Int("runs", 1, "Repeat the capability checks this many times and aggregate the results")
+		headersFile  = flag.String("headers-file", "", "File with one 'Key: Value' header per line (# comments, ${VAR} expansion)")
+		bearerToken  = flag.String("bearer-token", "", "Bearer token to send in the Authorization header (${VAR} expansion)")
+		bearerFile   = flag.String("bearer-token-file", "", "File containing the bearer token to send in the Authorization header")
+		useOAuth     = flag.Bool("oauth", false, "Authorize with the server's OAuth 2.1 authorization code flow (PKCE) before probing")
+		oauthClient  = flag.String("oauth-client-id", "", "OAuth client ID (default: register a client dynamically)")
+		oauthScopes  = flag.String("oauth-scopes", "", "OAuth scopes to request (comma or space separated)")
+		oauthPort    = flag.Int("oauth-port", 0, "Local port for the OAuth redirect listener (default: random)")
+		oauthCC      = flag.Bool("oauth-client-credentials", false, "Obtain an OAuth token with the client credentials grant (no browser; for CI)")
+		oauthSecret  = flag.String("oauth-client-secret", "", "OAuth client secret for -oauth-client-credentials (${VAR} expansion)")
+		oauthToken   = flag.String("oauth-token-url", "", "OAuth token endpoint (default: discovered from the server)")
+		noTokenCache = flag.Bool("no-token-cache", false, "Do not read or write cached OAuth tokens")
+		compareMode  = flag.Bool("compare-transports", false, "Probe the server over both SSE and streamable HTTP and report differences")
+		compareURL   = flag.String("compare-url", "", "URL of the other transport for -compare-transports (default: swap /mcp and /sse)")
+		baselineURL  = flag.String("baseline-url", "", "URL of the previous release of the server: compare it with -url and suggest a semantic version bump")
+		protoVersion = flag.String("protocol-version", latestProtocolVersion(), "Protocol version to request at initialization, e.g. 2025-03-26")
+		compareVers  = flag.String("compare-versions", "", "Comma separated protocol versions (or 'all') to run the checks under and compare")
+		strictMode   = flag.Bool("strict", false, "Check every response of the session against the MCP schema of the negotiated protocol version and report violations with JSON pointers")
+		versionMtx   = flag.Bool("version-matrix", false, "Initialize a fresh session with each known protocol version and a future one, and print how the server negotiates them")
+		caCert       = flag.String("ca-cert", "", "PEM file with CA certificates to trust in addition to the system roots")
+		insecure     = flag.Bool("insecure", false, "Skip TLS certificate verification (lab environments only)")
+		proxyFlag    = flag.String("proxy", "", "Proxy for connections to the server: http://, https://, socks5:// or socks5h:// URL (default: HTTP_PROXY/HTTPS_PROXY)")
+		suppressFile = flag.String("suppressions", "", "YAML file of accepted findings (check ID, optional subject pattern, reason and expiry date) that are reported but do not count as errors")
+		failLvl      = flag.String("fail-level", severityError, "Lowest finding severity that counts as an error: error, warning or info")
+		draftIssue   = flag.String("draft-issue", "", "If the run finds problems, write a markdown bug report for the server's maintainers to this file")
+		recordFile   = flag.String("record", "", "Record every JSON-RPC message of the run, with timestamps, direction, transport details and HTTP status, to this session recording (JSON Lines)")
+		exportVecs   = flag.String("export-vectors", "", "Write the conformance checks as a language-neutral test vector bundle to this file ('-' for stdout) and exit")
+		verifyVecs   = flag.String("verify-vectors", "", "Run the test vectors in this bundle against the server")
+		verifyCtr    = flag.String("verify-contract", "", "Check that the server satisfies this consumer contract (same as the verify-contract command)")
+		verifyPol    = flag.String("verify-policy", "", "Check that the server exposes nothing outside this policy (same as the verify-policy command)")
+		tourMode     = flag.Bool("tour", false, "Walk through connecting, capabilities, tools and a safe call, explaining each MCP concept (same as the tour command)")
+		conformMode  = flag.Bool("conformance", false, "Run the conformance suite and print a scored pass/fail/skip report (same as the conformance command)")
+		negativeMode = flag.Bool("negative-tests", false, "Send malformed requests and check that the server answers each with a JSON-RPC error, without hanging or crashing")
+		fuzzMode     = flag.Bool("fuzz", false, "Call the -call tool with mutated arguments generated from its input schema and record errors, timeouts and crashes (same as the fuzz command)")
+		fuzzIters    = flag.Int("fuzz-iterations", 100, "Number of fuzzing calls")
+		fuzzSeed     = flag.Uint64("fuzz-seed", 0, "Seed of the fuzzing mutations, to repeat a run (default: random, printed at the start)")
+		benchMode    = flag.Bool("bench", false, "Drive concurrent calls of the -call tool and report throughput, error rate and latency percentiles (same as the bench command)")
+		benchConc    = flag.Int("concurrency", 10, "Number of concurrent workers of the benchmark")
+		benchSess    = flag.Int("sessions", 1, "Number of sessions the benchmark's workers are spread over, or the isolation test opens (default: 3 with -isolation-test)")
+		benchReqs    = flag.Int("requests", 0, "Number of benchmark calls (default: 100 without -duration)")
+		benchDur     = flag.Duration("duration", 0, "How long the benchmark runs")
+		chaosMode    = flag.Bool("chaos", false, "Drop the session's HTTP connections at random, mid-call or between calls, and report how the session recovers (same as the chaos command)")
+		chaosIters   = flag.Int("chaos-iterations", 20, "Number of calls in a chaos run")
+		chaosRate    = flag.Float64("chaos-rate", 0.5, "Fraction of chaos calls with a dropped connection (0-1)")
+		chaosSeed    = flag.Uint64("chaos-seed", 0, "Seed of the chaos faults, to repeat a run (default: random, printed at the start)")
+		compareAuth  = flag.Bool("compare-auth", false, "Probe the server with and without credentials and report what is visible or callable anonymously (same as the compare-auth command)")
+		reconnTest   = flag.Bool("reconnect-test", false, "Close the SSE stream repeatedly and check that the client reopens it and calls still work (same as the reconnect-test command)")
+		resumeTest   = flag.Bool("resumability", false, "Drop a streamed tool call and check that it resumes with Last-Event-ID without losing or repeating events (same as the resumability command)")
+		sessionTest  = flag.Bool("session-test", false, "Check that the server rejects requests without a session or on an unknown or terminated one, and that DELETE terminates it (same as the session-test command)")
+		sessionID    = flag.String("session-id", "", "Join this existing streamable HTTP session instead of initializing a new one")
+		keepSession  = flag.Bool("keep-session", false, "Leave the streamable HTTP session open at exit instead of terminating it, to join it later with -session-id")
+		isoTest      = flag.Bool("isolation-test", false, "Open several sessions at once, run interleaved operations on each and check that no notification reaches the wrong session (same as the isolation-test command)")
+		isoWindow    = flag.Duration("isolation-window", 5*time.Second, "How long the isolation test waits for resource updates of the sessions' subscriptions")
+		replayFile   = flag.String("replay", "", "Re-send the client requests of this session recording and compare the responses with the recorded ones (same as the replay command)")
+		replayPaceF  = flag.String("replay-pace", "none", "Pacing of replayed requests: none, recorded, a speed-up such as 2x, or a delay between requests such as 100ms")
+		replayIgn    = flag.String("replay-ignore", "", "Response paths to leave out of the replay comparison, comma-separated, e.g. 'result.serverInfo.version,result.content.*.text'")
+		headerList   headerFlags
+		reportDests  sinkFlags
+		rootList     rootFlags
 	)
+	flag.Var(&rootList, "root", "Root to offer the server in roots/list responses: file:///path[,name] or a local path (repeatable)")
+	rootsChange := flag.String("roots-change", "", "After the capability test, add this root (file:///path[,name] or a local path), send notifications/roots/list_changed and report how the server reacts")
+	flag.Var(&reportDests, "o", "Destination for -report: file path, s3://bucket/key, gs://bucket/object or http(s):// URL to POST to (repeatable)")
+	flag.Var(&headerList, "H", "HTTP header in format 'Key: Value' (repeatable; values may contain commas and colons)")
 	flag.Parse()
+	if *bearerToken != "" && *bearerFile != "" {
+		fatalf("Invalid options: -bearer-token and -bearer-token-file cannot be used together")
+	}
+
+	// Apply settings from a saved server or config file profile; explicit flags take precedence
+	var profile *profileConfig
+	aliases := newAliasStore(nil, "", nil)
+	checkUpdates := os.Getenv(envCheckUpdates) != ""
+	if *serverAlias != "" {
+		if *profileName != "" {
+			fatalf("Invalid options: -server and -profile cannot be used together")
+		}
+		servers, err := loadSavedServers()
+		if err != nil {
+			fatalf("Failed to load saved servers: %v", err)
+		}
+		if profile, err = servers.lookup(*serverAlias); err != nil {
+			fatalf("Failed to load saved server: %v", err)
+		}
+		aliases = newAliasStore(profile.Aliases, fmt.Sprintf("saved server '%s'", *serverAlias), savedServerAliasSaver(*serverAlias))
+		toolCacheName = "server-" + *serverAlias
+	} else {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fatalf("Failed to load config: %v", err)
+		}
+		if profile, err = cfg.selectProfile(*profileName); err != nil {
+			fatalf("Failed to load profile: %v", err)
+		}
+		checkUpdates = checkUpdates || cfg.CheckUpdates
+		if profile != nil {
+			path := valueOr(*configPath, defaultConfigPath())
+			name := valueOr(*profileName, cfg.DefaultProfile)
+			aliases = newAliasStore(profile.Aliases, fmt.Sprintf("profile '%s' in %s", name, path), profileAliasSaver(path, name))
+			toolCacheName = "profile-" + name
+		}
+	}
+	var err error
+	var profileHeaders map[string]string
+	if profile != nil {
+		if profileHeaders, err = applyProfile(flag.CommandLine, profile); err != nil {
+			fatalf("Failed to apply profile: %v", err)
+		}
+		if profile.Expect != nil {
+			if err := profile.Expect.validate(); err != nil {
+				fatalf("Invalid profile expectations: %v", err)
+			}
+		}
+	}
+
+	// Fall back to the environment for the target and headers
+	if *serverURL == "" && *stdioCmd == "" {
+		*serverURL = os.Getenv(envURL)
+	}
+	envHeaderMap := parseHeaders(os.Getenv(envHeaders))
+
+	if err := validateOutputFormat(*output); err != nil {
+		fatalf("Invalid options: %v", err)
+	}
+	outputFormat = *output
+	if err := validateLayout(*layoutFlag); err != nil {
+		fatalf("Invalid options: %v", err)
+	}
+	if *maxPages < 0 {
+		fatalf("Invalid options: -max-pages cannot be negative")
+	}
+	maxListPages = *maxPages
+	if *resultOnly && *callTool == "" {
+		fatalf("Invalid options: -result-only requires -call")
+	}
+	if *resultOnly && outputFormat == outputNDJSON {
+		fatalf("Invalid options: -result-only supports 'text' or 'json' output")
+	}
+	if *resultOnly && *repeat > 1 {
+		fatalf("Invalid options: -result-only cannot be combined with -repeat")
+	}
+	if err := validateReportOptions(*reportFmt, reportDests); err != nil {
+		fatalf("Invalid options: %v", err)
+	}
+	if *quietFlag && *interactive {
+		fatalf("Invalid options: -q cannot be combined with -interactive")
+	}
+	quiet = *quietFlag
+	showBanner = !*noBanner
+	if *expandGroups != "" && !*groupFlag {
+		fatalf("Invalid options: -expand-groups requires -group")
+	}
+	groupToolListings = *groupFlag
+	setExpandedToolGroups(*expandGroups)
+
+	// The trace is colored if the terminal, not the -tee pipe, is stdout
+	var tracer *wireTracer
+	if *traceFlag {
+		tracer = newWireTracer()
+	}
+
+	// Duplicate console output to a file if requested
+	if *teeFile != "" {
+		tee, err := startTee(*teeFile)
+		if err != nil {
+			fatalf("Failed to set up output tee: %v", err)
+		}
+		addExitHook(func() { _ = tee.Close() })
+	}
+	defer runExitHooks()
+	// List the run's warnings at the end of the console output
+	addExitHook(printWarningSummary)
+
+	// Annotate each HTTP request with the connection it used
+	if *debug {
+		enableConnTracing()
+	}
+	// Track the connections of every HTTP transport, so that chaos and the
+	// reconnection test can drop them
+	if *chaosMode || *reconnTest {
+		enableChaos()
+	}
+	showTimings = *timingsFlag
+	// Retry requests that fail transiently, e.g. while the server starts
+	if *retries < 0 || *retryBackoff <= 0 {
+		fatalf("Invalid options: -retries cannot be negative and -retry-backoff must be positive")
+	}
+	if *retries > 0 && *stdioCmd != "" {
+		fatalf("Invalid options: -retries retries HTTP requests and requires -url")
+	}
+	if *retries > 0 {
+		retryPolicy = &retryOptions{retries: *retries, backoff: *retryBackoff, verbose: *verbose}
+	}
+
+	// Mention a newer release at the end of the run, if opted in
+	if checkUpdates {
+		startUpdateCheck()
+	}
+
+	// Write the report when the run ends, including runs that end in failure
+	if *reportFmt != "" {
+		addExitHook(func() {
+			if err := writeReport(*reportFmt, reportDests); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+			}
+		})
+	}
+
+	// Draft a bug report from the problems found, with the traffic that led to them
+	if *draftIssue != "" {
+		wireCapture = &wireLog{}
+		addExitHook(func() {
+			if err := writeIssueDraft(*draftIssue, os.Args[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to draft issue: %v\n", err)
+			}
+		})
+	}
+
+	// Record the run's traffic for bug reports, replay and statistics, and
+	// trace it on the console
+	if *recordFile != "" || tracer != nil {
+		transportName := strings.ToLower(*mode)
+		if *stdioCmd != "" {
+			transportName = "stdio"
+		}
+		if err := startCapture(*recordFile, transportName, tracer); err != nil {
+			fatalf("Invalid -record: %v", err)
+		}
+		addExitHook(stopCapture)
+	}
+
+	// In result-only and quiet modes everything except the tool result, the
+	// events or the JSON report is discarded. With an NDJSON event stream,
+	// informational output otherwise moves to stderr.
+	resultOut = os.Stdout
+	if *resultOnly || quiet {
+		if err := suppressInfoOutput(); err != nil {
+			fatalf("Failed to set up output: %v", err)
+		}
+	} else if outputFormat == outputNDJSON {
+		redirectInfoToStderr()
+	}
+
+	// With -q -output json, the run report is the only output
+	if quiet && outputFormat == outputJSON && !*resultOnly && *rawMethod == "" && *rawBatchSpec == "" {
+		addExitHook(writeJSONReport)
+	}
+
+	// Exporting test vectors does not need a server
+	if *exportVecs != "" {
+		if err := exportTestVectors(*exportVecs); err != nil {
+			fatalf("Failed to export test vectors: %v", err)
+		}
+		return
+	}
 
 	// Validate that either stdio or URL is provided
 	if *serverURL == "" && *stdioCmd == "" {
@@ -147,36 +562,979 @@ func main() {
 		fmt.Println("    probe -url <server-url> -list-only")
 		fmt.Println("  Call a specific tool:")
 		fmt.Println("    probe -url <server-url> -call <tool-name> -params '<json>' [-call-timeout 300s]")
+		fmt.Println("  Measure round-trip latency with ping requests:")
+		fmt.Println("    probe -url <server-url> -ping -ping-count 10")
+		fmt.Println("  Call the closest listed tool when the name is not exact:")
+		fmt.Println("    probe -url <server-url> -call <approximate-name> -fuzzy")
+		fmt.Println("  Call a tool only if its schema has not changed:")
+		fmt.Println("    probe -url <server-url> -call <tool-name> -params '<json>' -expect-schema-hash <hash>")
 		fmt.Println("  Load testing a tool:")
 		fmt.Println("    probe -url <server-url> -call <tool-name> -params '<json>' -repeat 1000 -concurrent 50")
+		fmt.Println("  Pass stdin to a tool as a string parameter:")
+		fmt.Println("    cat report.txt | probe -url <server-url> -call summarize -stdin-param text")
 		fmt.Println("  Interactive tool calling:")
 		fmt.Println("    probe -url <server-url> -interactive [-call-timeout 300s]")
+		fmt.Println("\nSubcommands:")
+		fmt.Println("  probe server add|list|show|remove   Manage saved server connections")
+		fmt.Println("  probe mock-server [-config mock.yaml] [-transport http|sse|stdio] [-listen 127.0.0.1:8000] [-seed N]")
+		fmt.Println("                                       Run a configurable mock MCP server for testing")
+		fmt.Println("  probe proxy -listen 127.0.0.1:9000 -target <url> [-latency 200ms] [-error-rate 0.1] [-drop-rate 0.1] [-corrupt-rate 0.1]")
+		fmt.Println("                                       Forward MCP traffic, injecting faults and logging everything")
+		fmt.Println("  probe proxy -target <url> -record session.jsonl")
+		fmt.Println("                                       Capture another client's traffic as a session recording")
+		fmt.Println("  probe proxy -listen 127.0.0.1:8080 -upstream <url> -pretty -validate")
+		fmt.Println("                                       Pretty-print and validate the messages between another client and a server")
+		fmt.Println("  probe gateway -url <server-url> [-transport http|sse] [-headers ...] [-log gateway.log] [-pretty] [-record session.jsonl]")
+		fmt.Println("                                       Bridge a remote server to stdio for clients that only speak stdio")
+		fmt.Println("  probe serve -cmd \"./my-server --flag\" [-listen 127.0.0.1:8080] [-env KEY=VALUE,...] [-pretty] [-record session.jsonl]")
+		fmt.Println("                                       Serve a stdio server over streamable HTTP and SSE")
+		fmt.Println("  probe mcp-server [-transport stdio|http|sse] [-listen 127.0.0.1:8000] [-allow-stdio] [-allow-hosts <hosts>]")
+		fmt.Println("                                       Serve probe_server, list_tools, call_remote_tool and more as MCP tools for agents")
+		fmt.Println("  probe serve-replay session.jsonl [-listen 127.0.0.1:8000] [-transport http|stdio] [-realtime]")
+		fmt.Println("                                       Serve a recorded server's responses as a mock server")
+		fmt.Println("  probe replay capture.jsonl -url <server-url> [-replay-pace none|recorded|2x|100ms] [-replay-ignore <paths>] [options]")
+		fmt.Println("                                       Re-send recorded requests and report responses that changed since the recording")
+		fmt.Println("  probe stats -audit-log session.jsonl [-output text|json]")
+		fmt.Println("                                       Summarize per-tool calls, error rates and latency from a recording")
+		fmt.Println("  probe aggregate reports/ [-output text|json]")
+		fmt.Println("                                       Merge the capability matrices in a directory into a fleet summary")
+		fmt.Println("  probe coverage -audit-log run1.jsonl,run2.jsonl [-output text|json]")
+		fmt.Println("                                       Report which tools, prompts, resources and schema branches were exercised")
+		fmt.Println("  probe tour -url <server-url> [options]")
+		fmt.Println("                                       Walk through a session with the server, explaining each MCP concept")
+		fmt.Println("  probe conformance -url <server-url> [-call <slow-tool> -params '<json>'] [options]")
+		fmt.Println("                                       Run the conformance suite and score the server")
+		fmt.Println("  probe bench -url <server-url> -call <tool> [-params '<json>'] [-concurrency 10] [-sessions 1] [-requests N | -duration 60s] [options]")
+		fmt.Println("                                       Drive concurrent tool calls and report throughput, error rate and latency")
+		fmt.Println("  probe fuzz -url <server-url> -call <tool> [-params '<json>'] [-fuzz-iterations 100] [-fuzz-seed N] [options]")
+		fmt.Println("                                       Call a tool with mutated arguments and record errors, timeouts and crashes")
+		fmt.Println("  probe chaos -url <server-url> [-call <tool> -params '<json>'] [-chaos-iterations 20] [-chaos-rate 0.5] [-chaos-seed N] [options]")
+		fmt.Println("                                       Drop connections mid-call and between calls and report how the session recovers")
+		fmt.Println("  probe compare-auth -url <server-url> -bearer-token <token> [-call <tool> -params '<json>'] [options]")
+		fmt.Println("                                       Probe with and without credentials and report what is exposed anonymously")
+		fmt.Println("  probe reconnect-test -url <server-url> -transport sse [-call <tool> -params '<json>'] [options]")
+		fmt.Println("                                       Close the SSE stream, check that it is reopened and report the reconnect time")
+		fmt.Println("  probe resumability -url <server-url> -transport http -call <tool> [-params '<json>'] [options]")
+		fmt.Println("                                       Drop a streamed tool call, resume it with Last-Event-ID and check what is delivered")
+		fmt.Println("  probe session-test -url <server-url> -transport http [options]")
+		fmt.Println("                                       Terminate a session with DELETE and check how unknown and terminated sessions are rejected")
+		fmt.Println("  probe isolation-test -url <server-url> [-sessions 3] [-call <tool> -params '<json>'] [options]")
+		fmt.Println("                                       Open several sessions at once and check that no notification reaches the wrong one")
+		fmt.Println("  probe verify-contract contract.yaml -url <server-url> [options]")
+		fmt.Println("                                       Check that a server provides what a consumer depends on")
+		fmt.Println("  probe verify-policy policy.yaml -url <server-url> [options]")
+		fmt.Println("                                       Check that a server exposes nothing outside an allowlist policy")
+		fmt.Println("  probe completion bash|zsh [command name...]")
+		fmt.Println("                                       Print a shell completion script (tools and -params keys of profiles)")
+		fmt.Println("  probe checks")
+		fmt.Println("                                       List the check IDs used in findings and suppression files")
+		fmt.Println("  probe protocols")
+		fmt.Println("                                       List the protocol versions and the features each introduced")
+		fmt.Println("  probe version [-verbose] [-output text|json]")
+		fmt.Println("                                       Show the build: version, commit, mcp-go, protocol versions, Go and platform")
+		fmt.Println("  probe self-update [-check] [-force]")
+		fmt.Println("                                       Install the latest release after verifying its checksum and signature")
 		fmt.Println("\nCustom HTTP Headers:")
 		fmt.Println("  Use -headers to send custom headers (format: 'key1:value1,key2:value2')")
 		fmt.Println("  Examples:")
 		fmt.Println("    probe -url <url> -headers 'Authorization:Bearer YOUR_TOKEN'")
 		fmt.Println("    probe -url <url> -headers 'Authorization:Bearer abc123,X-Custom:value'")
+		fmt.Println("    probe -url <url> -headers 'Authorization:Bearer ${MCP_TOKEN}'")
+		fmt.Println("  Use repeatable -H 'Key: Value' flags for values containing commas or colons:")
+		fmt.Println("    probe -url <url> -H 'Cookie: a=1, b=2' -H 'X-Time: 12:00'")
+		fmt.Println("  Use -headers-file to read one 'Key: Value' header per line from a file:")
+		fmt.Println("    probe -url <url> -headers-file ./headers.txt")
+		fmt.Println("  Use -bearer-token or -bearer-token-file to set the Authorization header:")
+		fmt.Println("    probe -url <url> -bearer-token '${MCP_TOKEN}'")
+		fmt.Println("    probe -url <url> -bearer-token-file ~/.config/mcp/token")
+		fmt.Println("  Credential headers are redacted in verbose output")
+		fmt.Println("  ${VAR} references in -headers and -params are expanded from the environment")
+		fmt.Println("  MCPPROBE_URL and MCPPROBE_HEADERS are used when -url and -headers are not given")
+		fmt.Println("\nOAuth:")
+		fmt.Println("  -oauth:        Authorize in the browser (OAuth 2.1 + PKCE) and send the token with every request")
+		fmt.Println("  -oauth-client-id, -oauth-scopes, -oauth-port: Use a pre-registered client, request scopes, fix the callback port")
+		fmt.Println("  -oauth-client-credentials: Get a token without a browser using -oauth-client-id and -oauth-client-secret")
+		fmt.Println("  -oauth-token-url: Token endpoint for client credentials (default: discovered from the server)")
+		fmt.Println("  -no-token-cache: Do not reuse or save tokens (cached per server in the user config directory)")
+		fmt.Println("\nProfiles:")
+		fmt.Println("  -config:       Config file with named profiles (default: ~/.mcpprobe.yaml)")
+		fmt.Println("  -profile:      Use the named profile (e.g. -profile staging)")
+		fmt.Println("  -server:       Use a saved server connection (manage with 'probe server add|list|show|remove')")
+		fmt.Println("\nSessions:")
+		fmt.Println("  -session-id:   Join an existing streamable HTTP session instead of initializing (with -call, -list, -raw-method or -ping)")
+		fmt.Println("  -keep-session: Leave the session open at exit, to join it later with -session-id")
+		fmt.Println("  -isolation-window: Time the isolation test waits for resource updates (default: 5s)")
 		fmt.Println("\nTimeout Options:")
 		fmt.Println("  -timeout:      Connection/initialization timeout (default: 30s)")
 		fmt.Println("  -call-timeout: Tool execution timeout (default: 300s)")
+		fmt.Println("  -accept-timeout: Time to connect, open event streams and answer initialization and listings (default: disabled)")
+		fmt.Println("  -retries:      Retry HTTP requests that fail transiently this many times (default: 0)")
+		fmt.Println("  -retry-backoff: Wait before the first retry, doubled for each further retry (default: 500ms)")
+		fmt.Println("  -ca-cert:      PEM file with CA certificates to trust (private CAs)")
+		fmt.Println("  -insecure:     Skip TLS certificate verification (lab environments only)")
+		fmt.Println("  -proxy:        Proxy URL: http://, https://, socks5:// or socks5h:// (default: HTTP_PROXY/HTTPS_PROXY/NO_PROXY)")
+		fmt.Println("  -settle-delay: Wait after initialization before listing (default: 0)")
+		fmt.Println("  -max-pages: Most pages of each list to follow, 0 for no limit (default: 100)")
+		fmt.Println("\nReadiness Options:")
+		fmt.Println("  -wait-ready:   Poll until the server connects and initializes before probing")
+		fmt.Println("  -wait-timeout: Maximum time to wait with -wait-ready (default: 2m)")
+		fmt.Println("\nRepeated Runs:")
+		fmt.Println("  -runs:         Repeat the capability checks N times and report intermittent failures (default: 1)")
+		fmt.Println("  -compare-transports: Run the checks over both SSE and streamable HTTP and report differences")
+		fmt.Println("  -compare-url:  URL of the other transport (default: swap /mcp and /sse in -url)")
+		fmt.Println("  -compare-versions: Run the checks (and -call) under each protocol version, e.g. 'all', and report differences")
+		fmt.Println("  -version-matrix: Initialize with each known protocol version and a future one, and print a compatibility table")
+		fmt.Println("  -strict:       Check every response against the MCP schema of the negotiated version (JSON pointers)")
+		fmt.Println("  -baseline-url: Compare -url with the server's previous release and suggest a major/minor/patch bump")
+		fmt.Println("\nResource Templates:")
+		fmt.Println("  -read-template: Expand a resource template (name or URI template) and read the resource")
+		fmt.Println("  -template-vars: Template variables: 'id=42,lang=en' or JSON such as '{\"tags\":[\"a\",\"b\"]}'")
+		fmt.Println("\nPrompts:")
+		fmt.Println("  -get-prompt:   Get a prompt and render its messages, validating the response")
+		fmt.Println("  -prompt-args:  Prompt arguments as a JSON object, e.g. '{\"language\":\"go\"}'")
+		fmt.Println("\nCompletions:")
+		fmt.Println("  -complete:     Request argument completions, e.g. 'prompt:review:language:py' or 'resource:file:///{path}:path:src/'")
+		fmt.Println("  -raw-method:   Send any JSON-RPC request and print the raw response, e.g. -raw-method x/stats -raw-params '{\"since\":60}'")
+		fmt.Println("  -raw-batch:    Send a JSON-RPC batch (protocol 2025-03-26), e.g. -raw-batch '[{\"method\":\"ping\"},{\"method\":\"tools/list\"}]'")
+		fmt.Println("  With -get-prompt and -read-template, missing arguments are asked for with the server's suggestions")
+		fmt.Println("\nResource Subscriptions:")
+		fmt.Println("  -subscribe:    Subscribe to resource URIs (comma-separated) and print updates until Ctrl-C")
+		fmt.Println("  -subscribe-all: Subscribe to every listed resource and print updates until Ctrl-C")
+		fmt.Println("\nPing and Keep-Alive:")
+		fmt.Println("  -ping:         Send ping requests and report the round-trip latency")
+		fmt.Println("  -ping-count:   Number of pings to send, with a min/avg/max summary (default: 1)")
+		fmt.Println("  -ping-interval: Time between pings (default: 1s)")
+		fmt.Println("  -keepalive:    Ping this often during -interactive or -subscribe so idle gateways keep the stream open")
+		fmt.Println("\nTool Listings:")
+		fmt.Println("  -group:        Group tools by category (tool metadata or name prefix) with counts per group")
+		fmt.Println("  -expand-groups: With -group, show details for these groups (comma-separated, or 'all')")
+		fmt.Println("\nSampling:")
+		fmt.Println("  -sampling-endpoint: Forward the server's sampling requests to an OpenAI-compatible API")
+		fmt.Println("  -sampling-model: Models to use (comma-separated); model hints select among them")
+		fmt.Println("  -sampling-api-key: API key for the sampling endpoint (${VAR} expansion)")
+		fmt.Println("  -sampling-stub: Answer them with a deterministic stub and break down tool call latency")
+		fmt.Println("  -sampling-stub-delay: Time the stub takes to answer (default: 0)")
+		fmt.Println("  -sampling-stub-reply: Text the stub answers with (default: an echo of the last message)")
+		fmt.Println("  Every sampling request is shown in full and checked against the sampling policy:")
+		fmt.Println("  -sampling-max-tokens: Flag requests asking for more than this many tokens")
+		fmt.Println("  -sampling-token-budget: Flag requests once the session has asked for more tokens in total")
+		fmt.Println("  -sampling-deny-models: Flag requests whose model hints match these patterns (comma-separated, * wildcards)")
+		fmt.Println("  -sampling-enforce: Refuse requests that violate the policy instead of only flagging them")
+		fmt.Println("\nElicitation:")
+		fmt.Println("  Requests for user input are asked on the terminal, driven by the requested schema")
+		fmt.Println("  -elicitation-answers <file>: Answer them from a JSON list of {message, action, content} instead")
+		fmt.Println("\nRoots:")
+		fmt.Println("  -root file:///path[,name]: Answer the server's roots/list requests with this root (repeatable)")
+		fmt.Println("  In -interactive mode, 'roots add|remove|notify' sends notifications/roots/list_changed")
+		fmt.Println("  -roots-change <root>: After the capability test, add this root mid-session and report how the server reacts")
+		fmt.Println("\nLogging:")
+		fmt.Println("  -log-level:    Set the server's log level after initialization and print its log messages")
+		fmt.Println("\nSaving Content:")
+		fmt.Println("  -save-content: Write tool result and resource content items to files in this directory")
 		fmt.Println("\nLoad Testing Options:")
 		fmt.Println("  -repeat:       Number of times to call the tool (default: 1)")
 		fmt.Println("  -concurrent:   Number of concurrent workers (default: 1)")
+		fmt.Println("  For sustained load over several sessions with latency percentiles, use the bench command")
 		fmt.Println("\nDebug Options:")
-		fmt.Println("  -debug:        Enable debug output showing raw JSON-RPC messages")
-		os.Exit(1)
+		fmt.Println("  -debug:        Enable debug output showing raw JSON-RPC messages and HTTP connection reuse")
+		fmt.Println("  -timings:      Print how long each request took, with p50/p95/p99 for repeated requests")
+		fmt.Println("  -trace:        Print every JSON-RPC message as pretty-printed JSON with its direction and timing")
+		fmt.Println("  -record:       Record every JSON-RPC message to a session recording (JSON Lines) for bug reports and serve-replay")
+		fmt.Println("  -replay-pace:  Pacing of the replay command: none, recorded, a speed-up such as 2x, or a delay (default: none)")
+		fmt.Println("  -replay-ignore: Response paths the replay command does not compare, e.g. 'result.serverInfo.version'")
+		fmt.Println("\nOutput Options:")
+		fmt.Println("  -tee:          Also write all output to a file (ANSI codes stripped)")
+		fmt.Println("  -output:       Output format: text, json or ndjson (default: text)")
+		fmt.Println("  -result-only:  With -call, print only the tool result (e.g. for shell pipelines)")
+		fmt.Println("  -q:            Quiet: no informational output; with -output json, only the JSON run report")
+		fmt.Println("  -no-banner:    Do not print the startup banner")
+		fmt.Println("  -report html -o <file>: Write a self-contained HTML report of the probe run")
+		fmt.Println("  -report json -o <dest>: Write a JSON report; -o also accepts s3://, gs:// and http(s):// (repeatable)")
+		fmt.Println("  -report matrix -o <dest>: Write a compact capability matrix for 'probe aggregate'")
+		fmt.Println("  -draft-issue <file>: If problems are found, write a markdown bug report for the server's maintainers")
+		fmt.Println("\nTest Vector Options:")
+		fmt.Println("  -export-vectors <file>: Write the conformance checks as a language-neutral JSON bundle ('-' for stdout)")
+		fmt.Println("  -verify-vectors <file>: Run the test vectors in a bundle against the server")
+		fmt.Println("\nFindings:")
+		fmt.Println("  -suppressions <file>: YAML list of accepted findings (check ID, subject pattern, reason) not counted as errors")
+		fmt.Println("                 An entry with 'expires: YYYY-MM-DD' is a waiver that stops applying after that day")
+		fmt.Println("  -fail-level:   Lowest severity that fails the run: error, warning or info (default: error)")
+		fmt.Println("  Run 'probe checks' for the list of check IDs")
+		exitProgram(1)
+	}
+
+	// Expand ${VAR} references in tool parameters
+	if *toolParams, err = expandParamVars(*toolParams); err != nil {
+		fatalf("Invalid -params: %v", err)
+	}
+
+	if *useOAuth && *stdioCmd != "" {
+		fatalf("Invalid options: -oauth requires an HTTP or SSE server (-url)")
+	}
+	if *oauthCC {
+		if *useOAuth {
+			fatalf("Invalid options: -oauth and -oauth-client-credentials cannot be used together")
+		}
+		if *stdioCmd != "" {
+			fatalf("Invalid options: -oauth-client-credentials requires an HTTP or SSE server (-url)")
+		}
+		if *oauthClient == "" || *oauthSecret == "" {
+			fatalf("Invalid options: -oauth-client-credentials requires -oauth-client-id and -oauth-client-secret")
+		}
+		if *oauthSecret, err = expandEnvVars(*oauthSecret, nil); err != nil {
+			fatalf("Invalid -oauth-client-secret: %v", err)
+		}
+	}
+	if (*caCert != "" || *insecure) && *stdioCmd != "" {
+		fatalf("Invalid options: -ca-cert and -insecure require an HTTP or SSE server (-url)")
+	}
+	if probeTLSConfig, err = loadTLSConfig(*caCert, *insecure); err != nil {
+		fatalf("Invalid TLS options: %v", err)
+	}
+	if *insecure {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", report.addWarning(warningProbe, "TLS certificate verification is disabled (-insecure)"))
+	}
+	if *proxyFlag != "" {
+		if *stdioCmd != "" {
+			fatalf("Invalid options: -proxy requires an HTTP or SSE server (-url)")
+		}
+		if probeProxyURL, err = parseProxyURL(*proxyFlag); err != nil {
+			fatalf("Invalid options: %v", err)
+		}
+	}
+	if *runs < 1 {
+		fatalf("Invalid options: -runs must be at least 1")
+	}
+	selectedMode, err := selectProbeMode(flag.CommandLine)
+	if err != nil {
+		fatalf("Invalid options: %v", err)
+	}
+	if *strictMode && selectedMode != nil && !selectedMode.Session {
+		fatalf("Invalid options: -strict checks the probe's session and cannot be combined with -%s, which does not run it", selectedMode.Flag)
+	}
+	if *repeat > 1 && selectedMode != nil && selectedMode.UsesCall {
+		fatalf("Invalid options: -repeat applies to -call on its own and cannot be combined with -%s", selectedMode.Flag)
+	}
+	if *compareMode && *stdioCmd != "" {
+		fatalf("Invalid options: -compare-transports requires -url")
+	}
+	var protocolVersions []string
+	if err := setRequestedProtocolVersion(*protoVersion); err != nil {
+		fatalf("Invalid options: %v", err)
+	}
+	// Any -protocol-version conflicts with the modes that request each
+	// version in turn, including one that names the default version
+	protoVersionSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "protocol-version" {
+			protoVersionSet = true
+		}
+	})
+	if *compareVers != "" {
+		if protoVersionSet {
+			fatalf("Invalid options: -protocol-version cannot be combined with -compare-versions, which requests each version in turn")
+		}
+		if protocolVersions, err = parseProtocolVersions(*compareVers); err != nil {
+			fatalf("Invalid options: %v", err)
+		}
+	}
+	if *tourMode && (*quietFlag || *output != outputText) {
+		fatalf("Invalid options: tour explains each step as text and cannot be combined with -q or -output json or ndjson")
+	}
+	if *negativeMode && *stdioCmd == "" && strings.ToLower(*mode) != "http" {
+		fatalf("Invalid options: -negative-tests requires -stdio or -transport http")
+	}
+	if *fuzzMode {
+		if *callTool == "" {
+			fatalf("Invalid options: fuzz requires -call with the tool to fuzz")
+		}
+		if *fuzzIters < 1 {
+			fatalf("Invalid options: -fuzz-iterations must be at least 1")
+		}
+	}
+	if *benchMode {
+		if *callTool == "" {
+			fatalf("Invalid options: bench requires -call with the tool to benchmark")
+		}
+		if *benchConc < 1 || *benchSess < 1 || *benchReqs < 0 || *benchDur < 0 {
+			fatalf("Invalid options: -concurrency and -sessions must be at least 1, and -requests and -duration cannot be negative")
+		}
+		if *benchSess > *benchConc {
+			fatalf("Invalid options: -sessions cannot exceed -concurrency, since each session needs a worker")
+		}
+	}
+	if *chaosMode {
+		if *stdioCmd != "" {
+			fatalf("Invalid options: chaos drops HTTP connections and requires -url")
+		}
+		if *chaosIters < 1 {
+			fatalf("Invalid options: -chaos-iterations must be at least 1")
+		}
+		if *chaosRate < 0 || *chaosRate > 1 {
+			fatalf("Invalid options: -chaos-rate must be between 0 and 1")
+		}
+	}
+	if *compareAuth && *stdioCmd != "" {
+		fatalf("Invalid options: compare-auth compares HTTP credentials and requires -url")
+	}
+	if *reconnTest && (*stdioCmd != "" || strings.ToLower(*mode) != "sse") {
+		fatalf("Invalid options: reconnect-test closes the SSE stream and requires -url and -transport sse")
+	}
+	if *resumeTest {
+		if *stdioCmd != "" || strings.ToLower(*mode) != "http" {
+			fatalf("Invalid options: resumability resumes streamable HTTP responses and requires -url and -transport http")
+		}
+		if *callTool == "" {
+			fatalf("Invalid options: resumability requires -call with a tool whose response is streamed, such as one that reports progress")
+		}
+	}
+	if *sessionTest && (*stdioCmd != "" || strings.ToLower(*mode) != "http") {
+		fatalf("Invalid options: session-test tests streamable HTTP sessions and requires -url and -transport http")
+	}
+	isolationSessions := isolationDefaultSessions
+	if *isoTest {
+		if *stdioCmd != "" {
+			fatalf("Invalid options: isolation-test opens several sessions to one server and requires -url; each stdio session is a server process of its own")
+		}
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "sessions" {
+				isolationSessions = *benchSess
+			}
+		})
+		if isolationSessions < 2 {
+			fatalf("Invalid options: isolation-test needs at least 2 -sessions")
+		}
+		if *isoWindow < 0 {
+			fatalf("Invalid options: -isolation-window must not be negative")
+		}
+		// Notifications that are not tied to a request arrive on the
+		// streamable HTTP GET stream
+		listenForNotifications = true
+	}
+	if *sessionID != "" || *keepSession {
+		if *stdioCmd != "" || strings.ToLower(*mode) != "http" {
+			fatalf("Invalid options: -session-id and -keep-session apply to streamable HTTP sessions and require -url and -transport http")
+		}
+		if *sessionTest {
+			fatalf("Invalid options: session-test opens and terminates a session of its own and cannot be combined with -session-id or -keep-session")
+		}
+	}
+	if *sessionID != "" {
+		if selectedMode != nil && !selectedMode.Session {
+			fatalf("Invalid options: -session-id cannot be combined with -%s, which opens sessions of its own", selectedMode.Flag)
+		}
+		// Without the initialization handshake the server's capabilities
+		// are unknown, so only the modes that do not depend on them can run
+		if selectedMode == nil || !slices.Contains([]string{"call", "list", "list-only", "raw-method", "ping"}, selectedMode.Flag) {
+			fatalf("Invalid options: -session-id skips the initialization handshake and requires -call, -list, -list-only, -raw-method or -ping")
+		}
+		resumeSessionID = *sessionID
+	}
+	if *versionMtx && protoVersionSet {
+		fatalf("Invalid options: -protocol-version cannot be combined with -version-matrix, which requests each version in turn")
+	}
+	var vectorBundle *testVectorBundle
+	if *verifyVecs != "" {
+		if vectorBundle, err = loadTestVectors(*verifyVecs); err != nil {
+			fatalf("Invalid test vectors: %v", err)
+		}
+	}
+	var consumerContract *contract
+	if *verifyCtr != "" {
+		if consumerContract, err = loadContract(*verifyCtr); err != nil {
+			fatalf("Invalid contract: %v", err)
+		}
+	}
+	var exposurePolicy *serverPolicy
+	if *verifyPol != "" {
+		if exposurePolicy, err = loadPolicy(*verifyPol); err != nil {
+			fatalf("Invalid policy: %v", err)
+		}
+	}
+	var replayExchanges []*recordedExchange
+	var replaySessions int
+	var pace replayPace
+	var replayIgnore []string
+	if *replayFile != "" {
+		if replayExchanges, replaySessions, err = loadReplay(*replayFile); err != nil {
+			fatalf("Invalid recording: %v", err)
+		}
+		if pace, err = parseReplayPace(*replayPaceF); err != nil {
+			fatalf("Invalid -replay-pace: %v", err)
+		}
+		for _, path := range strings.Split(*replayIgn, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				replayIgnore = append(replayIgnore, path)
+			}
+		}
+	}
+	if *tmplVars != "" && *readTmpl == "" {
+		fatalf("Invalid options: -template-vars requires -read-template")
+	}
+	if *fuzzy && *callTool == "" {
+		fatalf("Invalid options: -fuzzy requires -call")
+	}
+	if *expectHash != "" {
+		if *callTool == "" {
+			fatalf("Invalid options: -expect-schema-hash requires -call")
+		}
+		if err := validateSchemaHash(*expectHash); err != nil {
+			fatalf("Invalid -expect-schema-hash: %v", err)
+		}
+	}
+	if *promptArgs != "" && *getPromptArg == "" {
+		fatalf("Invalid options: -prompt-args requires -get-prompt")
+	}
+	var rawRequestParams json.RawMessage
+	if *rawParams != "" && *rawMethod == "" {
+		fatalf("Invalid options: -raw-params requires -raw-method")
+	}
+	if *rawMethod != "" {
+		params, err := parseRawParams(*rawParams)
+		if err != nil {
+			fatalf("Invalid options: %v", err)
+		}
+		rawRequestParams = params
+	}
+	var rawBatch []byte
+	var rawBatchEntries []batchEntry
+	if *rawBatchSpec != "" {
+		if *stdioCmd == "" && strings.ToLower(*mode) != "http" {
+			fatalf("Invalid options: -raw-batch requires -stdio or -transport http")
+		}
+		if feature := findProtocolFeature(featureBatching); !feature.available(requestedProtocolVersion) {
+			fatalf("Invalid options: %s; request it with -protocol-version 2025-03-26", feature.requirement())
+		}
+		if rawBatch, rawBatchEntries, err = parseRawBatch(*rawBatchSpec); err != nil {
+			fatalf("Invalid options: %v", err)
+		}
+	}
+	if *subscribe != "" || *subscribeAll {
+		listenForNotifications = true
+	}
+	if *pingMode {
+		if *pingCount < 1 {
+			fatalf("Invalid options: -ping-count must be at least 1")
+		}
+		if *pingInterval <= 0 {
+			fatalf("Invalid options: -ping-interval must be positive")
+		}
+	} else if *pingCount != 1 || *pingInterval != time.Second {
+		fatalf("Invalid options: -ping-count and -ping-interval require -ping")
+	}
+	if *keepalive < 0 {
+		fatalf("Invalid options: -keepalive must not be negative")
+	}
+	if *keepalive > 0 && !*interactive && *subscribe == "" && !*subscribeAll {
+		fatalf("Invalid options: -keepalive requires -interactive, -subscribe or -subscribe-all")
+	}
+	var bridge *samplingBridge
+	if *samplingURL != "" {
+		if bridge, err = newSamplingBridge(*samplingURL, *samplingMdl, *samplingKey, *callTimeout); err != nil {
+			fatalf("Invalid -sampling-endpoint: %v", err)
+		}
+		// Servers may send sampling requests outside a tool call's response
+		listenForNotifications = true
+	} else if *samplingMdl != "" || *samplingKey != "" {
+		fatalf("Invalid options: -sampling-model and -sampling-api-key require -sampling-endpoint")
+	}
+	var stub *samplingStub
+	if *samplingStb {
+		if bridge != nil {
+			fatalf("Invalid options: -sampling-stub and -sampling-endpoint are mutually exclusive")
+		}
+		if *stubDelay < 0 {
+			fatalf("Invalid options: -sampling-stub-delay must not be negative")
+		}
+		stub = &samplingStub{delay: *stubDelay, reply: *stubReply}
+		listenForNotifications = true
+	} else if *stubDelay != 0 || *stubReply != "" {
+		fatalf("Invalid options: -sampling-stub-delay and -sampling-stub-reply require -sampling-stub")
+	}
+	if *sampleMaxTok < 0 || *sampleBudget < 0 {
+		fatalf("Invalid options: -sampling-max-tokens and -sampling-token-budget must not be negative")
+	}
+	deniedModels, err := parseDeniedModels(*sampleDeny)
+	if err != nil {
+		fatalf("Invalid -sampling-deny-models: %v", err)
+	}
+	sampler := &samplingInspector{policy: samplingPolicy{
+		maxTokens:    *sampleMaxTok,
+		tokenBudget:  *sampleBudget,
+		deniedModels: deniedModels,
+		enforce:      *sampleEnforc,
+	}}
+	var elicitAnswers []elicitationAnswer
+	if *elicitFile != "" {
+		if elicitAnswers, err = loadElicitationAnswers(*elicitFile); err != nil {
+			fatalf("Invalid -elicitation-answers: %v", err)
+		}
+	}
+	elicitor := newElicitationHandler(elicitAnswers, *elicitFile)
+	if elicitor != nil && (*elicitFile != "" || *callTool != "" || *interactive) {
+		// Servers may send elicitation requests during a tool call on the
+		// session's stream rather than in the call's response
+		listenForNotifications = true
+	}
+	var changedRoot mcp.Root
+	if *rootsChange != "" {
+		if changedRoot, err = parseRoot(*rootsChange); err != nil {
+			fatalf("Invalid -roots-change: %v", err)
+		}
+	}
+	roots := newRootsProvider(rootList)
+	if len(rootList) > 0 || *interactive || *rootsChange != "" {
+		// Servers ask for roots/list on the session's stream, after
+		// initialization or a list_changed notification
+		listenForNotifications = true
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "fail-level" {
+			return
+		}
+		if err := setFailLevel(*failLvl); err != nil {
+			fatalf("Invalid -fail-level: %v", err)
+		}
+	})
+	if *suppressFile != "" {
+		loaded, err := loadSuppressions(*suppressFile)
+		if err != nil {
+			fatalf("Invalid -suppressions: %v", err)
+		}
+		useSuppressions(*suppressFile, loaded)
+	}
+	var minLogLevel mcp.LoggingLevel
+	if *logLevel != "" {
+		level, err := parseLogLevel(*logLevel)
+		if err != nil {
+			fatalf("Invalid -log-level: %v", err)
+		}
+		minLogLevel = level
+		// Servers may log at any time, not only while answering a request
+		listenForNotifications = true
+	}
+	if *saveContent != "" {
+		if *callTool == "" && *readTmpl == "" && !*interactive {
+			fatalf("Invalid options: -save-content requires -call, -read-template or -interactive")
+		}
+		if err := setContentDir(*saveContent); err != nil {
+			fatalf("Invalid -save-content: %v", err)
+		}
 	}
 
 	// Validate tool calling inputs
 	if err := validateInputs(*callTool, *toolParams); err != nil {
-		log.Fatalf("Input validation failed: %v", err)
+		fatalf("Input validation failed: %v", err)
 	}
 
-	fmt.Printf("=== MCP Server Test Tool ===\n")
+	// Inject stdin as a tool parameter if requested
+	if *stdinParam != "" {
+		if *callTool == "" {
+			fatalf("Invalid options: -stdin-param requires -call")
+		}
+		paramsWithStdin, err := injectStdinParam(*toolParams, *stdinParam, os.Stdin)
+		if err != nil {
+			fatalf("Failed to read stdin parameter: %v", err)
+		}
+		*toolParams = paramsWithStdin
+	}
+
+	// Parse headers; command line headers override environment and profile headers.
+	// Precedence from lowest to highest is profile, MCPPROBE_HEADERS, -headers-file,
+	// -headers and -H. The unexpanded headers are kept for display so secrets are
+	// not printed.
+	var fileHeaders map[string]string
+	if *headersFile != "" {
+		if fileHeaders, err = readHeadersFile(*headersFile); err != nil {
+			fatalf("Invalid headers file: %v", err)
+		}
+	}
+	displayHeaders := mergeHeaders(profileHeaders, envHeaderMap, fileHeaders, parseHeaders(*headers), headerList.toMap())
+
+	// A bearer token flag replaces any other Authorization header
+	token := *bearerToken
+	if token == "" && *bearerFile != "" {
+		if token, err = readBearerTokenFile(*bearerFile); err != nil {
+			fatalf("Invalid options: %v", err)
+		}
+	}
+	if token != "" {
+		if *stdioCmd != "" {
+			fatalf("Invalid options: -bearer-token and -bearer-token-file require an HTTP or SSE server (-url)")
+		}
+		if *useOAuth || *oauthCC {
+			fatalf("Invalid options: -bearer-token and -bearer-token-file cannot be combined with -oauth or -oauth-client-credentials")
+		}
+		displayHeaders["Authorization"] = "Bearer " + token
+	}
+	headerMap, err := expandHeaderVars(displayHeaders)
+	if err != nil {
+		fatalf("Invalid headers: %v", err)
+	}
+
+	// Set by the OAuth flow; the transports add the bearer token to every request
+	var oauthConfig *transport.OAuthConfig
+
+	// dialWith creates and starts a fresh, quiet client for the given transport,
+	// URL and credentials
+	dialWith := func(ctx context.Context, transportName, target string, headers map[string]string, oauth *transport.OAuthConfig) (*client.Client, error) {
+		var c *client.Client
+		var err error
+		switch transportName {
+		case "sse":
+			c, err = createSSEClient(target, headers, *callTimeout, *acceptTime, oauth, quietLogger{})
+		case "http":
+			c, err = createHTTPClient(target, headers, *callTimeout, *acceptTime, oauth, quietLogger{})
+		default:
+			return nil, fmt.Errorf("unsupported transport type '%s'", transportName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Start(ctx); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	// dialSSE returns a function that opens and starts a fresh SSE stream,
+	// for transports that reopen their stream when it is lost
+	dialSSE := func(logger util.Logger) func(ctx context.Context) (*transport.SSE, error) {
+		return func(ctx context.Context) (*transport.SSE, error) {
+			t, err := newSSETransport(*serverURL, headerMap, *callTimeout, *acceptTime, oauthConfig, logger)
+			if err != nil {
+				return nil, err
+			}
+			if err := t.Start(ctx); err != nil {
+				_ = t.Close()
+				return nil, err
+			}
+			return t, nil
+		}
+	}
+
+	// dialURL creates and starts a fresh, quiet client for the given transport and URL
+	dialURL := func(ctx context.Context, transportName, target string) (*client.Client, error) {
+		return dialWith(ctx, transportName, target, headerMap, oauthConfig)
+	}
+
+	// dial connects to the configured server for readiness polling and repeated runs
+	dial := func(ctx context.Context) (*client.Client, error) {
+		if *stdioCmd != "" {
+			return createStdioClient(*stdioCmd, *stdioArgs, *stdioEnv, false)
+		}
+		return dialURL(ctx, strings.ToLower(*mode), *serverURL)
+	}
+
+	// Block until the server accepts connections and completes initialization
+	if *waitReady {
+		if err := waitForReady(dial, *waitTimeout, *timeout); err != nil {
+			fatalf("Server not ready: %v", err)
+		}
+	}
+
+	// Authorize with OAuth before connecting
+	if *useOAuth {
+		fmt.Println("=== OAuth Authorization ===")
+		oauthConfig, err = authorizeOAuth(*serverURL, oauthOptions{
+			clientID: *oauthClient,
+			scopes:   parseScopes(*oauthScopes),
+			port:     *oauthPort,
+			cache:    !*noTokenCache,
+		})
+		if err != nil {
+			fatalf("OAuth authorization failed: %v", err)
+		}
+	}
+	if *oauthCC {
+		fmt.Println("=== OAuth Client Credentials ===")
+		oauthConfig, err = clientCredentialsOAuth(*serverURL, oauthOptions{
+			clientID:     *oauthClient,
+			clientSecret: *oauthSecret,
+			tokenURL:     *oauthToken,
+			scopes:       parseScopes(*oauthScopes),
+			cache:        !*noTokenCache,
+		})
+		if err != nil {
+			fatalf("OAuth token request failed: %v", err)
+		}
+	}
+
+	// Probe the server over both transports and report the differences
+	if *compareMode {
+		transportName := strings.ToLower(*mode)
+		otherName := "sse"
+		if transportName == "sse" {
+			otherName = "http"
+		}
+		otherURL := *compareURL
+		if otherURL == "" {
+			if otherURL, err = otherTransportURL(*serverURL, transportName); err != nil {
+				fatalf("Invalid options: %v", err)
+			}
+		}
+		report.setTarget(*serverURL+" | "+otherURL, transportName+" | "+otherName)
+		targetFor := func(name, target string) transportTarget {
+			return transportTarget{name: name, url: target, dial: func(ctx context.Context) (*client.Client, error) {
+				return dialURL(ctx, name, target)
+			}}
+		}
+		// Always list SSE first so output is stable regardless of -transport
+		a, b := targetFor(transportName, *serverURL), targetFor(otherName, otherURL)
+		if transportName != "sse" {
+			a, b = b, a
+		}
+		if err := runTransportComparison(a, b, *timeout); err != nil {
+			failRun(err)
+		}
+		printFinished()
+		return
+	}
+
+	// Compare the server with its previous release and suggest a version bump
+	if *baselineURL != "" {
+		transportName := strings.ToLower(*mode)
+		currentTarget, currentName := *serverURL, transportName
+		if *stdioCmd != "" {
+			currentTarget, currentName = *stdioCmd, "stdio"
+		}
+		report.setTarget(*baselineURL+" | "+currentTarget, transportName+" | "+currentName)
+		opts := suiteOptions{callTool: *callTool}
+		if *callTool != "" {
+			if opts.callArgs, err = parseToolParameters(*toolParams); err != nil {
+				fatalf("Invalid tool parameters: %v", err)
+			}
+		}
+		baseline := transportTarget{name: transportName, url: *baselineURL, dial: func(ctx context.Context) (*client.Client, error) {
+			return dialURL(ctx, transportName, *baselineURL)
+		}}
+		current := transportTarget{name: currentName, url: currentTarget, dial: dial}
+		if err := runBaselineComparison(baseline, current, opts, *timeout); err != nil {
+			failRun(err)
+		}
+		printFinished()
+		return
+	}
+
+	// runMode runs a mode that opens sessions of its own, against -url over
+	// -transport or the -stdio command, and finishes the run
+	runMode := func(run func() error) {
+		target, transportName := *serverURL, strings.ToLower(*mode)
+		if *stdioCmd != "" {
+			target, transportName = *stdioCmd, "stdio"
+		}
+		report.setTarget(target, transportName)
+		fmt.Printf("Target: %s (%s)\n\n", target, transportName)
+		if err := run(); err != nil {
+			failRun(err)
+		}
+		printFinished()
+	}
+
+	// Run the checks under each protocol version and report the differences
+	if len(protocolVersions) > 0 {
+		opts := suiteOptions{callTool: *callTool}
+		if *callTool != "" {
+			if opts.callArgs, err = parseToolParameters(*toolParams); err != nil {
+				fatalf("Invalid tool parameters: %v", err)
+			}
+		}
+		runMode(func() error { return runVersionComparison(dial, protocolVersions, opts, *timeout) })
+		return
+	}
+
+	// Initialize with each protocol version and tabulate how the server negotiates
+	if *versionMtx {
+		runMode(func() error { return runVersionMatrix(dial, *timeout) })
+		return
+	}
+
+	// openWire opens a session that carries messages as bytes, for the
+	// messages the client library cannot send
+	openWire := func() (negativeWire, error) {
+		if *stdioCmd != "" {
+			return startStdioNegativeWire(*stdioCmd, *stdioArgs, *stdioEnv)
+		}
+		return newHTTPNegativeWire(*serverURL, headerMap, oauthConfig, *timeout, *acceptTime), nil
+	}
+
+	// Run the conformance suite and score the server
+	if *conformMode {
+		opts := conformanceOptions{callTool: *callTool}
+		if *stdioCmd != "" || strings.ToLower(*mode) == "http" {
+			opts.openWire = openWire
+		}
+		if *callTool != "" {
+			if opts.callArgs, err = parseToolParameters(*toolParams); err != nil {
+				fatalf("Invalid tool parameters: %v", err)
+			}
+		}
+		runMode(func() error { return runConformance(dial, opts, *timeout) })
+		return
+	}
+
+	// Send malformed requests and check that the server rejects each properly
+	if *negativeMode {
+		runMode(func() error { return runNegativeTests(openWire, *timeout) })
+		return
+	}
+
+	// Send a JSON-RPC batch and show the response to each of its requests
+	if rawBatch != nil {
+		runMode(func() error { return runRawBatch(openWire, rawBatch, rawBatchEntries, *timeout) })
+		return
+	}
+
+	// Call a tool with mutated arguments
+	if *fuzzMode {
+		opts := fuzzOptions{tool: *callTool, iterations: *fuzzIters, seed: *fuzzSeed}
+		if opts.baseline, err = parseToolParameters(*toolParams); err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		runMode(func() error { return runFuzz(dial, opts, *timeout, *callTimeout) })
+		return
+	}
+
+	// Drive concurrent tool calls and measure the server under load
+	if *benchMode {
+		opts := benchOptions{tool: *callTool, concurrency: *benchConc, sessions: *benchSess, requests: *benchReqs, duration: *benchDur}
+		if opts.requests == 0 && opts.duration == 0 {
+			opts.requests = benchDefaultRequests
+		}
+		if opts.args, err = parseToolParameters(*toolParams); err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		runMode(func() error { return runBenchmark(dial, opts, *timeout, *callTimeout) })
+		return
+	}
+
+	// Drop connections at random and observe how the session recovers
+	if *chaosMode {
+		opts := chaosOptions{tool: *callTool, iterations: *chaosIters, rate: *chaosRate, seed: *chaosSeed, httpSession: strings.ToLower(*mode) == "http"}
+		if opts.args, err = parseToolParameters(*toolParams); err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		runMode(func() error { return runChaos(dial, opts, *timeout, *callTimeout) })
+		return
+	}
+
+	// Probe the server with and without credentials and report the difference
+	if *compareAuth {
+		transportName := strings.ToLower(*mode)
+		anonHeaders, dropped := anonymousHeaders(headerMap)
+		if len(dropped) == 0 && oauthConfig == nil {
+			fatalf("Invalid options: compare-auth needs credentials to leave out; use -bearer-token, -H with a credential header, -oauth or -oauth-client-credentials")
+		}
+		surface := &authSurfaceReport{DroppedHeaders: dropped, DroppedOAuth: oauthConfig != nil}
+		args, err := parseToolParameters(*toolParams)
+		if err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		dialAnonymous := func(ctx context.Context) (*client.Client, error) {
+			return dialWith(ctx, transportName, *serverURL, anonHeaders, nil)
+		}
+		runMode(func() error {
+			return runAuthComparison(dial, dialAnonymous, surface, *callTool, args, *timeout, *callTimeout)
+		})
+		return
+	}
+
+	// Drop a streamed response and check that it resumes with Last-Event-ID
+	if *resumeTest {
+		args, err := parseToolParameters(*toolParams)
+		if err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		runMode(func() error {
+			wire := newHTTPNegativeWire(*serverURL, headerMap, oauthConfig, *callTimeout, *acceptTime)
+			return runResumabilityTest(wire, *callTool, args, *timeout, *callTimeout)
+		})
+		return
+	}
+
+	// Terminate a session and check how the server rejects requests on it
+	if *sessionTest {
+		runMode(func() error {
+			wire := newHTTPNegativeWire(*serverURL, headerMap, oauthConfig, *timeout, *acceptTime)
+			return runSessionLifecycle(wire, *timeout)
+		})
+		return
+	}
+
+	// Open several sessions and check that none sees another's notifications
+	if *isoTest {
+		args, err := parseToolParameters(*toolParams)
+		if err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		runMode(func() error {
+			return runIsolationTest(dial, isolationSessions, *callTool, args, *isoWindow, *timeout, *callTimeout)
+		})
+		return
+	}
+
+	// Close the SSE stream and check that the client reopens it
+	if *reconnTest {
+		args, err := parseToolParameters(*toolParams)
+		if err != nil {
+			fatalf("Invalid tool parameters: %v", err)
+		}
+		runMode(func() error { return runReconnectTest(dialSSE(nil), *callTool, args, *timeout, *callTimeout) })
+		return
+	}
+
+	// Re-send a recorded session's requests and compare the responses
+	if replayExchanges != nil {
+		runMode(func() error {
+			return runReplay(dial, replayExchanges, replaySessions, *replayFile, pace, replayIgnore, *timeout, *callTimeout)
+		})
+		return
+	}
+
+	// Verify the server against a test vector bundle
+	if vectorBundle != nil {
+		runMode(func() error { return runTestVectors(dial, vectorBundle, *verifyVecs, *timeout) })
+		return
+	}
+
+	// Check that the server satisfies a consumer contract
+	if consumerContract != nil {
+		runMode(func() error {
+			return runContractVerification(dial, consumerContract, *verifyCtr, *timeout, *callTimeout)
+		})
+		return
+	}
+
+	// Check that the server exposes nothing outside a policy
+	if exposurePolicy != nil {
+		runMode(func() error { return runPolicyVerification(dial, exposurePolicy, *verifyPol, *timeout) })
+		return
+	}
+
+	// Repeat the capability checks and aggregate the results
+	if *runs > 1 {
+		printBanner()
+		runMode(func() error { return runRepeatedSuite(dial, *runs, *timeout) })
+		return
+	}
+
+	printBanner()
+
+	// Hold back the output of a capability test so that its summary comes first
+	var layout *summaryLayout
+	capabilityTest := !*tourMode && !*list && !*listOnly && *callTool == "" && *readTmpl == "" && *getPromptArg == "" && *completeArg == "" &&
+		*rawMethod == "" && !*pingMode && *subscribe == "" && !*subscribeAll && !*interactive
+	if capabilityTest && *layoutFlag == layoutSummary && outputFormat == outputText && !quiet && !*debug {
+		target := *serverURL
+		if *stdioCmd != "" {
+			target = *stdioCmd
+		}
+		fmt.Printf("Testing %s...\n", target)
+		layout = startSummaryLayout(*verbose)
+	}
 
 	// Create client based on transport type
 	var mcpClient *client.Client
-	var err error
 	var isStdio bool
 
 	// Create debug logger if enabled (for SSE/HTTP transports)
@@ -189,6 +1547,7 @@ func main() {
 	// Check if stdio mode is enabled
 	if *stdioCmd != "" {
 		isStdio = true
+		report.setTarget(*stdioCmd, "stdio")
 		fmt.Printf("Transport: stdio\n")
 		fmt.Printf("Command: %s\n", *stdioCmd)
 		if *stdioArgs != "" {
@@ -197,41 +1556,76 @@ func main() {
 		if *stdioEnv != "" {
 			fmt.Printf("Environment: %s\n", *stdioEnv)
 		}
-		fmt.Printf("Timeout: %s\n", *timeout)
+		fmt.Printf("Timeout: %s\n", humanDuration(*timeout))
 		fmt.Println()
 
 		fmt.Println("Creating stdio client...")
+		connectStart := time.Now()
 		mcpClient, err = createStdioClient(*stdioCmd, *stdioArgs, *stdioEnv, *debug)
+		report.addTiming("connect", time.Since(connectStart), err)
+		emitEvent(eventConnect, map[string]any{
+			"transport":  "stdio",
+			"target":     *stdioCmd,
+			"durationMs": durationMillis(time.Since(connectStart)),
+			"error":      errorField(err),
+		})
 	} else {
 		isStdio = false
+		report.setTarget(*serverURL, strings.ToLower(*mode))
 		fmt.Printf("Server URL: %s\n", *serverURL)
 		fmt.Printf("Transport: %s\n", *mode)
-		fmt.Printf("Timeout: %s\n", *timeout)
+		fmt.Printf("Timeout: %s\n", humanDuration(*timeout))
+		if probeProxyURL != nil {
+			fmt.Printf("Proxy: %s\n", probeProxyURL.Redacted())
+		}
 		fmt.Println()
 
-		// Parse headers
-		headerMap := parseHeaders(*headers)
-		if len(headerMap) > 0 && *verbose {
-			fmt.Printf("Headers: %v\n", headerMap)
+		if len(displayHeaders) > 0 && *verbose {
+			fmt.Printf("Headers: %v\n", redactHeaders(displayHeaders))
 		}
 
 		switch strings.ToLower(*mode) {
 		case "sse":
 			fmt.Println("Creating SSE client...")
-			mcpClient, err = createSSEClient(*serverURL, headerMap, *callTimeout, logger)
+			if *interactive {
+				// A long session reopens the stream if it is lost
+				mcpClient = client.NewClient(newReconnectingSSE(dialSSE(logger), *timeout, printReconnect))
+			} else {
+				mcpClient, err = createSSEClient(*serverURL, headerMap, *callTimeout, *acceptTime, oauthConfig, logger)
+			}
 		case "http":
 			fmt.Println("Creating HTTP client...")
-			mcpClient, err = createHTTPClient(*serverURL, headerMap, *callTimeout, logger)
+			mcpClient, err = createHTTPClient(*serverURL, headerMap, *callTimeout, *acceptTime, oauthConfig, logger)
 		default:
-			fmt.Printf("Error: Unsupported transport type '%s'. Use 'sse' or 'http'\n", *mode)
-			os.Exit(1)
+			failRun(fmt.Errorf("unsupported transport type '%s' (use 'sse' or 'http')", *mode))
 		}
 	}
 
 	if err != nil {
-		log.Fatalf("Failed to create client: %v", err)
+		fatalf("Failed to create client: %v", err)
+	}
+	if *strictMode {
+		mcpClient = enableStrict(mcpClient)
 	}
+	if bridge != nil {
+		sampler.responder = bridge
+		fmt.Printf("Sampling requests are forwarded to %s\n", bridge.endpoint)
+	}
+	if stub != nil {
+		sampler.responder = stub
+		activeSamplingStub = stub
+		fmt.Printf("Sampling requests are answered by the stub after %s\n", humanDuration(stub.delay))
+	}
+	enableSampling(mcpClient, sampler)
+	if elicitor != nil {
+		enableElicitation(mcpClient, elicitor)
+	}
+	enableRoots(mcpClient, roots)
 	defer func(mcpClient *client.Client) {
+		if *keepSession || resumeSessionID != "" {
+			// Closing a streamable HTTP client terminates its session
+			return
+		}
 		_ = mcpClient.Close()
 	}(mcpClient)
 
@@ -242,17 +1636,37 @@ func main() {
 	needsManualStart := !isStdio || *debug
 	if needsManualStart {
 		fmt.Println("Starting client connection...")
-		if err := mcpClient.Start(context.Background()); err != nil {
-			log.Fatalf("Failed to start client: %v", err)
+		connectStart := time.Now()
+		err := mcpClient.Start(context.Background())
+		report.addTiming("connect", time.Since(connectStart), err)
+		emitEvent(eventConnect, map[string]any{
+			"transport":  report.Transport,
+			"target":     report.Target,
+			"durationMs": durationMillis(time.Since(connectStart)),
+			"error":      errorField(err),
+		})
+		if err != nil {
+			if isUnauthorized(err) {
+				diagnoseUnauthorized(*serverURL, strings.ToLower(*mode), headerMap, *timeout)
+			}
+			if isTLSError(err) {
+				diagnoseTLSFailure(*serverURL, *timeout)
+			}
+			fatalf("Failed to start client: %v", err)
 		}
 		fmt.Println("Client connection started successfully")
 	} else {
+		// The transport is already running; Start only registers the handlers
+		// for notifications and server requests such as sampling
+		if err := mcpClient.Start(context.Background()); err != nil {
+			fatalf("Failed to start client: %v", err)
+		}
 		fmt.Println("Stdio client started automatically")
 	}
 
 	// Display POST URL for SSE connections
 	if strings.ToLower(*mode) == "sse" {
-		if sseTransport, ok := mcpClient.GetTransport().(*transport.SSE); ok {
+		if sseTransport, ok := unwrapTransport(mcpClient.GetTransport()).(*transport.SSE); ok {
 			endpoint := sseTransport.GetEndpoint()
 			if endpoint != nil {
 				fmt.Printf("SSE POST URL: %s\n", endpoint.String())
@@ -260,63 +1674,229 @@ func main() {
 		}
 	}
 
-	// Perform initialization handshake with timeout
-	fmt.Println("\nPerforming initialization handshake...")
-	initCtx, initCancel := context.WithTimeout(context.Background(), *timeout)
-	defer initCancel()
-	if err := performInitialization(initCtx, mcpClient, *verbose); err != nil {
-		log.Fatalf("Failed to initialize: %v", err)
+	// Perform initialization handshake with timeout, unless the session
+	// was initialized before
+	if resumeSessionID != "" {
+		fmt.Printf("\nResuming session %s (protocol %s); the server's capabilities are not known\n", resumeSessionID, requestedProtocolVersion)
+		report.setSessionID(resumeSessionID)
+	} else {
+		fmt.Println("\nPerforming initialization handshake...")
+		initCtx, initCancel := context.WithTimeout(context.Background(), *timeout)
+		defer initCancel()
+		if err := performInitialization(initCtx, mcpClient, *verbose); err != nil {
+			if isUnauthorized(err) && !isStdio {
+				diagnoseUnauthorized(*serverURL, strings.ToLower(*mode), headerMap, *timeout)
+			}
+			if isTLSError(err) && !isStdio {
+				diagnoseTLSFailure(*serverURL, *timeout)
+			}
+			fatalf("Failed to initialize: %v", err)
+		}
+		fmt.Println("\nInitialization completed successfully")
+
+		// Display the session the streamable HTTP server assigned
+		if httpTransport, ok := unwrapTransport(mcpClient.GetTransport()).(*transport.StreamableHTTP); ok {
+			if id := httpTransport.GetSessionId(); id != "" {
+				fmt.Printf("Session ID: %s\n", id)
+				report.setSessionID(id)
+				if *keepSession {
+					fmt.Printf("The session is left open at exit; join it with -session-id %s\n", id)
+				}
+			} else if *keepSession {
+				fmt.Println("The server did not assign a session ID; it is stateless, so -keep-session has no effect")
+			}
+		}
+	}
+
+	// Report the TLS connection to HTTPS servers
+	if tlsInfo := observedTLS(*serverURL); tlsInfo != nil && !isStdio {
+		for _, f := range tlsInfo.findings {
+			report.recordFinding(f)
+		}
+		report.setTLS(tlsInfo)
+		emitEvent(eventTLS, map[string]any{
+			"host":        tlsInfo.Host,
+			"version":     tlsInfo.Version,
+			"cipherSuite": tlsInfo.CipherSuite,
+			"verified":    tlsInfo.Verified,
+			"chain":       tlsInfo.Chain,
+			"warnings":    tlsInfo.Warnings,
+		})
+		if *verbose {
+			printTLSDiagnostics(tlsInfo)
+		}
+	}
+
+	// Give servers that register capabilities asynchronously time to settle
+	if *settleDelay > 0 {
+		fmt.Printf("Waiting %s for the server to settle...\n", humanDuration(*settleDelay))
+		time.Sleep(*settleDelay)
+	}
+
+	if minLogLevel != "" {
+		applyLogLevel(mcpClient, minLogLevel, *timeout)
+	}
+
+	// Verify what the profile expects of the server before using it
+	if profile != nil && profile.Expect != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err := verifyExpectations(ctx, mcpClient, profile.Expect)
+		cancel()
+		if err != nil {
+			failRun(err)
+		}
 	}
-	fmt.Println("\nInitialization completed successfully")
 
 	// Handle different execution modes with appropriate context management
 	switch {
+	case *tourMode:
+		transportName := strings.ToLower(*mode)
+		if isStdio {
+			transportName = "stdio"
+		}
+		if err := runTour(mcpClient, transportName, *timeout, *callTimeout); err != nil {
+			failRun(err)
+		}
 	case *list:
 		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 		defer cancel()
 		if err := listToolsMinimal(ctx, mcpClient); err != nil {
-			log.Fatalf("Failed to list tools: %v", err)
+			fatalf("Failed to list tools: %v", err)
 		}
 	case *listOnly:
 		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 		defer cancel()
 		if err := listToolsOnly(ctx, mcpClient, *verbose); err != nil {
-			log.Fatalf("Failed to list tools: %v", err)
+			fatalf("Failed to list tools: %v", err)
 		}
 	case *callTool != "":
+		// Check the name against the listed tools before calling
+		resolveCtx, resolveCancel := context.WithTimeout(context.Background(), *timeout)
+		name, tool, err := resolveToolName(resolveCtx, mcpClient, *callTool, *fuzzy)
+		resolveCancel()
+		if err == nil && *expectHash != "" {
+			// Without the listed schema the call cannot be proven safe
+			if tool == nil {
+				err = fmt.Errorf("cannot check -expect-schema-hash: tool '%s' could not be listed", name)
+			} else {
+				err = checkToolSchemaHash(*tool, *expectHash)
+			}
+		}
+		if err != nil {
+			failRun(err)
+		}
+		*callTool = name
+
 		if *repeat > 1 {
 			if err := runLoadTest(mcpClient, *callTool, *toolParams, *repeat, *concurrent, *callTimeout); err != nil {
 				fmt.Fprintf(os.Stderr, "Load test completed with errors: %v\n", err)
-				os.Exit(1)
+				exitProgram(1)
+			}
+		} else if *resultOnly {
+			ctx, cancel := context.WithTimeout(context.Background(), *callTimeout)
+			defer cancel()
+			result, err := callToolResultOnly(ctx, mcpClient, *callTool, *toolParams)
+			if errors.Is(err, errCallInterrupted) {
+				report.addError("Failed to call tool '%s': %v", *callTool, err)
+				exitProgram(interruptedExitCode)
+			}
+			if err != nil {
+				report.addError("Failed to call tool '%s': %v", *callTool, err)
+				fmt.Fprintf(os.Stderr, "Failed to call tool '%s': %v\n", *callTool, err)
+				exitProgram(1)
+			}
+			if result.IsError {
+				fmt.Fprintf(os.Stderr, "Tool '%s' returned an error result\n", *callTool)
+				exitProgram(1)
 			}
 		} else {
 			ctx, cancel := context.WithTimeout(context.Background(), *callTimeout)
 			defer cancel()
 			if err := callSpecificTool(ctx, mcpClient, *callTool, *toolParams, *verbose); err != nil {
 				handleToolCallError(err, *callTool)
-				os.Exit(1)
+				if infoSuppressed {
+					fmt.Fprintf(os.Stderr, "Failed to call tool '%s': %v\n", *callTool, err)
+				}
+				if errors.Is(err, errCallInterrupted) {
+					exitProgram(interruptedExitCode)
+				}
+				exitProgram(1)
+			}
+		}
+	case *readTmpl != "":
+		ctx, cancel := context.WithTimeout(context.Background(), *callTimeout)
+		defer cancel()
+		if err := readResourceTemplate(ctx, mcpClient, *readTmpl, *tmplVars); err != nil {
+			failRun(err)
+		}
+	case *getPromptArg != "":
+		ctx, cancel := context.WithTimeout(context.Background(), *callTimeout)
+		defer cancel()
+		if err := getPrompt(ctx, mcpClient, *getPromptArg, *promptArgs); err != nil {
+			failRun(err)
+		}
+	case *completeArg != "":
+		ctx, cancel := context.WithTimeout(context.Background(), *callTimeout)
+		defer cancel()
+		if err := completeArgument(ctx, mcpClient, *completeArg); err != nil {
+			failRun(err)
+		}
+	case *rawMethod != "":
+		ctx, cancel := context.WithTimeout(context.Background(), *callTimeout)
+		defer cancel()
+		if err := sendRawRequest(ctx, mcpClient, *rawMethod, rawRequestParams); err != nil {
+			failRun(err)
+		}
+	case *pingMode:
+		if err := runPing(mcpClient, *pingCount, *pingInterval, *timeout); err != nil {
+			failRun(err)
+		}
+	case *subscribe != "" || *subscribeAll:
+		// Subscriptions stay open until interrupted; each request has its own timeout
+		if *keepalive > 0 {
+			defer startKeepalive(mcpClient, *keepalive, *timeout)()
+		}
+		var uris []string
+		for _, uri := range strings.Split(*subscribe, ",") {
+			if uri = strings.TrimSpace(uri); uri != "" {
+				uris = append(uris, uri)
 			}
 		}
+		if err := watchResourceUpdates(mcpClient, uris, *subscribeAll, *timeout); err != nil {
+			failRun(err)
+		}
 	case *interactive:
 		// Interactive mode manages its own contexts for each tool call
 		// Connection uses background context to stay alive indefinitely
-		if err := interactiveModeWithTimeout(mcpClient, *callTimeout, *verbose); err != nil {
-			log.Fatalf("Interactive mode failed: %v", err)
+		if *keepalive > 0 {
+			defer startKeepalive(mcpClient, *keepalive, *timeout)()
+		}
+		if err := interactiveModeWithTimeout(mcpClient, aliases, roots, *callTimeout, *verbose); err != nil {
+			fatalf("Interactive mode failed: %v", err)
 		}
 	default:
 		// Default behavior: test server capabilities
+		layout.section(sectionCapabilities)
 		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 		defer cancel()
-		if err := testServerCapabilities(ctx, mcpClient, *verbose); err != nil {
-			log.Fatalf("Failed to test capabilities: %v", err)
+		if err := testServerCapabilities(ctx, mcpClient, minLogLevel, *verbose); err != nil {
+			fatalf("Failed to test capabilities: %v", err)
 		}
+		if *rootsChange != "" {
+			if _, err := roots.probeReaction(mcpClient, changedRoot, *timeout); err != nil {
+				fmt.Printf("\n%v\n", err)
+				report.addError("%v", err)
+			}
+		}
+		layout.finish()
 	}
 
-	fmt.Println("\n=== Finished ===")
+	printFinished()
+	enforceFailLevel()
 
 	// For stdio transport, exit immediately to avoid blocking on subprocess cleanup
 	if isStdio {
-		os.Exit(0)
+		exitProgram(0)
 	}
 }
 
@@ -370,6 +1950,7 @@ func runLoadTest(mcpClient *client.Client, toolName string, paramsJSON string, r
 				_, callErr := mcpClient.CallTool(ctx, req)
 				cancel()
 				dur := time.Since(t0)
+				report.addTiming("tools/call "+toolName, dur, callErr)
 				results[idx] = result{duration: dur, err: callErr}
 
 				mu.Lock()
@@ -400,8 +1981,8 @@ func runLoadTest(mcpClient *client.Client, toolName string, paramsJSON string, r
 	throughput := float64(repeat) / totalDuration.Seconds()
 
 	fmt.Printf("\n=== Load Test Results ===\n")
-	fmt.Printf("Total calls:  %d (%d succeeded, %d failed)\n", repeat, successes, failures)
-	fmt.Printf("Duration:     %s\n", totalDuration.Round(time.Millisecond))
+	fmt.Printf("Total calls:  %s (%s succeeded, %s failed)\n", humanCount(repeat), humanCount(successes), humanCount(failures))
+	fmt.Printf("Duration:     %s\n", humanDuration(totalDuration))
 	fmt.Printf("Throughput:   %.2f calls/sec\n", throughput)
 
 	if len(successDurations) > 0 {
@@ -417,11 +1998,11 @@ func runLoadTest(mcpClient *client.Client, toolName string, paramsJSON string, r
 		p99 := successDurations[int(float64(n-1)*0.99)]
 
 		fmt.Printf("Latency (successful calls):\n")
-		fmt.Printf("  Min:  %s\n", successDurations[0].Round(time.Microsecond))
-		fmt.Printf("  Mean: %s\n", mean.Round(time.Microsecond))
-		fmt.Printf("  P95:  %s\n", p95.Round(time.Microsecond))
-		fmt.Printf("  P99:  %s\n", p99.Round(time.Microsecond))
-		fmt.Printf("  Max:  %s\n", successDurations[n-1].Round(time.Microsecond))
+		fmt.Printf("  Min:  %s\n", humanDuration(successDurations[0]))
+		fmt.Printf("  Mean: %s\n", humanDuration(mean))
+		fmt.Printf("  P95:  %s\n", humanDuration(p95))
+		fmt.Printf("  P99:  %s\n", humanDuration(p99))
+		fmt.Printf("  Max:  %s\n", humanDuration(successDurations[n-1]))
 	}
 
 	if failures > 0 {
@@ -446,38 +2027,253 @@ func parseHeaders(headerStr string) map[string]string {
 	return headers
 }
 
-func createSSEClient(serverURL string, headers map[string]string, callTimeout time.Duration, logger util.Logger) (*client.Client, error) {
+// headerFlags collects repeatable -H "Key: Value" flags. Unlike -headers,
+// each flag holds exactly one header, so values may contain commas and colons.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if _, _, err := parseHeaderLine(value); err != nil {
+		return err
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// toMap returns the headers as a map; later flags override earlier ones
+func (h *headerFlags) toMap() map[string]string {
+	headers := make(map[string]string)
+	for _, line := range *h {
+		if key, value, err := parseHeaderLine(line); err == nil {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+// parseHeaderLine parses a single "Key: Value" header. Only the first colon
+// separates the key from the value.
+func parseHeaderLine(line string) (string, string, error) {
+	key, value, found := strings.Cut(line, ":")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", fmt.Errorf("invalid header '%s' (expected 'Key: Value')", line)
+	}
+	if strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid header name '%s'", key)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// newProbeHTTPClient creates the HTTP client used by the SSE and HTTP transports.
+// timeout bounds a complete request including reading the response, while
+// acceptTimeout (if non-zero) bounds connecting, the TLS handshake, opening
+// event streams and the answers to requests that need no work, such as
+// initialization and listings. This lets an unresponsive server fail fast
+// while still allowing legitimately long tool calls, whose JSON responses may
+// only start once the tool has finished.
+func newProbeHTTPClient(timeout, acceptTimeout time.Duration) *http.Client {
+	var roundTripper http.RoundTripper = &tlsCaptureTransport{base: newProbeTransport(acceptTimeout)}
+	if acceptTimeout > 0 {
+		roundTripper = &streamAcceptTransport{base: roundTripper, timeout: acceptTimeout}
+	}
+	if wireCapture != nil {
+		roundTripper = &wireCaptureTransport{base: roundTripper, log: wireCapture}
+	}
+	if sessionCapture != nil {
+		roundTripper = &captureTransport{base: roundTripper, capture: sessionCapture}
+	}
+	if connTracing {
+		roundTripper = &connTraceTransport{base: roundTripper}
+	}
+	if retryPolicy != nil {
+		roundTripper = &retryTransport{base: roundTripper, opts: *retryPolicy}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: roundTripper,
+	}
+}
+
+// newProbeTransport creates the HTTP transport for connections to the server,
+// with the TLS settings from -ca-cert and -insecure and the proxy from -proxy
+// or the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY)
+func newProbeTransport(acceptTimeout time.Duration) *http.Transport {
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if probeTLSConfig != nil {
+		httpTransport.TLSClientConfig = probeTLSConfig.Clone()
+	}
+	if probeProxyURL != nil {
+		httpTransport.Proxy = http.ProxyURL(probeProxyURL)
+	}
+	if acceptTimeout > 0 {
+		dialer := &net.Dialer{Timeout: acceptTimeout, KeepAlive: 30 * time.Second}
+		httpTransport.DialContext = dialer.DialContext
+		httpTransport.TLSHandshakeTimeout = acceptTimeout
+	}
+	if chaosConns != nil {
+		httpTransport.DialContext = chaosConns.wrap(httpTransport.DialContext)
+	}
+	return httpTransport
+}
+
+// streamAcceptTransport bounds the time until the server answers a GET that
+// opens an event stream, or a request that needs no work (see acceptLimited).
+// Other requests are not limited, since a streamable HTTP server may only send
+// the response headers of a tool call once the tool has finished.
+type streamAcceptTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// acceptLimited reports whether -accept-timeout limits the time until the
+// server answers a request: a GET that opens an event stream, or a POST of
+// the handshake, a ping, a listing or a notification, which a server answers
+// without running anything
+func acceptLimited(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet:
+		return true
+	case http.MethodPost:
+		method := jsonRPCMethod(req)
+		return method == "initialize" || method == "ping" || strings.HasSuffix(method, "/list") || strings.HasPrefix(method, "notifications/")
+	}
+	return false
+}
+
+func (t *streamAcceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !acceptLimited(req) {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(t.timeout, cancel)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() {
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("timeout awaiting response headers after %s", humanDuration(t.timeout))
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnCloseBody releases a request's context once its body is closed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// probeProxyURL is the proxy set with -proxy; nil uses the environment
+var probeProxyURL *url.URL
+
+// parseProxyURL validates a -proxy URL
+func parseProxyURL(value string) (*url.URL, error) {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s' (use http, https, socks5 or socks5h)", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("proxy URL '%s' has no host", parsed.Redacted())
+	}
+	return parsed, nil
+}
+
+func createSSEClient(serverURL string, headers map[string]string, callTimeout, acceptTimeout time.Duration, oauth *transport.OAuthConfig, logger util.Logger) (*client.Client, error) {
+	t, err := newSSETransport(serverURL, headers, callTimeout, acceptTimeout, oauth, logger)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewClient(t), nil
+}
+
+// newSSETransport creates an SSE transport, which is not started
+func newSSETransport(serverURL string, headers map[string]string, callTimeout, acceptTimeout time.Duration, oauth *transport.OAuthConfig, logger util.Logger) (*transport.SSE, error) {
 	// Create custom HTTP client with appropriate timeout for long-running tool calls
 	// Add buffer to account for network overhead
-	httpClient := &http.Client{
-		Timeout: callTimeout + (30 * time.Second),
-	}
+	httpClient := withOAuthRetry(newProbeHTTPClient(callTimeout+(30*time.Second), acceptTimeout), oauth)
 
 	var options []transport.ClientOption
 	options = append(options, transport.WithHTTPClient(httpClient))
+	// Responses arrive over the SSE stream; allow them to take as long as the tool call timeout
+	options = append(options, transport.WithResponseTimeout(callTimeout))
 	if len(headers) > 0 {
 		options = append(options, client.WithHeaders(headers))
 	}
+	if oauth != nil {
+		options = append(options, transport.WithOAuth(*oauth))
+	}
 	if logger != nil {
 		options = append(options, transport.WithSSELogger(logger))
 	}
-	return client.NewSSEMCPClient(serverURL, options...)
+	return transport.NewSSE(serverURL, options...)
 }
 
-func createHTTPClient(serverURL string, headers map[string]string, callTimeout time.Duration, logger util.Logger) (*client.Client, error) {
+func createHTTPClient(serverURL string, headers map[string]string, callTimeout, acceptTimeout time.Duration, oauth *transport.OAuthConfig, logger util.Logger) (*client.Client, error) {
 	var options []transport.StreamableHTTPCOption
 	// Set HTTP timeout for tool call execution
-	options = append(options, transport.WithHTTPTimeout(callTimeout))
+	options = append(options, transport.WithHTTPBasicClient(withOAuthRetry(newProbeHTTPClient(callTimeout, acceptTimeout), oauth)))
 	if len(headers) > 0 {
 		options = append(options, transport.WithHTTPHeaders(headers))
 	}
+	if oauth != nil {
+		options = append(options, transport.WithHTTPOAuth(*oauth))
+	}
 	if logger != nil {
 		options = append(options, transport.WithHTTPLogger(logger))
 	}
+	if listenForNotifications {
+		options = append(options, transport.WithContinuousListening())
+	}
+	if resumeSessionID != "" {
+		// Join the existing session instead of initializing a new one
+		options = append(options, transport.WithSession(resumeSessionID))
+		httpTransport, err := transport.NewStreamableHTTP(serverURL, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the transport: %w", err)
+		}
+		httpTransport.SetProtocolVersion(requestedProtocolVersion)
+		return client.NewClient(httpTransport, client.WithSession()), nil
+	}
 	return client.NewStreamableHttpClient(serverURL, options...)
 }
 
 func createStdioClient(command, argsStr, envStr string, debug bool) (*client.Client, error) {
+	args, env := parseStdioOptions(argsStr, envStr)
+
+	// In debug mode, or to record or trace the traffic, spawn the
+	// subprocess manually and wrap its I/O streams
+	if debug || sessionCapture != nil {
+		return createStdioClientWithPipes(command, env, args, debug)
+	}
+
+	// Create stdio client using the mcp-go library
+	// The library auto-starts stdio clients, so no need to call Start() later
+	return client.NewStdioMCPClientWithOptions(command, env, args, transport.WithCommandFunc(stdioCommand))
+}
+
+// parseStdioOptions parses -args and -env into the arguments and the
+// environment variables of a stdio server
+func parseStdioOptions(argsStr, envStr string) ([]string, []string) {
 	// Parse arguments (comma-separated)
 	var args []string
 	if argsStr != "" {
@@ -499,24 +2295,29 @@ func createStdioClient(command, argsStr, envStr string, debug bool) (*client.Cli
 			}
 		}
 	}
+	return args, env
+}
 
-	// If debug mode, spawn subprocess manually and wrap I/O streams
-	if debug {
-		return createStdioClientWithDebug(command, env, args)
-	}
-
-	// Create stdio client using the mcp-go library
-	// The library auto-starts stdio clients, so no need to call Start() later
-	return client.NewStdioMCPClient(command, env, args...)
+// stdioCommand builds the command of a stdio server as the library would,
+// in a process group of its own
+func stdioCommand(ctx context.Context, command string, env []string, args []string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	isolateFromTerminalSignals(cmd)
+	return cmd, nil
 }
 
-// createStdioClientWithDebug creates a stdio client with debug logging of all JSON-RPC messages
-func createStdioClientWithDebug(command string, env []string, args []string) (*client.Client, error) {
+// createStdioClientWithPipes starts a stdio server with its streams wrapped
+// for debug output, the -record session recording and the -trace output. Debug clients are
+// started by the caller; others are started here, as the library starts its
+// stdio clients.
+func createStdioClientWithPipes(command string, env []string, args []string, debug bool) (*client.Client, error) {
 	// Create the command
 	cmd := exec.Command(command, args...)
 
 	// Set up environment
 	cmd.Env = append(os.Environ(), env...)
+	isolateFromTerminalSignals(cmd)
 
 	// Get stdin pipe (we write to it)
 	stdin, err := cmd.StdinPipe()
@@ -542,22 +2343,38 @@ func createStdioClientWithDebug(command string, env []string, args []string) (*c
 	}
 
 	// Wrap streams with logging
-	loggingStdin := newLoggingWriteCloser(stdin, "SEND")
-	loggingStdout := newLoggingReader(stdout, "RECV")
-	loggingStderr := newLoggingReadCloser(stderr, "STDERR")
+	var input io.Reader = stdout
+	var output io.WriteCloser = stdin
+	var logging io.ReadCloser = stderr
+	if debug {
+		input = newLoggingReader(stdout, "RECV")
+		output = newLoggingWriteCloser(stdin, "SEND")
+		logging = newLoggingReadCloser(stderr, "STDERR")
+	}
+	if sessionCapture != nil {
+		input, output = sessionCapture.stdioStreams(input, output)
+	}
 
 	// Create transport using NewIO with wrapped streams
-	stdioTransport := transport.NewIO(loggingStdout, loggingStdin, loggingStderr)
+	stdioTransport := transport.NewIO(input, output, logging)
+	if !debug {
+		if err := stdioTransport.Start(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to start stdio transport: %w", err)
+		}
+	}
 
 	// Create client with the transport
 	return client.NewClient(stdioTransport), nil
 }
 
-func performInitialization(ctx context.Context, mcpClient *client.Client, verbose bool) error {
-	// Create initialization request
-	initRequest := mcp.InitializeRequest{
+// newInitializeRequest builds the initialization request sent by the probe.
+// It asks for the newest protocol version unless -protocol-version is given;
+// servers answer with the version they support, and checks for newer
+// features are skipped for older ones.
+func newInitializeRequest() mcp.InitializeRequest {
+	return mcp.InitializeRequest{
 		Params: mcp.InitializeParams{
-			ProtocolVersion: "2024-11-05",
+			ProtocolVersion: requestedProtocolVersion,
 			Capabilities: mcp.ClientCapabilities{
 				Roots: &struct {
 					ListChanged bool `json:"listChanged,omitempty"`
@@ -572,6 +2389,11 @@ func performInitialization(ctx context.Context, mcpClient *client.Client, verbos
 			},
 		},
 	}
+}
+
+func performInitialization(ctx context.Context, mcpClient *client.Client, verbose bool) error {
+	// Create initialization request
+	initRequest := newInitializeRequest()
 
 	if verbose {
 		fmt.Printf("Sending initialization request with protocol version: %s\n", initRequest.Params.ProtocolVersion)
@@ -579,10 +2401,21 @@ func performInitialization(ctx context.Context, mcpClient *client.Client, verbos
 	}
 
 	// Send initialization request
+	initStart := time.Now()
 	initResult, err := mcpClient.Initialize(ctx, initRequest)
+	report.addTiming("initialize", time.Since(initStart), err)
 	if err != nil {
 		return fmt.Errorf("initialization failed: %w", err)
 	}
+	report.setInitResult(initResult)
+	checkNegotiatedVersion(initRequest.Params.ProtocolVersion, initResult.ProtocolVersion)
+	emitEvent(eventInit, map[string]any{
+		"serverInfo":      initResult.ServerInfo,
+		"protocolVersion": initResult.ProtocolVersion,
+		"requested":       initRequest.Params.ProtocolVersion,
+		"capabilities":    initResult.Capabilities,
+		"durationMs":      durationMillis(time.Since(initStart)),
+	})
 
 	if verbose {
 		fmt.Printf("Server info: %s v%s\n", initResult.ServerInfo.Name, initResult.ServerInfo.Version)
@@ -598,6 +2431,9 @@ func printServerCapabilities(caps mcp.ServerCapabilities) {
 	if caps.Logging != nil {
 		fmt.Printf("  - Logging: supported\n")
 	}
+	if caps.Completions != nil {
+		fmt.Printf("  - Completions: supported\n")
+	}
 	if caps.Prompts != nil {
 		fmt.Printf("  - Prompts: supported (list_changed: %t)\n", caps.Prompts.ListChanged)
 	}
@@ -613,7 +2449,7 @@ func printServerCapabilities(caps mcp.ServerCapabilities) {
 	}
 }
 
-func testServerCapabilities(ctx context.Context, mcpClient *client.Client, verbose bool) error {
+func testServerCapabilities(ctx context.Context, mcpClient *client.Client, logLevel mcp.LoggingLevel, verbose bool) error {
 
 	// Get server capabilities
 	serverCaps := mcpClient.GetServerCapabilities()
@@ -622,7 +2458,7 @@ func testServerCapabilities(ctx context.Context, mcpClient *client.Client, verbo
 	fmt.Println("\n--- Tools Capability ---")
 	if serverCaps.Tools != nil {
 		if err := testTools(ctx, mcpClient, verbose); err != nil {
-			fmt.Printf("Warning: Tools test failed: %v\n", err)
+			fmt.Printf("Warning: %s\n", report.addFinding(checkIDToolsList, "", "Tools test failed: %v", err))
 		}
 	} else {
 
@@ -633,7 +2469,7 @@ func testServerCapabilities(ctx context.Context, mcpClient *client.Client, verbo
 	if serverCaps.Resources != nil {
 		fmt.Println("--- Testing Resources Capability ---")
 		if err := testResources(ctx, mcpClient, verbose); err != nil {
-			fmt.Printf("Warning: Resources test failed: %v\n", err)
+			fmt.Printf("Warning: %s\n", report.addFinding(checkIDResourcesList, "", "Resources test failed: %v", err))
 		}
 	} else {
 		fmt.Println("--- Resources Capability ---")
@@ -644,16 +2480,82 @@ func testServerCapabilities(ctx context.Context, mcpClient *client.Client, verbo
 	if serverCaps.Prompts != nil {
 		fmt.Println("--- Testing Prompts Capability ---")
 		if err := testPrompts(ctx, mcpClient, verbose); err != nil {
-			fmt.Printf("Warning: Prompts test failed: %v\n", err)
+			fmt.Printf("Warning: %s\n", report.addFinding(checkIDPromptsList, "", "Prompts test failed: %v", err))
 		}
 	} else {
 		fmt.Println("\n--- Prompts Capability ---")
 		fmt.Println("Prompts capability not supported by server")
 	}
 
+	// Test Logging capability
+	if serverCaps.Logging != nil {
+		fmt.Println("\n--- Testing Logging Capability ---")
+		if err := testLogging(ctx, mcpClient, logLevel); err != nil {
+			// Levels the server rejected are recorded as findings
+			fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "Logging test failed: %v", err))
+		}
+	} else {
+		fmt.Println("\n--- Logging Capability ---")
+		fmt.Println("Logging capability not supported by server")
+	}
+
+	printPartialCapabilities()
 	return nil
 }
 
+// relistDelay is how long to wait before listing tools again when a server
+// advertises the tools capability but initially returns no tools
+const relistDelay = 2 * time.Second
+
+// listTools lists the server's tools and records the result in the run report.
+// Some servers populate their tool registry asynchronously after startup, so if
+// the tools capability is advertised but no tools are returned, the listing is
+// repeated once after relistDelay and a late-appearing tool surface is reported.
+func listTools(ctx context.Context, mcpClient *client.Client) (*mcp.ListToolsResult, error) {
+	toolsResult, err := listToolsOnce(ctx, mcpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(toolsResult.Tools) == 0 && mcpClient.GetServerCapabilities().Tools != nil {
+		fmt.Printf("Server advertises tools but listed none; listing again in %s...\n", relistDelay)
+		select {
+		case <-time.After(relistDelay):
+		case <-ctx.Done():
+			return toolsResult, nil
+		}
+
+		retryResult, err := listToolsOnce(ctx, mcpClient)
+		if err != nil {
+			return nil, err
+		}
+		if len(retryResult.Tools) > 0 {
+			fmt.Printf("Tools appeared late: %d tools listed on the second attempt (consider -settle-delay)\n", len(retryResult.Tools))
+			report.setToolsAppearedLate()
+		} else {
+			fmt.Println("Still no tools listed after waiting")
+		}
+		toolsResult = retryResult
+	}
+
+	return toolsResult, nil
+}
+
+// listToolsOnce performs a single tools/list and records it in the run report
+func listToolsOnce(ctx context.Context, mcpClient *client.Client) (*mcp.ListToolsResult, error) {
+	listStart := time.Now()
+	toolsResult, titles, err := listAllTools(ctx, mcpClient)
+	report.addTiming("tools/list", time.Since(listStart), err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	report.setTools(toolsResult.Tools)
+	report.setToolTitles(titles)
+	cacheTools(toolsResult.Tools)
+	emitListEvent(eventListTools, toolNames(toolsResult.Tools), time.Since(listStart))
+	return toolsResult, nil
+}
+
 func formatToolInputSchema(schema mcp.ToolInputSchema, indent string) string {
 	var result strings.Builder
 
@@ -738,31 +2640,51 @@ func formatToolAnnotations(annotations mcp.ToolAnnotation) string {
 func testTools(ctx context.Context, mcpClient *client.Client, verbose bool) error {
 	fmt.Println("Requesting list of available tools...")
 
-	toolsRequest := mcp.ListToolsRequest{}
-	toolsResult, err := mcpClient.ListTools(ctx, toolsRequest)
+	toolsResult, err := listTools(ctx, mcpClient)
+	recordEndpoint("Tools", mcp.MethodToolsList, err)
 	if err != nil {
-		return fmt.Errorf("failed to list tools: %w", err)
+		return err
 	}
 
 	fmt.Printf("Found %d tools:\n\n", len(toolsResult.Tools))
 
-	for i, tool := range toolsResult.Tools {
+	printName := func(i int, tool mcp.Tool) {
 		annotationsStr := formatToolAnnotations(tool.Annotations)
 		if annotationsStr != "" {
-			fmt.Printf("  %02d: %s %s\n", i+1, tool.Name, annotationsStr)
+			fmt.Printf("  %02d: %s %s\n", i+1, toolLabel(tool), annotationsStr)
 		} else {
-			fmt.Printf("  %02d: %s\n", i+1, tool.Name)
+			fmt.Printf("  %02d: %s\n", i+1, toolLabel(tool))
 		}
+	}
+	printTool := func(i int, tool mcp.Tool) {
+		printName(i, tool)
 		if verbose {
 			if tool.Description != "" {
 				fmt.Printf("     Description: %s\n", tool.Description)
 			}
+			if hints := formatToolHints(tool.Annotations); hints != "" {
+				fmt.Printf("     Annotations: %s\n", hints)
+			}
 			fmt.Println("     Input Schema:")
 			schemaOutput := formatToolInputSchema(tool.InputSchema, "       ")
 			fmt.Print(schemaOutput)
+			fmt.Printf("     Schema Hash: %s\n", schemaHashLabel(tool))
 			fmt.Println()
 		}
 	}
+	if groupToolListings && len(toolsResult.Tools) > 0 {
+		brief := printName
+		if !verbose {
+			brief = nil
+		}
+		if printGroupedTools(toolsResult.Tools, printTool, brief) {
+			fmt.Println("(use -expand-groups <group,...> or -expand-groups all for descriptions and schemas)")
+		}
+	} else {
+		for i, tool := range toolsResult.Tools {
+			printTool(i, tool)
+		}
+	}
 
 	if len(toolsResult.Tools) == 0 {
 		fmt.Println("  (No tools available)")
@@ -775,11 +2697,15 @@ func testTools(ctx context.Context, mcpClient *client.Client, verbose bool) erro
 func testResources(ctx context.Context, mcpClient *client.Client, verbose bool) error {
 	fmt.Println("Requesting list of available resources...")
 
-	resourcesRequest := mcp.ListResourcesRequest{}
-	resourcesResult, err := mcpClient.ListResources(ctx, resourcesRequest)
+	listStart := time.Now()
+	resourcesResult, err := listAllResources(ctx, mcpClient)
+	report.addTiming("resources/list", time.Since(listStart), err)
+	recordEndpoint("Resources", mcp.MethodResourcesList, err)
 	if err != nil {
 		return fmt.Errorf("failed to list resources: %w", err)
 	}
+	report.setResources(resourcesResult.Resources)
+	emitListEvent(eventListResources, resourceURIs(resourcesResult.Resources), time.Since(listStart))
 
 	fmt.Printf("Found %d resources:\n\n", len(resourcesResult.Resources))
 
@@ -804,30 +2730,21 @@ func testResources(ctx context.Context, mcpClient *client.Client, verbose bool)
 
 	// Also test resource templates if available
 	fmt.Println("Requesting list of available resource templates...")
-	templatesRequest := mcp.ListResourceTemplatesRequest{}
-	templatesResult, err := mcpClient.ListResourceTemplates(ctx, templatesRequest)
+	listStart = time.Now()
+	templatesResult, err := listAllResourceTemplates(ctx, mcpClient)
+	report.addTiming("resources/templates/list", time.Since(listStart), err)
+	recordEndpoint("Resources", mcp.MethodResourcesTemplatesList, err)
 	if err != nil {
-		fmt.Printf("Warning: Failed to list resource templates: %v\n", err)
+		fmt.Printf("Warning: %s\n", report.addFinding(checkIDTemplatesList, "", "Failed to list resource templates: %v", err))
 		return nil
 	}
+	report.setResourceTemplates(templatesResult.ResourceTemplates)
+	emitListEvent(eventListTemplates, resourceTemplateStrings(templatesResult.ResourceTemplates), time.Since(listStart))
 
 	fmt.Printf("Found %d resource templates:\n\n", len(templatesResult.ResourceTemplates))
 
 	for i, template := range templatesResult.ResourceTemplates {
-		// Access the underlying template pattern using the template's MarshalJSON method
-		var templateStr string
-		if template.URITemplate != nil {
-			// Use the template's MarshalJSON method
-			jsonBytes, err := template.URITemplate.MarshalJSON()
-			if err == nil {
-				// Remove quotes from the JSON string
-				templateStr = strings.Trim(string(jsonBytes), "\"")
-			} else {
-				templateStr = fmt.Sprintf("(Error marshaling template: %v)", err)
-			}
-		} else {
-			templateStr = "(empty template)"
-		}
+		templateStr := resourceTemplateString(template)
 
 		fmt.Printf("  %02d: %s\n", i+1, templateStr)
 		if verbose {
@@ -854,11 +2771,15 @@ func testResources(ctx context.Context, mcpClient *client.Client, verbose bool)
 func testPrompts(ctx context.Context, mcpClient *client.Client, verbose bool) error {
 	fmt.Println("Requesting list of available prompts...")
 
-	promptsRequest := mcp.ListPromptsRequest{}
-	promptsResult, err := mcpClient.ListPrompts(ctx, promptsRequest)
+	listStart := time.Now()
+	promptsResult, err := listAllPrompts(ctx, mcpClient)
+	report.addTiming("prompts/list", time.Since(listStart), err)
+	recordEndpoint("Prompts", mcp.MethodPromptsList, err)
 	if err != nil {
 		return fmt.Errorf("failed to list prompts: %w", err)
 	}
+	report.setPrompts(promptsResult.Prompts)
+	emitListEvent(eventListPrompts, promptNames(promptsResult.Prompts), time.Since(listStart))
 
 	fmt.Printf("Found %d prompts:\n\n", len(promptsResult.Prompts))
 
@@ -922,16 +2843,112 @@ func callSpecificTool(ctx context.Context, mcpClient *client.Client, toolName st
 	}
 
 	// Call the tool
-	fmt.Printf("Calling tool '%s'...\n", toolName)
-	result, err := mcpClient.CallTool(ctx, request)
+	start := time.Now()
+	fmt.Printf("Calling tool '%s' at %s...\n", toolName, consoleClock(start))
+	result, err := callToolRecorded(ctx, mcpClient, request)
 	if err != nil {
-		return fmt.Errorf("failed to call tool: %w", err)
+		return fmt.Errorf("failed to call tool after %s: %w", humanDuration(time.Since(start)), err)
 	}
+	fmt.Printf("Answered after %s\n", humanDuration(time.Since(start)))
 
-	// Format and display the result
+	// Format and display the result, then check it against the output schema
 	formatToolResult(result, verbose)
+	saveToolContent(toolName, result)
 
-	return nil
+	return checkToolResult(toolName, result)
+}
+
+// callToolResultOnly calls a tool and writes nothing but its result to resultOut.
+// Text content items are concatenated; with -output json the full result is written as JSON.
+func callToolResultOnly(ctx context.Context, mcpClient *client.Client, toolName string, paramsJSON string) (*mcp.CallToolResult, error) {
+	params, err := parseToolParameters(paramsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      toolName,
+			Arguments: params,
+		},
+	}
+
+	result, err := callToolRecorded(ctx, mcpClient, request)
+	if err != nil {
+		return nil, err
+	}
+	saveToolContent(toolName, result)
+
+	if outputFormat == outputJSON {
+		jsonBytes, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode result: %w", err)
+		}
+		_, _ = fmt.Fprintln(resultOut, string(jsonBytes))
+		return result, nil
+	}
+
+	var texts []string
+	for _, content := range result.Content {
+		if c, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, c.Text)
+		}
+	}
+	if len(texts) > 0 {
+		text := strings.Join(texts, "\n")
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		_, _ = fmt.Fprint(resultOut, text)
+	}
+
+	return result, nil
+}
+
+// callToolRecorded calls a tool and records the call and its timing in the run report
+func callToolRecorded(ctx context.Context, mcpClient *client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	emitEvent(eventToolCallStart, map[string]any{
+		"tool":      request.Params.Name,
+		"arguments": request.Params.Arguments,
+	})
+
+	samplingBefore, samplingWaitedBefore := samplingStubUsage()
+	start := time.Now()
+	result, err := callToolInterruptible(ctx, mcpClient, request)
+	duration := time.Since(start)
+	samplingAfter, samplingWaitedAfter := samplingStubUsage()
+	samplingRequests, samplingWaited := samplingAfter-samplingBefore, samplingWaitedAfter-samplingWaitedBefore
+	printSamplingLatency(request.Params.Name, samplingRequests, samplingWaited, duration)
+
+	event := map[string]any{
+		"tool":       request.Params.Name,
+		"durationMs": durationMillis(duration),
+		"error":      errorField(err),
+	}
+	if result != nil {
+		event["isError"] = result.IsError
+		event["result"] = result
+	}
+	if samplingRequests > 0 {
+		event["samplingRequests"] = samplingRequests
+		event["samplingMs"] = durationMillis(samplingWaited)
+	}
+	emitEvent(eventToolCallResult, event)
+
+	record := toolCallRecord{
+		Tool:      request.Params.Name,
+		Arguments: request.GetArguments(),
+		Result:    result,
+		StartedAt: timestamp(start),
+		Duration:  duration,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	report.addToolCall(record)
+	report.addTiming("tools/call "+request.Params.Name, duration, err)
+
+	return result, err
 }
 
 // parseToolParameters parses JSON parameters for tool calls
@@ -947,6 +2964,30 @@ func parseToolParameters(paramsJSON string) (map[string]interface{}, error) {
 	return params, nil
 }
 
+// injectStdinParam reads all of r and sets it as the named string parameter
+// in the JSON parameters, returning the updated JSON
+func injectStdinParam(paramsJSON string, name string, r io.Reader) (string, error) {
+	params, err := parseToolParameters(paramsJSON)
+	if err != nil {
+		return "", err
+	}
+	if _, exists := params[name]; exists {
+		return "", fmt.Errorf("parameter '%s' is set in both -params and -stdin-param", name)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	params[name] = string(data)
+
+	jsonBytes, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode parameters: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
 // displayToolRequest displays the tool request in verbose mode
 func displayToolRequest(toolName string, params map[string]interface{}, verbose bool) {
 	if !verbose {
@@ -970,6 +3011,17 @@ func displayToolRequest(toolName string, params map[string]interface{}, verbose
 func formatToolResult(result *mcp.CallToolResult, verbose bool) {
 	fmt.Println("\n=== Tool Call Result ===")
 
+	// In JSON output mode, show the complete result as returned by the server
+	if outputFormat == outputJSON {
+		jsonBytes, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Printf("Failed to encode result: %v\n", err)
+			return
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+
 	if result.IsError {
 		fmt.Printf("Tool call failed:\n")
 	} else {
@@ -1005,12 +3057,12 @@ func formatToolResult(result *mcp.CallToolResult, verbose bool) {
 		}
 	}
 
-	// Note: StructuredContent field doesn't exist in the current mcp-go version
-	// This functionality may be added in future versions
+	printStructuredContent(result)
 }
 
 // handleToolCallError handles errors from tool calls with user-friendly messages
 func handleToolCallError(err error, toolName string) {
+	report.addError("Failed to call tool '%s': %v", toolName, err)
 	fmt.Printf("Failed to call tool '%s':\n", toolName)
 
 	// Categorize error types
@@ -1026,6 +3078,9 @@ func handleToolCallError(err error, toolName string) {
 	case strings.Contains(errStr, "parameter"):
 		fmt.Printf("   Parameter error: %v\n", err)
 		fmt.Printf("   Check parameter format and required fields.\n")
+	case strings.Contains(errStr, "awaiting response headers"), strings.Contains(errStr, "TLS handshake timeout"),
+		strings.Contains(errStr, "dial tcp") && strings.Contains(errStr, "i/o timeout"):
+		fmt.Printf("   Server did not accept the request in time. Check the server is alive or increase -accept-timeout.\n")
 	case strings.Contains(errStr, "timeout"):
 		fmt.Printf("   Request timed out. Try increasing the timeout with -timeout flag.\n")
 	case strings.Contains(errStr, "Invalid session ID"):
@@ -1048,17 +3103,23 @@ func listToolsOnly(ctx context.Context, mcpClient *client.Client, verbose bool)
 
 	fmt.Println("Requesting list of available tools...")
 
-	toolsRequest := mcp.ListToolsRequest{}
-	toolsResult, err := mcpClient.ListTools(ctx, toolsRequest)
+	toolsResult, err := listTools(ctx, mcpClient)
 	if err != nil {
-		return fmt.Errorf("failed to list tools: %w", err)
+		return err
 	}
 
 	fmt.Printf("\nFound %d tools:\n\n", len(toolsResult.Tools))
 
-	for i, tool := range toolsResult.Tools {
+	printName := func(i int, tool mcp.Tool) {
+		fmt.Printf("%02d: %s", i+1, toolLabel(tool))
+		if annotationsStr := formatToolAnnotations(tool.Annotations); annotationsStr != "" {
+			fmt.Printf(" %s", annotationsStr)
+		}
+		fmt.Println()
+	}
+	printTool := func(i int, tool mcp.Tool) {
 		annotationsStr := formatToolAnnotations(tool.Annotations)
-		fmt.Printf("%02d: %s", i+1, tool.Name)
+		fmt.Printf("%02d: %s", i+1, toolLabel(tool))
 		if annotationsStr != "" {
 			fmt.Printf(" %s", annotationsStr)
 		}
@@ -1068,6 +3129,9 @@ func listToolsOnly(ctx context.Context, mcpClient *client.Client, verbose bool)
 		fmt.Println()
 
 		if verbose {
+			if hints := formatToolHints(tool.Annotations); hints != "" {
+				fmt.Printf("   Annotations: %s\n", hints)
+			}
 			// Pretty print the input schema
 			schemaJSON, err := json.MarshalIndent(tool.InputSchema, "   ", "  ")
 			if err == nil && string(schemaJSON) != "{}" && string(schemaJSON) != "null" {
@@ -1076,11 +3140,25 @@ func listToolsOnly(ctx context.Context, mcpClient *client.Client, verbose bool)
 				for _, line := range lines {
 					fmt.Printf("   %s\n", line)
 				}
+				fmt.Printf("   Schema Hash: %s\n", schemaHashLabel(tool))
 
 				fmt.Println()
 			}
 		}
 	}
+	if groupToolListings && len(toolsResult.Tools) > 0 {
+		brief := printName
+		if !verbose {
+			brief = nil
+		}
+		if printGroupedTools(toolsResult.Tools, printTool, brief) {
+			fmt.Println("(use -expand-groups <group,...> or -expand-groups all for descriptions and schemas)")
+		}
+	} else {
+		for i, tool := range toolsResult.Tools {
+			printTool(i, tool)
+		}
+	}
 
 	if len(toolsResult.Tools) == 0 {
 		fmt.Println("  (No tools available)")
@@ -1098,26 +3176,32 @@ func listToolsMinimal(ctx context.Context, mcpClient *client.Client) error {
 		return nil
 	}
 
-	toolsRequest := mcp.ListToolsRequest{}
-	toolsResult, err := mcpClient.ListTools(ctx, toolsRequest)
+	toolsResult, err := listTools(ctx, mcpClient)
 	if err != nil {
-		return fmt.Errorf("failed to list tools: %w", err)
+		return err
 	}
 
-	for i, tool := range toolsResult.Tools {
+	printName := func(i int, tool mcp.Tool) {
 		annotationsStr := formatToolAnnotations(tool.Annotations)
 		if annotationsStr != "" {
-			fmt.Printf("%02d: %s %s\n", i+1, tool.Name, annotationsStr)
+			fmt.Printf("%02d: %s %s\n", i+1, toolLabel(tool), annotationsStr)
 		} else {
-			fmt.Printf("%02d: %s\n", i+1, tool.Name)
+			fmt.Printf("%02d: %s\n", i+1, toolLabel(tool))
 		}
 	}
+	if groupToolListings && len(toolsResult.Tools) > 0 {
+		printGroupedTools(toolsResult.Tools, printName, nil)
+		return nil
+	}
+	for i, tool := range toolsResult.Tools {
+		printName(i, tool)
+	}
 
 	return nil
 }
 
 // interactiveModeWithTimeout provides an interactive interface for tool calling with timeout management
-func interactiveModeWithTimeout(mcpClient *client.Client, timeout time.Duration, verbose bool) error {
+func interactiveModeWithTimeout(mcpClient *client.Client, aliases *aliasStore, roots *rootsProvider, timeout time.Duration, verbose bool) error {
 	fmt.Println("\n=== Interactive Tool Calling Mode ===")
 	fmt.Println("Type 'help' for commands, 'exit' to quit")
 
@@ -1131,10 +3215,9 @@ func interactiveModeWithTimeout(mcpClient *client.Client, timeout time.Duration,
 	// Get list of available tools with fresh context
 	listCtx, listCancel := context.WithTimeout(context.Background(), timeout)
 	defer listCancel()
-	toolsRequest := mcp.ListToolsRequest{}
-	toolsResult, err := mcpClient.ListTools(listCtx, toolsRequest)
+	toolsResult, err := listTools(listCtx, mcpClient)
 	if err != nil {
-		return fmt.Errorf("failed to list tools: %w", err)
+		return err
 	}
 
 	if len(toolsResult.Tools) == 0 {
@@ -1142,7 +3225,7 @@ func interactiveModeWithTimeout(mcpClient *client.Client, timeout time.Duration,
 		return nil
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := stdinScanner()
 
 	for {
 		fmt.Print("\n> ")
@@ -1155,14 +3238,37 @@ func interactiveModeWithTimeout(mcpClient *client.Client, timeout time.Duration,
 			continue
 		}
 
-		// Split command and arguments
-		parts := strings.Fields(input)
+		// Split command and arguments, honoring quotes
+		parts, err := splitCommandLine(input)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		if len(parts) == 0 {
+			continue
+		}
 		command := parts[0]
 		var args []string
 		if len(parts) > 1 {
 			args = parts[1:]
 		}
 
+		// Expand an alias into the command it stands for
+		if expanded, ok, err := aliases.expand(command, args); ok {
+			if err == nil {
+				parts, err = splitCommandLine(expanded)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			fmt.Printf("(%s)\n", expanded)
+			input, command, args = expanded, parts[0], parts[1:]
+		}
+
 		switch command {
 		case "exit", "quit", "q":
 			fmt.Println("Exiting interactive mode...")
@@ -1170,17 +3276,38 @@ func interactiveModeWithTimeout(mcpClient *client.Client, timeout time.Duration,
 		case "help", "h", "?":
 			printInteractiveHelp()
 		case "list", "ls", "l":
-			listToolsInteractive(toolsResult.Tools)
+			listToolsInteractive(toolsResult.Tools, strings.Join(args, " "))
+		case "groups":
+			listToolGroupsInteractive(toolsResult.Tools)
+		case "alias":
+			if err := aliases.command(input); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		case "unalias":
+			if len(args) != 1 {
+				fmt.Println("Usage: unalias <name>")
+			} else if err := aliases.unalias(args[0]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		case "roots":
+			if err := roots.command(mcpClient, args); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
 		case "call", "c":
-			// Handle "call 3" or "c 3" syntax
+			// Handle "call 3", "call search" and "call search query=mcp limit=5"
 			if len(args) > 0 {
-				if num, err := strconv.Atoi(args[0]); err == nil && num > 0 && num <= len(toolsResult.Tools) {
-					tool := toolsResult.Tools[num-1]
-					if err := callToolDirectlyWithTimeout(mcpClient, &tool, scanner, timeout, verbose); err != nil {
+				tool := findInteractiveTool(toolsResult.Tools, args[0])
+				switch {
+				case tool == nil:
+					fmt.Printf("Invalid tool number or name: %s\n", args[0])
+				case len(args) > 1:
+					if err := callToolQuick(mcpClient, tool, args[1:], scanner, timeout, verbose); err != nil {
+						fmt.Printf("Error: %v\n", err)
+					}
+				default:
+					if err := callToolDirectlyWithTimeout(mcpClient, tool, scanner, timeout, verbose); err != nil {
 						fmt.Printf("Error: %v\n", err)
 					}
-				} else {
-					fmt.Printf("Invalid tool number: %s\n", args[0])
 				}
 			} else {
 				// No arguments, show guided selection
@@ -1212,19 +3339,41 @@ func interactiveModeWithTimeout(mcpClient *client.Client, timeout time.Duration,
 func printInteractiveHelp() {
 	fmt.Println("\nAvailable commands:")
 	fmt.Println("  list, ls, l     - List available tools")
+	fmt.Println("  list fs         - List the tools of group 'fs' with descriptions")
+	fmt.Println("  groups          - List the tool groups with their counts")
 	fmt.Println("  call, c         - Call a tool (guided selection)")
 	fmt.Println("  call 3, c 3     - Call tool number 3 directly")
+	fmt.Println("  call search     - Call a tool by name")
+	fmt.Println("  call search query=\"golang mcp\" limit=5")
+	fmt.Println("                  - Call a tool with name=value arguments, without prompting")
 	fmt.Println("  3               - Call tool number 3 directly")
+	fmt.Println("  alias           - List aliases")
+	fmt.Println("  alias s = call search query=$1")
+	fmt.Println("                  - Define an alias ($1, $2... and $@ are its arguments), saved to the profile")
+	fmt.Println("  unalias s       - Remove an alias")
+	fmt.Println("  roots           - List the roots offered to the server")
+	fmt.Println("  roots add file:///path[,name], roots remove <uri>")
+	fmt.Println("                  - Change the roots and send notifications/roots/list_changed")
+	fmt.Println("  roots notify    - Send notifications/roots/list_changed and wait for roots/list")
+	fmt.Println("  roots probe file:///path[,name]")
+	fmt.Println("                  - Add a root, notify, and report how the server's roots/list and listings react")
 	fmt.Println("  help, h, ?      - Show this help")
 	fmt.Println("  exit, quit, q   - Exit interactive mode")
 }
 
-// listToolsInteractive lists tools in interactive mode
-func listToolsInteractive(tools []mcp.Tool) {
-	fmt.Printf("\nAvailable tools (%d):\n", len(tools))
-	for i, tool := range tools {
+// listToolsInteractive lists tools in interactive mode, grouped with -group.
+// With a group name, only that group's tools are listed.
+func listToolsInteractive(tools []mcp.Tool, group string) {
+	printName := func(i int, tool mcp.Tool) {
+		fmt.Printf("  %02d: %s", i+1, toolLabel(tool))
+		if annotationsStr := formatToolAnnotations(tool.Annotations); annotationsStr != "" {
+			fmt.Printf(" %s", annotationsStr)
+		}
+		fmt.Println()
+	}
+	printTool := func(i int, tool mcp.Tool) {
 		annotationsStr := formatToolAnnotations(tool.Annotations)
-		fmt.Printf("  %02d: %s", i+1, tool.Name)
+		fmt.Printf("  %02d: %s", i+1, toolLabel(tool))
 		if annotationsStr != "" {
 			fmt.Printf(" %s", annotationsStr)
 		}
@@ -1233,12 +3382,46 @@ func listToolsInteractive(tools []mcp.Tool) {
 		}
 		fmt.Println()
 	}
+
+	if group != "" {
+		groups := groupTools(tools)
+		selected := findToolGroup(groups, group)
+		if selected == nil {
+			fmt.Printf("No tool group named '%s' (use 'groups' to list them)\n", group)
+			return
+		}
+		fmt.Printf("\n[%s] (%d tool%s)\n", selected.Name, len(selected.Indexes), pluralS(len(selected.Indexes)))
+		for _, i := range selected.Indexes {
+			printTool(i, tools[i])
+		}
+		return
+	}
+
+	fmt.Printf("\nAvailable tools (%d):\n", len(tools))
+	if groupToolListings && len(tools) > 0 {
+		if printGroupedTools(tools, printTool, printName) {
+			fmt.Println("(use 'list <group>' for descriptions)")
+		}
+		return
+	}
+	for i, tool := range tools {
+		printTool(i, tool)
+	}
+}
+
+// listToolGroupsInteractive lists the tool groups with their counts
+func listToolGroupsInteractive(tools []mcp.Tool) {
+	groups := groupTools(tools)
+	fmt.Printf("\nTool groups (%d):\n", len(groups))
+	for _, group := range groups {
+		fmt.Printf("  %-20s %d tool%s\n", group.Name, len(group.Indexes), pluralS(len(group.Indexes)))
+	}
 }
 
 // callToolInteractiveWithTimeout calls a tool in interactive mode with guided selection and timeout management
 func callToolInteractiveWithTimeout(mcpClient *client.Client, tools []mcp.Tool, scanner *bufio.Scanner, timeout time.Duration, verbose bool) error {
 	// List tools
-	listToolsInteractive(tools)
+	listToolsInteractive(tools, "")
 
 	// Select tool
 	fmt.Print("\nEnter tool number (or 'cancel'): ")
@@ -1271,10 +3454,13 @@ func callToolDirectlyWithTimeout(mcpClient *client.Client, tool *mcp.Tool, scann
 
 // callToolDirectly calls a specific tool with parameter collection
 func callToolDirectly(ctx context.Context, mcpClient *client.Client, tool *mcp.Tool, scanner *bufio.Scanner, verbose bool) error {
-	fmt.Printf("\nCalling tool: %s\n", tool.Name)
+	fmt.Printf("\nCalling tool: %s\n", toolLabel(*tool))
 	if tool.Description != "" {
 		fmt.Printf("Description: %s\n", tool.Description)
 	}
+	if !confirmDestructiveCall(tool, scanner) {
+		return nil
+	}
 
 	// Collect parameters
 	params, err := collectToolParameters(tool, scanner)
@@ -1293,16 +3479,19 @@ func callToolDirectly(ctx context.Context, mcpClient *client.Client, tool *mcp.T
 		},
 	}
 
-	fmt.Printf("\nCalling tool '%s'...\n", tool.Name)
-	result, err := mcpClient.CallTool(ctx, request)
+	start := time.Now()
+	fmt.Printf("\nCalling tool '%s' at %s...\n", tool.Name, consoleClock(start))
+	result, err := callToolRecorded(ctx, mcpClient, request)
 	if err != nil {
-		return fmt.Errorf("failed to call tool: %w", err)
+		return fmt.Errorf("failed to call tool after %s: %w", humanDuration(time.Since(start)), err)
 	}
+	fmt.Printf("Answered after %s\n", humanDuration(time.Since(start)))
 
 	// Display result
 	formatToolResult(result, verbose)
+	saveToolContent(tool.Name, result)
 
-	return nil
+	return checkToolResult(tool.Name, result)
 }
 
 // collectToolParameters collects parameters for a tool call interactively
@@ -0,0 +1,81 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptLimited(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodPost, `{"jsonrpc":"2.0","id":0,"method":"initialize"}`, true},
+		{http.MethodPost, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, true},
+		{http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"ping"}`, true},
+		{http.MethodPost, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, true},
+		{http.MethodPost, `{"jsonrpc":"2.0","id":3,"method":"resources/templates/list"}`, true},
+		{http.MethodPost, `{"jsonrpc":"2.0","id":4,"method":"tools/call"}`, false},
+		{http.MethodPost, `{"jsonrpc":"2.0","id":5,"method":"resources/read"}`, false},
+		{http.MethodPost, `[{"jsonrpc":"2.0","id":6,"method":"ping"}]`, false},
+		{http.MethodDelete, "", false},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, "http://127.0.0.1/mcp", bytes.NewReader([]byte(tt.body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := acceptLimited(req); got != tt.want {
+			t.Errorf("%s %s: acceptLimited = %v, want %v", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptTimeoutSilentServer(t *testing.T) {
+	// The server accepts connections and reads the requests, but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+			go func() { _, _ = io.Copy(io.Discard, conn) }()
+		}
+	}()
+
+	mcpClient, err := createHTTPClient("http://"+listener.Addr().String()+"/mcp", nil, time.Minute, 200*time.Millisecond, nil, quietLogger{})
+	if err != nil {
+		t.Fatalf("createHTTPClient: %v", err)
+	}
+	t.Cleanup(func() { _ = mcpClient.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := mcpClient.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	start := time.Now()
+	_, err = mcpClient.Initialize(ctx, newInitializeRequest())
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Fatalf("Initialize: err = %v, want the accept timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Initialize failed after %s, want about the 200ms accept timeout", elapsed)
+	}
+}
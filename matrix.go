@@ -0,0 +1,375 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Identification of capability matrix documents, which 'aggregate' uses to
+// pick them out of a directory of other files
+const (
+	matrixFormat        = "mcpprobe-capability-matrix"
+	matrixFormatVersion = 1
+)
+
+// capabilityMatrix is a compact summary of a probe run for aggregation
+// across many servers: what the server is, what it offers and how it did
+type capabilityMatrix struct {
+	Format          string            `json:"format"`
+	FormatVersion   int               `json:"formatVersion"`
+	Probe           string            `json:"probe"`
+	GeneratedAt     timestamp         `json:"generatedAt"`
+	Target          string            `json:"target"`
+	Transport       string            `json:"transport"`
+	Server          string            `json:"server,omitempty"`
+	ServerVersion   string            `json:"serverVersion,omitempty"`
+	ProtocolVersion string            `json:"protocolVersion,omitempty"`
+	Capabilities    []string          `json:"capabilities"`
+	Counts          matrixCounts      `json:"counts"`
+	Checks          map[string]string `json:"checks,omitempty"`
+	Findings        map[string]int    `json:"findings,omitempty"`
+	Errors          int               `json:"errors"`
+	Warnings        int               `json:"warnings"`
+	SurfaceHash     string            `json:"surfaceHash,omitempty"`
+	ToolHashes      map[string]string `json:"toolHashes,omitempty"`
+}
+
+// matrixCounts is how many of each item the server listed
+type matrixCounts struct {
+	Tools             int `json:"tools"`
+	Resources         int `json:"resources"`
+	ResourceTemplates int `json:"resourceTemplates"`
+	Prompts           int `json:"prompts"`
+}
+
+// advertisedCapabilities names the capabilities and sub-capabilities the
+// server advertised, such as "resources" and "resources.subscribe"
+func advertisedCapabilities(caps mcp.ServerCapabilities) []string {
+	names := []string{}
+	if caps.Tools != nil {
+		names = append(names, "tools")
+		if caps.Tools.ListChanged {
+			names = append(names, "tools.listChanged")
+		}
+	}
+	if caps.Resources != nil {
+		names = append(names, "resources")
+		if caps.Resources.Subscribe {
+			names = append(names, "resources.subscribe")
+		}
+		if caps.Resources.ListChanged {
+			names = append(names, "resources.listChanged")
+		}
+	}
+	if caps.Prompts != nil {
+		names = append(names, "prompts")
+		if caps.Prompts.ListChanged {
+			names = append(names, "prompts.listChanged")
+		}
+	}
+	if caps.Logging != nil {
+		names = append(names, "logging")
+	}
+	if caps.Completions != nil {
+		names = append(names, "completions")
+	}
+	for name := range caps.Experimental {
+		names = append(names, "experimental."+name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCapabilityMatrix summarizes a report. The report must be locked.
+func newCapabilityMatrix(r *probeReport) *capabilityMatrix {
+	m := &capabilityMatrix{
+		Format:          matrixFormat,
+		FormatVersion:   matrixFormatVersion,
+		Probe:           fmt.Sprintf("%s %s", r.ProbeName, r.ProbeVersion),
+		GeneratedAt:     timestamp(time.Now()),
+		Target:          r.Target,
+		Transport:       r.Transport,
+		ProtocolVersion: r.ProtocolVersion,
+		Capabilities:    advertisedCapabilities(r.Capabilities),
+		Counts: matrixCounts{
+			Tools:             len(r.Tools),
+			Resources:         len(r.Resources),
+			ResourceTemplates: len(r.ResourceTemplates),
+			Prompts:           len(r.Prompts),
+		},
+		Errors:   len(r.Errors),
+		Warnings: len(r.Warnings),
+	}
+	if r.ServerInfo != nil {
+		m.Server = r.ServerInfo.Name
+		m.ServerVersion = r.ServerInfo.Version
+	}
+	if len(r.Checks) > 0 {
+		m.Checks = map[string]string{}
+		for _, c := range r.Checks {
+			m.Checks[c.ID] = c.Status
+		}
+	}
+	for _, f := range r.Findings {
+		if f.Suppressed {
+			continue
+		}
+		if m.Findings == nil {
+			m.Findings = map[string]int{}
+		}
+		m.Findings[f.Severity]++
+	}
+
+	// The surface hash changes whenever a tool is added, removed or
+	// changes its schemas, so that servers with the same tools group together
+	if len(r.Tools) > 0 {
+		m.ToolHashes = map[string]string{}
+		var lines []string
+		for _, tool := range r.Tools {
+			hash, err := toolSchemaHash(tool)
+			if err != nil {
+				continue
+			}
+			m.ToolHashes[tool.Name] = hash
+			lines = append(lines, tool.Name+" "+hash)
+		}
+		sort.Strings(lines)
+		m.SurfaceHash = sha256Hex([]byte(strings.Join(lines, "\n")))
+	}
+	return m
+}
+
+// renderMatrix writes the capability matrix of a report. The report must
+// be locked.
+func renderMatrix(w io.Writer, r *probeReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newCapabilityMatrix(r))
+}
+
+// fleetSummary is the merged capability matrices of a directory
+type fleetSummary struct {
+	Directory    string              `json:"directory"`
+	Servers      []*capabilityMatrix `json:"servers"`
+	Capabilities map[string]int      `json:"capabilities"`
+	Protocols    map[string]int      `json:"protocolVersions"`
+	Failing      int                 `json:"failing"`
+	Skipped      []string            `json:"skipped,omitempty"`
+}
+
+// readCapabilityMatrices reads the capability matrices in a directory and
+// its subdirectories. JSON files that are not matrices are skipped, and
+// matrices that cannot be read are listed as skipped.
+func readCapabilityMatrices(dir string) (*fleetSummary, error) {
+	summary := &fleetSummary{Directory: dir, Capabilities: map[string]int{}, Protocols: map[string]int{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			summary.Skipped = append(summary.Skipped, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+		var m capabilityMatrix
+		if json.Unmarshal(data, &m) != nil || m.Format != matrixFormat {
+			return nil
+		}
+		if m.FormatVersion > matrixFormatVersion {
+			summary.Skipped = append(summary.Skipped, fmt.Sprintf("%s: format version %d is newer than this probe supports (%d)", path, m.FormatVersion, matrixFormatVersion))
+			return nil
+		}
+		summary.Servers = append(summary.Servers, &m)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	sort.SliceStable(summary.Servers, func(i, j int) bool {
+		a, b := summary.Servers[i], summary.Servers[j]
+		if a.Server != b.Server {
+			return a.Server < b.Server
+		}
+		return a.Target < b.Target
+	})
+	for _, m := range summary.Servers {
+		for _, name := range m.Capabilities {
+			summary.Capabilities[name]++
+		}
+		summary.Protocols[valueOr(m.ProtocolVersion, "unknown")]++
+		if m.failing() {
+			summary.Failing++
+		}
+	}
+	return summary, nil
+}
+
+// failing reports whether the run had errors or failing checks
+func (m *capabilityMatrix) failing() bool {
+	if m.Errors > 0 {
+		return true
+	}
+	for _, status := range m.Checks {
+		if status == "fail" || status == "flaky" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSummaryLabel counts the checks by status, such as "5 pass, 1 fail"
+func (m *capabilityMatrix) checkSummaryLabel() string {
+	if len(m.Checks) == 0 {
+		return "-"
+	}
+	counts := map[string]int{}
+	for _, status := range m.Checks {
+		counts[status]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for i, status := range statuses {
+		statuses[i] = fmt.Sprintf("%d %s", counts[status], status)
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// printFleetSummary prints the merged capability matrices as a table
+func printFleetSummary(summary *fleetSummary) {
+	fmt.Printf("=== Capability Matrix: %s ===\n", summary.Directory)
+	fmt.Printf("Servers: %d, with errors or failing checks: %d\n\n", len(summary.Servers), summary.Failing)
+	if len(summary.Servers) == 0 {
+		fmt.Println("No capability matrices found (write them with -report matrix -o <file>)")
+	} else {
+		headers := []string{"Server", "Version", "Protocol", "Tools", "Res", "Tmpl", "Prompts", "Capabilities", "Checks", "Errors", "Surface"}
+		rows := [][]string{headers}
+		for _, m := range summary.Servers {
+			surface := m.SurfaceHash
+			if len(surface) > 12 {
+				surface = surface[:12]
+			}
+			rows = append(rows, []string{
+				valueOr(m.Server, m.Target),
+				valueOr(m.ServerVersion, "-"),
+				valueOr(m.ProtocolVersion, "-"),
+				fmt.Sprint(m.Counts.Tools),
+				fmt.Sprint(m.Counts.Resources),
+				fmt.Sprint(m.Counts.ResourceTemplates),
+				fmt.Sprint(m.Counts.Prompts),
+				valueOr(strings.Join(baseCapabilities(m.Capabilities), ","), "-"),
+				m.checkSummaryLabel(),
+				fmt.Sprint(m.Errors),
+				valueOr(surface, "-"),
+			})
+		}
+		widths := make([]int, len(headers))
+		for _, row := range rows {
+			for i, cell := range row {
+				widths[i] = max(widths[i], len(cell))
+			}
+		}
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			}
+			fmt.Println(strings.TrimRight(strings.Join(cells, "  "), " "))
+		}
+	}
+
+	if len(summary.Capabilities) > 0 {
+		names := make([]string, 0, len(summary.Capabilities))
+		for name := range summary.Capabilities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("\nCapabilities:")
+		for _, name := range names {
+			fmt.Printf("  %-24s %d of %d\n", name, summary.Capabilities[name], len(summary.Servers))
+		}
+	}
+	if len(summary.Protocols) > 0 {
+		versions := make([]string, 0, len(summary.Protocols))
+		for version := range summary.Protocols {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+		fmt.Println("\nProtocol versions:")
+		for _, version := range versions {
+			fmt.Printf("  %-24s %d\n", version, summary.Protocols[version])
+		}
+	}
+	for _, skipped := range summary.Skipped {
+		fmt.Printf("\nSkipped %s\n", skipped)
+	}
+}
+
+// baseCapabilities drops the sub-capabilities, for a compact table column
+func baseCapabilities(names []string) []string {
+	var base []string
+	for _, name := range names {
+		if !strings.Contains(name, ".") {
+			base = append(base, name)
+		}
+	}
+	return base
+}
+
+// runAggregateCommand implements the 'aggregate' subcommand
+func runAggregateCommand(args []string) error {
+	var dir string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("aggregate", flag.ContinueOnError)
+	format := fs.String("output", outputText, "Output format: 'text' or 'json'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if dir == "" && fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if dir == "" {
+		return fmt.Errorf("usage: aggregate <directory> [-output text|json]")
+	}
+	if *format != outputText && *format != outputJSON {
+		return fmt.Errorf("unsupported output format '%s' (use 'text' or 'json')", *format)
+	}
+
+	summary, err := readCapabilityMatrices(dir)
+	if err != nil {
+		return err
+	}
+	if *format == outputJSON {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode the summary: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printFleetSummary(summary)
+	return nil
+}
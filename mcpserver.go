@@ -0,0 +1,447 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// probeToolServer serves MCPProbe's own probing as MCP tools, so that an
+// agent can inspect and test other MCP servers. Each tool call opens a
+// session of its own with the target server and closes it when done.
+type probeToolServer struct {
+	timeout     time.Duration
+	callTimeout time.Duration
+	allowStdio  bool
+	allowHosts  []string
+	logger      *log.Logger
+}
+
+// probeTarget is the server a tool call is about
+type probeTarget struct {
+	url       string
+	transport string
+	headers   map[string]string
+	command   string
+	args      []string
+	env       []string
+}
+
+// probeServerResult is the summary returned by probe_server
+type probeServerResult struct {
+	Target            string                 `json:"target"`
+	Server            mcp.Implementation     `json:"server"`
+	ProtocolVersion   string                 `json:"protocolVersion"`
+	Instructions      string                 `json:"instructions,omitempty"`
+	Capabilities      mcp.ServerCapabilities `json:"capabilities"`
+	Tools             []string               `json:"tools,omitempty"`
+	Resources         []string               `json:"resources,omitempty"`
+	ResourceTemplates []string               `json:"resourceTemplates,omitempty"`
+	Prompts           []string               `json:"prompts,omitempty"`
+	TimingsMs         map[string]float64     `json:"timingsMs"`
+	Problems          []string               `json:"problems,omitempty"`
+}
+
+// runMCPServerCommand implements the 'mcp-server' subcommand
+func runMCPServerCommand(args []string) error {
+	fs := flag.NewFlagSet("mcp-server", flag.ContinueOnError)
+	transportName := fs.String("transport", "stdio", "Transport to serve: 'stdio', 'http' or 'sse'")
+	listen := fs.String("listen", "127.0.0.1:8000", "Address to listen on for the http and sse transports")
+	timeout := fs.Duration("timeout", 30*time.Second, "Timeout for connecting to a target server and for each listing")
+	callTimeout := fs.Duration("call-timeout", 5*time.Minute, "Timeout for a call_remote_tool tool call")
+	allowStdio := fs.Bool("allow-stdio", false, "Allow targets that start a local stdio server (lets the agent run commands)")
+	allowHosts := fs.String("allow-hosts", "", "Only allow target URLs on these hosts (comma-separated host, host:port or scheme://host[:port]; default: any host)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p := &probeToolServer{
+		timeout:     *timeout,
+		callTimeout: *callTimeout,
+		allowStdio:  *allowStdio,
+		logger:      log.New(os.Stderr, "[mcp-server] ", log.LstdFlags),
+	}
+	for _, host := range strings.Split(*allowHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			p.allowHosts = append(p.allowHosts, strings.ToLower(host))
+		}
+	}
+	mcpServer := p.newServer()
+
+	switch strings.ToLower(*transportName) {
+	case "stdio":
+		p.logger.Printf("Serving %s's probing tools on stdio", ProgName)
+		return server.ServeStdio(mcpServer)
+	case "http":
+		p.logger.Printf("Serving %s's probing tools at http://%s/mcp", ProgName, *listen)
+		return server.NewStreamableHTTPServer(mcpServer).Start(*listen)
+	case "sse":
+		p.logger.Printf("Serving %s's probing tools at http://%s/sse", ProgName, *listen)
+		return server.NewSSEServer(mcpServer).Start(*listen)
+	default:
+		return fmt.Errorf("unsupported transport '%s' (use 'stdio', 'http' or 'sse')", *transportName)
+	}
+}
+
+// newServer builds the MCP server and its tools
+func (p *probeToolServer) newServer() *server.MCPServer {
+	s := server.NewMCPServer(ProgName, ProgVer,
+		server.WithToolCapabilities(false),
+		server.WithInstructions("Probe, inspect and test other MCP servers. Every tool takes the target server as 'url' "+
+			"(with 'transport' and 'headers')"+p.stdioHint()+"; each call opens a fresh session with it."))
+
+	target := p.targetOptions()
+	s.AddTool(mcp.NewTool("probe_server", append([]mcp.ToolOption{
+		mcp.WithDescription("Connect to an MCP server and summarize it: server info, negotiated protocol version, capabilities, " +
+			"the names of its tools, resources, resource templates and prompts, the time each step took, and any problems found"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	}, target...)...), p.probeServer)
+
+	s.AddTool(mcp.NewTool("list_tools", append([]mcp.ToolOption{
+		mcp.WithDescription("List the tools of an MCP server in full, as the server describes them: names, titles, descriptions, " +
+			"input and output schemas and annotations"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	}, target...)...), p.listTools)
+
+	s.AddTool(mcp.NewTool("call_remote_tool", append([]mcp.ToolOption{
+		mcp.WithDescription("Call a tool of an MCP server and return its result as the server sent it, with the time the call took. " +
+			"The tool runs on the target server and may have side effects there."),
+		mcp.WithString("tool", mcp.Required(), mcp.Description("Name of the tool to call")),
+		mcp.WithObject("arguments", mcp.Description("Arguments of the tool call")),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	}, target...)...), p.callRemoteTool)
+
+	s.AddTool(mcp.NewTool("read_remote_resource", append([]mcp.ToolOption{
+		mcp.WithDescription("Read a resource of an MCP server and return its contents"),
+		mcp.WithString("uri", mcp.Required(), mcp.Description("URI of the resource")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	}, target...)...), p.readRemoteResource)
+
+	s.AddTool(mcp.NewTool("get_remote_prompt", append([]mcp.ToolOption{
+		mcp.WithDescription("Get a prompt of an MCP server with the given arguments and return its messages"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Name of the prompt")),
+		mcp.WithObject("arguments", mcp.Description("Prompt arguments, all strings"), mcp.AdditionalProperties(map[string]any{"type": "string"})),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	}, target...)...), p.getRemotePrompt)
+	return s
+}
+
+// targetOptions are the parameters naming the target server, shared by
+// every tool
+func (p *probeToolServer) targetOptions() []mcp.ToolOption {
+	options := []mcp.ToolOption{
+		mcp.WithString("url", mcp.Description("URL of the MCP server, such as https://example.com/mcp")),
+		mcp.WithString("transport", mcp.Enum("http", "sse"), mcp.DefaultString("http"),
+			mcp.Description("Transport of the server: streamable HTTP or SSE")),
+		mcp.WithObject("headers", mcp.Description("HTTP headers to send, such as Authorization"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"})),
+	}
+	if p.allowStdio {
+		options = append(options,
+			mcp.WithString("command", mcp.Description("Command of a local stdio server to start instead of connecting to a URL")),
+			mcp.WithArray("args", mcp.WithStringItems(), mcp.Description("Arguments of the stdio server")),
+			mcp.WithArray("env", mcp.WithStringItems(), mcp.Description("Environment variables of the stdio server as KEY=VALUE")))
+	}
+	return options
+}
+
+// stdioHint mentions stdio targets in the instructions when they are allowed
+func (p *probeToolServer) stdioHint() string {
+	if p.allowStdio {
+		return ", or a local stdio server as 'command' (with 'args' and 'env')"
+	}
+	return ""
+}
+
+// parseTarget reads the target server from a tool call's arguments
+func (p *probeToolServer) parseTarget(request mcp.CallToolRequest) (*probeTarget, error) {
+	t := &probeTarget{
+		url:       request.GetString("url", ""),
+		transport: request.GetString("transport", "http"),
+		headers:   map[string]string{},
+		command:   request.GetString("command", ""),
+		args:      request.GetStringSlice("args", nil),
+		env:       request.GetStringSlice("env", nil),
+	}
+	if headers, ok := request.GetArguments()["headers"].(map[string]any); ok {
+		for key, value := range headers {
+			t.headers[key] = fmt.Sprint(value)
+		}
+	}
+
+	switch {
+	case t.command != "" && !p.allowStdio:
+		return nil, fmt.Errorf("stdio targets are not allowed (start the server with -allow-stdio)")
+	case t.command != "" && t.url != "":
+		return nil, fmt.Errorf("give either url or command, not both")
+	case t.command != "":
+		return t, nil
+	case t.url == "":
+		return nil, fmt.Errorf("url is required")
+	case t.transport != "http" && t.transport != "sse":
+		return nil, fmt.Errorf("transport must be 'http' or 'sse'")
+	}
+	parsed, err := url.Parse(t.url)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid url '%s'", t.url)
+	}
+	if len(p.allowHosts) > 0 && !p.hostAllowed(parsed) {
+		return nil, fmt.Errorf("host '%s' is not allowed (allowed: %s)", parsed.Host, strings.Join(p.allowHosts, ", "))
+	}
+	return t, nil
+}
+
+// hostAllowed reports whether a target URL matches one of -allow-hosts. An
+// entry that is a host name allows any port over HTTP or HTTPS; one that also
+// names the port (host:port) or the scheme (https://host[:port]) allows only
+// those.
+func (p *probeToolServer) hostAllowed(target *url.URL) bool {
+	defaultPorts := map[string]string{"http": "80", "https": "443"}
+	port := target.Port()
+	if port == "" {
+		port = defaultPorts[target.Scheme]
+	}
+	for _, entry := range p.allowHosts {
+		scheme, host, found := strings.Cut(entry, "://")
+		if !found {
+			scheme, host = "", entry
+		}
+		if scheme != "" && scheme != target.Scheme {
+			continue
+		}
+		// An entry with a scheme but no port means the scheme's default port
+		entryPort := defaultPorts[scheme]
+		if name, portPart, err := net.SplitHostPort(host); err == nil {
+			host, entryPort = name, portPart
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if strings.Trim(host, "[]") == strings.ToLower(target.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// String names the target for results and the log
+func (t *probeTarget) String() string {
+	if t.command != "" {
+		return strings.Join(append([]string{t.command}, t.args...), " ")
+	}
+	return fmt.Sprintf("%s (%s)", t.url, t.transport)
+}
+
+// connect opens and initializes a session with the target server
+func (p *probeToolServer) connect(ctx context.Context, t *probeTarget) (*client.Client, *mcp.InitializeResult, error) {
+	var mcpClient *client.Client
+	var err error
+	switch {
+	case t.command != "":
+		mcpClient, err = client.NewStdioMCPClientWithOptions(t.command, t.env, t.args, transport.WithCommandFunc(stdioCommand))
+	case t.transport == "sse":
+		mcpClient, err = createSSEClient(t.url, t.headers, p.callTimeout, 0, nil, nil)
+	default:
+		mcpClient, err = createHTTPClient(t.url, t.headers, p.callTimeout, 0, nil, nil)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	if t.command == "" {
+		if err := mcpClient.Start(ctx); err != nil {
+			_ = mcpClient.Close()
+			return nil, nil, fmt.Errorf("failed to connect: %w", err)
+		}
+	}
+	result, err := mcpClient.Initialize(ctx, newInitializeRequest())
+	if err != nil {
+		_ = mcpClient.Close()
+		return nil, nil, fmt.Errorf("failed to initialize: %w", err)
+	}
+	return mcpClient, result, nil
+}
+
+// session connects to the target of a tool call and runs fn with the open
+// session. Failures are returned as tool errors, so that the agent sees them.
+func (p *probeToolServer) session(ctx context.Context, request mcp.CallToolRequest, timeout time.Duration,
+	fn func(ctx context.Context, t *probeTarget, mcpClient *client.Client, init *mcp.InitializeResult) (any, error)) (*mcp.CallToolResult, error) {
+	t, err := p.parseTarget(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	p.logger.Printf("%s: %s", request.Params.Name, t)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	mcpClient, init, err := p.connect(ctx, t)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("%s: %v", t, err)), nil
+	}
+	defer func() { _ = mcpClient.Close() }()
+
+	result, err := fn(ctx, t, mcpClient, init)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("%s: %v", t, err)), nil
+	}
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return mcp.NewToolResultStructured(result, string(text)), nil
+}
+
+// probeServer implements probe_server
+func (p *probeToolServer) probeServer(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start := time.Now()
+	return p.session(ctx, request, p.timeout, func(ctx context.Context, t *probeTarget, mcpClient *client.Client, init *mcp.InitializeResult) (any, error) {
+		result := &probeServerResult{
+			Target:          t.String(),
+			Server:          init.ServerInfo,
+			ProtocolVersion: init.ProtocolVersion,
+			Instructions:    init.Instructions,
+			Capabilities:    init.Capabilities,
+			TimingsMs:       map[string]float64{"initialize": durationMillis(time.Since(start))},
+		}
+
+		// Each listing the server advertises is followed through every page
+		lists := []struct {
+			advertised bool
+			method     mcp.MCPMethod
+			itemsKey   string
+			idKey      string
+			names      *[]string
+		}{
+			{init.Capabilities.Tools != nil, mcp.MethodToolsList, "tools", "name", &result.Tools},
+			{init.Capabilities.Resources != nil, mcp.MethodResourcesList, "resources", "uri", &result.Resources},
+			{init.Capabilities.Resources != nil, mcp.MethodResourcesTemplatesList, "resourceTemplates", "uriTemplate", &result.ResourceTemplates},
+			{init.Capabilities.Prompts != nil, mcp.MethodPromptsList, "prompts", "name", &result.Prompts},
+		}
+		for _, l := range lists {
+			if !l.advertised {
+				continue
+			}
+			listStart := time.Now()
+			list, err := followPages(ctx, mcpClient, l.method, l.itemsKey, l.idKey)
+			result.TimingsMs[string(l.method)] = durationMillis(time.Since(listStart))
+			if err != nil {
+				result.Problems = append(result.Problems, fmt.Sprintf("%s failed: %v", l.method, err))
+				continue
+			}
+			*l.names = itemKeys(list.items, l.idKey)
+			for _, problem := range list.problems {
+				result.Problems = append(result.Problems, fmt.Sprintf("%s: %s", l.method, problem))
+			}
+		}
+
+		pingStart := time.Now()
+		if err := mcpClient.Ping(ctx); err != nil {
+			result.Problems = append(result.Problems, fmt.Sprintf("ping failed: %v", err))
+		} else {
+			result.TimingsMs["ping"] = durationMillis(time.Since(pingStart))
+		}
+		return result, nil
+	})
+}
+
+// listTools implements list_tools. The tools are returned as the server
+// listed them, as the library drops fields it does not know.
+func (p *probeToolServer) listTools(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return p.session(ctx, request, p.timeout, func(ctx context.Context, _ *probeTarget, mcpClient *client.Client, _ *mcp.InitializeResult) (any, error) {
+		list, err := followPages(ctx, mcpClient, mcp.MethodToolsList, "tools", "name")
+		if err != nil {
+			return nil, fmt.Errorf("tools/list failed: %w", err)
+		}
+		result := map[string]any{"tools": list.items}
+		if list.items == nil {
+			result["tools"] = []json.RawMessage{}
+		}
+		if len(list.problems) > 0 {
+			result["problems"] = list.problems
+		}
+		return result, nil
+	})
+}
+
+// callRemoteTool implements call_remote_tool. A tool error of the target is
+// part of the result rather than an error of this call.
+func (p *probeToolServer) callRemoteTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tool, err := request.RequireString("tool")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	arguments, _ := request.GetArguments()["arguments"].(map[string]any)
+	return p.session(ctx, request, p.callTimeout, func(ctx context.Context, _ *probeTarget, mcpClient *client.Client, _ *mcp.InitializeResult) (any, error) {
+		call := mcp.CallToolRequest{}
+		call.Params.Name = tool
+		call.Params.Arguments = arguments
+		start := time.Now()
+		result, err := mcpClient.CallTool(ctx, call)
+		if err != nil {
+			return nil, fmt.Errorf("tools/call failed after %s: %w", humanDuration(time.Since(start)), err)
+		}
+		return map[string]any{"durationMs": durationMillis(time.Since(start)), "result": result}, nil
+	})
+}
+
+// readRemoteResource implements read_remote_resource
+func (p *probeToolServer) readRemoteResource(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	uri, err := request.RequireString("uri")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return p.session(ctx, request, p.timeout, func(ctx context.Context, _ *probeTarget, mcpClient *client.Client, _ *mcp.InitializeResult) (any, error) {
+		read := mcp.ReadResourceRequest{}
+		read.Params.URI = uri
+		start := time.Now()
+		result, err := mcpClient.ReadResource(ctx, read)
+		if err != nil {
+			return nil, fmt.Errorf("resources/read failed: %w", err)
+		}
+		return map[string]any{"durationMs": durationMillis(time.Since(start)), "result": result}, nil
+	})
+}
+
+// getRemotePrompt implements get_remote_prompt
+func (p *probeToolServer) getRemotePrompt(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	arguments := map[string]string{}
+	if args, ok := request.GetArguments()["arguments"].(map[string]any); ok {
+		for key, value := range args {
+			arguments[key] = fmt.Sprint(value)
+		}
+	}
+	return p.session(ctx, request, p.timeout, func(ctx context.Context, _ *probeTarget, mcpClient *client.Client, _ *mcp.InitializeResult) (any, error) {
+		get := mcp.GetPromptRequest{}
+		get.Params.Name = name
+		get.Params.Arguments = arguments
+		start := time.Now()
+		result, err := mcpClient.GetPrompt(ctx, get)
+		if err != nil {
+			return nil, fmt.Errorf("prompts/get failed: %w", err)
+		}
+		return map[string]any{"durationMs": durationMillis(time.Since(start)), "result": result}, nil
+	})
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowStdio bool
+		allowHosts []string
+		args       map[string]any
+		wantErr    string
+	}{
+		{"any host", false, nil, map[string]any{"url": "https://mcp.example.com/mcp"}, ""},
+		{"allowed host", false, []string{"mcp.example.com"}, map[string]any{"url": "https://MCP.example.com/mcp"}, ""},
+		{"allowed host on any port", false, []string{"mcp.example.com"}, map[string]any{"url": "http://mcp.example.com:8080/mcp"}, ""},
+		{"host outside the allowlist", false, []string{"mcp.example.com"}, map[string]any{"url": "https://internal.example.com/mcp"}, "host 'internal.example.com' is not allowed"},
+		{"subdomain of an allowed host", false, []string{"example.com"}, map[string]any{"url": "https://mcp.example.com/mcp"}, "is not allowed"},
+		{"credentials before another host", false, []string{"mcp.example.com"}, map[string]any{"url": "https://mcp.example.com@internal.example.com/mcp"}, "is not allowed"},
+		{"allowed port", false, []string{"mcp.example.com:8443"}, map[string]any{"url": "https://mcp.example.com:8443/mcp"}, ""},
+		{"other port", false, []string{"mcp.example.com:8443"}, map[string]any{"url": "https://mcp.example.com:9000/mcp"}, "host 'mcp.example.com:9000' is not allowed"},
+		{"default port", false, []string{"mcp.example.com:443"}, map[string]any{"url": "https://mcp.example.com/mcp"}, ""},
+		{"port only in the URL", false, []string{"mcp.example.com:443"}, map[string]any{"url": "http://mcp.example.com/mcp"}, "is not allowed"},
+		{"allowed scheme", false, []string{"https://mcp.example.com"}, map[string]any{"url": "https://mcp.example.com/mcp"}, ""},
+		{"other scheme", false, []string{"https://mcp.example.com"}, map[string]any{"url": "http://mcp.example.com/mcp"}, "is not allowed"},
+		{"scheme's default port", false, []string{"https://mcp.example.com"}, map[string]any{"url": "https://mcp.example.com:8443/mcp"}, "is not allowed"},
+		{"scheme and port", false, []string{"http://127.0.0.1:8000"}, map[string]any{"url": "http://127.0.0.1:8000/mcp"}, ""},
+		{"IPv6 host", false, []string{"[::1]:8000"}, map[string]any{"url": "http://[::1]:8000/mcp"}, ""},
+		{"unsupported scheme", false, nil, map[string]any{"url": "file:///etc/passwd"}, "invalid url"},
+		{"unsupported transport", false, nil, map[string]any{"url": "https://mcp.example.com/mcp", "transport": "stdio"}, "transport must be"},
+		{"no target", false, nil, map[string]any{}, "url is required"},
+		{"stdio not allowed", false, nil, map[string]any{"command": "server"}, "stdio targets are not allowed"},
+		{"stdio not allowed with a host allowlist", false, []string{"mcp.example.com"}, map[string]any{"command": "server"}, "stdio targets are not allowed"},
+		{"stdio allowed", true, nil, map[string]any{"command": "server", "args": []any{"-v"}}, ""},
+		{"both url and command", true, nil, map[string]any{"command": "server", "url": "https://mcp.example.com/mcp"}, "either url or command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &probeToolServer{allowStdio: tt.allowStdio, allowHosts: tt.allowHosts}
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tt.args
+			target, err := p.parseTarget(request)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("parseTarget: %v", err)
+				}
+				if target == nil {
+					t.Fatal("parseTarget returned no target")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("parseTarget: err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
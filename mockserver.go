@@ -0,0 +1,454 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// mockConfig defines the tools, resources and prompts served by the mock server
+type mockConfig struct {
+	Name         string         `yaml:"name"`
+	Version      string         `yaml:"version"`
+	Instructions string         `yaml:"instructions,omitempty"`
+	Latency      time.Duration  `yaml:"latency,omitempty"`
+	Tools        []mockTool     `yaml:"tools,omitempty"`
+	Resources    []mockResource `yaml:"resources,omitempty"`
+	Prompts      []mockPrompt   `yaml:"prompts,omitempty"`
+}
+
+// mockFault describes latency and errors injected into a handler
+type mockFault struct {
+	Latency       time.Duration `yaml:"latency,omitempty"`
+	Error         string        `yaml:"error,omitempty"`
+	ErrorRate     float64       `yaml:"error_rate,omitempty"`
+	ProtocolError bool          `yaml:"protocol_error,omitempty"`
+}
+
+// mockTool is a tool served by the mock server. Its input schema is either
+// built from Params or given in full as InputSchema. The response may
+// reference arguments as {{name}}; the first case whose arguments match
+// replaces the response and the faults.
+type mockTool struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description,omitempty"`
+	Params      []mockParam    `yaml:"params,omitempty"`
+	InputSchema map[string]any `yaml:"input_schema,omitempty"`
+	Response    string         `yaml:"response,omitempty"`
+	Cases       []mockCase     `yaml:"cases,omitempty"`
+	mockFault   `yaml:",inline"`
+}
+
+// mockCase is a canned result of a tool for calls whose arguments have the
+// given values
+type mockCase struct {
+	When      map[string]any `yaml:"when"`
+	Response  string         `yaml:"response,omitempty"`
+	mockFault `yaml:",inline"`
+}
+
+// mockParam is a tool input parameter
+type mockParam struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Required    bool     `yaml:"required,omitempty"`
+	Enum        []string `yaml:"enum,omitempty"`
+}
+
+// mockResource is a static text resource served by the mock server
+type mockResource struct {
+	URI         string `yaml:"uri"`
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	MIMEType    string `yaml:"mime_type,omitempty"`
+	Text        string `yaml:"text,omitempty"`
+	mockFault   `yaml:",inline"`
+}
+
+// mockPrompt is a prompt served by the mock server. Message text may
+// reference arguments as {{name}}.
+type mockPrompt struct {
+	Name        string              `yaml:"name"`
+	Description string              `yaml:"description,omitempty"`
+	Arguments   []mockPromptArg     `yaml:"arguments,omitempty"`
+	Messages    []mockPromptMessage `yaml:"messages,omitempty"`
+	mockFault   `yaml:",inline"`
+}
+
+// mockPromptArg is a prompt argument
+type mockPromptArg struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+// mockPromptMessage is a message returned by a prompt
+type mockPromptMessage struct {
+	Role string `yaml:"role"`
+	Text string `yaml:"text"`
+}
+
+// defaultMockConfig is served when no -config is given, and printed by -print-config
+const defaultMockConfig = `# MCPProbe mock server configuration
+name: mcpprobe-mock
+version: 1.0.0
+instructions: A mock MCP server for trying out MCPProbe.
+# latency: 50ms             # added to every tool call, resource read and prompt
+
+tools:
+  - name: echo
+    description: Echo the given text back
+    params:
+      - name: text
+        type: string
+        description: Text to echo
+        required: true
+    response: "{{text}}"
+
+  - name: greet
+    description: Greet someone in the chosen style
+    params:
+      - name: name
+        type: string
+        required: true
+      - name: style
+        type: string
+        enum: [formal, casual]
+    response: "Hello {{name}} ({{style}})"
+
+  - name: slow
+    description: Responds after a two second delay
+    response: done
+    latency: 2s
+
+  - name: flaky
+    description: Fails about half of the time
+    response: ok
+    error: the flaky tool failed
+    error_rate: 0.5
+
+  - name: broken
+    description: Always fails with a JSON-RPC error
+    error: internal server error
+    protocol_error: true
+
+resources:
+  - uri: mock://readme
+    name: readme
+    description: A static text resource
+    mime_type: text/plain
+    text: This resource is served by the MCPProbe mock server.
+
+prompts:
+  - name: review
+    description: Ask for a code review
+    arguments:
+      - name: language
+        description: Programming language
+        required: true
+    messages:
+      - role: user
+        text: Please review this {{language}} code.
+`
+
+// runMockServerCommand implements the 'mock-server' subcommand, also run as 'mock'
+func runMockServerCommand(args []string) error {
+	fs := flag.NewFlagSet("mock-server", flag.ContinueOnError)
+	configPath := fs.String("config", "", "YAML file defining the mock tools, resources and prompts (default: built-in)")
+	listen := fs.String("listen", "127.0.0.1:8000", "Address to listen on for the http and sse transports")
+	transportName := fs.String("transport", "http", "Transport to serve: 'http', 'sse' or 'stdio'")
+	printConfig := fs.Bool("print-config", false, "Print the built-in configuration (a starting point for -config) and exit")
+	seed := fs.Uint64("seed", 0, "Seed of the injected errors' error_rate, to fail the same calls on every run (default: random)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *seed != 0 {
+		mockRand = rand.New(rand.NewPCG(*seed, *seed))
+	}
+
+	if *printConfig {
+		fmt.Print(defaultMockConfig)
+		return nil
+	}
+
+	data := []byte(defaultMockConfig)
+	if *configPath != "" {
+		var err error
+		if data, err = os.ReadFile(*configPath); err != nil {
+			return fmt.Errorf("failed to read mock config: %w", err)
+		}
+	}
+	var cfg mockConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse mock config: %w", err)
+	}
+
+	mcpServer, err := newMockServer(&cfg)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(*transportName) {
+	case "stdio":
+		mockLog.Printf("Serving '%s' on stdio", cfg.Name)
+		return server.ServeStdio(mcpServer)
+	case "http":
+		mockLog.Printf("Serving '%s' at http://%s/mcp (probe with: -url http://%s/mcp -transport http)", cfg.Name, *listen, *listen)
+		return server.NewStreamableHTTPServer(mcpServer).Start(*listen)
+	case "sse":
+		mockLog.Printf("Serving '%s' at http://%s/sse (probe with: -url http://%s/sse -transport sse)", cfg.Name, *listen, *listen)
+		return server.NewSSEServer(mcpServer).Start(*listen)
+	default:
+		return fmt.Errorf("unsupported transport '%s' (use 'http', 'sse' or 'stdio')", *transportName)
+	}
+}
+
+// mockRand decides which calls fail by error_rate; -seed makes it repeatable
+var (
+	mockRand   = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	mockRandMu sync.Mutex
+)
+
+// mockLog logs requests handled by the mock server. It writes to stderr so
+// that stdout stays clean for the stdio transport.
+var mockLog = log.New(os.Stderr, "[mock] ", log.LstdFlags)
+
+// newMockServer builds an MCP server from the mock configuration
+func newMockServer(cfg *mockConfig) (*server.MCPServer, error) {
+	if cfg.Name == "" {
+		cfg.Name = "mcpprobe-mock"
+	}
+	if cfg.Version == "" {
+		cfg.Version = "1.0.0"
+	}
+
+	options := []server.ServerOption{
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+		server.WithRecovery(),
+	}
+	if cfg.Instructions != "" {
+		options = append(options, server.WithInstructions(cfg.Instructions))
+	}
+	mcpServer := server.NewMCPServer(cfg.Name, cfg.Version, options...)
+
+	for _, t := range cfg.Tools {
+		tool, err := t.toMCP()
+		if err != nil {
+			return nil, err
+		}
+		mcpServer.AddTool(tool, t.handler(cfg.Latency))
+	}
+	for _, r := range cfg.Resources {
+		if r.URI == "" {
+			return nil, fmt.Errorf("mock resource is missing a uri")
+		}
+		mcpServer.AddResource(r.toMCP(), r.handler(cfg.Latency))
+	}
+	for _, p := range cfg.Prompts {
+		if p.Name == "" {
+			return nil, fmt.Errorf("mock prompt is missing a name")
+		}
+		mcpServer.AddPrompt(p.toMCP(), p.handler(cfg.Latency))
+	}
+	return mcpServer, nil
+}
+
+// inject applies the configured latency and decides whether the request fails.
+// It returns the error to fail with, or nil to respond normally.
+func (f mockFault) inject(ctx context.Context, baseLatency time.Duration) error {
+	if delay := baseLatency + f.Latency; delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if f.Error == "" {
+		return nil
+	}
+	if f.ErrorRate > 0 {
+		mockRandMu.Lock()
+		pass := mockRand.Float64() >= f.ErrorRate
+		mockRandMu.Unlock()
+		if pass {
+			return nil
+		}
+	}
+	return errors.New(f.Error)
+}
+
+// toMCP converts the mock tool definition to an MCP tool
+func (t mockTool) toMCP() (mcp.Tool, error) {
+	if t.Name == "" {
+		return mcp.Tool{}, fmt.Errorf("mock tool is missing a name")
+	}
+	for i, c := range t.Cases {
+		if len(c.When) == 0 {
+			return mcp.Tool{}, fmt.Errorf("mock tool '%s': case %d has no 'when' arguments", t.Name, i+1)
+		}
+	}
+	if t.InputSchema != nil {
+		if len(t.Params) > 0 {
+			return mcp.Tool{}, fmt.Errorf("mock tool '%s': use either params or input_schema", t.Name)
+		}
+		if t.InputSchema["type"] != "object" {
+			return mcp.Tool{}, fmt.Errorf("mock tool '%s': input_schema must have type object", t.Name)
+		}
+		raw, err := json.Marshal(t.InputSchema)
+		if err != nil {
+			return mcp.Tool{}, fmt.Errorf("mock tool '%s': invalid input_schema: %w", t.Name, err)
+		}
+		return mcp.NewToolWithRawSchema(t.Name, t.Description, raw), nil
+	}
+	schema := mcp.ToolInputSchema{Type: "object", Properties: make(map[string]any)}
+	for _, p := range t.Params {
+		paramType := p.Type
+		if paramType == "" {
+			paramType = "string"
+		}
+		switch paramType {
+		case "string", "number", "integer", "boolean":
+		default:
+			return mcp.Tool{}, fmt.Errorf("mock tool '%s': unsupported type '%s' for parameter '%s'", t.Name, paramType, p.Name)
+		}
+		property := map[string]any{"type": paramType}
+		if p.Description != "" {
+			property["description"] = p.Description
+		}
+		if len(p.Enum) > 0 {
+			property["enum"] = p.Enum
+		}
+		schema.Properties[p.Name] = property
+		if p.Required {
+			schema.Required = append(schema.Required, p.Name)
+		}
+	}
+	return mcp.Tool{Name: t.Name, Description: t.Description, InputSchema: schema}, nil
+}
+
+// handler returns the tool handler for the mock tool
+func (t mockTool) handler(baseLatency time.Duration) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		mockLog.Printf("tools/call %s", t.Name)
+		args := request.GetArguments()
+		response, fault := t.Response, t.mockFault
+		for _, c := range t.Cases {
+			if c.matches(args) {
+				response, fault = c.Response, c.mockFault
+				break
+			}
+		}
+		if err := fault.inject(ctx, baseLatency); err != nil {
+			if fault.ProtocolError {
+				return nil, err
+			}
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(expandMockTemplate(response, args)), nil
+	}
+}
+
+// matches reports whether the arguments have the case's values. Values are
+// compared as text, so that 42 in the YAML matches 42 in JSON.
+func (c mockCase) matches(args map[string]any) bool {
+	for name, want := range c.When {
+		got, ok := args[name]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// toMCP converts the mock resource definition to an MCP resource
+func (r mockResource) toMCP() mcp.Resource {
+	name := r.Name
+	if name == "" {
+		name = r.URI
+	}
+	options := []mcp.ResourceOption{mcp.WithResourceDescription(r.Description)}
+	if r.MIMEType != "" {
+		options = append(options, mcp.WithMIMEType(r.MIMEType))
+	}
+	return mcp.NewResource(r.URI, name, options...)
+}
+
+// handler returns the resource handler for the mock resource
+func (r mockResource) handler(baseLatency time.Duration) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		mockLog.Printf("resources/read %s", r.URI)
+		if err := r.inject(ctx, baseLatency); err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: r.URI, MIMEType: r.MIMEType, Text: r.Text}}, nil
+	}
+}
+
+// toMCP converts the mock prompt definition to an MCP prompt
+func (p mockPrompt) toMCP() mcp.Prompt {
+	options := []mcp.PromptOption{mcp.WithPromptDescription(p.Description)}
+	for _, arg := range p.Arguments {
+		argOptions := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+		if arg.Required {
+			argOptions = append(argOptions, mcp.RequiredArgument())
+		}
+		options = append(options, mcp.WithArgument(arg.Name, argOptions...))
+	}
+	return mcp.NewPrompt(p.Name, options...)
+}
+
+// handler returns the prompt handler for the mock prompt
+func (p mockPrompt) handler(baseLatency time.Duration) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		mockLog.Printf("prompts/get %s", p.Name)
+		if err := p.inject(ctx, baseLatency); err != nil {
+			return nil, err
+		}
+		args := make(map[string]any, len(request.Params.Arguments))
+		for k, v := range request.Params.Arguments {
+			args[k] = v
+		}
+		var messages []mcp.PromptMessage
+		for _, m := range p.Messages {
+			role := mcp.RoleUser
+			if m.Role == string(mcp.RoleAssistant) {
+				role = mcp.RoleAssistant
+			}
+			messages = append(messages, mcp.NewPromptMessage(role, mcp.NewTextContent(expandMockTemplate(m.Text, args))))
+		}
+		return mcp.NewGetPromptResult(p.Description, messages), nil
+	}
+}
+
+// mockTemplatePattern matches {{name}} references in mock responses
+var mockTemplatePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// expandMockTemplate replaces {{name}} references with argument values
+func expandMockTemplate(text string, args map[string]any) string {
+	return mockTemplatePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		name := mockTemplatePattern.FindStringSubmatch(ref)[1]
+		if v, ok := args[name]; ok {
+			return fmt.Sprint(v)
+		}
+		return ""
+	})
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// mockTestTools extends the built-in tools with canned cases, a JSON Schema
+// input and a tool slower than the test's -accept-timeout
+const mockTestTools = `  - name: lookup
+    input_schema:
+      type: object
+      properties:
+        id: {type: integer}
+      required: [id]
+    response: unknown id {{id}}
+    cases:
+      - when: {id: 42}
+        response: the answer
+      - when: {id: 13}
+        error: unlucky
+  - name: pause
+    response: resumed
+    latency: 300ms
+
+`
+
+// serveMock serves the mock configuration over streamable HTTP and returns
+// its URL. A wrap function puts a handler in front of the server, so that
+// tests can make it misbehave.
+func serveMock(t *testing.T, config string, wrap func(http.Handler) http.Handler) string {
+	t.Helper()
+	mockLog = log.New(io.Discard, "", 0)
+
+	var cfg mockConfig
+	if err := yaml.Unmarshal([]byte(config), &cfg); err != nil {
+		t.Fatalf("failed to parse mock config: %v", err)
+	}
+	mcpServer, err := newMockServer(&cfg)
+	if err != nil {
+		t.Fatalf("newMockServer: %v", err)
+	}
+	var handler http.Handler = server.NewStreamableHTTPServer(mcpServer)
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL + "/mcp"
+}
+
+// interceptMethod returns a wrap function for serveMock that answers the
+// requests of a JSON-RPC method itself instead of passing them to the mock
+// server. The answer is given the request's ID as sent.
+func interceptMethod(method string, answer func(w http.ResponseWriter, id json.RawMessage)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			var request struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+			}
+			if json.Unmarshal(body, &request) == nil && request.Method == method {
+				answer(w, request.ID)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// startMockServer serves the mock configuration over streamable HTTP and
+// returns a probe client connected to it
+func startMockServer(t *testing.T, config string, acceptTimeout time.Duration) *client.Client {
+	t.Helper()
+	mcpClient, err := createHTTPClient(serveMock(t, config, nil), nil, 10*time.Second, acceptTimeout, nil, quietLogger{})
+	if err != nil {
+		t.Fatalf("createHTTPClient: %v", err)
+	}
+	t.Cleanup(func() { _ = mcpClient.Close() })
+	ctx := context.Background()
+	if err := mcpClient.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := mcpClient.Initialize(ctx, newInitializeRequest()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return mcpClient
+}
+
+// callText calls a tool and returns the text of its result
+func callText(t *testing.T, mcpClient *client.Client, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := mcpClient.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatalf("calling %s: %v", name, err)
+	}
+	var text strings.Builder
+	for _, content := range result.Content {
+		if c, ok := content.(mcp.TextContent); ok {
+			text.WriteString(c.Text)
+		}
+	}
+	return text.String(), result.IsError
+}
+
+func TestMockServerEndToEnd(t *testing.T) {
+	// The accept timeout must not cut short a tool that answers later
+	config := strings.Replace(defaultMockConfig, "resources:", mockTestTools+"resources:", 1)
+	mcpClient := startMockServer(t, config, 100*time.Millisecond)
+	ctx := context.Background()
+
+	tools, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+		if tool.Name == "lookup" && !reflect.DeepEqual(tool.InputSchema.Properties["id"], map[string]any{"type": "integer"}) {
+			t.Errorf("lookup's input_schema was not served: %+v", tool.InputSchema)
+		}
+	}
+	if got := strings.Join(names, ","); got != "broken,echo,flaky,greet,lookup,pause,slow" {
+		t.Errorf("tools = %s", got)
+	}
+
+	calls := []struct {
+		tool    string
+		args    map[string]any
+		want    string
+		isError bool
+	}{
+		{"echo", map[string]any{"text": "hi"}, "hi", false},
+		{"greet", map[string]any{"name": "Ada", "style": "formal"}, "Hello Ada (formal)", false},
+		{"lookup", map[string]any{"id": 42}, "the answer", false},
+		{"lookup", map[string]any{"id": 13}, "unlucky", true},
+		{"lookup", map[string]any{"id": 7}, "unknown id 7", false},
+		{"pause", nil, "resumed", false},
+	}
+	for _, c := range calls {
+		text, isError := callText(t, mcpClient, c.tool, c.args)
+		if text != c.want || isError != c.isError {
+			t.Errorf("%s %v = %q (error %v), want %q (error %v)", c.tool, c.args, text, isError, c.want, c.isError)
+		}
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "broken"
+	if _, err := mcpClient.CallTool(ctx, request); err == nil || !strings.Contains(err.Error(), "internal server error") {
+		t.Errorf("broken tool: err = %v, want a JSON-RPC error", err)
+	}
+
+	read := mcp.ReadResourceRequest{}
+	read.Params.URI = "mock://readme"
+	resource, err := mcpClient.ReadResource(ctx, read)
+	if err != nil || len(resource.Contents) != 1 {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if problems := validateResourceContents(read.Params.URI, "text/plain", resource.Contents); len(problems) > 0 {
+		t.Errorf("resource problems: %v", problems)
+	}
+
+	get := mcp.GetPromptRequest{}
+	get.Params.Name = "review"
+	get.Params.Arguments = map[string]string{"language": "Go"}
+	prompt, err := mcpClient.GetPrompt(ctx, get)
+	if err != nil || len(prompt.Messages) != 1 {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if text := prompt.Messages[0].Content.(mcp.TextContent).Text; text != "Please review this Go code." {
+		t.Errorf("prompt text = %q", text)
+	}
+
+	if _, _, err := resolveToolName(ctx, mcpClient, "ech", false); err == nil || !strings.Contains(err.Error(), "did you mean echo?") {
+		t.Errorf("resolveToolName(ech) error = %v, want a suggestion", err)
+	}
+}
+
+func TestMockServerSeed(t *testing.T) {
+	mcpClient := startMockServer(t, defaultMockConfig, 0)
+	outcomes := func() string {
+		mockRandMu.Lock()
+		mockRand = rand.New(rand.NewPCG(7, 7))
+		mockRandMu.Unlock()
+		var s strings.Builder
+		for range 20 {
+			if _, isError := callText(t, mcpClient, "flaky", nil); isError {
+				s.WriteByte('x')
+			} else {
+				s.WriteByte('.')
+			}
+		}
+		return s.String()
+	}
+	first, second := outcomes(), outcomes()
+	if first != second {
+		t.Errorf("flaky outcomes differ with the same seed: %s and %s", first, second)
+	}
+	if !strings.Contains(first, "x") || !strings.Contains(first, ".") {
+		t.Errorf("flaky with error_rate 0.5 = %s, want both failures and successes", first)
+	}
+}
+
+func TestMockToolValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		tool mockTool
+		want string
+	}{
+		{"no name", mockTool{}, "missing a name"},
+		{"case without when", mockTool{Name: "t", Cases: []mockCase{{Response: "x"}}}, "has no 'when'"},
+		{"params and schema", mockTool{Name: "t", Params: []mockParam{{Name: "a"}}, InputSchema: map[string]any{"type": "object"}}, "either params or input_schema"},
+		{"schema not an object", mockTool{Name: "t", InputSchema: map[string]any{"type": "string"}}, "type object"},
+		{"unsupported param type", mockTool{Name: "t", Params: []mockParam{{Name: "a", Type: "array"}}}, "unsupported type"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.tool.toMCP(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
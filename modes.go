@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+)
+
+// probeMode is a mode of the probe, selected by setting its flag
+type probeMode struct {
+	// Flag is the name of the flag that selects the mode
+	Flag string
+	// UsesCall is set for modes that take the tool to use from -call, which
+	// is then an option of the mode rather than a mode of its own
+	UsesCall bool
+	// Session is set for modes that run in the probe's own session, which
+	// -strict and -session-id apply to. The others open sessions of their
+	// own, or none.
+	Session bool
+}
+
+// probeModes are the probe's modes, which are mutually exclusive. Every new
+// mode must be listed here so that combining it with another is rejected.
+var probeModes = []probeMode{
+	{Flag: "compare-transports"},
+	{Flag: "baseline-url", UsesCall: true},
+	{Flag: "compare-versions", UsesCall: true},
+	{Flag: "version-matrix"},
+	{Flag: "conformance", UsesCall: true},
+	{Flag: "negative-tests"},
+	{Flag: "raw-batch"},
+	{Flag: "fuzz", UsesCall: true},
+	{Flag: "bench", UsesCall: true},
+	{Flag: "chaos", UsesCall: true},
+	{Flag: "compare-auth", UsesCall: true},
+	{Flag: "reconnect-test", UsesCall: true},
+	{Flag: "resumability", UsesCall: true},
+	{Flag: "session-test"},
+	{Flag: "isolation-test", UsesCall: true},
+	{Flag: "replay"},
+	{Flag: "verify-vectors"},
+	{Flag: "verify-contract"},
+	{Flag: "verify-policy"},
+	{Flag: "runs"},
+	{Flag: "tour", Session: true},
+	{Flag: "list", Session: true},
+	{Flag: "list-only", Session: true},
+	{Flag: "call", Session: true},
+	{Flag: "read-template", Session: true},
+	{Flag: "get-prompt", Session: true},
+	{Flag: "complete", Session: true},
+	{Flag: "raw-method", Session: true},
+	{Flag: "ping", Session: true},
+	{Flag: "subscribe", Session: true},
+	{Flag: "subscribe-all", Session: true},
+	{Flag: "interactive", Session: true},
+	{Flag: "roots-change", Session: true},
+}
+
+// selectProbeMode returns the mode selected by the flags, or nil for the
+// capability test. A mode is selected when its flag differs from the
+// default, and it is an error to select more than one.
+func selectProbeMode(fs *flag.FlagSet) (*probeMode, error) {
+	var selected []*probeMode
+	usesCall := false
+	for i := range probeModes {
+		m := &probeModes[i]
+		f := fs.Lookup(m.Flag)
+		if f == nil {
+			return nil, fmt.Errorf("mode -%s has no flag", m.Flag)
+		}
+		if f.Value.String() == f.DefValue {
+			continue
+		}
+		selected = append(selected, m)
+		usesCall = usesCall || m.UsesCall
+	}
+	// -call names the tool of the modes that use one
+	if usesCall {
+		selected = slices.DeleteFunc(selected, func(m *probeMode) bool { return m.Flag == "call" })
+	}
+	switch len(selected) {
+	case 0:
+		return nil, nil
+	case 1:
+		return selected[0], nil
+	default:
+		return nil, fmt.Errorf("-%s cannot be combined with -%s", selected[0].Flag, selected[1].Flag)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// envTestMain makes the test binary run the probe's main, so that tests can
+// check how it handles a command line
+const envTestMain = "MCPPROBE_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(envTestMain) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runProbe runs the probe's main with the arguments and returns its exit
+// code and combined output
+func runProbe(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), envTestMain+"=1", "HOME="+t.TempDir())
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), string(out)
+	}
+	if err != nil {
+		t.Fatalf("running the probe: %v", err)
+	}
+	return 0, string(out)
+}
+
+// modeCases are the probe's modes as the command line selects them, with
+// whether each takes -call and runs the probe's session. They are kept
+// apart from probeModes, so that the tests check the table against them.
+var modeCases = []struct {
+	args     []string
+	usesCall bool
+	session  bool
+}{
+	{[]string{"-compare-transports"}, false, false},
+	{[]string{"-baseline-url", "http://127.0.0.1:1/mcp"}, true, false},
+	{[]string{"-compare-versions", "all"}, true, false},
+	{[]string{"-version-matrix"}, false, false},
+	{[]string{"-conformance"}, true, false},
+	{[]string{"-negative-tests"}, false, false},
+	{[]string{"-raw-batch", `[{"method":"ping"}]`}, false, false},
+	{[]string{"-fuzz"}, true, false},
+	{[]string{"-bench"}, true, false},
+	{[]string{"-chaos"}, true, false},
+	{[]string{"-compare-auth"}, true, false},
+	{[]string{"-reconnect-test"}, true, false},
+	{[]string{"-resumability"}, true, false},
+	{[]string{"-session-test"}, false, false},
+	{[]string{"-isolation-test"}, true, false},
+	{[]string{"-replay", "session.jsonl"}, false, false},
+	{[]string{"-verify-vectors", "vectors.json"}, false, false},
+	{[]string{"-verify-contract", "contract.yaml"}, false, false},
+	{[]string{"-verify-policy", "policy.yaml"}, false, false},
+	{[]string{"-runs", "2"}, false, false},
+	{[]string{"-tour"}, false, true},
+	{[]string{"-list"}, false, true},
+	{[]string{"-list-only"}, false, true},
+	{[]string{"-call", "echo"}, false, true},
+	{[]string{"-read-template", "file:///{path}"}, false, true},
+	{[]string{"-get-prompt", "greeting"}, false, true},
+	{[]string{"-complete", "prompt:greeting:name:A"}, false, true},
+	{[]string{"-raw-method", "ping"}, false, true},
+	{[]string{"-ping"}, false, true},
+	{[]string{"-subscribe", "file:///log"}, false, true},
+	{[]string{"-subscribe-all"}, false, true},
+	{[]string{"-interactive"}, false, true},
+	{[]string{"-roots-change", "file:///tmp"}, false, true},
+}
+
+func TestSelectProbeMode(t *testing.T) {
+	newFlags := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		for _, m := range probeModes {
+			if m.Flag == "runs" {
+				fs.Int(m.Flag, 1, "")
+			} else {
+				fs.String(m.Flag, "", "")
+			}
+		}
+		return fs
+	}
+	set := func(fs *flag.FlagSet, name string) {
+		value := "x"
+		if name == "runs" {
+			value = "2"
+		}
+		if err := fs.Set(name, value); err != nil {
+			t.Fatalf("setting -%s: %v", name, err)
+		}
+	}
+
+	if m, err := selectProbeMode(newFlags()); m != nil || err != nil {
+		t.Errorf("no mode = %v, %v, want the capability test", m, err)
+	}
+	for _, a := range probeModes {
+		fs := newFlags()
+		set(fs, a.Flag)
+		if m, err := selectProbeMode(fs); err != nil || m == nil || m.Flag != a.Flag {
+			t.Errorf("-%s = %v, %v", a.Flag, m, err)
+		}
+		for _, b := range probeModes {
+			if a.Flag >= b.Flag {
+				continue
+			}
+			fs := newFlags()
+			set(fs, a.Flag)
+			set(fs, b.Flag)
+			m, err := selectProbeMode(fs)
+			switch {
+			case a.Flag == "call" && b.UsesCall, b.Flag == "call" && a.UsesCall:
+				// -call names the mode's tool
+				if err != nil || m == nil || m.Flag == "call" {
+					t.Errorf("-%s -%s = %v, %v, want the mode that uses -call", a.Flag, b.Flag, m, err)
+				}
+			case err == nil:
+				t.Errorf("-%s -%s were accepted together as -%s", a.Flag, b.Flag, m.Flag)
+			}
+		}
+	}
+
+	if _, err := selectProbeMode(flag.NewFlagSet("probe", flag.ContinueOnError)); err == nil {
+		t.Error("a mode without a flag was not reported")
+	}
+}
+
+func TestModeConflicts(t *testing.T) {
+	for _, c := range modeCases {
+		name := strings.TrimPrefix(c.args[0], "-")
+		i := slices.IndexFunc(probeModes, func(m probeMode) bool { return m.Flag == name })
+		if i < 0 {
+			t.Fatalf("-%s is not in probeModes", name)
+		}
+		if m := probeModes[i]; m.UsesCall != c.usesCall || m.Session != c.session {
+			t.Errorf("probeModes has -%s with UsesCall %v and Session %v, want %v and %v", name, m.UsesCall, m.Session, c.usesCall, c.session)
+		}
+	}
+	if len(modeCases) != len(probeModes) {
+		t.Fatalf("modeCases has %d modes, probeModes %d", len(modeCases), len(probeModes))
+	}
+	run := func(args ...string) {
+		t.Helper()
+		code, out := runProbe(t, append([]string{"-no-banner", "-url", "http://127.0.0.1:1/mcp"}, args...)...)
+		if code != 1 || !strings.Contains(out, "Invalid options") {
+			t.Errorf("%s: exit %d, want a rejection; output:\n%s", strings.Join(args, " "), code, out)
+		}
+	}
+
+	// Every pair of modes is rejected before the probe connects, except
+	// -call with a mode that uses it
+	for i, a := range modeCases {
+		for _, b := range modeCases[i+1:] {
+			if a.args[0] == "-call" && b.usesCall || b.args[0] == "-call" && a.usesCall {
+				continue
+			}
+			run(append(slices.Clone(a.args), b.args...)...)
+		}
+	}
+	// -strict only applies to the modes that run the probe's session
+	for _, c := range modeCases {
+		if !c.session {
+			run(append([]string{"-strict"}, c.args...)...)
+		}
+	}
+}
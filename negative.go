@@ -0,0 +1,624 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// negativeRequestBase is the first ID of the negative test messages, clear
+// of the IDs the client assigns and of the other raw requests
+const negativeRequestBase = 8_000_000
+
+// negativePayloadSize is the size of the oversized payload, beyond the
+// message limits servers commonly set
+const negativePayloadSize = 16 << 20
+
+// negativeReplyWait bounds the wait for the answer to a malformed message
+// (or -timeout if shorter); a server that takes longer is hanging
+const negativeReplyWait = 10 * time.Second
+
+// negativeReplyLimit is the most of an HTTP answer that is read
+const negativeReplyLimit = 1 << 20
+
+// negativeWarn is the status of a malformed message the server rejects,
+// but not with the expected error
+const negativeWarn = "warn"
+
+// Categories of the negative tests, in the order they run
+const (
+	negativeUnknownMethods = "unknown methods"
+	negativeMissingParams  = "missing parameters"
+	negativeWrongTypes     = "wrong parameter types"
+	negativeInvalidIDs     = "invalid ids"
+	negativeMalformed      = "malformed messages"
+	negativeOversized      = "oversized payloads"
+)
+
+// negativeCase is a malformed message and the errors a robust server
+// answers it with
+type negativeCase struct {
+	id       string
+	category string
+	// capability is the capability the server must advertise for the
+	// message to make sense, if any
+	capability string
+	// message builds the message with the given request ID
+	message func(id int64) []byte
+	// codes are the JSON-RPC error codes of a correct answer; empty accepts
+	// any error
+	codes []int
+}
+
+// negativeCases are the malformed messages, in the order they are sent
+var negativeCases = []negativeCase{
+	{"unknown-method", negativeUnknownMethods, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"mcpprobe/no-such-method"}`, id)
+	}, []int{mcp.METHOD_NOT_FOUND}},
+	{"unknown-method.with-params", negativeUnknownMethods, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/execute","params":{"name":"x"}}`, id)
+	}, []int{mcp.METHOD_NOT_FOUND}},
+
+	{"missing-params.tools-call", negativeMissingParams, "tools", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/call"}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+	{"missing-params.tool-name", negativeMissingParams, "tools", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"arguments":{}}}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+	{"missing-params.resource-uri", negativeMissingParams, "resources", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"resources/read","params":{}}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+	{"missing-params.prompt-name", negativeMissingParams, "prompts", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"prompts/get","params":{}}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+
+	{"wrong-type.tool-name", negativeWrongTypes, "tools", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":42}}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+	{"wrong-type.tool-arguments", negativeWrongTypes, "tools", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"mcpprobe-no-such-tool","arguments":"not an object"}}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+	{"wrong-type.cursor", negativeWrongTypes, "tools", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/list","params":{"cursor":5}}`, id)
+	}, []int{mcp.INVALID_PARAMS}},
+	{"wrong-type.params", negativeWrongTypes, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"ping","params":"not an object"}`, id)
+	}, []int{mcp.INVALID_REQUEST, mcp.INVALID_PARAMS}},
+
+	{"invalid-id.null", negativeInvalidIDs, "", func(int64) []byte {
+		return []byte(`{"jsonrpc":"2.0","id":null,"method":"ping"}`)
+	}, []int{mcp.INVALID_REQUEST}},
+	{"invalid-id.object", negativeInvalidIDs, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":{"n":%d},"method":"ping"}`, id)
+	}, []int{mcp.INVALID_REQUEST}},
+	{"invalid-id.boolean", negativeInvalidIDs, "", func(int64) []byte {
+		return []byte(`{"jsonrpc":"2.0","id":true,"method":"ping"}`)
+	}, []int{mcp.INVALID_REQUEST}},
+	{"invalid-id.fractional", negativeInvalidIDs, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d.5,"method":"ping"}`, id)
+	}, []int{mcp.INVALID_REQUEST}},
+
+	{"malformed.json", negativeMalformed, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"ping"`, id)
+	}, []int{mcp.PARSE_ERROR}},
+	{"malformed.version", negativeMalformed, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"1.0","id":%d,"method":"ping"}`, id)
+	}, []int{mcp.INVALID_REQUEST}},
+	{"malformed.no-method", negativeMalformed, "", func(id int64) []byte {
+		return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d}`, id)
+	}, []int{mcp.INVALID_REQUEST}},
+	{"malformed.not-object", negativeMalformed, "", func(int64) []byte {
+		return []byte(`42`)
+	}, []int{mcp.INVALID_REQUEST}},
+
+	{"oversized.payload", negativeOversized, "", oversizedMessage, nil},
+}
+
+// oversizedMessage builds a tools/call of an unknown tool with an argument
+// of negativePayloadSize bytes
+func oversizedMessage(id int64) []byte {
+	message, _ := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      id,
+		"method":  "tools/call",
+		"params": map[string]any{
+			"name":      "mcpprobe-no-such-tool",
+			"arguments": map[string]any{"padding": strings.Repeat("A", negativePayloadSize)},
+		},
+	})
+	return message
+}
+
+// negativeReply is the server's answer to a message
+type negativeReply struct {
+	// status is the HTTP status; zero over stdio
+	status int
+	// body is the JSON-RPC response as sent, if any
+	body []byte
+}
+
+// negativeWire carries messages to the server as bytes, so that messages
+// the client library would refuse to send, and answers it could not match
+// to a request, get through
+type negativeWire interface {
+	// exchange sends a message and returns the server's answer to it
+	exchange(ctx context.Context, message []byte) (*negativeReply, error)
+	// notify sends a notification, which has no answer
+	notify(ctx context.Context, message []byte) error
+	// exchangeBatch sends a batch and collects the responses to it, whether
+	// the server sends them as one array or one by one, until want have
+	// arrived
+	exchangeBatch(ctx context.Context, message []byte, want int) (*batchReply, error)
+	// setProtocolVersion records the negotiated protocol version
+	setProtocolVersion(version string)
+	close()
+}
+
+// runNegativeTests sends each malformed message to the server and checks
+// that it answers with a JSON-RPC error rather than a result, silence or a
+// crash. After each message the server must still answer a ping. An error
+// is returned if a finding counts as an error.
+func runNegativeTests(open func() (negativeWire, error), timeout time.Duration) error {
+	fmt.Println("=== Negative Tests ===")
+	wait := min(timeout, negativeReplyWait)
+
+	wire, err := open()
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer wire.close()
+	caps, _, err := initializeNegativeWire(wire, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	var failed []string
+	counts := map[string]int{}
+	category := ""
+	record := func(c negativeCase, start time.Time, status, detail string) {
+		if c.category != category {
+			category = c.category
+			fmt.Printf("\n%s%s\n", strings.ToUpper(category[:1]), category[1:])
+		}
+		counts[status]++
+		line := fmt.Sprintf("  %-4s  %-28s  %s", strings.ToUpper(status), c.id, detail)
+		fmt.Println(strings.TrimRight(line, " "))
+		if status == checkFail || status == negativeWarn {
+			checkID := checkIDNegativeMishandled
+			if status == negativeWarn {
+				checkID = checkIDNegativeErrorCode
+			}
+			f := report.addFinding(checkID, c.id, "%s: %s", c.id, detail)
+			fmt.Printf("        %s\n", f)
+			if f.fails() {
+				failed = append(failed, c.id)
+			}
+		}
+		emitEvent(eventCheck, map[string]any{
+			"id":         c.id,
+			"category":   c.category,
+			"status":     status,
+			"detail":     detail,
+			"durationMs": durationMillis(time.Since(start)),
+		})
+	}
+
+	stopped := ""
+	for i, c := range negativeCases {
+		start := time.Now()
+		if stopped != "" {
+			record(c, start, checkSkip, fmt.Sprintf("the server stopped answering after %s", stopped))
+			continue
+		}
+		if _, ok := caps[c.capability]; c.capability != "" && !ok {
+			record(c, start, checkSkip, fmt.Sprintf("the server does not advertise %s", c.capability))
+			continue
+		}
+
+		id := int64(negativeRequestBase + 2*i + 1)
+		ctx, cancel := context.WithTimeout(context.Background(), wait)
+		reply, err := wire.exchange(ctx, c.message(id))
+		cancel()
+		var status, detail string
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			status, detail = checkFail, fmt.Sprintf("no answer within %s", humanDuration(wait))
+		case err != nil:
+			// The connection is gone or the server exited
+			record(c, start, checkFail, err.Error())
+			stopped = c.id
+			continue
+		default:
+			status, detail = judgeNegativeReply(reply, c.codes)
+		}
+
+		if err := pingNegativeWire(wire, id+1, wait); err != nil {
+			status, detail = checkFail, fmt.Sprintf("%s, then the server stopped answering: %v", detail, err)
+			stopped = c.id
+		}
+		record(c, start, status, detail)
+	}
+
+	fmt.Printf("\nSummary: %d passed, %d failed, %d with unexpected errors, %d skipped\n", counts[checkPass], counts[checkFail], counts[negativeWarn], counts[checkSkip])
+	if len(failed) > 0 {
+		return fmt.Errorf("negative tests failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// initializeNegativeWire completes the handshake on the wire and returns
+// the server's capabilities and the negotiated protocol version. The probe
+// advertises no capabilities of its own, so that the server has no reason
+// to send requests.
+func initializeNegativeWire(wire negativeWire, timeout time.Duration) (map[string]json.RawMessage, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	params := newInitializeRequest().Params
+	params.Capabilities = mcp.ClientCapabilities{}
+	message, err := json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": negativeRequestBase, "method": mcp.MethodInitialize, "params": params})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode the initialize request: %w", err)
+	}
+	reply, err := wire.exchange(ctx, message)
+	if err != nil {
+		return nil, "", err
+	}
+	var response struct {
+		Result *struct {
+			ProtocolVersion string                     `json:"protocolVersion"`
+			Capabilities    map[string]json.RawMessage `json:"capabilities"`
+		} `json:"result"`
+		Error *mcp.JSONRPCErrorDetails `json:"error"`
+	}
+	if err := json.Unmarshal(reply.body, &response); err != nil {
+		return nil, "", fmt.Errorf("the answer is not a JSON-RPC response: %w", err)
+	}
+	if response.Error != nil {
+		return nil, "", &rpcError{Code: response.Error.Code, Message: response.Error.Message}
+	}
+	if response.Result == nil {
+		return nil, "", fmt.Errorf("the answer has no result")
+	}
+	wire.setProtocolVersion(response.Result.ProtocolVersion)
+	if err := wire.notify(ctx, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); err != nil {
+		return nil, "", fmt.Errorf("failed to send notifications/initialized: %w", err)
+	}
+	return response.Result.Capabilities, response.Result.ProtocolVersion, nil
+}
+
+// pingNegativeWire returns an error unless the server answers a ping
+func pingNegativeWire(wire negativeWire, id int64, wait time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	reply, err := wire.exchange(ctx, fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"ping"}`, id))
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("no answer to ping within %s", humanDuration(wait))
+	}
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Result json.RawMessage          `json:"result"`
+		Error  *mcp.JSONRPCErrorDetails `json:"error"`
+	}
+	if err := json.Unmarshal(reply.body, &response); err != nil {
+		return fmt.Errorf("the answer to ping is not a JSON-RPC response (HTTP %d)", reply.status)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("ping failed: %w", &rpcError{Code: response.Error.Code, Message: response.Error.Message})
+	}
+	return nil
+}
+
+// judgeNegativeReply decides whether an answer to a malformed message is the
+// JSON-RPC error a robust server sends
+func judgeNegativeReply(reply *negativeReply, codes []int) (string, string) {
+	if reply.status >= 500 {
+		return checkFail, fmt.Sprintf("HTTP %d: the server failed on the message", reply.status)
+	}
+	if len(bytes.TrimSpace(reply.body)) == 0 {
+		switch {
+		case reply.status == http.StatusRequestEntityTooLarge:
+			return checkPass, "rejected with HTTP 413 (content too large)"
+		case reply.status >= 400:
+			return negativeWarn, fmt.Sprintf("rejected with HTTP %d but no JSON-RPC error", reply.status)
+		default:
+			return checkFail, fmt.Sprintf("accepted with HTTP %d and no answer", reply.status)
+		}
+	}
+
+	var response struct {
+		Result json.RawMessage          `json:"result"`
+		Error  *mcp.JSONRPCErrorDetails `json:"error"`
+	}
+	if err := json.Unmarshal(reply.body, &response); err != nil {
+		if reply.status >= 400 {
+			return negativeWarn, fmt.Sprintf("rejected with HTTP %d but the body is not a JSON-RPC error", reply.status)
+		}
+		return checkFail, fmt.Sprintf("the answer is not a JSON-RPC response: %s", truncateText(string(reply.body), 80))
+	}
+	switch {
+	case response.Error == nil && response.Result != nil:
+		return checkFail, "accepted: answered with a result instead of an error"
+	case response.Error == nil:
+		return checkFail, "the answer has neither a result nor an error"
+	case len(codes) == 0 || slices.Contains(codes, response.Error.Code):
+		return checkPass, fmt.Sprintf("error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	want := make([]string, len(codes))
+	for i, code := range codes {
+		want[i] = fmt.Sprint(code)
+	}
+	return negativeWarn, fmt.Sprintf("error %d: %s (expected %s)", response.Error.Code, response.Error.Message, strings.Join(want, " or "))
+}
+
+// isJSONRPCResponse reports whether a message is a response rather than a
+// request or notification from the server
+func isJSONRPCResponse(message []byte) bool {
+	var m struct {
+		Method string          `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(message, &m) != nil {
+		// Answer with what the server sent; judging it is up to the caller
+		return true
+	}
+	return m.Method == "" && (m.Result != nil || m.Error != nil)
+}
+
+// httpNegativeWire sends each message as a POST to a streamable HTTP server
+type httpNegativeWire struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	oauth   *transport.OAuthConfig
+	session string
+	version string
+}
+
+// newHTTPNegativeWire creates the wire to a streamable HTTP server, with the
+// probe's headers and OAuth token
+func newHTTPNegativeWire(serverURL string, headers map[string]string, oauth *transport.OAuthConfig, timeout, acceptTimeout time.Duration) *httpNegativeWire {
+	return &httpNegativeWire{
+		client:  withOAuthRetry(newProbeHTTPClient(timeout, acceptTimeout), oauth),
+		url:     serverURL,
+		headers: headers,
+		oauth:   oauth,
+	}
+}
+
+// post sends a message and returns the HTTP response
+func (w *httpNegativeWire) post(ctx context.Context, message []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	if w.oauth != nil {
+		if token, err := w.oauth.TokenStore.GetToken(ctx); err == nil {
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		}
+	}
+	if w.session != "" {
+		req.Header.Set(mcpSessionHeader, w.session)
+	}
+	if w.version != "" {
+		req.Header.Set("MCP-Protocol-Version", w.version)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if session := resp.Header.Get(mcpSessionHeader); session != "" && w.session == "" {
+		w.session = session
+	}
+	return resp, nil
+}
+
+func (w *httpNegativeWire) exchange(ctx context.Context, message []byte) (*negativeReply, error) {
+	resp, err := w.post(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	reply := &negativeReply{status: resp.StatusCode}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		reply.body, err = io.ReadAll(io.LimitReader(resp.Body, negativeReplyLimit))
+		return reply, err
+	}
+
+	// The answer is the first response on the stream
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), negativeReplyLimit)
+	var lines []string
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		if data := eventData(lines); len(data) > 0 && isJSONRPCResponse(data) {
+			reply.body = data
+			return reply, nil
+		}
+		lines = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	reply.body = eventData(lines)
+	return reply, nil
+}
+
+func (w *httpNegativeWire) notify(ctx context.Context, message []byte) error {
+	resp, err := w.post(ctx, message)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *httpNegativeWire) setProtocolVersion(version string) {
+	w.version = version
+}
+
+func (w *httpNegativeWire) close() {
+	if w.session == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, w.url, nil)
+	if err != nil {
+		return
+	}
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set(mcpSessionHeader, w.session)
+	if resp, err := w.client.Do(req); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// stdioNegativeWire writes each message as a line to a stdio server it
+// started
+type stdioNegativeWire struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// lines are the server's messages; closed when it exits
+	lines   chan []byte
+	exited  chan struct{}
+	waitErr error
+}
+
+// startStdioNegativeWire starts the stdio server for the negative tests.
+// Its standard error is discarded.
+func startStdioNegativeWire(command, argsStr, envStr string) (*stdioNegativeWire, error) {
+	args, env := parseStdioOptions(argsStr, envStr)
+	cmd, err := stdioCommand(context.Background(), command, env, args)
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start subprocess: %w", err)
+	}
+
+	w := &stdioNegativeWire{cmd: cmd, stdin: stdin, lines: make(chan []byte, 16), exited: make(chan struct{})}
+	go func() {
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				w.lines <- line
+			}
+			if err != nil {
+				break
+			}
+		}
+		w.waitErr = cmd.Wait()
+		close(w.lines)
+		close(w.exited)
+	}()
+	return w, nil
+}
+
+func (w *stdioNegativeWire) exchange(ctx context.Context, message []byte) (*negativeReply, error) {
+	// Discard late answers to earlier messages
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-w.lines:
+			if !ok {
+				return nil, w.exitError()
+			}
+		default:
+			drained = true
+		}
+	}
+
+	// The write runs on its own, so that a server that stops reading hangs
+	// the exchange only until the deadline
+	written := make(chan error, 1)
+	go func() {
+		_, err := w.stdin.Write(append(message, '\n'))
+		written <- err
+	}()
+	for {
+		select {
+		case err := <-written:
+			if err != nil {
+				return nil, fmt.Errorf("failed to write to the server: %w", err)
+			}
+			written = nil
+		case line, ok := <-w.lines:
+			if !ok {
+				return nil, w.exitError()
+			}
+			// Skip the server's own requests and notifications
+			if isJSONRPCResponse(line) {
+				return &negativeReply{body: line}, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// exitError describes the exit of the server
+func (w *stdioNegativeWire) exitError() error {
+	if w.waitErr != nil {
+		return fmt.Errorf("the server exited: %w", w.waitErr)
+	}
+	return fmt.Errorf("the server exited")
+}
+
+func (w *stdioNegativeWire) notify(ctx context.Context, message []byte) error {
+	if _, err := w.stdin.Write(append(message, '\n')); err != nil {
+		return fmt.Errorf("failed to write to the server: %w", err)
+	}
+	return nil
+}
+
+func (w *stdioNegativeWire) setProtocolVersion(string) {}
+
+func (w *stdioNegativeWire) close() {
+	_ = w.stdin.Close()
+	select {
+	case <-w.exited:
+	case <-time.After(2 * time.Second):
+		_ = w.cmd.Process.Kill()
+	}
+}
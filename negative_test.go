@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// mockNegativeVerdicts are the verdicts of the negative tests against the
+// mock server, among them all that do not pass
+var mockNegativeVerdicts = map[string]string{
+	"unknown-method":              "PASS",
+	"malformed.json":              "PASS",
+	"oversized.payload":           "PASS",
+	"missing-params.resource-uri": "WARN",
+	"wrong-type.tool-name":        "WARN",
+	"wrong-type.cursor":           "WARN",
+	"malformed.not-object":        "WARN",
+	"invalid-id.null":             "FAIL",
+	"invalid-id.object":           "FAIL",
+	"invalid-id.boolean":          "FAIL",
+	"invalid-id.fractional":       "FAIL",
+	"malformed.no-method":         "FAIL",
+}
+
+// withVerdicts returns the verdicts with some replaced
+func withVerdicts(base map[string]string, changes map[string]string) map[string]string {
+	verdicts := maps.Clone(base)
+	maps.Copy(verdicts, changes)
+	return verdicts
+}
+
+func TestNegativeTests(t *testing.T) {
+	suppressions := filepath.Join(t.TempDir(), "suppressions.yaml")
+	if err := os.WriteFile(suppressions, []byte("suppressions:\n  - check: C028\n    reason: the mock server accepts these ids\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	crash := interceptMethod("tools/execute", func(w http.ResponseWriter, _ json.RawMessage) {
+		http.Error(w, "crashed", http.StatusInternalServerError)
+	})
+	// stop fails every request once the server has seen tools/execute
+	stop := func(next http.Handler) http.Handler {
+		var stopped atomic.Bool
+		handler := interceptMethod("tools/execute", func(w http.ResponseWriter, _ json.RawMessage) {
+			stopped.Store(true)
+			http.Error(w, "crashed", http.StatusInternalServerError)
+		})(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if stopped.Load() {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+	skipped := map[string]string{}
+	for _, c := range negativeCases[2:] {
+		skipped[c.id] = "SKIP"
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wrap     func(http.Handler) http.Handler
+		exitCode int
+		want     map[string]string
+		output   string
+	}{
+		{
+			name:     "mock server",
+			exitCode: 1,
+			want:     mockNegativeVerdicts,
+			output:   "Summary: 10 passed, 5 failed, 4 with unexpected errors, 0 skipped",
+		},
+		{
+			name:   "failures suppressed",
+			args:   []string{"-suppressions", suppressions},
+			want:   mockNegativeVerdicts,
+			output: "5 finding(s) suppressed",
+		},
+		{
+			name:     "warnings at -fail-level warning",
+			args:     []string{"-suppressions", suppressions, "-fail-level", "warning"},
+			exitCode: 1,
+			want:     mockNegativeVerdicts,
+			output:   "negative tests failed: missing-params.resource-uri, wrong-type.tool-name, wrong-type.cursor, malformed.not-object",
+		},
+		{
+			name:     "server error",
+			wrap:     crash,
+			exitCode: 1,
+			want:     withVerdicts(mockNegativeVerdicts, map[string]string{"unknown-method.with-params": "FAIL"}),
+			output:   "HTTP 500: the server failed on the message",
+		},
+		{
+			name:     "server stops answering",
+			wrap:     stop,
+			exitCode: 1,
+			want:     withVerdicts(skipped, map[string]string{"unknown-method": "PASS", "unknown-method.with-params": "FAIL"}),
+			output:   "then the server stopped answering",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverURL := serveMock(t, defaultMockConfig, tt.wrap)
+			code, output := runProbe(t, append([]string{"-url", serverURL, "-negative-tests"}, tt.args...)...)
+			if code != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", code, tt.exitCode)
+			}
+			if !strings.Contains(output, tt.output) {
+				t.Errorf("the output does not contain %q", tt.output)
+			}
+			checkVerdicts(t, output, tt.want, "FAIL", "WARN", "SKIP")
+		})
+	}
+}
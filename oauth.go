@@ -0,0 +1,342 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	// oauthFlowTimeout bounds how long to wait for the user to authorize in the browser
+	oauthFlowTimeout = 5 * time.Minute
+	// oauthTokenTimeout bounds discovery and the token request for the client credentials grant
+	oauthTokenTimeout = 60 * time.Second
+)
+
+// oauthOptions are the command line settings for the OAuth flow
+type oauthOptions struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	scopes       []string
+	port         int
+	cache        bool
+}
+
+// oauthCallback is the result delivered to the localhost redirect listener
+type oauthCallback struct {
+	code  string
+	state string
+	err   error
+}
+
+// authorizeOAuth performs the OAuth 2.1 authorization code flow with PKCE
+// against the server: authorization server discovery, dynamic client
+// registration (unless a client ID is given), browser authorization and the
+// code exchange. The returned config holds the token and is passed to the
+// transport, which adds the bearer token to every request and refreshes it.
+func authorizeOAuth(serverURL string, opts oauthOptions) (*transport.OAuthConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oauthFlowTimeout)
+	defer cancel()
+
+	store := newOAuthTokenStore(serverURL, opts.clientID, opts.scopes, opts.cache)
+	if entry, ok := store.cached(); ok {
+		config := transport.OAuthConfig{
+			ClientID:     entry.ClientID,
+			ClientSecret: entry.ClientSecret,
+			Scopes:       opts.scopes,
+			TokenStore:   store,
+			PKCEEnabled:  true,
+			HTTPClient:   newProbeHTTPClient(oauthTokenTimeout, 0),
+		}
+		store.refresh = refreshWith(config, serverURL)
+		if token, err := store.GetToken(ctx); err == nil && !token.IsExpired() {
+			fmt.Println("Using cached token")
+			fmt.Println()
+			return &config, nil
+		}
+		fmt.Println("Cached token has expired; authorizing again")
+		store.clear()
+	}
+
+	// Listen first so the redirect URI (and its port) is known for registration
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", opts.port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback listener: %w", err)
+	}
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	config := transport.OAuthConfig{
+		ClientID:    opts.clientID,
+		RedirectURI: redirectURI,
+		Scopes:      opts.scopes,
+		TokenStore:  store,
+		PKCEEnabled: true,
+		HTTPClient:  newProbeHTTPClient(oauthTokenTimeout, 0),
+	}
+	handler := transport.NewOAuthHandler(config)
+	handler.SetBaseURL(oauthBaseURL(serverURL))
+
+	metadata, err := handler.GetServerMetadata(ctx)
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("authorization server discovery failed: %w", err)
+	}
+	fmt.Printf("Authorization server: %s\n", metadata.Issuer)
+
+	if config.ClientID == "" {
+		fmt.Println("Registering client...")
+		if err := handler.RegisterClient(ctx, ProgName); err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("dynamic client registration failed (use -oauth-client-id with a pre-registered client): %w", err)
+		}
+		config.ClientID = handler.GetClientID()
+		config.ClientSecret = handler.GetClientSecret()
+		fmt.Printf("Registered client ID: %s\n", config.ClientID)
+	}
+
+	codeVerifier, err := transport.GenerateCodeVerifier()
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to generate PKCE verifier: %w", err)
+	}
+	state, err := transport.GenerateState()
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	authURL, err := handler.GetAuthorizationURL(ctx, state, transport.GenerateCodeChallenge(codeVerifier))
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to build authorization URL: %w", err)
+	}
+
+	callbacks := make(chan oauthCallback, 1)
+	server := &http.Server{Handler: oauthCallbackHandler(callbacks), ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	defer func() { _ = server.Close() }()
+
+	fmt.Printf("\nOpen this URL to authorize %s:\n  %s\n\n", ProgName, authURL)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Printf("(Could not open a browser automatically: %v)\n", err)
+	}
+	fmt.Printf("Waiting for authorization (redirect to %s)...\n", redirectURI)
+
+	var result oauthCallback
+	select {
+	case result = <-callbacks:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out after %s waiting for authorization", oauthFlowTimeout)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	store.setClient(config.ClientID, config.ClientSecret, "")
+	if err := handler.ProcessAuthorizationResponse(ctx, result.code, result.state, codeVerifier); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	store.refresh = refreshWith(config, serverURL)
+	fmt.Println("Authorization completed successfully")
+	fmt.Println()
+	return &config, nil
+}
+
+// refreshWith returns a refresh function that uses the token's refresh token
+func refreshWith(config transport.OAuthConfig, serverURL string) func(context.Context, *transport.Token) (*transport.Token, error) {
+	handler := transport.NewOAuthHandler(config)
+	handler.SetBaseURL(oauthBaseURL(serverURL))
+	return func(ctx context.Context, token *transport.Token) (*transport.Token, error) {
+		if token.RefreshToken == "" {
+			return nil, errors.New("no refresh token")
+		}
+		return handler.RefreshToken(ctx, token.RefreshToken)
+	}
+}
+
+// clientCredentialsOAuth obtains a token with the OAuth client credentials
+// grant, for headless environments such as CI where no browser is available.
+// The token endpoint is discovered from the server unless opts.tokenURL is set.
+func clientCredentialsOAuth(serverURL string, opts oauthOptions) (*transport.OAuthConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oauthTokenTimeout)
+	defer cancel()
+
+	store := newOAuthTokenStore(serverURL, opts.clientID, opts.scopes, opts.cache)
+	config := &transport.OAuthConfig{
+		ClientID:     opts.clientID,
+		ClientSecret: opts.clientSecret,
+		Scopes:       opts.scopes,
+		TokenStore:   store,
+		HTTPClient:   newProbeHTTPClient(oauthTokenTimeout, 0),
+	}
+
+	tokenURL := opts.tokenURL
+	if entry, ok := store.cached(); ok && (tokenURL == "" || tokenURL == entry.TokenURL) {
+		tokenURL = entry.TokenURL
+		store.refresh = func(ctx context.Context, _ *transport.Token) (*transport.Token, error) {
+			return requestClientCredentials(ctx, tokenURL, opts)
+		}
+		if token, err := store.GetToken(ctx); err == nil && !token.IsExpired() {
+			fmt.Println("Using cached token")
+			fmt.Println()
+			return config, nil
+		}
+		store.clear()
+	}
+
+	if tokenURL == "" {
+		handler := transport.NewOAuthHandler(transport.OAuthConfig{ClientID: opts.clientID, HTTPClient: newProbeHTTPClient(oauthTokenTimeout, 0)})
+		handler.SetBaseURL(oauthBaseURL(serverURL))
+		metadata, err := handler.GetServerMetadata(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("authorization server discovery failed (use -oauth-token-url): %w", err)
+		}
+		fmt.Printf("Authorization server: %s\n", metadata.Issuer)
+		tokenURL = metadata.TokenEndpoint
+	}
+	fmt.Printf("Token endpoint: %s\n", tokenURL)
+
+	token, err := requestClientCredentials(ctx, tokenURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	// The secret is supplied on every run, so it is not cached
+	store.setClient(opts.clientID, "", tokenURL)
+	if err := store.SaveToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+	// Client credentials tokens rarely come with a refresh token; repeat the grant instead
+	store.refresh = func(ctx context.Context, _ *transport.Token) (*transport.Token, error) {
+		return requestClientCredentials(ctx, tokenURL, opts)
+	}
+
+	if token.ExpiresIn > 0 {
+		fmt.Printf("Token acquired (expires in %s)\n", time.Duration(token.ExpiresIn)*time.Second)
+	} else {
+		fmt.Println("Token acquired")
+	}
+	fmt.Println()
+	return config, nil
+}
+
+// requestClientCredentials performs the client credentials token request
+func requestClientCredentials(ctx context.Context, tokenURL string, opts oauthOptions) (*transport.Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(opts.scopes) > 0 {
+		form.Set("scope", strings.Join(opts.scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// Client credentials are form-encoded before being used for basic auth (RFC 6749 section 2.3.1)
+	req.SetBasicAuth(url.QueryEscape(opts.clientID), url.QueryEscape(opts.clientSecret))
+
+	resp, err := newProbeHTTPClient(oauthTokenTimeout, 0).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr transport.OAuthError
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.ErrorCode != "" {
+			return nil, fmt.Errorf("token request failed: %w", oauthErr)
+		}
+		return nil, fmt.Errorf("token request failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var token transport.Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token response did not include an access token")
+	}
+	if token.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// oauthCallbackHandler receives the authorization server's redirect
+func oauthCallbackHandler(callbacks chan<- oauthCallback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		result := oauthCallback{code: query.Get("code"), state: query.Get("state")}
+		if errCode := query.Get("error"); errCode != "" {
+			result.err = fmt.Errorf("authorization denied: %s %s", errCode, query.Get("error_description"))
+		} else if result.code == "" {
+			result.err = errors.New("authorization response did not include a code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "<html><body><h2>%s authorization failed</h2><p>%s</p></body></html>",
+				ProgName, html.EscapeString(result.err.Error()))
+		} else {
+			_, _ = fmt.Fprintf(w, "<html><body><h2>%s authorization complete</h2><p>You can close this window.</p></body></html>", ProgName)
+		}
+
+		select {
+		case callbacks <- result:
+		default:
+		}
+	})
+	return mux
+}
+
+// oauthBaseURL returns the server URL used for metadata discovery
+func oauthBaseURL(serverURL string) string {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return serverURL
+	}
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+// parseScopes splits a comma or space separated list of scopes
+func parseScopes(scopes string) []string {
+	return strings.FieldsFunc(scopes, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// openBrowser opens a URL in the user's default browser
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
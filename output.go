@@ -0,0 +1,288 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Output formats supported by the -output flag
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// outputFormat is the format selected with -output
+var outputFormat = outputText
+
+// resultOut receives machine-consumable output such as -result-only content.
+// It keeps pointing at the real stdout even when informational output is
+// suppressed by redirecting os.Stdout.
+var resultOut io.Writer = os.Stdout
+
+// quiet is set by -q: informational output is discarded, so that stdout
+// carries only machine-readable output
+var quiet bool
+
+// showBanner is cleared by -no-banner to omit the startup banner
+var showBanner = true
+
+// printBanner prints the startup banner unless -no-banner is set
+func printBanner() {
+	if showBanner {
+		fmt.Printf("=== MCP Server Test Tool ===\n")
+		fmt.Printf("Started %s\n", time.Time(report.StartedAt).Local().Format(consoleDateLayout))
+	}
+}
+
+// printFinished marks the end of a run with the local time and how long the
+// run took, for matching the output to server logs. With -timings it is
+// preceded by the timing of each request.
+func printFinished() {
+	if showTimings {
+		printTimingSummary()
+	}
+	started := time.Time(report.StartedAt)
+	fmt.Println("\n=== Finished ===")
+	fmt.Printf("Finished at %s after %s\n", consoleClock(time.Now()), humanDuration(time.Since(started)))
+}
+
+// writeJSONReport writes the run report to resultOut as a single JSON
+// document. It is used with -q -output json.
+func writeJSONReport() {
+	report.finish()
+	if err := renderReport(resultOut, reportJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write JSON report: %v\n", err)
+	}
+}
+
+// validateOutputFormat checks that the requested -output format is supported
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputNDJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format '%s' (use 'text', 'json' or 'ndjson')", format)
+	}
+}
+
+// infoSuppressed is set once informational output is discarded, so that
+// errors ending the run are written to stderr instead
+var infoSuppressed bool
+
+// suppressInfoOutput discards informational output written to os.Stdout.
+// Results must be written to resultOut to remain visible.
+func suppressInfoOutput() error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	resultOut = os.Stdout
+	os.Stdout = devNull
+	infoSuppressed = true
+	return nil
+}
+
+// redirectInfoToStderr sends informational output to stderr so that stdout
+// carries only machine-readable output written to resultOut
+func redirectInfoToStderr() {
+	resultOut = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// exitHooks are run before the process exits so that buffered output
+// (for example a -tee file) is flushed and closed properly
+var (
+	exitHooks   []func()
+	exitHooksMu sync.Mutex
+)
+
+// addExitHook registers a function to run before the program exits
+func addExitHook(hook func()) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// runExitHooks runs registered exit hooks in reverse order of registration
+func runExitHooks() {
+	exitHooksMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitHooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// exitProgram runs exit hooks and terminates the process with the given code
+func exitProgram(code int) {
+	runExitHooks()
+	os.Exit(code)
+}
+
+// failRun reports an error that ends the run and exits with status 1. With
+// -q or -result-only the error goes to stderr, since stdout is discarded.
+func failRun(err error) {
+	report.addError("%v", err)
+	if infoSuppressed {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	} else {
+		fmt.Printf("\n%v\n", err)
+	}
+	exitProgram(1)
+}
+
+// fatalf logs a message and exits with status 1, flushing output first
+func fatalf(format string, v ...any) {
+	log.Printf(format, v...)
+	report.addError(format, v...)
+	exitProgram(1)
+}
+
+// consoleTee duplicates everything written to stdout and stderr into a file.
+// The terminal receives the output unchanged, while ANSI escape sequences are
+// stripped from the copy written to the file.
+type consoleTee struct {
+	file     *os.File
+	mu       sync.Mutex
+	stripper *ansiStripper
+	wg       sync.WaitGroup
+	restore  []func()
+}
+
+// startTee redirects stdout and stderr through pipes that copy to the given file
+func startTee(path string) (*consoleTee, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tee file: %w", err)
+	}
+
+	t := &consoleTee{file: file, stripper: &ansiStripper{w: file}}
+	if err := t.capture(&os.Stdout); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	if err := t.capture(&os.Stderr); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+
+	// The log package captured the original stderr at init time
+	log.SetOutput(os.Stderr)
+
+	return t, nil
+}
+
+// capture replaces *stream with a pipe and copies everything written to it
+// to both the original stream and the tee file
+func (t *consoleTee) capture(stream **os.File) error {
+	orig := *stream
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create tee pipe: %w", err)
+	}
+	*stream = w
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				_, _ = orig.Write(buf[:n])
+				t.mu.Lock()
+				_, _ = t.stripper.Write(buf[:n])
+				t.mu.Unlock()
+			}
+			if err != nil {
+				_ = r.Close()
+				return
+			}
+		}
+	}()
+
+	t.restore = append(t.restore, func() {
+		*stream = orig
+		_ = w.Close()
+	})
+	return nil
+}
+
+// Close restores the original streams, waits for pending output and closes the file
+func (t *consoleTee) Close() error {
+	for _, restore := range t.restore {
+		restore()
+	}
+	t.restore = nil
+	t.wg.Wait()
+	log.SetOutput(os.Stderr)
+	return t.file.Close()
+}
+
+// ansiStripper is an io.Writer that removes ANSI escape sequences (CSI and OSC)
+// from the data passed through it. State is kept between writes so sequences
+// split across writes are handled correctly.
+type ansiStripper struct {
+	w     io.Writer
+	state int
+}
+
+const (
+	ansiNormal = iota
+	ansiEscape
+	ansiCSI
+	ansiOSC
+	ansiOSCEscape
+)
+
+func (a *ansiStripper) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch a.state {
+		case ansiNormal:
+			if b == 0x1b {
+				a.state = ansiEscape
+			} else {
+				out = append(out, b)
+			}
+		case ansiEscape:
+			switch b {
+			case '[':
+				a.state = ansiCSI
+			case ']':
+				a.state = ansiOSC
+			default:
+				// Two-byte escape sequence
+				a.state = ansiNormal
+			}
+		case ansiCSI:
+			// CSI sequences end with a byte in the range 0x40-0x7E
+			if b >= 0x40 && b <= 0x7e {
+				a.state = ansiNormal
+			}
+		case ansiOSC:
+			// OSC sequences end with BEL or ESC \
+			if b == 0x07 {
+				a.state = ansiNormal
+			} else if b == 0x1b {
+				a.state = ansiOSCEscape
+			}
+		case ansiOSCEscape:
+			a.state = ansiNormal
+		}
+	}
+	if _, err := a.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
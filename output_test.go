@@ -0,0 +1,103 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ansiCases are output with ANSI escape sequences and its plain text
+var ansiCases = []struct {
+	name  string
+	input string
+	want  string
+}{
+	{"plain", "✓ no sequences\n", "✓ no sequences\n"},
+	{"color", "\x1b[1;31mred\x1b[0m and \x1b[32mgreen\x1b[m\n", "red and green\n"},
+	{"private mode", "\x1b[?25lhidden cursor\x1b[?25h", "hidden cursor"},
+	{"line erase", "\x1b[2K\rprogress 50%", "\rprogress 50%"},
+	{"two-byte escape", "\x1b=keypad\x1b>", "keypad"},
+	{"title with BEL", "\x1b]0;MCPProbe\x07title set", "title set"},
+	{"hyperlink", "see \x1b]8;;https://example.com\x1b\\the docs\x1b]8;;\x1b\\.", "see the docs."},
+	{"sequences only", "\x1b[0m\x1b]8;;\x1b\\", ""},
+}
+
+func TestANSIStripper(t *testing.T) {
+	for _, tt := range ansiCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			a := &ansiStripper{w: &out}
+			if n, err := a.Write([]byte(tt.input)); err != nil || n != len(tt.input) {
+				t.Fatalf("Write = %d, %v, want %d", n, err, len(tt.input))
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("one write = %q, want %q", got, tt.want)
+			}
+
+			// A sequence split across writes is stripped all the same
+			for i := 1; i < len(tt.input); i++ {
+				out.Reset()
+				a := &ansiStripper{w: &out}
+				_, _ = a.Write([]byte(tt.input[:i]))
+				_, _ = a.Write([]byte(tt.input[i:]))
+				if got := out.String(); got != tt.want {
+					t.Errorf("split at %d = %q, want %q", i, got, tt.want)
+				}
+			}
+
+			out.Reset()
+			a = &ansiStripper{w: &out}
+			for i := range len(tt.input) {
+				_, _ = a.Write([]byte{tt.input[i]})
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("byte by byte = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeeFile(t *testing.T) {
+	// The console output goes nowhere; only the tee file is checked
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = devNull.Close() }()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		log.SetOutput(os.Stderr)
+	}()
+
+	path := filepath.Join(t.TempDir(), "probe.log")
+	tee, err := startTee(path)
+	if err != nil {
+		t.Fatalf("startTee: %v", err)
+	}
+	var want bytes.Buffer
+	for _, c := range ansiCases {
+		fmt.Fprint(os.Stdout, c.input)
+		want.WriteString(c.want)
+	}
+	if err := tee.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("tee file = %q, want %q", got, want.Bytes())
+	}
+	if bytes.IndexByte(got, 0x1b) >= 0 {
+		t.Errorf("the tee file has escape sequences: %q", got)
+	}
+}
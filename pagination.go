@@ -0,0 +1,267 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// listRequestBase is the first ID of the raw list requests, clear of the IDs
+// the client assigns and of the other raw requests
+const listRequestBase = 4_000_000
+
+// defaultMaxPages is the default of -max-pages
+const defaultMaxPages = 100
+
+// maxListPages is the most pages a listing follows, set by -max-pages.
+// Zero follows cursors until the server stops returning them.
+var maxListPages = defaultMaxPages
+
+// listRequests numbers the raw list requests
+var listRequests atomic.Int64
+
+// listPagination is how a listing was paged
+type listPagination struct {
+	Method    string `json:"method"`
+	Pages     int    `json:"pages"`
+	Items     int    `json:"items"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// listPage is a page of a list result as sent by the server
+type listPage struct {
+	Items      []json.RawMessage
+	NextCursor string
+}
+
+// requestListPage sends one raw list request. The requests are sent raw so
+// that the cursors can be checked and the pages counted.
+func requestListPage(ctx context.Context, mcpClient *client.Client, method mcp.MCPMethod, itemsKey, cursor string) (*listPage, error) {
+	params := map[string]any{}
+	if cursor != "" {
+		params["cursor"] = cursor
+	}
+	body, _ := json.Marshal(params)
+	request := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(listRequestBase + listRequests.Add(1)),
+		Method:  string(method),
+		Params:  json.RawMessage(body),
+	}
+	response, err := mcpClient.GetTransport().SendRequest(ctx, request)
+	if err == nil && response.Error != nil {
+		err = &rpcError{Code: response.Error.Code, Message: response.Error.Message}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(response.Result, &raw); err != nil {
+		return nil, fmt.Errorf("invalid response to %s: %w", method, err)
+	}
+	page := &listPage{}
+	if items, ok := raw[itemsKey]; ok {
+		if err := json.Unmarshal(items, &page.Items); err != nil {
+			return nil, fmt.Errorf("invalid response to %s: %s is not a list", method, itemsKey)
+		}
+	}
+	if next, ok := raw["nextCursor"]; ok && string(next) != "null" {
+		if err := json.Unmarshal(next, &page.NextCursor); err != nil {
+			return nil, fmt.Errorf("invalid response to %s: nextCursor is not a string: %s", method, next)
+		}
+	}
+	return page, nil
+}
+
+// itemKeys returns the identifying field of each listed item
+func itemKeys(items []json.RawMessage, idKey string) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		var fields map[string]any
+		if json.Unmarshal(item, &fields) == nil {
+			keys[i], _ = fields[idKey].(string)
+		}
+	}
+	return keys
+}
+
+// pagedList is a listing followed through its pages, with the problems
+// found in how the server paged it
+type pagedList struct {
+	items     []json.RawMessage
+	pages     int
+	truncated bool
+	problems  []string
+}
+
+// followPages lists every item of a list method, following nextCursor up to
+// -max-pages. The cursors are checked as they are followed: a cursor that
+// repeats ends the listing, and items listed twice (which are kept once),
+// empty pages that are not the last and pages that differ when requested
+// again are problems.
+func followPages(ctx context.Context, mcpClient *client.Client, method mcp.MCPMethod, itemsKey, idKey string) (*pagedList, error) {
+	list := &pagedList{}
+	seen := map[string]int{}
+	cursors := map[string]int{}
+	firstCursor := ""
+	var secondPage []string
+	cursor := ""
+	for {
+		if maxListPages > 0 && list.pages == maxListPages {
+			list.truncated = true
+			break
+		}
+		page, err := requestListPage(ctx, mcpClient, method, itemsKey, cursor)
+		if err != nil {
+			if list.pages > 0 {
+				return nil, fmt.Errorf("page %d: %w", list.pages+1, err)
+			}
+			return nil, err
+		}
+		list.pages++
+		keys := itemKeys(page.Items, idKey)
+		if list.pages == 2 {
+			secondPage = keys
+		}
+		for i, key := range keys {
+			if first, ok := seen[key]; ok && key != "" {
+				list.problems = append(list.problems, fmt.Sprintf("'%s' is listed on page %d and again on page %d", key, first, list.pages))
+				continue
+			}
+			seen[key] = list.pages
+			list.items = append(list.items, page.Items[i])
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if len(page.Items) == 0 {
+			list.problems = append(list.problems, fmt.Sprintf("page %d is empty but has a nextCursor", list.pages))
+		}
+		if previous, ok := cursors[page.NextCursor]; ok {
+			list.problems = append(list.problems, fmt.Sprintf("page %d returned the cursor already returned by page %d; stopped following it", list.pages, previous))
+			break
+		}
+		cursors[page.NextCursor] = list.pages
+		if firstCursor == "" {
+			firstCursor = page.NextCursor
+		}
+		cursor = page.NextCursor
+	}
+
+	// A cursor names a position in the listing, so asking for it again
+	// should return the same page
+	if secondPage != nil {
+		page, err := requestListPage(ctx, mcpClient, method, itemsKey, firstCursor)
+		switch {
+		case err != nil:
+			list.problems = append(list.problems, fmt.Sprintf("requesting page 2 again with the same cursor failed: %v", err))
+		case !slices.Equal(itemKeys(page.Items, idKey), secondPage):
+			list.problems = append(list.problems, "requesting page 2 again with the same cursor returned different items")
+		}
+	}
+	return list, nil
+}
+
+// listAllPages lists every item of a list method through followPages and
+// returns them as a single result with the items under itemsKey. The
+// problems in the paging are findings.
+func listAllPages(ctx context.Context, mcpClient *client.Client, method mcp.MCPMethod, itemsKey, idKey string) (json.RawMessage, error) {
+	list, err := followPages(ctx, mcpClient, method, itemsKey, idKey)
+	if err != nil {
+		return nil, err
+	}
+
+	report.setPagination(listPagination{Method: string(method), Pages: list.pages, Items: len(list.items), Truncated: list.truncated})
+	if list.pages > 1 {
+		fmt.Printf("Listed %d item(s) of %s in %d pages\n", len(list.items), method, list.pages)
+	}
+	if list.truncated {
+		fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "stopped after %d pages of %s (-max-pages); the listing is incomplete", list.pages, method))
+	}
+	for _, p := range list.problems {
+		fmt.Printf("Warning: %s\n", report.addFinding(checkIDPagination, string(method), "%s: %s", method, p))
+	}
+
+	merged, err := json.Marshal(map[string][]json.RawMessage{itemsKey: list.items})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge the pages of %s: %w", method, err)
+	}
+	return merged, nil
+}
+
+// listAllTools lists the server's tools through every page. The library
+// drops the tools' title field, so the titles are returned by tool name.
+func listAllTools(ctx context.Context, mcpClient *client.Client) (*mcp.ListToolsResult, map[string]string, error) {
+	data, err := listAllPages(ctx, mcpClient, mcp.MethodToolsList, "tools", "name")
+	if err != nil {
+		return nil, nil, err
+	}
+	var result mcp.ListToolsResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, nil, fmt.Errorf("invalid response to tools/list: %w", err)
+	}
+	var named struct {
+		Tools []struct {
+			Name  string `json:"name"`
+			Title string `json:"title"`
+		} `json:"tools"`
+	}
+	titles := map[string]string{}
+	if err := json.Unmarshal(data, &named); err == nil {
+		for _, t := range named.Tools {
+			if t.Title != "" {
+				titles[t.Name] = t.Title
+			}
+		}
+	}
+	return &result, titles, nil
+}
+
+// listAllResources lists the server's resources through every page
+func listAllResources(ctx context.Context, mcpClient *client.Client) (*mcp.ListResourcesResult, error) {
+	data, err := listAllPages(ctx, mcpClient, mcp.MethodResourcesList, "resources", "uri")
+	if err != nil {
+		return nil, err
+	}
+	var result mcp.ListResourcesResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid response to resources/list: %w", err)
+	}
+	return &result, nil
+}
+
+// listAllResourceTemplates lists the server's resource templates through
+// every page
+func listAllResourceTemplates(ctx context.Context, mcpClient *client.Client) (*mcp.ListResourceTemplatesResult, error) {
+	data, err := listAllPages(ctx, mcpClient, mcp.MethodResourcesTemplatesList, "resourceTemplates", "uriTemplate")
+	if err != nil {
+		return nil, err
+	}
+	var result mcp.ListResourceTemplatesResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid response to resources/templates/list: %w", err)
+	}
+	return &result, nil
+}
+
+// listAllPrompts lists the server's prompts through every page
+func listAllPrompts(ctx context.Context, mcpClient *client.Client) (*mcp.ListPromptsResult, error) {
+	data, err := listAllPages(ctx, mcpClient, mcp.MethodPromptsList, "prompts", "name")
+	if err != nil {
+		return nil, err
+	}
+	var result mcp.ListPromptsResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid response to prompts/list: %w", err)
+	}
+	return &result, nil
+}
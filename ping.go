@@ -0,0 +1,101 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+)
+
+// pingMethod is the MCP liveness request
+const pingMethod = "ping"
+
+// sendPing sends one ping request and returns its round-trip time
+func sendPing(mcpClient *client.Client, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	err := mcpClient.Ping(ctx)
+	rtt := time.Since(start)
+	report.addTiming(pingMethod, rtt, err)
+	return rtt, err
+}
+
+// runPing implements -ping: it sends count ping requests, interval apart,
+// prints the round-trip time of each and a min/avg/max summary. Ctrl-C stops
+// early and still prints the summary. An error is returned if any ping failed.
+func runPing(mcpClient *client.Client, count int, interval, timeout time.Duration) error {
+	fmt.Println("\n=== Ping ===")
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var latencies []time.Duration
+	sent, failed := 0, 0
+pings:
+	for sent < count {
+		if sent > 0 {
+			select {
+			case <-interrupt:
+				fmt.Println("Interrupted")
+				break pings
+			case <-time.After(interval):
+			}
+		}
+		sent++
+		rtt, err := sendPing(mcpClient, timeout)
+		if err != nil {
+			f := report.addFinding(checkIDPing, "", "ping %d failed after %s: %v", sent, humanDuration(rtt), err)
+			fmt.Println(f)
+			if f.fails() {
+				failed++
+			}
+			continue
+		}
+		latencies = append(latencies, rtt)
+		fmt.Printf("ping %d: %s\n", sent, humanDuration(rtt))
+	}
+
+	fmt.Printf("\n%d ping%s sent, %d answered, %d failed\n", sent, pluralS(sent), len(latencies), failed)
+	if summary := summarizeLatencies(latencies); summary != nil {
+		fmt.Printf("round-trip min/avg/max = %s/%s/%s\n", humanDuration(summary.Min), humanDuration(summary.Mean), humanDuration(summary.Max))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d pings failed", failed, sent)
+	}
+	return nil
+}
+
+// startKeepalive pings the server every interval until the returned stop
+// function is called, so that idle gateways and load balancers do not drop
+// the session. Failed pings are reported as they happen; pings are otherwise
+// silent.
+func startKeepalive(mcpClient *client.Client, interval, timeout time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastAnswered := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if _, err := sendPing(mcpClient, min(timeout, interval)); err != nil {
+				f := report.addFinding(checkIDPing, "", "keep-alive ping failed: %v", err)
+				fmt.Printf("\n[%s] Warning: %s (last answered %s)\n", consoleClock(time.Now()), f, humanAgo(lastAnswered))
+				continue
+			}
+			lastAnswered = time.Now()
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// policyRequestBase is the first ID of the policy's method probes, clear of
+// the IDs the client assigns and of the other raw requests
+const policyRequestBase = 10_000_000
+
+// serverPolicy is what a server may expose, for gates that validate a
+// server before it is deployed behind a gateway. Each list is an allowlist
+// of patterns (see policyAllows); a section that is left out is not checked,
+// and an empty list allows nothing.
+type serverPolicy struct {
+	Name string `yaml:"name,omitempty"`
+	// Capabilities are the top-level capabilities the server may advertise,
+	// and its experimental capabilities as "experimental.<name>"
+	Capabilities []string `yaml:"capabilities"`
+	// Methods are the requests the server may answer. Each standard request
+	// outside them, and each of ProbeMethods, must be rejected as not found.
+	Methods      []string `yaml:"methods"`
+	ProbeMethods []string `yaml:"probe_methods,omitempty"`
+	// Tools, Resources, ResourceTemplates and Prompts are the names, URIs
+	// and URI templates the server may list
+	Tools             []string `yaml:"tools"`
+	Resources         []string `yaml:"resources"`
+	ResourceTemplates []string `yaml:"resource_templates"`
+	Prompts           []string `yaml:"prompts"`
+}
+
+// policyProbeName is the tool and prompt name, and policyProbeURI the
+// resource URI, sent to methods outside the policy. They exist on no server,
+// so a method that is implemented fails without side effects.
+const (
+	policyProbeName = "mcpprobe-policy-check"
+	policyProbeURI  = "mcpprobe://policy-check"
+)
+
+// policyMethodProbe is a request sent to find out whether the server
+// implements a method, with params that are harmless if it does
+type policyMethodProbe struct {
+	method mcp.MCPMethod
+	params map[string]any
+}
+
+// policyMethodProbes are the standard requests a server may answer. ping is
+// left out: every server must answer it.
+var policyMethodProbes = []policyMethodProbe{
+	{mcp.MethodToolsList, map[string]any{}},
+	{mcp.MethodToolsCall, map[string]any{"name": policyProbeName, "arguments": map[string]any{}}},
+	{mcp.MethodResourcesList, map[string]any{}},
+	{mcp.MethodResourcesTemplatesList, map[string]any{}},
+	{mcp.MethodResourcesRead, map[string]any{"uri": policyProbeURI}},
+	{"resources/subscribe", map[string]any{"uri": policyProbeURI}},
+	{"resources/unsubscribe", map[string]any{"uri": policyProbeURI}},
+	{mcp.MethodPromptsList, map[string]any{}},
+	{mcp.MethodPromptsGet, map[string]any{"name": policyProbeName}},
+	{mcp.MethodCompletionComplete, map[string]any{
+		"ref":      map[string]any{"type": "ref/prompt", "name": policyProbeName},
+		"argument": map[string]any{"name": "value", "value": ""},
+	}},
+	{mcp.MethodSetLogLevel, map[string]any{"level": "info"}},
+}
+
+// policyCommandArgs turns "verify-policy <file> [flags]" into the equivalent
+// -verify-policy flag, so that the policy is verified with the probe's usual
+// connection options
+func policyCommandArgs(args []string) ([]string, error) {
+	if len(args) < 3 || strings.HasPrefix(args[2], "-") {
+		return nil, fmt.Errorf("usage: probe verify-policy <policy.yaml> -url <server-url> [options]")
+	}
+	return append([]string{args[0], "-verify-policy", args[2]}, args[3:]...), nil
+}
+
+// loadPolicy reads and validates a policy file
+func loadPolicy(filePath string) (*serverPolicy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	var p serverPolicy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	if p.Capabilities == nil && p.Methods == nil && p.Tools == nil && p.Resources == nil && p.ResourceTemplates == nil && p.Prompts == nil {
+		return nil, fmt.Errorf("%s has no capabilities, methods, tools, resources, resource_templates or prompts section", filePath)
+	}
+	if len(p.ProbeMethods) > 0 && p.Methods == nil {
+		return nil, fmt.Errorf("probe_methods requires a methods section")
+	}
+	for _, name := range p.Capabilities {
+		if top, _, _ := strings.Cut(name, "."); !slices.Contains(serverCapabilityNames, top) {
+			return nil, fmt.Errorf("capabilities: unknown capability '%s' (known: %s)", name, strings.Join(serverCapabilityNames, ", "))
+		}
+	}
+	for _, method := range p.ProbeMethods {
+		if method == "" || strings.HasPrefix(method, "notifications/") {
+			return nil, fmt.Errorf("probe_methods: '%s' is not a request method", method)
+		}
+	}
+	return &p, nil
+}
+
+// policyAllows reports whether name matches one of the policy's patterns.
+// In a pattern, * matches any characters, slashes included, so that
+// "tools/*" and "file:///docs/*" cover everything below them, and ?
+// matches a single character.
+func policyAllows(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		expr := regexp.QuoteMeta(pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		if regexp.MustCompile("^" + expr + "$").MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPolicyVerification checks that a server exposes nothing outside a
+// policy and prints the result of each check. Each capability, method, tool,
+// resource or prompt outside the policy is a finding. It returns an error if
+// the server does not comply.
+func runPolicyVerification(dial func(ctx context.Context) (*client.Client, error), p *serverPolicy, source string, timeout time.Duration) error {
+	title := source
+	if p.Name != "" {
+		title = fmt.Sprintf("%s (%s)", source, p.Name)
+	}
+	fmt.Printf("=== Policy %s ===\n", title)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	mcpClient, err := dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() { _ = mcpClient.Close() }()
+	initResult, err := mcpClient.Initialize(ctx, newInitializeRequest())
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	report.setInitResult(initResult)
+	fmt.Println()
+
+	var summaries []checkSummary
+	violations, suppressed := 0, 0
+	// record prints a check's result. Each item outside the policy is a
+	// finding with the item as its subject.
+	record := func(id string, start time.Time, detail string, outside []string, reason string) {
+		summary := checkSummary{ID: id, Runs: 1, AvgTime: time.Since(start)}
+		var lines []string
+		failed := false
+		for _, subject := range outside {
+			f := report.addFinding(checkIDPolicy, subject, "%s %s", subject, reason)
+			if f.Suppressed {
+				suppressed++
+			} else {
+				violations++
+				failed = true
+			}
+			lines = append(lines, f.String())
+		}
+		if failed {
+			summary.Status, summary.Failed, summary.Errors = checkFail, 1, lines
+		} else {
+			summary.Status, summary.Passed = checkPass, 1
+			summary.Suppressed = len(outside) > 0
+		}
+		summaries = append(summaries, summary)
+		emitEvent(eventCheck, map[string]any{
+			"id":            id,
+			"status":        summary.Status,
+			"avgDurationMs": durationMillis(summary.AvgTime),
+			"errors":        summary.Errors,
+		})
+		fmt.Printf("  %-5s  %-28s %s\n", strings.ToUpper(summary.Status), id, detail)
+		for _, line := range lines {
+			fmt.Printf("         %s\n", line)
+		}
+	}
+	// recordError fails a check that could not be run
+	recordError := func(id string, start time.Time, err error) {
+		summaries = append(summaries, checkSummary{ID: id, Status: checkFail, Runs: 1, Failed: 1, Errors: []string{err.Error()}, AvgTime: time.Since(start)})
+		emitEvent(eventCheck, map[string]any{"id": id, "status": checkFail, "errors": []string{err.Error()}})
+		f := report.addFinding(checkIDPolicy, id, "%s could not be verified: %v", id, err)
+		if f.Suppressed {
+			suppressed++
+		} else {
+			violations++
+		}
+		fmt.Printf("  %-5s  %-28s %v\n", strings.ToUpper(checkFail), id, err)
+		fmt.Printf("         %s\n", f)
+	}
+	// recordList checks the names a list method returned
+	recordList := func(id, kind string, start time.Time, allowed, names []string) {
+		var outside []string
+		for _, name := range names {
+			if !policyAllows(allowed, name) {
+				outside = append(outside, kind+" "+name)
+			}
+		}
+		detail := fmt.Sprintf("%d %s%s listed", len(names), kind, pluralS(len(names)))
+		if len(outside) > 0 {
+			detail += fmt.Sprintf(", %d outside the policy", len(outside))
+		}
+		record(id, start, detail, outside, "is not allowed by the policy")
+	}
+	caps := initResult.Capabilities
+
+	if p.Capabilities != nil {
+		start := time.Now()
+		var outside, exposed []string
+		for _, name := range advertisedCapabilities(caps) {
+			// Sub-capabilities are covered by their capability and its methods
+			if strings.Contains(name, ".") && !strings.HasPrefix(name, "experimental.") {
+				continue
+			}
+			exposed = append(exposed, name)
+			if !policyAllows(p.Capabilities, name) {
+				outside = append(outside, "capability "+name)
+			}
+		}
+		record("capabilities", start, valueOr(strings.Join(exposed, ", "), "none advertised"), outside, "is advertised but not allowed by the policy")
+	}
+
+	if p.Methods != nil {
+		probes := slices.Clone(policyMethodProbes)
+		for _, method := range p.ProbeMethods {
+			probes = append(probes, policyMethodProbe{mcp.MCPMethod(method), map[string]any{}})
+		}
+		for i, probe := range probes {
+			method := string(probe.method)
+			if policyAllows(p.Methods, method) {
+				continue
+			}
+			start := time.Now()
+			answer, err := probePolicyMethod(ctx, mcpClient, i, probe.method, probe.params)
+			if err != nil {
+				recordError("method "+method, start, err)
+				continue
+			}
+			var outside []string
+			if answer != "" {
+				outside = append(outside, "method "+method)
+			}
+			record("method "+method, start, valueOr(answer, "not found"), outside, "is answered but not allowed by the policy")
+		}
+	}
+
+	if p.Tools != nil && caps.Tools != nil {
+		start := time.Now()
+		result, _, err := listAllTools(ctx, mcpClient)
+		if err != nil {
+			recordError("tools", start, err)
+		} else {
+			report.setTools(result.Tools)
+			names := make([]string, 0, len(result.Tools))
+			for _, tool := range result.Tools {
+				names = append(names, tool.Name)
+			}
+			recordList("tools", "tool", start, p.Tools, names)
+		}
+	}
+	if p.Resources != nil && caps.Resources != nil {
+		start := time.Now()
+		result, err := listAllResources(ctx, mcpClient)
+		if err != nil {
+			recordError("resources", start, err)
+		} else {
+			report.setResources(result.Resources)
+			names := make([]string, 0, len(result.Resources))
+			for _, res := range result.Resources {
+				names = append(names, res.URI)
+			}
+			recordList("resources", "resource", start, p.Resources, names)
+		}
+	}
+	if p.ResourceTemplates != nil && caps.Resources != nil {
+		start := time.Now()
+		result, err := listAllResourceTemplates(ctx, mcpClient)
+		if err != nil {
+			recordError("resource templates", start, err)
+		} else {
+			report.setResourceTemplates(result.ResourceTemplates)
+			names := make([]string, 0, len(result.ResourceTemplates))
+			for _, tmpl := range result.ResourceTemplates {
+				if tmpl.URITemplate != nil {
+					names = append(names, tmpl.URITemplate.Raw())
+				}
+			}
+			recordList("resource templates", "resource template", start, p.ResourceTemplates, names)
+		}
+	}
+	if p.Prompts != nil && caps.Prompts != nil {
+		start := time.Now()
+		result, err := listAllPrompts(ctx, mcpClient)
+		if err != nil {
+			recordError("prompts", start, err)
+		} else {
+			report.setPrompts(result.Prompts)
+			names := make([]string, 0, len(result.Prompts))
+			for _, prompt := range result.Prompts {
+				names = append(names, prompt.Name)
+			}
+			recordList("prompts", "prompt", start, p.Prompts, names)
+		}
+	}
+	report.setChecks(summaries)
+
+	passed := countPassed(summaries)
+	fmt.Printf("\n%d policy checks: %d compliant, %d not compliant", len(summaries), passed, len(summaries)-passed)
+	// A check fails with a finding for each item outside the policy
+	if findings := violations + suppressed; findings > 0 {
+		fmt.Printf(" (%d finding%s", findings, pluralS(findings))
+		if suppressed > 0 {
+			fmt.Printf(", %d suppressed", suppressed)
+		}
+		fmt.Print(")")
+	}
+	fmt.Println()
+	if violations > 0 {
+		fmt.Println("Result: NOT COMPLIANT")
+		return fmt.Errorf("server does not comply with the policy: %d violation%s", violations, pluralS(violations))
+	}
+	fmt.Println("Result: COMPLIANT")
+	return nil
+}
+
+// countPassed counts the checks that passed
+func countPassed(summaries []checkSummary) int {
+	passed := 0
+	for _, s := range summaries {
+		if s.Status == checkPass {
+			passed++
+		}
+	}
+	return passed
+}
+
+// probePolicyMethod sends a request the policy does not allow. It returns
+// an empty answer if the server rejects the method as not found, or how
+// the server answered it otherwise.
+func probePolicyMethod(ctx context.Context, mcpClient *client.Client, index int, method mcp.MCPMethod, params map[string]any) (string, error) {
+	request := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(int64(policyRequestBase + index)),
+		Method:  string(method),
+		Params:  params,
+	}
+	start := time.Now()
+	response, err := mcpClient.GetTransport().SendRequest(ctx, request)
+	if err == nil && response.Error != nil {
+		err = &rpcError{Code: response.Error.Code, Message: response.Error.Message}
+	}
+	report.addTiming(string(method), time.Since(start), err)
+
+	var rpcErr *rpcError
+	switch {
+	case errors.As(err, &rpcErr) && rpcErr.Code == mcp.METHOD_NOT_FOUND:
+		return "", nil
+	case errors.As(err, &rpcErr):
+		return fmt.Sprintf("answered with error %d, so the method is implemented", rpcErr.Code), nil
+	case err != nil:
+		return "", fmt.Errorf("failed to send %s: %w", method, err)
+	}
+	return "answered with a result", nil
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// promptRequestID is the ID of the raw prompts/get request, clear of the
+// IDs the client assigns
+const promptRequestID = 2_000_000
+
+// promptMessageJSON is a message of a prompts/get result as sent by the server
+type promptMessageJSON struct {
+	Role    string         `json:"role"`
+	Content map[string]any `json:"content"`
+}
+
+// parsePromptArgs parses -prompt-args, a JSON object of argument values.
+// Prompt arguments are strings, so numbers and booleans are converted.
+func parsePromptArgs(spec string) (map[string]string, error) {
+	args := map[string]string{}
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return args, nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(spec), &values); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for name, v := range values {
+		switch v.(type) {
+		case map[string]any, []any, nil:
+			return nil, fmt.Errorf("argument '%s' must be a string", name)
+		}
+		args[name] = scalarString(v)
+	}
+	return args, nil
+}
+
+// findPrompt looks up a prompt by name among the server's prompts
+func findPrompt(ctx context.Context, mcpClient *client.Client, name string) (*mcp.Prompt, error) {
+	listStart := time.Now()
+	result, err := mcpClient.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	report.addTiming("prompts/list", time.Since(listStart), err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+	report.setPrompts(result.Prompts)
+
+	for i, prompt := range result.Prompts {
+		if prompt.Name == name {
+			return &result.Prompts[i], nil
+		}
+	}
+	if len(result.Prompts) == 0 {
+		return nil, fmt.Errorf("prompt '%s' not found: the server lists no prompts", name)
+	}
+	return nil, fmt.Errorf("prompt '%s' not found; available: %s", name, strings.Join(promptNames(result.Prompts), ", "))
+}
+
+// getPrompt fetches a prompt with the given arguments, renders its messages
+// and validates the server's response
+func getPrompt(ctx context.Context, mcpClient *client.Client, name, argsSpec string) error {
+	fmt.Println("\n=== Get Prompt ===")
+	args, err := parsePromptArgs(argsSpec)
+	if err != nil {
+		return fmt.Errorf("invalid -prompt-args: %w", err)
+	}
+	prompt, err := findPrompt(ctx, mcpClient, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Prompt: %s\n", prompt.Name)
+
+	declared := map[string]bool{}
+	var missing []string
+	for _, arg := range prompt.Arguments {
+		declared[arg.Name] = true
+		if value, ok := args[arg.Name]; ok {
+			fmt.Printf("  %s = %q\n", arg.Name, value)
+		} else if arg.Required {
+			missing = append(missing, arg.Name)
+		}
+	}
+	if len(missing) > 0 && !stdinIsTerminal() {
+		return fmt.Errorf("no value for required argument(s) %s (use -prompt-args)", strings.Join(missing, ", "))
+	}
+	// On a terminal the missing arguments are asked for, with the server's
+	// completions if it offers them
+	if len(missing) > 0 {
+		complete := newArgumentCompleter(ctx, mcpClient, completionRef{Type: refPrompt, Name: prompt.Name})
+		reader := bufio.NewReader(os.Stdin)
+		for _, argName := range missing {
+			value, err := askArgument(reader, argName, complete, args)
+			if err != nil {
+				return fmt.Errorf("no value for required argument '%s'", argName)
+			}
+			args[argName] = value
+		}
+	}
+	// Undeclared arguments are still sent, to see how the server handles them
+	var undeclared []string
+	for argName := range args {
+		if !declared[argName] {
+			undeclared = append(undeclared, argName)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, argName := range undeclared {
+		fmt.Printf("  %s = %q (not declared by the prompt)\n", argName, args[argName])
+	}
+
+	// The request is sent raw so that malformed messages are reported as
+	// problems rather than failing to parse
+	params, _ := json.Marshal(map[string]any{"name": name, "arguments": args})
+	request := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(int64(promptRequestID)),
+		Method:  string(mcp.MethodPromptsGet),
+		Params:  json.RawMessage(params),
+	}
+	getStart := time.Now()
+	response, err := mcpClient.GetTransport().SendRequest(ctx, request)
+	if err == nil && response.Error != nil {
+		err = fmt.Errorf("server returned error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	report.addTiming("prompts/get", time.Since(getStart), err)
+	if err != nil {
+		return fmt.Errorf("failed to get prompt '%s': %w", name, err)
+	}
+	var result struct {
+		Description string              `json:"description"`
+		Messages    []promptMessageJSON `json:"messages"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return fmt.Errorf("invalid response to prompts/get: %w", err)
+	}
+	fmt.Printf("Got %d message(s) in %s\n", len(result.Messages), humanDuration(time.Since(getStart)))
+	if result.Description != "" {
+		fmt.Printf("Description: %s\n", result.Description)
+	}
+	fmt.Println()
+
+	for i, msg := range result.Messages {
+		printPromptMessage(i+1, msg)
+	}
+	problems := validatePromptMessages(result.Messages)
+	if len(problems) > 0 {
+		fmt.Println("\nProblems with the response:")
+		unsuppressed := 0
+		for _, p := range problems {
+			f := report.addFinding(checkIDPromptResponse, name, "prompts/get %s: %s", name, p)
+			fmt.Printf("  ! %s\n", f)
+			if f.fails() {
+				unsuppressed++
+			}
+		}
+		if unsuppressed > 0 {
+			return fmt.Errorf("invalid response to prompts/get (%d problem(s))", unsuppressed)
+		}
+		fmt.Println("\nAll problems are suppressed")
+		return nil
+	}
+	fmt.Println("\nResponse is valid")
+	return nil
+}
+
+// printPromptMessage prints one message of a prompts/get result
+func printPromptMessage(n int, msg promptMessageJSON) {
+	contentType, _ := msg.Content["type"].(string)
+	fmt.Printf("--- Message %d: %s (%s) ---\n", n, valueOr(msg.Role, "no role"), valueOr(contentType, "no type"))
+	switch contentType {
+	case "text":
+		fmt.Println(contentString(msg.Content, "text"))
+	case "image", "audio":
+		fmt.Printf("[%s, %s of base64]\n", valueOr(contentString(msg.Content, "mimeType"), "no MIME type"), humanBytes(int64(len(contentString(msg.Content, "data")))))
+	case "resource":
+		resource, _ := msg.Content["resource"].(map[string]any)
+		fmt.Printf("%s (%s)\n", contentString(resource, "uri"), valueOr(contentString(resource, "mimeType"), "no MIME type"))
+		if text, ok := resource["text"].(string); ok {
+			fmt.Println(text)
+		} else {
+			fmt.Printf("[binary, %s of base64]\n", humanBytes(int64(len(contentString(resource, "blob")))))
+		}
+	case "resource_link":
+		fmt.Printf("%s (%s)\n", contentString(msg.Content, "uri"), valueOr(contentString(msg.Content, "mimeType"), "no MIME type"))
+	default:
+		fmt.Println(itemJSON(msg.Content))
+	}
+}
+
+// validatePromptMessages checks the messages of a prompts/get result
+func validatePromptMessages(messages []promptMessageJSON) []string {
+	if len(messages) == 0 {
+		return []string{"result has no messages"}
+	}
+	var problems []string
+	for i, msg := range messages {
+		n := i + 1
+		if msg.Role != string(mcp.RoleUser) && msg.Role != string(mcp.RoleAssistant) {
+			problems = append(problems, fmt.Sprintf("message %d has role '%s', expected 'user' or 'assistant'", n, msg.Role))
+		}
+		if msg.Content == nil {
+			problems = append(problems, fmt.Sprintf("message %d has no content", n))
+			continue
+		}
+		switch contentType, _ := msg.Content["type"].(string); contentType {
+		case "text":
+			if contentString(msg.Content, "text") == "" {
+				problems = append(problems, fmt.Sprintf("message %d has no text", n))
+			}
+		case "image", "audio":
+			mimeType := contentString(msg.Content, "mimeType")
+			if !strings.HasPrefix(mimeType, contentType+"/") {
+				problems = append(problems, fmt.Sprintf("message %d has %s content with MIME type '%s'", n, contentType, mimeType))
+			}
+			if _, err := base64.StdEncoding.DecodeString(contentString(msg.Content, "data")); err != nil {
+				problems = append(problems, fmt.Sprintf("message %d: %s data is not valid base64: %v", n, contentType, err))
+			}
+		case "resource":
+			resource, _ := msg.Content["resource"].(map[string]any)
+			_, hasText := resource["text"].(string)
+			_, hasBlob := resource["blob"].(string)
+			switch {
+			case resource == nil:
+				problems = append(problems, fmt.Sprintf("message %d has resource content without a resource", n))
+			case contentString(resource, "uri") == "":
+				problems = append(problems, fmt.Sprintf("message %d has a resource without a uri", n))
+			case !hasText && !hasBlob:
+				problems = append(problems, fmt.Sprintf("message %d has a resource with neither text nor blob", n))
+			}
+		case "resource_link":
+			if contentString(msg.Content, "uri") == "" {
+				problems = append(problems, fmt.Sprintf("message %d has a resource link without a uri", n))
+			}
+		case "":
+			problems = append(problems, fmt.Sprintf("message %d has content without a type", n))
+		default:
+			problems = append(problems, fmt.Sprintf("message %d has unknown content type '%s'", n, contentType))
+		}
+	}
+	return problems
+}
+
+// contentString returns a string field of a content object, or ""
+func contentString(content map[string]any, key string) string {
+	s, _ := content[key].(string)
+	return s
+}
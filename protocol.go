@@ -0,0 +1,212 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Protocol features whose checks depend on the negotiated version
+const (
+	featureStreamableHTTP    = "streamable-http"
+	featureBatching          = "batching"
+	featureToolAnnotations   = "tool-annotations"
+	featureAudioContent      = "audio-content"
+	featureCompletions       = "completions"
+	featureStructuredOutput  = "structured-output"
+	featureElicitation       = "elicitation"
+	featureResourceLinks     = "resource-links"
+	featureTitles            = "titles"
+	featureCompletionContext = "completion-context"
+	featureProtocolHeader    = "protocol-version-header"
+	featureIcons             = "icons"
+	featureURLElicitation    = "url-elicitation"
+)
+
+// protocolFeature is a protocol feature and the versions that have it.
+// Versions are dates, so they compare as strings. Until is the first
+// version without the feature, for features that were removed.
+type protocolFeature struct {
+	ID    string
+	Name  string
+	Since string
+	Until string
+}
+
+// protocolFeatures is the probe's knowledge of what each protocol version
+// introduced, oldest first. Features of 2024-11-05 are the baseline.
+var protocolFeatures = []protocolFeature{
+	{featureStreamableHTTP, "streamable HTTP transport", "2025-03-26", ""},
+	{featureBatching, "JSON-RPC batching", "2025-03-26", "2025-06-18"},
+	{featureToolAnnotations, "tool annotations", "2025-03-26", ""},
+	{featureAudioContent, "audio content", "2025-03-26", ""},
+	{featureCompletions, "argument completions (completion/complete)", "2025-03-26", ""},
+	{featureStructuredOutput, "structured tool output (outputSchema, structuredContent)", "2025-06-18", ""},
+	{featureElicitation, "elicitation", "2025-06-18", ""},
+	{featureResourceLinks, "resource links in tool results", "2025-06-18", ""},
+	{featureTitles, "title fields", "2025-06-18", ""},
+	{featureCompletionContext, "completion context arguments", "2025-06-18", ""},
+	{featureProtocolHeader, "MCP-Protocol-Version HTTP header", "2025-06-18", ""},
+	{featureIcons, "icons", "2025-11-25", ""},
+	{featureURLElicitation, "URL mode elicitation", "2025-11-25", ""},
+}
+
+// protocolNote records a check that was skipped because the negotiated
+// protocol version predates the feature it checks
+type protocolNote struct {
+	Feature    string `json:"feature"`
+	Requires   string `json:"requires"`
+	Negotiated string `json:"negotiated"`
+	Note       string `json:"note"`
+}
+
+// latestProtocolVersion is the newest protocol version the probe knows,
+// which it requests at initialization unless -protocol-version is given
+func latestProtocolVersion() string {
+	return mcp.LATEST_PROTOCOL_VERSION
+}
+
+// requestedProtocolVersion is the protocol version requested at
+// initialization, set by -protocol-version
+var requestedProtocolVersion = latestProtocolVersion()
+
+// setRequestedProtocolVersion sets -protocol-version. Versions the probe
+// does not know are sent anyway, to see how the server negotiates them.
+func setRequestedProtocolVersion(version string) error {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("-protocol-version cannot be empty")
+	}
+	if !slices.Contains(mcp.ValidProtocolVersions, version) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", report.addWarning(warningProbe, "protocol version '%s' is not one the probe knows (%s); requesting it anyway", version, strings.Join(mcp.ValidProtocolVersions, ", ")))
+	}
+	requestedProtocolVersion = version
+	report.Build.ProtocolVersion = version
+	return nil
+}
+
+// checkNegotiatedVersion reports how the server answered the requested
+// protocol version. A server that supports the requested version must answer
+// with it; otherwise it answers with a version it supports, which the probe
+// then uses. A different answer is a mismatch worth knowing about, not
+// necessarily an error.
+func checkNegotiatedVersion(requested, answered string) {
+	report.mu.Lock()
+	report.RequestedProtocolVersion = requested
+	report.mu.Unlock()
+	if answered == requested {
+		fmt.Printf("Server answered with the requested protocol version %s\n", answered)
+		return
+	}
+	// Answering with an older version is ordinary negotiation; a newer one
+	// than asked for is not something a client can be expected to handle
+	if answered < requested {
+		fmt.Printf("Note: protocol version mismatch: requested %s, the server answered with the older version %s\n", requested, answered)
+		return
+	}
+	fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "protocol version mismatch: requested %s, the server answered with the newer version %s", requested, answered))
+}
+
+// findProtocolFeature returns the feature with the given ID
+func findProtocolFeature(id string) *protocolFeature {
+	for i := range protocolFeatures {
+		if protocolFeatures[i].ID == id {
+			return &protocolFeatures[i]
+		}
+	}
+	return nil
+}
+
+// available reports whether a protocol version has the feature. An unknown
+// or empty version is assumed to be the newest, so nothing is skipped.
+func (f *protocolFeature) available(version string) bool {
+	if version == "" || !slices.Contains(mcp.ValidProtocolVersions, version) {
+		version = latestProtocolVersion()
+	}
+	return version >= f.Since && (f.Until == "" || version < f.Until)
+}
+
+// requirement describes the versions that have the feature
+func (f *protocolFeature) requirement() string {
+	if f.Until != "" {
+		return fmt.Sprintf("%s requires protocol ≥ %s and < %s", f.Name, f.Since, f.Until)
+	}
+	return fmt.Sprintf("%s requires protocol ≥ %s", f.Name, f.Since)
+}
+
+// negotiatedSupports reports whether the negotiated protocol version has a
+// feature. If it does not, the check that needs it is skipped: a note saying
+// so is printed and recorded in the report, once per feature, so that older
+// but compliant servers are not failed for it.
+func negotiatedSupports(id, check string) bool {
+	feature := findProtocolFeature(id)
+	report.mu.Lock()
+	negotiated := report.ProtocolVersion
+	report.mu.Unlock()
+	if feature == nil || feature.available(negotiated) {
+		return true
+	}
+	if report.addProtocolNote(protocolNote{
+		Feature:    feature.ID,
+		Requires:   feature.Since,
+		Negotiated: negotiated,
+		Note:       fmt.Sprintf("%s; the server negotiated %s, so %s", feature.requirement(), negotiated, check),
+	}) {
+		fmt.Printf("Note: %s; the server negotiated %s, so %s\n", feature.requirement(), negotiated, check)
+	}
+	return false
+}
+
+// addProtocolNote records a protocol note, reporting whether it is the
+// first for its feature
+func (r *probeReport) addProtocolNote(note protocolNote) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.ProtocolNotes {
+		if existing.Feature == note.Feature {
+			return false
+		}
+	}
+	r.ProtocolNotes = append(r.ProtocolNotes, note)
+	return true
+}
+
+// runProtocolsCommand implements the 'protocols' subcommand
+func runProtocolsCommand(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: protocols")
+	}
+	versions := slices.Clone(mcp.ValidProtocolVersions)
+	slices.Sort(versions)
+	for i, version := range versions {
+		if i > 0 {
+			fmt.Println()
+		}
+		label := version
+		if version == latestProtocolVersion() {
+			label += " (requested by default)"
+		}
+		fmt.Println(label)
+		listed := false
+		for _, f := range protocolFeatures {
+			switch {
+			case f.Since == version:
+				fmt.Printf("  + %s\n", f.Name)
+				listed = true
+			case f.Until == version:
+				fmt.Printf("  - %s (removed)\n", f.Name)
+				listed = true
+			}
+		}
+		if !listed {
+			fmt.Println("  (baseline)")
+		}
+	}
+	return nil
+}
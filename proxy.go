@@ -0,0 +1,558 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// proxyLogLimit is the maximum number of bytes of a body or event shown in the log
+	proxyLogLimit = 4096
+	// mcpSessionHeader carries the streamable HTTP session ID
+	mcpSessionHeader = "Mcp-Session-Id"
+)
+
+// hopHeaders are connection-specific headers that are not forwarded
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// faultProxy forwards MCP traffic to a target server, injecting faults
+type faultProxy struct {
+	target      *url.URL
+	latency     time.Duration
+	jitter      time.Duration
+	errorRate   float64
+	errorStatus int
+	dropRate    float64
+	corruptRate float64
+	logBodies   bool
+	client      *http.Client
+	logger      *log.Logger
+
+	// recorder captures the traffic as a session recording (observation mode)
+	recorder *sessionRecorder
+	// tracer pretty-prints the messages instead of logging raw bodies
+	tracer *wireTracer
+	// validator checks the messages against JSON-RPC and the MCP schema
+	validator *proxyValidator
+	// ssePaths holds the message endpoints announced by SSE servers
+	ssePaths sync.Map
+}
+
+// runProxyCommand implements the 'proxy' subcommand
+func runProxyCommand(args []string) error {
+	fs := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:9000", "Address to listen on")
+	target := fs.String("target", "", "URL of the MCP server to forward to (required)")
+	fs.StringVar(target, "upstream", "", "Same as -target")
+	latency := fs.Duration("latency", 0, "Delay added before forwarding each request")
+	jitter := fs.Duration("jitter", 0, "Random extra delay of up to this much per request")
+	errorRate := fs.Float64("error-rate", 0, "Fraction of requests answered with an HTTP error instead of being forwarded (0-1)")
+	errorStatus := fs.Int("error-status", http.StatusServiceUnavailable, "HTTP status returned for injected errors")
+	dropRate := fs.Float64("drop-rate", 0, "Fraction of SSE events to drop (0-1)")
+	corruptRate := fs.Float64("corrupt-rate", 0, "Fraction of response bodies and SSE events to corrupt (0-1)")
+	logBodies := fs.Bool("log-bodies", true, "Log request and response bodies and SSE events")
+	record := fs.String("record", "", "Record the traffic to this session recording file (observation only; no faults)")
+	pretty := fs.Bool("pretty", false, "Log each JSON-RPC message as pretty-printed JSON with its direction and timing, instead of raw bodies")
+	validate := fs.Bool("validate", false, "Check every message against JSON-RPC 2.0 and every response against the MCP schema, and log violations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *target == "" {
+		return fmt.Errorf("-target (or -upstream) is required")
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		return fmt.Errorf("invalid -target URL '%s'", *target)
+	}
+	for name, rate := range map[string]float64{"error-rate": *errorRate, "drop-rate": *dropRate, "corrupt-rate": *corruptRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("-%s must be between 0 and 1", name)
+		}
+	}
+	if *errorStatus < 400 || *errorStatus > 599 {
+		return fmt.Errorf("-error-status must be an HTTP error status (400-599)")
+	}
+	if *record != "" && (*latency > 0 || *jitter > 0 || *errorRate > 0 || *dropRate > 0 || *corruptRate > 0) {
+		return fmt.Errorf("-record captures real traffic and cannot be combined with fault injection")
+	}
+
+	p := &faultProxy{
+		target:      targetURL,
+		latency:     *latency,
+		jitter:      *jitter,
+		errorRate:   *errorRate,
+		errorStatus: *errorStatus,
+		dropRate:    *dropRate,
+		corruptRate: *corruptRate,
+		logBodies:   *logBodies,
+		client: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+			// Redirects are passed through to the client
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		logger: log.New(os.Stdout, "", log.Ltime|log.Lmicroseconds),
+	}
+	if *pretty {
+		p.tracer = newWireTracer()
+		p.logBodies = false
+	}
+	if *validate {
+		p.validator = newProxyValidator()
+	}
+	if *record != "" {
+		if p.recorder, err = newSessionRecorder(*record); err != nil {
+			return err
+		}
+		defer func() { _ = p.recorder.Close() }()
+	}
+
+	p.logger.Printf("Proxying http://%s -> %s", *listen, targetURL)
+	if p.recorder != nil {
+		p.logger.Printf("Recording session to %s", *record)
+	} else {
+		p.logger.Printf("Faults: latency=%s jitter=%s error-rate=%.2f (status %d) drop-rate=%.2f corrupt-rate=%.2f",
+			p.latency, p.jitter, p.errorRate, p.errorStatus, p.dropRate, p.corruptRate)
+	}
+	if p.validator != nil {
+		p.logger.Printf("Validating messages against JSON-RPC 2.0 and the MCP schema")
+	}
+	server := &http.Server{Addr: *listen, Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	return server.ListenAndServe()
+}
+
+// chance returns true with the given probability
+func chance(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// upstreamURL maps an incoming request onto the target server. The path is
+// kept so that SSE message endpoints work; a request for / uses the target path.
+func (p *faultProxy) upstreamURL(r *http.Request) *url.URL {
+	u := *p.target
+	if r.URL.Path != "/" {
+		u.Path = r.URL.Path
+		u.RawPath = r.URL.RawPath
+	}
+	if r.URL.RawQuery != "" {
+		u.RawQuery = r.URL.RawQuery
+	}
+	return &u
+}
+
+func (p *faultProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	p.logger.Printf("-> %s %s%s", r.Method, r.URL.RequestURI(), p.bodyForLog(body))
+	p.inspect(p.recordFor(r, directionClient, 0, ""), body)
+
+	if delay := p.latency + randomDuration(p.jitter); delay > 0 {
+		p.logger.Printf("   injected delay %s", humanDuration(delay))
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if chance(p.errorRate) {
+		p.logger.Printf("<- %d (injected error)", p.errorStatus)
+		http.Error(w, fmt.Sprintf("%s fault proxy: injected error", ProgName), p.errorStatus)
+		return
+	}
+
+	upstream, err := http.NewRequestWithContext(r.Context(), r.Method, p.upstreamURL(r).String(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	upstream.Header = r.Header.Clone()
+	removeHopHeaders(upstream.Header)
+	// Let the transport negotiate compression so bodies can be logged and altered
+	upstream.Header.Del("Accept-Encoding")
+	upstream.Host = p.target.Host
+
+	resp, err := p.client.Do(upstream)
+	if err != nil {
+		p.logger.Printf("<- 502 upstream error: %v", err)
+		http.Error(w, fmt.Sprintf("upstream error: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	removeHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		p.logger.Printf("<- %d event stream (%s)", resp.StatusCode, humanDuration(time.Since(start)))
+		w.Header().Del("Content-Length")
+		w.WriteHeader(resp.StatusCode)
+		p.streamEvents(w, resp.Body, r, resp)
+		p.logger.Printf("   event stream closed (%s)", r.URL.Path)
+		return
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		p.logger.Printf("<- 502 failed to read upstream response: %v", err)
+		http.Error(w, "failed to read upstream response", http.StatusBadGateway)
+		return
+	}
+	note := ""
+	if len(respBody) > 0 && chance(p.corruptRate) {
+		respBody = corruptFrame(respBody)
+		note = " (injected corruption)"
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(respBody)
+	if p.recorder != nil && len(bytes.TrimSpace(respBody)) == 0 && resp.StatusCode >= 400 {
+		rec := p.recordFor(r, directionServer, resp.StatusCode, resp.Header.Get(mcpSessionHeader))
+		rec.Error = resp.Status
+		p.recorder.record(rec)
+	}
+	p.logger.Printf("<- %d %s (%s)%s%s", resp.StatusCode, resp.Header.Get("Content-Type"),
+		humanDuration(time.Since(start)), note, p.bodyForLog(respBody))
+	p.inspect(p.recordFor(r, directionServer, resp.StatusCode, resp.Header.Get(mcpSessionHeader)), respBody)
+}
+
+// inspect records, pretty-prints and validates the JSON-RPC messages of a
+// body, as configured. Bodies that are not JSON-RPC, such as the HTML of an
+// error page, are only recorded.
+func (p *faultProxy) inspect(rec sessionRecord, body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+	if p.recorder != nil {
+		p.recorder.recordMessages(rec, body)
+	}
+	if p.tracer != nil {
+		p.tracer.traceMessages(rec, body)
+	}
+	if p.validator != nil {
+		for _, v := range p.validator.check(rec, body) {
+			p.logger.Printf("   invalid %s", v)
+		}
+	}
+}
+
+// streamEvents forwards an SSE stream event by event, dropping or corrupting
+// events as configured. Endpoint events that point at the target are
+// rewritten to point at the proxy.
+func (p *faultProxy) streamEvents(w http.ResponseWriter, body io.Reader, r *http.Request, resp *http.Response) {
+	flusher, _ := w.(http.Flusher)
+	reader := bufio.NewReader(body)
+	var event []string
+	session := resp.Header.Get(mcpSessionHeader)
+	legacySSE := false
+
+	flush := func() bool {
+		if len(event) == 0 {
+			return true
+		}
+		lines := event
+		event = nil
+
+		name := "message"
+		for _, line := range lines {
+			if v, ok := strings.CutPrefix(line, "event:"); ok {
+				name = strings.TrimSpace(v)
+			}
+		}
+		if name == "endpoint" {
+			lines = p.rewriteEndpoint(lines, r)
+			if id := p.rememberEndpoint(lines); id != "" && session == "" {
+				session = id
+			}
+			legacySSE = true
+		}
+		raw := []byte(strings.Join(lines, "\n"))
+		if name == "message" {
+			rec := p.recordFor(r, directionServer, resp.StatusCode, session)
+			if legacySSE {
+				rec.Transport = "sse"
+			}
+			p.inspect(rec, eventData(lines))
+		}
+
+		switch {
+		case chance(p.dropRate):
+			p.logger.Printf("   dropped event %s%s", name, p.bodyForLog(raw))
+			return true
+		case chance(p.corruptRate):
+			raw = corruptFrame(raw)
+			p.logger.Printf("   event %s (injected corruption)%s", name, p.bodyForLog(raw))
+		default:
+			p.logger.Printf("   event %s%s", name, p.bodyForLog(raw))
+		}
+
+		if _, err := w.Write(append(raw, '\n', '\n')); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" && err == nil {
+			if !flush() {
+				return
+			}
+			continue
+		}
+		if line != "" {
+			event = append(event, line)
+		}
+		if err != nil {
+			flush()
+			return
+		}
+	}
+}
+
+// rewriteEndpoint makes an absolute SSE endpoint URL on the target point at the proxy
+func (p *faultProxy) rewriteEndpoint(lines []string, r *http.Request) []string {
+	origin := p.target.Scheme + "://" + p.target.Host
+	proxyOrigin := "http://" + r.Host
+	rewritten := make([]string, len(lines))
+	for i, line := range lines {
+		if data, ok := strings.CutPrefix(line, "data:"); ok && strings.HasPrefix(strings.TrimSpace(data), origin) {
+			line = "data: " + proxyOrigin + strings.TrimPrefix(strings.TrimSpace(data), origin)
+		}
+		rewritten[i] = line
+	}
+	return rewritten
+}
+
+// rememberEndpoint notes the message endpoint announced by an SSE server so
+// that requests to it are recorded as the SSE transport. It returns the
+// session ID from the endpoint, if any.
+func (p *faultProxy) rememberEndpoint(lines []string) string {
+	endpoint, err := url.Parse(strings.TrimSpace(string(eventData(lines))))
+	if err != nil {
+		return ""
+	}
+	p.ssePaths.Store(endpoint.Path, true)
+	if id := endpoint.Query().Get("sessionId"); id != "" {
+		return id
+	}
+	return endpoint.Query().Get("session_id")
+}
+
+// recordFor returns a session record describing a request to the proxy
+func (p *faultProxy) recordFor(r *http.Request, direction string, status int, session string) sessionRecord {
+	transportName := "http"
+	if _, ok := p.ssePaths.Load(r.URL.Path); ok {
+		transportName = "sse"
+	}
+	if session == "" {
+		session = r.Header.Get(mcpSessionHeader)
+	}
+	if session == "" {
+		session = r.URL.Query().Get("sessionId")
+	}
+	if session == "" {
+		session = r.URL.Query().Get("session_id")
+	}
+	return sessionRecord{
+		Direction:  direction,
+		Transport:  transportName,
+		HTTPMethod: r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		Session:    session,
+	}
+}
+
+// eventData returns the data of an SSE event, joining multi-line data
+func eventData(lines []string) []byte {
+	var data []string
+	for _, line := range lines {
+		if v, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(v, " "))
+		}
+	}
+	return []byte(strings.Join(data, "\n"))
+}
+
+// bodyForLog formats a body for logging, truncating long bodies
+func (p *faultProxy) bodyForLog(body []byte) string {
+	if !p.logBodies {
+		return ""
+	}
+	return formatLogBody(body)
+}
+
+// formatLogBody indents a body under its log line, truncating long bodies
+func formatLogBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	text := string(body)
+	if len(text) > proxyLogLimit {
+		text = text[:proxyLogLimit] + fmt.Sprintf("... (%s)", humanBytes(int64(len(body))))
+	}
+	return "\n   " + strings.ReplaceAll(text, "\n", "\n   ")
+}
+
+// corruptFrame damages a frame by truncating it at a random point, which
+// leaves JSON payloads unparseable
+func corruptFrame(frame []byte) []byte {
+	if len(frame) < 2 {
+		return []byte("\x00")
+	}
+	return append([]byte{}, frame[:1+rand.IntN(len(frame)-1)]...)
+}
+
+// randomDuration returns a random duration in [0, limit)
+func randomDuration(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(limit)))
+}
+
+// removeHopHeaders deletes connection-specific headers
+func removeHopHeaders(header http.Header) {
+	for _, h := range hopHeaders {
+		header.Del(h)
+	}
+}
+
+// proxyValidator checks the messages passing through the proxy: each must be
+// a JSON-RPC 2.0 message, and each response is checked as -strict checks the
+// probe's own, against the request it answers and the MCP schema of the
+// session's negotiated protocol version
+type proxyValidator struct {
+	mu sync.Mutex
+	// pending are the requests awaiting a response, by direction, session
+	// and ID
+	pending map[string]transport.JSONRPCRequest
+	// versions are the negotiated protocol versions by session
+	versions map[string]string
+}
+
+func newProxyValidator() *proxyValidator {
+	return &proxyValidator{pending: map[string]transport.JSONRPCRequest{}, versions: map[string]string{}}
+}
+
+// check returns the violations of the messages in a body, each prefixed with
+// the method it concerns
+func (v *proxyValidator) check(rec sessionRecord, body []byte) []string {
+	if !json.Valid(body) {
+		return []string{"message: is not valid JSON"}
+	}
+	messages := []json.RawMessage{body}
+	if body[0] == '[' {
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return []string{"batch: is not an array of messages"}
+		}
+	}
+	var violations []string
+	for _, msg := range messages {
+		for i, violation := range v.checkMessage(rec, msg) {
+			if i == maxStrictViolations {
+				violations = append(violations, "... more violations not shown")
+				break
+			}
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+// checkMessage validates one message. Requests are remembered so that their
+// responses can be checked; the responses of the other side answer them.
+func (v *proxyValidator) checkMessage(rec sessionRecord, msg json.RawMessage) []string {
+	var m struct {
+		JSONRPC *string         `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  *string         `json:"method"`
+	}
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return []string{"message: is not a JSON object"}
+	}
+	if m.Method != nil {
+		var violations []string
+		if m.JSONRPC == nil || *m.JSONRPC != mcp.JSONRPC_VERSION {
+			violations = append(violations, fmt.Sprintf(`%s: /jsonrpc: must be "2.0"`, *m.Method))
+		}
+		if *m.Method == "" {
+			violations = append(violations, `request: /method: must not be empty`)
+		}
+		var request transport.JSONRPCRequest
+		if len(m.ID) > 0 && json.Unmarshal(msg, &request) == nil {
+			v.mu.Lock()
+			v.pending[rec.Direction+" "+rec.Session+" "+string(m.ID)] = request
+			v.mu.Unlock()
+		}
+		return violations
+	}
+
+	var response transport.JSONRPCResponse
+	if err := json.Unmarshal(msg, &response); err != nil {
+		return []string{fmt.Sprintf("response: is not a valid response: %v", err)}
+	}
+	answers := directionClient
+	if rec.Direction == directionClient {
+		answers = directionServer
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// The response to initialize is the first to carry its session
+	for _, key := range []string{answers + " " + rec.Session + " " + string(m.ID), answers + "  " + string(m.ID)} {
+		request, ok := v.pending[key]
+		if !ok {
+			continue
+		}
+		delete(v.pending, key)
+		violations, negotiated := responseViolations(request, &response, v.versions[rec.Session])
+		if negotiated != "" {
+			v.versions[rec.Session] = negotiated
+		}
+		for i := range violations {
+			violations[i] = request.Method + ": " + violations[i]
+		}
+		return violations
+	}
+	if len(m.ID) == 0 || string(m.ID) == "null" {
+		// Errors about messages whose ID could not be read have none
+		return nil
+	}
+	return []string{fmt.Sprintf("response: /id: %s answers no pending request", m.ID)}
+}
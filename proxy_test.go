@@ -0,0 +1,193 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lockedBuffer is a buffer that a server's goroutines can write to while
+// the test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startProxy serves the proxy in front of the target and returns the URL
+// of the target's endpoint through it and the proxy's log
+func startProxy(t *testing.T, p *faultProxy, target string) (string, *lockedBuffer) {
+	t.Helper()
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := &lockedBuffer{}
+	p.target = targetURL
+	p.client = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	p.logger = log.New(logs, "", 0)
+	proxyServer := httptest.NewServer(p)
+	t.Cleanup(proxyServer.Close)
+	return proxyServer.URL + targetURL.Path, logs
+}
+
+func TestProxy(t *testing.T) {
+	// A tool without its inputSchema, which the client library accepts
+	noSchema := interceptMethod("tools/list", func(w http.ResponseWriter, id json.RawMessage) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"echo"}]}}`, id)
+	})
+
+	tests := []struct {
+		name     string
+		proxy    *faultProxy
+		wrap     func(http.Handler) http.Handler
+		args     []string
+		exitCode int
+		logged   []string
+		invalid  bool
+	}{
+		{
+			name:   "forwarded",
+			proxy:  &faultProxy{validator: newProxyValidator()},
+			args:   []string{"-list"},
+			logged: []string{"-> POST /mcp", "<- 200 application/json"},
+		},
+		{
+			name:     "injected errors",
+			proxy:    &faultProxy{errorRate: 1, errorStatus: http.StatusServiceUnavailable},
+			args:     []string{"-list"},
+			exitCode: 1,
+			logged:   []string{"<- 503 (injected error)"},
+		},
+		{
+			name:    "invalid response passed on",
+			proxy:   &faultProxy{validator: newProxyValidator()},
+			wrap:    noSchema,
+			args:    []string{"-list"},
+			logged:  []string{`invalid tools/list: /result/tools/0: "inputSchema" is required`},
+			invalid: true,
+		},
+		{
+			name:     "invalid response failing the probe",
+			proxy:    &faultProxy{validator: newProxyValidator()},
+			wrap:     noSchema,
+			args:     []string{"-list", "-strict", "-fail-level", "error"},
+			exitCode: 1,
+			logged:   []string{`invalid tools/list: /result/tools/0: "inputSchema" is required`},
+			invalid:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyURL, logs := startProxy(t, tt.proxy, serveMock(t, defaultMockConfig, tt.wrap))
+			code, output := runProbe(t, append([]string{"-url", proxyURL}, tt.args...)...)
+			if code != tt.exitCode {
+				t.Errorf("exit code = %d, want %d\n%s", code, tt.exitCode, output)
+			}
+			proxyLog := logs.String()
+			for _, want := range tt.logged {
+				if !strings.Contains(proxyLog, want) {
+					t.Errorf("the proxy did not log %q", want)
+				}
+			}
+			if got := strings.Contains(proxyLog, "   invalid "); got != tt.invalid {
+				t.Errorf("violations logged = %v, want %v", got, tt.invalid)
+			}
+			if t.Failed() {
+				t.Logf("proxy log:\n%s", proxyLog)
+			}
+		})
+	}
+}
+
+func TestProxyValidator(t *testing.T) {
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`
+	tests := []struct {
+		name     string
+		messages []string
+		want     []string
+	}{
+		{"valid exchange", []string{
+			initialize,
+			`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","capabilities":{},"serverInfo":{"name":"s","version":"1"}}}`,
+			`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			`{"jsonrpc":"2.0","id":2,"result":{"tools":[]}}`,
+		}, nil},
+		{"batch", []string{
+			`[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`,
+			`[{"jsonrpc":"2.0","id":1,"result":{}}]`,
+		}, nil},
+		{"not JSON", []string{`{"jsonrpc":`}, []string{"message: is not valid JSON"}},
+		{"not an object", []string{`42`}, []string{"message: is not a JSON object"}},
+		{"wrong version", []string{`{"jsonrpc":"1.0","id":1,"method":"ping"}`}, []string{`ping: /jsonrpc: must be "2.0"`}},
+		{"empty method", []string{`{"jsonrpc":"2.0","id":1,"method":""}`}, []string{"request: /method: must not be empty"}},
+		{"unanswered id", []string{`{"jsonrpc":"2.0","id":9,"result":{}}`}, []string{"response: /id: 9 answers no pending request"}},
+		{"result and error", []string{
+			`{"jsonrpc":"2.0","id":1,"method":"ping"}`,
+			`{"jsonrpc":"2.0","id":1,"result":{},"error":{"code":-32603,"message":"failed"}}`,
+		}, []string{"ping: /: a response must not have both a result and an error"}},
+		{"result shape", []string{
+			initialize,
+			`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","capabilities":{},"serverInfo":{"name":"s","version":"1"}}}`,
+			`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"echo"}]}}`,
+		}, []string{`tools/list: /result/tools/0: "inputSchema" is required`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newProxyValidator()
+			var got []string
+			for i, message := range tt.messages {
+				// Requests come from the client, responses from the server
+				rec := sessionRecord{Direction: directionClient, Session: "s1"}
+				if i%2 == 1 {
+					rec.Direction = directionServer
+				}
+				got = append(got, v.check(rec, []byte(message))...)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyOptions(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "-target (or -upstream) is required"},
+		{[]string{"-upstream", "mock.example.com/mcp"}, "invalid -target URL"},
+		{[]string{"-upstream", "http://127.0.0.1:1/mcp", "-drop-rate", "1.5"}, "-drop-rate must be between 0 and 1"},
+		{[]string{"-upstream", "http://127.0.0.1:1/mcp", "-error-status", "200"}, "-error-status must be an HTTP error status"},
+		{[]string{"-upstream", "http://127.0.0.1:1/mcp", "-record", "session.jsonl", "-latency", "1s"}, "cannot be combined with fault injection"},
+	}
+	for _, tt := range tests {
+		if err := runProxyCommand(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("proxy %v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// splitCommandLine splits an interactive command line into words like a
+// shell: words are separated by spaces, and single quotes, double quotes and
+// backslashes can be used to include spaces, as in query="golang mcp"
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// findInteractiveTool looks up a tool by its number in the list or its name
+func findInteractiveTool(tools []mcp.Tool, spec string) *mcp.Tool {
+	if num, err := strconv.Atoi(spec); err == nil && num > 0 && num <= len(tools) {
+		return &tools[num-1]
+	}
+	for i := range tools {
+		if tools[i].Name == spec {
+			return &tools[i]
+		}
+	}
+	return nil
+}
+
+// toolInputSchema returns the properties and required parameters of a
+// tool's input schema, whether given as a schema or raw JSON
+func toolInputSchema(tool *mcp.Tool) (map[string]any, map[string]bool) {
+	data, err := json.Marshal(tool)
+	if err != nil {
+		return nil, nil
+	}
+	var parsed struct {
+		InputSchema struct {
+			Properties map[string]any `json:"properties"`
+			Required   []string       `json:"required"`
+		} `json:"inputSchema"`
+	}
+	if json.Unmarshal(data, &parsed) != nil {
+		return nil, nil
+	}
+	required := map[string]bool{}
+	for _, name := range parsed.InputSchema.Required {
+		required[name] = true
+	}
+	return parsed.InputSchema.Properties, required
+}
+
+// parseQuickCallArgs converts key=value arguments to tool parameters,
+// coercing each value to the type its schema property declares
+func parseQuickCallArgs(tool *mcp.Tool, args []string) (map[string]any, error) {
+	properties, required := toolInputSchema(tool)
+	params := map[string]any{}
+	for _, arg := range args {
+		name, raw, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid argument '%s' (expected name=value)", arg)
+		}
+		prop, known := properties[name]
+		if !known {
+			fmt.Printf("Warning: %s\n", report.addWarning(warningProbe, "'%s' is not a parameter of %s; sending it as given", name, tool.Name))
+		}
+		value, err := coerceArgValue(raw, prop)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", name, err)
+		}
+		params[name] = value
+	}
+
+	var missing []string
+	for name := range required {
+		if _, ok := params[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing required parameter(s) %s", strings.Join(missing, ", "))
+	}
+	return params, nil
+}
+
+// coerceArgValue converts a command line value to the first type declared by
+// a schema property that it is valid for. Without a declared type, JSON
+// values are decoded and anything else is a string.
+func coerceArgValue(raw string, prop any) (any, error) {
+	var types []string
+	for _, t := range schemaPropertyTypes(prop) {
+		if t != "null" || raw == "null" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		var value any
+		if json.Unmarshal([]byte(raw), &value) == nil {
+			return value, nil
+		}
+		return raw, nil
+	}
+
+	for _, t := range types {
+		switch t {
+		case "string":
+			return raw, nil
+		case "integer":
+			if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return n, nil
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return f, nil
+			}
+		case "boolean":
+			switch strings.ToLower(raw) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0":
+				return false, nil
+			}
+		case "null":
+			return nil, nil
+		case "array":
+			var arr []any
+			if json.Unmarshal([]byte(raw), &arr) == nil {
+				return arr, nil
+			}
+			// Comma separated items are coerced to the item type
+			schema, _ := prop.(map[string]any)
+			items := []any{}
+			for _, item := range strings.Split(raw, ",") {
+				value, err := coerceArgValue(strings.TrimSpace(item), schema["items"])
+				if err != nil {
+					return nil, fmt.Errorf("item '%s': %w", item, err)
+				}
+				items = append(items, value)
+			}
+			return items, nil
+		case "object":
+			var obj map[string]any
+			if json.Unmarshal([]byte(raw), &obj) == nil {
+				return obj, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("'%s' is not a valid %s", raw, typeList(types))
+}
+
+// callToolQuick calls a tool with key=value arguments from the interactive
+// command line instead of prompting for each parameter
+func callToolQuick(mcpClient *client.Client, tool *mcp.Tool, args []string, scanner *bufio.Scanner, timeout time.Duration, verbose bool) error {
+	params, err := parseQuickCallArgs(tool, args)
+	if err != nil {
+		return err
+	}
+	if !confirmDestructiveCall(tool, scanner) {
+		return nil
+	}
+	displayToolRequest(tool.Name, params, verbose)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      tool.Name,
+			Arguments: params,
+		},
+	}
+	fmt.Printf("\nCalling tool '%s'...\n", tool.Name)
+	result, err := callToolRecorded(ctx, mcpClient, request)
+	if err != nil {
+		return fmt.Errorf("failed to call tool: %w", err)
+	}
+	formatToolResult(result, verbose)
+	saveToolContent(tool.Name, result)
+	return checkToolResult(tool.Name, result)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// rawRequestID is the ID of the -raw-method request, clear of the IDs the
+// client assigns and of the other raw requests
+const rawRequestID = 5_000_000
+
+// parseRawParams checks -raw-params: a JSON object or array, as JSON-RPC
+// allows, or empty to send no params
+func parseRawParams(spec string) (json.RawMessage, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	var params any
+	if err := json.Unmarshal([]byte(spec), &params); err != nil {
+		return nil, fmt.Errorf("-raw-params is not valid JSON: %w", err)
+	}
+	switch params.(type) {
+	case map[string]any, []any:
+		return json.RawMessage(spec), nil
+	default:
+		return nil, fmt.Errorf("-raw-params must be a JSON object or array")
+	}
+}
+
+// sendRawRequest sends an arbitrary request over the session and prints
+// the server's JSON-RPC response, unmodelled fields included. The response
+// goes to the result output, so that it is the only output with -q. A
+// JSON-RPC error response is printed and returned as an error.
+func sendRawRequest(ctx context.Context, mcpClient *client.Client, method string, params json.RawMessage) error {
+	fmt.Println("\n=== Raw Request ===")
+	request := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(int64(rawRequestID)),
+		Method:  method,
+		Params:  params,
+	}
+	if data, err := json.MarshalIndent(request, "", "  "); err == nil {
+		fmt.Println(string(data))
+	}
+
+	start := time.Now()
+	response, err := mcpClient.GetTransport().SendRequest(ctx, request)
+	duration := time.Since(start)
+	if err == nil && response.Error != nil {
+		err = &rpcError{Code: response.Error.Code, Message: response.Error.Message}
+	}
+	report.addTiming(method, duration, err)
+	if response == nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+
+	fmt.Printf("\n=== Raw Response (%s) ===\n", humanDuration(duration))
+	data, encodeErr := json.MarshalIndent(response, "", "  ")
+	if encodeErr != nil {
+		return fmt.Errorf("failed to encode the response to %s: %w", method, encodeErr)
+	}
+	fmt.Fprintln(resultOut, string(data))
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	return nil
+}
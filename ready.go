@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+)
+
+// readyPollInterval is how long to wait between readiness attempts
+const readyPollInterval = 2 * time.Second
+
+// waitForReady repeatedly connects to the server and performs the
+// initialization handshake until it succeeds or waitTimeout passes. Each
+// attempt uses a fresh client which is closed afterwards. This lets CI
+// pipelines block until a freshly started server can be probed.
+func waitForReady(dial func(ctx context.Context) (*client.Client, error), waitTimeout, attemptTimeout time.Duration) error {
+	fmt.Printf("Waiting up to %s for the server to become ready...\n", humanDuration(waitTimeout))
+
+	start := time.Now()
+	deadline := start.Add(waitTimeout)
+	for attempt := 1; ; attempt++ {
+		err := readyAttempt(dial, min(attemptTimeout, time.Until(deadline)))
+		if err == nil {
+			elapsed := time.Since(start)
+			report.addTiming("wait-ready", elapsed, nil)
+			emitEvent(eventWaitReady, map[string]any{
+				"attempts":   attempt,
+				"durationMs": durationMillis(elapsed),
+			})
+			fmt.Printf("Server ready after %s (%d attempt(s))\n\n", humanDuration(elapsed), attempt)
+			return nil
+		}
+
+		fmt.Printf("  Attempt %d: not ready: %v\n", attempt, err)
+		if time.Until(deadline) < readyPollInterval {
+			elapsed := time.Since(start)
+			report.addTiming("wait-ready", elapsed, err)
+			emitEvent(eventWaitReady, map[string]any{
+				"attempts":   attempt,
+				"durationMs": durationMillis(elapsed),
+				"error":      errorField(err),
+			})
+			return fmt.Errorf("gave up after %s (%d attempts): %w", humanDuration(elapsed), attempt, err)
+		}
+		time.Sleep(readyPollInterval)
+	}
+}
+
+// readyAttempt makes a single connect and initialize attempt
+func readyAttempt(dial func(ctx context.Context) (*client.Client, error), timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mcpClient, err := dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = mcpClient.Close() }()
+
+	_, err = mcpClient.Initialize(ctx, newInitializeRequest())
+	// A server that demands authorization is up and able to respond
+	if isUnauthorized(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message directions in a session recording
+const (
+	directionClient = "client_to_server"
+	directionServer = "server_to_client"
+)
+
+// sessionRecord is one line of a session recording (JSON Lines). Each record
+// holds a single JSON-RPC message with the transport details it was seen
+// with; HTTP errors without a JSON-RPC body are recorded with Error set.
+type sessionRecord struct {
+	Time       timestamp       `json:"time"`
+	Direction  string          `json:"direction"`
+	Transport  string          `json:"transport,omitempty"`
+	HTTPMethod string          `json:"http_method,omitempty"`
+	Path       string          `json:"path,omitempty"`
+	Status     int             `json:"status,omitempty"`
+	Session    string          `json:"session,omitempty"`
+	Message    json.RawMessage `json:"message,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// sessionRecorder appends records to a session recording file
+type sessionRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// newSessionRecorder creates (or truncates) a session recording file
+func newSessionRecorder(path string) (*sessionRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+	return &sessionRecorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// record writes one record, stamping it with the current time if unset
+func (r *sessionRecorder) record(rec sessionRecord) {
+	if time.Time(rec.Time).IsZero() {
+		rec.Time = timestamp(time.Now())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(rec); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", report.addWarning(warningProbe, "failed to write recording: %v", err))
+	}
+}
+
+// recordMessages records each JSON-RPC message in body, which may be a single
+// message or a batch. Bodies that are not JSON are recorded as an error.
+func (r *sessionRecorder) recordMessages(rec sessionRecord, body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+	if !json.Valid(body) {
+		rec.Error = string(body)
+		r.record(rec)
+		return
+	}
+
+	var batch []json.RawMessage
+	if body[0] == '[' && json.Unmarshal(body, &batch) == nil {
+		for _, msg := range batch {
+			rec.Message = msg
+			r.record(rec)
+		}
+		return
+	}
+	rec.Message = json.RawMessage(body)
+	r.record(rec)
+}
+
+// Close closes the recording file
+func (r *sessionRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// readSessionRecording loads all records from a session recording file
+func readSessionRecording(path string) ([]sessionRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var records []sessionRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec sessionRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid record: %w", path, lineNum, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return records, nil
+}
+
+// recordedExchange is a recorded client request and, if one was recorded,
+// the server's response to it
+type recordedExchange struct {
+	Method   string
+	Params   json.RawMessage
+	Result   json.RawMessage
+	Error    json.RawMessage
+	Sent     time.Time
+	Received time.Time
+	Answered bool
+}
+
+// pairRecordedExchanges pairs the client requests in a session recording
+// with the server's responses, in the order the requests were sent, and
+// counts the sessions seen
+func pairRecordedExchanges(records []sessionRecord) ([]*recordedExchange, int) {
+	var exchanges []*recordedExchange
+	sessions := map[string]bool{}
+	// Request IDs restart in every session, so responses are matched to the
+	// oldest outstanding request with the same ID
+	pending := map[string][]*recordedExchange{}
+
+	for _, rec := range records {
+		if rec.Session != "" {
+			sessions[rec.Session] = true
+		}
+		if len(rec.Message) == 0 {
+			continue
+		}
+		var msg struct {
+			jsonrpcMessage
+			Result json.RawMessage `json:"result,omitempty"`
+			Error  json.RawMessage `json:"error,omitempty"`
+		}
+		if json.Unmarshal(rec.Message, &msg) != nil || len(msg.ID) == 0 || string(msg.ID) == "null" {
+			continue
+		}
+		id := string(msg.ID)
+
+		switch {
+		case rec.Direction == directionClient && msg.Method != "":
+			ex := &recordedExchange{Method: msg.Method, Params: msg.Params, Sent: time.Time(rec.Time)}
+			exchanges = append(exchanges, ex)
+			pending[id] = append(pending[id], ex)
+		case rec.Direction == directionServer && msg.Method == "":
+			queue := pending[id]
+			if len(queue) == 0 {
+				continue
+			}
+			ex := queue[0]
+			pending[id] = queue[1:]
+			ex.Result, ex.Error, ex.Received, ex.Answered = msg.Result, msg.Error, time.Time(rec.Time), true
+		}
+	}
+	return exchanges, len(sessions)
+}
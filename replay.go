@@ -0,0 +1,413 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// replayRequestBase is the first JSON-RPC ID of replayed requests; the
+	// recorded IDs are not reused, as they restart in every session
+	replayRequestBase = 13000000
+
+	// replayMaxDifferences is how many differences are listed per response
+	replayMaxDifferences = 5
+
+	// replayMaxValue is how much of a differing value is shown
+	replayMaxValue = 60
+)
+
+// replayCommandArgs turns "replay <capture.jsonl> [flags]" into the
+// equivalent -replay flag, so that the recording is replayed with the
+// probe's usual connection options
+func replayCommandArgs(args []string) ([]string, error) {
+	if len(args) < 3 || strings.HasPrefix(args[2], "-") {
+		return nil, fmt.Errorf("usage: probe replay <capture.jsonl> -url <server-url> [options]")
+	}
+	return append([]string{args[0], "-replay", args[2]}, args[3:]...), nil
+}
+
+// replayPace is how long the replay waits between requests: not at all,
+// the recorded gaps divided by factor, or a fixed delay
+type replayPace struct {
+	factor float64
+	delay  time.Duration
+}
+
+// parseReplayPace parses -replay-pace: "none", "recorded", a speed-up such
+// as "2x" or a fixed delay such as "100ms"
+func parseReplayPace(spec string) (replayPace, error) {
+	switch spec = strings.TrimSpace(spec); {
+	case spec == "" || spec == "none":
+		return replayPace{}, nil
+	case spec == "recorded":
+		return replayPace{factor: 1}, nil
+	case strings.HasSuffix(spec, "x"):
+		factor, err := strconv.ParseFloat(strings.TrimSuffix(spec, "x"), 64)
+		if err != nil || factor <= 0 {
+			return replayPace{}, fmt.Errorf("'%s' is not a positive speed-up such as 2x", spec)
+		}
+		return replayPace{factor: factor}, nil
+	}
+	delay, err := time.ParseDuration(spec)
+	if err != nil || delay < 0 {
+		return replayPace{}, fmt.Errorf("'%s' is not none, recorded, a speed-up such as 2x or a delay such as 100ms", spec)
+	}
+	return replayPace{delay: delay}, nil
+}
+
+// wait sleeps until the next request is due, given when the previous one
+// was sent now and in the recording
+func (p replayPace) wait(lastSent time.Time, prev, next *recordedExchange) {
+	var gap time.Duration
+	switch {
+	case p.factor > 0 && prev != nil && !prev.Sent.IsZero() && !next.Sent.IsZero():
+		gap = time.Duration(float64(next.Sent.Sub(prev.Sent)) / p.factor)
+	case p.delay > 0 && prev != nil:
+		gap = p.delay
+	}
+	if wait := time.Until(lastSent.Add(gap)); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// loadReplay reads the client requests of a session recording
+func loadReplay(path string) ([]*recordedExchange, int, error) {
+	records, err := readSessionRecording(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	exchanges, sessions := pairRecordedExchanges(records)
+	if len(exchanges) == 0 {
+		return nil, 0, fmt.Errorf("%s holds no client requests", path)
+	}
+	return exchanges, sessions, nil
+}
+
+// replayIgnored reports whether a difference at path is ignored by one of the
+// -replay-ignore patterns. Patterns are dot separated paths into the
+// response, where * matches any one key or index.
+func replayIgnored(patterns []string, path string) bool {
+	keys := strings.Split(path, ".")
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, ".")
+		if len(parts) > len(keys) {
+			continue
+		}
+		match := true
+		for i, part := range parts {
+			if part != "*" && part != keys[i] {
+				match = false
+				break
+			}
+		}
+		// A pattern also ignores everything below the path it names
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonDifferences lists the paths at which two decoded JSON values differ,
+// with the recorded and the replayed value
+func jsonDifferences(path string, recorded, replayed any, ignore []string) []string {
+	if path != "" && replayIgnored(ignore, path) {
+		return nil
+	}
+	switch r := recorded.(type) {
+	case map[string]any:
+		if n, ok := replayed.(map[string]any); ok {
+			keys := map[string]bool{}
+			for key := range r {
+				keys[key] = true
+			}
+			for key := range n {
+				keys[key] = true
+			}
+			sorted := make([]string, 0, len(keys))
+			for key := range keys {
+				sorted = append(sorted, key)
+			}
+			sort.Strings(sorted)
+			var diffs []string
+			for _, key := range sorted {
+				rv, rok := r[key]
+				nv, nok := n[key]
+				child := joinPath(path, key)
+				switch {
+				case !rok && !replayIgnored(ignore, child):
+					diffs = append(diffs, fmt.Sprintf("%s: added %s", child, replayValue(nv)))
+				case !nok && !replayIgnored(ignore, child):
+					diffs = append(diffs, fmt.Sprintf("%s: removed (was %s)", child, replayValue(rv)))
+				case rok && nok:
+					diffs = append(diffs, jsonDifferences(child, rv, nv, ignore)...)
+				}
+			}
+			return diffs
+		}
+	case []any:
+		if n, ok := replayed.([]any); ok {
+			var diffs []string
+			for i := 0; i < max(len(r), len(n)); i++ {
+				child := joinPath(path, strconv.Itoa(i))
+				switch {
+				case i >= len(r) && !replayIgnored(ignore, child):
+					diffs = append(diffs, fmt.Sprintf("%s: added %s", child, replayValue(n[i])))
+				case i >= len(n) && !replayIgnored(ignore, child):
+					diffs = append(diffs, fmt.Sprintf("%s: removed (was %s)", child, replayValue(r[i])))
+				case i < len(r) && i < len(n):
+					diffs = append(diffs, jsonDifferences(child, r[i], n[i], ignore)...)
+				}
+			}
+			return diffs
+		}
+	}
+	if reflect.DeepEqual(recorded, replayed) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %s, recorded %s", displayPath(path), replayValue(replayed), replayValue(recorded))}
+}
+
+// joinPath appends a key to a dot separated path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// replayValue shows a value in a difference, shortened if it is long
+func replayValue(value any) string {
+	data, _ := json.Marshal(value)
+	if len(data) > replayMaxValue {
+		return string(data[:replayMaxValue]) + "..."
+	}
+	return string(data)
+}
+
+// replayResponse decodes a response as {"result": ...} or {"error": ...},
+// so that a result and an error are compared as different keys
+func replayResponse(result, rpcError json.RawMessage) any {
+	var value any
+	if len(rpcError) > 0 && string(rpcError) != "null" {
+		_ = json.Unmarshal(rpcError, &value)
+		return map[string]any{"error": value}
+	}
+	_ = json.Unmarshal(result, &value)
+	return map[string]any{"result": value}
+}
+
+// replaySubject names a replayed request by its method and the tool, prompt
+// or resource it concerns
+func replaySubject(ex *recordedExchange) string {
+	label := ex.Method
+	var params struct {
+		Name string `json:"name"`
+		URI  string `json:"uri"`
+	}
+	if json.Unmarshal(ex.Params, &params) == nil {
+		switch {
+		case params.Name != "":
+			label += " " + params.Name
+		case params.URI != "":
+			label += " " + params.URI
+		}
+	}
+	return label
+}
+
+// runReplay re-sends the client requests of a session recording in the
+// recorded order and compares each response with the recorded one. Every
+// recorded initialize starts a new session; requests before the first are
+// sent in a session the probe initializes itself. A request that succeeded
+// in the recording and fails now is a C041 finding, any other difference a
+// C042 finding. It returns an error if a finding counts as an error.
+func runReplay(dial func(ctx context.Context) (*client.Client, error), exchanges []*recordedExchange, sessions int, source string, pace replayPace, ignore []string, timeout, callTimeout time.Duration) error {
+	fmt.Printf("=== Replay (%s request%s from %s) ===\n", humanCount(len(exchanges)), pluralS(len(exchanges)), source)
+	if sessions > 1 {
+		fmt.Printf("The recording holds %d sessions; they are replayed one after another\n", sessions)
+	}
+
+	var mcpClient *client.Client
+	defer func() {
+		if mcpClient != nil {
+			_ = mcpClient.Close()
+		}
+	}()
+	connect := func() error {
+		if mcpClient != nil {
+			_ = mcpClient.Close()
+		}
+		var err error
+		// The connection outlives any one request's timeout
+		if mcpClient, err = dial(context.Background()); err != nil {
+			mcpClient = nil
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		return nil
+	}
+
+	var summaries []checkSummary
+	var failed []string
+	identical, changed, skipped := 0, 0, 0
+	var lastSent time.Time
+	for i, ex := range exchanges {
+		subject := replaySubject(ex)
+		label := fmt.Sprintf("#%d %s", i+1, subject)
+		if i > 0 {
+			pace.wait(lastSent, exchanges[i-1], ex)
+		}
+
+		initialize := ex.Method == string(mcp.MethodInitialize)
+		if initialize || mcpClient == nil {
+			if err := connect(); err != nil {
+				return err
+			}
+			if !initialize {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				_, err := mcpClient.Initialize(ctx, newInitializeRequest())
+				cancel()
+				if err != nil {
+					return fmt.Errorf("failed to initialize: %w", err)
+				}
+				fmt.Println("The recording starts without initialize; the requests are sent in a session initialized by the probe")
+			}
+		}
+
+		wait := timeout
+		if ex.Method == string(mcp.MethodToolsCall) {
+			wait = callTimeout
+		}
+		request := transport.JSONRPCRequest{
+			JSONRPC: mcp.JSONRPC_VERSION,
+			ID:      mcp.NewRequestId(int64(replayRequestBase + i)),
+			Method:  ex.Method,
+		}
+		if len(ex.Params) > 0 {
+			request.Params = ex.Params
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), wait)
+		lastSent = time.Now()
+		response, err := mcpClient.GetTransport().SendRequest(ctx, request)
+		elapsed := time.Since(lastSent)
+		if err == nil && initialize && response.Error == nil {
+			err = finishReplayedInitialize(ctx, mcpClient, response.Result)
+		}
+		cancel()
+
+		summary := checkSummary{ID: label, Runs: 1, AvgTime: elapsed}
+		var detail string
+		var diffs []string
+		var f *finding
+		fail := func(check, format string, v ...any) {
+			rf := report.addFinding(check, subject, format, v...)
+			f = &rf
+			summary.Status, summary.Failed, summary.Suppressed = checkFail, 1, rf.Suppressed
+			summary.Errors = []string{rf.Message}
+			if rf.fails() {
+				failed = append(failed, label)
+			}
+		}
+		recordedOK := ex.Answered && len(ex.Error) == 0
+		switch {
+		case err != nil:
+			detail = fmt.Sprintf("failed: %v", err)
+			if ex.Answered {
+				fail(checkIDReplayRegression, "%s was answered in the recording and fails now: %v", subject, err)
+				changed++
+			} else {
+				summary.Status, summary.Skipped = checkSkip, 1
+				skipped++
+			}
+		case !ex.Answered:
+			detail = "no response was recorded"
+			summary.Status, summary.Skipped = checkSkip, 1
+			skipped++
+		default:
+			var rpcError json.RawMessage
+			if response.Error != nil {
+				rpcError, _ = json.Marshal(response.Error)
+			}
+			diffs = jsonDifferences("", replayResponse(ex.Result, ex.Error), replayResponse(response.Result, rpcError), ignore)
+			switch {
+			case len(diffs) == 0:
+				detail = "identical"
+				summary.Status, summary.Passed = checkPass, 1
+				identical++
+			case recordedOK && response.Error != nil:
+				detail = fmt.Sprintf("error %d: %s (recorded a result)", response.Error.Code, response.Error.Message)
+				fail(checkIDReplayRegression, "%s succeeded in the recording and fails now with error %d: %s", subject, response.Error.Code, response.Error.Message)
+				// The error replacing the result says it all
+				diffs = nil
+				changed++
+			default:
+				detail = fmt.Sprintf("%d difference%s", len(diffs), pluralS(len(diffs)))
+				fail(checkIDReplayChanged, "the response to %s differs from the recording in %d place%s", subject, len(diffs), pluralS(len(diffs)))
+				changed++
+			}
+		}
+
+		summaries = append(summaries, summary)
+		emitEvent(eventCheck, map[string]any{
+			"id":            label,
+			"status":        summary.Status,
+			"detail":        detail,
+			"differences":   diffs,
+			"avgDurationMs": durationMillis(elapsed),
+		})
+		fmt.Printf("  %-5s  %-28s %s\n", strings.ToUpper(summary.Status), label, detail)
+		if f != nil {
+			fmt.Printf("         %s\n", f)
+		}
+		for j, d := range diffs {
+			if j == replayMaxDifferences {
+				fmt.Printf("         ... and %d more\n", len(diffs)-j)
+				break
+			}
+			fmt.Printf("         %s\n", d)
+		}
+	}
+	report.setChecks(summaries)
+
+	fmt.Printf("\n%d requests replayed: %d identical, %d changed, %d not compared\n", len(exchanges), identical, changed, skipped)
+	if len(failed) > 0 {
+		return fmt.Errorf("the server's responses changed since the recording: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// finishReplayedInitialize completes the handshake of a replayed
+// initialize: the protocol version is set on HTTP transports and the
+// initialized notification is sent, as the client does after Initialize
+func finishReplayedInitialize(ctx context.Context, mcpClient *client.Client, result json.RawMessage) error {
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if json.Unmarshal(result, &init) == nil && init.ProtocolVersion != "" {
+		if httpConn, ok := mcpClient.GetTransport().(transport.HTTPConnection); ok {
+			httpConn.SetProtocolVersion(init.ProtocolVersion)
+		}
+	}
+	notification := mcp.JSONRPCNotification{
+		JSONRPC:      mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{Method: "notifications/initialized"},
+	}
+	if err := mcpClient.GetTransport().SendNotification(ctx, notification); err != nil {
+		return fmt.Errorf("failed to send the initialized notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replayExchange is a recorded client request and the server's response to it
+type replayExchange struct {
+	method   string
+	params   string
+	target   string
+	response json.RawMessage
+	latency  time.Duration
+}
+
+// replayServer answers requests with the responses from a session recording.
+// A request is matched to a recorded one with the same method and params; if
+// there is none, to one for the same tool, prompt or resource; and for
+// methods without a target, to any recorded call of the method. Repeated
+// matches return the recorded responses in order, then repeat the last.
+type replayServer struct {
+	realtime bool
+	logger   *log.Logger
+
+	mu       sync.Mutex
+	exact    map[string][]*replayExchange
+	byTarget map[string][]*replayExchange
+	byMethod map[string][]*replayExchange
+	served   map[string]int
+}
+
+// jsonrpcMessage holds the fields of a JSON-RPC message needed for replay
+type jsonrpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// runServeReplayCommand implements the 'serve-replay' subcommand
+func runServeReplayCommand(args []string) error {
+	var path string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("serve-replay", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:8000", "Address to listen on for the http transport")
+	transportName := fs.String("transport", "http", "Transport to serve: 'http' or 'stdio'")
+	realtime := fs.Bool("realtime", false, "Delay each response by the time the recorded server took")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		return fmt.Errorf("usage: serve-replay <session.jsonl> [-listen 127.0.0.1:8000] [-transport http|stdio] [-realtime]")
+	}
+
+	records, err := readSessionRecording(path)
+	if err != nil {
+		return err
+	}
+	// Log to stderr so that stdout stays clean for the stdio transport
+	rs := newReplayServer(records, *realtime, log.New(os.Stderr, "[replay] ", log.LstdFlags))
+	if len(rs.byMethod) == 0 {
+		return fmt.Errorf("%s contains no request/response pairs to replay", path)
+	}
+	rs.logger.Printf("Loaded %d recorded exchanges from %s", rs.count(), path)
+
+	switch strings.ToLower(*transportName) {
+	case "stdio":
+		rs.logger.Printf("Serving replay on stdio")
+		return rs.serveStdio(os.Stdin, os.Stdout)
+	case "http":
+		rs.logger.Printf("Serving replay at http://%s/mcp (probe with: -url http://%s/mcp -transport http)", *listen, *listen)
+		server := &http.Server{Addr: *listen, Handler: rs, ReadHeaderTimeout: 30 * time.Second}
+		return server.ListenAndServe()
+	default:
+		return fmt.Errorf("unsupported transport '%s' (use 'http' or 'stdio')", *transportName)
+	}
+}
+
+// newReplayServer pairs the recorded requests with their responses
+func newReplayServer(records []sessionRecord, realtime bool, logger *log.Logger) *replayServer {
+	rs := &replayServer{
+		realtime: realtime,
+		logger:   logger,
+		exact:    map[string][]*replayExchange{},
+		byTarget: map[string][]*replayExchange{},
+		byMethod: map[string][]*replayExchange{},
+		served:   map[string]int{},
+	}
+
+	type pendingRequest struct {
+		msg  jsonrpcMessage
+		time time.Time
+	}
+	// Request IDs restart in every session, so responses are matched to the
+	// oldest outstanding request with the same ID
+	pending := map[string][]pendingRequest{}
+
+	for _, rec := range records {
+		if len(rec.Message) == 0 {
+			continue
+		}
+		var msg jsonrpcMessage
+		if json.Unmarshal(rec.Message, &msg) != nil || len(msg.ID) == 0 || string(msg.ID) == "null" {
+			continue
+		}
+		id := string(msg.ID)
+
+		switch {
+		case rec.Direction == directionClient && msg.Method != "":
+			pending[id] = append(pending[id], pendingRequest{msg: msg, time: time.Time(rec.Time)})
+		case rec.Direction == directionServer && msg.Method == "":
+			queue := pending[id]
+			if len(queue) == 0 {
+				continue
+			}
+			req := queue[0]
+			pending[id] = queue[1:]
+
+			params, target := replayKey(req.msg.Params)
+			ex := &replayExchange{
+				method:   req.msg.Method,
+				params:   params,
+				target:   target,
+				response: rec.Message,
+				latency:  time.Time(rec.Time).Sub(req.time),
+			}
+			rs.exact[ex.method+"\x00"+ex.params] = append(rs.exact[ex.method+"\x00"+ex.params], ex)
+			if ex.target != "" {
+				rs.byTarget[ex.method+"\x00"+ex.target] = append(rs.byTarget[ex.method+"\x00"+ex.target], ex)
+			}
+			rs.byMethod[ex.method] = append(rs.byMethod[ex.method], ex)
+		}
+	}
+	return rs
+}
+
+// replayKey returns the canonical form of request params (without _meta) and
+// the tool or prompt name or resource URI they refer to
+func replayKey(params json.RawMessage) (string, string) {
+	var value any
+	if len(params) == 0 || json.Unmarshal(params, &value) != nil {
+		return "", ""
+	}
+	target := ""
+	if m, ok := value.(map[string]any); ok {
+		delete(m, "_meta")
+		if name, ok := m["name"].(string); ok {
+			target = name
+		} else if uri, ok := m["uri"].(string); ok {
+			target = uri
+		}
+	}
+	canonical, _ := json.Marshal(value)
+	return string(canonical), target
+}
+
+// count returns the number of recorded exchanges
+func (rs *replayServer) count() int {
+	n := 0
+	for _, list := range rs.byMethod {
+		n += len(list)
+	}
+	return n
+}
+
+// lookup finds the recorded exchange for a request
+func (rs *replayServer) lookup(msg jsonrpcMessage) *replayExchange {
+	params, target := replayKey(msg.Params)
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	key := "exact\x00" + msg.Method + "\x00" + params
+	list := rs.exact[msg.Method+"\x00"+params]
+	if len(list) == 0 && target != "" {
+		key = "target\x00" + msg.Method + "\x00" + target
+		list = rs.byTarget[msg.Method+"\x00"+target]
+	}
+	if len(list) == 0 && target == "" {
+		key = "method\x00" + msg.Method
+		list = rs.byMethod[msg.Method]
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	n := rs.served[key]
+	rs.served[key] = n + 1
+	return list[min(n, len(list)-1)]
+}
+
+// handle returns the response to one message, or nil for notifications and
+// client responses
+func (rs *replayServer) handle(raw json.RawMessage) json.RawMessage {
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return jsonrpcErrorResponse(json.RawMessage("null"), -32700, "parse error")
+	}
+	if msg.Method == "" {
+		return nil
+	}
+	if len(msg.ID) == 0 || string(msg.ID) == "null" {
+		rs.logger.Printf("<- %s (notification)", msg.Method)
+		return nil
+	}
+
+	ex := rs.lookup(msg)
+	if ex == nil {
+		rs.logger.Printf("<- %s: no recorded response", msg.Method)
+		return jsonrpcErrorResponse(msg.ID, -32601, fmt.Sprintf("no recorded response for %s", msg.Method))
+	}
+	if rs.realtime && ex.latency > 0 {
+		time.Sleep(ex.latency)
+	}
+	rs.logger.Printf("<- %s: replayed", msg.Method)
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(ex.response, &response); err != nil {
+		return jsonrpcErrorResponse(msg.ID, -32603, "invalid recorded response")
+	}
+	response["id"] = msg.ID
+	out, _ := json.Marshal(response)
+	return out
+}
+
+// handleBody answers a single message or a batch, returning nil if no
+// response is due
+func (rs *replayServer) handleBody(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return jsonrpcErrorResponse(json.RawMessage("null"), -32700, "parse error")
+		}
+		var responses []json.RawMessage
+		for _, msg := range batch {
+			if resp := rs.handle(msg); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		out, _ := json.Marshal(responses)
+		return out
+	}
+	return rs.handle(body)
+}
+
+// ServeHTTP implements the streamable HTTP transport with JSON responses
+func (rs *replayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		// Recorded sessions have no server-initiated stream to offer
+		w.Header().Set("Allow", "POST, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if bytes.Contains(body, []byte(`"initialize"`)) {
+		var msg jsonrpcMessage
+		if json.Unmarshal(body, &msg) == nil && msg.Method == "initialize" {
+			w.Header().Set(mcpSessionHeader, newSessionID())
+		}
+	}
+
+	response := rs.handleBody(body)
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(response)
+}
+
+// serveStdio implements the stdio transport (newline-delimited JSON-RPC)
+func (rs *replayServer) serveStdio(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if response := rs.handleBody(line); response != nil {
+			if _, err := fmt.Fprintf(out, "%s\n", response); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// jsonrpcErrorResponse builds a JSON-RPC error response
+func jsonrpcErrorResponse(id json.RawMessage, code int, message string) json.RawMessage {
+	out, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   map[string]any{"code": code, "message": message},
+	})
+	return out
+}
+
+// newSessionID returns a random session ID
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,433 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// probeReport collects the results of a probe run so they can be rendered
+// in structured formats (for example the HTML report) once the run finishes
+type probeReport struct {
+	mu sync.Mutex
+
+	ProbeName                string                 `json:"probeName"`
+	ProbeVersion             string                 `json:"probeVersion"`
+	Build                    *buildInfo             `json:"build"`
+	Target                   string                 `json:"target"`
+	Transport                string                 `json:"transport"`
+	SessionID                string                 `json:"sessionId,omitempty"`
+	StartedAt                timestamp              `json:"startedAt"`
+	FinishedAt               timestamp              `json:"finishedAt"`
+	ServerInfo               *mcp.Implementation    `json:"serverInfo,omitempty"`
+	ProtocolVersion          string                 `json:"protocolVersion,omitempty"`
+	RequestedProtocolVersion string                 `json:"requestedProtocolVersion,omitempty"`
+	ProtocolNotes            []protocolNote         `json:"protocolNotes,omitempty"`
+	Instructions             string                 `json:"instructions,omitempty"`
+	Capabilities             mcp.ServerCapabilities `json:"capabilities"`
+	Tools                    []mcp.Tool             `json:"tools,omitempty"`
+	ToolTitles               map[string]string      `json:"toolTitles,omitempty"`
+	ToolsAppearedLate        bool                   `json:"toolsAppearedLate,omitempty"`
+	Resources                []mcp.Resource         `json:"resources,omitempty"`
+	ResourceTemplates        []mcp.ResourceTemplate `json:"resourceTemplates,omitempty"`
+	Prompts                  []mcp.Prompt           `json:"prompts,omitempty"`
+	Pagination               []listPagination       `json:"pagination,omitempty"`
+	Endpoints                []capabilityEndpoint   `json:"capabilityEndpoints,omitempty"`
+	ToolCalls                []toolCallRecord       `json:"toolCalls,omitempty"`
+	SamplingRequests         []samplingRecord       `json:"samplingRequests,omitempty"`
+	RootsReaction            *rootsReaction         `json:"rootsReaction,omitempty"`
+	Checks                   []checkSummary         `json:"checks,omitempty"`
+	Findings                 []finding              `json:"findings,omitempty"`
+	TransportDiffs           []behaviorDifference   `json:"transportDifferences,omitempty"`
+	VersionDiffs             []behaviorDifference   `json:"protocolVersionDifferences,omitempty"`
+	VersionMatrix            []versionMatrixEntry   `json:"protocolVersionMatrix,omitempty"`
+	Conformance              *conformanceReport     `json:"conformance,omitempty"`
+	Fuzz                     *fuzzReport            `json:"fuzz,omitempty"`
+	Bench                    *benchReport           `json:"bench,omitempty"`
+	Chaos                    *chaosReport           `json:"chaos,omitempty"`
+	AuthSurface              *authSurfaceReport     `json:"authSurface,omitempty"`
+	ReconnectTest            *reconnectReport       `json:"reconnectTest,omitempty"`
+	Resumability             *resumabilityReport    `json:"resumability,omitempty"`
+	Isolation                *isolationReport       `json:"isolation,omitempty"`
+	BaselineDiffs            []behaviorDifference   `json:"baselineDifferences,omitempty"`
+	VersionBump              *versionBump           `json:"versionBump,omitempty"`
+	TLS                      *tlsDiagnostics        `json:"tls,omitempty"`
+	Timings                  []timingRecord         `json:"timings"`
+	TimingSummary            []timingSummary        `json:"timingSummary,omitempty"`
+	Retries                  []retryRecord          `json:"retries,omitempty"`
+	Reconnects               []sseReconnect         `json:"reconnects,omitempty"`
+	Warnings                 []warningRecord        `json:"warnings,omitempty"`
+	Errors                   []string               `json:"errors,omitempty"`
+}
+
+// timingRecord is the duration of a single operation in the probe run
+type timingRecord struct {
+	Operation string        `json:"operation"`
+	StartedAt timestamp     `json:"startedAt"`
+	Duration  time.Duration `json:"durationNs"`
+	Failed    bool          `json:"failed,omitempty"`
+}
+
+// toolCallRecord is a tool call made during the probe run
+type toolCallRecord struct {
+	Tool      string              `json:"tool"`
+	Arguments map[string]any      `json:"arguments,omitempty"`
+	Result    *mcp.CallToolResult `json:"result,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	StartedAt timestamp           `json:"startedAt"`
+	Duration  time.Duration       `json:"durationNs"`
+}
+
+// report is the report for the current probe run
+var report = &probeReport{
+	ProbeName:    ProgName,
+	ProbeVersion: ProgVer,
+	Build:        currentBuildInfo(),
+	StartedAt:    timestamp(time.Now()),
+}
+
+// setTarget records the server being probed
+func (r *probeReport) setTarget(target, transportName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Target = target
+	r.Transport = transportName
+}
+
+// setSessionID records the streamable HTTP session the probe used
+func (r *probeReport) setSessionID(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.SessionID = id
+}
+
+// setInitResult records the server's answer to the initialization handshake
+func (r *probeReport) setInitResult(result *mcp.InitializeResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	serverInfo := result.ServerInfo
+	r.ServerInfo = &serverInfo
+	r.ProtocolVersion = result.ProtocolVersion
+	r.Instructions = result.Instructions
+	r.Capabilities = result.Capabilities
+}
+
+// setTools records the tools listed by the server
+func (r *probeReport) setTools(tools []mcp.Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Tools = tools
+}
+
+// setToolTitles records the title fields of the listed tools, by name
+func (r *probeReport) setToolTitles(titles map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ToolTitles = titles
+}
+
+// toolTitle returns the title field of a listed tool
+func (r *probeReport) toolTitle(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ToolTitles[name]
+}
+
+// listedTool returns the listed tool with the given name, or nil if the
+// tools have not been listed or the server did not list it
+func (r *probeReport) listedTool(name string) *mcp.Tool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.Tools {
+		if r.Tools[i].Name == name {
+			tool := r.Tools[i]
+			return &tool
+		}
+	}
+	return nil
+}
+
+// setToolsAppearedLate records that tools were only listed on a repeated attempt
+func (r *probeReport) setToolsAppearedLate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ToolsAppearedLate = true
+}
+
+// setResources records the resources listed by the server
+func (r *probeReport) setResources(resources []mcp.Resource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Resources = resources
+}
+
+// setResourceTemplates records the resource templates listed by the server
+func (r *probeReport) setResourceTemplates(templates []mcp.ResourceTemplate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResourceTemplates = templates
+}
+
+// setPrompts records the prompts listed by the server
+func (r *probeReport) setPrompts(prompts []mcp.Prompt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Prompts = prompts
+}
+
+// setPagination records how a listing was paged, replacing an earlier
+// listing with the same method
+func (r *probeReport) setPagination(p listPagination) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.Pagination {
+		if r.Pagination[i].Method == p.Method {
+			r.Pagination[i] = p
+			return
+		}
+	}
+	r.Pagination = append(r.Pagination, p)
+}
+
+// addToolCall records a completed tool call
+func (r *probeReport) addToolCall(call toolCallRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ToolCalls = append(r.ToolCalls, call)
+}
+
+// addSamplingRequest records a sampling request the server sent
+func (r *probeReport) addSamplingRequest(record samplingRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.SamplingRequests = append(r.SamplingRequests, record)
+}
+
+// setRootsReaction records how the server reacted to a roots change,
+// replacing an earlier one
+func (r *probeReport) setRootsReaction(reaction *rootsReaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.RootsReaction = reaction
+}
+
+// setChecks records the aggregated results of the capability checks
+func (r *probeReport) setChecks(checks []checkSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Checks = checks
+}
+
+// setTransportDiffs records the differences found by -compare-transports
+func (r *probeReport) setTransportDiffs(diffs []behaviorDifference) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.TransportDiffs = diffs
+}
+
+// setVersionDiffs records the differences found by -compare-versions
+func (r *probeReport) setVersionDiffs(diffs []behaviorDifference) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.VersionDiffs = diffs
+}
+
+// setVersionMatrix records how the server answered each protocol version
+// requested by -version-matrix
+func (r *probeReport) setVersionMatrix(entries []versionMatrixEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.VersionMatrix = entries
+}
+
+// setConformance records the scored result of the conformance suite
+func (r *probeReport) setConformance(score *conformanceReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Conformance = score
+}
+
+// setFuzz records the result of fuzzing a tool
+func (r *probeReport) setFuzz(result *fuzzReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Fuzz = result
+}
+
+// setBench records the result of a benchmark
+func (r *probeReport) setBench(result *benchReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Bench = result
+}
+
+// setChaos records the result of a chaos run
+func (r *probeReport) setChaos(result *chaosReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Chaos = result
+}
+
+// setAuthSurface records the result of compare-auth
+func (r *probeReport) setAuthSurface(result *authSurfaceReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.AuthSurface = result
+}
+
+// setReconnectTest records the result of the SSE reconnection test
+func (r *probeReport) setReconnectTest(result *reconnectReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ReconnectTest = result
+}
+
+// setResumability records the result of the resumability test
+func (r *probeReport) setResumability(result *resumabilityReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Resumability = result
+}
+
+// setIsolation records the result of the session isolation test
+func (r *probeReport) setIsolation(result *isolationReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Isolation = result
+}
+
+// addReconnect records a lost SSE stream of an interactive session
+func (r *probeReport) addReconnect(event sseReconnect) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Reconnects = append(r.Reconnects, event)
+}
+
+// setBaselineDiffs records the differences found by -baseline-url and the
+// version bump they suggest
+func (r *probeReport) setBaselineDiffs(diffs []behaviorDifference, bump *versionBump) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.BaselineDiffs = diffs
+	r.VersionBump = bump
+}
+
+// setTLS records the TLS connection to the server
+func (r *probeReport) setTLS(tlsInfo *tlsDiagnostics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.TLS = tlsInfo
+}
+
+// addTiming records how long an operation took
+func (r *probeReport) addTiming(operation string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Timings are recorded when the operation ends
+	started := timestamp(time.Now().Add(-duration))
+	r.Timings = append(r.Timings, timingRecord{Operation: operation, StartedAt: started, Duration: duration, Failed: err != nil})
+}
+
+// addRetry records a request that was sent again
+func (r *probeReport) addRetry(retry retryRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Retries = append(r.Retries, retry)
+}
+
+// addError records an error encountered during the run
+func (r *probeReport) addError(format string, v ...any) {
+	message := fmt.Sprintf(format, v...)
+	emitEvent(eventError, map[string]any{"error": message})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Errors = append(r.Errors, message)
+}
+
+// finish marks the end of the probe run
+func (r *probeReport) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishedAt = timestamp(time.Now())
+	r.TimingSummary = summarizeTimings(r.Timings)
+}
+
+// Report formats supported by the -report flag
+const (
+	reportHTML   = "html"
+	reportJSON   = "json"
+	reportMatrix = "matrix"
+)
+
+// validateReportOptions checks the -report and -o flags
+func validateReportOptions(format string, destinations []string) error {
+	if format == "" {
+		return nil
+	}
+	if format != reportHTML && format != reportJSON && format != reportMatrix {
+		return fmt.Errorf("unsupported report format '%s' (use 'html', 'json' or 'matrix')", format)
+	}
+	if len(destinations) == 0 {
+		return fmt.Errorf("-report requires -o <destination>")
+	}
+	return nil
+}
+
+// writeReport renders the report in the given format and writes it to each
+// destination. All destinations are attempted even if one fails.
+func writeReport(format string, destinations []string) error {
+	report.finish()
+
+	var buf bytes.Buffer
+	if err := renderReport(&buf, format); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, dest := range destinations {
+		sink, err := parseSink(dest)
+		if err == nil {
+			err = sink.write(buf.Bytes(), reportContentType(format))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write report to %s: %v\n", dest, err)
+			failed = append(failed, dest)
+			continue
+		}
+		fmt.Printf("Report written to %s\n", sink)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("report not written to %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// renderReport renders the report in the given format
+func renderReport(w io.Writer, format string) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	switch format {
+	case reportHTML:
+		return renderHTMLReport(w, report)
+	case reportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case reportMatrix:
+		return renderMatrix(w, report)
+	default:
+		return fmt.Errorf("unsupported report format '%s'", format)
+	}
+}
@@ -0,0 +1,470 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// htmlReportView is the data passed to the HTML report template
+type htmlReportView struct {
+	Report    *probeReport
+	Started   string
+	Generated string
+	Duration  string
+	Timings   []htmlTimingBar
+	Templates []htmlTemplateView
+	Partial   []partialCapability
+}
+
+// htmlTimingBar is one bar in the timing chart
+type htmlTimingBar struct {
+	Operation string
+	Started   string
+	Duration  string
+	Percent   float64
+	Failed    bool
+}
+
+// htmlTemplateView is a resource template with its URI template expanded to a string
+type htmlTemplateView struct {
+	URITemplate string
+	Template    mcp.ResourceTemplate
+}
+
+// renderHTMLReport writes a self-contained HTML report (no external assets)
+func renderHTMLReport(w io.Writer, r *probeReport) error {
+	view := htmlReportView{
+		Report:    r,
+		Started:   machineTime(time.Time(r.StartedAt)),
+		Generated: time.Time(r.FinishedAt).Format(time.RFC1123),
+		Duration:  humanDuration(time.Time(r.FinishedAt).Sub(time.Time(r.StartedAt))),
+		Partial:   r.partialCapabilities(),
+	}
+
+	var longest time.Duration
+	for _, t := range r.Timings {
+		longest = max(longest, t.Duration)
+	}
+	for _, t := range r.Timings {
+		percent := 0.0
+		if longest > 0 {
+			percent = float64(t.Duration) / float64(longest) * 100
+		}
+		view.Timings = append(view.Timings, htmlTimingBar{
+			Operation: t.Operation,
+			Started:   machineTime(time.Time(t.StartedAt)),
+			Duration:  humanDuration(t.Duration),
+			Percent:   max(percent, 0.5),
+			Failed:    t.Failed,
+		})
+	}
+
+	for _, t := range r.ResourceTemplates {
+		view.Templates = append(view.Templates, htmlTemplateView{
+			URITemplate: resourceTemplateString(t),
+			Template:    t,
+		})
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"json":        highlightJSON,
+		"annotations": formatToolAnnotations,
+		"duration":    humanDuration,
+		"percent":     func(rate float64) string { return fmt.Sprintf("%.2f%%", rate*100) },
+		// The report is locked while rendering, so titles are read directly
+		"title": func(tool mcp.Tool) string {
+			if title := r.ToolTitles[tool.Name]; title != "" {
+				return title
+			}
+			return tool.Annotations.Title
+		},
+	}).Parse(htmlReportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, view)
+}
+
+// resourceTemplateString returns the URI template pattern of a resource template
+func resourceTemplateString(t mcp.ResourceTemplate) string {
+	if t.URITemplate == nil {
+		return "(empty template)"
+	}
+	jsonBytes, err := t.URITemplate.MarshalJSON()
+	if err != nil {
+		return "(invalid template)"
+	}
+	return strings.Trim(string(jsonBytes), "\"")
+}
+
+// highlightJSON renders a value as indented JSON wrapped in spans for syntax highlighting
+func highlightJSON(v any) template.HTML {
+	jsonBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return template.HTML(template.HTMLEscapeString(err.Error()))
+	}
+	src := string(jsonBytes)
+
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '"':
+			// Find the end of the string, honouring escapes
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(src))
+			class := "s"
+			if strings.HasPrefix(strings.TrimLeft(src[j:], " "), ":") {
+				class = "k"
+			}
+			b.WriteString(`<span class="` + class + `">` + template.HTMLEscapeString(src[i:j]) + `</span>`)
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(src) && strings.IndexByte("0123456789.eE+-", src[j]) >= 0 {
+				j++
+			}
+			b.WriteString(`<span class="n">` + src[i:j] + `</span>`)
+			i = j
+		case strings.HasPrefix(src[i:], "true"), strings.HasPrefix(src[i:], "null"):
+			b.WriteString(`<span class="b">` + src[i:i+4] + `</span>`)
+			i += 4
+		case strings.HasPrefix(src[i:], "false"):
+			b.WriteString(`<span class="b">false</span>`)
+			i += 5
+		default:
+			b.WriteString(template.HTMLEscapeString(string(c)))
+			i++
+		}
+	}
+	return template.HTML(b.String())
+}
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>MCPProbe report - {{.Report.Target}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1100px; color: #222; padding: 0 1em; }
+h1 { font-size: 1.6em; margin-bottom: 0.2em; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 0.2em; margin-top: 1.6em; }
+table.info td { padding: 2px 12px 2px 0; vertical-align: top; }
+table.info td:first-child { color: #666; white-space: nowrap; }
+details { border: 1px solid #e2e2e2; border-radius: 4px; margin: 6px 0; padding: 4px 10px; background: #fafafa; }
+details[open] { background: #fff; }
+summary { cursor: pointer; font-weight: 600; }
+summary .desc { font-weight: normal; color: #555; }
+.badge { display: inline-block; font-size: 0.75em; padding: 1px 6px; border-radius: 8px; background: #e8eef8; color: #2a4a80; margin-left: 4px; font-weight: normal; }
+.badge.err { background: #fbe3e3; color: #8a1f1f; }
+pre { background: #f6f8fa; padding: 10px; border-radius: 4px; overflow-x: auto; font-size: 0.85em; }
+pre .k { color: #0550ae; } pre .s { color: #0a3069; } pre .n { color: #953800; } pre .b { color: #8250df; }
+.chart { margin: 8px 0; }
+.bar-row { display: flex; align-items: center; margin: 3px 0; font-size: 0.85em; }
+.bar-label { width: 260px; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar-track { flex: 1; background: #f0f0f0; border-radius: 3px; margin: 0 8px; }
+.bar { height: 14px; background: #4a7bd0; border-radius: 3px; }
+.bar.failed { background: #d04a4a; }
+.bar-value { width: 110px; text-align: right; font-family: monospace; }
+.errors li { color: #8a1f1f; }
+table.checks { border-collapse: collapse; }
+table.checks th, table.checks td { text-align: left; padding: 3px 14px 3px 0; }
+table.checks td.check-error { color: #8a1f1f; font-size: 0.85em; }
+.empty { color: #888; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>MCPProbe report</h1>
+<table class="info">
+<tr><td>Target</td><td>{{.Report.Target}}</td></tr>
+<tr><td>Transport</td><td>{{.Report.Transport}}</td></tr>
+{{- with .Report.ServerInfo}}
+<tr><td>Server</td><td>{{.Name}} v{{.Version}}</td></tr>
+{{- end}}
+{{- with .Report.ProtocolVersion}}
+<tr><td>Protocol version</td><td>{{.}}</td></tr>
+{{- end}}
+<tr><td>Started</td><td>{{.Started}}</td></tr>
+<tr><td>Generated</td><td>{{.Generated}} (run took {{.Duration}})</td></tr>
+<tr><td>Probe</td><td>{{.Report.ProbeName}} v{{.Report.ProbeVersion}}</td></tr>
+{{- with .Report.Build}}
+<tr><td>Probe build</td><td>{{.Summary}}</td></tr>
+{{- end}}
+</table>
+{{- with .Report.Instructions}}
+<h2>Server instructions</h2>
+<pre>{{.}}</pre>
+{{- end}}
+
+{{- if .Report.Errors}}
+<h2>Errors</h2>
+<ul class="errors">
+{{- range .Report.Errors}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+
+{{- if .Report.Warnings}}
+<h2>Warnings</h2>
+<ul>
+{{- range .Report.Warnings}}
+<li><span class="badge">{{if .Check}}{{.Check}}{{else}}{{.Source}}{{end}}</span> {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+
+{{- if .Report.ProtocolNotes}}
+<h2>Protocol Notes</h2>
+<ul>
+{{- range .Report.ProtocolNotes}}
+<li>{{.Note}}</li>
+{{- end}}
+</ul>
+{{- end}}
+
+{{- if .Partial}}
+<h2>Partially Implemented Capabilities</h2>
+<table class="checks">
+<tr><th>Capability</th><th>Endpoint</th><th>Status</th><th>Detail</th></tr>
+{{- range .Partial}}
+{{- $capability := .Capability}}
+{{- range .Works}}
+<tr><td>{{$capability}}</td><td>{{.}}</td><td><span class="badge">works</span></td><td></td></tr>
+{{- end}}
+{{- range .Failed}}
+<tr><td>{{$capability}}</td><td>{{.Method}}</td><td><span class="badge err">{{.Failure}}</span></td><td>{{.Error}}</td></tr>
+{{- end}}
+<tr><td>{{$capability}}</td><td colspan="3">A client can rely on: {{.Reliable}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.Checks}}
+<h2>Checks</h2>
+<table class="checks">
+<tr><th>Check</th><th>Status</th><th>Passed</th><th>Average</th></tr>
+{{- range .Report.Checks}}
+<tr><td>{{.ID}}</td><td><span class="badge{{if or (eq .Status "fail") (eq .Status "flaky")}} err{{end}}">{{.Status}}</span>{{if .Varies}} <span class="badge err">varies</span>{{end}}</td><td>{{.Passed}}/{{.Runs}}</td><td>{{.AvgTime}}</td></tr>
+{{- range .Errors}}
+<tr><td></td><td colspan="3" class="check-error">{{.}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.TransportDiffs}}
+<h2>Transport Differences</h2>
+<table class="checks">
+<tr><th>Check</th><th>Impact</th><th>Difference</th></tr>
+{{- range .Report.TransportDiffs}}
+<tr><td>{{.Check}}</td><td><span class="badge{{if eq .Impact "breaking"}} err{{end}}">{{.Impact}}</span></td><td class="check-error">{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.VersionDiffs}}
+<h2>Protocol Version Differences</h2>
+<table class="checks">
+<tr><th>Check</th><th>Impact</th><th>Difference</th></tr>
+{{- range .Report.VersionDiffs}}
+<tr><td>{{.Check}}</td><td><span class="badge{{if eq .Impact "breaking"}} err{{end}}">{{.Impact}}</span></td><td class="check-error">{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.VersionMatrix}}
+<h2>Protocol Version Matrix</h2>
+<table class="checks">
+<tr><th>Requested</th><th>Result</th><th>Answered</th><th>Detail</th></tr>
+{{- range .Report.VersionMatrix}}
+<tr><td>{{.Requested}}{{if not .Known}} (future){{end}}</td><td><span class="badge{{if .HardFailure}} err{{end}}">{{.Result}}</span></td><td>{{.Answered}}</td><td class="check-error">{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Report.Conformance}}
+<h2>Conformance</h2>
+<p>Score: {{.Score}}% ({{.Passed}} of {{.Executed}} executed checks passed{{if .Skipped}}, {{.Skipped}} skipped{{end}})</p>
+<table class="checks">
+<tr><th>Check</th><th>Level</th><th>Status</th><th>Detail</th></tr>
+{{- range .Checks}}
+<tr><td>{{.ID}}</td><td>{{.Level}}</td><td><span class="badge{{if eq .Status "fail"}} err{{end}}">{{.Status}}</span>{{if .Suppressed}} <span class="badge">suppressed</span>{{end}}</td><td class="check-error">{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Report.Fuzz}}
+<h2>Fuzzing: {{.Tool}}</h2>
+<p>{{.Iterations}} calls with seed {{.Seed}}:{{range $outcome, $n := .Outcomes}} <span class="badge{{if or (eq $outcome "timeout") (eq $outcome "crash")}} err{{end}}">{{$n}} {{$outcome}}</span>{{end}}</p>
+{{- if .Cases}}
+<table class="checks">
+<tr><th>Iteration</th><th>Mutation</th><th>Input</th><th>Outcome</th><th>Detail</th></tr>
+{{- range .Cases}}
+<tr><td>{{.Iteration}}</td><td>{{.Mutation}}</td><td>{{.Input}}</td><td><span class="badge{{if ne .Outcome "error"}} err{{end}}">{{.Outcome}}</span></td><td class="check-error">{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+
+{{- with .Report.Bench}}
+<h2>Benchmark: {{.Tool}}</h2>
+<table class="checks">
+<tr><td>Workers</td><td>{{.Concurrency}} over {{.Sessions}} session(s)</td></tr>
+<tr><td>Calls</td><td>{{.Calls}} ({{.Succeeded}} succeeded, {{.ToolErrors}} tool errors, {{.Failed}} failed, {{.Timeouts}} timed out)</td></tr>
+<tr><td>Error rate</td><td><span class="badge{{if or .Failed .Timeouts}} err{{end}}">{{percent .ErrorRate}}</span></td></tr>
+<tr><td>Throughput</td><td>{{printf "%.2f" .Throughput}} calls/sec over {{duration .Duration}}</td></tr>
+{{- with .Latency}}
+<tr><td>Latency</td><td>min {{duration .Min}}, p50 {{duration .P50}}, p90 {{duration .P90}}, p95 {{duration .P95}}, p99 {{duration .P99}}, max {{duration .Max}}</td></tr>
+{{- end}}
+{{- range .Errors}}
+<tr><td>Error</td><td class="check-error">{{.Count}} × {{.Error}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.BaselineDiffs}}
+<h2>Baseline Differences</h2>
+<table class="checks">
+<tr><th>Check</th><th>Impact</th><th>Difference</th></tr>
+{{- range .Report.BaselineDiffs}}
+<tr><td>{{.Check}}</td><td><span class="badge{{if eq .Impact "breaking"}} err{{end}}">{{.Impact}}</span></td><td class="check-error">{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Report.VersionBump}}
+<h2>Suggested Version Bump</h2>
+<table class="checks">
+<tr><td>Suggested</td><td><span class="badge{{if eq .Suggested "major"}} err{{end}}">{{.Suggested}}</span> {{.Reason}}</td></tr>
+{{- if .BaselineVersion}}
+<tr><td>Server version</td><td>{{.BaselineVersion}} → {{.CurrentVersion}}{{if .Expected}} (expected at least {{.Expected}}){{end}}{{if .Inconsistent}} <span class="badge err">inconsistent</span>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Report.TLS}}
+<h2>TLS</h2>
+<table class="checks">
+<tr><td>Host</td><td>{{.Host}}</td></tr>
+<tr><td>Version</td><td>{{.Version}}</td></tr>
+<tr><td>Cipher suite</td><td>{{.CipherSuite}}</td></tr>
+<tr><td>Certificate</td><td>{{if .Verified}}<span class="badge">verified</span>{{else}}<span class="badge err">not verified</span>{{end}}</td></tr>
+{{- range $i, $cert := .Chain}}
+<tr><td>Chain {{$i}}</td><td>{{$cert.Subject}}<br><span class="desc">issued by {{$cert.Issuer}}; expires {{$cert.NotAfter.Format "2006-01-02"}} ({{$cert.DaysUntilExpiry}} days); {{$cert.PublicKey}}, {{$cert.SignatureAlgorithm}}</span></td></tr>
+{{- end}}
+{{- range .Warnings}}
+<tr><td></td><td class="check-error">{{.}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>Capabilities</h2>
+<details><summary>Server capabilities</summary>
+<pre>{{json .Report.Capabilities}}</pre>
+</details>
+
+<h2>Timing</h2>
+{{- if .Timings}}
+<div class="chart">
+{{- range .Timings}}
+<div class="bar-row"><span class="bar-label" title="{{.Operation}} (started {{.Started}})">{{.Operation}}</span><span class="bar-track"><div class="bar{{if .Failed}} failed{{end}}" style="width: {{printf "%.1f" .Percent}}%"></div></span><span class="bar-value">{{.Duration}}</span></div>
+{{- end}}
+</div>
+{{- else}}
+<p class="empty">No timings recorded</p>
+{{- end}}
+
+<h2>Tools ({{len .Report.Tools}})</h2>
+{{- range .Report.Tools}}
+<details><summary>{{.Name}}{{with title .}} ({{.}}){{end}}{{with annotations .Annotations}} <span class="badge">{{.}}</span>{{end}}{{with .Description}} <span class="desc">- {{.}}</span>{{end}}</summary>
+<p>Input schema:</p>
+<pre>{{json .InputSchema}}</pre>
+</details>
+{{- else}}
+<p class="empty">No tools listed</p>
+{{- end}}
+
+<h2>Resources ({{len .Report.Resources}})</h2>
+{{- range .Report.Resources}}
+<details><summary>{{.URI}}{{with .Name}} <span class="desc">- {{.}}</span>{{end}}</summary>
+<pre>{{json .}}</pre>
+</details>
+{{- else}}
+<p class="empty">No resources listed</p>
+{{- end}}
+
+<h2>Resource templates ({{len .Templates}})</h2>
+{{- range .Templates}}
+<details><summary>{{.URITemplate}}{{with .Template.Name}} <span class="desc">- {{.}}</span>{{end}}</summary>
+<pre>{{json .Template}}</pre>
+</details>
+{{- else}}
+<p class="empty">No resource templates listed</p>
+{{- end}}
+
+<h2>Prompts ({{len .Report.Prompts}})</h2>
+{{- range .Report.Prompts}}
+<details><summary>{{.Name}}{{with .Description}} <span class="desc">- {{.}}</span>{{end}}</summary>
+<pre>{{json .Arguments}}</pre>
+</details>
+{{- else}}
+<p class="empty">No prompts listed</p>
+{{- end}}
+
+{{- if .Report.ToolCalls}}
+<h2>Tool calls ({{len .Report.ToolCalls}})</h2>
+{{- range .Report.ToolCalls}}
+<details><summary>{{.Tool}} <span class="badge{{if or .Error (and .Result .Result.IsError)}} err{{end}}">{{.Duration}}</span></summary>
+<p>Arguments:</p>
+<pre>{{json .Arguments}}</pre>
+{{- if .Error}}
+<p>Error: {{.Error}}</p>
+{{- else}}
+<p>Result:</p>
+<pre>{{json .Result}}</pre>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+
+{{- with .Report.RootsReaction}}
+<h2>Roots Change</h2>
+<table class="checks">
+<tr><th>Added root</th><td>{{.AddedRoot}}</td></tr>
+<tr><th>Re-queried roots</th><td>{{if .Requeried}}yes, after {{.RequeryAfter}}{{else}}<span class="badge err">no</span>{{end}}</td></tr>
+<tr><th>Sent list_changed</th><td>{{range .ListChanged}}{{.}} {{else}}none{{end}}</td></tr>
+<tr><th>Tools</th><td>{{range .ToolsAdded}}+ {{.}}<br>{{end}}{{range .ToolsRemoved}}- {{.}}<br>{{end}}</td></tr>
+<tr><th>Resources</th><td>{{range .ResourcesAdded}}+ {{.}}<br>{{end}}{{range .ResourcesRemoved}}- {{.}}<br>{{end}}</td></tr>
+</table>
+{{- end}}
+
+{{- if .Report.SamplingRequests}}
+<h2>Sampling requests ({{len .Report.SamplingRequests}})</h2>
+{{- range .Report.SamplingRequests}}
+<details><summary>{{len .Request.Messages}} message(s), maxTokens {{.Request.MaxTokens}}{{if .Violations}} <span class="badge err">{{len .Violations}} violation(s)</span>{{end}}{{if .Refused}} <span class="badge err">refused</span>{{end}}</summary>
+{{- range .Violations}}
+<p>{{.}}</p>
+{{- end}}
+<pre>{{json .Request}}</pre>
+</details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`
@@ -0,0 +1,299 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resumabilityRequestBase is the first JSON-RPC ID of the resumability test's
+// requests
+const resumabilityRequestBase = 11000000
+
+// resumabilityProgressToken is sent with the test's tool calls, so that a
+// server that reports progress streams the call's response
+const resumabilityProgressToken = "mcpprobe-resumability"
+
+// Verdicts of the resumability test
+const (
+	resumeSupported   = "supported"
+	resumeUnsupported = "not supported"
+	resumeBroken      = "broken"
+	resumeNotStreamed = "not streamed"
+	resumeSingleEvent = "single event"
+)
+
+// resumabilityCommandArgs turns "resumability [flags]" into the equivalent
+// -resumability flag, so that the test uses the probe's usual connection
+// options
+func resumabilityCommandArgs(args []string) []string {
+	return append([]string{args[0], "-resumability"}, args[2:]...)
+}
+
+// streamEvent is an SSE event of a streamed response
+type streamEvent struct {
+	ID   string
+	Data []byte
+}
+
+// resumabilityReport is the result of the resumability test
+type resumabilityReport struct {
+	Tool            string        `json:"tool"`
+	Verdict         string        `json:"verdict"`
+	Detail          string        `json:"detail,omitempty"`
+	EventIDs        bool          `json:"eventIds"`
+	Baseline        int           `json:"baselineEvents"`
+	BeforeInterrupt int           `json:"beforeInterrupt"`
+	LastEventID     string        `json:"lastEventId,omitempty"`
+	ResumeStatus    int           `json:"resumeStatus,omitempty"`
+	Resumed         int           `json:"resumedEvents"`
+	Missing         int           `json:"missing"`
+	Duplicated      int           `json:"duplicated"`
+	ResumeTime      time.Duration `json:"resumeNs,omitempty"`
+}
+
+// runResumabilityTest checks that a streamed tool call can be resumed with
+// Last-Event-ID. It calls the tool once without interruption to learn how
+// many events its stream carries, then calls it again, drops the stream
+// after the first event and resumes it with a GET that sends the event's ID.
+// The resumed stream must deliver the rest of the events, including the
+// response, without repeating any. It returns an error if the server
+// attaches event IDs, which are how it offers resumability, but resuming
+// fails, loses events or repeats them.
+func runResumabilityTest(wire *httpNegativeWire, tool string, args map[string]any, timeout, callTimeout time.Duration) error {
+	fmt.Printf("=== Resumability: tools/call %s ===\n", tool)
+	defer wire.close()
+	if _, _, err := initializeNegativeWire(wire, timeout); err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	result := &resumabilityReport{Tool: tool}
+	defer report.setResumability(result)
+	conclude := func(verdict, format string, v ...any) {
+		result.Verdict = verdict
+		result.Detail = fmt.Sprintf(format, v...)
+	}
+
+	// The uninterrupted call is the reference for what the stream carries
+	baseline, streamed, err := streamToolCall(wire, resumabilityRequestBase+1, tool, args, callTimeout, 0)
+	if err != nil {
+		return fmt.Errorf("failed to call '%s': %w", tool, err)
+	}
+	result.Baseline = len(baseline)
+	fmt.Printf("Uninterrupted call: %d event%s\n", len(baseline), pluralS(len(baseline)))
+	for _, event := range baseline {
+		result.EventIDs = result.EventIDs || event.ID != ""
+	}
+
+	switch {
+	case !streamed:
+		conclude(resumeNotStreamed, "the server answered with JSON, so there is no stream to resume; use a tool that reports progress")
+	case len(baseline) < 2:
+		conclude(resumeSingleEvent, "the stream carries only the response, so there is nothing to resume; use a tool that reports progress")
+	case !result.EventIDs:
+		conclude(resumeUnsupported, "the server does not attach event IDs, so a client cannot resume a dropped stream")
+	}
+	if result.Verdict != "" {
+		printResumability(result)
+		return nil
+	}
+
+	// Drop the stream after its first event
+	id := resumabilityRequestBase + 2
+	before, _, err := streamToolCall(wire, id, tool, args, callTimeout, 1)
+	if err != nil {
+		return fmt.Errorf("failed to call '%s' again: %w", tool, err)
+	}
+	result.BeforeInterrupt = len(before)
+	if len(before) == 0 || before[len(before)-1].ID == "" {
+		conclude(resumeBroken, "the first event of the second call has no event ID")
+		return resumabilityFinding(result)
+	}
+	result.LastEventID = before[len(before)-1].ID
+	fmt.Printf("Interrupted call:   dropped the stream after %d event%s (last event ID %s)\n", len(before), pluralS(len(before)), result.LastEventID)
+
+	start := time.Now()
+	resumed, status, err := resumeStream(wire, result.LastEventID, id, callTimeout)
+	result.ResumeStatus = status
+	result.Resumed = len(resumed)
+	if err != nil {
+		conclude(resumeBroken, "resuming with Last-Event-ID %s failed: %v", result.LastEventID, err)
+		return resumabilityFinding(result)
+	}
+	result.ResumeTime = time.Since(start)
+	fmt.Printf("Resumed stream:     %d event%s in %s\n", len(resumed), pluralS(len(resumed)), humanDuration(result.ResumeTime))
+
+	// Events are matched by their ID, and the response by its request ID
+	seen := map[string]bool{}
+	responses := 0
+	for _, event := range append(before, resumed...) {
+		if event.ID != "" && seen[event.ID] {
+			result.Duplicated++
+		} else if isJSONRPCResponse(event.Data) {
+			responses++
+		}
+		seen[event.ID] = true
+	}
+	result.Duplicated += max(responses-1, 0)
+	result.Missing = max(len(baseline)-(len(before)+len(resumed)-result.Duplicated), 0)
+
+	switch {
+	case responses == 0:
+		conclude(resumeBroken, "the response never arrived on the resumed stream")
+	case result.Duplicated > 0 && result.Missing > 0:
+		conclude(resumeBroken, "%d event%s repeated and %d lost after resuming", result.Duplicated, pluralS(result.Duplicated), result.Missing)
+	case result.Duplicated > 0:
+		conclude(resumeBroken, "%d event%s delivered again after resuming", result.Duplicated, pluralS(result.Duplicated))
+	case result.Missing > 0:
+		conclude(resumeBroken, "%d event%s lost after resuming", result.Missing, pluralS(result.Missing))
+	default:
+		conclude(resumeSupported, "the resumed stream delivered the remaining %d event%s without loss or repetition", len(resumed), pluralS(len(resumed)))
+		printResumability(result)
+		return nil
+	}
+	return resumabilityFinding(result)
+}
+
+// resumabilityFinding reports a server that attaches event IDs but does not
+// resume its streams correctly
+func resumabilityFinding(result *resumabilityReport) error {
+	fmt.Printf("        %s\n", report.addFinding(checkIDResumability, result.Tool, "event IDs are attached but the stream does not resume: %s", result.Detail))
+	printResumability(result)
+	return fmt.Errorf("the server attaches event IDs to '%s' but resuming its stream is %s: %s", result.Tool, result.Verdict, result.Detail)
+}
+
+// printResumability prints the verdict of the resumability test
+func printResumability(result *resumabilityReport) {
+	emitEvent(eventCheck, map[string]any{
+		"id":       "resumability",
+		"status":   result.Verdict,
+		"detail":   result.Detail,
+		"eventIds": result.EventIDs,
+	})
+	fmt.Printf("\nResumability: %s (%s)\n", strings.ToUpper(result.Verdict), result.Detail)
+}
+
+// streamToolCall calls a tool with a progress token and reads the events of
+// the answer. With stopAfter above 0 it drops the stream after that many
+// events. It also tells whether the answer was streamed.
+func streamToolCall(wire *httpNegativeWire, id int, tool string, args map[string]any, callTimeout time.Duration, stopAfter int) ([]streamEvent, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	params := map[string]any{"name": tool, "_meta": map[string]any{"progressToken": resumabilityProgressToken}}
+	if args != nil {
+		params["arguments"] = args
+	}
+	message, err := json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": id, "method": mcp.MethodToolsCall, "params": params})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode the request: %w", err)
+	}
+	resp, err := wire.post(ctx, message)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, negativeReplyLimit))
+		return []streamEvent{{Data: body}}, false, err
+	}
+	events, err := readStreamEvents(resp.Body, id, stopAfter)
+	return events, true, err
+}
+
+// resumeStream resumes a dropped stream with a GET that sends the ID of the
+// last event received, and reads its events until the response to the
+// request with the given ID. It returns the events and the HTTP status.
+func resumeStream(wire *httpNegativeWire, lastEventID string, id int, callTimeout time.Duration) ([]streamEvent, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wire.url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for key, value := range wire.headers {
+		req.Header.Set(key, value)
+	}
+	if wire.oauth != nil {
+		if token, err := wire.oauth.TokenStore.GetToken(ctx); err == nil {
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		}
+	}
+	req.Header.Set(mcpSessionHeader, wire.session)
+	if wire.version != "" {
+		req.Header.Set("MCP-Protocol-Version", wire.version)
+	}
+	req.Header.Set("Last-Event-ID", lastEventID)
+	resp, err := wire.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return nil, resp.StatusCode, fmt.Errorf("the answer is %s, not an event stream", valueOr(resp.Header.Get("Content-Type"), "untyped"))
+	}
+	events, err := readStreamEvents(resp.Body, id, 0)
+	if ctx.Err() != nil {
+		// What arrived before the timeout is judged by the caller
+		err = nil
+	}
+	return events, resp.StatusCode, err
+}
+
+// readStreamEvents reads SSE events until the response to the request with
+// the given ID, the end of the stream, or stopAfter events if above 0
+func readStreamEvents(body io.Reader, id int, stopAfter int) ([]streamEvent, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), negativeReplyLimit)
+	var events []streamEvent
+	var lines []string
+	want := fmt.Sprint(id)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		event := streamEvent{Data: eventData(lines)}
+		for _, line := range lines {
+			if v, ok := strings.CutPrefix(line, "id:"); ok {
+				event.ID = strings.TrimSpace(v)
+			}
+		}
+		lines = nil
+		if len(event.Data) == 0 {
+			// A priming event or comment carries nothing to deliver
+			continue
+		}
+		events = append(events, event)
+		if stopAfter > 0 && len(events) == stopAfter {
+			return events, nil
+		}
+		var response struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if isJSONRPCResponse(event.Data) && json.Unmarshal(event.Data, &response) == nil && string(response.ID) == want {
+			return events, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return events, err
+	}
+	return events, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryDelay caps the backoff, including delays the server asks for
+const maxRetryDelay = 30 * time.Second
+
+// retryPolicy is set by -retries: HTTP requests that fail transiently are
+// sent again with exponential backoff. Nil disables retries.
+var retryPolicy *retryOptions
+
+// retryOptions configures the retries of HTTP requests
+type retryOptions struct {
+	retries int
+	backoff time.Duration
+	// verbose prints each retry
+	verbose bool
+}
+
+// retryRecord is a request that was sent again
+type retryRecord struct {
+	Request string        `json:"request"`
+	Attempt int           `json:"attempt"`
+	Reason  string        `json:"reason"`
+	Delay   time.Duration `json:"delayNs"`
+}
+
+// retryTransport sends a request again when it could not reach the server or
+// the server answered that it is temporarily unavailable. Only failures that
+// mean the request was not processed are retried, so that a tool call is not
+// run twice: connection failures, 429, 502, 503 and 504. A gateway's 502 or
+// 504 may come after the server started the call, so tool calls are only
+// retried after connection failures, 429 and 503.
+type retryTransport struct {
+	base http.RoundTripper
+	opts retryOptions
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		reason, retryAfter := retryReason(req, resp, err)
+		if reason == "" || attempt > t.opts.retries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		delay := retryDelay(t.opts.backoff, attempt, retryAfter)
+		label := req.Method + " " + req.URL.Path
+		if method := jsonRPCMethod(req); method != "" {
+			label += " (" + method + ")"
+		}
+		report.addRetry(retryRecord{Request: label, Attempt: attempt, Reason: reason, Delay: delay})
+		if t.opts.verbose {
+			fmt.Printf("Retrying %s in %s (retry %d of %d): %s\n", label, humanDuration(delay), attempt, t.opts.retries, reason)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind the request body: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryReason returns why a request should be sent again, or "" if it
+// should not, and how long the server asked the client to wait
+func retryReason(req *http.Request, resp *http.Response, err error) (string, time.Duration) {
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return err.Error(), 0
+		}
+		return "", 0
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		if jsonRPCMethod(req) == "tools/call" {
+			return "", 0
+		}
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		return "", 0
+	}
+	return resp.Status, parseRetryAfter(resp.Header.Get("Retry-After"))
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// retryDelay is the wait before a retry: the backoff doubles with each
+// attempt, and a random jitter of up to half of it keeps clients that failed
+// together from retrying together. A longer Retry-After is honored. Both are
+// capped at maxRetryDelay.
+func retryDelay(backoff time.Duration, attempt int, retryAfter time.Duration) time.Duration {
+	delay := backoff
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	delay = delay/2 + rand.N(delay/2+1)
+	return min(max(delay, retryAfter), maxRetryDelay)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	backoff := 100 * time.Millisecond
+	for attempt := 1; attempt <= 4; attempt++ {
+		base := backoff << (attempt - 1)
+		for range 50 {
+			if d := retryDelay(backoff, attempt, 0); d < base/2 || d > base {
+				t.Fatalf("retryDelay(%s, %d, 0) = %s, want between %s and %s", backoff, attempt, d, base/2, base)
+			}
+		}
+	}
+	if d := retryDelay(backoff, 1, 5*time.Second); d != 5*time.Second {
+		t.Errorf("retryDelay with Retry-After 5s = %s, want 5s", d)
+	}
+	if d := retryDelay(time.Second, 20, 0); d > maxRetryDelay {
+		t.Errorf("retryDelay after 20 attempts = %s, want at most %s", d, maxRetryDelay)
+	}
+	if d := retryDelay(backoff, 1, time.Hour); d != maxRetryDelay {
+		t.Errorf("retryDelay with Retry-After 1h = %s, want %s", d, maxRetryDelay)
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	newRequest := func(body string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost/mcp", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	listTools := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	callTool := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"charge"}}`
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		err    error
+		retry  bool
+	}{
+		{"429", callTool, http.StatusTooManyRequests, nil, true},
+		{"503", callTool, http.StatusServiceUnavailable, nil, true},
+		{"502 listing", listTools, http.StatusBadGateway, nil, true},
+		{"504 listing", listTools, http.StatusGatewayTimeout, nil, true},
+		{"502 tool call", callTool, http.StatusBadGateway, nil, false},
+		{"504 tool call", callTool, http.StatusGatewayTimeout, nil, false},
+		{"500", listTools, http.StatusInternalServerError, nil, false},
+		{"200", listTools, http.StatusOK, nil, false},
+		{"dial error", callTool, 0, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"read error", callTool, 0, &net.OpError{Op: "read", Err: errors.New("connection reset")}, false},
+	}
+	for _, tt := range tests {
+		var resp *http.Response
+		if tt.err == nil {
+			resp = &http.Response{StatusCode: tt.status, Status: http.StatusText(tt.status), Header: http.Header{}}
+		}
+		reason, _ := retryReason(newRequest(tt.body), resp, tt.err)
+		if (reason != "") != tt.retry {
+			t.Errorf("%s: retry reason %q, want retry = %v", tt.name, reason, tt.retry)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("7"); d != 7*time.Second {
+		t.Errorf("parseRetryAfter(7) = %s, want 7s", d)
+	}
+	if d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); d < 58*time.Second || d > time.Minute {
+		t.Errorf("parseRetryAfter(date in a minute) = %s, want about 1m", d)
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if d := parseRetryAfter(value); d != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", value, d)
+		}
+	}
+}
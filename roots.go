@@ -0,0 +1,390 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// rootsRequestWait is how long 'roots notify' waits for the server to ask
+// for the changed list
+const rootsRequestWait = 3 * time.Second
+
+// rootsSettleWait is how long a roots change is given to show in the
+// server's listings after it asked for the new roots
+const rootsSettleWait = time.Second
+
+// rootFlags collects repeatable -root file:///path[,name] flags
+type rootFlags []mcp.Root
+
+func (r *rootFlags) String() string {
+	var uris []string
+	for _, root := range *r {
+		uris = append(uris, root.URI)
+	}
+	return strings.Join(uris, ", ")
+}
+
+func (r *rootFlags) Set(value string) error {
+	root, err := parseRoot(value)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, root)
+	return nil
+}
+
+// parseRoot parses file:///path[,name]. A plain path is converted to a
+// file:// URI of its absolute form.
+func parseRoot(spec string) (mcp.Root, error) {
+	location, name, _ := strings.Cut(spec, ",")
+	location, name = strings.TrimSpace(location), strings.TrimSpace(name)
+	if location == "" {
+		return mcp.Root{}, fmt.Errorf("root '%s' has no URI", spec)
+	}
+	if !strings.Contains(location, "://") {
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return mcp.Root{}, fmt.Errorf("invalid root path '%s': %w", location, err)
+		}
+		location = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Scheme != "file" {
+		return mcp.Root{}, fmt.Errorf("root '%s' must be a file:// URI", location)
+	}
+	return mcp.Root{URI: location, Name: name}, nil
+}
+
+// rootsProvider answers the server's roots/list requests with the configured
+// roots, which interactive mode can change
+type rootsProvider struct {
+	mu    sync.Mutex
+	roots []mcp.Root
+	// notified is when list_changed was last sent, until the server asks
+	// for the list again; requested receives how long it took
+	notified  time.Time
+	requested chan time.Duration
+	// listChanged collects the list_changed notifications the server sends
+	// while a roots change is observed, or is nil
+	listChanged []string
+	observing   bool
+}
+
+// rootsReaction is how the server reacted to a change of the roots
+type rootsReaction struct {
+	AddedRoot        string        `json:"addedRoot"`
+	Requeried        bool          `json:"requeried"`
+	RequeryAfter     time.Duration `json:"requeryAfterNs,omitempty"`
+	ListChanged      []string      `json:"listChangedNotifications,omitempty"`
+	ToolsAdded       []string      `json:"toolsAdded,omitempty"`
+	ToolsRemoved     []string      `json:"toolsRemoved,omitempty"`
+	ResourcesAdded   []string      `json:"resourcesAdded,omitempty"`
+	ResourcesRemoved []string      `json:"resourcesRemoved,omitempty"`
+}
+
+// newRootsProvider creates the provider with the -root flags
+func newRootsProvider(roots []mcp.Root) *rootsProvider {
+	return &rootsProvider{roots: roots, requested: make(chan time.Duration, 1)}
+}
+
+// enableRoots makes the client answer roots/list requests with provider, and
+// collect the list_changed notifications that follow a roots change
+func enableRoots(mcpClient *client.Client, provider *rootsProvider) {
+	client.WithRootsHandler(provider)(mcpClient)
+	mcpClient.OnNotification(func(n mcp.JSONRPCNotification) {
+		switch mcp.MCPMethod(n.Method) {
+		case mcp.MethodNotificationToolsListChanged, mcp.MethodNotificationResourcesListChanged, mcp.MethodNotificationPromptsListChanged:
+			provider.mu.Lock()
+			if provider.observing && !slices.Contains(provider.listChanged, n.Method) {
+				provider.listChanged = append(provider.listChanged, n.Method)
+			}
+			provider.mu.Unlock()
+		}
+	})
+}
+
+// ListRoots implements client.RootsHandler
+func (p *rootsProvider) ListRoots(ctx context.Context, request mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	p.mu.Lock()
+	roots := append([]mcp.Root{}, p.roots...)
+	notified := p.notified
+	p.notified = time.Time{}
+	p.mu.Unlock()
+
+	report.addTiming(string(mcp.MethodListRoots), 0, nil)
+	if notified.IsZero() {
+		fmt.Printf("[roots] server requested roots/list; answered %d root(s)\n", len(roots))
+	} else {
+		after := time.Since(notified)
+		fmt.Printf("[roots] server requested roots/list %s after list_changed; answered %d root(s)\n", humanDuration(after), len(roots))
+		select {
+		case p.requested <- after:
+		default:
+		}
+	}
+	return &mcp.ListRootsResult{Roots: roots}, nil
+}
+
+// command runs the interactive 'roots' command: list, add, remove or notify
+func (p *rootsProvider) command(mcpClient *client.Client, args []string) error {
+	if len(args) == 0 {
+		p.print()
+		return nil
+	}
+	switch args[0] {
+	case "add":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: roots add file:///path[,name]")
+		}
+		root, err := parseRoot(args[1])
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		for _, existing := range p.roots {
+			if existing.URI == root.URI {
+				p.mu.Unlock()
+				return fmt.Errorf("%s is already a root", root.URI)
+			}
+		}
+		p.roots = append(p.roots, root)
+		p.mu.Unlock()
+		fmt.Printf("Added root %s\n", root.URI)
+	case "remove", "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: roots remove <uri>")
+		}
+		p.mu.Lock()
+		removed := false
+		for i, existing := range p.roots {
+			if existing.URI == args[1] || existing.Name == args[1] {
+				p.roots = append(p.roots[:i], p.roots[i+1:]...)
+				removed = true
+				break
+			}
+		}
+		p.mu.Unlock()
+		if !removed {
+			return fmt.Errorf("%s is not a root", args[1])
+		}
+		fmt.Printf("Removed root %s\n", args[1])
+	case "notify":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: roots notify")
+		}
+	case "probe":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: roots probe file:///path[,name]")
+		}
+		root, err := parseRoot(args[1])
+		if err != nil {
+			return err
+		}
+		_, err = p.probeReaction(mcpClient, root, rootsRequestWait)
+		return err
+	default:
+		return fmt.Errorf("unknown roots command '%s' (use add, remove, notify or probe)", args[0])
+	}
+	_, err := p.notify(mcpClient)
+	return err
+}
+
+// notify sends notifications/roots/list_changed and waits briefly for the
+// server to ask for the new list, which is how a server shows it reacted.
+// It returns how long the server took to ask, or a negative duration if it
+// did not.
+func (p *rootsProvider) notify(mcpClient *client.Client) (time.Duration, error) {
+	select {
+	case <-p.requested:
+	default:
+	}
+	p.mu.Lock()
+	p.notified = time.Now()
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootsRequestWait)
+	defer cancel()
+	if err := mcpClient.RootListChanges(ctx); err != nil {
+		return -1, fmt.Errorf("failed to send %s: %w", mcp.MethodNotificationRootsListChanged, err)
+	}
+	fmt.Printf("Sent %s; waiting up to %s for the server to request roots/list...\n", mcp.MethodNotificationRootsListChanged, humanDuration(rootsRequestWait))
+	select {
+	case after := <-p.requested:
+		return after, nil
+	case <-ctx.Done():
+		fmt.Printf("The server did not request roots/list within %s\n", humanDuration(rootsRequestWait))
+		p.mu.Lock()
+		p.notified = time.Time{}
+		p.mu.Unlock()
+		return -1, nil
+	}
+}
+
+// probeReaction adds root mid-session, sends list_changed and observes how
+// the server reacts: whether it asks for the roots again, whether it sends
+// list_changed notifications of its own, and how its tool and resource
+// listings change. The reaction is printed and recorded in the report.
+func (p *rootsProvider) probeReaction(mcpClient *client.Client, root mcp.Root, timeout time.Duration) (*rootsReaction, error) {
+	p.mu.Lock()
+	for _, existing := range p.roots {
+		if existing.URI == root.URI {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("%s is already a root", root.URI)
+		}
+	}
+	p.mu.Unlock()
+
+	fmt.Println("\n=== Roots Change ===")
+	toolsBefore, resourcesBefore := listedSurface(mcpClient, timeout)
+
+	p.mu.Lock()
+	p.roots = append(p.roots, root)
+	p.listChanged = nil
+	p.observing = true
+	p.mu.Unlock()
+	fmt.Printf("Added root %s\n", root.URI)
+	after, err := p.notify(mcpClient)
+	if err != nil {
+		p.mu.Lock()
+		p.observing = false
+		p.mu.Unlock()
+		return nil, err
+	}
+	time.Sleep(rootsSettleWait)
+	toolsAfter, resourcesAfter := listedSurface(mcpClient, timeout)
+
+	p.mu.Lock()
+	p.observing = false
+	reaction := &rootsReaction{
+		AddedRoot:   root.URI,
+		Requeried:   after >= 0,
+		ListChanged: slices.Clone(p.listChanged),
+	}
+	p.mu.Unlock()
+	if reaction.Requeried {
+		reaction.RequeryAfter = after
+	}
+	if toolsBefore != nil && toolsAfter != nil {
+		reaction.ToolsAdded, reaction.ToolsRemoved = listDifference(toolsBefore, toolsAfter)
+	}
+	if resourcesBefore != nil && resourcesAfter != nil {
+		reaction.ResourcesAdded, reaction.ResourcesRemoved = listDifference(resourcesBefore, resourcesAfter)
+	}
+	report.setRootsReaction(reaction)
+	printRootsReaction(reaction, toolsBefore != nil && toolsAfter != nil, resourcesBefore != nil && resourcesAfter != nil)
+	return reaction, nil
+}
+
+// listedSurface returns the names of the server's tools and the URIs of its
+// resources, or nil for a capability the server does not advertise or a
+// listing that failed
+func listedSurface(mcpClient *client.Client, timeout time.Duration) ([]string, []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	caps := mcpClient.GetServerCapabilities()
+	var tools, resources []string
+	if caps.Tools != nil {
+		if result, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{}); err == nil {
+			tools = []string{}
+			for _, tool := range result.Tools {
+				tools = append(tools, tool.Name)
+			}
+		} else {
+			fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "failed to list tools: %v", err))
+		}
+	}
+	if caps.Resources != nil {
+		if result, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{}); err == nil {
+			resources = []string{}
+			for _, resource := range result.Resources {
+				resources = append(resources, resource.URI)
+			}
+		} else {
+			fmt.Printf("Warning: %s\n", report.addWarning(warningCheck, "failed to list resources: %v", err))
+		}
+	}
+	return tools, resources
+}
+
+// listDifference returns the items of after that are not in before, and
+// those of before that are not in after
+func listDifference(before, after []string) ([]string, []string) {
+	var added, removed []string
+	for _, item := range after {
+		if !slices.Contains(before, item) {
+			added = append(added, item)
+		}
+	}
+	for _, item := range before {
+		if !slices.Contains(after, item) {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+// printRootsReaction prints the observed reaction to a roots change
+func printRootsReaction(r *rootsReaction, toolsListed, resourcesListed bool) {
+	fmt.Println("Server reaction:")
+	if r.Requeried {
+		fmt.Printf("  Re-queried roots:   yes, after %s\n", humanDuration(r.RequeryAfter))
+	} else {
+		fmt.Println("  Re-queried roots:   no")
+	}
+	if len(r.ListChanged) > 0 {
+		fmt.Printf("  Sent list_changed:  %s\n", strings.Join(r.ListChanged, ", "))
+	} else {
+		fmt.Println("  Sent list_changed:  none")
+	}
+	printListChange := func(label string, listed bool, added, removed []string) {
+		switch {
+		case !listed:
+			fmt.Printf("  %-19s not listed\n", label+":")
+		case len(added) == 0 && len(removed) == 0:
+			fmt.Printf("  %-19s unchanged\n", label+":")
+		default:
+			fmt.Printf("  %-19s %d added, %d removed\n", label+":", len(added), len(removed))
+			for _, item := range added {
+				fmt.Printf("    + %s\n", item)
+			}
+			for _, item := range removed {
+				fmt.Printf("    - %s\n", item)
+			}
+		}
+	}
+	printListChange("Tools", toolsListed, r.ToolsAdded, r.ToolsRemoved)
+	printListChange("Resources", resourcesListed, r.ResourcesAdded, r.ResourcesRemoved)
+	if !r.Requeried && len(r.ListChanged) == 0 && len(r.ToolsAdded)+len(r.ToolsRemoved)+len(r.ResourcesAdded)+len(r.ResourcesRemoved) == 0 {
+		fmt.Println("The server showed no reaction to the roots change")
+	}
+}
+
+// print lists the current roots
+func (p *rootsProvider) print() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.roots) == 0 {
+		fmt.Println("No roots (add one with 'roots add file:///path[,name]')")
+		return
+	}
+	fmt.Println("Roots:")
+	for _, root := range p.roots {
+		if root.Name != "" {
+			fmt.Printf("  %s (%s)\n", root.URI, root.Name)
+		} else {
+			fmt.Printf("  %s\n", root.URI)
+		}
+	}
+}
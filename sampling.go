@@ -0,0 +1,293 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// chatCompletionsPath is appended to a -sampling-endpoint base URL
+const chatCompletionsPath = "/chat/completions"
+
+// maxSamplingErrorBody limits how much of an error response is reported
+const maxSamplingErrorBody = 512
+
+// samplingBridge answers sampling/createMessage requests from the server by
+// forwarding them to an OpenAI-compatible chat completions API
+type samplingBridge struct {
+	endpoint   string
+	models     []string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// chatMessage is a message of the chat completions API; Content is a string
+// or a list of content parts
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// chatRequest is a chat completions request
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+}
+
+// chatResponse is the part of a chat completions response the bridge uses
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newSamplingBridge creates the bridge from -sampling-endpoint,
+// -sampling-model (comma-separated) and -sampling-api-key
+func newSamplingBridge(endpoint, models, apiKey string, timeout time.Duration) (*samplingBridge, error) {
+	endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("endpoint '%s' must be an http:// or https:// URL", endpoint)
+	}
+	if !strings.HasSuffix(endpoint, chatCompletionsPath) {
+		endpoint += chatCompletionsPath
+	}
+	key, err := expandEnvVars(apiKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key: %w", err)
+	}
+	bridge := &samplingBridge{
+		endpoint:   endpoint,
+		apiKey:     key,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+	for _, model := range strings.Split(models, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			bridge.models = append(bridge.models, model)
+		}
+	}
+	return bridge, nil
+}
+
+// CreateMessage implements client.SamplingHandler
+func (b *samplingBridge) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	start := time.Now()
+	result, err := b.createMessage(ctx, request.CreateMessageParams)
+	report.addTiming(string(mcp.MethodSamplingCreateMessage), time.Since(start), err)
+	if err != nil {
+		fmt.Printf("[sampling] failed after %s: %v\n", humanDuration(time.Since(start)), err)
+		report.addError("%s: %v", mcp.MethodSamplingCreateMessage, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// createMessage translates a sampling request to a chat completion and the
+// answer back
+func (b *samplingBridge) createMessage(ctx context.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	model, err := b.selectModel(params.ModelPreferences)
+	if err != nil {
+		return nil, err
+	}
+	chat := chatRequest{Model: model, MaxTokens: params.MaxTokens, Stop: params.StopSequences}
+	if params.Temperature != 0 {
+		chat.Temperature = &params.Temperature
+	}
+	if params.SystemPrompt != "" {
+		chat.Messages = append(chat.Messages, chatMessage{Role: "system", Content: params.SystemPrompt})
+	}
+	for i, message := range params.Messages {
+		content, err := chatContent(message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		chat.Messages = append(chat.Messages, chatMessage{Role: string(message.Role), Content: content})
+	}
+	fmt.Printf("[sampling] asking %s\n", model)
+	if params.IncludeContext != "" && params.IncludeContext != "none" {
+		fmt.Printf("[sampling] includeContext '%s' is not supported; no context is added\n", params.IncludeContext)
+	}
+
+	answer, err := b.complete(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	if len(answer.Choices) == 0 {
+		return nil, fmt.Errorf("the chat completions API returned no choices")
+	}
+	choice := answer.Choices[0]
+	result := &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(choice.Message.Content),
+		},
+		Model:      valueOr(answer.Model, model),
+		StopReason: samplingStopReason(choice.FinishReason),
+	}
+	fmt.Printf("[sampling] %s answered (%d prompt / %d completion tokens, stop: %s): %s\n",
+		result.Model, answer.Usage.PromptTokens, answer.Usage.CompletionTokens, valueOr(result.StopReason, "unknown"), truncateText(choice.Message.Content, 80))
+	return result, nil
+}
+
+// complete sends a chat completions request
+func (b *samplingBridge) complete(ctx context.Context, chat chatRequest) (*chatResponse, error) {
+	body, err := json.Marshal(chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("chat completions request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat completions response: %w", err)
+	}
+
+	var answer chatResponse
+	decodeErr := json.Unmarshal(data, &answer)
+	if resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && answer.Error != nil && answer.Error.Message != "" {
+			return nil, fmt.Errorf("chat completions API returned %s: %s", resp.Status, answer.Error.Message)
+		}
+		return nil, fmt.Errorf("chat completions API returned %s: %s", resp.Status, truncateText(string(data), maxSamplingErrorBody))
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to parse chat completions response: %w", decodeErr)
+	}
+	return &answer, nil
+}
+
+// selectModel picks the model for a request. Hints are matched in order as
+// substrings of the -sampling-model names, as the specification describes;
+// without a match the first configured model is used. With no configured
+// model, the first hint is used as the model name.
+func (b *samplingBridge) selectModel(prefs *mcp.ModelPreferences) (string, error) {
+	if prefs != nil {
+		for _, hint := range prefs.Hints {
+			name := strings.ToLower(strings.TrimSpace(hint.Name))
+			if name == "" {
+				continue
+			}
+			if len(b.models) == 0 {
+				return hint.Name, nil
+			}
+			for _, model := range b.models {
+				if strings.Contains(strings.ToLower(model), name) {
+					return model, nil
+				}
+			}
+		}
+	}
+	if len(b.models) == 0 {
+		return "", fmt.Errorf("the request has no model hint and no -sampling-model is set")
+	}
+	return b.models[0], nil
+}
+
+// chatContent converts MCP message content to chat completions content:
+// text as a string, images and audio as content parts
+func chatContent(content any) (any, error) {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text, nil
+	case mcp.ImageContent:
+		return []map[string]any{{
+			"type":      "image_url",
+			"image_url": map[string]any{"url": "data:" + c.MIMEType + ";base64," + c.Data},
+		}}, nil
+	case mcp.AudioContent:
+		format, ok := map[string]string{"audio/wav": "wav", "audio/x-wav": "wav", "audio/mpeg": "mp3", "audio/mp3": "mp3"}[c.MIMEType]
+		if !ok {
+			return nil, fmt.Errorf("audio of type '%s' cannot be forwarded (wav and mp3 only)", c.MIMEType)
+		}
+		return []map[string]any{{
+			"type":        "input_audio",
+			"input_audio": map[string]any{"data": c.Data, "format": format},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %T", content)
+	}
+}
+
+// samplingStopReason maps a chat completions finish reason to an MCP stop
+// reason; unknown reasons are passed through
+func samplingStopReason(finishReason string) string {
+	switch finishReason {
+	case "stop":
+		return "endTurn"
+	case "length":
+		return "maxTokens"
+	default:
+		return finishReason
+	}
+}
+
+// describeModelPreferences summarizes model preferences for the log line
+func describeModelPreferences(prefs *mcp.ModelPreferences) string {
+	if prefs == nil {
+		return ""
+	}
+	var parts []string
+	var hints []string
+	for _, hint := range prefs.Hints {
+		hints = append(hints, hint.Name)
+	}
+	if len(hints) > 0 {
+		parts = append(parts, "hints "+strings.Join(hints, ", "))
+	}
+	for _, p := range []struct {
+		name  string
+		value float64
+	}{{"cost", prefs.CostPriority}, {"speed", prefs.SpeedPriority}, {"intelligence", prefs.IntelligencePriority}} {
+		if p.value != 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2g", p.name, p.value))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(parts, "; ")
+}
+
+// truncateText shortens text to at most n runes on a single line
+func truncateText(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Rules of the sampling policy, used as the subjects of its findings
+const (
+	samplingRuleMaxTokens      = "max-tokens"
+	samplingRuleTokenBudget    = "token-budget"
+	samplingRuleModel          = "model"
+	samplingRuleIncludeContext = "include-context"
+)
+
+// samplingPolicy is what sampling requests may ask for, set by the
+// -sampling-max-tokens, -sampling-token-budget and -sampling-deny-models
+// flags. Zero limits are unlimited.
+type samplingPolicy struct {
+	maxTokens    int
+	tokenBudget  int
+	deniedModels []string
+	enforce      bool
+}
+
+// samplingRecord is a sampling request the server sent, as recorded in the
+// report
+type samplingRecord struct {
+	Request    mcp.CreateMessageParams `json:"request"`
+	Violations []string                `json:"violations,omitempty"`
+	Refused    bool                    `json:"refused,omitempty"`
+}
+
+// samplingInspector shows every sampling request in full and checks it
+// against the policy before passing it to the responder, the
+// -sampling-endpoint bridge or the -sampling-stub. Without a responder the
+// request is refused once it has been shown.
+type samplingInspector struct {
+	policy    samplingPolicy
+	responder client.SamplingHandler
+
+	// mu guards requested, the maxTokens of the session's requests so far
+	mu        sync.Mutex
+	requested int
+}
+
+// parseDeniedModels parses -sampling-deny-models: comma-separated glob
+// patterns, matched case-insensitively against the requested model hints
+func parseDeniedModels(spec string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid model pattern '%s': %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// enableSampling makes the client answer sampling requests through inspector
+func enableSampling(mcpClient *client.Client, inspector *samplingInspector) {
+	client.WithSamplingHandler(inspector)(mcpClient)
+}
+
+// CreateMessage implements client.SamplingHandler
+func (s *samplingInspector) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	params := request.CreateMessageParams
+	printSamplingRequest(params)
+
+	record := samplingRecord{Request: params, Violations: s.check(params)}
+	record.Refused = s.responder == nil || (s.policy.enforce && len(record.Violations) > 0)
+	report.addSamplingRequest(record)
+	emitEvent(eventSamplingRequest, map[string]any{
+		"request":    params,
+		"violations": record.Violations,
+		"refused":    record.Refused,
+	})
+
+	switch {
+	case s.responder == nil:
+		fmt.Println("[sampling] refused: no responder is configured (-sampling-endpoint or -sampling-stub)")
+		return nil, fmt.Errorf("the client has no sampling responder configured")
+	case record.Refused:
+		fmt.Println("[sampling] refused by the sampling policy (-sampling-enforce)")
+		return nil, fmt.Errorf("the request violates the client's sampling policy: %s", strings.Join(record.Violations, "; "))
+	}
+	return s.responder.CreateMessage(ctx, request)
+}
+
+// check returns the request's violations of the policy. Each is reported as
+// a finding with the rule as its subject; suppressed findings are not
+// violations.
+func (s *samplingInspector) check(params mcp.CreateMessageParams) []string {
+	s.mu.Lock()
+	s.requested += params.MaxTokens
+	requested := s.requested
+	s.mu.Unlock()
+
+	var violations []string
+	violate := func(rule, format string, args ...any) {
+		f := report.addFinding(checkIDSamplingPolicy, rule, format, args...)
+		fmt.Printf("[sampling] %s\n", f)
+		if !f.Suppressed {
+			violations = append(violations, f.Message)
+		}
+	}
+	if s.policy.maxTokens > 0 && params.MaxTokens > s.policy.maxTokens {
+		violate(samplingRuleMaxTokens, "sampling request asks for %d tokens, over the limit of %d per request", params.MaxTokens, s.policy.maxTokens)
+	}
+	if s.policy.tokenBudget > 0 && requested > s.policy.tokenBudget {
+		violate(samplingRuleTokenBudget, "sampling requests have asked for %d tokens in total, over the budget of %d", requested, s.policy.tokenBudget)
+	}
+	if params.ModelPreferences != nil {
+		for _, hint := range params.ModelPreferences.Hints {
+			name := strings.ToLower(strings.TrimSpace(hint.Name))
+			for _, pattern := range s.policy.deniedModels {
+				if matched, _ := path.Match(pattern, name); matched {
+					violate(samplingRuleModel, "sampling request asks for model '%s', which matches the denied pattern '%s'", hint.Name, pattern)
+					break
+				}
+			}
+		}
+	}
+	// Context from all servers would show this server what the client does
+	// with the others
+	if params.IncludeContext == "allServers" {
+		violate(samplingRuleIncludeContext, "sampling request asks to include context from all servers")
+	}
+	return violations
+}
+
+// printSamplingRequest shows a sampling request in full: its parameters,
+// the system prompt and every message
+func printSamplingRequest(params mcp.CreateMessageParams) {
+	fmt.Printf("[sampling] server requested a message (%d message(s))\n", len(params.Messages))
+	fmt.Printf("  maxTokens:         %d\n", params.MaxTokens)
+	if prefs := strings.TrimPrefix(describeModelPreferences(params.ModelPreferences), "; "); prefs != "" {
+		fmt.Printf("  model preferences: %s\n", prefs)
+	}
+	if params.Temperature != 0 {
+		fmt.Printf("  temperature:       %g\n", params.Temperature)
+	}
+	if len(params.StopSequences) > 0 {
+		fmt.Printf("  stopSequences:     %q\n", params.StopSequences)
+	}
+	if params.IncludeContext != "" {
+		fmt.Printf("  includeContext:    %s\n", params.IncludeContext)
+	}
+	if params.Metadata != nil {
+		if data, err := json.Marshal(params.Metadata); err == nil {
+			fmt.Printf("  metadata:          %s\n", data)
+		}
+	}
+	if params.SystemPrompt != "" {
+		fmt.Printf("  system: %s\n", indentContinuation(params.SystemPrompt))
+	}
+	for i, message := range params.Messages {
+		fmt.Printf("  [%d] %s: %s\n", i+1, message.Role, indentContinuation(describeSamplingContent(message.Content)))
+	}
+}
+
+// describeSamplingContent shows text content in full and summarizes the
+// other content types
+func describeSamplingContent(content any) string {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text
+	case mcp.ImageContent:
+		return fmt.Sprintf("[image %s, %s of base64]", c.MIMEType, humanBytes(int64(len(c.Data))))
+	case mcp.AudioContent:
+		return fmt.Sprintf("[audio %s, %s of base64]", c.MIMEType, humanBytes(int64(len(c.Data))))
+	default:
+		return fmt.Sprintf("[%T]", content)
+	}
+}
+
+// indentContinuation indents the lines after the first of multi-line text so
+// that it stays under its label
+func indentContinuation(text string) string {
+	return strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n      ")
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// samplingStubModel is the model name the stub reports
+const samplingStubModel = "mcpprobe-stub"
+
+// samplingStub answers sampling/createMessage requests itself, after a fixed
+// delay and with a reply that depends only on the request, so that tools
+// which use sampling can be tested reproducibly and without an LLM
+type samplingStub struct {
+	delay time.Duration
+	reply string
+
+	// requests and waited count the answered requests and the time spent
+	// answering them, for the latency breakdown of tool calls
+	requests atomic.Int64
+	waited   atomic.Int64
+}
+
+// activeSamplingStub is the stub set by -sampling-stub, or nil
+var activeSamplingStub *samplingStub
+
+// CreateMessage implements client.SamplingHandler
+func (s *samplingStub) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	start := time.Now()
+	params := request.CreateMessageParams
+	fmt.Printf("[sampling] the stub answers after %s\n", humanDuration(s.delay))
+
+	var err error
+	if s.delay > 0 {
+		timer := time.NewTimer(s.delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("cancelled while the stub was waiting: %w", ctx.Err())
+		}
+	}
+	elapsed := time.Since(start)
+	s.requests.Add(1)
+	s.waited.Add(int64(elapsed))
+	report.addTiming(string(mcp.MethodSamplingCreateMessage), elapsed, err)
+	if err != nil {
+		fmt.Printf("[sampling] failed after %s: %v\n", humanDuration(elapsed), err)
+		report.addError("%s: %v", mcp.MethodSamplingCreateMessage, err)
+		return nil, err
+	}
+
+	text, stopReason := s.answer(params)
+	fmt.Printf("[sampling] %s answered (stop: %s): %s\n", samplingStubModel, stopReason, truncateText(text, 80))
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(text),
+		},
+		Model:      samplingStubModel,
+		StopReason: stopReason,
+	}, nil
+}
+
+// answer returns the stub's reply: -sampling-stub-reply, or an echo of the
+// last text message. A reply longer than maxTokens words is cut there and
+// stops with maxTokens, so servers' handling of truncation can be tested.
+func (s *samplingStub) answer(params mcp.CreateMessageParams) (string, string) {
+	text := s.reply
+	if text == "" {
+		last := "a message without text"
+		for i := len(params.Messages) - 1; i >= 0; i-- {
+			if content, ok := params.Messages[i].Content.(mcp.TextContent); ok {
+				last = content.Text
+				break
+			}
+		}
+		text = "Stub answer to: " + last
+	}
+	if words := strings.Fields(text); params.MaxTokens > 0 && len(words) > params.MaxTokens {
+		return strings.Join(words[:params.MaxTokens], " "), "maxTokens"
+	}
+	return text, "endTurn"
+}
+
+// samplingStubUsage returns the requests answered by the stub so far and the
+// time spent answering them, or zeros without -sampling-stub
+func samplingStubUsage() (int64, time.Duration) {
+	if activeSamplingStub == nil {
+		return 0, 0
+	}
+	return activeSamplingStub.requests.Load(), time.Duration(activeSamplingStub.waited.Load())
+}
+
+// printSamplingLatency breaks down a tool call that the stub answered
+// sampling requests for: the time spent in the stub is known exactly, so the
+// rest is the server's own work and the round trips of the sampling requests
+func printSamplingLatency(tool string, requests int64, sampling, total time.Duration) {
+	if requests == 0 {
+		return
+	}
+	overhead := max(total-sampling, 0)
+	fmt.Printf("Sampling latency for '%s': %s end to end, %d sampling request(s) taking %s in the stub, %s in the server and round trips\n",
+		tool, humanDuration(total), requests, humanDuration(sampling), humanDuration(overhead))
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// contentDir is the directory set with -save-content; empty disables saving
+var contentDir string
+
+// mimeExtensions are preferred extensions for common MIME types. The system
+// MIME database is consulted for others, and may be missing or list several
+// extensions for one type.
+var mimeExtensions = map[string]string{
+	"text/plain":       ".txt",
+	"text/markdown":    ".md",
+	"text/html":        ".html",
+	"text/csv":         ".csv",
+	"application/json": ".json",
+	"application/xml":  ".xml",
+	"application/pdf":  ".pdf",
+	"image/png":        ".png",
+	"image/jpeg":       ".jpg",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"image/svg+xml":    ".svg",
+	"audio/wav":        ".wav",
+	"audio/x-wav":      ".wav",
+	"audio/mpeg":       ".mp3",
+	"audio/ogg":        ".ogg",
+	"audio/webm":       ".weba",
+}
+
+// setContentDir sets the -save-content directory, creating it if necessary
+func setContentDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	contentDir = dir
+	return nil
+}
+
+// saveToolContent writes each content item of a tool result to the
+// -save-content directory
+func saveToolContent(toolName string, result *mcp.CallToolResult) {
+	if contentDir == "" || result == nil {
+		return
+	}
+	for i, content := range result.Content {
+		var data []byte
+		var mimeType, defaultExt string
+		var err error
+		switch c := content.(type) {
+		case mcp.TextContent:
+			data, defaultExt = []byte(c.Text), ".txt"
+		case mcp.ImageContent:
+			mimeType = c.MIMEType
+			data, err = base64.StdEncoding.DecodeString(c.Data)
+		case mcp.AudioContent:
+			mimeType = c.MIMEType
+			data, err = base64.StdEncoding.DecodeString(c.Data)
+		case mcp.EmbeddedResource:
+			data, mimeType, err = resourceContentData(c.Resource)
+		default:
+			fmt.Printf("Content %d not saved: unsupported content type %T\n", i+1, c)
+			continue
+		}
+		if err != nil {
+			reportSaveError(i+1, err)
+			continue
+		}
+		saveContentItem(toolName, i+1, len(result.Content), mimeType, defaultExt, data)
+	}
+}
+
+// saveResourceContents writes each content item of a resources/read result to
+// the -save-content directory, named after the last segment of its URI
+func saveResourceContents(contents []mcp.ResourceContents) {
+	if contentDir == "" {
+		return
+	}
+	for i, content := range contents {
+		data, mimeType, err := resourceContentData(content)
+		if err != nil {
+			reportSaveError(i+1, err)
+			continue
+		}
+		uri, defaultExt := "", ""
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			uri, defaultExt = c.URI, ".txt"
+		case mcp.BlobResourceContents:
+			uri = c.URI
+		}
+		saveContentItem(uriFileName(uri), i+1, len(contents), mimeType, defaultExt, data)
+	}
+}
+
+// uriFileName returns the last path segment of a URI, without its scheme,
+// query or fragment
+func uriFileName(uri string) string {
+	if i := strings.Index(uri, "://"); i >= 0 {
+		uri = uri[i+3:]
+	}
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri = uri[:i]
+	}
+	uri = strings.TrimRight(uri, "/")
+	return uri[strings.LastIndex(uri, "/")+1:]
+}
+
+// resourceContentData returns the bytes and MIME type of a resource content
+// item, decoding blobs
+func resourceContentData(content mcp.ResourceContents) ([]byte, string, error) {
+	switch c := content.(type) {
+	case mcp.TextResourceContents:
+		return []byte(c.Text), c.MIMEType, nil
+	case mcp.BlobResourceContents:
+		data, err := base64.StdEncoding.DecodeString(c.Blob)
+		if err != nil {
+			return nil, c.MIMEType, fmt.Errorf("blob is not valid base64: %w", err)
+		}
+		return data, c.MIMEType, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported resource content type %T", c)
+	}
+}
+
+// saveContentItem writes one content item to a new file named
+// <name>[-<n>]<ext>; an existing file is never overwritten
+func saveContentItem(name string, n, total int, mimeType, defaultExt string, data []byte) {
+	ext := extensionForMIME(mimeType)
+	if ext == "" {
+		ext = valueOr(defaultExt, ".bin")
+	}
+	// A resource named report.pdf is saved as report.pdf, not report.pdf.pdf
+	base := sanitizeFileName(strings.TrimSuffix(name, ext))
+	if total > 1 {
+		base = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	path := filepath.Join(contentDir, base+ext)
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			path = filepath.Join(contentDir, fmt.Sprintf("%s.%d%s", base, i, ext))
+			continue
+		}
+		if err != nil {
+			reportSaveError(n, err)
+			return
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			reportSaveError(n, err)
+			return
+		}
+		break
+	}
+	fmt.Printf("Saved content %d to %s (%s)\n", n, path, humanBytes(int64(len(data))))
+}
+
+// extensionForMIME returns the file extension for a MIME type, or "" if unknown
+func extensionForMIME(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ""
+	}
+	if ext, ok := mimeExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return ".txt"
+	}
+	return ""
+}
+
+// sanitizeFileName makes a tool name or URI segment safe to use as a file name
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	name = strings.Trim(name, ".")
+	if name == "" {
+		return "content"
+	}
+	return name
+}
+
+// reportSaveError reports a content item that could not be saved
+func reportSaveError(n int, err error) {
+	fmt.Printf("Content %d not saved: %v\n", n, err)
+	report.addError("-save-content: content %d: %v", n, err)
+}
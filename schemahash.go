@@ -0,0 +1,78 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// minSchemaHashLength is the shortest -expect-schema-hash prefix accepted
+const minSchemaHashLength = 8
+
+// schemaHashPattern matches a full schema hash or a prefix of one
+var schemaHashPattern = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// toolSchemaHash returns the hex SHA-256 hash of a tool's input schema and,
+// if it declares one, its output schema. The schemas are hashed as canonical
+// JSON (sorted keys, no whitespace), so the hash only changes when the
+// tool's contract does, not when the server reorders or reformats it.
+func toolSchemaHash(tool mcp.Tool) (string, error) {
+	data, err := json.Marshal(tool)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tool '%s': %w", tool.Name, err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("failed to decode tool '%s': %w", tool.Name, err)
+	}
+	schemas := map[string]any{"inputSchema": fields["inputSchema"]}
+	if output, ok := fields["outputSchema"]; ok {
+		schemas["outputSchema"] = output
+	}
+	canonical, err := json.Marshal(schemas)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode schema of tool '%s': %w", tool.Name, err)
+	}
+	return sha256Hex(canonical), nil
+}
+
+// validateSchemaHash checks an -expect-schema-hash value: hex, at least
+// minSchemaHashLength digits and no longer than a SHA-256 hash
+func validateSchemaHash(expected string) error {
+	if !schemaHashPattern.MatchString(expected) {
+		return fmt.Errorf("'%s' is not a hex hash", expected)
+	}
+	if len(expected) < minSchemaHashLength || len(expected) > 64 {
+		return fmt.Errorf("give between %d and 64 hex digits of the hash", minSchemaHashLength)
+	}
+	return nil
+}
+
+// checkToolSchemaHash fails unless the tool's schema hash starts with the
+// expected hash (compared case-insensitively)
+func checkToolSchemaHash(tool mcp.Tool, expected string) error {
+	hash, err := toolSchemaHash(tool)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(hash, strings.ToLower(expected)) {
+		return fmt.Errorf("tool '%s' schema hash is %s, expected %s: its schema changed; review the new contract with -list-only before updating the hash", tool.Name, hash, expected)
+	}
+	fmt.Printf("Schema hash of tool '%s' matches (%s)\n", tool.Name, hash)
+	return nil
+}
+
+// schemaHashLabel returns a tool's schema hash for listings, or the error
+func schemaHashLabel(tool mcp.Tool) string {
+	hash, err := toolSchemaHash(tool)
+	if err != nil {
+		return fmt.Sprintf("(unavailable: %v)", err)
+	}
+	return hash
+}
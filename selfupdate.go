@@ -0,0 +1,436 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latestReleaseURL is the GitHub API endpoint for the latest release
+const latestReleaseURL = "https://api.github.com/repos/PivotLLM/MCPProbe/releases/latest"
+
+// Release assets besides the binaries: SHA-256 checksums of every asset in
+// sha256sum format, and an ed25519 signature of the checksums file
+const (
+	checksumsAsset = "SHA256SUMS"
+	signatureAsset = "SHA256SUMS.sig"
+)
+
+// envCheckUpdates opts in to the startup version check, as check_updates
+// does in the config file
+const envCheckUpdates = "MCPPROBE_CHECK_UPDATES"
+
+// updateCheckInterval is how often the startup version check asks for the
+// latest release; in between, the cached answer is used
+const updateCheckInterval = 24 * time.Hour
+
+// updateCheckTimeout bounds the startup version check, which must never
+// hold up a probe run
+const updateCheckTimeout = 3 * time.Second
+
+// maxReleaseDownload bounds a downloaded binary
+const maxReleaseDownload = 256 << 20
+
+// releaseSigningKey is the base64 ed25519 public key that signs release
+// checksums. The release workflow sets it with
+// -ldflags "-X main.releaseSigningKey=<key>"; builds without it cannot
+// verify a release, so self-update refuses to install one.
+var releaseSigningKey string
+
+// releaseInfo is the part of a GitHub release the updater uses
+type releaseInfo struct {
+	TagName string         `json:"tag_name"`
+	HTMLURL string         `json:"html_url"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is a file attached to a release
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// updateCheckState caches the startup version check between runs
+type updateCheckState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+// asset returns the release's asset with the given name
+func (r *releaseInfo) asset(name string) *releaseAsset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// runSelfUpdateCommand implements the 'self-update' subcommand
+func runSelfUpdateCommand(args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	checkOnly := fs.Bool("check", false, "Only report whether a newer release is available")
+	force := fs.Bool("force", false, "Install the latest release even if it is not newer than this build")
+	releaseURL := fs.String("release-url", latestReleaseURL, "Release API endpoint (GitHub release JSON), e.g. for a mirror")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Time limit for the update")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: self-update [-check] [-force] [-release-url <url>]")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	httpClient := &http.Client{}
+	release, err := fetchLatestRelease(ctx, httpClient, *releaseURL)
+	if err != nil {
+		return err
+	}
+	newer := compareReleaseVersions(release.TagName, ProgVer) > 0
+	fmt.Printf("Installed: %s\n", ProgVer)
+	fmt.Printf("Latest:    %s\n", release.TagName)
+	if *checkOnly {
+		if newer {
+			fmt.Printf("A newer release is available: %s\n", valueOr(release.HTMLURL, release.TagName))
+		} else {
+			fmt.Println("This build is up to date")
+		}
+		return nil
+	}
+	if !newer && !*force {
+		fmt.Println("This build is up to date (use -force to reinstall)")
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the running binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to resolve the running binary: %w", err)
+	}
+	return installRelease(ctx, httpClient, release, exe)
+}
+
+// installRelease replaces exe with this platform's binary from the release,
+// once the binary matches the release's checksums and their signature
+// verifies against releaseSigningKey. exe is left unchanged on any failure.
+func installRelease(ctx context.Context, httpClient *http.Client, release *releaseInfo, exe string) error {
+	if releaseSigningKey == "" {
+		return fmt.Errorf("this build has no release signing key and cannot verify releases; download %s manually or rebuild from source", valueOr(release.HTMLURL, release.TagName))
+	}
+	name := releaseAssetName()
+	binary := release.asset(name)
+	if binary == nil {
+		return fmt.Errorf("release %s has no build for %s/%s (expected asset '%s')", release.TagName, runtime.GOOS, runtime.GOARCH, name)
+	}
+	sums := release.asset(checksumsAsset)
+	if sums == nil {
+		return fmt.Errorf("release %s has no %s; refusing to install an unverified binary", release.TagName, checksumsAsset)
+	}
+	sumsData, err := downloadAsset(ctx, httpClient, sums.URL, 1<<20)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksumsSignature(ctx, httpClient, release, sumsData); err != nil {
+		return err
+	}
+	want, err := checksumFor(sumsData, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Downloading %s...\n", name)
+	tmp, err := downloadVerified(ctx, httpClient, binary.URL, filepath.Dir(exe), want)
+	if err != nil {
+		return err
+	}
+	if err := replaceExecutable(exe, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	fmt.Printf("Updated %s to %s (SHA-256 %s)\n", exe, release.TagName, want)
+	return nil
+}
+
+// fetchLatestRelease asks the release API for the latest release
+func fetchLatestRelease(ctx context.Context, httpClient *http.Client, url string) (*releaseInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", ProgName+"/"+ProgVer)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the latest release: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the latest release: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release API returned %s", resp.Status)
+	}
+	var release releaseInfo
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("the latest release has no tag")
+	}
+	return &release, nil
+}
+
+// releaseAssetName is the name of this platform's binary in a release
+func releaseAssetName() string {
+	name := fmt.Sprintf("mcp-probe-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// downloadAsset downloads a small release asset into memory
+func downloadAsset(ctx context.Context, httpClient *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset URL: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %s", url, humanBytes(limit))
+	}
+	return data, nil
+}
+
+// verifyChecksumsSignature checks the release's signature of its checksums
+// against releaseSigningKey
+func verifyChecksumsSignature(ctx context.Context, httpClient *http.Client, release *releaseInfo, sums []byte) error {
+	key, err := base64.StdEncoding.DecodeString(releaseSigningKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("this build's release signing key is invalid")
+	}
+	sigAsset := release.asset(signatureAsset)
+	if sigAsset == nil {
+		return fmt.Errorf("release %s has no %s; refusing to install an unsigned release", release.TagName, signatureAsset)
+	}
+	data, err := downloadAsset(ctx, httpClient, sigAsset.URL, 4096)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", signatureAsset, err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), sums, sig) {
+		return fmt.Errorf("the signature of %s does not verify; refusing to install release %s", checksumsAsset, release.TagName)
+	}
+	fmt.Printf("Verified the signature of %s\n", checksumsAsset)
+	return nil
+}
+
+// checksumFor finds an asset's SHA-256 in a sha256sum-format checksums file
+func checksumFor(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+			return "", fmt.Errorf("invalid checksum for %s in %s", name, checksumsAsset)
+		}
+		return sum, nil
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, name)
+}
+
+// downloadVerified downloads the binary to a temporary file in dir and
+// checks its SHA-256. The file is removed unless it matches.
+func downloadVerified(ctx context.Context, httpClient *http.Client, url, dir, want string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid asset URL: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".mcp-probe-update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the new binary next to the current one: %w", err)
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(resp.Body, maxReleaseDownload+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+		err = fmt.Errorf("failed to download %s: %w", url, err)
+	case n > maxReleaseDownload:
+		err = fmt.Errorf("%s is larger than %s", url, humanBytes(maxReleaseDownload))
+	case hex.EncodeToString(hash.Sum(nil)) != want:
+		err = fmt.Errorf("checksum mismatch for %s: got %x, expected %s", url, hash.Sum(nil), want)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// replaceExecutable moves the new binary over the running one. Windows does
+// not allow replacing a running executable, so it is moved aside first.
+func replaceExecutable(exe, newPath string) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return fmt.Errorf("failed to read the running binary: %w", err)
+	}
+	if err := os.Chmod(newPath, info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("failed to make the new binary executable: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to move the running binary aside: %w", err)
+		}
+		if err := os.Rename(newPath, exe); err != nil {
+			_ = os.Rename(old, exe)
+			return fmt.Errorf("failed to install the new binary: %w", err)
+		}
+		return nil
+	}
+	if err := os.Rename(newPath, exe); err != nil {
+		return fmt.Errorf("failed to install the new binary (check write permission for %s): %w", filepath.Dir(exe), err)
+	}
+	return nil
+}
+
+// compareReleaseVersions compares two versions such as "v1.2.0" and
+// "1.1.0" numerically, ignoring any pre-release or build suffix
+func compareReleaseVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numeric components of a version
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// updateCheckPath returns the file caching the startup version check
+func updateCheckPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "mcpprobe", "update-check.json"), nil
+}
+
+// startUpdateCheck looks for a newer release in the background and registers
+// an exit hook that mentions it on stderr. The release API is asked at most
+// once per updateCheckInterval; failures are silent.
+func startUpdateCheck() {
+	path, err := updateCheckPath()
+	if err != nil {
+		return
+	}
+	latest := make(chan string, 1)
+	var state updateCheckState
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &state) == nil && time.Since(state.CheckedAt) < updateCheckInterval {
+		latest <- state.Latest
+	} else {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+			defer cancel()
+			release, err := fetchLatestRelease(ctx, &http.Client{}, latestReleaseURL)
+			if err != nil {
+				return
+			}
+			state := updateCheckState{CheckedAt: time.Now().UTC(), Latest: release.TagName}
+			if data, err := json.Marshal(state); err == nil {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+					_ = os.WriteFile(path, data, 0o644)
+				}
+			}
+			latest <- release.TagName
+		}()
+	}
+	// A check still in flight when the run ends is not waited for
+	addExitHook(func() {
+		select {
+		case tag := <-latest:
+			if !quiet && compareReleaseVersions(tag, ProgVer) > 0 {
+				fmt.Fprintf(os.Stderr, "\nMCPProbe %s is available (this is %s); update with 'probe self-update'\n", tag, ProgVer)
+			}
+		default:
+		}
+	})
+}
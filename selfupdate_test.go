@@ -0,0 +1,119 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallRelease(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := releaseSigningKey
+	t.Cleanup(func() { releaseSigningKey = saved })
+
+	name := releaseAssetName()
+	binary := []byte("the new release\n")
+	sumsFor := func(data []byte, asset string) string {
+		return fmt.Sprintf("%x  %s\n%x  %s\n", sha256.Sum256([]byte("other")), "mcp-probe-plan9-386", sha256.Sum256(data), asset)
+	}
+	sign := func(key ed25519.PrivateKey, sums string) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(sums)))
+	}
+	goodSums := sumsFor(binary, name)
+
+	tests := []struct {
+		name    string
+		key     string
+		sums    string
+		sig     string
+		wantErr string
+	}{
+		{"good signature", base64.StdEncoding.EncodeToString(publicKey), goodSums, sign(privateKey, goodSums), ""},
+		{"tampered checksums", base64.StdEncoding.EncodeToString(publicKey), strings.Replace(goodSums, "plan9", "linux", 1), sign(privateKey, goodSums), "does not verify"},
+		{"wrong key", base64.StdEncoding.EncodeToString(publicKey), goodSums, sign(otherKey, goodSums), "does not verify"},
+		{"missing entry", base64.StdEncoding.EncodeToString(publicKey), sumsFor(binary, "mcp-probe-other"), sign(privateKey, sumsFor(binary, "mcp-probe-other")), "has no checksum for " + name},
+		{"checksum mismatch", base64.StdEncoding.EncodeToString(publicKey), sumsFor([]byte("another build"), name), sign(privateKey, sumsFor([]byte("another build"), name)), "checksum mismatch"},
+		{"no signing key", "", goodSums, sign(privateKey, goodSums), "has no release signing key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/" + name:
+					_, _ = w.Write(binary)
+				case "/" + checksumsAsset:
+					_, _ = w.Write([]byte(tt.sums))
+				case "/" + signatureAsset:
+					_, _ = w.Write([]byte(tt.sig + "\n"))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			release := &releaseInfo{TagName: "v9.9.9", Assets: []releaseAsset{
+				{Name: name, URL: server.URL + "/" + name},
+				{Name: checksumsAsset, URL: server.URL + "/" + checksumsAsset},
+				{Name: signatureAsset, URL: server.URL + "/" + signatureAsset},
+			}}
+
+			dir := t.TempDir()
+			exe := filepath.Join(dir, "mcp-probe")
+			current := []byte("the running build\n")
+			if err := os.WriteFile(exe, current, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			releaseSigningKey = tt.key
+			err := installRelease(context.Background(), server.Client(), release, exe)
+
+			got, readErr := os.ReadFile(exe)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("installRelease: %v", err)
+				}
+				if !bytes.Equal(got, binary) {
+					t.Errorf("executable = %q, want the release", got)
+				}
+				if info, err := os.Stat(exe); err != nil || info.Mode().Perm()&0o111 == 0 {
+					t.Errorf("the installed binary is not executable: %v %v", info.Mode(), err)
+				}
+			} else {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("installRelease: err = %v, want %q", err, tt.wantErr)
+				}
+				if !bytes.Equal(got, current) {
+					t.Errorf("executable = %q after a failed update, want it unchanged", got)
+				}
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("the update left files behind: %v", entries)
+			}
+		})
+	}
+}
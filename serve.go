@@ -0,0 +1,629 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// serveStopTimeout is how long a stdio server may take to exit after its
+	// stdin is closed before it is killed
+	serveStopTimeout = 5 * time.Second
+	// serveQueueSize is the number of messages buffered for each stream
+	serveQueueSize = 64
+)
+
+// stdioBridge serves a stdio MCP server over streamable HTTP and SSE. Each
+// HTTP session gets a process of its own, as a stdio server holds a single
+// session; messages are passed through unchanged.
+type stdioBridge struct {
+	command   string
+	args      []string
+	env       []string
+	logger    *log.Logger
+	logBodies bool
+	recorder  *sessionRecorder
+	tracer    *wireTracer
+
+	mu       sync.Mutex
+	sessions map[string]*bridgeSession
+}
+
+// bridgeSession is one session and the stdio server process behind it
+type bridgeSession struct {
+	id        string
+	transport string
+	cmd       *exec.Cmd
+	done      chan struct{}
+
+	stdinMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu sync.Mutex
+	// exchanges are the POST requests awaiting responses, oldest first
+	exchanges []*bridgeExchange
+	// listener receives the messages the server sends outside of a request:
+	// the standalone stream of streamable HTTP, or the SSE stream
+	listener chan []byte
+}
+
+// bridgeExchange collects the messages of one POST of streamable HTTP: the
+// responses to its requests, and the server's messages sent while it is
+// the newest request in flight, such as progress notifications
+type bridgeExchange struct {
+	ids map[string]bool
+	out chan []byte
+}
+
+// runServeCommand implements the 'serve' subcommand
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	command := fs.String("cmd", "", "Command line of the stdio MCP server to serve (required)")
+	extraArgs := fs.String("args", "", "More arguments to pass to the stdio server (comma-separated)")
+	env := fs.String("env", "", "Environment variables for stdio server (KEY=VALUE,...)")
+	listen := fs.String("listen", "127.0.0.1:8080", "Address to listen on")
+	logBodies := fs.Bool("log-bodies", true, "Log message bodies")
+	pretty := fs.Bool("pretty", false, "Log each JSON-RPC message as pretty-printed JSON with its direction and timing, instead of raw bodies")
+	record := fs.String("record", "", "Record the served traffic to this session recording file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fields := strings.Fields(*command)
+	if len(fields) == 0 {
+		return fmt.Errorf("-cmd is required")
+	}
+	stdioArgs, stdioEnv := parseStdioOptions(*extraArgs, *env)
+	b := &stdioBridge{
+		command:   fields[0],
+		args:      append(fields[1:], stdioArgs...),
+		env:       stdioEnv,
+		logger:    log.New(os.Stdout, "", log.Ltime|log.Lmicroseconds),
+		logBodies: *logBodies,
+		sessions:  map[string]*bridgeSession{},
+	}
+	if _, err := exec.LookPath(b.command); err != nil {
+		return fmt.Errorf("failed to find stdio server: %w", err)
+	}
+	if *pretty {
+		b.tracer = newWireTracer()
+		b.logBodies = false
+	}
+	if *record != "" {
+		var err error
+		if b.recorder, err = newSessionRecorder(*record); err != nil {
+			return err
+		}
+		defer func() { _ = b.recorder.Close() }()
+	}
+
+	b.logger.Printf("Serving %s at http://%s/mcp (streamable HTTP) and http://%s/sse (SSE)", *command, *listen, *listen)
+	if *record != "" {
+		b.logger.Printf("Recording session to %s", *record)
+	}
+
+	// The stdio servers run in their own process groups, so they are
+	// stopped here rather than by the terminal's interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: *listen, Handler: b, ReadHeaderTimeout: 30 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), serveStopTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+	err := server.ListenAndServe()
+	b.stopAll()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (b *stdioBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/sse" && r.Method == http.MethodGet:
+		b.serveSSEStream(w, r)
+	case r.URL.Path == "/message" && r.Method == http.MethodPost:
+		b.serveSSEMessage(w, r)
+	case r.Method == http.MethodPost:
+		b.servePost(w, r)
+	case r.Method == http.MethodGet:
+		b.serveStandaloneStream(w, r)
+	case r.Method == http.MethodDelete:
+		b.serveDelete(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// servePost handles a POST of streamable HTTP. An initialize request without
+// a session starts a new server process. Requests are answered with JSON
+// when the response is the only message, and with an event stream when the
+// server sends other messages first.
+func (b *stdioBridge) servePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	body = bytes.TrimSpace(body)
+	messages := parseGatewayMessages(body)
+	if len(messages) == 0 {
+		writeBridgeJSON(w, http.StatusBadRequest, jsonrpcErrorResponse(nil, mcp.PARSE_ERROR, "invalid JSON-RPC message"))
+		return
+	}
+
+	var s *bridgeSession
+	if id := r.Header.Get(mcpSessionHeader); id != "" {
+		if s = b.session(id); s == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		initialize := false
+		for _, m := range messages {
+			initialize = initialize || m.Method == string(mcp.MethodInitialize)
+		}
+		if !initialize {
+			http.Error(w, "missing "+mcpSessionHeader+" header", http.StatusBadRequest)
+			return
+		}
+		if s, err = b.start("http"); err != nil {
+			b.logger.Printf("   failed to start the stdio server: %v", err)
+			http.Error(w, fmt.Sprintf("failed to start the stdio server: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set(mcpSessionHeader, s.id)
+
+	ids := map[string]bool{}
+	for _, m := range messages {
+		if m.Method != "" && len(m.ID) > 0 {
+			ids[string(m.ID)] = true
+		}
+	}
+	var ex *bridgeExchange
+	if len(ids) > 0 {
+		ex = s.begin(ids)
+		defer s.end(ex)
+	}
+	b.logger.Printf("-> [%s] %s%s", shortSessionID(s.id), describeGatewayMessages(messages), b.bodyForLog(body))
+	b.inspect(b.recordFor(r, s), body)
+	if err := s.write(body); err != nil {
+		http.Error(w, "the stdio server is not running", http.StatusBadGateway)
+		return
+	}
+	if ex == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	streaming := false
+	for len(ids) > 0 {
+		var msg []byte
+		select {
+		case msg = <-ex.out:
+		case <-s.done:
+			// Take the answers the server wrote before it exited first
+			select {
+			case msg = <-ex.out:
+			default:
+			}
+		case <-r.Context().Done():
+			return
+		}
+		if msg == nil {
+			// The server exited without answering
+			for id := range ids {
+				msg = jsonrpcErrorResponse(json.RawMessage(id), mcp.INTERNAL_ERROR, "the stdio server exited")
+				delete(ids, id)
+				break
+			}
+		}
+		var m jsonrpcMessage
+		_ = json.Unmarshal(msg, &m)
+		if m.Method == "" {
+			delete(ids, string(m.ID))
+		}
+		if !streaming && len(ids) == 0 && len(messages) == 1 {
+			writeBridgeJSON(w, http.StatusOK, msg)
+			return
+		}
+		if !streaming {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			streaming = true
+		}
+		if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// serveStandaloneStream handles the GET stream of streamable HTTP, which
+// carries the messages the server sends outside of a request
+func (b *stdioBridge) serveStandaloneStream(w http.ResponseWriter, r *http.Request) {
+	s := b.session(r.Header.Get(mcpSessionHeader))
+	if s == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	out, ok := s.listen()
+	if !ok {
+		http.Error(w, "a stream is already open for this session", http.StatusConflict)
+		return
+	}
+	defer s.unlisten(out)
+	b.logger.Printf("<- [%s] stream of server messages opened", shortSessionID(s.id))
+	w.Header().Set(mcpSessionHeader, s.id)
+	b.stream(w, r, s, out, "")
+	b.logger.Printf("   [%s] stream of server messages closed", shortSessionID(s.id))
+}
+
+// serveDelete ends a streamable HTTP session, stopping its server process
+func (b *stdioBridge) serveDelete(w http.ResponseWriter, r *http.Request) {
+	s := b.session(r.Header.Get(mcpSessionHeader))
+	if s == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	b.logger.Printf("-> [%s] session ended by the client", shortSessionID(s.id))
+	b.stop(s)
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveSSEStream handles the stream of the SSE transport. Each stream is a
+// session with its own server process, which is stopped when the client
+// disconnects.
+func (b *stdioBridge) serveSSEStream(w http.ResponseWriter, r *http.Request) {
+	s, err := b.start("sse")
+	if err != nil {
+		b.logger.Printf("   failed to start the stdio server: %v", err)
+		http.Error(w, fmt.Sprintf("failed to start the stdio server: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer b.stop(s)
+	out, _ := s.listen()
+	b.stream(w, r, s, out, "/message?sessionId="+s.id)
+	b.logger.Printf("   [%s] event stream closed by the client", shortSessionID(s.id))
+}
+
+// serveSSEMessage handles a message posted to a session of the SSE
+// transport, whose answers are sent on the session's stream
+func (b *stdioBridge) serveSSEMessage(w http.ResponseWriter, r *http.Request) {
+	s := b.session(r.URL.Query().Get("sessionId"))
+	if s == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	body = bytes.TrimSpace(body)
+	b.logger.Printf("-> [%s] %s%s", shortSessionID(s.id), describeGatewayMessages(parseGatewayMessages(body)), b.bodyForLog(body))
+	b.inspect(b.recordFor(r, s), body)
+	if err := s.write(body); err != nil {
+		http.Error(w, "the stdio server is not running", http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte("Accepted"))
+}
+
+// stream writes the messages of a listener as SSE events until the client
+// disconnects or the server process exits. SSE streams first announce the
+// message endpoint.
+func (b *stdioBridge) stream(w http.ResponseWriter, r *http.Request, s *bridgeSession, out chan []byte, endpoint string) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if endpoint != "" {
+		_, _ = fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case msg := <-out:
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-s.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// start starts a server process for a new session
+func (b *stdioBridge) start(transportName string) (*bridgeSession, error) {
+	cmd, err := stdioCommand(context.Background(), b.command, b.env, b.args)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start subprocess: %w", err)
+	}
+
+	s := &bridgeSession{id: newSessionID(), transport: transportName, cmd: cmd, stdin: stdin, done: make(chan struct{})}
+	b.mu.Lock()
+	b.sessions[s.id] = s
+	b.mu.Unlock()
+	b.logger.Printf("   [%s] started %s (pid %d) for a new %s session", shortSessionID(s.id), b.command, cmd.Process.Pid, transportName)
+
+	go func() {
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				b.receive(s, line)
+			}
+			if err != nil {
+				break
+			}
+		}
+		err := cmd.Wait()
+		b.mu.Lock()
+		delete(b.sessions, s.id)
+		b.mu.Unlock()
+		close(s.done)
+		if err != nil {
+			b.logger.Printf("   [%s] the stdio server exited: %v", shortSessionID(s.id), err)
+		} else {
+			b.logger.Printf("   [%s] the stdio server exited", shortSessionID(s.id))
+		}
+	}()
+	return s, nil
+}
+
+// receive routes a message from a server process: responses go to the
+// POST that sent the request, other messages to the newest POST in flight
+// or, failing that, to the session's listener
+func (b *stdioBridge) receive(s *bridgeSession, line []byte) {
+	messages := parseGatewayMessages(line)
+	b.logger.Printf("<- [%s] %s%s", shortSessionID(s.id), describeGatewayMessages(messages), b.bodyForLog(line))
+	b.inspect(sessionRecord{Direction: directionServer, Transport: s.transport, Session: s.id}, line)
+
+	bodies := [][]byte{line}
+	if len(line) > 0 && line[0] == '[' {
+		var batch []json.RawMessage
+		if json.Unmarshal(line, &batch) == nil {
+			bodies = nil
+			for _, msg := range batch {
+				bodies = append(bodies, msg)
+			}
+		}
+	}
+	for i, body := range bodies {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err != nil {
+			b.logger.Printf("   [%s] not forwarded: the server wrote invalid JSON", shortSessionID(s.id))
+			return
+		}
+		m := jsonrpcMessage{}
+		if len(messages) > i {
+			m = messages[i]
+		}
+		if !s.deliver(m, compact.Bytes()) {
+			b.logger.Printf("   [%s] dropped %s: no stream is open to send it on", shortSessionID(s.id), describeGatewayMessages([]jsonrpcMessage{m}))
+		}
+	}
+}
+
+// stop closes the stdin of a session's server process, killing it if it
+// does not exit in time
+func (b *stdioBridge) stop(s *bridgeSession) {
+	s.stdinMu.Lock()
+	_ = s.stdin.Close()
+	s.stdinMu.Unlock()
+	select {
+	case <-s.done:
+	case <-time.After(serveStopTimeout):
+		_ = s.cmd.Process.Kill()
+		<-s.done
+	}
+}
+
+// stopAll stops the server processes of all sessions
+func (b *stdioBridge) stopAll() {
+	b.mu.Lock()
+	sessions := make([]*bridgeSession, 0, len(b.sessions))
+	for _, s := range b.sessions {
+		sessions = append(sessions, s)
+	}
+	b.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, s := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.stop(s)
+		}()
+	}
+	wg.Wait()
+}
+
+// session returns the session with an ID, or nil
+func (b *stdioBridge) session(id string) *bridgeSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sessions[id]
+}
+
+// inspect records and pretty-prints the messages of a body, as configured
+func (b *stdioBridge) inspect(rec sessionRecord, body []byte) {
+	if b.recorder != nil {
+		b.recorder.recordMessages(rec, body)
+	}
+	if b.tracer != nil {
+		b.tracer.traceMessages(rec, body)
+	}
+}
+
+// recordFor returns a session record of a message posted by a client
+func (b *stdioBridge) recordFor(r *http.Request, s *bridgeSession) sessionRecord {
+	return sessionRecord{
+		Direction:  directionClient,
+		Transport:  s.transport,
+		HTTPMethod: r.Method,
+		Path:       r.URL.Path,
+		Session:    s.id,
+	}
+}
+
+// bodyForLog formats a body for logging, truncating long bodies
+func (b *stdioBridge) bodyForLog(body []byte) string {
+	if !b.logBodies {
+		return ""
+	}
+	return formatLogBody(body)
+}
+
+// write sends a message to the server process as one line
+func (s *bridgeSession) write(body []byte) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		return fmt.Errorf("failed to compact message: %w", err)
+	}
+	line.WriteByte('\n')
+	s.stdinMu.Lock()
+	defer s.stdinMu.Unlock()
+	_, err := s.stdin.Write(line.Bytes())
+	return err
+}
+
+// begin registers a POST awaiting the responses to the given request IDs
+func (s *bridgeSession) begin(ids map[string]bool) *bridgeExchange {
+	ex := &bridgeExchange{ids: map[string]bool{}, out: make(chan []byte, serveQueueSize)}
+	for id := range ids {
+		ex.ids[id] = true
+	}
+	s.mu.Lock()
+	s.exchanges = append(s.exchanges, ex)
+	s.mu.Unlock()
+	return ex
+}
+
+// end removes a POST once it has its responses or its client went away
+func (s *bridgeSession) end(ex *bridgeExchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.exchanges {
+		if e == ex {
+			s.exchanges = append(s.exchanges[:i], s.exchanges[i+1:]...)
+			return
+		}
+	}
+}
+
+// listen opens the session's listener; there may be only one
+func (s *bridgeSession) listen() (chan []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return nil, false
+	}
+	s.listener = make(chan []byte, serveQueueSize)
+	return s.listener, true
+}
+
+// unlisten closes the session's listener
+func (s *bridgeSession) unlisten(out chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == out {
+		s.listener = nil
+	}
+}
+
+// deliver queues a message for the stream it belongs on. It reports false
+// if there is no such stream or the stream is not keeping up.
+func (s *bridgeSession) deliver(m jsonrpcMessage, msg []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out chan []byte
+	switch {
+	case s.transport == "sse":
+		out = s.listener
+	case m.Method == "" && len(m.ID) > 0:
+		for _, ex := range s.exchanges {
+			if ex.ids[string(m.ID)] {
+				out = ex.out
+				delete(ex.ids, string(m.ID))
+				break
+			}
+		}
+	case len(s.exchanges) > 0:
+		out = s.exchanges[len(s.exchanges)-1].out
+	default:
+		out = s.listener
+	}
+	if out == nil {
+		return false
+	}
+	select {
+	case out <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeBridgeJSON writes a JSON response
+func writeBridgeJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// shortSessionID abbreviates a session ID for the log
+func shortSessionID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// savedServers maps alias names to saved connection settings. Saved servers
+// use the same settings as config file profiles.
+type savedServers map[string]profileConfig
+
+// savedServersPath returns the file where saved server aliases are stored
+func savedServersPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "mcpprobe", "servers.yaml"), nil
+}
+
+// loadSavedServers reads the saved server aliases; a missing file means none are saved
+func loadSavedServers() (savedServers, error) {
+	path, err := savedServersPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return savedServers{}, nil
+		}
+		return nil, fmt.Errorf("failed to read saved servers: %w", err)
+	}
+
+	servers := savedServers{}
+	if err := yaml.Unmarshal(data, &servers); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return servers, nil
+}
+
+// save writes the saved server aliases. The file may contain credentials in
+// headers, so it is only readable by the current user.
+func (s savedServers) save() error {
+	path, err := savedServersPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved servers: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write saved servers: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the saved server with the given alias
+func (s savedServers) lookup(name string) (*profileConfig, error) {
+	server, ok := s[name]
+	if !ok {
+		return nil, fmt.Errorf("no saved server named '%s' (use 'server list' to see saved servers)", name)
+	}
+	return &server, nil
+}
+
+// names returns the sorted alias names
+func (s savedServers) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serverTarget describes where a saved server connects to
+func serverTarget(server profileConfig) (string, string) {
+	if server.Stdio != "" {
+		return server.Stdio, "stdio"
+	}
+	transportName := server.Transport
+	if transportName == "" {
+		transportName = "http"
+	}
+	return server.URL, transportName
+}
+
+// runServerCommand implements the 'server' subcommand for managing saved connections
+func runServerCommand(args []string) error {
+	if len(args) == 0 {
+		printServerUsage()
+		return fmt.Errorf("missing server command")
+	}
+
+	switch args[0] {
+	case "add":
+		return serverAdd(args[1:])
+	case "list", "ls":
+		return serverList()
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: server show <name>")
+		}
+		return serverShow(args[1])
+	case "remove", "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: server remove <name>")
+		}
+		return serverRemove(args[1])
+	case "help", "-h", "-help", "--help":
+		printServerUsage()
+		return nil
+	default:
+		printServerUsage()
+		return fmt.Errorf("unknown server command '%s'", args[0])
+	}
+}
+
+// printServerUsage prints help for the 'server' subcommand
+func printServerUsage() {
+	fmt.Println("Manage saved server connections:")
+	fmt.Println("  probe server add <name> <url> [-transport sse|http] [-headers 'key:value,...'] [-H 'Key: Value'] ...")
+	fmt.Println("  probe server add <name> -stdio ./my-server [-args 'arg1,arg2'] [-env 'KEY=VALUE,...']")
+	fmt.Println("  probe server list")
+	fmt.Println("  probe server show <name>")
+	fmt.Println("  probe server remove <name>")
+	fmt.Println("\nUse a saved server with: probe -server <name> [options]")
+}
+
+// serverAdd saves (or replaces) a server alias
+func serverAdd(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: server add <name> [url] [options]")
+	}
+	name := args[0]
+	args = args[1:]
+
+	var server profileConfig
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		server.URL = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("server add", flag.ContinueOnError)
+	transportName := fs.String("transport", "", "Transport mode: 'sse' or 'http'")
+	headers := fs.String("headers", "", "HTTP headers in format 'key1:value1,key2:value2'")
+	var headerList headerFlags
+	fs.Var(&headerList, "H", "HTTP header in format 'Key: Value' (repeatable)")
+	headersFile := fs.String("headers-file", "", "File with one 'Key: Value' header per line")
+	timeout := fs.String("timeout", "", "Connection timeout for initialization and listing")
+	callTimeout := fs.String("call-timeout", "", "Timeout for tool call execution")
+	acceptTimeout := fs.String("accept-timeout", "", "Time allowed to connect to the server, open event streams and answer initialization, pings and listings")
+	caCert := fs.String("ca-cert", "", "PEM file with CA certificates to trust")
+	insecure := fs.Bool("insecure", false, "Skip TLS certificate verification")
+	proxyURL := fs.String("proxy", "", "Proxy URL (http, https, socks5 or socks5h)")
+	stdioCmd := fs.String("stdio", "", "Path to MCP server executable (enables stdio transport)")
+	stdioArgs := fs.String("args", "", "Arguments to pass to the stdio server (comma-separated)")
+	stdioEnv := fs.String("env", "", "Environment variables for stdio server (KEY=VALUE,...)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	server.Transport = *transportName
+	server.Timeout = *timeout
+	server.CallTimeout = *callTimeout
+	server.AcceptTimeout = *acceptTimeout
+	if *caCert != "" {
+		// Saved servers are used from any directory
+		path, err := filepath.Abs(*caCert)
+		if err != nil {
+			return fmt.Errorf("invalid -ca-cert: %w", err)
+		}
+		server.CACert = path
+	}
+	server.Insecure = *insecure
+	if *proxyURL != "" {
+		if _, err := parseProxyURL(*proxyURL); err != nil {
+			return err
+		}
+		server.Proxy = *proxyURL
+	}
+	server.Stdio = *stdioCmd
+	var fileHeaders map[string]string
+	if *headersFile != "" {
+		var err error
+		if fileHeaders, err = readHeadersFile(*headersFile); err != nil {
+			return err
+		}
+	}
+	if h := mergeHeaders(fileHeaders, parseHeaders(*headers), headerList.toMap()); len(h) > 0 {
+		server.Headers = h
+	}
+	if *stdioArgs != "" {
+		for _, arg := range strings.Split(*stdioArgs, ",") {
+			server.Args = append(server.Args, strings.TrimSpace(arg))
+		}
+	}
+	if *stdioEnv != "" {
+		server.Env = make(map[string]string)
+		for _, pair := range strings.Split(*stdioEnv, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if key != "" {
+				server.Env[key] = value
+			}
+		}
+	}
+
+	if server.URL == "" && server.Stdio == "" {
+		return fmt.Errorf("a server URL or -stdio command is required")
+	}
+	if server.URL != "" && server.Stdio != "" {
+		return fmt.Errorf("specify either a URL or -stdio, not both")
+	}
+
+	servers, err := loadSavedServers()
+	if err != nil {
+		return err
+	}
+	_, replaced := servers[name]
+	servers[name] = server
+	if err := servers.save(); err != nil {
+		return err
+	}
+
+	if replaced {
+		fmt.Printf("Updated saved server '%s'\n", name)
+	} else {
+		fmt.Printf("Saved server '%s'\n", name)
+	}
+	return nil
+}
+
+// serverList prints all saved servers
+func serverList() error {
+	servers, err := loadSavedServers()
+	if err != nil {
+		return err
+	}
+	if len(servers) == 0 {
+		fmt.Println("No saved servers (add one with 'server add <name> <url>')")
+		return nil
+	}
+
+	names := servers.names()
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	for _, name := range names {
+		target, transportName := serverTarget(servers[name])
+		fmt.Printf("%-*s  %-5s  %s\n", width, name, transportName, target)
+	}
+	return nil
+}
+
+// serverShow prints the settings of a saved server
+func serverShow(name string) error {
+	servers, err := loadSavedServers()
+	if err != nil {
+		return err
+	}
+	server, err := servers.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	// Credentials are not shown; the file itself holds them
+	shown := *server
+	shown.Headers = redactHeaders(server.Headers)
+	if shown.Auth.BearerToken != "" {
+		shown.Auth.BearerToken = "<redacted>"
+	}
+	if shown.Auth.OAuth.ClientSecret != "" {
+		shown.Auth.OAuth.ClientSecret = "<redacted>"
+	}
+	data, err := yaml.Marshal(shown)
+	if err != nil {
+		return fmt.Errorf("failed to encode server: %w", err)
+	}
+	fmt.Printf("%s:\n", name)
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+	return nil
+}
+
+// serverRemove deletes a saved server
+func serverRemove(name string) error {
+	servers, err := loadSavedServers()
+	if err != nil {
+		return err
+	}
+	if _, err := servers.lookup(name); err != nil {
+		return err
+	}
+	delete(servers, name)
+	if err := servers.save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed saved server '%s'\n", name)
+	return nil
+}
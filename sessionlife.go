@@ -0,0 +1,244 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sessionRequestBase is the first JSON-RPC ID of the session lifecycle
+// test's requests
+const sessionRequestBase = 12000000
+
+// resumeSessionID is set by -session-id: streamable HTTP clients join this
+// session instead of initializing a new one
+var resumeSessionID string
+
+// sessionTestCommandArgs turns "session-test [flags]" into the equivalent
+// -session-test flag, so that the test uses the probe's usual connection
+// options
+func sessionTestCommandArgs(args []string) []string {
+	return append([]string{args[0], "-session-test"}, args[2:]...)
+}
+
+// runSessionLifecycle checks how a streamable HTTP server handles the
+// session it assigns: the session answers requests, a request without a
+// session ID is rejected with 400, one with an unknown session ID with 404,
+// the client can terminate the session with DELETE, and a request on the
+// terminated session is rejected with 404. It returns an error if a finding
+// counts as an error.
+func runSessionLifecycle(wire *httpNegativeWire, timeout time.Duration) error {
+	fmt.Println("=== Session Lifecycle ===")
+	defer wire.close()
+	if _, _, err := initializeNegativeWire(wire, timeout); err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	session := wire.session
+	if session == "" {
+		fmt.Println("The server did not assign a session ID; it is stateless, and there is no session lifecycle to test")
+		report.setChecks([]checkSummary{{ID: "session.assigned", Status: checkSkip, Runs: 1, Skipped: 1}})
+		return nil
+	}
+	report.setSessionID(session)
+	fmt.Printf("Session ID: %s\n\n", session)
+
+	var summaries []checkSummary
+	var failed []string
+	nextID := sessionRequestBase
+	record := func(id string, start time.Time, status, detail string, f *finding) {
+		summary := checkSummary{ID: id, Status: status, Runs: 1, AvgTime: time.Since(start)}
+		switch status {
+		case checkPass:
+			summary.Passed = 1
+		case checkSkip:
+			summary.Skipped = 1
+		default:
+			summary.Failed = 1
+			summary.Errors = []string{detail}
+		}
+		if f != nil && f.Suppressed {
+			summary.Suppressed = true
+		}
+		summaries = append(summaries, summary)
+		emitEvent(eventCheck, map[string]any{
+			"id":            id,
+			"status":        status,
+			"detail":        detail,
+			"avgDurationMs": durationMillis(summary.AvgTime),
+		})
+		fmt.Printf("  %-5s  %-28s %s\n", strings.ToUpper(status), id, detail)
+		if f != nil {
+			fmt.Printf("         %s\n", f)
+			if f.fails() {
+				failed = append(failed, id)
+			}
+		}
+	}
+	// expect pings the server with the given session ID, or none, and
+	// records whether it answered with one of the expected statuses. A ping
+	// that is accepted where it should be rejected is a C037 finding, one
+	// rejected with another status a C038 finding.
+	expect := func(id, label, sessionID string, want ...int) {
+		start := time.Now()
+		nextID++
+		status, err := sessionPing(wire, sessionID, nextID, timeout)
+		if err != nil {
+			record(id, start, checkFail, fmt.Sprintf("%s: %v", label, err), nil)
+			failed = append(failed, id)
+			return
+		}
+		detail := fmt.Sprintf("%s: HTTP %d", label, status)
+		for _, w := range want {
+			if status == w {
+				record(id, start, checkPass, detail, nil)
+				return
+			}
+		}
+		expected := make([]string, len(want))
+		for i, w := range want {
+			expected[i] = fmt.Sprint(w)
+		}
+		var f finding
+		if status < 300 {
+			f = report.addFinding(checkIDSessionAccepted, id, "%s was accepted (HTTP %d) instead of rejected with %s", label, status, strings.Join(expected, " or "))
+		} else {
+			f = report.addFinding(checkIDSessionStatus, id, "%s was rejected with HTTP %d instead of %s", label, status, strings.Join(expected, " or "))
+		}
+		record(id, start, checkFail, detail, &f)
+	}
+
+	// The session itself must work for the other checks to mean anything
+	start := time.Now()
+	nextID++
+	status, err := sessionPing(wire, session, nextID, timeout)
+	if err != nil || status != http.StatusOK {
+		detail := fmt.Sprintf("HTTP %d", status)
+		if err != nil {
+			detail = err.Error()
+		}
+		record("session.ping", start, checkFail, "ping on the session: "+detail, nil)
+		report.setChecks(summaries)
+		return fmt.Errorf("the session does not answer ping: %s", detail)
+	}
+	record("session.ping", start, checkPass, "ping on the session: HTTP 200", nil)
+
+	expect("session.missing", "request without a session ID", "", http.StatusBadRequest)
+	expect("session.unknown", "request with an unknown session ID", bogusSessionID(session), http.StatusNotFound, http.StatusBadRequest)
+
+	start = time.Now()
+	status, err = terminateSession(wire, session, timeout)
+	terminated := false
+	switch {
+	case err != nil:
+		record("session.delete", start, checkFail, fmt.Sprintf("DELETE: %v", err), nil)
+		failed = append(failed, "session.delete")
+	case status == http.StatusMethodNotAllowed:
+		record("session.delete", start, checkSkip, "DELETE: HTTP 405; the server does not let clients terminate sessions", nil)
+	case status >= 200 && status < 300:
+		record("session.delete", start, checkPass, fmt.Sprintf("DELETE: HTTP %d", status), nil)
+		terminated = true
+	default:
+		f := report.addFinding(checkIDSessionStatus, "session.delete", "DELETE of the session was answered with HTTP %d instead of a success or 405", status)
+		record("session.delete", start, checkFail, fmt.Sprintf("DELETE: HTTP %d", status), &f)
+	}
+	if terminated {
+		// The session is gone; the wire must not terminate it again
+		wire.session = ""
+		expect("session.terminated", "request on the terminated session", session, http.StatusNotFound)
+	} else {
+		record("session.terminated", time.Now(), checkSkip, "the session was not terminated", nil)
+	}
+	report.setChecks(summaries)
+
+	passed, skipped := countPassed(summaries), 0
+	for _, s := range summaries {
+		if s.Status == checkSkip {
+			skipped++
+		}
+	}
+	fmt.Printf("\n%d session checks: %d passed, %d failed, %d skipped\n", len(summaries), passed, len(summaries)-passed-skipped, skipped)
+	if len(failed) > 0 {
+		return fmt.Errorf("the server mishandles its sessions: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// sessionPing sends a ping with the given session ID, or without one, and
+// returns the HTTP status of the answer
+func sessionPing(wire *httpNegativeWire, sessionID string, id int, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": "ping"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode the request: %w", err)
+	}
+	// The request goes out on a copy of the wire, so that a session ID
+	// assigned in answer does not replace the test's own
+	probe := *wire
+	probe.session = sessionID
+	reply, err := probe.exchange(ctx, message)
+	if err != nil {
+		return 0, err
+	}
+	return reply.status, nil
+}
+
+// bogusSessionID returns a session ID of the same shape as the assigned one,
+// with its last hexadecimal digits changed, so that a server that only checks
+// the format of session IDs does not pass as one that knows its sessions
+func bogusSessionID(session string) string {
+	id := []byte(session)
+	for i, changed := len(id)-1, 0; i >= 0 && changed < 12; i-- {
+		switch c := id[i]; {
+		case c >= '0' && c <= '9':
+			id[i] = '0' + byte(rand.IntN(10))
+		case c >= 'a' && c <= 'f':
+			id[i] = 'a' + byte(rand.IntN(6))
+		case c >= 'A' && c <= 'F':
+			id[i] = 'A' + byte(rand.IntN(6))
+		default:
+			continue
+		}
+		changed++
+	}
+	if string(id) == session {
+		return fmt.Sprintf("mcpprobe-%016x", rand.Uint64())
+	}
+	return string(id)
+}
+
+// terminateSession sends the DELETE that ends a session and returns the
+// HTTP status of the answer
+func terminateSession(wire *httpNegativeWire, sessionID string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, wire.url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the request: %w", err)
+	}
+	for key, value := range wire.headers {
+		req.Header.Set(key, value)
+	}
+	if wire.oauth != nil {
+		if token, err := wire.oauth.TokenStore.GetToken(ctx); err == nil {
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		}
+	}
+	req.Header.Set(mcpSessionHeader, sessionID)
+	if wire.version != "" {
+		req.Header.Set("MCP-Protocol-Version", wire.version)
+	}
+	resp, err := wire.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
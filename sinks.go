@@ -0,0 +1,305 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sinkTimeout bounds uploads to remote output sinks
+const sinkTimeout = 60 * time.Second
+
+// outputSink is a destination a report can be written to
+type outputSink interface {
+	write(data []byte, contentType string) error
+	String() string
+}
+
+// sinkFlags collects repeatable -o destinations
+type sinkFlags []string
+
+func (s *sinkFlags) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *sinkFlags) Set(value string) error {
+	if _, err := parseSink(value); err != nil {
+		return err
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// parseSink returns the sink for a destination. Destinations are local file
+// paths, s3://bucket/key, gs://bucket/object or an http(s):// URL to POST to.
+func parseSink(dest string) (outputSink, error) {
+	scheme, rest, found := strings.Cut(dest, "://")
+	if !found {
+		if dest == "" {
+			return nil, fmt.Errorf("empty output destination")
+		}
+		return fileSink{path: dest}, nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "file":
+		return fileSink{path: rest}, nil
+	case "http", "https":
+		if _, err := url.Parse(dest); err != nil {
+			return nil, fmt.Errorf("invalid output URL '%s': %w", dest, err)
+		}
+		return httpSink{url: dest}, nil
+	case "s3", "gs":
+		bucket, key, _ := strings.Cut(rest, "/")
+		if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+			return nil, fmt.Errorf("invalid output destination '%s' (expected %s://bucket/key)", dest, scheme)
+		}
+		if scheme == "s3" {
+			return s3Sink{bucket: bucket, key: key}, nil
+		}
+		return gcsSink{bucket: bucket, object: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output destination '%s' (use a file path, s3://, gs:// or http(s)://)", dest)
+	}
+}
+
+// fileSink writes to a local file
+type fileSink struct {
+	path string
+}
+
+func (f fileSink) String() string { return f.path }
+
+func (f fileSink) write(data []byte, _ string) error {
+	if err := os.WriteFile(f.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// httpSink POSTs the report to an HTTP endpoint
+type httpSink struct {
+	url string
+}
+
+func (h httpSink) String() string { return h.url }
+
+func (h httpSink) write(data []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", ProgName+"/"+ProgVer)
+	return doSinkRequest(req)
+}
+
+// s3Sink uploads to Amazon S3 (or an S3-compatible store) using credentials
+// from the standard AWS environment variables
+type s3Sink struct {
+	bucket string
+	key    string
+}
+
+func (s s3Sink) String() string { return "s3://" + s.bucket + "/" + s.key }
+
+func (s s3Sink) write(data []byte, contentType string) error {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to write to S3")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// Use path-style addressing for custom endpoints (e.g. MinIO), otherwise
+	// virtual-hosted style against AWS
+	var target string
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL_S3"); endpoint != "" {
+		target = strings.TrimRight(endpoint, "/") + "/" + s.bucket + "/" + s3EscapePath(s.key)
+	} else {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, region, s3EscapePath(s.key))
+	}
+
+	req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	signS3Request(req, data, accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), region, time.Now().UTC())
+	return doSinkRequest(req)
+}
+
+// s3EscapePath URI-encodes each segment of a path the way Signature Version 4
+// expects: every byte except the unreserved characters A-Z, a-z, 0-9, '-',
+// '.', '_' and '~' is percent-encoded
+func s3EscapePath(path string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+// signS3Request adds AWS Signature Version 4 headers to an S3 request. The
+// request path is sent with the same encoding as the signed canonical URI.
+func signS3Request(req *http.Request, payload []byte, accessKey, secretKey, sessionToken, region string, now time.Time) {
+	canonicalURI := s3EscapePath(req.URL.Path)
+	req.URL.RawPath = canonicalURI
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	signed := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	if sessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// sha256Hex returns the hex encoded SHA-256 hash of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// gcsSink uploads to Google Cloud Storage. The access token is taken from
+// GOOGLE_OAUTH_ACCESS_TOKEN, or from the gcloud CLI if that is not set.
+type gcsSink struct {
+	bucket string
+	object string
+}
+
+func (g gcsSink) String() string { return "gs://" + g.bucket + "/" + g.object }
+
+func (g gcsSink) write(data []byte, contentType string) error {
+	token, err := gcsAccessToken()
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(g.bucket), url.QueryEscape(g.object))
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return doSinkRequest(req)
+}
+
+// gcsAccessToken returns an OAuth access token for Google Cloud Storage
+func gcsAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("set GOOGLE_OAUTH_ACCESS_TOKEN or install and log in to gcloud to write to GCS: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// doSinkRequest sends an upload request and checks the response status
+func doSinkRequest(req *http.Request) error {
+	httpClient := &http.Client{Timeout: sinkTimeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload to %s failed: %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// reportContentType returns the MIME type for a report format
+func reportContentType(format string) string {
+	switch format {
+	case reportHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
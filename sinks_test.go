@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestS3EscapePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"reports/run.json", "reports/run.json"},
+		{"run:2026-10-16.json", "run%3A2026-10-16.json"},
+		{"a b+c", "a%20b%2Bc"},
+		{"!$&'()*,;=@", "%21%24%26%27%28%29%2A%2C%3B%3D%40"},
+		{"unreserved-._~", "unreserved-._~"},
+		{"é", "%C3%A9"},
+	}
+	for _, tt := range tests {
+		if got := s3EscapePath(tt.path); got != tt.want {
+			t.Errorf("s3EscapePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSignS3Request(t *testing.T) {
+	payload := []byte("hello")
+	req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:9000/bucket/runs/run:2026-10-16 (1).json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	signS3Request(req, payload, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "", "us-east-1", now)
+
+	// The wire path must use the encoding that was signed
+	if got, want := req.URL.EscapedPath(), "/bucket/runs/run%3A2026-10-16%20%281%29.json"; got != want {
+		t.Errorf("escaped path = %q, want %q", got, want)
+	}
+	if got, want := req.Header.Get("X-Amz-Date"), "20261016T120000Z"; got != want {
+		t.Errorf("X-Amz-Date = %q, want %q", got, want)
+	}
+	if got, want := req.Header.Get("X-Amz-Content-Sha256"), sha256Hex(payload); got != want {
+		t.Errorf("X-Amz-Content-Sha256 = %q, want %q", got, want)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261016/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=67be21a60d1704a84983d396c52658c621e6e7ae2f61c5632bfb2537e86ac7a9"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+}
+
+func TestSignS3RequestSessionToken(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "https://bucket.s3.eu-west-1.amazonaws.com/key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signS3Request(req, nil, "AKID", "secret", "session", "eu-west-1", time.Now().UTC())
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session" {
+		t.Errorf("X-Amz-Security-Token = %q, want %q", got, "session")
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("session token is not signed: %s", auth)
+	}
+}
@@ -0,0 +1,466 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// reconnectAttempts is how often a lost SSE stream is opened again before
+// the session is given up
+const reconnectAttempts = 3
+
+// reconnectTestCycles is how often the reconnection test closes the stream
+const reconnectTestCycles = 3
+
+// reconnectTestCalls is the number of calls made on each reopened stream
+const reconnectTestCalls = 3
+
+// sseReconnect is a lost SSE stream and how it was reopened
+type sseReconnect struct {
+	At       time.Time     `json:"at"`
+	Cause    string        `json:"cause"`
+	Endpoint string        `json:"endpoint,omitempty"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"durationNs"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// reconnectingSSE is an SSE transport that opens a new stream when the
+// current one is lost. An SSE session is tied to its stream, so the new
+// stream is a new session: the endpoint is received again and the
+// initialize handshake is repeated before requests continue. Requests in
+// flight when the stream is lost fail; requests made while the stream is
+// being reopened wait for it.
+type reconnectingSSE struct {
+	// connect opens and starts a new stream, which lasts as long as ctx
+	connect func(ctx context.Context) (*transport.SSE, error)
+	// timeout limits the repeated initialize handshake
+	timeout time.Duration
+	// onReconnect is told about each lost stream, reopened or not
+	onReconnect func(sseReconnect)
+
+	mu      sync.Mutex
+	ctx     context.Context
+	current *transport.SSE
+	// ready is closed while current can be used, and replaced while the
+	// stream is reopened
+	ready  chan struct{}
+	failed error
+	closed bool
+
+	onNotification  func(mcp.JSONRPCNotification)
+	protocolVersion string
+	// initRequest and initialized record the handshake, to repeat it
+	initRequest *transport.JSONRPCRequest
+	initialized bool
+}
+
+// newReconnectingSSE returns a transport that reopens its stream with
+// connect when the stream is lost
+func newReconnectingSSE(connect func(ctx context.Context) (*transport.SSE, error), timeout time.Duration, onReconnect func(sseReconnect)) *reconnectingSSE {
+	ready := make(chan struct{})
+	close(ready)
+	return &reconnectingSSE{connect: connect, timeout: timeout, onReconnect: onReconnect, ready: ready}
+}
+
+// Start opens the first stream. Streams opened later last as long as ctx.
+func (t *reconnectingSSE) Start(ctx context.Context) error {
+	sse, err := t.connect(ctx)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.ctx = ctx
+	t.current = sse
+	t.mu.Unlock()
+	t.attach(sse)
+	return nil
+}
+
+// attach routes the stream's notifications to the client and watches for
+// its loss
+func (t *reconnectingSSE) attach(sse *transport.SSE) {
+	sse.SetNotificationHandler(func(notification mcp.JSONRPCNotification) {
+		t.mu.Lock()
+		handler := t.onNotification
+		t.mu.Unlock()
+		if handler != nil {
+			handler(notification)
+		}
+	})
+	sse.SetConnectionLostHandler(func(err error) { t.lost(sse, err) })
+}
+
+// stream returns the current stream
+func (t *reconnectingSSE) stream() *transport.SSE {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
+
+// session waits until a stream can be used and returns it
+func (t *reconnectingSSE) session(ctx context.Context) (*transport.SSE, error) {
+	t.mu.Lock()
+	ready := t.ready
+	t.mu.Unlock()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to wait for the SSE stream to reopen: %w", ctx.Err())
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed != nil {
+		return nil, t.failed
+	}
+	return t.current, nil
+}
+
+// lost starts reopening the stream, unless the transport was closed or the
+// stream was already replaced
+func (t *reconnectingSSE) lost(sse *transport.SSE, cause error) {
+	t.mu.Lock()
+	if t.closed || sse != t.current {
+		t.mu.Unlock()
+		return
+	}
+	t.ready = make(chan struct{})
+	t.mu.Unlock()
+
+	// Closing the old stream fails the requests waiting for answers on it
+	_ = sse.Close()
+	go t.reopen(cause)
+}
+
+// reopen opens a new stream and repeats the handshake on it, backing off
+// between attempts
+func (t *reconnectingSSE) reopen(cause error) {
+	start := time.Now()
+	event := sseReconnect{At: start, Cause: strings.TrimSpace(cause.Error())}
+	var err error
+	for event.Attempts = 1; event.Attempts <= reconnectAttempts; event.Attempts++ {
+		if event.Attempts > 1 {
+			time.Sleep(time.Duration(event.Attempts-1) * time.Second)
+		}
+		var sse *transport.SSE
+		if sse, err = t.resume(); err == nil {
+			event.Endpoint = sse.GetEndpoint().String()
+			event.Duration = time.Since(start)
+			t.mu.Lock()
+			if t.closed {
+				t.mu.Unlock()
+				_ = sse.Close()
+				return
+			}
+			t.current = sse
+			close(t.ready)
+			t.mu.Unlock()
+			t.onReconnect(event)
+			return
+		}
+		t.mu.Lock()
+		closed := t.closed
+		t.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+
+	event.Attempts = reconnectAttempts
+	event.Duration = time.Since(start)
+	event.Error = err.Error()
+	t.mu.Lock()
+	t.failed = fmt.Errorf("the SSE stream was lost and could not be reopened: %w", err)
+	close(t.ready)
+	t.mu.Unlock()
+	t.onReconnect(event)
+}
+
+// resume opens a new stream and repeats the initialize handshake on it
+func (t *reconnectingSSE) resume() (*transport.SSE, error) {
+	t.mu.Lock()
+	ctx, version, init, initialized := t.ctx, t.protocolVersion, t.initRequest, t.initialized
+	t.mu.Unlock()
+
+	sse, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		sse.SetProtocolVersion(version)
+	}
+	t.attach(sse)
+	if init == nil {
+		return sse, nil
+	}
+
+	initCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	response, err := sse.SendRequest(initCtx, *init)
+	if err == nil && response.Error != nil {
+		err = errors.New(response.Error.Message)
+	}
+	if err == nil && initialized {
+		err = sse.SendNotification(initCtx, mcp.JSONRPCNotification{
+			JSONRPC:      mcp.JSONRPC_VERSION,
+			Notification: mcp.Notification{Method: "notifications/initialized"},
+		})
+	}
+	if err != nil {
+		_ = sse.Close()
+		return nil, fmt.Errorf("failed to initialize the reopened stream: %w", err)
+	}
+	return sse, nil
+}
+
+func (t *reconnectingSSE) SendRequest(ctx context.Context, request transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	sse, err := t.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.Method == string(mcp.MethodInitialize) {
+		t.mu.Lock()
+		t.initRequest = &request
+		t.mu.Unlock()
+	}
+	return sse.SendRequest(ctx, request)
+}
+
+func (t *reconnectingSSE) SendNotification(ctx context.Context, notification mcp.JSONRPCNotification) error {
+	sse, err := t.session(ctx)
+	if err != nil {
+		return err
+	}
+	if notification.Method == "notifications/initialized" {
+		t.mu.Lock()
+		t.initialized = true
+		t.mu.Unlock()
+	}
+	return sse.SendNotification(ctx, notification)
+}
+
+func (t *reconnectingSSE) SetNotificationHandler(handler func(notification mcp.JSONRPCNotification)) {
+	t.mu.Lock()
+	t.onNotification = handler
+	t.mu.Unlock()
+}
+
+// SetProtocolVersion passes the negotiated version to the current stream and
+// to the streams opened after it
+func (t *reconnectingSSE) SetProtocolVersion(version string) {
+	t.mu.Lock()
+	t.protocolVersion = version
+	sse := t.current
+	t.mu.Unlock()
+	if sse != nil {
+		sse.SetProtocolVersion(version)
+	}
+}
+
+func (t *reconnectingSSE) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	sse := t.current
+	t.mu.Unlock()
+	if sse == nil {
+		return nil
+	}
+	return sse.Close()
+}
+
+// GetSessionId is empty, as for SSE: the session is identified by the
+// endpoint
+func (t *reconnectingSSE) GetSessionId() string {
+	return ""
+}
+
+// printReconnect reports a lost SSE stream of an interactive session
+func printReconnect(event sseReconnect) {
+	report.addReconnect(event)
+	if event.Error != "" {
+		fmt.Printf("\n[%s] Error: the SSE stream was lost (%s) and could not be reopened after %d attempt%s: %s\n",
+			consoleClock(time.Now()), event.Cause, event.Attempts, pluralS(event.Attempts), event.Error)
+		return
+	}
+	fmt.Printf("\n[%s] The SSE stream was lost (%s) and reopened in %s; new endpoint %s\n",
+		consoleClock(time.Now()), event.Cause, humanDuration(event.Duration), event.Endpoint)
+}
+
+// reconnectTestCommandArgs turns "reconnect-test [flags]" into the equivalent
+// -reconnect-test flag, so that the test uses the probe's usual connection
+// options
+func reconnectTestCommandArgs(args []string) []string {
+	return append([]string{args[0], "-reconnect-test"}, args[2:]...)
+}
+
+// reconnectCycle is a cycle of the reconnection test
+type reconnectCycle struct {
+	Cycle      int           `json:"cycle"`
+	Dropped    int           `json:"droppedConnections"`
+	Endpoint   string        `json:"endpoint,omitempty"`
+	NewSession bool          `json:"newSession"`
+	Reconnect  time.Duration `json:"reconnectNs,omitempty"`
+	Calls      int           `json:"calls"`
+	Succeeded  int           `json:"succeeded"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// reconnectReport is the result of the reconnection test
+type reconnectReport struct {
+	Operation string           `json:"operation"`
+	Cycles    []reconnectCycle `json:"cycles"`
+	Reconnect *latencyStats    `json:"reconnect,omitempty"`
+}
+
+// runReconnectTest opens an SSE session, then repeatedly closes its stream
+// and checks that the client reopens it, receives the endpoint again and
+// can still make calls. The reconnect time runs from closing the stream
+// until the new session is initialized. It returns an error if a stream was
+// not reopened or calls failed on it.
+func runReconnectTest(connect func(ctx context.Context) (*transport.SSE, error), tool string, args map[string]any, timeout, callTimeout time.Duration) error {
+	operation := string(mcp.MethodToolsList)
+	if tool != "" {
+		operation = string(mcp.MethodToolsCall) + " " + tool
+	}
+	fmt.Printf("=== SSE Reconnection: %s ===\n", operation)
+
+	// The streams last as long as the run, not the handshake
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	reconnects := make(chan sseReconnect, 1)
+	sse := newReconnectingSSE(connect, timeout, func(event sseReconnect) { reconnects <- event })
+	mcpClient := client.NewClient(sse)
+	defer func() { _ = mcpClient.Close() }()
+	if err := mcpClient.Start(runCtx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	_, err := mcpClient.Initialize(ctx, newInitializeRequest())
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	if tool != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, resolved, err := resolveToolName(ctx, mcpClient, tool, false)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if resolved != nil && isDestructive(resolved) {
+			if !stdinIsTerminal() {
+				return fmt.Errorf("'%s' is flagged as destructive; calling it repeatedly needs confirmation on a terminal", resolved.Name)
+			}
+			if !confirmDestructiveCall(resolved, stdinScanner()) {
+				return nil
+			}
+		}
+	}
+	if outcome, detail, _ := chaosCall(mcpClient, tool, args, callTimeout); outcome != chaosCompleted {
+		return fmt.Errorf("%s fails before the stream is closed: %s", operation, detail)
+	}
+	endpoint := sse.stream().GetEndpoint().String()
+	fmt.Printf("Endpoint: %s\n\n", endpoint)
+
+	result := &reconnectReport{Operation: operation}
+	var failed []string
+	var durations []time.Duration
+	finding := func(cycle int, format string, v ...any) {
+		subject := fmt.Sprintf("cycle %d", cycle)
+		f := report.addFinding(checkIDSSEReconnect, subject, format, v...)
+		fmt.Printf("        %s\n", f)
+		if f.fails() {
+			failed = append(failed, subject)
+		}
+	}
+
+	for i := 1; i <= reconnectTestCycles; i++ {
+		cycle := reconnectCycle{Cycle: i}
+		dropped := time.Now()
+		cycle.Dropped = chaosConns.drop()
+
+		var event sseReconnect
+		timer := time.NewTimer(timeout + reconnectAttempts*(timeout+time.Second))
+		select {
+		case event = <-reconnects:
+			timer.Stop()
+		case <-timer.C:
+			event.Error = fmt.Sprintf("the stream was not reopened within %s", humanDuration(time.Since(dropped)))
+		}
+		if event.Error != "" {
+			cycle.Error = event.Error
+			result.Cycles = append(result.Cycles, cycle)
+			fmt.Printf("  %d  %d connection%s closed, stream not reopened\n", i, cycle.Dropped, pluralS(cycle.Dropped))
+			finding(i, "the SSE stream was not reopened after it was closed: %s", event.Error)
+			emitEvent(eventCheck, map[string]any{"id": fmt.Sprintf("reconnect.%d", i), "status": "failed", "detail": event.Error})
+			break
+		}
+		cycle.Reconnect = time.Since(dropped)
+		cycle.Endpoint = event.Endpoint
+		cycle.NewSession = event.Endpoint != endpoint
+		endpoint = event.Endpoint
+		durations = append(durations, cycle.Reconnect)
+
+		var errs []string
+		for range reconnectTestCalls {
+			cycle.Calls++
+			outcome, detail, _ := chaosCall(mcpClient, tool, args, callTimeout)
+			if outcome == chaosCompleted {
+				cycle.Succeeded++
+			} else {
+				errs = append(errs, detail)
+			}
+		}
+		result.Cycles = append(result.Cycles, cycle)
+
+		session := "new session"
+		if !cycle.NewSession {
+			session = "same endpoint"
+		}
+		fmt.Printf("  %d  %d connection%s closed, reopened in %s (%s, attempt %d), %d of %d calls succeeded\n",
+			i, cycle.Dropped, pluralS(cycle.Dropped), humanDuration(cycle.Reconnect), session, event.Attempts, cycle.Succeeded, cycle.Calls)
+		fmt.Printf("     Endpoint: %s\n", cycle.Endpoint)
+		if len(errs) > 0 {
+			cycle.Error = errs[0]
+			result.Cycles[len(result.Cycles)-1] = cycle
+			finding(i, "%d of %d calls (%s) failed after the SSE stream was reopened: %s", len(errs), cycle.Calls, operation, errs[0])
+		}
+		status := "passed"
+		if len(errs) > 0 {
+			status = "failed"
+		}
+		emitEvent(eventCheck, map[string]any{
+			"id":          fmt.Sprintf("reconnect.%d", i),
+			"status":      status,
+			"detail":      cycle.Error,
+			"endpoint":    cycle.Endpoint,
+			"reconnectMs": durationMillis(cycle.Reconnect),
+		})
+	}
+	result.Reconnect = newLatencyStats(durations)
+	report.setReconnectTest(result)
+
+	fmt.Println("\n=== SSE Reconnection Results ===")
+	fmt.Printf("%d of %d stream%s reopened\n", len(durations), reconnectTestCycles, pluralS(reconnectTestCycles))
+	if result.Reconnect != nil {
+		fmt.Printf("  Reconnect:  min %s, avg %s, max %s\n", humanDuration(result.Reconnect.Min), humanDuration(result.Reconnect.Mean), humanDuration(result.Reconnect.Max))
+	}
+	if len(failed) > 0 {
+		fmt.Println("Result: NOT RECOVERED")
+		return fmt.Errorf("the client did not recover from a closed SSE stream in %d of %d cycle%s: %s", len(failed), reconnectTestCycles, pluralS(reconnectTestCycles), strings.Join(failed, ", "))
+	}
+	fmt.Println("Result: RECOVERED")
+	return nil
+}
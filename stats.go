@@ -0,0 +1,223 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// latencySummary summarizes a set of response times
+type latencySummary struct {
+	Min  time.Duration `json:"minNs"`
+	Mean time.Duration `json:"meanNs"`
+	P50  time.Duration `json:"p50Ns"`
+	P95  time.Duration `json:"p95Ns"`
+	Max  time.Duration `json:"maxNs"`
+}
+
+// toolUsage is the recorded usage of one tool
+type toolUsage struct {
+	Name       string          `json:"name"`
+	Calls      int             `json:"calls"`
+	Errors     int             `json:"errors"`
+	ErrorRate  float64         `json:"errorRate"`
+	Unanswered int             `json:"unanswered,omitempty"`
+	Latency    *latencySummary `json:"latency,omitempty"`
+	latencies  []time.Duration
+}
+
+// usageStats is the tool usage aggregated from an audit log
+type usageStats struct {
+	AuditLog   string         `json:"auditLog"`
+	Records    int            `json:"records"`
+	Sessions   int            `json:"sessions"`
+	ToolCalls  int            `json:"toolCalls"`
+	Tools      []*toolUsage   `json:"tools"`
+	NeverUsed  []string       `json:"neverUsed"`
+	OtherCalls map[string]int `json:"otherMethods"`
+}
+
+// runStatsCommand implements the 'stats' subcommand
+func runStatsCommand(args []string) error {
+	var path string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	auditLog := fs.String("audit-log", path, "Session recording (JSON Lines) to aggregate, e.g. from 'proxy -record'")
+	format := fs.String("output", outputText, "Output format: 'text' or 'json'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *auditLog == "" && fs.NArg() > 0 {
+		*auditLog = fs.Arg(0)
+	}
+	if *auditLog == "" {
+		return fmt.Errorf("usage: stats -audit-log <session.jsonl> [-output text|json]")
+	}
+	if *format != outputText && *format != outputJSON {
+		return fmt.Errorf("unsupported output format '%s' (use 'text' or 'json')", *format)
+	}
+
+	records, err := readSessionRecording(*auditLog)
+	if err != nil {
+		return err
+	}
+	stats := aggregateToolUsage(*auditLog, records)
+
+	if *format == outputJSON {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode stats: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printToolUsage(stats)
+	return nil
+}
+
+// aggregateToolUsage pairs the recorded tools/call requests with their
+// responses and aggregates them per tool. Tools listed by the server in a
+// tools/list response but never called are reported as never used.
+func aggregateToolUsage(path string, records []sessionRecord) *usageStats {
+	stats := &usageStats{AuditLog: path, Records: len(records), OtherCalls: map[string]int{}}
+	usage := map[string]*toolUsage{}
+	listed := map[string]bool{}
+
+	exchanges, sessions := pairRecordedExchanges(records)
+	for _, ex := range exchanges {
+		switch ex.Method {
+		case "tools/call":
+			var params struct {
+				Name string `json:"name"`
+			}
+			_ = json.Unmarshal(ex.Params, &params)
+			u := usage[params.Name]
+			if u == nil {
+				u = &toolUsage{Name: params.Name}
+				usage[params.Name] = u
+			}
+			u.Calls++
+			stats.ToolCalls++
+			if !ex.Answered {
+				continue
+			}
+			u.latencies = append(u.latencies, ex.Received.Sub(ex.Sent))
+			var result struct {
+				IsError bool `json:"isError"`
+			}
+			_ = json.Unmarshal(ex.Result, &result)
+			if len(ex.Error) > 0 || result.IsError {
+				u.Errors++
+			}
+		case "tools/list":
+			stats.OtherCalls[ex.Method]++
+			for _, tool := range listedTools(ex) {
+				listed[tool.Name] = true
+			}
+		default:
+			stats.OtherCalls[ex.Method]++
+		}
+	}
+
+	for _, u := range usage {
+		u.Unanswered = u.Calls - len(u.latencies)
+		u.ErrorRate = float64(u.Errors) / float64(u.Calls)
+		u.Latency = summarizeLatencies(u.latencies)
+		stats.Tools = append(stats.Tools, u)
+	}
+	sort.Slice(stats.Tools, func(i, j int) bool {
+		a, b := stats.Tools[i], stats.Tools[j]
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		return a.Name < b.Name
+	})
+	stats.NeverUsed = []string{}
+	for name := range listed {
+		if usage[name] == nil {
+			stats.NeverUsed = append(stats.NeverUsed, name)
+		}
+	}
+	sort.Strings(stats.NeverUsed)
+	stats.Sessions = sessions
+	return stats
+}
+
+// summarizeLatencies returns the latency summary of a set of response times,
+// or nil if there are none
+func summarizeLatencies(latencies []time.Duration) *latencySummary {
+	if len(latencies) == 0 {
+		return nil
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	n := len(sorted)
+	return &latencySummary{
+		Min:  sorted[0],
+		Mean: total / time.Duration(n),
+		P50:  sorted[(n-1)/2],
+		P95:  sorted[int(float64(n-1)*0.95)],
+		Max:  sorted[n-1],
+	}
+}
+
+// printToolUsage prints the aggregated tool usage as a table
+func printToolUsage(stats *usageStats) {
+	fmt.Printf("=== Tool Usage: %s ===\n", stats.AuditLog)
+	fmt.Printf("Records: %s", humanCount(stats.Records))
+	if stats.Sessions > 0 {
+		fmt.Printf(" (%d session(s))", stats.Sessions)
+	}
+	fmt.Printf(", tool calls: %s\n\n", humanCount(stats.ToolCalls))
+
+	if len(stats.Tools) == 0 {
+		fmt.Println("No tool calls recorded")
+	} else {
+		width := len("Tool")
+		for _, u := range stats.Tools {
+			width = max(width, len(u.Name))
+		}
+		fmt.Printf("%-*s  %6s  %6s  %6s  %9s  %9s  %9s  %9s  %9s\n", width, "Tool", "Calls", "Errors", "Rate", "Min", "Mean", "P50", "P95", "Max")
+		for _, u := range stats.Tools {
+			fmt.Printf("%-*s  %6d  %6d  %5.1f%%", width, u.Name, u.Calls, u.Errors, u.ErrorRate*100)
+			if l := u.Latency; l != nil {
+				for _, d := range []time.Duration{l.Min, l.Mean, l.P50, l.P95, l.Max} {
+					fmt.Printf("  %9s", humanDuration(d))
+				}
+			}
+			if u.Unanswered > 0 {
+				fmt.Printf("  (%d unanswered)", u.Unanswered)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(stats.NeverUsed) > 0 {
+		fmt.Printf("\nNever called (%d listed by the server): %s\n", len(stats.NeverUsed), strings.Join(stats.NeverUsed, ", "))
+	}
+	if len(stats.OtherCalls) > 0 {
+		methods := make([]string, 0, len(stats.OtherCalls))
+		for method := range stats.OtherCalls {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for i, method := range methods {
+			methods[i] = fmt.Sprintf("%s %d", method, stats.OtherCalls[method])
+		}
+		fmt.Printf("\nOther requests: %s\n", strings.Join(methods, ", "))
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// isolateFromTerminalSignals does nothing where process groups are not
+// available
+func isolateFromTerminalSignals(cmd *exec.Cmd) {}
@@ -0,0 +1,18 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolateFromTerminalSignals starts a stdio server in its own process group,
+// so that Ctrl-C in the terminal reaches only the probe, which can then
+// cancel the running request instead of losing the server
+func isolateFromTerminalSignals(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,470 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxStrictViolations limits the violations reported for one response, so a
+// listing with the same mistake in every item stays readable
+const maxStrictViolations = 10
+
+// shape is the part of the MCP schema that -strict checks for a value: its
+// JSON type, required fields, the shapes of known fields and of array items,
+// allowed values, and the protocol feature that introduced it. An empty kind
+// accepts any JSON type. Fields the
+// schema does not list are allowed, as the schema allows them.
+type shape struct {
+	kind     string
+	required []string
+	fields   map[string]*shape
+	items    *shape
+	enum     []string
+	oneOf    []string
+	feature  string
+	content  bool
+}
+
+// Shapes of the JSON types
+var (
+	stringVal = &shape{kind: "string"}
+	intVal    = &shape{kind: "integer"}
+	boolVal   = &shape{kind: "boolean"}
+	objectVal = &shape{kind: "object"}
+)
+
+// object returns the shape of an object with the given fields
+func object(fields map[string]*shape, required ...string) *shape {
+	return &shape{kind: "object", fields: fields, required: required}
+}
+
+// arrayOf returns the shape of an array of items
+func arrayOf(items *shape) *shape {
+	return &shape{kind: "array", items: items}
+}
+
+// since returns a copy of s that belongs to a protocol feature
+func since(feature string, s *shape) *shape {
+	c := *s
+	c.feature = feature
+	return &c
+}
+
+// contentBlock is a content block, checked by its type
+var contentBlock = &shape{kind: "object", content: true}
+
+// resourceContents are the contents of a resource: text or a blob
+var resourceContents = &shape{
+	kind:     "object",
+	required: []string{"uri"},
+	oneOf:    []string{"text", "blob"},
+	fields:   map[string]*shape{"uri": stringVal, "mimeType": stringVal, "text": stringVal, "blob": stringVal},
+}
+
+// contentTypes are the shapes of the content block types
+var contentTypes = map[string]*shape{
+	"text":     object(map[string]*shape{"text": stringVal}, "text"),
+	"image":    object(map[string]*shape{"data": stringVal, "mimeType": stringVal}, "data", "mimeType"),
+	"audio":    since(featureAudioContent, object(map[string]*shape{"data": stringVal, "mimeType": stringVal}, "data", "mimeType")),
+	"resource": object(map[string]*shape{"resource": resourceContents}, "resource"),
+	"resource_link": since(featureResourceLinks, object(map[string]*shape{
+		"uri": stringVal, "name": stringVal, "title": since(featureTitles, stringVal), "mimeType": stringVal, "description": stringVal,
+	}, "uri", "name")),
+}
+
+// icons are the icons of an implementation, tool, resource or prompt
+var icons = since(featureIcons, arrayOf(object(map[string]*shape{"src": stringVal, "mimeType": stringVal}, "src")))
+
+// resultShapes are the shapes of the results of the methods -strict checks
+var resultShapes = map[mcp.MCPMethod]*shape{
+	mcp.MethodInitialize: object(map[string]*shape{
+		"protocolVersion": stringVal,
+		"capabilities": object(map[string]*shape{
+			"tools":        object(map[string]*shape{"listChanged": boolVal}),
+			"resources":    object(map[string]*shape{"subscribe": boolVal, "listChanged": boolVal}),
+			"prompts":      object(map[string]*shape{"listChanged": boolVal}),
+			"logging":      objectVal,
+			"completions":  since(featureCompletions, objectVal),
+			"experimental": objectVal,
+		}),
+		"serverInfo": object(map[string]*shape{
+			"name": stringVal, "version": stringVal, "title": since(featureTitles, stringVal), "icons": icons,
+		}, "name", "version"),
+		"instructions": stringVal,
+	}, "protocolVersion", "capabilities", "serverInfo"),
+	mcp.MethodPing: objectVal,
+	mcp.MethodToolsList: object(map[string]*shape{
+		"tools": arrayOf(object(map[string]*shape{
+			"name":         stringVal,
+			"title":        since(featureTitles, stringVal),
+			"description":  stringVal,
+			"inputSchema":  &shape{kind: "object", required: []string{"type"}, fields: map[string]*shape{"type": {kind: "string", enum: []string{"object"}}}},
+			"outputSchema": since(featureStructuredOutput, &shape{kind: "object", required: []string{"type"}, fields: map[string]*shape{"type": {kind: "string", enum: []string{"object"}}}}),
+			"annotations": since(featureToolAnnotations, object(map[string]*shape{
+				"title": stringVal, "readOnlyHint": boolVal, "destructiveHint": boolVal, "idempotentHint": boolVal, "openWorldHint": boolVal,
+			})),
+			"icons": icons,
+		}, "name", "inputSchema")),
+		"nextCursor": stringVal,
+	}, "tools"),
+	mcp.MethodToolsCall: object(map[string]*shape{
+		"content":           arrayOf(contentBlock),
+		"structuredContent": since(featureStructuredOutput, objectVal),
+		"isError":           boolVal,
+	}, "content"),
+	mcp.MethodResourcesList: object(map[string]*shape{
+		"resources": arrayOf(object(map[string]*shape{
+			"uri": stringVal, "name": stringVal, "title": since(featureTitles, stringVal), "description": stringVal,
+			"mimeType": stringVal, "size": intVal, "icons": icons,
+		}, "uri", "name")),
+		"nextCursor": stringVal,
+	}, "resources"),
+	mcp.MethodResourcesTemplatesList: object(map[string]*shape{
+		"resourceTemplates": arrayOf(object(map[string]*shape{
+			"uriTemplate": stringVal, "name": stringVal, "title": since(featureTitles, stringVal), "description": stringVal,
+			"mimeType": stringVal, "icons": icons,
+		}, "uriTemplate", "name")),
+		"nextCursor": stringVal,
+	}, "resourceTemplates"),
+	mcp.MethodResourcesRead: object(map[string]*shape{
+		"contents": arrayOf(resourceContents),
+	}, "contents"),
+	mcp.MethodPromptsList: object(map[string]*shape{
+		"prompts": arrayOf(object(map[string]*shape{
+			"name": stringVal, "title": since(featureTitles, stringVal), "description": stringVal,
+			"arguments": arrayOf(object(map[string]*shape{
+				"name": stringVal, "title": since(featureTitles, stringVal), "description": stringVal, "required": boolVal,
+			}, "name")),
+			"icons": icons,
+		}, "name")),
+		"nextCursor": stringVal,
+	}, "prompts"),
+	mcp.MethodPromptsGet: object(map[string]*shape{
+		"description": stringVal,
+		"messages": arrayOf(object(map[string]*shape{
+			"role":    {kind: "string", enum: []string{"user", "assistant"}},
+			"content": contentBlock,
+		}, "role", "content")),
+	}, "messages"),
+	mcp.MethodCompletionComplete: object(map[string]*shape{
+		"completion": object(map[string]*shape{
+			"values": arrayOf(stringVal), "total": intVal, "hasMore": boolVal,
+		}, "values"),
+	}, "completion"),
+	mcp.MethodSetLogLevel:   objectVal,
+	"resources/subscribe":   objectVal,
+	"resources/unsubscribe": objectVal,
+}
+
+// strictTransport checks every response the session receives against the
+// MCP schema of the negotiated protocol version, and reports violations as
+// findings with the JSON pointer of the offending value
+type strictTransport struct {
+	transport.Interface
+
+	mu sync.Mutex
+	// version is the negotiated protocol version, once initialized
+	version string
+	// reported are the violations already reported, which are not repeated
+	reported map[string]bool
+}
+
+// enableStrict returns a client for the same connection whose responses are
+// checked by -strict. It must be called before the client is started and
+// before any handlers are set.
+func enableStrict(mcpClient *client.Client) *client.Client {
+	strict := &strictTransport{Interface: mcpClient.GetTransport(), reported: map[string]bool{}}
+	if resumeSessionID != "" {
+		// A resumed session was negotiated before; its version is the one
+		// requested
+		strict.version = requestedProtocolVersion
+		return client.NewClient(strict, client.WithSession())
+	}
+	return client.NewClient(strict)
+}
+
+// unwrapTransport returns the transport under -strict's checks, or the
+// current stream of a reconnecting SSE transport
+func unwrapTransport(t transport.Interface) transport.Interface {
+	if s, ok := t.(*strictTransport); ok {
+		t = s.Interface
+	}
+	if r, ok := t.(*reconnectingSSE); ok {
+		return r.stream()
+	}
+	return t
+}
+
+// SetRequestHandler passes server requests such as sampling through to the
+// client, if the transport supports them
+func (t *strictTransport) SetRequestHandler(handler transport.RequestHandler) {
+	if bidirectional, ok := t.Interface.(transport.BidirectionalInterface); ok {
+		bidirectional.SetRequestHandler(handler)
+	}
+}
+
+// SetProtocolVersion passes the negotiated version to HTTP transports, which
+// send it in a header
+func (t *strictTransport) SetProtocolVersion(version string) {
+	if httpConn, ok := t.Interface.(transport.HTTPConnection); ok {
+		httpConn.SetProtocolVersion(version)
+	}
+}
+
+// SetConnectionLostHandler passes the handler to transports that report
+// lost connections
+func (t *strictTransport) SetConnectionLostHandler(handler func(error)) {
+	if setter, ok := t.Interface.(interface{ SetConnectionLostHandler(func(error)) }); ok {
+		setter.SetConnectionLostHandler(handler)
+	}
+}
+
+// SendRequest sends the request and checks the response
+func (t *strictTransport) SendRequest(ctx context.Context, request transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	response, err := t.Interface.SendRequest(ctx, request)
+	if err == nil && response != nil {
+		t.check(request, response)
+	}
+	return response, err
+}
+
+// check reports the response's violations of the JSON-RPC envelope and of
+// the schema of the method's result
+func (t *strictTransport) check(request transport.JSONRPCRequest, response *transport.JSONRPCResponse) {
+	t.mu.Lock()
+	version := t.version
+	t.mu.Unlock()
+	violations, negotiated := responseViolations(request, response, version)
+	if negotiated != "" {
+		t.mu.Lock()
+		t.version = negotiated
+		t.mu.Unlock()
+	}
+	t.report(mcp.MCPMethod(request.Method), violations)
+}
+
+// responseViolations returns the violations of the JSON-RPC envelope and of
+// the schema of the method's result in a response, under the negotiated
+// protocol version. For initialize, it also returns the version the server
+// answered with, under which the result is checked.
+func responseViolations(request transport.JSONRPCRequest, response *transport.JSONRPCResponse, version string) ([]string, string) {
+	method := mcp.MCPMethod(request.Method)
+	var violations []string
+	if response.JSONRPC != mcp.JSONRPC_VERSION {
+		violations = append(violations, fmt.Sprintf(`/jsonrpc: must be "2.0", got %q`, response.JSONRPC))
+	}
+	if requestIDKey(response.ID) != requestIDKey(request.ID) {
+		violations = append(violations, fmt.Sprintf("/id: must be the request's ID %v, got %v", request.ID.Value(), response.ID.Value()))
+	}
+	switch {
+	case response.Error != nil && len(response.Result) > 0:
+		violations = append(violations, "/: a response must not have both a result and an error")
+	case response.Error == nil && len(response.Result) == 0:
+		violations = append(violations, "/: a response must have a result or an error")
+	case response.Error != nil && response.Error.Message == "":
+		violations = append(violations, "/error/message: is required")
+	}
+
+	negotiated := ""
+	if s, ok := resultShapes[method]; ok && response.Error == nil && len(response.Result) > 0 {
+		var result any
+		decoder := json.NewDecoder(bytes.NewReader(response.Result))
+		decoder.UseNumber()
+		if err := decoder.Decode(&result); err != nil {
+			violations = append(violations, fmt.Sprintf("/result: is not valid JSON: %v", err))
+		} else {
+			if method == mcp.MethodInitialize {
+				if fields, ok := result.(map[string]any); ok {
+					negotiated, _ = fields["protocolVersion"].(string)
+					version = negotiated
+				}
+			}
+			validateShape(s, result, "/result", version, &violations)
+		}
+	}
+	return violations, negotiated
+}
+
+// report records new violations as findings and prints them
+func (t *strictTransport) report(method mcp.MCPMethod, violations []string) {
+	t.mu.Lock()
+	var fresh []string
+	for _, v := range violations {
+		key := string(method) + " " + v
+		if !t.reported[key] {
+			t.reported[key] = true
+			fresh = append(fresh, v)
+		}
+	}
+	t.mu.Unlock()
+	for i, v := range fresh {
+		if i == maxStrictViolations {
+			fmt.Printf("[strict] %s: %d more violation(s) not shown\n", method, len(fresh)-i)
+			break
+		}
+		fmt.Printf("[strict] %s\n", report.addFinding(checkIDStrict, string(method), "%s: %s", method, v))
+	}
+}
+
+// requestIDKey normalizes a request ID for comparison: numbers of any Go
+// type compare by value, and never equal strings
+func requestIDKey(id mcp.RequestId) string {
+	switch v := id.Value().(type) {
+	case string:
+		return "s:" + v
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("n:%v", v)
+	}
+}
+
+// validateShape appends the violations of value against s, each starting
+// with the JSON pointer of the value
+func validateShape(s *shape, value any, pointer, version string, violations *[]string) {
+	violate := func(format string, args ...any) {
+		*violations = append(*violations, fmt.Sprintf("%s: %s", displayPointer(pointer), fmt.Sprintf(format, args...)))
+	}
+	if s.feature != "" {
+		if f := findProtocolFeature(s.feature); f != nil && !f.available(version) {
+			violate("%s; the session negotiated %s", f.requirement(), version)
+		}
+	}
+	if !hasJSONType(value, s.kind) {
+		violate("must be %s, got %s", withArticle(s.kind), withArticle(jsonType(value)))
+		return
+	}
+	if len(s.enum) > 0 {
+		if text, _ := value.(string); !slices.Contains(s.enum, text) {
+			violate("must be one of %s, got %q", strings.Join(s.enum, ", "), text)
+		}
+	}
+	switch v := value.(type) {
+	case []any:
+		if s.items != nil {
+			for i, item := range v {
+				validateShape(s.items, item, fmt.Sprintf("%s/%d", pointer, i), version, violations)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				violate("%q is required", name)
+			}
+		}
+		if len(s.oneOf) > 0 {
+			present := 0
+			for _, name := range s.oneOf {
+				if _, ok := v[name]; ok {
+					present++
+				}
+			}
+			if present != 1 {
+				violate("must have exactly one of %s", strings.Join(s.oneOf, ", "))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := s.fields[name]; ok {
+				validateShape(field, v[name], pointer+"/"+escapePointer(name), version, violations)
+			}
+		}
+		if s.content {
+			validateContentBlock(v, pointer, version, violations)
+		}
+	}
+}
+
+// validateContentBlock checks a content block against the shape of its type
+func validateContentBlock(block map[string]any, pointer, version string, violations *[]string) {
+	kind, ok := block["type"].(string)
+	if !ok {
+		*violations = append(*violations, fmt.Sprintf("%s: a content block must have a string \"type\"", displayPointer(pointer)))
+		return
+	}
+	s, ok := contentTypes[kind]
+	if !ok {
+		*violations = append(*violations, fmt.Sprintf("%s/type: unknown content type %q", displayPointer(pointer), kind))
+		return
+	}
+	validateShape(s, block, pointer, version, violations)
+}
+
+// hasJSONType reports whether value has the JSON type kind; an empty kind
+// accepts any value
+func hasJSONType(value any, kind string) bool {
+	if kind == "" {
+		return true
+	}
+	if kind == "integer" {
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	}
+	return jsonType(value) == kind
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// withArticle prefixes a JSON type with its indefinite article
+func withArticle(kind string) string {
+	switch kind {
+	case "null":
+		return "null"
+	case "object", "array", "integer":
+		return "an " + kind
+	default:
+		return "a " + kind
+	}
+}
+
+// escapePointer escapes a field name for a JSON pointer (RFC 6901)
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// displayPointer returns the pointer, or "/" for the whole document
+func displayPointer(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
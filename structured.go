@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// hasOutputSchema reports whether a tool declares an output schema
+func hasOutputSchema(tool *mcp.Tool) bool {
+	return tool.RawOutputSchema != nil || tool.OutputSchema.Type != ""
+}
+
+// resolveOutputSchema prepares a tool's output schema for validation
+func resolveOutputSchema(tool *mcp.Tool) (*jsonschema.Resolved, error) {
+	data := []byte(tool.RawOutputSchema)
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(tool.OutputSchema); err != nil {
+			return nil, err
+		}
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return schema.Resolve(nil)
+}
+
+// printStructuredContent shows the structured content of a tool result
+func printStructuredContent(result *mcp.CallToolResult) {
+	if result.StructuredContent == nil {
+		return
+	}
+	data, err := json.MarshalIndent(result.StructuredContent, "", "  ")
+	if err != nil {
+		fmt.Printf("\nStructured content: (cannot be encoded: %v)\n", err)
+		return
+	}
+	fmt.Printf("\nStructured content:\n%s\n", data)
+}
+
+// validateStructuredContent checks a tool result's structured content
+// against the tool's output schema. Error results are exempt, as they carry
+// the error rather than the declared output.
+func validateStructuredContent(tool *mcp.Tool, result *mcp.CallToolResult) []string {
+	if result.StructuredContent != nil {
+		if _, ok := result.StructuredContent.(map[string]any); !ok {
+			return []string{fmt.Sprintf("structuredContent must be a JSON object, not %s", itemJSON(result.StructuredContent))}
+		}
+	}
+	if tool == nil || !hasOutputSchema(tool) || result.IsError {
+		return nil
+	}
+	if result.StructuredContent == nil {
+		return []string{"the tool declares an outputSchema but the result has no structuredContent"}
+	}
+	schema, err := resolveOutputSchema(tool)
+	if err != nil {
+		return []string{fmt.Sprintf("the tool's outputSchema is invalid: %v", err)}
+	}
+	// Validate the decoded JSON, not the library's representation of it
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return []string{fmt.Sprintf("structuredContent cannot be encoded: %v", err)}
+	}
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return []string{fmt.Sprintf("structuredContent cannot be decoded: %v", err)}
+	}
+	if err := schema.Validate(instance); err != nil {
+		return []string{"structuredContent does not match the outputSchema: " + strings.TrimPrefix(err.Error(), "validating root: ")}
+	}
+	return nil
+}
+
+// checkToolResult validates a tool result against the listed tool's output
+// schema and records the problems as findings. It returns an error if any
+// of them is not suppressed. Servers that negotiated a protocol version
+// without structured output are not checked.
+func checkToolResult(toolName string, result *mcp.CallToolResult) error {
+	if !negotiatedSupports(featureStructuredOutput, "structured content is not checked") {
+		return nil
+	}
+	tool := report.listedTool(toolName)
+	problems := validateStructuredContent(tool, result)
+	if len(problems) == 0 {
+		if tool != nil && hasOutputSchema(tool) && !result.IsError {
+			fmt.Println("\nStructured content matches the output schema")
+		}
+		return nil
+	}
+	fmt.Println("\nProblems with the result:")
+	unsuppressed := 0
+	for _, p := range problems {
+		f := report.addFinding(checkIDOutputSchema, toolName, "tools/call %s: %s", toolName, p)
+		fmt.Printf("  ! %s\n", f)
+		if f.fails() {
+			unsuppressed++
+		}
+	}
+	if unsuppressed > 0 {
+		return fmt.Errorf("invalid result from tool '%s' (%d problem(s))", toolName, unsuppressed)
+	}
+	fmt.Println("\nAll problems are suppressed")
+	return nil
+}
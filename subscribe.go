@@ -0,0 +1,125 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// listenForNotifications opens the streamable HTTP GET stream on which
+// servers send notifications that are not tied to a request, such as
+// resource updates. It is set for -subscribe.
+var listenForNotifications bool
+
+// watchResourceUpdates subscribes to resources and prints the update
+// notifications the server sends until interrupted. With all set, every
+// resource the server lists is subscribed to.
+func watchResourceUpdates(mcpClient *client.Client, uris []string, all bool, timeout time.Duration) error {
+	fmt.Println("\n=== Resource Subscriptions ===")
+	caps := mcpClient.GetServerCapabilities()
+	if caps.Resources == nil || !caps.Resources.Subscribe {
+		return fmt.Errorf("the server does not advertise the resources.subscribe capability")
+	}
+
+	var updates atomic.Int64
+	// lastUpdate is when each resource last changed, for the time between updates
+	var lastUpdateMu sync.Mutex
+	lastUpdate := map[string]time.Time{}
+	watchLogMessages(mcpClient)
+	mcpClient.OnNotification(func(n mcp.JSONRPCNotification) {
+		now := time.Now()
+		stamp := consoleClock(now)
+		switch n.Method {
+		case string(mcp.MethodNotificationResourceUpdated):
+			uri, _ := n.Params.AdditionalFields["uri"].(string)
+			updates.Add(1)
+			lastUpdateMu.Lock()
+			previous, seen := lastUpdate[uri]
+			lastUpdate[uri] = now
+			lastUpdateMu.Unlock()
+			if seen {
+				fmt.Printf("[%s] updated  %s (previous update %s)\n", stamp, uri, humanAgo(previous))
+			} else {
+				fmt.Printf("[%s] updated  %s\n", stamp, uri)
+			}
+			emitEvent(eventResourceUpdated, map[string]any{"uri": uri})
+		case string(mcp.MethodNotificationResourcesListChanged):
+			fmt.Printf("[%s] resource list changed\n", stamp)
+			emitEvent(eventResourceUpdated, map[string]any{"listChanged": true})
+		case logMessageMethod:
+			// Printed by watchLogMessages
+		default:
+			fmt.Printf("[%s] %s\n", stamp, n.Method)
+		}
+	})
+
+	if all {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		listStart := time.Now()
+		result, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{})
+		cancel()
+		report.addTiming("resources/list", time.Since(listStart), err)
+		if err != nil {
+			return fmt.Errorf("failed to list resources: %w", err)
+		}
+		report.setResources(result.Resources)
+		for _, res := range result.Resources {
+			uris = append(uris, res.URI)
+		}
+		if len(uris) == 0 {
+			return fmt.Errorf("the server lists no resources to subscribe to")
+		}
+	}
+
+	var subscribed []string
+	for _, uri := range uris {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		request := mcp.SubscribeRequest{}
+		request.Params.URI = uri
+		start := time.Now()
+		err := mcpClient.Subscribe(ctx, request)
+		cancel()
+		report.addTiming("resources/subscribe", time.Since(start), err)
+		if err != nil {
+			fmt.Printf("Failed to subscribe to %s: %v\n", uri, err)
+			report.addError("resources/subscribe %s: %v", uri, err)
+			continue
+		}
+		fmt.Printf("Subscribed to %s\n", uri)
+		subscribed = append(subscribed, uri)
+	}
+	if len(subscribed) == 0 {
+		return fmt.Errorf("no subscriptions succeeded")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("\nWatching %d resource(s) for updates (press Ctrl-C to stop)...\n", len(subscribed))
+	<-ctx.Done()
+	stop()
+
+	fmt.Println()
+	for _, uri := range subscribed {
+		unsubCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		request := mcp.UnsubscribeRequest{}
+		request.Params.URI = uri
+		if err := mcpClient.Unsubscribe(unsubCtx, request); err != nil {
+			fmt.Printf("Failed to unsubscribe from %s: %v\n", uri, err)
+		}
+		cancel()
+	}
+	fmt.Printf("Received %d update(s) for %s\n", updates.Load(), strings.Join(subscribed, ", "))
+	return nil
+}
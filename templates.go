@@ -0,0 +1,309 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// parseTemplateVars parses -template-vars: either a JSON object, whose array
+// and object values expand as RFC 6570 lists and associative arrays, or
+// comma separated name=value pairs
+func parseTemplateVars(spec string) (uritemplate.Values, error) {
+	values := uritemplate.Values{}
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return values, nil
+	}
+
+	if strings.HasPrefix(spec, "{") {
+		var vars map[string]any
+		if err := json.Unmarshal([]byte(spec), &vars); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		for name, v := range vars {
+			value, err := templateValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("variable '%s': %w", name, err)
+			}
+			values.Set(name, value)
+		}
+		return values, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable '%s' (expected name=value)", pair)
+		}
+		values.Set(name, uritemplate.String(value))
+	}
+	return values, nil
+}
+
+// templateValue converts a JSON value to a URI template value
+func templateValue(v any) (uritemplate.Value, error) {
+	switch v := v.(type) {
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = scalarString(item)
+		}
+		return uritemplate.List(items...), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var kv []string
+		for _, key := range keys {
+			kv = append(kv, key, scalarString(v[key]))
+		}
+		return uritemplate.KV(kv...), nil
+	case nil:
+		return uritemplate.Value{}, fmt.Errorf("null is not a valid value")
+	default:
+		return uritemplate.String(scalarString(v)), nil
+	}
+}
+
+// scalarString formats a JSON scalar the way it appears in a URI
+func scalarString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// findResourceTemplate looks up a template by name or URI template among the
+// server's resource templates
+func findResourceTemplate(ctx context.Context, mcpClient *client.Client, spec string) (*mcp.ResourceTemplate, error) {
+	listStart := time.Now()
+	result, err := mcpClient.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	report.addTiming("resources/templates/list", time.Since(listStart), err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource templates: %w", err)
+	}
+	report.setResourceTemplates(result.ResourceTemplates)
+
+	var available []string
+	for i, tmpl := range result.ResourceTemplates {
+		if tmpl.URITemplate == nil {
+			continue
+		}
+		if tmpl.Name == spec || tmpl.URITemplate.Raw() == spec {
+			return &result.ResourceTemplates[i], nil
+		}
+		available = append(available, fmt.Sprintf("%s (%s)", tmpl.URITemplate.Raw(), tmpl.Name))
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("resource template '%s' not found: the server lists no resource templates", spec)
+	}
+	return nil, fmt.Errorf("resource template '%s' not found; available: %s", spec, strings.Join(available, ", "))
+}
+
+// promptTemplateVars asks on a terminal for the variables that have no value,
+// offering the server's completions if complete is set. Variables left
+// without a value, because there is no terminal or the answer is empty, are
+// undefined and expand to nothing as RFC 6570 specifies.
+func promptTemplateVars(tmpl *uritemplate.Template, values uritemplate.Values, in io.Reader, interactive bool, complete argumentCompleter) {
+	if !interactive {
+		return
+	}
+	var missing []string
+	for _, name := range tmpl.Varnames() {
+		if !values.Get(name).Valid() {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	fmt.Println("Enter the template variables (empty to leave a variable undefined):")
+	reader := bufio.NewReader(in)
+	for _, name := range missing {
+		// Variables with string values are the context for completions
+		resolved := map[string]string{}
+		for _, other := range tmpl.Varnames() {
+			if v := values.Get(other); v.Valid() && v.T == uritemplate.ValueTypeString {
+				resolved[other] = v.String()
+			}
+		}
+		value, err := askArgument(reader, name, complete, resolved)
+		if err != nil {
+			// Input ended: the remaining variables stay undefined
+			return
+		}
+		if value != "" {
+			values.Set(name, uritemplate.String(value))
+		}
+	}
+}
+
+// stdinIsTerminal reports whether standard input is an interactive terminal.
+// /dev/null is a character device too, but there is no one to ask.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// readResourceTemplate expands a resource template with the given variables,
+// reads the resulting resource and validates the server's response
+func readResourceTemplate(ctx context.Context, mcpClient *client.Client, spec, varsSpec string) error {
+	fmt.Println("\n=== Read Resource Template ===")
+	values, err := parseTemplateVars(varsSpec)
+	if err != nil {
+		return fmt.Errorf("invalid -template-vars: %w", err)
+	}
+	resTmpl, err := findResourceTemplate(ctx, mcpClient, spec)
+	if err != nil {
+		return err
+	}
+	tmpl := resTmpl.URITemplate.Template
+	fmt.Printf("Template: %s (%s)\n", tmpl.Raw(), resTmpl.Name)
+
+	complete := newArgumentCompleter(ctx, mcpClient, completionRef{Type: refResource, URI: tmpl.Raw()})
+	promptTemplateVars(tmpl, values, os.Stdin, stdinIsTerminal(), complete)
+	for _, name := range tmpl.Varnames() {
+		fmt.Printf("  %s = %s\n", name, describeTemplateValue(values.Get(name)))
+	}
+	uri, err := tmpl.Expand(values)
+	if err != nil {
+		return fmt.Errorf("failed to expand template: %w", err)
+	}
+	fmt.Printf("URI: %s\n", uri)
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	readStart := time.Now()
+	result, err := mcpClient.ReadResource(ctx, request)
+	report.addTiming("resources/read", time.Since(readStart), err)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	fmt.Printf("Read %d content item(s) in %s\n\n", len(result.Contents), humanDuration(time.Since(readStart)))
+
+	problems := validateResourceContents(uri, resTmpl.MIMEType, result.Contents)
+	for i, content := range result.Contents {
+		printResourceContent(i+1, content)
+	}
+	saveResourceContents(result.Contents)
+	if len(problems) > 0 {
+		fmt.Println("\nProblems with the response:")
+		unsuppressed := 0
+		for _, p := range problems {
+			f := report.addFinding(checkIDResourceResponse, uri, "resources/read %s: %s", uri, p)
+			fmt.Printf("  ! %s\n", f)
+			if f.fails() {
+				unsuppressed++
+			}
+		}
+		if unsuppressed > 0 {
+			return fmt.Errorf("invalid response to resources/read (%d problem(s))", unsuppressed)
+		}
+		fmt.Println("\nAll problems are suppressed")
+		return nil
+	}
+	fmt.Println("\nResponse is valid")
+	return nil
+}
+
+// describeTemplateValue formats a template variable for display
+func describeTemplateValue(v uritemplate.Value) string {
+	if !v.Valid() {
+		return "(undefined)"
+	}
+	switch v.T {
+	case uritemplate.ValueTypeList:
+		return "[" + strings.Join(v.List(), ", ") + "]"
+	case uritemplate.ValueTypeKV:
+		kv := v.KV()
+		var pairs []string
+		for i := 0; i+1 < len(kv); i += 2 {
+			pairs = append(pairs, kv[i]+": "+kv[i+1])
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	default:
+		return fmt.Sprintf("%q", v.String())
+	}
+}
+
+// validateResourceContents checks a resources/read result against the URI
+// that was requested and the template's MIME type
+func validateResourceContents(uri, mimeType string, contents []mcp.ResourceContents) []string {
+	if len(contents) == 0 {
+		return []string{"result has no contents"}
+	}
+	var problems []string
+	for i, content := range contents {
+		n := i + 1
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			problems = append(problems, checkContentMeta(n, uri, c.URI, mimeType, c.MIMEType)...)
+		case mcp.BlobResourceContents:
+			problems = append(problems, checkContentMeta(n, uri, c.URI, mimeType, c.MIMEType)...)
+			if _, err := base64.StdEncoding.DecodeString(c.Blob); err != nil {
+				problems = append(problems, fmt.Sprintf("content %d: blob is not valid base64: %v", n, err))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("content %d has neither text nor blob", n))
+		}
+	}
+	return problems
+}
+
+// checkContentMeta checks the URI and MIME type of one content item. Servers
+// may return several items for one read, such as the files of a directory,
+// so only the first must have the requested URI.
+func checkContentMeta(n int, requested, uri, wantMIME, mimeType string) []string {
+	var problems []string
+	if n == 1 && uri != requested {
+		problems = append(problems, fmt.Sprintf("content %d has uri %s, expected %s", n, uri, requested))
+	}
+	if wantMIME != "" && mimeType != "" && mimeType != wantMIME {
+		problems = append(problems, fmt.Sprintf("content %d has MIME type %s, but the template declares %s", n, mimeType, wantMIME))
+	}
+	return problems
+}
+
+// printResourceContent prints one content item of a resources/read result
+func printResourceContent(n int, content mcp.ResourceContents) {
+	switch c := content.(type) {
+	case mcp.TextResourceContents:
+		fmt.Printf("--- Content %d: %s (%s) ---\n", n, c.URI, valueOr(c.MIMEType, "no MIME type"))
+		fmt.Println(c.Text)
+	case mcp.BlobResourceContents:
+		fmt.Printf("--- Content %d: %s (%s) ---\n", n, c.URI, valueOr(c.MIMEType, "no MIME type"))
+		fmt.Printf("[binary, %s of base64]\n", humanBytes(int64(len(c.Blob))))
+	}
+}
+
+// valueOr returns value, or fallback if value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yosida95/uritemplate/v3"
+)
+
+func TestParseTemplateVars(t *testing.T) {
+	tmpl := uritemplate.MustNew("docs://{lang}{/path*}{?q,keys*}")
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"lang=en,q=a b", "docs://en?q=a%20b"},
+		{"lang=en, q=x=y", "docs://en?q=x%3Dy"},
+		{`{"lang":"de","path":["guides","setup"],"q":"proxy"}`, "docs://de/guides/setup?q=proxy"},
+		{`{"lang":"en","keys":{"b":2,"a":true}}`, "docs://en?a=true&b=2"},
+		{"", "docs://"},
+	}
+	for _, tt := range tests {
+		values, err := parseTemplateVars(tt.spec)
+		if err != nil {
+			t.Errorf("parseTemplateVars(%q): %v", tt.spec, err)
+			continue
+		}
+		got, err := tmpl.Expand(values)
+		if err != nil {
+			t.Errorf("expanding %q: %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTemplateVars(%q) expands to %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"lang", "=en", `{"lang":`, `{"lang":null}`} {
+		if _, err := parseTemplateVars(spec); err == nil {
+			t.Errorf("parseTemplateVars(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestPromptTemplateVars(t *testing.T) {
+	tmpl := uritemplate.MustNew("items://list{/opt}{?page,limit}")
+
+	// Without a terminal, variables without a value stay undefined
+	values := uritemplate.Values{}
+	values.Set("page", uritemplate.String("2"))
+	promptTemplateVars(tmpl, values, strings.NewReader(""), false, nil)
+	if got, _ := tmpl.Expand(values); got != "items://list?page=2" {
+		t.Errorf("non-interactive expansion = %q, want %q", got, "items://list?page=2")
+	}
+
+	// An empty answer leaves a variable undefined as well
+	values = uritemplate.Values{}
+	promptTemplateVars(tmpl, values, strings.NewReader("all\n\n10\n"), true, nil)
+	if got, _ := tmpl.Expand(values); got != "items://list/all?limit=10" {
+		t.Errorf("interactive expansion = %q, want %q", got, "items://list/all?limit=10")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// machineTimeLayout is RFC 3339 with millisecond precision, used for every
+// timestamp in machine-readable output so that lines sort and align
+const machineTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// consoleClockLayout is the local time of day shown in console output
+const consoleClockLayout = "15:04:05.000"
+
+// consoleDateLayout is the local date and time shown at the start and end of
+// a run, with the zone so that it can be matched to server logs
+const consoleDateLayout = "Mon 2 Jan 2006 15:04:05.000 MST"
+
+// machineTime formats a time for machine-readable output, in UTC
+func machineTime(t time.Time) string {
+	return t.UTC().Format(machineTimeLayout)
+}
+
+// consoleClock formats a time as the local time of day for console output
+func consoleClock(t time.Time) string {
+	return t.Local().Format(consoleClockLayout)
+}
+
+// timestamp is a time that is written to JSON in the machine time format
+type timestamp time.Time
+
+// MarshalJSON implements json.Marshaler
+func (t timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(machineTime(time.Time(t)))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *timestamp) UnmarshalJSON(data []byte) error {
+	var parsed time.Time
+	if err := parsed.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*t = timestamp(parsed)
+	return nil
+}
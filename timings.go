@@ -0,0 +1,95 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// showTimings is set by -timings: the run ends with a table of how long each
+// request took
+var showTimings bool
+
+// timingSummary aggregates the timings of one operation, such as
+// "tools/list" or "tools/call echo", over the run
+type timingSummary struct {
+	Operation string        `json:"operation"`
+	Count     int           `json:"count"`
+	Failed    int           `json:"failed,omitempty"`
+	Total     time.Duration `json:"totalNs"`
+	Latency   *latencyStats `json:"latency"`
+}
+
+// summarizeTimings groups the timings by operation, in the order each
+// operation was first completed
+func summarizeTimings(records []timingRecord) []timingSummary {
+	var summaries []timingSummary
+	durations := make(map[string][]time.Duration)
+	index := make(map[string]int)
+	for _, t := range records {
+		i, ok := index[t.Operation]
+		if !ok {
+			i = len(summaries)
+			index[t.Operation] = i
+			summaries = append(summaries, timingSummary{Operation: t.Operation})
+		}
+		summaries[i].Count++
+		summaries[i].Total += t.Duration
+		if t.Failed {
+			summaries[i].Failed++
+		}
+		durations[t.Operation] = append(durations[t.Operation], t.Duration)
+	}
+	for i := range summaries {
+		summaries[i].Latency = newLatencyStats(durations[summaries[i].Operation])
+	}
+	return summaries
+}
+
+// printTimingSummary prints how long each operation of the run took. When an
+// operation was repeated its p50, p95 and p99 are shown; otherwise the table
+// only has the duration of each request.
+func printTimingSummary() {
+	report.mu.Lock()
+	summaries := summarizeTimings(report.Timings)
+	report.mu.Unlock()
+	if len(summaries) == 0 {
+		return
+	}
+
+	width := len("Operation")
+	repeated := false
+	for _, s := range summaries {
+		width = max(width, len(s.Operation))
+		repeated = repeated || s.Count > 1
+	}
+
+	fmt.Println("\n=== Timing ===")
+	if !repeated {
+		fmt.Printf("%-*s  %8s\n", width, "Operation", "Duration")
+		for _, s := range summaries {
+			line := fmt.Sprintf("%-*s  %8s", width, s.Operation, humanDuration(s.Total))
+			if s.Failed > 0 {
+				line += "  failed"
+			}
+			fmt.Println(line)
+		}
+		return
+	}
+
+	fmt.Printf("%-*s  %5s  %8s  %8s  %8s  %8s\n", width, "Operation", "Count", "p50", "p95", "p99", "Max")
+	for _, s := range summaries {
+		cells := []string{humanDuration(s.Latency.P50), "-", "-", "-"}
+		if s.Count > 1 {
+			cells = []string{humanDuration(s.Latency.P50), humanDuration(s.Latency.P95), humanDuration(s.Latency.P99), humanDuration(s.Latency.Max)}
+		}
+		line := fmt.Sprintf("%-*s  %5d  %8s  %8s  %8s  %8s", width, s.Operation, s.Count, cells[0], cells[1], cells[2], cells[3])
+		if s.Failed > 0 {
+			line += fmt.Sprintf("  %d failed", s.Failed)
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeTLSConfig is the TLS configuration for connections to the server and
+// its authorization server, set from -ca-cert and -insecure. nil uses the
+// system defaults.
+var probeTLSConfig *tls.Config
+
+// loadTLSConfig builds the TLS configuration for -ca-cert and -insecure. The
+// CA bundle is trusted in addition to the system roots, so that a private CA
+// does not break connections to public authorization servers.
+func loadTLSConfig(caCertPath string, insecure bool) (*tls.Config, error) {
+	if caCertPath == "" && !insecure {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caCertPath != "" {
+		pem, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caCertPath)
+		}
+		config.RootCAs = pool
+	}
+
+	// For lab servers with self-signed certificates only: this accepts any
+	// certificate, so the connection can be intercepted
+	config.InsecureSkipVerify = insecure
+	return config, nil
+}
+
+// tlsCertExpiryWarning is how close to expiry a certificate is flagged
+const tlsCertExpiryWarning = 30 * 24 * time.Hour
+
+// tlsCertificate describes one certificate of the server's chain
+type tlsCertificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	DNSNames           []string  `json:"dnsNames,omitempty"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysUntilExpiry    int       `json:"daysUntilExpiry"`
+	PublicKey          string    `json:"publicKey"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+}
+
+// tlsDiagnostics describes the TLS connection to the server
+type tlsDiagnostics struct {
+	Host        string           `json:"host"`
+	Version     string           `json:"version"`
+	CipherSuite string           `json:"cipherSuite"`
+	ALPN        string           `json:"alpn,omitempty"`
+	Verified    bool             `json:"verified"`
+	VerifyError string           `json:"verifyError,omitempty"`
+	Chain       []tlsCertificate `json:"chain"`
+	Warnings    []string         `json:"warnings,omitempty"`
+	findings    []finding
+}
+
+// tlsStates holds the first TLS connection state seen for each host
+var (
+	tlsStates   = map[string]tls.ConnectionState{}
+	tlsStatesMu sync.Mutex
+)
+
+// tlsCaptureTransport remembers the TLS connection state of responses
+type tlsCaptureTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tlsCaptureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.TLS != nil {
+		tlsStatesMu.Lock()
+		if _, ok := tlsStates[req.URL.Host]; !ok {
+			tlsStates[req.URL.Host] = *resp.TLS
+		}
+		tlsStatesMu.Unlock()
+	}
+	return resp, err
+}
+
+// observedTLS returns the diagnostics for the connection to a server, or nil
+// if no TLS connection to it was made
+func observedTLS(serverURL string) *tlsDiagnostics {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil
+	}
+	tlsStatesMu.Lock()
+	state, ok := tlsStates[parsed.Host]
+	tlsStatesMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return analyzeTLS(parsed.Hostname(), parsed.Host, state)
+}
+
+// analyzeTLS describes a TLS connection and flags weak configurations
+func analyzeTLS(hostname, host string, state tls.ConnectionState) *tlsDiagnostics {
+	d := &tlsDiagnostics{
+		Host:        host,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+	}
+	warn := func(check, subject, format string, v ...any) {
+		f := newFinding(check, subject, format, v...)
+		d.findings = append(d.findings, f)
+		d.Warnings = append(d.Warnings, f.String())
+	}
+
+	if state.Version < tls.VersionTLS12 {
+		warn(checkIDTLSVersion, d.Version, "%s is deprecated; servers should use TLS 1.2 or later", d.Version)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == state.CipherSuite {
+			warn(checkIDInsecureCipher, d.CipherSuite, "cipher suite %s is insecure", d.CipherSuite)
+		}
+	}
+	if strings.HasPrefix(d.CipherSuite, "TLS_RSA_") {
+		warn(checkIDNoFwdSecrecy, d.CipherSuite, "cipher suite %s has no forward secrecy", d.CipherSuite)
+	} else if strings.Contains(d.CipherSuite, "_CBC_") {
+		warn(checkIDCBCCipher, d.CipherSuite, "cipher suite %s uses CBC mode; prefer an AEAD suite (GCM or ChaCha20-Poly1305)", d.CipherSuite)
+	}
+
+	for i, cert := range state.PeerCertificates {
+		c := tlsCertificate{
+			Subject:            cert.Subject.String(),
+			Issuer:             cert.Issuer.String(),
+			DNSNames:           cert.DNSNames,
+			NotAfter:           cert.NotAfter,
+			DaysUntilExpiry:    int(time.Until(cert.NotAfter).Hours() / 24),
+			PublicKey:          describePublicKey(cert),
+			SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		}
+		d.Chain = append(d.Chain, c)
+
+		switch remaining := time.Until(cert.NotAfter); {
+		case remaining < 0:
+			warn(checkIDCertExpired, c.Subject, "certificate %s expired on %s", c.Subject, cert.NotAfter.Format(time.DateOnly))
+		case remaining < tlsCertExpiryWarning:
+			warn(checkIDCertExpiring, c.Subject, "certificate %s expires in %d days", c.Subject, c.DaysUntilExpiry)
+		}
+		if weak := weakPublicKey(cert); weak != "" {
+			warn(checkIDWeakKey, c.Subject, "certificate %s has a weak key (%s)", c.Subject, weak)
+		}
+		// The signature of a self-signed root is not relied on
+		selfSigned := i > 0 && cert.Subject.String() == cert.Issuer.String()
+		if !selfSigned && (cert.SignatureAlgorithm == x509.SHA1WithRSA || cert.SignatureAlgorithm == x509.ECDSAWithSHA1 || cert.SignatureAlgorithm == x509.MD5WithRSA) {
+			warn(checkIDWeakSignature, c.Subject, "certificate %s is signed with %s", c.Subject, c.SignatureAlgorithm)
+		}
+	}
+
+	// With -insecure the chain was not verified during the handshake
+	d.Verified = len(state.VerifiedChains) > 0
+	if !d.Verified && len(state.PeerCertificates) > 0 {
+		opts := x509.VerifyOptions{DNSName: hostname, Intermediates: x509.NewCertPool()}
+		if probeTLSConfig != nil {
+			opts.Roots = probeTLSConfig.RootCAs
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+			d.VerifyError = err.Error()
+			warn(checkIDCertUnverified, host, "certificate does not verify: %v", err)
+		} else {
+			d.Verified = true
+		}
+	}
+	return d
+}
+
+// describePublicKey returns the key type and size of a certificate
+func describePublicKey(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// weakPublicKey describes a certificate key that is too small, or returns ""
+func weakPublicKey(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return fmt.Sprintf("RSA %d", key.N.BitLen())
+		}
+	case *ecdsa.PublicKey:
+		if key.Curve.Params().BitSize < 256 {
+			return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+		}
+	}
+	return ""
+}
+
+// printTLSDiagnostics prints the TLS connection details and any warnings
+func printTLSDiagnostics(d *tlsDiagnostics) {
+	fmt.Println("\n=== TLS ===")
+	fmt.Printf("Host: %s\n", d.Host)
+	fmt.Printf("Version: %s\n", d.Version)
+	fmt.Printf("Cipher suite: %s\n", d.CipherSuite)
+	if d.ALPN != "" {
+		fmt.Printf("ALPN: %s\n", d.ALPN)
+	}
+	fmt.Println("Certificate chain:")
+	for i, c := range d.Chain {
+		fmt.Printf("  %d: %s\n", i, c.Subject)
+		fmt.Printf("     Issuer: %s\n", c.Issuer)
+		if len(c.DNSNames) > 0 {
+			fmt.Printf("     Names: %s\n", strings.Join(c.DNSNames, ", "))
+		}
+		fmt.Printf("     Expires: %s (%d days)\n", c.NotAfter.Format(time.DateOnly), c.DaysUntilExpiry)
+		fmt.Printf("     Key: %s, signed with %s\n", c.PublicKey, c.SignatureAlgorithm)
+	}
+	if len(d.Warnings) == 0 {
+		fmt.Println("No weak TLS configuration found")
+		return
+	}
+	fmt.Println("Warnings:")
+	for _, w := range d.Warnings {
+		fmt.Printf("  ! %s\n", w)
+	}
+}
+
+// isTLSError reports whether err is a failure to verify the server's certificate
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// diagnoseTLSFailure explains why the server's certificate was rejected by
+// connecting again without verification and analyzing the chain it presents
+func diagnoseTLSFailure(serverURL string, timeout time.Duration) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return
+	}
+
+	// Connect the same way as the probe (including any proxy), but only to
+	// read the chain: the response is discarded
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	httpTransport := newProbeTransport(0)
+	httpTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	defer httpTransport.CloseIdleConnections()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, serverURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := httpTransport.RoundTrip(req)
+	if err != nil {
+		fmt.Printf("\nCould not connect to analyze the certificate: %v\n", err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.TLS == nil {
+		return
+	}
+	state := *resp.TLS
+
+	printTLSDiagnostics(analyzeTLS(parsed.Hostname(), parsed.Host, state))
+	fmt.Println("\nTrust a private CA with -ca-cert <bundle.pem>, or skip verification for a lab server with -insecure")
+	fmt.Println()
+}
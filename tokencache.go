@@ -0,0 +1,287 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// tokenRefreshMargin is how long before expiry a token is refreshed, so that
+// requests in flight do not race the expiry
+const tokenRefreshMargin = 30 * time.Second
+
+// cachedToken is a token cache entry. The client credentials are kept with the
+// token because a dynamically registered client is needed to refresh it, and
+// the scopes because a token is only reused for the scopes it was granted for.
+type cachedToken struct {
+	ClientID     string          `json:"client_id"`
+	ClientSecret string          `json:"client_secret,omitempty"`
+	TokenURL     string          `json:"token_url,omitempty"`
+	Scopes       []string        `json:"scopes,omitempty"`
+	Token        transport.Token `json:"token"`
+}
+
+// tokenCachePath returns the file where OAuth tokens are cached
+func tokenCachePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "mcpprobe", "tokens.json"), nil
+}
+
+// loadTokenCache reads the token cache, keyed by server URL; a missing file means no tokens are cached
+func loadTokenCache() (map[string]cachedToken, error) {
+	path, err := tokenCachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]cachedToken{}, nil
+		}
+		return nil, fmt.Errorf("failed to read token cache: %w", err)
+	}
+	cache := map[string]cachedToken{}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// saveTokenCache writes the token cache so that only the current user can read it
+func saveTokenCache(cache map[string]cachedToken) error {
+	path, err := tokenCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode token cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	return nil
+}
+
+// oauthTokenStore is the transport's token store. It refreshes tokens shortly
+// before they expire and, unless caching is disabled, persists every token it
+// is given so that later runs against the same server can reuse it.
+type oauthTokenStore struct {
+	server string
+	cache  bool
+
+	mu    sync.Mutex
+	entry cachedToken
+	valid bool
+
+	// refresh obtains a new token, e.g. with the refresh token or by
+	// repeating the client credentials grant
+	refresh   func(ctx context.Context, token *transport.Token) (*transport.Token, error)
+	refreshMu sync.Mutex
+}
+
+// newOAuthTokenStore returns the token store for a server, loading any cached
+// token for the given client ID and scopes. An empty client ID accepts any
+// cached client; the scopes must be the ones the token was obtained for.
+func newOAuthTokenStore(serverURL, clientID string, scopes []string, cache bool) *oauthTokenStore {
+	s := &oauthTokenStore{server: oauthBaseURL(serverURL), cache: cache}
+	s.entry.Scopes = normalizeScopes(scopes)
+	if !cache {
+		return s
+	}
+	entries, err := loadTokenCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", report.addWarning(warningProbe, "ignoring token cache: %v", err))
+		return s
+	}
+	if entry, ok := entries[s.server]; ok && entry.Token.AccessToken != "" && (clientID == "" || clientID == entry.ClientID) &&
+		slices.Equal(normalizeScopes(entry.Scopes), s.entry.Scopes) {
+		s.entry = entry
+		s.valid = true
+	}
+	return s
+}
+
+// normalizeScopes sorts scopes and drops duplicates, so that the same set of
+// scopes always compares equal
+func normalizeScopes(scopes []string) []string {
+	if len(scopes) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(scopes)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+// cached returns the cache entry loaded for the server, if any
+func (s *oauthTokenStore) cached() (cachedToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entry, s.valid
+}
+
+// setClient records the client a token belongs to, before it is saved
+func (s *oauthTokenStore) setClient(clientID, clientSecret, tokenURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry.ClientID = clientID
+	s.entry.ClientSecret = clientSecret
+	s.entry.TokenURL = tokenURL
+}
+
+// clear discards the current token
+func (s *oauthTokenStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry.Token = transport.Token{}
+	s.valid = false
+}
+
+// GetToken returns the current token, refreshing it first if it is about to expire
+func (s *oauthTokenStore) GetToken(ctx context.Context) (*transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	token, valid := s.entry.Token, s.valid
+	s.mu.Unlock()
+	if !valid {
+		return nil, transport.ErrNoToken
+	}
+
+	if !token.ExpiresAt.IsZero() && time.Until(token.ExpiresAt) < tokenRefreshMargin && s.refresh != nil {
+		if refreshed, err := s.renew(ctx, token.AccessToken); err == nil {
+			return refreshed, nil
+		}
+		// Fall through with the old token; the transport reports it if it has expired
+	}
+	return &token, nil
+}
+
+// SaveToken stores a token and writes it to the cache
+func (s *oauthTokenStore) SaveToken(ctx context.Context, token *transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.entry.Token = *token
+	s.valid = true
+	entry := s.entry
+	s.mu.Unlock()
+
+	if s.cache {
+		if err := s.persist(entry); err != nil {
+			// The token is still usable for this run
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", report.addWarning(warningProbe, "failed to cache OAuth token: %v", err))
+		}
+	}
+	return nil
+}
+
+// persist writes one entry to the token cache
+func (s *oauthTokenStore) persist(entry cachedToken) error {
+	entries, err := loadTokenCache()
+	if err != nil {
+		return err
+	}
+	entries[s.server] = entry
+	return saveTokenCache(entries)
+}
+
+// renew obtains a new token to replace the rejected one. Concurrent callers
+// that saw the same token share a single refresh.
+func (s *oauthTokenStore) renew(ctx context.Context, rejected string) (*transport.Token, error) {
+	if s.refresh == nil {
+		return nil, errors.New("token cannot be refreshed")
+	}
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.Lock()
+	current := s.entry.Token
+	s.mu.Unlock()
+	if current.AccessToken != rejected && current.AccessToken != "" {
+		// Another request already refreshed it
+		return &current, nil
+	}
+
+	token, err := s.refresh(ctx, &current)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SaveToken(ctx, token); err != nil {
+		return nil, err
+	}
+	if !quiet {
+		fmt.Fprintln(os.Stderr, "OAuth token refreshed")
+	}
+	return token, nil
+}
+
+// oauthRetryTransport retries a request once with a refreshed token when the
+// server rejects the current one with 401, e.g. because it was revoked or
+// expired earlier than advertised during a long session
+type oauthRetryTransport struct {
+	base  http.RoundTripper
+	store *oauthTokenStore
+}
+
+func (t *oauthRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	rejected, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+
+	token, refreshErr := t.store.renew(req.Context(), rejected)
+	if refreshErr != nil {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	_ = resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// withOAuthRetry wraps an HTTP client so that rejected tokens are refreshed
+func withOAuthRetry(httpClient *http.Client, oauth *transport.OAuthConfig) *http.Client {
+	if oauth == nil {
+		return httpClient
+	}
+	store, ok := oauth.TokenStore.(*oauthTokenStore)
+	if !ok {
+		return httpClient
+	}
+	httpClient.Transport = &oauthRetryTransport{base: httpClient.Transport, store: store}
+	return httpClient
+}
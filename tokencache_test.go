@@ -0,0 +1,132 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// useTempTokenCache points the token cache at an empty temporary directory
+func useTempTokenCache(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	const serverURL = "https://mcp.example.com/mcp"
+	cached := cachedToken{
+		ClientID: "probe-client",
+		Scopes:   []string{"read", "write"},
+		Token:    transport.Token{AccessToken: "cached-token", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		clientID string
+		scopes   []string
+		cache    bool
+		reused   bool
+	}{
+		{"same client and scopes", serverURL, "probe-client", []string{"read", "write"}, true, true},
+		{"scopes in another order", serverURL, "probe-client", []string{"write", "read", "read"}, true, true},
+		{"registered client", serverURL, "", []string{"read", "write"}, true, true},
+		{"query string", serverURL + "?tenant=a", "probe-client", []string{"read", "write"}, true, true},
+		{"fewer scopes", serverURL, "probe-client", []string{"read"}, true, false},
+		{"more scopes", serverURL, "probe-client", []string{"read", "write", "admin"}, true, false},
+		{"no scopes", serverURL, "probe-client", nil, true, false},
+		{"other client", serverURL, "other-client", []string{"read", "write"}, true, false},
+		{"other server", "https://other.example.com/mcp", "probe-client", []string{"read", "write"}, true, false},
+		{"no token cache", serverURL, "probe-client", []string{"read", "write"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempTokenCache(t)
+			if err := saveTokenCache(map[string]cachedToken{oauthBaseURL(serverURL): cached}); err != nil {
+				t.Fatal(err)
+			}
+			store := newOAuthTokenStore(tt.url, tt.clientID, tt.scopes, tt.cache)
+			token, err := store.GetToken(context.Background())
+			if tt.reused {
+				if err != nil || token.AccessToken != "cached-token" {
+					t.Fatalf("GetToken = %v, %v, want the cached token", token, err)
+				}
+				return
+			}
+			if !errors.Is(err, transport.ErrNoToken) {
+				t.Fatalf("GetToken = %v, %v, want no token", token, err)
+			}
+		})
+	}
+}
+
+func TestTokenCacheExpiry(t *testing.T) {
+	const serverURL = "https://mcp.example.com/mcp"
+	useTempTokenCache(t)
+	expiring := cachedToken{
+		ClientID: "probe-client",
+		Token:    transport.Token{AccessToken: "expiring-token", RefreshToken: "refresh", ExpiresAt: time.Now().Add(tokenRefreshMargin / 2)},
+	}
+	if err := saveTokenCache(map[string]cachedToken{oauthBaseURL(serverURL): expiring}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a way to refresh it, the old token is returned for the
+	// transport to report
+	store := newOAuthTokenStore(serverURL, "probe-client", nil, true)
+	if token, err := store.GetToken(context.Background()); err != nil || token.AccessToken != "expiring-token" {
+		t.Fatalf("GetToken without refresh = %v, %v", token, err)
+	}
+
+	refreshed := 0
+	store.refresh = func(ctx context.Context, token *transport.Token) (*transport.Token, error) {
+		refreshed++
+		if token.RefreshToken != "refresh" {
+			t.Errorf("refreshing with %+v", token)
+		}
+		return &transport.Token{AccessToken: "fresh-token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+	for range 2 {
+		if token, err := store.GetToken(context.Background()); err != nil || token.AccessToken != "fresh-token" {
+			t.Fatalf("GetToken = %v, %v, want the refreshed token", token, err)
+		}
+	}
+	if refreshed != 1 {
+		t.Errorf("refreshed %d times, want once", refreshed)
+	}
+
+	// The refreshed token is cached for the next run, with its client
+	entries, err := loadTokenCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := entries[oauthBaseURL(serverURL)]; entry.Token.AccessToken != "fresh-token" || entry.ClientID != "probe-client" {
+		t.Errorf("cached entry = %+v, want the refreshed token", entry)
+	}
+}
+
+func TestNoTokenCacheWrites(t *testing.T) {
+	useTempTokenCache(t)
+	store := newOAuthTokenStore("https://mcp.example.com/mcp", "probe-client", nil, false)
+	if err := store.SaveToken(context.Background(), &transport.Token{AccessToken: "new-token"}); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := store.GetToken(context.Background()); err != nil || token.AccessToken != "new-token" {
+		t.Errorf("GetToken = %v, %v, want the saved token for this run", token, err)
+	}
+	entries, err := loadTokenCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("-no-token-cache wrote the cache: %+v", entries)
+	}
+}
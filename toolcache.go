@@ -0,0 +1,107 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolCacheName identifies the profile or saved server in use, e.g.
+// "profile-dev" or "server-prod". Tool listings are only cached when it is set.
+var toolCacheName string
+
+// toolSnapshot is the cached tools/list result of a profile or saved server
+type toolSnapshot struct {
+	Target  string     `json:"target"`
+	SavedAt time.Time  `json:"savedAt"`
+	Tools   []mcp.Tool `json:"tools"`
+}
+
+// cachedTools is a tool snapshot as read back from the cache, with the tools
+// in the form the coverage report uses, which keeps schemas as plain JSON
+type cachedTools struct {
+	Target  string       `json:"target"`
+	SavedAt time.Time    `json:"savedAt"`
+	Tools   []listedTool `json:"tools"`
+}
+
+// toolCachePath returns the snapshot file of a profile or saved server
+func toolCachePath(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "mcpprobe", "tools", sanitizeFileName(name)+".json"), nil
+}
+
+// cacheTools saves the tools a server listed for the profile or saved server
+// in use, for shell completion. Failures only produce a warning.
+func cacheTools(tools []mcp.Tool) {
+	if toolCacheName == "" {
+		return
+	}
+	report.mu.Lock()
+	target := report.Target
+	report.mu.Unlock()
+	if err := saveToolSnapshot(toolCacheName, &toolSnapshot{Target: target, SavedAt: time.Now().UTC(), Tools: tools}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", report.addWarning(warningProbe, "failed to cache tool list: %v", err))
+	}
+}
+
+// saveToolSnapshot writes a tool snapshot to the cache
+func saveToolSnapshot(name string, snapshot *toolSnapshot) error {
+	path, err := toolCachePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tool list: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write tool cache: %w", err)
+	}
+	return nil
+}
+
+// loadToolSnapshot reads the cached tools of a profile or saved server. A
+// missing cache is not an error; nil is returned.
+func loadToolSnapshot(name string) (*cachedTools, error) {
+	path, err := toolCachePath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read tool cache: %w", err)
+	}
+	var cached cachedTools
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &cached, nil
+}
+
+// find returns a cached tool by name, or nil
+func (c *cachedTools) find(name string) *listedTool {
+	for i := range c.Tools {
+		if c.Tools[i].Name == name {
+			return &c.Tools[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ungroupedTools is the group of tools without a category or shared prefix
+const ungroupedTools = "other"
+
+// groupToolListings is set by -group to list tools by category
+var groupToolListings bool
+
+// expandedToolGroups are the groups whose tools are listed in full detail
+// with -group; the others only list tool names. "all" expands every group.
+var expandedToolGroups = map[string]bool{}
+
+// toolGroup is a category of tools; Indexes are positions in the tool list,
+// so tools keep their numbers when grouped
+type toolGroup struct {
+	Name    string
+	Indexes []int
+}
+
+// toolCategory returns the category a server gives a tool in its _meta, as
+// "category" or "group", or a vendor key such as "com.example/category"
+func toolCategory(tool mcp.Tool) string {
+	if tool.Meta == nil {
+		return ""
+	}
+	for _, key := range sortedAnyKeys(tool.Meta.AdditionalFields) {
+		if key != "category" && key != "group" && !strings.HasSuffix(key, "/category") {
+			continue
+		}
+		if category, ok := tool.Meta.AdditionalFields[key].(string); ok && strings.TrimSpace(category) != "" {
+			return strings.TrimSpace(category)
+		}
+	}
+	return ""
+}
+
+// toolNamePrefix returns the part of a tool name before the first '_', '.'
+// or '/', or "" if there is none
+func toolNamePrefix(name string) string {
+	if i := strings.IndexAny(name, "_./"); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// groupTools groups tools by their annotated category or, failing that, by
+// a name prefix shared with at least one other tool. Groups are sorted by
+// name with the ungrouped tools last.
+func groupTools(tools []mcp.Tool) []toolGroup {
+	prefixCount := map[string]int{}
+	for _, tool := range tools {
+		if toolCategory(tool) == "" {
+			prefixCount[toolNamePrefix(tool.Name)]++
+		}
+	}
+
+	byName := map[string]*toolGroup{}
+	var names []string
+	for i, tool := range tools {
+		name := toolCategory(tool)
+		if name == "" {
+			name = toolNamePrefix(tool.Name)
+			if name == "" || prefixCount[name] < 2 {
+				name = ungroupedTools
+			}
+		}
+		if byName[name] == nil {
+			byName[name] = &toolGroup{Name: name}
+			names = append(names, name)
+		}
+		byName[name].Indexes = append(byName[name].Indexes, i)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == ungroupedTools) != (names[j] == ungroupedTools) {
+			return names[j] == ungroupedTools
+		}
+		return names[i] < names[j]
+	})
+	groups := make([]toolGroup, len(names))
+	for i, name := range names {
+		groups[i] = *byName[name]
+	}
+	return groups
+}
+
+// findToolGroup returns the group with the given name, or nil
+func findToolGroup(groups []toolGroup, name string) *toolGroup {
+	for i := range groups {
+		if strings.EqualFold(groups[i].Name, name) {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+// setExpandedToolGroups parses -expand-groups
+func setExpandedToolGroups(spec string) {
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			expandedToolGroups[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// toolGroupExpanded reports whether a group is listed in full detail
+func toolGroupExpanded(name string) bool {
+	return expandedToolGroups["all"] || expandedToolGroups[strings.ToLower(name)]
+}
+
+// printGroupedTools lists tools under a heading per group with its count.
+// Tools of expanded groups are printed with detail, the others with brief;
+// a nil brief prints every tool with detail. It reports whether any group
+// was collapsed.
+func printGroupedTools(tools []mcp.Tool, detail, brief func(i int, tool mcp.Tool)) bool {
+	groups := groupTools(tools)
+	collapsed := false
+	for _, group := range groups {
+		expanded := brief == nil || toolGroupExpanded(group.Name)
+		collapsed = collapsed || !expanded
+		fmt.Printf("[%s] (%d tool%s)\n", group.Name, len(group.Indexes), pluralS(len(group.Indexes)))
+		for _, i := range group.Indexes {
+			if expanded {
+				detail(i, tools[i])
+			} else {
+				brief(i, tools[i])
+			}
+		}
+	}
+	fmt.Printf("%d tool%s in %d group%s\n", len(tools), pluralS(len(tools)), len(groups), pluralS(len(groups)))
+	return collapsed
+}
+
+// pluralS returns "s" unless n is 1
+func pluralS(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
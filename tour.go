@@ -0,0 +1,282 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// tourSteps is the number of steps of the tour
+const tourSteps = 5
+
+// tourCommandArgs turns "tour [flags]" into the equivalent -tour flag, so
+// that the tour connects with the probe's usual connection options
+func tourCommandArgs(args []string) []string {
+	return append([]string{args[0], "-tour"}, args[2:]...)
+}
+
+// tourGuide walks through the steps of the tour, pausing between them when
+// someone is at the terminal
+type tourGuide struct {
+	scanner *bufio.Scanner
+	pause   bool
+	step    int
+}
+
+// begin starts the next step. It returns false if the user stopped the tour.
+func (g *tourGuide) begin(title string) bool {
+	if g.step > 0 && g.pause {
+		fmt.Print("\nPress Enter to continue, or q to stop the tour: ")
+		if !g.scanner.Scan() || strings.EqualFold(strings.TrimSpace(g.scanner.Text()), "q") {
+			return false
+		}
+	}
+	g.step++
+	fmt.Printf("\n--- Step %d of %d: %s ---\n", g.step, tourSteps, title)
+	return true
+}
+
+// explain prints an explanation of an MCP concept, set off from the
+// server's own output
+func (g *tourGuide) explain(lines ...string) {
+	fmt.Println()
+	for _, line := range lines {
+		fmt.Printf("  | %s\n", line)
+	}
+}
+
+// runTour implements the tour command: it explains the connection and the
+// handshake the probe just made, the server's capabilities and tools, and
+// makes a safe tool call, introducing each MCP concept as it appears
+func runTour(mcpClient *client.Client, transportName string, timeout, callTimeout time.Duration) error {
+	fmt.Println("\n=== Tour of an MCP Server ===")
+	g := &tourGuide{scanner: stdinScanner(), pause: stdinIsTerminal()}
+	fmt.Println("This tour explains what the probe does at each step of a session with the server.")
+	if !g.pause {
+		fmt.Println("(Standard input is not a terminal, so the tour runs without pausing and skips questions.)")
+	}
+
+	report.mu.Lock()
+	target := report.Target
+	serverInfo := report.ServerInfo
+	requested, negotiated := report.RequestedProtocolVersion, report.ProtocolVersion
+	caps := report.Capabilities
+	instructions := report.Instructions
+	report.mu.Unlock()
+
+	g.begin("Connecting")
+	fmt.Printf("Connected to %s over %s\n", target, transportName)
+	switch transportName {
+	case "stdio":
+		g.explain(
+			"MCP messages are JSON-RPC 2.0: requests with an id that expect a response,",
+			"and notifications without one. Over stdio the probe started the server as",
+			"a child process and exchanges one JSON message per line on its standard",
+			"input and output. The server's standard error is for its own logs.")
+	case "sse":
+		g.explain(
+			"MCP messages are JSON-RPC 2.0: requests with an id that expect a response,",
+			"and notifications without one. With the SSE transport (from the 2024-11-05",
+			"specification) the probe holds open a Server-Sent Events stream, on which the",
+			"server sends its messages, and POSTs its own messages to a URL the server",
+			"announced on that stream.")
+	default:
+		g.explain(
+			"MCP messages are JSON-RPC 2.0: requests with an id that expect a response,",
+			"and notifications without one. With the streamable HTTP transport the probe",
+			"POSTs each message to a single endpoint. The server answers with JSON, or",
+			"with a stream of events when it has more to send, such as progress. An",
+			"Mcp-Session-Id header ties the requests of one session together.")
+	}
+
+	if !g.begin("The initialization handshake") {
+		return nil
+	}
+	if serverInfo != nil {
+		fmt.Printf("Server: %s %s\n", serverInfo.Name, serverInfo.Version)
+	}
+	fmt.Printf("Protocol version: requested %s, negotiated %s\n", requested, negotiated)
+	if instructions != "" {
+		fmt.Printf("Instructions: %s\n", truncateText(instructions, 200))
+	}
+	g.explain(
+		"Every session starts with an initialize request. The client sends the newest",
+		"protocol version it supports, its name and what it can do for the server; the",
+		"server answers with the version to use, which may be older, its own name and",
+		"its capabilities. The client then sends notifications/initialized, and only",
+		"then does the session begin. A server may also send instructions: hints for",
+		"the model on how to use it.")
+
+	if !g.begin("Capabilities") {
+		return nil
+	}
+	names := advertisedCapabilities(caps)
+	if len(names) == 0 {
+		fmt.Println("The server advertises no capabilities")
+	} else {
+		fmt.Printf("The server advertises: %s\n", strings.Join(names, ", "))
+	}
+	g.explain(
+		"Capabilities say which features of the protocol the server offers. A client",
+		"only uses what is advertised:",
+		"  tools        functions the model can call, such as a search or a lookup",
+		"  resources    data the client can read by URI, such as files or records",
+		"  prompts      templates of messages that users pick, such as slash commands",
+		"  logging      log messages the client can ask for with logging/setLevel",
+		"  completions  suggestions for prompt and resource template arguments",
+		"listChanged means the server notifies the client when the list changes, and",
+		"resources.subscribe means the client can watch a resource for updates.")
+
+	if !g.begin("Listing tools") {
+		return nil
+	}
+	if caps.Tools == nil {
+		fmt.Println("The server does not advertise tools, so the tour ends here.")
+		printTourNextSteps()
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	toolsResult, err := listTools(ctx, mcpClient)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to list tools: %w", err)
+	}
+	for i, tool := range toolsResult.Tools {
+		line := fmt.Sprintf("  %02d: %s %s", i+1, toolLabel(tool), formatToolAnnotations(tool.Annotations))
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	if len(toolsResult.Tools) == 0 {
+		fmt.Println("  (No tools available)")
+	}
+	g.explain(
+		"tools/list returns each tool's name, a description written for the model and",
+		"an inputSchema: a JSON Schema of the arguments, which says which are required",
+		"and their types. The model reads the description to decide when to call a",
+		"tool and the schema to decide what to send. Long lists come in pages, each",
+		"ending with a nextCursor for the next. Annotations such as readOnlyHint and",
+		"destructiveHint describe a tool's behavior; they are hints from the server, so",
+		"clients should only trust them from servers they trust.")
+
+	if !g.begin("A safe tool call") {
+		return nil
+	}
+	tool := g.chooseSafeTool(toolsResult.Tools)
+	if tool == nil {
+		printTourNextSteps()
+		return nil
+	}
+	if err := g.callTool(mcpClient, tool, callTimeout); err != nil {
+		fmt.Printf("The call failed: %v\n", err)
+	}
+	g.explain(
+		"tools/call sends the tool's name and arguments. The result holds content",
+		"blocks, usually text but also images, audio or embedded resources, and may",
+		"hold structuredContent that follows the tool's outputSchema. A tool that",
+		"fails reports isError in its result, so that the model can see the error and",
+		"try again; a JSON-RPC error instead means the request itself was wrong, such",
+		"as an unknown tool.")
+	printTourNextSteps()
+	return nil
+}
+
+// chooseSafeTool picks the tool to call: one the server marks as read-only,
+// chosen by the user when there are several. Without a read-only tool the
+// user may pick any tool, after a warning; without a terminal no call is
+// made.
+func (g *tourGuide) chooseSafeTool(tools []mcp.Tool) *mcp.Tool {
+	var safe []*mcp.Tool
+	for i := range tools {
+		if a := tools[i].Annotations; a.ReadOnlyHint != nil && *a.ReadOnlyHint {
+			safe = append(safe, &tools[i])
+		}
+	}
+
+	if len(safe) == 0 {
+		fmt.Println("No tool is marked read-only (readOnlyHint), so the probe cannot tell which calls are safe.")
+		if !g.pause || len(tools) == 0 {
+			fmt.Println("Skipping the call.")
+			return nil
+		}
+		fmt.Print("Name a tool you know is safe to call, or press Enter to skip: ")
+		if !g.scanner.Scan() {
+			return nil
+		}
+		tool := findInteractiveTool(tools, strings.TrimSpace(g.scanner.Text()))
+		if tool == nil {
+			fmt.Println("Skipping the call.")
+			return nil
+		}
+		if !confirmDestructiveCall(tool, g.scanner) {
+			return nil
+		}
+		return tool
+	}
+
+	fmt.Println("Tools the server marks as read-only, which should not change anything:")
+	for i, tool := range safe {
+		fmt.Printf("  %d. %s\n", i+1, toolLabel(*tool))
+	}
+	if !g.pause || len(safe) == 1 {
+		return safe[0]
+	}
+	fmt.Printf("Which one should the probe call? [1-%d, Enter for 1]: ", len(safe))
+	if !g.scanner.Scan() {
+		return nil
+	}
+	answer := strings.TrimSpace(g.scanner.Text())
+	if answer == "" {
+		return safe[0]
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(safe) {
+		return safe[n-1]
+	}
+	fmt.Println("Not a listed number; calling the first.")
+	return safe[0]
+}
+
+// callTool calls the chosen tool, asking for its arguments when someone is
+// at the terminal. Without a terminal only tools without required arguments
+// are called.
+func (g *tourGuide) callTool(mcpClient *client.Client, tool *mcp.Tool, callTimeout time.Duration) error {
+	args := map[string]any{}
+	if g.pause {
+		var err error
+		if args, err = collectToolParameters(tool, g.scanner); err != nil {
+			return err
+		}
+	} else if _, required := toolInputSchema(tool); len(required) > 0 {
+		fmt.Printf("'%s' has required arguments (%s) and there is no one to ask; skipping the call.\n", tool.Name, strings.Join(getRequiredParamsList(required), ", "))
+		return nil
+	}
+
+	displayToolRequest(tool.Name, args, true)
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	start := time.Now()
+	result, err := callToolRecorded(ctx, mcpClient, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: tool.Name, Arguments: args}})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Answered after %s\n", humanDuration(time.Since(start)))
+	formatToolResult(result, true)
+	return nil
+}
+
+// printTourNextSteps suggests where to go after the tour
+func printTourNextSteps() {
+	fmt.Println("\n=== Next Steps ===")
+	fmt.Println("  -list-only      every tool with its description, annotations and schema")
+	fmt.Println("  -interactive    call tools with prompts for their arguments")
+	fmt.Println("  -debug          show the raw JSON-RPC messages of a session")
+	fmt.Println("  conformance     score the server against the specification")
+	fmt.Println("  protocols       the protocol versions and what each introduced")
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ANSI colors of the wire trace
+const (
+	traceReset  = "\033[0m"
+	traceBold   = "\033[1m"
+	traceKey    = "\033[36m"
+	traceString = "\033[32m"
+	traceNumber = "\033[33m"
+	traceWord   = "\033[35m"
+	traceError  = "\033[31m"
+)
+
+// wireTracer prints JSON-RPC messages as they are sent and received, for
+// -trace, the proxy's -pretty and the gateway. Each message is headed by its
+// direction, the time since the trace started and, for responses, the time
+// since the request.
+type wireTracer struct {
+	mu sync.Mutex
+	// out is where the trace is printed; nil is whatever stdout is at the
+	// time, which may be the -tee pipe
+	out     io.Writer
+	start   time.Time
+	color   bool
+	pending map[string]time.Time
+}
+
+// newWireTracer creates a tracer printing to stdout
+func newWireTracer() *wireTracer {
+	t := newWireTracerTo(os.Stdout)
+	t.out = nil
+	return t
+}
+
+// newWireTracerTo creates a tracer printing to a file, colorizing its output
+// if the file is a terminal and NO_COLOR is not set
+func newWireTracerTo(file *os.File) *wireTracer {
+	return &wireTracer{
+		out:     file,
+		start:   time.Now(),
+		color:   isTerminal(file) && os.Getenv("NO_COLOR") == "",
+		pending: map[string]time.Time{},
+	}
+}
+
+// isTerminal reports whether a file is a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// traceMessages prints each JSON-RPC message in body, which may be a single
+// message or a batch
+func (t *wireTracer) traceMessages(rec sessionRecord, body []byte) {
+	if !json.Valid(body) {
+		t.print(rec, "not JSON", "", string(body))
+		return
+	}
+	var batch []json.RawMessage
+	if body[0] == '[' && json.Unmarshal(body, &batch) == nil {
+		for i, msg := range batch {
+			t.traceMessage(rec, msg, fmt.Sprintf(" (batch %d/%d)", i+1, len(batch)))
+		}
+		return
+	}
+	t.traceMessage(rec, body, "")
+}
+
+// traceMessage prints one message, naming its kind, ID and method
+func (t *wireTracer) traceMessage(rec sessionRecord, msg json.RawMessage, suffix string) {
+	var m struct {
+		jsonrpcMessage
+		Error json.RawMessage `json:"error,omitempty"`
+	}
+	_ = json.Unmarshal(msg, &m)
+	id := string(m.ID)
+	// The requests of each side are answered by the other. Requests are
+	// kept by session, as IDs restart in every session, except that the
+	// response to initialize is the first to carry its session.
+	key := rec.Direction + " " + rec.Session + " " + id
+	answers := directionClient
+	if rec.Direction == directionClient {
+		answers = directionServer
+	}
+
+	var label string
+	now := time.Now()
+	t.mu.Lock()
+	switch {
+	case m.Method != "" && id != "":
+		label = fmt.Sprintf("request %s %s", id, m.Method)
+		t.pending[key] = now
+	case m.Method != "":
+		label = "notification " + m.Method
+	default:
+		label = "response " + id
+		if len(m.Error) > 0 {
+			label = "error response " + id
+		}
+		for _, request := range []string{answers + " " + rec.Session + " " + id, answers + "  " + id} {
+			if sent, ok := t.pending[request]; ok {
+				label += fmt.Sprintf(" after %s", humanDuration(now.Sub(sent)))
+				delete(t.pending, request)
+				break
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, msg, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(msg)
+	}
+	t.print(rec, label+suffix, "", pretty.String())
+}
+
+// traceError prints a failed request or an HTTP error without a JSON-RPC body
+func (t *wireTracer) traceError(rec sessionRecord) {
+	t.print(rec, "error", rec.Error, "")
+}
+
+// print writes a traced message: a header line with the direction arrow,
+// the elapsed time, the label and the transport details, then the body
+func (t *wireTracer) print(rec sessionRecord, label, problem, body string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	arrow := "→"
+	if rec.Direction == directionServer {
+		arrow = "←"
+	}
+	header := fmt.Sprintf("%s +%s  %s", arrow, humanDuration(time.Since(t.start)), label)
+	var details []string
+	if rec.HTTPMethod != "" && rec.Direction == directionClient {
+		details = append(details, rec.HTTPMethod+" "+rec.Path)
+	}
+	if rec.Status != 0 {
+		details = append(details, fmt.Sprintf("HTTP %d", rec.Status))
+	}
+	if len(details) > 0 {
+		header += "  [" + strings.Join(details, ", ") + "]"
+	}
+
+	var out strings.Builder
+	if t.color {
+		out.WriteString(traceBold + header + traceReset + "\n")
+	} else {
+		out.WriteString(header + "\n")
+	}
+	if problem != "" {
+		if t.color {
+			out.WriteString(traceError + problem + traceReset + "\n")
+		} else {
+			out.WriteString(problem + "\n")
+		}
+	}
+	if body != "" {
+		if t.color {
+			body = colorizeJSON(body)
+		}
+		out.WriteString(body + "\n")
+	}
+	out.WriteString("\n")
+	if t.out == nil {
+		fmt.Print(out.String())
+		return
+	}
+	_, _ = io.WriteString(t.out, out.String())
+}
+
+// colorizeJSON colors the keys, strings, numbers and literals of indented
+// JSON with ANSI escape sequences
+func colorizeJSON(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(text))
+			color := traceString
+			if rest := strings.TrimLeft(text[end:], " "); strings.HasPrefix(rest, ":") {
+				color = traceKey
+			}
+			out.WriteString(color + text[i:end] + traceReset)
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			out.WriteString(traceNumber + text[i:end] + traceReset)
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i
+			for end < len(text) && text[end] >= 'a' && text[end] <= 'z' {
+				end++
+			}
+			out.WriteString(traceWord + text[i:end] + traceReset)
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
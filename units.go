@@ -0,0 +1,78 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// byteUnits are the units of humanBytes, in steps of 1000
+var byteUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+// humanDuration formats a duration for people: sub-millisecond durations in
+// microseconds, milliseconds with up to one decimal below 10ms and whole ones
+// above, seconds with up to one decimal, and longer durations in minutes and
+// seconds or hours and minutes
+func humanDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	switch {
+	case d < time.Millisecond:
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	case d < 10*time.Millisecond:
+		return oneDecimal(float64(d)/float64(time.Millisecond)) + "ms"
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return oneDecimal(d.Seconds()) + "s"
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// oneDecimal formats a number with one decimal, leaving out a zero decimal
+func oneDecimal(value float64) string {
+	return strconv.FormatFloat(math.Round(value*10)/10, 'f', -1, 64)
+}
+
+// humanAgo describes how long ago a time was, e.g. "2.3s ago"
+func humanAgo(t time.Time) string {
+	return humanDuration(time.Since(t)) + " ago"
+}
+
+// humanBytes formats a byte count for people in decimal units: whole bytes
+// below 1 KB and one decimal above, e.g. "512 B" or "1.4 MB"
+func humanBytes(n int64) string {
+	if n < 1000 && n > -1000 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	unit := 0
+	for (value >= 999.95 || value <= -999.95) && unit < len(byteUnits)-1 {
+		value /= 1000
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, byteUnits[unit])
+}
+
+// humanCount formats a count with thousands separators, e.g. "12,345"
+func humanCount(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return sign + digits
+}
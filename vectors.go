@@ -0,0 +1,486 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Test vector bundle format identifiers
+const (
+	vectorFormat  = "mcpprobe-test-vectors"
+	vectorVersion = 1
+
+	// vectorRequestIDBase keeps the IDs of raw vector requests clear of the
+	// IDs the client assigns to its own requests
+	vectorRequestIDBase = 1_000_000
+)
+
+// Outcomes a test vector can expect
+const (
+	outcomeResult = "result"
+	outcomeError  = "error"
+)
+
+// testVectorBundle is a language-neutral bundle of conformance test vectors.
+// Each vector is one JSON-RPC request and the outcome a conforming server
+// produces, so that implementations in any language can run the same checks.
+type testVectorBundle struct {
+	Format    string       `json:"format"`
+	Version   int          `json:"version"`
+	Generator string       `json:"generator,omitempty"`
+	Vectors   []testVector `json:"vectors"`
+}
+
+// testVector is a single request and its expected outcome. Vectors other than
+// initialize are sent in a session that has completed initialization; a
+// vector that requires a capability is skipped if the server does not
+// advertise it.
+type testVector struct {
+	ID          string        `json:"id"`
+	Description string        `json:"description"`
+	Spec        string        `json:"spec,omitempty"`
+	Requires    string        `json:"requires,omitempty"`
+	Request     vectorRequest `json:"request"`
+	Expect      vectorExpect  `json:"expect"`
+}
+
+// vectorRequest is the JSON-RPC request a vector sends
+type vectorRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// vectorExpect is the expected outcome of a vector. Paths are dot separated
+// keys (and array indices) into the result, or into the error object for an
+// error outcome; types are JSON type names.
+type vectorExpect struct {
+	Outcome    string            `json:"outcome"`
+	ErrorCodes []int             `json:"errorCodes,omitempty"`
+	Types      map[string]string `json:"types,omitempty"`
+	Equals     map[string]any    `json:"equals,omitempty"`
+}
+
+// builtinTestVectors returns the conformance checks that MCPProbe exports
+func builtinTestVectors() testVectorBundle {
+	initParams, _ := json.Marshal(newInitializeRequest().Params)
+	return testVectorBundle{
+		Format:    vectorFormat,
+		Version:   vectorVersion,
+		Generator: ProgName + " " + ProgVer,
+		Vectors: []testVector{
+			{
+				ID:          "initialize-result",
+				Description: "initialize returns the protocol version, capabilities and server info",
+				Spec:        "basic/lifecycle",
+				Request:     vectorRequest{Method: string(mcp.MethodInitialize), Params: initParams},
+				Expect: vectorExpect{Outcome: outcomeResult, Types: map[string]string{
+					"protocolVersion": "string",
+					"capabilities":    "object",
+					"serverInfo":      "object",
+					"serverInfo.name": "string",
+				}},
+			},
+			{
+				ID:          "ping",
+				Description: "ping returns an empty result",
+				Spec:        "basic/utilities/ping",
+				Request:     vectorRequest{Method: string(mcp.MethodPing)},
+				Expect:      vectorExpect{Outcome: outcomeResult, Types: map[string]string{"": "object"}},
+			},
+			{
+				ID:          "unknown-method",
+				Description: "an unknown method is rejected with 'method not found'",
+				Spec:        "basic",
+				Request:     vectorRequest{Method: "mcpprobe/no-such-method"},
+				Expect:      vectorExpect{Outcome: outcomeError, ErrorCodes: []int{mcp.METHOD_NOT_FOUND}},
+			},
+			{
+				ID:          "tools-list",
+				Description: "tools/list returns an array of tools",
+				Spec:        "server/tools",
+				Requires:    "tools",
+				Request:     vectorRequest{Method: string(mcp.MethodToolsList), Params: json.RawMessage(`{}`)},
+				Expect:      vectorExpect{Outcome: outcomeResult, Types: map[string]string{"tools": "array"}},
+			},
+			{
+				ID:          "tools-call-unknown-tool",
+				Description: "calling an unknown tool is rejected with 'invalid params'",
+				Spec:        "server/tools",
+				Requires:    "tools",
+				Request:     vectorRequest{Method: string(mcp.MethodToolsCall), Params: json.RawMessage(`{"name":"mcpprobe-no-such-tool","arguments":{}}`)},
+				Expect:      vectorExpect{Outcome: outcomeError, ErrorCodes: []int{mcp.INVALID_PARAMS}},
+			},
+			{
+				ID:          "resources-list",
+				Description: "resources/list returns an array of resources",
+				Spec:        "server/resources",
+				Requires:    "resources",
+				Request:     vectorRequest{Method: string(mcp.MethodResourcesList), Params: json.RawMessage(`{}`)},
+				Expect:      vectorExpect{Outcome: outcomeResult, Types: map[string]string{"resources": "array"}},
+			},
+			{
+				ID:          "resources-templates-list",
+				Description: "resources/templates/list returns an array of resource templates",
+				Spec:        "server/resources",
+				Requires:    "resources",
+				Request:     vectorRequest{Method: string(mcp.MethodResourcesTemplatesList), Params: json.RawMessage(`{}`)},
+				Expect:      vectorExpect{Outcome: outcomeResult, Types: map[string]string{"resourceTemplates": "array"}},
+			},
+			{
+				ID:          "resources-read-unknown",
+				Description: "reading an unknown resource is rejected with 'resource not found'",
+				Spec:        "server/resources",
+				Requires:    "resources",
+				Request:     vectorRequest{Method: string(mcp.MethodResourcesRead), Params: json.RawMessage(`{"uri":"mcpprobe://no-such-resource"}`)},
+				Expect:      vectorExpect{Outcome: outcomeError, ErrorCodes: []int{mcp.RESOURCE_NOT_FOUND}},
+			},
+			{
+				ID:          "prompts-list",
+				Description: "prompts/list returns an array of prompts",
+				Spec:        "server/prompts",
+				Requires:    "prompts",
+				Request:     vectorRequest{Method: string(mcp.MethodPromptsList), Params: json.RawMessage(`{}`)},
+				Expect:      vectorExpect{Outcome: outcomeResult, Types: map[string]string{"prompts": "array"}},
+			},
+			{
+				ID:          "prompts-get-unknown",
+				Description: "getting an unknown prompt is rejected with 'invalid params'",
+				Spec:        "server/prompts",
+				Requires:    "prompts",
+				Request:     vectorRequest{Method: string(mcp.MethodPromptsGet), Params: json.RawMessage(`{"name":"mcpprobe-no-such-prompt"}`)},
+				Expect:      vectorExpect{Outcome: outcomeError, ErrorCodes: []int{mcp.INVALID_PARAMS}},
+			},
+			{
+				ID:          "logging-set-level",
+				Description: "logging/setLevel accepts a valid level",
+				Spec:        "server/utilities/logging",
+				Requires:    "logging",
+				Request:     vectorRequest{Method: string(mcp.MethodSetLogLevel), Params: json.RawMessage(`{"level":"info"}`)},
+				Expect:      vectorExpect{Outcome: outcomeResult},
+			},
+		},
+	}
+}
+
+// exportTestVectors writes the built-in test vectors to a file, or to stdout for "-"
+func exportTestVectors(path string) error {
+	data, err := json.MarshalIndent(builtinTestVectors(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode test vectors: %w", err)
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = resultOut.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write test vectors: %w", err)
+	}
+	fmt.Printf("Wrote %d test vectors to %s\n", len(builtinTestVectors().Vectors), path)
+	return nil
+}
+
+// loadTestVectors reads and validates a test vector bundle
+func loadTestVectors(path string) (*testVectorBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test vectors: %w", err)
+	}
+	var bundle testVectorBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if bundle.Format != vectorFormat {
+		return nil, fmt.Errorf("%s is not a test vector bundle (format '%s', expected '%s')", path, bundle.Format, vectorFormat)
+	}
+	if bundle.Version != vectorVersion {
+		return nil, fmt.Errorf("unsupported test vector bundle version %d (supported: %d)", bundle.Version, vectorVersion)
+	}
+	seen := map[string]bool{}
+	for i, v := range bundle.Vectors {
+		switch {
+		case v.ID == "":
+			return nil, fmt.Errorf("vector %d has no id", i+1)
+		case seen[v.ID]:
+			return nil, fmt.Errorf("duplicate vector id '%s'", v.ID)
+		case v.Request.Method == "":
+			return nil, fmt.Errorf("vector '%s' has no request method", v.ID)
+		case v.Expect.Outcome != outcomeResult && v.Expect.Outcome != outcomeError:
+			return nil, fmt.Errorf("vector '%s': outcome must be '%s' or '%s'", v.ID, outcomeResult, outcomeError)
+		}
+		for path, typ := range v.Expect.Types {
+			switch typ {
+			case "object", "array", "string", "number", "boolean", "null":
+			default:
+				return nil, fmt.Errorf("vector '%s': unknown type '%s' for '%s'", v.ID, typ, path)
+			}
+		}
+		seen[v.ID] = true
+	}
+	if len(bundle.Vectors) == 0 {
+		return nil, fmt.Errorf("%s contains no test vectors", path)
+	}
+	return &bundle, nil
+}
+
+// runTestVectors verifies a server against a test vector bundle and prints
+// the results. It returns an error if any vector failed.
+func runTestVectors(dial func(ctx context.Context) (*client.Client, error), bundle *testVectorBundle, source string, timeout time.Duration) error {
+	fmt.Printf("=== Test Vectors (%d from %s) ===\n", len(bundle.Vectors), source)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Duration(len(bundle.Vectors)+1))
+	defer cancel()
+
+	mcpClient, err := dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() { _ = mcpClient.Close() }()
+	initResult, err := mcpClient.Initialize(ctx, newInitializeRequest())
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	width := 0
+	for _, v := range bundle.Vectors {
+		width = max(width, len(v.ID))
+	}
+	var summaries []checkSummary
+	passed, failed, skipped, suppressed := 0, 0, 0, 0
+	for i, v := range bundle.Vectors {
+		summary := checkSummary{ID: v.ID, Runs: 1}
+		reason := ""
+		var f *finding
+		if v.Requires != "" && !hasCapability(initResult.Capabilities, v.Requires) {
+			summary.Status, summary.Skipped = checkSkip, 1
+			reason = fmt.Sprintf("server does not advertise %s", v.Requires)
+			skipped++
+		} else {
+			start := time.Now()
+			err := runTestVector(ctx, dial, mcpClient, v, vectorRequestIDBase+i, timeout)
+			summary.AvgTime = time.Since(start)
+			if err != nil {
+				summary.Status, summary.Failed = checkFail, 1
+				summary.Errors = []string{err.Error()}
+				reason = err.Error()
+				vf := report.addFinding(checkIDTestVector, v.ID, "test vector %s failed", v.ID)
+				f = &vf
+				summary.Suppressed = vf.Suppressed
+				if vf.Suppressed {
+					suppressed++
+				} else {
+					failed++
+				}
+			} else {
+				summary.Status, summary.Passed = checkPass, 1
+				passed++
+			}
+		}
+		summaries = append(summaries, summary)
+		emitEvent(eventCheck, map[string]any{
+			"id":            v.ID,
+			"status":        summary.Status,
+			"description":   v.Description,
+			"avgDurationMs": durationMillis(summary.AvgTime),
+			"errors":        summary.Errors,
+		})
+
+		line := fmt.Sprintf("  %-5s  %-*s  %s", strings.ToUpper(summary.Status), width, v.ID, v.Description)
+		fmt.Println(line)
+		if f != nil {
+			fmt.Printf("         %s\n", f)
+		}
+		if reason != "" {
+			fmt.Printf("         %s\n", reason)
+		}
+	}
+	report.setChecks(summaries)
+
+	fmt.Printf("\n%d vectors: %d passed, %d failed, %d skipped", len(bundle.Vectors), passed, failed, skipped)
+	if suppressed > 0 {
+		fmt.Printf(", %d suppressed", suppressed)
+	}
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test vectors failed", failed, len(bundle.Vectors))
+	}
+	return nil
+}
+
+// runTestVector sends one vector's request and checks the response.
+// initialize is sent on a fresh connection, as a session initializes only once.
+func runTestVector(ctx context.Context, dial func(ctx context.Context) (*client.Client, error), session *client.Client, v testVector, id int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	target := session
+	if v.Request.Method == string(mcp.MethodInitialize) {
+		fresh, err := dial(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer func() { _ = fresh.Close() }()
+		target = fresh
+	}
+
+	request := transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(int64(id)),
+		Method:  v.Request.Method,
+	}
+	if len(v.Request.Params) > 0 {
+		request.Params = v.Request.Params
+	}
+	response, err := target.GetTransport().SendRequest(ctx, request)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	return checkVectorResponse(v.Expect, response)
+}
+
+// checkVectorResponse compares a response with the expected outcome
+func checkVectorResponse(expect vectorExpect, response *transport.JSONRPCResponse) error {
+	var subject any
+	switch {
+	case expect.Outcome == outcomeError && response.Error == nil:
+		return fmt.Errorf("expected an error, got a result: %s", truncateWire(string(response.Result)))
+	case expect.Outcome == outcomeResult && response.Error != nil:
+		return fmt.Errorf("expected a result, got error %d: %s", response.Error.Code, response.Error.Message)
+	case expect.Outcome == outcomeError:
+		if len(expect.ErrorCodes) > 0 && !containsInt(expect.ErrorCodes, response.Error.Code) {
+			return fmt.Errorf("expected error code %s, got %d: %s", joinInts(expect.ErrorCodes), response.Error.Code, response.Error.Message)
+		}
+		data, _ := json.Marshal(response.Error)
+		_ = json.Unmarshal(data, &subject)
+	default:
+		if err := json.Unmarshal(response.Result, &subject); err != nil {
+			return fmt.Errorf("result is not valid JSON: %w", err)
+		}
+	}
+
+	var problems []string
+	for _, path := range sortedKeys(expect.Types) {
+		value, ok := valueAtPath(subject, path)
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("'%s' is missing", displayPath(path)))
+		case jsonTypeName(value) != expect.Types[path]:
+			problems = append(problems, fmt.Sprintf("'%s' is %s, expected %s", displayPath(path), jsonTypeName(value), expect.Types[path]))
+		}
+	}
+	for path, want := range expect.Equals {
+		value, ok := valueAtPath(subject, path)
+		if !ok || !reflect.DeepEqual(value, want) {
+			got, _ := json.Marshal(value)
+			expected, _ := json.Marshal(want)
+			problems = append(problems, fmt.Sprintf("'%s' is %s, expected %s", displayPath(path), got, expected))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// valueAtPath returns the value at a dot separated path; "" is the value itself
+func valueAtPath(value any, path string) (any, bool) {
+	if path == "" {
+		return value, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonTypeName returns the JSON type of a decoded value
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// displayPath names a path in messages
+func displayPath(path string) string {
+	if path == "" {
+		return "(response)"
+	}
+	return path
+}
+
+// hasCapability reports whether the server advertised a capability
+func hasCapability(caps mcp.ServerCapabilities, name string) bool {
+	switch name {
+	case "tools":
+		return caps.Tools != nil
+	case "resources":
+		return caps.Resources != nil
+	case "prompts":
+		return caps.Prompts != nil
+	case "logging":
+		return caps.Logging != nil
+	case "completions":
+		return caps.Completions != nil
+	default:
+		_, ok := caps.Experimental[name]
+		return ok
+	}
+}
+
+// containsInt reports whether list contains value
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// joinInts formats a list of alternatives such as "-32602 or -32601"
+func joinInts(list []int) string {
+	parts := make([]string, len(list))
+	for i, v := range list {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, " or ")
+}
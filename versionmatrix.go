@@ -0,0 +1,224 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// futureProtocolVersion is a protocol version no server can know, requested
+// by -version-matrix to see how the server negotiates versions newer than
+// its own
+const futureProtocolVersion = "2099-01-01"
+
+// versionMatrixRequestID is the ID of the -version-matrix requests, clear of
+// the IDs the client assigns and of the other raw requests
+const versionMatrixRequestID = 6_000_000
+
+// Results of an initialization in the version matrix
+const (
+	versionAccepted   = "accepted"
+	versionNegotiated = "negotiated"
+	versionEchoed     = "echoed"
+	versionRejected   = "rejected"
+	versionUnusable   = "unusable"
+)
+
+// versionMatrixEntry is how the server answered an initialization with one
+// protocol version
+type versionMatrixEntry struct {
+	Requested string        `json:"requested"`
+	Known     bool          `json:"known"`
+	Answered  string        `json:"answered,omitempty"`
+	Result    string        `json:"result"`
+	Detail    string        `json:"detail,omitempty"`
+	Duration  time.Duration `json:"durationNs"`
+}
+
+// HardFailure reports whether the entry is a failure to negotiate: the
+// specification asks a server to answer a version it does not support with
+// one it does, not with an error or by claiming the unknown version
+func (e versionMatrixEntry) HardFailure() bool {
+	return e.Result == versionRejected || e.Result == versionEchoed || e.Result == versionUnusable
+}
+
+// versionMatrixVersions returns the protocol versions -version-matrix
+// requests: every version the probe knows, oldest first, then one from the
+// future
+func versionMatrixVersions() []string {
+	versions := slices.Clone(mcp.ValidProtocolVersions)
+	sort.Strings(versions)
+	return append(versions, futureProtocolVersion)
+}
+
+// runVersionMatrix initializes a fresh session with each protocol version,
+// records what the server answers and prints a compatibility table. It
+// returns an error if the server failed to negotiate any version.
+func runVersionMatrix(dial func(ctx context.Context) (*client.Client, error), timeout time.Duration) error {
+	fmt.Println("=== Protocol Version Matrix ===")
+
+	var entries []versionMatrixEntry
+	for _, version := range versionMatrixVersions() {
+		entry := initializeWithVersion(dial, version, timeout)
+		entries = append(entries, entry)
+		emitEvent(eventVersionMatrix, map[string]any{
+			"requested":  entry.Requested,
+			"known":      entry.Known,
+			"answered":   entry.Answered,
+			"result":     entry.Result,
+			"detail":     entry.Detail,
+			"durationMs": durationMillis(entry.Duration),
+		})
+	}
+	report.setVersionMatrix(entries)
+
+	fmt.Printf("%-12s  %-11s  %-12s  %-9s  Detail\n", "Requested", "Result", "Answered", "Time")
+	hard, failures := 0, 0
+	for _, e := range entries {
+		requested := e.Requested
+		if !e.Known {
+			requested += "*"
+		}
+		line := fmt.Sprintf("%-12s  %-11s  %-12s  %-9s  %s", requested, e.Result, valueOr(e.Answered, "-"), humanDuration(e.Duration), e.Detail)
+		fmt.Println(strings.TrimRight(line, " "))
+		if e.HardFailure() {
+			hard++
+			if report.addFinding(checkIDVersionNegotiation, e.Requested, "requesting protocol version %s: %s", e.Requested, e.Detail).fails() {
+				failures++
+			}
+		}
+	}
+	fmt.Println("* a version from the future, which the server should answer with a version it supports")
+
+	if failures > 0 {
+		return fmt.Errorf("protocol version negotiation failed for %d version(s)", failures)
+	}
+	if hard > 0 {
+		fmt.Printf("\nWarning: %s\n", report.addWarning(warningCheck, "the server failed instead of negotiating %d version(s)", hard))
+		return nil
+	}
+	fmt.Println("\nThe server negotiated every protocol version")
+	return nil
+}
+
+// initializeWithVersion connects a fresh session and sends an initialize
+// request for version. The request is sent raw so that versions the client
+// library would refuse, and the server's errors, are seen as sent. A version
+// that is answered is then checked by completing the handshake and pinging.
+func initializeWithVersion(dial func(ctx context.Context) (*client.Client, error), version string, timeout time.Duration) versionMatrixEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	entry := versionMatrixEntry{Requested: version, Known: slices.Contains(mcp.ValidProtocolVersions, version)}
+	start := time.Now()
+	mcpClient, err := dial(ctx)
+	if err != nil {
+		entry.Result, entry.Detail = versionRejected, fmt.Sprintf("failed to connect: %v", err)
+		entry.Duration = time.Since(start)
+		return entry
+	}
+	defer func() { _ = mcpClient.Close() }()
+
+	params := newInitializeRequest().Params
+	params.ProtocolVersion = version
+	body, err := json.Marshal(params)
+	if err != nil {
+		entry.Result, entry.Detail = versionRejected, fmt.Sprintf("failed to encode the request: %v", err)
+		return entry
+	}
+	response, err := mcpClient.GetTransport().SendRequest(ctx, transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(int64(versionMatrixRequestID)),
+		Method:  string(mcp.MethodInitialize),
+		Params:  json.RawMessage(body),
+	})
+	entry.Duration = time.Since(start)
+	report.addTiming(string(mcp.MethodInitialize)+" "+version, entry.Duration, err)
+	switch {
+	case err != nil:
+		entry.Result, entry.Detail = versionRejected, err.Error()
+		return entry
+	case response.Error != nil:
+		entry.Result = versionRejected
+		entry.Detail = fmt.Sprintf("error %d: %s%s", response.Error.Code, response.Error.Message, supportedVersionsDetail(response.Error.Data))
+		return entry
+	}
+
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil || result.ProtocolVersion == "" {
+		entry.Result, entry.Detail = versionRejected, "the result has no protocolVersion"
+		return entry
+	}
+	entry.Answered = result.ProtocolVersion
+	switch {
+	case entry.Answered == version && entry.Known:
+		entry.Result = versionAccepted
+	case entry.Answered == version:
+		entry.Result, entry.Detail = versionEchoed, "the server claims to support a version that does not exist"
+		return entry
+	case !slices.Contains(mcp.ValidProtocolVersions, entry.Answered):
+		entry.Result, entry.Detail = versionNegotiated, "answered with a version the probe does not know"
+		return entry
+	case entry.Answered > version:
+		entry.Result, entry.Detail = versionNegotiated, "newer than requested; a client of this version would disconnect"
+		return entry
+	default:
+		entry.Result = versionNegotiated
+	}
+
+	// The answered version must also work for the rest of the session
+	if httpConn, ok := mcpClient.GetTransport().(transport.HTTPConnection); ok {
+		httpConn.SetProtocolVersion(entry.Answered)
+	}
+	err = mcpClient.GetTransport().SendNotification(ctx, mcp.JSONRPCNotification{
+		JSONRPC:      mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{Method: "notifications/initialized"},
+	})
+	if err == nil {
+		var ping *transport.JSONRPCResponse
+		ping, err = mcpClient.GetTransport().SendRequest(ctx, transport.JSONRPCRequest{
+			JSONRPC: mcp.JSONRPC_VERSION,
+			ID:      mcp.NewRequestId(int64(versionMatrixRequestID + 1)),
+			Method:  string(mcp.MethodPing),
+		})
+		if err == nil && ping.Error != nil {
+			err = &rpcError{Code: ping.Error.Code, Message: ping.Error.Message}
+		}
+	}
+	if err != nil {
+		entry.Result, entry.Detail = versionUnusable, fmt.Sprintf("the session failed after initialization: %v", err)
+	}
+	return entry
+}
+
+// supportedVersionsDetail describes the supported versions that a server
+// lists in the data of an unsupported version error, as the specification's
+// example does
+func supportedVersionsDetail(data any) string {
+	fields, ok := data.(map[string]any)
+	if !ok {
+		return ""
+	}
+	list, ok := fields["supported"].([]any)
+	if !ok || len(list) == 0 {
+		return ""
+	}
+	var supported []string
+	for _, v := range list {
+		supported = append(supported, fmt.Sprint(v))
+	}
+	return " (supported: " + strings.Join(supported, ", ") + ")"
+}
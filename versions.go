@@ -0,0 +1,155 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// parseProtocolVersions parses the -compare-versions list, oldest first.
+// "all" selects every protocol version the probe supports.
+func parseProtocolVersions(spec string) ([]string, error) {
+	var versions []string
+	if strings.EqualFold(strings.TrimSpace(spec), "all") {
+		versions = slices.Clone(mcp.ValidProtocolVersions)
+	} else {
+		for _, v := range strings.Split(spec, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if !slices.Contains(mcp.ValidProtocolVersions, v) {
+				return nil, fmt.Errorf("unknown protocol version '%s' (supported: %s)", v, strings.Join(mcp.ValidProtocolVersions, ", "))
+			}
+			if !slices.Contains(versions, v) {
+				versions = append(versions, v)
+			}
+		}
+	}
+	if len(versions) < 2 {
+		return nil, fmt.Errorf("-compare-versions needs at least two protocol versions or 'all'")
+	}
+	// Versions are dates, so they sort chronologically
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// runVersionComparison runs the capability checks once per protocol version
+// and compares each version's results with the oldest. It returns an error
+// if the server behaves differently under any version or a check failed.
+func runVersionComparison(dial func(ctx context.Context) (*client.Client, error), versions []string, opts suiteOptions, timeout time.Duration) error {
+	fmt.Println("=== Protocol Version Comparison ===")
+
+	results := make([][]checkOutcome, len(versions))
+	for i, version := range versions {
+		opts.protocolVersion = version
+		results[i] = runCapabilitySuite(dial, timeout, opts)
+		negotiated := negotiatedVersion(results[i])
+		switch {
+		case negotiated == "":
+			fmt.Printf("%s  initialization failed\n", version)
+		case negotiated != version:
+			fmt.Printf("%s  server negotiated %s instead (not compared)\n", version, negotiated)
+		default:
+			fmt.Printf("%s  negotiated\n", version)
+		}
+	}
+	fmt.Println()
+
+	width := len("Check")
+	for _, o := range results[0] {
+		width = max(width, len(o.ID))
+	}
+	header := fmt.Sprintf("%-*s", width, "Check")
+	for _, version := range versions {
+		header += fmt.Sprintf("  %-22s", version)
+	}
+	fmt.Println(strings.TrimRight(header, " "))
+	for row, o := range results[0] {
+		line := fmt.Sprintf("%-*s", width, o.ID)
+		for i := range versions {
+			line += fmt.Sprintf("  %-22s", outcomeCell(results[i][row]))
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+
+	// The negotiated version is expected to differ, so it is not compared.
+	// Versions the server did not accept would only repeat the results of
+	// the version it chose instead.
+	base := withoutProtocolVersion(results[0])
+	diffs := []behaviorDifference{}
+	for i := 1; i < len(versions); i++ {
+		if negotiated := negotiatedVersion(results[i]); negotiated != "" && negotiated != versions[i] {
+			continue
+		}
+		for _, d := range compareOutcomes(versions[0], versions[i], base, withoutProtocolVersion(results[i])) {
+			d.Detail = fmt.Sprintf("%s → %s: %s", versions[0], versions[i], d.Detail)
+			diffs = append(diffs, d)
+		}
+	}
+	report.setVersionDiffs(diffs)
+	emitEvent(eventVersionDiff, map[string]any{
+		"versions":    versions,
+		"differences": diffs,
+		"summary":     impactSummary(diffs),
+	})
+
+	failed := recordSuiteFailures(results...) > 0
+	differ := recordDifferences(checkIDVersionDiff, diffs, nil) > 0
+
+	if len(diffs) == 0 {
+		fmt.Println("\nNo differences: the server behaves the same under every version")
+	} else {
+		fmt.Printf("\nDifferences from %s (%d: %s):\n", versions[0], len(diffs), impactSummary(diffs))
+		printDifferences(diffs)
+	}
+
+	switch {
+	case differ:
+		return fmt.Errorf("protocol versions differ (%s)", impactSummary(diffs))
+	case failed:
+		return fmt.Errorf("checks failed under every protocol version")
+	}
+	return nil
+}
+
+// negotiatedVersion returns the protocol version the server chose, or "" if
+// initialization failed
+func negotiatedVersion(outcomes []checkOutcome) string {
+	for _, o := range outcomes {
+		if o.ID == checkInitialize && o.Err == nil {
+			var version string
+			_ = json.Unmarshal([]byte(o.Items["protocolVersion"]), &version)
+			return version
+		}
+	}
+	return ""
+}
+
+// withoutProtocolVersion returns a copy of the outcomes with the negotiated
+// protocol version removed from the initialize result
+func withoutProtocolVersion(outcomes []checkOutcome) []checkOutcome {
+	result := slices.Clone(outcomes)
+	for i, o := range result {
+		if o.ID == checkInitialize && o.Items != nil {
+			items := make(map[string]string, len(o.Items))
+			for key, value := range o.Items {
+				if key != "protocolVersion" {
+					items[key] = value
+				}
+			}
+			result[i].Items = items
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sources of warnings
+const (
+	// warningFinding is a finding of severity warning
+	warningFinding = "finding"
+	// warningCheck is a sub-check that failed without failing the run
+	warningCheck = "check"
+	// warningCapability is an advertised capability that does not work
+	warningCapability = "capability"
+	// warningProbe is a problem of the probe's own setup, such as a cache
+	// that could not be written
+	warningProbe = "probe"
+)
+
+// warningSummaryLimit is the number of warnings listed at the end of the
+// console output; the report lists all of them
+const warningSummaryLimit = 20
+
+// warningRecord is a warning raised during the run. Warnings are kept apart
+// from the results so that they can be counted, and are summarized at the
+// end of the console output.
+type warningRecord struct {
+	Source  string    `json:"source"`
+	Check   string    `json:"check,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	Message string    `json:"message"`
+	At      timestamp `json:"at"`
+}
+
+// addWarning records a warning and returns its message, for printing where
+// it was raised
+func (r *probeReport) addWarning(source, format string, v ...any) string {
+	message := fmt.Sprintf(format, v...)
+	r.recordWarning(warningRecord{Source: source, Message: message})
+	return message
+}
+
+// recordWarning records a warning and emits it with -output ndjson
+func (r *probeReport) recordWarning(w warningRecord) {
+	w.At = timestamp(time.Now())
+	fields := map[string]any{"source": w.Source, "message": w.Message}
+	if w.Check != "" {
+		fields["check"] = w.Check
+	}
+	if w.Subject != "" {
+		fields["subject"] = w.Subject
+	}
+	emitEvent(eventWarning, fields)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, w)
+}
+
+// printWarningSummary lists the run's warnings at the end of the console
+// output, so that they are not lost in a long run
+func printWarningSummary() {
+	report.mu.Lock()
+	warnings := append([]warningRecord(nil), report.Warnings...)
+	report.mu.Unlock()
+	if len(warnings) == 0 {
+		return
+	}
+
+	counts := map[string]int{}
+	for _, w := range warnings {
+		counts[w.Source]++
+	}
+	fmt.Printf("\n=== Warnings (%d) ===\n", len(warnings))
+	for i, w := range warnings {
+		if i == warningSummaryLimit {
+			fmt.Printf("  ... and %d more (all are included in -report json)\n", len(warnings)-i)
+			break
+		}
+		label := w.Source
+		if w.Check != "" {
+			label = w.Check
+		}
+		fmt.Printf("  %-10s  %s\n", label, w.Message)
+	}
+	summary := ""
+	for _, source := range []string{warningFinding, warningCheck, warningCapability, warningProbe} {
+		if counts[source] > 0 {
+			if summary != "" {
+				summary += ", "
+			}
+			summary += fmt.Sprintf("%d %s", counts[source], source)
+		}
+	}
+	fmt.Printf("By source: %s\n", summary)
+}